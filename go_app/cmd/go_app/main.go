@@ -0,0 +1,217 @@
+// @title Go App API
+// @version 1.0
+// @description 用户管理系统 API 文档
+// @termsOfService http://swagger.io/terms/
+
+// @contact.name API Support
+// @contact.url http://www.swagger.io/support
+// @contact.email support@swagger.io
+
+// @license.name Apache 2.0
+// @license.url http://www.apache.org/licenses/LICENSE-2.0.html
+
+// @host localhost:8080
+// @BasePath /api
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name Authorization
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"go_app/config"
+	_ "go_app/docs"
+	"go_app/migrations"
+	"go_app/pkg/ratelimit"
+	"go_app/pkg/websocket"
+	"go_app/repository"
+	"go_app/services"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// 退出码，便于脚本判断执行结果
+const (
+	exitOK       = 0
+	exitError    = 1 // 执行失败
+	exitUsage    = 2 // 参数错误
+	exitNotFound = 3 // 用户不存在
+	exitConflict = 4 // 用户已存在
+)
+
+const usage = `用法: go_app [-config path] <命令> [参数]
+
+命令:
+  serve      启动 HTTP 服务（未指定命令时默认执行）
+  migrate    管理数据库迁移：up / down [n] / status / create <name>
+  user       用户管理：create / reset-password / disable / enable / list / revoke-sessions
+
+退出码: 0 成功，1 执行失败，2 参数错误，3 用户不存在，4 用户已存在
+
+选项:`
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	fs := flag.NewFlagSet("go_app", flag.ContinueOnError)
+	configPath := fs.String("config", "", "配置文件路径，默认读取 GO_APP_CONFIG 环境变量或 "+config.DefaultPath)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	command, rest := "serve", fs.Args()
+	if len(rest) > 0 {
+		command, rest = rest[0], rest[1:]
+	}
+
+	switch command {
+	case "serve":
+		serve(*configPath)
+		return exitOK
+	case "migrate":
+		_, db, err := openDB(*configPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		return migrations.Run(db, rest)
+	case "user":
+		return runUser(*configPath, rest)
+	case "help":
+		fs.SetOutput(os.Stdout)
+		fs.Usage()
+		return exitOK
+	}
+	fmt.Fprintf(os.Stderr, "未知的命令: %s\n\n", command)
+	fs.Usage()
+	return exitUsage
+}
+
+// openDB 加载配置并连接数据库，供命令行子命令使用，不监听配置变更
+func openDB(configPath string) (*config.Config, *gorm.DB, error) {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("加载配置失败: %v", err)
+	}
+	if err := services.ConnectDB(cfg.Database); err != nil {
+		return nil, nil, fmt.Errorf("连接数据库失败: %v", err)
+	}
+	// 命令行输出需要便于脚本解析，关闭 SQL 日志
+	return cfg, services.GetDB().Session(&gorm.Session{Logger: gormlogger.Discard}), nil
+}
+
+// userServices 用户管理命令使用的服务
+type userServices struct {
+	users     *services.UserService
+	roles     *services.RoleService
+	twoFactor *services.TwoFactorService
+	// sharedThrottle 登录失败记录保存在共享存储中，命令行解除锁定对运行中的服务生效
+	sharedThrottle bool
+}
+
+// newUserServices 创建命令行使用的用户、角色和两步验证服务，与 HTTP 服务共用同一套业务逻辑。
+// 数据库存在未执行的迁移时拒绝操作，避免在旧表结构上读写
+func newUserServices(configPath string) (*userServices, error) {
+	cfg, db, err := openDB(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := migrations.NewMigrator(db).Pending()
+	if err != nil {
+		return nil, fmt.Errorf("查询迁移状态失败: %v", err)
+	}
+	if pending > 0 {
+		return nil, fmt.Errorf("数据库有 %d 个迁移未执行，请先运行 go_app migrate up", pending)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	tokenService, err := services.NewTokenService(userRepo, sessionRepo, cfg.JWT)
+	if err != nil {
+		return nil, fmt.Errorf("加载 JWT 密钥失败: %v", err)
+	}
+	sessionService := services.NewSessionService(userRepo, sessionRepo, tokenService, cfg.Session, cfg.JWT)
+	roleService := services.NewRoleService(repository.NewRoleRepository(db), userRepo)
+	redisClient, err := connectRedis(cfg)
+	if err != nil {
+		return nil, err
+	}
+	throttle := services.NewLoginThrottleService(newLoginAttemptRepository(cfg, redisClient), cfg.LoginThrottle)
+	userService := services.NewUserService(userRepo, sessionService, roleService, throttle, cfg.ImageHost)
+	// 重置密码时同时吊销用户授权给其他应用的令牌
+	oauthService, err := services.NewOAuthService(userRepo, repository.NewOAuthClientRepository(db), repository.NewOAuthConsentRepository(db),
+		repository.NewOAuthCodeRepository(db), repository.NewOAuthTokenRepository(db), cfg.JWT, cfg.OAuthServer)
+	if err != nil {
+		return nil, fmt.Errorf("加载 JWT 密钥失败: %v", err)
+	}
+	userService.SetOAuthService(oauthService)
+	return &userServices{
+		users: userService,
+		roles: roleService,
+		twoFactor: services.NewTwoFactorService(userRepo, repository.NewTwoFactorRepository(db),
+			repository.NewTwoFactorChallengeRepository(db), throttle, cfg.TwoFactor),
+		sharedThrottle: cfg.LoginThrottle.Store == config.LoginThrottleStoreRedis,
+	}, nil
+}
+
+// connectRedis 有组件配置为使用 Redis 时连接 Redis，否则返回 nil
+func connectRedis(cfg *config.Config) (*redis.Client, error) {
+	if cfg.LoginThrottle.Store != config.LoginThrottleStoreRedis && cfg.RateLimit.Store != config.RateLimitStoreRedis &&
+		cfg.WebSocket.Backplane != config.WebSocketBackplaneRedis {
+		return nil, nil
+	}
+	client, err := services.NewRedisClient(cfg.Redis)
+	if err != nil {
+		return nil, fmt.Errorf("连接 Redis 失败: %v", err)
+	}
+	return client, nil
+}
+
+// newLoginAttemptRepository 按 login_throttle.store 创建登录失败记录存储
+func newLoginAttemptRepository(cfg *config.Config, client *redis.Client) repository.LoginAttemptRepository {
+	if cfg.LoginThrottle.Store == config.LoginThrottleStoreRedis {
+		return repository.NewRedisLoginAttemptRepository(client)
+	}
+	return repository.NewMemoryLoginAttemptRepository()
+}
+
+// newRateLimitStore 按 rate_limit.store 创建限流计数存储
+func newRateLimitStore(cfg *config.Config, client *redis.Client) ratelimit.Store {
+	if cfg.RateLimit.Store == config.RateLimitStoreRedis {
+		return ratelimit.NewRedisStore(client)
+	}
+	return ratelimit.NewMemoryStore()
+}
+
+// newWebSocketBackplane 按 websocket.backplane 创建 WebSocket 消息的跨实例转发通道
+func newWebSocketBackplane(cfg *config.Config, client *redis.Client) websocket.Backplane {
+	if cfg.WebSocket.Backplane == config.WebSocketBackplaneRedis {
+		return websocket.NewRedisBackplane(client)
+	}
+	return websocket.NewMemoryBackplane(websocket.NewMemoryHub())
+}
+
+// newPresenceRepository 在线状态存储，与 websocket.backplane 一致：使用 Redis 转发消息的多个实例共享在线状态
+func newPresenceRepository(cfg *config.Config, client *redis.Client) repository.PresenceRepository {
+	if cfg.WebSocket.Backplane == config.WebSocketBackplaneRedis {
+		return repository.NewRedisPresenceRepository(client)
+	}
+	return repository.NewMemoryPresenceRepository()
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCLI 按顺序在同一个 SQLite 数据库上执行命令，校验退出码和输出
+func TestCLI(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	writeFile(t, configPath, `
+database:
+  driver: sqlite
+  path: `+filepath.Join(dir, "app.db")+`
+  migrate_on_start: false
+jwt:
+  secret: cli-test-secret
+`)
+	t.Setenv("GO_APP_CONFIG", configPath)
+
+	steps := []struct {
+		name  string
+		args  []string
+		stdin string
+		code  int
+		// stdout、stderr 为输出中应包含的内容
+		stdout, stderr string
+	}{
+		// 命令和参数错误
+		{"UnknownCommand", []string{"bogus"}, "", exitUsage, "", "未知的命令: bogus"},
+		{"UnknownFlag", []string{"-bogus"}, "", exitUsage, "", "flag provided but not defined"},
+		{"Help", []string{"help"}, "", exitOK, "user       用户管理", ""},
+		{"MissingConfig", []string{"-config", filepath.Join(dir, "missing.yaml"), "migrate", "status"}, "", exitError, "", "加载配置失败"},
+		{"MigrateUsage", []string{"migrate"}, "", exitUsage, "", "down [n]"},
+		{"MigrateUnknown", []string{"migrate", "bogus"}, "", exitUsage, "", "down [n]"},
+		{"MigrateDownInvalid", []string{"migrate", "down", "0"}, "", exitUsage, "", "回滚数量无效: 0"},
+		{"UserUsage", []string{"user"}, "", exitUsage, "", "子命令:"},
+		{"UserUnknown", []string{"user", "bogus"}, "", exitUsage, "", "未知的子命令: bogus"},
+		{"UserExtraArgs", []string{"user", "disable", "--id", "1", "extra"}, "", exitUsage, "", "多余的参数: extra"},
+		{"UserTargetBoth", []string{"user", "disable", "--id", "1", "--email", "a@example.com"}, "", exitUsage, "", "--id 或 --email"},
+		{"UserTargetInvalid", []string{"user", "disable", "--id", "0"}, "", exitUsage, "", "无效的用户ID"},
+		{"CreateInvalidEmail", []string{"user", "create", "--email", "alice", "--password", "secret1"}, "", exitUsage, "", "有效的邮箱"},
+		{"CreateShortPassword", []string{"user", "create", "--email", "alice@example.com", "--password", "123"}, "", exitUsage, "", "密码长度"},
+		{"CreateBothPasswords", []string{"user", "create", "--email", "alice@example.com", "--password", "secret1", "--password-stdin"}, "", exitUsage, "", "不能同时使用"},
+		{"ListInvalidFormat", []string{"user", "list", "--format", "xml"}, "", exitUsage, "", "--format"},
+
+		// 迁移
+		{"PendingMigrations", []string{"user", "list"}, "", exitError, "", "migrate up"},
+		{"MigrateUp", []string{"migrate", "up"}, "", exitOK, "已执行 0001_", ""},
+		{"MigrateUpAgain", []string{"migrate", "up"}, "", exitOK, "没有需要执行的迁移", ""},
+		{"MigrateDown", []string{"migrate", "down"}, "", exitOK, "已回滚", ""},
+		{"MigrateStatus", []string{"migrate", "status"}, "", exitOK, "未执行", ""},
+		{"MigrateUpRest", []string{"migrate", "up"}, "", exitOK, "已执行", ""},
+
+		// 用户管理
+		{"Create", []string{"user", "create", "--email", "alice@example.com", "--password", "secret1"}, "", exitOK, "1\n", ""},
+		{"CreateDuplicate", []string{"user", "create", "--email", "alice@example.com", "--password", "secret1"}, "", exitConflict, "", "已被注册"},
+		{"CreateFromStdin", []string{"user", "create", "--email", "bob@example.com", "--password-stdin"}, "secret22\n", exitOK, "2\n", ""},
+		{"CreateStdinShort", []string{"user", "create", "--email", "carol@example.com", "--password-stdin"}, "123\n", exitUsage, "", "密码长度"},
+		{"DisableNotFound", []string{"user", "disable", "--id", "99"}, "", exitNotFound, "", "用户不存在"},
+		{"DisableByEmailNotFound", []string{"user", "disable", "--email", "nobody@example.com"}, "", exitNotFound, "", "用户不存在"},
+		{"Disable", []string{"user", "disable", "--email", "alice@example.com"}, "", exitOK, "", "已禁用用户 1"},
+		{"GrantUnknownRole", []string{"user", "grant-role", "--id", "1", "--role", "nosuch"}, "", exitNotFound, "", "nosuch"},
+		{"GrantRole", []string{"user", "grant-role", "--id", "1", "--role", "admin"}, "", exitOK, "", "分配角色 admin"},
+		{"ResetPasswordNotFound", []string{"user", "reset-password", "--id", "99", "--password", "secret1"}, "", exitNotFound, "", "用户不存在"},
+		{"UnlockMemoryStore", []string{"user", "unlock", "--id", "1"}, "", exitError, "", "login_throttle.store"},
+		{"ListTable", []string{"user", "list"}, "", exitOK, "ID  USERNAME  EMAIL", ""},
+	}
+	for _, step := range steps {
+		code, stdout, stderr := runCLI(t, step.stdin, step.args...)
+		if code != step.code {
+			t.Fatalf("%s: 退出码 = %d, want %d\nstdout: %s\nstderr: %s", step.name, code, step.code, stdout, stderr)
+		}
+		if !strings.Contains(stdout, step.stdout) {
+			t.Fatalf("%s: 标准输出中没有 %q\nstdout: %s", step.name, step.stdout, stdout)
+		}
+		if !strings.Contains(stderr, step.stderr) {
+			t.Fatalf("%s: 标准错误中没有 %q\nstderr: %s", step.name, step.stderr, stderr)
+		}
+	}
+
+	// 机器可读的输出格式
+	code, stdout, stderr := runCLI(t, "", "user", "list", "--format", "json")
+	if code != exitOK {
+		t.Fatalf("json: 退出码 = %d, stderr: %s", code, stderr)
+	}
+	var rows []userRow
+	if err := json.Unmarshal([]byte(stdout), &rows); err != nil {
+		t.Fatalf("json: 解析输出失败: %v\n%s", err, stdout)
+	}
+	if len(rows) != 2 || rows[0].Email != "alice@example.com" || !rows[0].Disabled || rows[0].DisabledAt == nil ||
+		rows[1].Username != "bob" || rows[1].Disabled {
+		t.Fatalf("json: 输出 = %+v", rows)
+	}
+
+	code, stdout, stderr = runCLI(t, "", "user", "list", "--format", "csv")
+	if code != exitOK {
+		t.Fatalf("csv: 退出码 = %d, stderr: %s", code, stderr)
+	}
+	records, err := csv.NewReader(strings.NewReader(stdout)).ReadAll()
+	if err != nil {
+		t.Fatalf("csv: 解析输出失败: %v\n%s", err, stdout)
+	}
+	if len(records) != 3 || strings.Join(records[0], ",") != "id,username,email,disabled,disabled_at,created_at" ||
+		records[1][3] != "true" || records[1][4] == "" || records[2][2] != "bob@example.com" || records[2][4] != "" {
+		t.Fatalf("csv: 输出 = %v", records)
+	}
+}
+
+// runCLI 执行命令并返回退出码、标准输出和标准错误
+func runCLI(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	in := filepath.Join(dir, "stdin")
+	writeFile(t, in, stdin)
+	files := make([]*os.File, 3)
+	for i, name := range []string{"stdin", "stdout", "stderr"} {
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		files[i] = f
+	}
+
+	oldStdin, oldStdout, oldStderr := os.Stdin, os.Stdout, os.Stderr
+	os.Stdin, os.Stdout, os.Stderr = files[0], files[1], files[2]
+	code := run(args)
+	os.Stdin, os.Stdout, os.Stderr = oldStdin, oldStdout, oldStderr
+
+	return code, readAll(t, files[1]), readAll(t, files[2])
+}
+
+func readAll(t *testing.T, f *os.File) string {
+	t.Helper()
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "log"
+
+    "go_app/config"
+    "go_app/controllers"
+    "go_app/middleware"
+    "go_app/migrations"
+    "go_app/models"
+    "go_app/pkg/logger"
+    "go_app/pkg/mailer"
+    "go_app/pkg/websocket"
+    "go_app/repository"
+    "go_app/routes"
+    "go_app/services"
+
+    "github.com/gin-gonic/gin"
+    swaggerFiles "github.com/swaggo/files"
+    ginSwagger "github.com/swaggo/gin-swagger"
+)
+
+// serve 启动 HTTP 服务
+func serve(configPath string) {
+    // 启动时加载配置并注入各服务，可热加载的配置项通过订阅更新
+    cfg, err := config.LoadConfig(configPath)
+    if err != nil {
+        log.Fatal("加载配置失败:", err)
+    }
+
+    // 监听配置文件和 SIGHUP，热加载可在运行时变更的配置
+    configStore := config.NewStore(configPath, cfg)
+    configStore.Subscribe(func(cfg *config.Config) {
+        level, _ := logger.ParseLevel(cfg.Log.Level)
+        logger.SetLevel(level)
+    })
+    if err := configStore.Watch(context.Background()); err != nil {
+        log.Printf("监听配置文件失败，配置热加载不可用: %v", err)
+    }
+
+    // 设置 Gin 模式
+    if cfg.Server.Mode == "release" {
+        gin.SetMode(gin.ReleaseMode)
+    }
+
+    // 连接数据库
+    if err := services.ConnectDB(cfg.Database); err != nil {
+        log.Fatal("连接数据库失败:", err)
+    }
+
+    // 获取数据库实例
+    db := services.GetDB()
+
+    // 数据库表结构通过 migrations 目录中的版本化迁移管理，多实例同时启动时由迁移锁保证只执行一次；
+    // 关闭 migrate_on_start 时需要先运行 migrate up，存在未执行的迁移则拒绝启动
+    migrator := migrations.NewMigrator(db)
+    if cfg.Database.MigrateOnStart {
+        applied, err := migrator.Up()
+        if err != nil {
+            log.Fatal("数据库迁移失败:", err)
+        }
+        for _, m := range applied {
+            log.Printf("已执行迁移 %04d_%s", m.Version, m.Name)
+        }
+    } else if pending, err := migrator.Pending(); err != nil {
+        log.Fatal("查询迁移状态失败:", err)
+    } else if pending > 0 {
+        log.Fatalf("有 %d 个迁移未执行，请先运行 go_app migrate up", pending)
+    }
+
+    // 仅开发环境：按当前模型补齐尚未编写迁移的字段
+    if cfg.Database.AutoMigrate {
+        log.Println("警告: 已开启 database.auto_migrate，表结构变更请补充对应的迁移文件")
+        if err := db.AutoMigrate(&models.User{}, &models.UserToken{}, &models.RefreshToken{}, &models.Activity{}, &models.Notification{},
+            &models.Role{}, &models.RolePermission{}, &models.UserRole{}, &models.PasswordResetToken{}, &models.EmailVerificationToken{},
+            &models.TwoFactor{}, &models.TwoFactorRecoveryCode{}, &models.TwoFactorChallenge{}, &models.Passkey{}, &models.PasskeyCeremony{}, &models.APIKey{},
+            &models.UserIdentity{}, &models.OIDCFlow{}, &models.OAuthClient{}, &models.OAuthConsent{}, &models.OAuthAuthorizationCode{}, &models.OAuthToken{},
+            &models.WebSocketMessage{}, &models.WebSocketCursor{}, &models.ActivityParticipant{}); err != nil {
+            log.Fatal("数据库迁移失败:", err)
+        }
+    }
+
+    // 初始化 Gin 引擎
+    r := gin.Default()
+    // 客户端 IP 用于登录失败限制和会话记录，只信任配置中的反向代理转发的 X-Forwarded-For
+    if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+        log.Fatal("配置受信任的代理失败:", err)
+    }
+
+    // 添加中间件
+    r.Use(gin.Logger())
+    r.Use(gin.Recovery())
+    r.Use(func(c *gin.Context) {
+        // 根据请求的 Content-Type 设置响应格式
+        contentType := c.ContentType()
+        switch contentType {
+        case "application/x-www-form-urlencoded":
+            c.Header("Content-Type", "application/x-www-form-urlencoded")
+        case "multipart/form-data":
+            c.Header("Content-Type", "multipart/form-data")
+        default:
+            c.Header("Content-Type", "application/json")
+        }
+    })
+
+    // Swagger 配置
+    r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+
+    // 初始化服务和控制器
+    userRepo := repository.NewUserRepository(db)
+    sessionRepo := repository.NewSessionRepository(db)
+    tokenService, err := services.NewTokenService(userRepo, sessionRepo, cfg.JWT)
+    if err != nil {
+        log.Fatal("加载 JWT 密钥失败:", err)
+    }
+    sessionService := services.NewSessionService(userRepo, sessionRepo, tokenService, cfg.Session, cfg.JWT)
+    roleService := services.NewRoleService(repository.NewRoleRepository(db), userRepo)
+    redisClient, err := connectRedis(cfg)
+    if err != nil {
+        log.Fatal(err)
+    }
+    loginThrottleService := services.NewLoginThrottleService(newLoginAttemptRepository(cfg, redisClient), cfg.LoginThrottle)
+    configStore.Subscribe(loginThrottleService.OnConfigChange)
+    userService := services.NewUserService(userRepo, sessionService, roleService, loginThrottleService, cfg.ImageHost)
+    roleController := controllers.NewRoleController(roleService)
+    mail, err := mailer.New(cfg.Mail)
+    if err != nil {
+        log.Fatal("初始化邮件发送失败:", err)
+    }
+    emailVerificationService := services.NewEmailVerificationService(userRepo, repository.NewEmailVerificationRepository(db), mail, cfg.EmailVerification)
+    configStore.Subscribe(emailVerificationService.OnConfigChange)
+    go emailVerificationService.Run(context.Background())
+    emailController := controllers.NewEmailController(emailVerificationService)
+    twoFactorService := services.NewTwoFactorService(userRepo, repository.NewTwoFactorRepository(db), repository.NewTwoFactorChallengeRepository(db), loginThrottleService, cfg.TwoFactor)
+    configStore.Subscribe(twoFactorService.OnConfigChange)
+    twoFactorController := controllers.NewTwoFactorController(twoFactorService, userService)
+    userController := controllers.NewUserController(userService, roleService, emailVerificationService, twoFactorService)
+    passkeyService, err := services.NewPasskeyService(userRepo, repository.NewPasskeyRepository(db), repository.NewPasskeyCeremonyRepository(db), cfg.WebAuthn)
+    if err != nil {
+        log.Fatal("初始化通行密钥失败:", err)
+    }
+    configStore.Subscribe(passkeyService.OnConfigChange)
+    passkeyController := controllers.NewPasskeyController(passkeyService, userService)
+    apiKeyService := services.NewAPIKeyService(userRepo, repository.NewAPIKeyRepository(db), cfg.APIKey)
+    configStore.Subscribe(apiKeyService.OnConfigChange)
+    apiKeyController := controllers.NewAPIKeyController(apiKeyService)
+    oidcService := services.NewOIDCService(userService, repository.NewUserIdentityRepository(db), repository.NewOIDCFlowRepository(db), cfg.OIDC)
+    configStore.Subscribe(oidcService.OnConfigChange)
+    oidcController := controllers.NewOIDCController(oidcService, userService, twoFactorService)
+    oauthService, err := services.NewOAuthService(userRepo, repository.NewOAuthClientRepository(db), repository.NewOAuthConsentRepository(db),
+        repository.NewOAuthCodeRepository(db), repository.NewOAuthTokenRepository(db), cfg.JWT, cfg.OAuthServer)
+    if err != nil {
+        log.Fatal("初始化 OAuth 授权服务失败:", err)
+    }
+    configStore.Subscribe(oauthService.OnConfigChange)
+    userService.SetOAuthService(oauthService)
+    oauthController := controllers.NewOAuthController(oauthService)
+    oauthClientController := controllers.NewOAuthClientController(oauthService)
+    passwordResetService := services.NewPasswordResetService(userService, repository.NewPasswordResetRepository(db), mail, cfg.PasswordReset)
+    configStore.Subscribe(passwordResetService.OnConfigChange)
+    go passwordResetService.Run(context.Background())
+    passwordController := controllers.NewPasswordController(passwordResetService)
+    configStore.Subscribe(sessionService.OnConfigChange)
+    configStore.Subscribe(userService.OnConfigChange)
+    sessionController := controllers.NewSessionController(sessionService)
+
+    // 初始化 WebSocket 管理器，发给用户的消息保存在数据库中供重连后补发
+    wsOutbox := services.NewWebSocketOutboxService(repository.NewWebSocketOutboxRepository(db), cfg.WebSocket)
+    configStore.Subscribe(wsOutbox.OnConfigChange)
+    wsAuthorizer := services.NewWebSocketTopicAuthorizer(repository.NewActivityRepository(db))
+    wsManager, err := websocket.NewManager(cfg.WebSocket, newWebSocketBackplane(cfg, redisClient), wsOutbox, wsAuthorizer.Authorize)
+    if err != nil {
+        log.Fatal("初始化 WebSocket 管理器失败:", err)
+    }
+    configStore.Subscribe(wsManager.OnConfigChange)
+
+    // 在线状态与 WebSocket 消息通道使用相同的存储，多实例部署时共享
+    presenceService := services.NewPresenceService(newPresenceRepository(cfg, redisClient), userRepo, wsManager, cfg.WebSocket)
+    configStore.Subscribe(presenceService.OnConfigChange)
+    wsManager.SetPresenceTracker(presenceService)
+    go presenceService.Run(context.Background())
+    presenceController := controllers.NewPresenceController(presenceService)
+
+    // 初始化 WebSocket 控制器
+    wsController := controllers.NewWebSocketController(wsManager)
+
+    // 公开验签公钥，供其他服务验证本服务签发的 token
+    wellKnownController := controllers.NewWellKnownController(tokenService)
+    r.GET("/.well-known/jwks.json", wellKnownController.JWKS)
+
+    // 接口限流
+    rateLimitService := services.NewRateLimitService(newRateLimitStore(cfg, redisClient), cfg.RateLimit)
+    configStore.Subscribe(rateLimitService.OnConfigChange)
+
+    // API 路由组
+    api := r.Group("/api")
+    {
+        routes.SetupRoutes(api, userController, sessionController, roleController, passwordController, emailController, twoFactorController, passkeyController, apiKeyController, oidcController, oauthController, oauthClientController, presenceController, tokenService, apiKeyService, roleService, emailVerificationService, rateLimitService, cfg.OAuthServer.Enabled)
+        api.GET("/ws", middleware.JWT(tokenService), middleware.RateLimit(rateLimitService), wsController.HandleConnection)
+    }
+
+    // OAuth2 / OIDC 授权服务，供其他应用使用本服务的账号登录
+    if cfg.OAuthServer.Enabled {
+        routes.SetupOAuthRoutes(r, oauthController, rateLimitService)
+    }
+
+    // 启动服务器
+    serverAddr := fmt.Sprintf(":%d", cfg.Server.Port)
+    log.Printf("服务器启动在 http://localhost%s", serverAddr)
+    if err := r.Run(serverAddr); err != nil {
+        log.Fatal("启动服务器失败:", err)
+    }
+    
+    // 添加静态文件服务
+    r.Static("/uploads", "./uploads")
+}
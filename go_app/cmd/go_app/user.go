@@ -0,0 +1,514 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/repository"
+	"go_app/services"
+)
+
+const userUsage = `用法: go_app [-config path] user <子命令> [参数]
+
+子命令:
+  create           创建用户，成功后在标准输出打印用户ID，--role 可额外分配角色
+  reset-password   重置用户密码并注销其全部会话
+  disable          禁用用户并注销其全部会话
+  enable           解除用户禁用
+  list             列出用户，--format table|json|csv
+  revoke-sessions  注销用户的全部会话
+  grant-role       为用户分配角色，如 --role admin
+  revoke-role      移除用户的角色
+  reset-2fa        重置用户的两步验证，用于用户丢失身份验证器和恢复码的情况
+  unlock           解除用户因登录失败次数过多导致的临时锁定，需要 login_throttle.store 为 redis
+
+指定用户使用 --id 或 --email；密码使用 --password 或 --password-stdin（从标准输入读取一行）。
+执行 go_app user <子命令> -h 查看各子命令的参数`
+
+// 密码最小长度，与注册接口的校验规则一致
+const minPasswordLength = 6
+
+// userCommand 用户管理子命令
+type userCommand struct {
+	name  string
+	flags func(fs *flag.FlagSet) // 注册子命令参数
+	// validate 在连接数据库之前校验参数，参数错误时不会访问数据库
+	validate func() error
+	run      func(svc *userServices) error
+}
+
+// usageError 参数错误，退出码为 exitUsage
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usageErrorf(format string, args ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+func runUser(configPath string, args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Fprintln(os.Stderr, userUsage)
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	var cmd *userCommand
+	for _, c := range userCommands() {
+		if c.name == args[0] {
+			cmd = c
+			break
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "未知的子命令: %s\n\n%s\n", args[0], userUsage)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet("go_app user "+cmd.name, flag.ContinueOnError)
+	cmd.flags(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "多余的参数: %s\n", strings.Join(fs.Args(), " "))
+		return exitUsage
+	}
+
+	if err := cmd.validate(); err != nil {
+		return exitCode(err)
+	}
+	svc, err := newUserServices(configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	if err := cmd.run(svc); err != nil {
+		return exitCode(err)
+	}
+	return exitOK
+}
+
+// exitCode 输出错误信息并返回对应的退出码
+func exitCode(err error) int {
+	fmt.Fprintln(os.Stderr, err)
+	var ue *usageError
+	switch {
+	case errors.As(err, &ue):
+		return exitUsage
+	case errors.Is(err, repository.ErrNotFound), errors.Is(err, errcode.UserNotFound), errors.Is(err, errcode.RoleNotFound):
+		return exitNotFound
+	case errors.Is(err, repository.ErrDuplicate):
+		return exitConflict
+	}
+	return exitError
+}
+
+// userTarget 通过 --id 或 --email 指定的目标用户
+type userTarget struct {
+	id    uint
+	email string
+}
+
+func (t *userTarget) register(fs *flag.FlagSet) {
+	fs.Func("id", "用户ID", func(s string) error {
+		id, err := strconv.ParseUint(s, 10, 32)
+		if err != nil || id == 0 {
+			return errors.New("无效的用户ID")
+		}
+		t.id = uint(id)
+		return nil
+	})
+	fs.StringVar(&t.email, "email", "", "用户邮箱")
+}
+
+func (t *userTarget) validate() error {
+	if (t.id == 0) == (t.email == "") {
+		return usageErrorf("必须且只能指定 --id 或 --email 其中之一")
+	}
+	return nil
+}
+
+func (t *userTarget) resolve(us *services.UserService) (*models.User, error) {
+	var (
+		user *models.User
+		err  error
+	)
+	if t.id != 0 {
+		user, err = us.GetUserByID(t.id)
+	} else {
+		user, err = us.GetUserByEmail(t.email)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("用户不存在: %w", err)
+	}
+	return user, err
+}
+
+// passwordInput 通过 --password 或 --password-stdin 提供的密码
+type passwordInput struct {
+	value     string
+	fromStdin bool
+}
+
+func (p *passwordInput) register(fs *flag.FlagSet) {
+	fs.StringVar(&p.value, "password", "", "新密码（会留在 shell 历史中，脚本中建议使用 --password-stdin）")
+	fs.BoolVar(&p.fromStdin, "password-stdin", false, "从标准输入读取密码")
+}
+
+func (p *passwordInput) validate() error {
+	if p.value != "" && p.fromStdin {
+		return usageErrorf("--password 和 --password-stdin 不能同时使用")
+	}
+	if p.value == "" && !p.fromStdin {
+		return usageErrorf("必须通过 --password 或 --password-stdin 提供密码")
+	}
+	if p.value != "" && len(p.value) < minPasswordLength {
+		return usageErrorf("密码长度不能少于 %d 位", minPasswordLength)
+	}
+	return nil
+}
+
+func (p *passwordInput) read() (string, error) {
+	if !p.fromStdin {
+		return p.value, nil
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("读取密码失败: %v", err)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if len(password) < minPasswordLength {
+		return "", usageErrorf("密码长度不能少于 %d 位", minPasswordLength)
+	}
+	return password, nil
+}
+
+func userCommands() []*userCommand {
+	var (
+		target   userTarget
+		password passwordInput
+		username string
+		email    string
+		format   string
+		role     string
+		roles    []string
+	)
+
+	registerRole := func(fs *flag.FlagSet) {
+		target.register(fs)
+		fs.StringVar(&role, "role", "", "角色名称，如 admin、moderator、user")
+	}
+	validateRole := func() error {
+		if err := target.validate(); err != nil {
+			return err
+		}
+		if role == "" {
+			return usageErrorf("必须通过 --role 指定角色")
+		}
+		return nil
+	}
+
+	return []*userCommand{
+		{
+			name: "create",
+			flags: func(fs *flag.FlagSet) {
+				fs.StringVar(&email, "email", "", "邮箱（必填）")
+				fs.StringVar(&username, "username", "", "用户名，默认使用邮箱 @ 之前的部分")
+				fs.Func("role", "额外分配的角色，可重复指定；默认角色 "+models.DefaultRole+" 总会分配", func(s string) error {
+					roles = append(roles, s)
+					return nil
+				})
+				password.register(fs)
+			},
+			validate: func() error {
+				if email == "" || !strings.Contains(email, "@") {
+					return usageErrorf("必须通过 --email 提供有效的邮箱")
+				}
+				return password.validate()
+			},
+			run: func(svc *userServices) error {
+				if username == "" {
+					username = email[:strings.Index(email, "@")]
+				}
+				pw, err := password.read()
+				if err != nil {
+					return err
+				}
+				hashed, err := svc.users.HashPassword(pw)
+				if err != nil {
+					return err
+				}
+				// 管理员通过命令行创建的账号视为邮箱已验证
+				now := time.Now()
+				user := &models.User{Username: username, Email: email, Password: hashed, EmailVerifiedAt: &now}
+				if err := svc.users.CreateUser(user); err != nil {
+					if errors.Is(err, repository.ErrDuplicate) {
+						return fmt.Errorf("邮箱 %s 已被注册: %w", email, err)
+					}
+					return err
+				}
+				for _, r := range roles {
+					if err := svc.roles.AssignRole(user.ID, r); err != nil {
+						return fmt.Errorf("用户 %d 已创建，分配角色 %s 失败: %w", user.ID, r, err)
+					}
+				}
+				fmt.Println(user.ID)
+				return nil
+			},
+		},
+		{
+			name: "reset-password",
+			flags: func(fs *flag.FlagSet) {
+				target.register(fs)
+				password.register(fs)
+			},
+			validate: func() error {
+				if err := target.validate(); err != nil {
+					return err
+				}
+				return password.validate()
+			},
+			run: func(svc *userServices) error {
+				user, err := target.resolve(svc.users)
+				if err != nil {
+					return err
+				}
+				pw, err := password.read()
+				if err != nil {
+					return err
+				}
+				if err := svc.users.ResetPassword(user.ID, pw); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "已重置用户 %d（%s）的密码，其全部会话已注销\n", user.ID, user.Email)
+				return nil
+			},
+		},
+		{
+			name:     "disable",
+			flags:    target.register,
+			validate: target.validate,
+			run: func(svc *userServices) error {
+				user, err := target.resolve(svc.users)
+				if err != nil {
+					return err
+				}
+				if err := svc.users.DisableUser(user.ID); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "已禁用用户 %d（%s），其全部会话已注销\n", user.ID, user.Email)
+				return nil
+			},
+		},
+		{
+			name:     "enable",
+			flags:    target.register,
+			validate: target.validate,
+			run: func(svc *userServices) error {
+				user, err := target.resolve(svc.users)
+				if err != nil {
+					return err
+				}
+				if err := svc.users.EnableUser(user.ID); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "已解除用户 %d（%s）的禁用\n", user.ID, user.Email)
+				return nil
+			},
+		},
+		{
+			name:     "revoke-sessions",
+			flags:    target.register,
+			validate: target.validate,
+			run: func(svc *userServices) error {
+				user, err := target.resolve(svc.users)
+				if err != nil {
+					return err
+				}
+				if err := svc.users.RevokeSessions(user.ID); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "已注销用户 %d（%s）的全部会话\n", user.ID, user.Email)
+				return nil
+			},
+		},
+		{
+			name:     "grant-role",
+			flags:    registerRole,
+			validate: validateRole,
+			run: func(svc *userServices) error {
+				user, err := target.resolve(svc.users)
+				if err != nil {
+					return err
+				}
+				if err := svc.roles.AssignRole(user.ID, role); err != nil {
+					return roleError(role, err)
+				}
+				fmt.Fprintf(os.Stderr, "已为用户 %d（%s）分配角色 %s\n", user.ID, user.Email, role)
+				return nil
+			},
+		},
+		{
+			name:     "revoke-role",
+			flags:    registerRole,
+			validate: validateRole,
+			run: func(svc *userServices) error {
+				user, err := target.resolve(svc.users)
+				if err != nil {
+					return err
+				}
+				if err := svc.roles.RevokeRole(user.ID, role); err != nil {
+					return roleError(role, err)
+				}
+				fmt.Fprintf(os.Stderr, "已移除用户 %d（%s）的角色 %s\n", user.ID, user.Email, role)
+				return nil
+			},
+		},
+		{
+			name:     "reset-2fa",
+			flags:    target.register,
+			validate: target.validate,
+			run: func(svc *userServices) error {
+				user, err := target.resolve(svc.users)
+				if err != nil {
+					return err
+				}
+				if err := svc.twoFactor.Reset(user.ID); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "已重置用户 %d（%s）的两步验证\n", user.ID, user.Email)
+				return nil
+			},
+		},
+		{
+			name:     "unlock",
+			flags:    target.register,
+			validate: target.validate,
+			run: func(svc *userServices) error {
+				// 内存存储的失败记录在服务进程中，命令行进程无法访问，只能通过管理接口解除
+				if !svc.sharedThrottle {
+					return errors.New("login_throttle.store 为 memory 时请使用 POST /api/admin/users/unlock 解除锁定")
+				}
+				user, err := target.resolve(svc.users)
+				if err != nil {
+					return err
+				}
+				if err := svc.users.UnlockLogin(user.ID, ""); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "已解除用户 %d（%s）的登录锁定\n", user.ID, user.Email)
+				return nil
+			},
+		},
+		{
+			name: "list",
+			flags: func(fs *flag.FlagSet) {
+				fs.StringVar(&format, "format", "table", "输出格式：table / json / csv")
+			},
+			validate: func() error {
+				if format != "table" && format != "json" && format != "csv" {
+					return usageErrorf("--format 只能是 table、json 或 csv，当前为 %q", format)
+				}
+				return nil
+			},
+			run: func(svc *userServices) error {
+				users, err := svc.users.ListUsersSafe()
+				if err != nil {
+					return err
+				}
+				return writeUsers(os.Stdout, format, users)
+			},
+		},
+	}
+}
+
+// roleError 为角色不存在的错误补充角色名称
+func roleError(role string, err error) error {
+	if err == errcode.RoleNotFound {
+		return fmt.Errorf("%w: %s", err, role)
+	}
+	return err
+}
+
+// userRow 用户列表输出格式
+type userRow struct {
+	ID         uint       `json:"id"`
+	Username   string     `json:"username"`
+	Email      string     `json:"email"`
+	Disabled   bool       `json:"disabled"`
+	DisabledAt *time.Time `json:"disabledAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+func writeUsers(w io.Writer, format string, users []models.User) error {
+	rows := make([]userRow, 0, len(users))
+	for _, u := range users {
+		rows = append(rows, userRow{
+			ID:         u.ID,
+			Username:   u.Username,
+			Email:      u.Email,
+			Disabled:   u.IsDisabled(),
+			DisabledAt: u.DisabledAt,
+			CreatedAt:  u.CreatedAt,
+		})
+	}
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rows)
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"id", "username", "email", "disabled", "disabled_at", "created_at"})
+		for _, r := range rows {
+			disabledAt := ""
+			if r.DisabledAt != nil {
+				disabledAt = r.DisabledAt.Format(time.RFC3339)
+			}
+			cw.Write([]string{
+				strconv.FormatUint(uint64(r.ID), 10),
+				r.Username,
+				r.Email,
+				strconv.FormatBool(r.Disabled),
+				disabledAt,
+				r.CreatedAt.Format(time.RFC3339),
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tUSERNAME\tEMAIL\tSTATUS\tCREATED AT")
+	for _, r := range rows {
+		status := "active"
+		if r.Disabled {
+			status = "disabled"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", r.ID, r.Username, r.Email, status, r.CreatedAt.Format("2006-01-02 15:04:05"))
+	}
+	return tw.Flush()
+}
@@ -2,12 +2,25 @@ package config
 
 // Config 应用配置结构体
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Database DatabaseConfig `yaml:"database"`
-	JWT      JWTConfig      `yaml:"jwt"`
-    ImageHost struct {
-        Token string `yaml:"token"` // ImgBB API Key
-    } `yaml:"image_host"`
+	Server    ServerConfig    `yaml:"server"`
+	Database  DatabaseConfig  `yaml:"database"`
+	JWT       JWTConfig       `yaml:"jwt"`
+	Session   SessionConfig   `yaml:"session"`
+	ImageHost ImageHostConfig `yaml:"image_host"`
+	Log       LogConfig       `yaml:"log"`
+	Mail      MailConfig      `yaml:"mail"`
+	Redis     RedisConfig     `yaml:"redis"`
+
+	PasswordReset     PasswordResetConfig     `yaml:"password_reset"`
+	EmailVerification EmailVerificationConfig `yaml:"email_verification"`
+	TwoFactor         TwoFactorConfig         `yaml:"two_factor"`
+	WebAuthn          WebAuthnConfig          `yaml:"webauthn"`
+	APIKey            APIKeyConfig            `yaml:"api_key"`
+	OIDC              OIDCConfig              `yaml:"oidc"`
+	OAuthServer       OAuthServerConfig       `yaml:"oauth_server"`
+	LoginThrottle     LoginThrottleConfig     `yaml:"login_throttle"`
+	RateLimit         RateLimitConfig         `yaml:"rate_limit"`
+	WebSocket         WebSocketConfig         `yaml:"websocket"`
 }
 
 // ServerConfig 服务器配置
@@ -15,46 +28,379 @@ type ServerConfig struct {
 	Port    int    `yaml:"port"`
 	Mode    string `yaml:"mode"` // debug or release
 	BaseURL string `yaml:"base_url"`
+	// TrustedProxies 受信任的反向代理 IP 或 CIDR，只有来自这些地址的请求才使用 X-Forwarded-For
+	// 确定客户端 IP；为空时直接使用连接地址
+	TrustedProxies []string `yaml:"trusted_proxies"`
 }
 
+// 数据库驱动
+const (
+	DatabaseDriverMySQL    = "mysql"
+	DatabaseDriverPostgres = "postgres"
+	DatabaseDriverSQLite   = "sqlite"
+)
+
+// SQLiteMemory SQLite 内存数据库路径
+const SQLiteMemory = ":memory:"
+
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
+	Driver   string `yaml:"driver"` // mysql（默认）/ postgres / sqlite
 	Host     string `yaml:"host"`
 	Port     int    `yaml:"port"`
 	User     string `yaml:"user"`
 	Password string `yaml:"password"`
 	DBName   string `yaml:"dbname"`
+	SSLMode  string `yaml:"sslmode"` // 仅 postgres 使用，默认 disable
+	Path     string `yaml:"path"`    // 仅 sqlite 使用，数据库文件路径，":memory:" 为内存数据库
+
+	// 连接池
+	MaxOpenConns    int `yaml:"max_open_conns"`     // 最大打开连接数，0 表示不限制
+	MaxIdleConns    int `yaml:"max_idle_conns"`     // 最大空闲连接数
+	ConnMaxLifetime int `yaml:"conn_max_lifetime"`  // 连接最大存活时间（秒），0 表示不限制
+	ConnMaxIdleTime int `yaml:"conn_max_idle_time"` // 连接最大空闲时间（秒），0 表示不限制
+
+	// 结构迁移
+	MigrateOnStart bool `yaml:"migrate_on_start"` // 启动时执行未执行的迁移，多实例同时启动时由迁移锁保证只执行一次
+	AutoMigrate    bool `yaml:"auto_migrate"`     // 启动时按模型 AutoMigrate，仅用于开发环境快速试验，生产环境禁止开启
 }
 
+// JWT 签名算法
+const (
+	JWTAlgorithmHS256 = "HS256"
+	JWTAlgorithmRS256 = "RS256"
+	JWTAlgorithmEdDSA = "EdDSA"
+)
+
 // JWTConfig JWT配置
 type JWTConfig struct {
-	Secret string `yaml:"secret"`
-	Expire int    `yaml:"expire"` // token过期时间（小时）
-}
-
-// LoadConfig 加载配置
-func LoadConfig() (*Config, error) {
-    return &Config{
-        Server: ServerConfig{
-            Port:    8080,
-            Mode:    "debug",
-            BaseURL: "http://localhost:8080",
-        },
-        Database: DatabaseConfig{
-            Host:     "127.0.0.1",
-            Port:     3306,
-            User:     "root",
-            Password: "123456", // 修改为实际的数据库密码
-            DBName:   "go_app",
-        },
-        JWT: JWTConfig{
-            Secret: "your-secret-key",
-            Expire: 24,
-        },
-        ImageHost: struct {
-            Token string `yaml:"token"`
-        }{
-            Token: "4e13065e1167a6f76cd6c64b17bf5653",
-        },
-    }, nil
+	Algorithm        string         `yaml:"algorithm"`         // 签名算法：HS256（默认）/ RS256 / EdDSA
+	Secret           string         `yaml:"secret"`            // HS256 密钥
+	SigningKey       JWTKeyConfig   `yaml:"signing_key"`       // RS256 / EdDSA 当前签名私钥
+	VerificationKeys []JWTKeyConfig `yaml:"verification_keys"` // 额外的验签公钥，密钥轮换期间保留旧密钥
+	Expire           int            `yaml:"expire"`            // 刷新令牌（会话）过期时间（小时）
+	AccessExpire     int            `yaml:"access_expire"`     // 访问令牌过期时间（分钟）
+}
+
+// JWTKeyConfig PEM 密钥文件配置
+type JWTKeyConfig struct {
+	KID  string `yaml:"kid"`  // 密钥ID，写入 JWT 头部 kid；为空时使用 JWK 指纹
+	File string `yaml:"file"` // PEM 文件路径
+}
+
+// ImageHostConfig 图床配置
+type ImageHostConfig struct {
+	Token string `yaml:"token"` // ImgBB API Key
+}
+
+// LogConfig 日志配置
+type LogConfig struct {
+	Level string `yaml:"level"` // debug / info / warn / error
+}
+
+// 邮件驱动
+const (
+	MailDriverSMTP   = "smtp"
+	MailDriverFile   = "file"
+	MailDriverMemory = "memory"
+)
+
+// SMTP 加密方式
+const (
+	SMTPEncryptionNone     = "none"
+	SMTPEncryptionSTARTTLS = "starttls"
+	SMTPEncryptionTLS      = "tls"
+)
+
+// MailConfig 邮件发送配置
+type MailConfig struct {
+	Driver string     `yaml:"driver"` // smtp / file（默认，写入本地目录）/ memory（仅测试）
+	From   string     `yaml:"from"`   // 发件人，如 "Go App <no-reply@example.com>"
+	Dir    string     `yaml:"dir"`    // file 驱动的邮件保存目录
+	SMTP   SMTPConfig `yaml:"smtp"`
+}
+
+// SMTPConfig SMTP 服务器配置
+type SMTPConfig struct {
+	Host       string `yaml:"host"`
+	Port       int    `yaml:"port"`
+	Username   string `yaml:"username"`
+	Password   string `yaml:"password"`
+	Encryption string `yaml:"encryption"` // none / starttls（默认）/ tls
+}
+
+// PasswordResetConfig 找回密码配置
+type PasswordResetConfig struct {
+	Expire   int    `yaml:"expire"`   // 重置令牌有效期（分钟）
+	URL      string `yaml:"url"`      // 前端重置密码页面地址，令牌以 token 参数追加到地址后；为空时邮件中只包含令牌
+	Cooldown int    `yaml:"cooldown"` // 同一账号两次发送重置邮件的最小间隔（分钟），期间的申请不再发送；0 表示不限制
+}
+
+// EmailVerificationConfig 邮箱验证配置
+type EmailVerificationConfig struct {
+	Expire   int    `yaml:"expire"`   // 验证令牌有效期（分钟）
+	URL      string `yaml:"url"`      // 前端验证页面地址，令牌以 token 参数追加到地址后；为空时邮件中只包含令牌
+	Cooldown int    `yaml:"cooldown"` // 同一账号两次发送验证邮件的最小间隔（分钟），期间重新发送和修改邮箱返回 429；0 表示不限制
+	// Restricted 邮箱未验证的账号不能访问的接口，格式为 "METHOD /path" 或 "/path"（不限方法），
+	// 路径以 * 结尾时按前缀匹配，如 "/api/admin/*"
+	Restricted []string `yaml:"restricted"`
+}
+
+// TwoFactorConfig TOTP 两步验证配置
+type TwoFactorConfig struct {
+	Issuer          string `yaml:"issuer"`           // 身份验证器中显示的服务名称
+	ChallengeExpire int    `yaml:"challenge_expire"` // 两步登录挑战令牌有效期（分钟）
+	MaxAttempts     int    `yaml:"max_attempts"`     // 每个挑战令牌允许的验证码错误次数，关闭两步验证和重新生成恢复码时连续错误的次数上限
+	Lockout         int    `yaml:"lockout"`          // 关闭两步验证和重新生成恢复码连续错误 max_attempts 次后的锁定时间（分钟）
+	RecoveryCodes   int    `yaml:"recovery_codes"`   // 每次生成的恢复码数量
+}
+
+// WebAuthnConfig 通行密钥（WebAuthn）配置
+type WebAuthnConfig struct {
+	RPID          string   `yaml:"rp_id"`           // 依赖方ID，为前端页面的域名（不含协议和端口），如 example.com
+	RPDisplayName string   `yaml:"rp_display_name"` // 创建通行密钥时显示的服务名称
+	RPOrigins     []string `yaml:"rp_origins"`      // 允许发起认证的前端页面来源，如 https://example.com
+	Timeout       int      `yaml:"timeout"`         // 注册和登录流程的有效期（秒）
+}
+
+// APIKeyConfig 个人 API Key 配置
+type APIKeyConfig struct {
+	MaxPerUser int `yaml:"max_per_user"` // 每个用户最多创建的 API Key 数量
+}
+
+// OIDCConfig 第三方（OpenID Connect）登录配置
+type OIDCConfig struct {
+	// RedirectURL 前端回调页面地址，需要在每个提供方登记；页面取出 code 和 state 后提交到 /api/login/oidc
+	RedirectURL string               `yaml:"redirect_url"`
+	FlowExpire  int                  `yaml:"flow_expire"`  // 登录流程有效期（分钟）
+	AllowSignup bool                 `yaml:"allow_signup"` // 第三方账号的邮箱未注册时自动创建账号
+	Providers   []OIDCProviderConfig `yaml:"providers"`
+}
+
+// OIDCProviderConfig OIDC 提供方配置
+type OIDCProviderConfig struct {
+	Name             string   `yaml:"name"`         // 提供方标识，只能包含小写字母、数字、- 和 _，如 google
+	DisplayName      string   `yaml:"display_name"` // 登录按钮上显示的名称
+	Issuer           string   `yaml:"issuer"`       // 如 https://accounts.google.com
+	ClientID         string   `yaml:"client_id"`
+	ClientSecret     string   `yaml:"client_secret"`      // 公开客户端可以为空，只使用 PKCE
+	ClientSecretFile string   `yaml:"client_secret_file"` // 从文件读取 client_secret，与 client_secret 二选一
+	Scopes           []string `yaml:"scopes"`             // 默认 openid email profile
+}
+
+// OAuthServerConfig 作为 OAuth2 / OIDC 授权服务供其他应用使用本服务账号登录的配置
+type OAuthServerConfig struct {
+	// Enabled 开启后注册 /oauth/* 和 /.well-known/openid-configuration，修改后需重启。
+	// ID Token 使用 jwt.signing_key 签名，要求 jwt.algorithm 为 RS256 或 EdDSA
+	Enabled bool `yaml:"enabled"`
+	// Issuer 签发者，即本服务对外的地址，发现文档位于 {issuer}/.well-known/openid-configuration
+	Issuer string `yaml:"issuer"`
+	// ConsentURL 前端授权确认页面，/oauth/authorize 校验请求后携带原参数跳转到该页面
+	ConsentURL    string `yaml:"consent_url"`
+	CodeExpire    int    `yaml:"code_expire"`    // 授权码有效期（分钟）
+	AccessExpire  int    `yaml:"access_expire"`  // 访问令牌和 ID Token 有效期（分钟）
+	RefreshExpire int    `yaml:"refresh_expire"` // 刷新令牌有效期（小时）
+}
+
+// RedisConfig Redis 连接配置，多实例部署时用于共享状态
+type RedisConfig struct {
+	Addr     string `yaml:"addr"` // host:port，为空表示不使用 Redis
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"`
+}
+
+// 登录失败记录存储
+const (
+	LoginThrottleStoreMemory = "memory"
+	LoginThrottleStoreRedis  = "redis"
+)
+
+// LoginThrottleConfig 登录失败限制配置，同时按账号和客户端 IP 统计失败次数
+type LoginThrottleConfig struct {
+	Store         string `yaml:"store"`           // memory（默认，仅单实例）/ redis（多实例共享）
+	MaxFailures   int    `yaml:"max_failures"`    // 同一账号连续失败达到该次数后临时锁定
+	IPMaxFailures int    `yaml:"ip_max_failures"` // 同一 IP 失败达到该次数后临时锁定该 IP
+	Lockout       int    `yaml:"lockout"`         // 锁定时长（分钟）
+	Window        int    `yaml:"window"`          // 失败计数保留时长（分钟），最后一次失败后超过该时长重新计数
+	BaseDelay     int    `yaml:"base_delay"`      // 账号失败后再次尝试需等待的初始时间（秒），之后每次失败翻倍
+	MaxDelay      int    `yaml:"max_delay"`       // 等待时间上限（秒）
+}
+
+// 限流存储
+const (
+	RateLimitStoreMemory = "memory"
+	RateLimitStoreRedis  = "redis"
+)
+
+// 限流算法
+const (
+	RateLimitTokenBucket   = "token_bucket"
+	RateLimitSlidingWindow = "sliding_window"
+)
+
+// 限流计数的区分方式
+const (
+	RateLimitKeyIP     = "ip"      // 客户端 IP
+	RateLimitKeyUser   = "user"    // 登录用户，未登录的请求按 IP
+	RateLimitKeyAPIKey = "api_key" // X-API-Key 请求头，没有时按 IP
+)
+
+// RateLimitConfig 接口限流配置
+type RateLimitConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Store   string `yaml:"store"` // memory（默认，仅单实例）/ redis（多实例共享）
+	// Default 未匹配 Routes 的 /api 接口使用的规则，Limit 为 0 时不限制
+	Default RateLimitRule `yaml:"default"`
+	// Routes 单独限制的接口，按顺序匹配第一条，匹配后不再计入 Default
+	Routes []RateLimitRule `yaml:"routes"`
+}
+
+// RateLimitRule 限流规则：Window 秒内最多 Limit 个请求
+type RateLimitRule struct {
+	Route     string `yaml:"route"`     // "METHOD /path" 或 "/path"，* 结尾按前缀匹配；default 中不填
+	Algorithm string `yaml:"algorithm"` // token_bucket（允许突发）/ sliding_window
+	Limit     int    `yaml:"limit"`
+	Window    int    `yaml:"window"` // 秒
+	Key       string `yaml:"key"`    // ip / user / api_key
+}
+
+// WebSocket 跨节点消息通道
+const (
+	WebSocketBackplaneMemory = "memory"
+	WebSocketBackplaneRedis  = "redis"
+)
+
+// WebSocketConfig WebSocket 实时推送配置
+type WebSocketConfig struct {
+	// Backplane memory（默认，仅单实例）/ redis（多实例部署时通过 Redis 发布订阅把消息转发到用户连接所在的实例）
+	Backplane string `yaml:"backplane"`
+	// MaxConnectionsPerUser 每个用户同时保持的连接数上限，同一用户在多个设备或标签页打开应用时各占一个连接
+	MaxConnectionsPerUser int `yaml:"max_connections_per_user"`
+	// OutboxSize 每个用户保留的最近消息条数，客户端重连后可按消息ID补发
+	OutboxSize int `yaml:"outbox_size"`
+	// OutboxRetention 消息保留时长（分钟），更早的消息不再补发，重连时告知客户端有消息缺失
+	OutboxRetention int `yaml:"outbox_retention"`
+	// PresenceGracePeriod 用户最后一个连接断开后仍视为在线的时长（秒），期间重连不会产生下线和上线事件
+	PresenceGracePeriod int `yaml:"presence_grace_period"`
+	// PresenceTTL 在线记录的有效期（秒），实例定期续期；实例异常退出后其连接在有效期过后视为离线。
+	// 在线状态与 Backplane 使用相同的存储，backplane 为 redis 时多个实例共享
+	PresenceTTL int `yaml:"presence_ttl"`
+}
+
+// 会话策略
+const (
+	SessionPolicySingle      = "single"       // 单会话：新登录会使该用户所有旧会话失效
+	SessionPolicyPerPlatform = "per_platform" // 每个平台一个会话：新登录只替换同平台的旧会话
+	SessionPolicyUnlimited   = "unlimited"    // 不限制：只替换同一设备的旧会话
+)
+
+// SessionConfig 会话配置
+type SessionConfig struct {
+	Policy string `yaml:"policy"` // single / per_platform / unlimited
+}
+
+// Default 返回默认配置，敏感信息（数据库密码、图床 Key 等）不设默认值
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:    8080,
+			Mode:    "debug",
+			BaseURL: "http://localhost:8080",
+		},
+		Database: DatabaseConfig{
+			Driver:          DatabaseDriverMySQL,
+			Host:            "127.0.0.1",
+			Port:            3306,
+			User:            "root",
+			DBName:          "go_app",
+			MaxOpenConns:    50,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 3600,
+			MigrateOnStart:  true,
+		},
+		JWT: JWTConfig{
+			Algorithm:    JWTAlgorithmHS256,
+			Expire:       24,
+			AccessExpire: 15,
+		},
+		Session: SessionConfig{
+			Policy: SessionPolicyPerPlatform,
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Mail: MailConfig{
+			Driver: MailDriverFile,
+			From:   "Go App <no-reply@localhost>",
+			Dir:    "data/mail",
+			SMTP: SMTPConfig{
+				Port:       587,
+				Encryption: SMTPEncryptionSTARTTLS,
+			},
+		},
+		PasswordReset: PasswordResetConfig{
+			Expire:   30,
+			Cooldown: 2,
+		},
+		EmailVerification: EmailVerificationConfig{
+			Expire:     24 * 60,
+			Cooldown:   2,
+			Restricted: []string{"/api/admin/*", "POST /api/users/avatar"},
+		},
+		TwoFactor: TwoFactorConfig{
+			Issuer:          "Go App",
+			ChallengeExpire: 5,
+			MaxAttempts:     5,
+			Lockout:         15,
+			RecoveryCodes:   10,
+		},
+		WebAuthn: WebAuthnConfig{
+			RPID:          "localhost",
+			RPDisplayName: "Go App",
+			RPOrigins:     []string{"http://localhost:8080"},
+			Timeout:       300,
+		},
+		APIKey: APIKeyConfig{
+			MaxPerUser: 20,
+		},
+		OIDC: OIDCConfig{
+			RedirectURL: "http://localhost:8080/login/oidc/callback",
+			FlowExpire:  10,
+			AllowSignup: true,
+		},
+		OAuthServer: OAuthServerConfig{
+			Issuer:        "http://localhost:8080",
+			ConsentURL:    "http://localhost:8080/oauth/consent",
+			CodeExpire:    5,
+			AccessExpire:  60,
+			RefreshExpire: 30 * 24,
+		},
+		LoginThrottle: LoginThrottleConfig{
+			Store:         LoginThrottleStoreMemory,
+			MaxFailures:   5,
+			IPMaxFailures: 50,
+			Lockout:       15,
+			Window:        15,
+			BaseDelay:     1,
+			MaxDelay:      60,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Store:   RateLimitStoreMemory,
+			Default: RateLimitRule{Algorithm: RateLimitTokenBucket, Limit: 300, Window: 60, Key: RateLimitKeyUser},
+			Routes: []RateLimitRule{
+				{Route: "POST /api/login*", Algorithm: RateLimitSlidingWindow, Limit: 10, Window: 60, Key: RateLimitKeyIP},
+				{Route: "POST /api/register", Algorithm: RateLimitSlidingWindow, Limit: 5, Window: 3600, Key: RateLimitKeyIP},
+				{Route: "POST /api/users/avatar", Algorithm: RateLimitSlidingWindow, Limit: 10, Window: 3600, Key: RateLimitKeyUser},
+			},
+		},
+		WebSocket: WebSocketConfig{
+			Backplane:             WebSocketBackplaneMemory,
+			MaxConnectionsPerUser: 5,
+			OutboxSize:            100,
+			OutboxRetention:       1440,
+			PresenceGracePeriod:   30,
+			PresenceTTL:           90,
+		},
+	}
 }
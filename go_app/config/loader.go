@@ -0,0 +1,163 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// EnvPrefix 环境变量前缀，如 GO_APP_DATABASE_PASSWORD 覆盖 database.password
+	EnvPrefix = "GO_APP_"
+	// EnvConfigPath 指定配置文件路径的环境变量
+	EnvConfigPath = EnvPrefix + "CONFIG"
+	// DefaultPath 默认配置文件路径
+	DefaultPath = "config/config.yaml"
+	// fileSuffix 以文件方式提供配置值的环境变量后缀，如 GO_APP_DATABASE_PASSWORD_FILE
+	fileSuffix = "_FILE"
+)
+
+// ResolvePath 确定配置文件路径，优先级：命令行参数 > GO_APP_CONFIG 环境变量 > 默认路径
+func ResolvePath(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if path := os.Getenv(EnvConfigPath); path != "" {
+		return path
+	}
+	return DefaultPath
+}
+
+// LoadConfig 加载配置：默认值 -> YAML 文件 -> GO_APP_* 环境变量，最后进行校验。
+// path 为空时使用 ResolvePath("") 的结果，默认路径的配置文件不存在时仅使用默认值和环境变量
+func LoadConfig(path string) (*Config, error) {
+	explicit := path != "" || os.Getenv(EnvConfigPath) != ""
+	path = ResolvePath(path)
+
+	cfg := Default()
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := decodeYAML(data, cfg); err != nil {
+			return nil, fmt.Errorf("解析配置文件 %s 失败: %v", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist) && !explicit:
+		// 未显式指定配置文件时允许只使用环境变量
+	default:
+		return nil, fmt.Errorf("读取配置文件失败: %v", err)
+	}
+
+	if err := applyEnv(reflect.ValueOf(cfg).Elem(), EnvPrefix); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// decodeYAML 严格解析 YAML，拼写错误的配置项会直接报错而不是被静默忽略
+func decodeYAML(data []byte, cfg *Config) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// applyEnv 按 yaml 标签递归生成环境变量名并覆盖配置值，
+// 每个配置项都可以通过 <NAME> 直接提供，或通过 <NAME>_FILE 从文件读取（适用于 Docker/K8s secrets）
+func applyEnv(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		name := prefix + strings.ToUpper(tag)
+		fv := v.Field(i)
+
+		if fv.Kind() == reflect.Struct {
+			if err := applyEnv(fv, name+"_"); err != nil {
+				return err
+			}
+			continue
+		}
+
+		raw, ok, err := lookupEnv(name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := setValue(fv, raw); err != nil {
+			return fmt.Errorf("环境变量 %s 的值无效: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// lookupEnv 读取环境变量，未设置时尝试读取 <NAME>_FILE 指向的文件
+func lookupEnv(name string) (string, bool, error) {
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true, nil
+	}
+	path, ok := os.LookupEnv(name + fileSuffix)
+	if !ok {
+		return "", false, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("读取 %s%s 指定的文件失败: %v", name, fileSuffix, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), true, nil
+}
+
+func setValue(fv reflect.Value, raw string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Slice:
+		// 结构体切片使用 YAML/JSON 格式，如 GO_APP_JWT_VERIFICATION_KEYS='[{kid: a, file: a.pem}]'；
+		// 字符串切片也可以直接使用逗号分隔
+		if fv.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(raw), "[") {
+			parts := strings.Split(raw, ",")
+			for i := range parts {
+				parts[i] = strings.TrimSpace(parts[i])
+			}
+			fv.Set(reflect.ValueOf(parts))
+			return nil
+		}
+		ptr := reflect.New(fv.Type())
+		if err := yaml.Unmarshal([]byte(raw), ptr.Interface()); err != nil {
+			return err
+		}
+		fv.Set(ptr.Elem())
+	default:
+		return fmt.Errorf("不支持的配置类型 %s", fv.Kind())
+	}
+	return nil
+}
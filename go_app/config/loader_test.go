@@ -0,0 +1,189 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestLoadConfigLayers 依次使用默认值、YAML 文件和 GO_APP_* 环境变量，后者覆盖前者
+func TestLoadConfigLayers(t *testing.T) {
+	t.Setenv(EnvConfigPath, "")
+	path := writeConfig(t, `
+server:
+  port: 9000
+  mode: release
+database:
+  host: db.internal
+  password: from-yaml
+jwt:
+  secret: yaml-secret-yaml-secret-yaml-secret
+`)
+	t.Setenv("GO_APP_SERVER_PORT", "9100")
+	t.Setenv("GO_APP_JWT_SECRET", "env-secret-env-secret-env-secret-env")
+	t.Setenv("GO_APP_SERVER_TRUSTED_PROXIES", "10.0.0.0/8, 127.0.0.1")
+	t.Setenv("GO_APP_RATE_LIMIT_ENABLED", "false")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+
+	def := Default()
+	checks := []struct {
+		name      string
+		got, want interface{}
+	}{
+		// 环境变量覆盖 YAML
+		{"server.port", cfg.Server.Port, 9100},
+		{"jwt.secret", cfg.JWT.Secret, "env-secret-env-secret-env-secret-env"},
+		// YAML 覆盖默认值
+		{"server.mode", cfg.Server.Mode, "release"},
+		{"database.host", cfg.Database.Host, "db.internal"},
+		{"database.password", cfg.Database.Password, "from-yaml"},
+		// 环境变量覆盖默认值
+		{"server.trusted_proxies", cfg.Server.TrustedProxies, []string{"10.0.0.0/8", "127.0.0.1"}},
+		{"rate_limit.enabled", cfg.RateLimit.Enabled, false},
+		// 未配置的项保持默认值
+		{"database.port", cfg.Database.Port, def.Database.Port},
+		{"jwt.access_expire", cfg.JWT.AccessExpire, def.JWT.AccessExpire},
+		{"rate_limit.routes", cfg.RateLimit.Routes, def.RateLimit.Routes},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+// TestLoadConfigPath 命令行参数优先于 GO_APP_CONFIG，只有默认路径的配置文件可以不存在
+func TestLoadConfigPath(t *testing.T) {
+	t.Setenv("GO_APP_JWT_SECRET", "secret")
+	flagPath := writeConfig(t, "server:\n  port: 9001\n")
+	envPath := writeConfig(t, "server:\n  port: 9002\n")
+	missing := filepath.Join(t.TempDir(), "missing.yaml")
+
+	t.Setenv(EnvConfigPath, envPath)
+	if got := loadPort(t, flagPath); got != 9001 {
+		t.Fatalf("指定路径时 server.port = %d, want 9001", got)
+	}
+	if got := loadPort(t, ""); got != 9002 {
+		t.Fatalf("使用 GO_APP_CONFIG 时 server.port = %d, want 9002", got)
+	}
+
+	t.Setenv(EnvConfigPath, missing)
+	if _, err := LoadConfig(""); err == nil {
+		t.Fatal("GO_APP_CONFIG 指定的文件不存在时未返回错误")
+	}
+	if _, err := LoadConfig(missing); err == nil {
+		t.Fatal("指定的文件不存在时未返回错误")
+	}
+
+	// 测试在 config 目录中运行，默认路径 config/config.yaml 不存在
+	t.Setenv(EnvConfigPath, "")
+	if got := loadPort(t, ""); got != Default().Server.Port {
+		t.Fatalf("只使用默认值时 server.port = %d, want %d", got, Default().Server.Port)
+	}
+}
+
+// TestLoadConfigSecretFiles <NAME>_FILE 从文件读取配置值并去掉末尾换行，<NAME> 优先于 <NAME>_FILE
+func TestLoadConfigSecretFiles(t *testing.T) {
+	t.Setenv(EnvConfigPath, "")
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "jwt_secret")
+	password := filepath.Join(dir, "db_password")
+	writeFile(t, secret, "file-secret\n")
+	writeFile(t, password, "file-password\r\n")
+
+	t.Setenv("GO_APP_JWT_SECRET_FILE", secret)
+	t.Setenv("GO_APP_DATABASE_PASSWORD_FILE", password)
+	t.Setenv("GO_APP_DATABASE_PASSWORD", "env-password")
+
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	if cfg.JWT.Secret != "file-secret" {
+		t.Fatalf("jwt.secret = %q, want file-secret", cfg.JWT.Secret)
+	}
+	if cfg.Database.Password != "env-password" {
+		t.Fatalf("database.password = %q, want env-password", cfg.Database.Password)
+	}
+
+	t.Setenv("GO_APP_JWT_SECRET_FILE", filepath.Join(dir, "missing"))
+	if _, err := LoadConfig(""); err == nil || !strings.Contains(err.Error(), "GO_APP_JWT_SECRET_FILE") {
+		t.Fatalf("secret 文件不存在时错误 = %v", err)
+	}
+}
+
+// TestLoadConfigInvalid 无法解析的配置文件、无效的环境变量和校验不通过的配置都返回错误
+func TestLoadConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		env  map[string]string
+		// want 错误信息中应包含的内容
+		want string
+	}{
+		{"UnknownField", "server:\n  prot: 9000\n", nil, "prot"},
+		{"MalformedYAML", "server: [\n", nil, "解析配置文件"},
+		{"WrongType", "server:\n  port: abc\n", nil, "解析配置文件"},
+		{"InvalidInt", "", map[string]string{"GO_APP_SERVER_PORT": "abc"}, "GO_APP_SERVER_PORT"},
+		{"InvalidBool", "", map[string]string{"GO_APP_RATE_LIMIT_ENABLED": "maybe"}, "GO_APP_RATE_LIMIT_ENABLED"},
+		{"InvalidSlice", "", map[string]string{"GO_APP_RATE_LIMIT_ROUTES": "[{"}, "GO_APP_RATE_LIMIT_ROUTES"},
+		{"PortRange", "server:\n  port: 70000\n", nil, "server.port"},
+		{"ShortSecretInRelease", "server:\n  mode: release\n", map[string]string{"GO_APP_JWT_SECRET": "short"}, "jwt.secret"},
+		{"UnknownDriver", "database:\n  driver: oracle\n", nil, "database.driver"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvConfigPath, "")
+			t.Setenv("GO_APP_JWT_SECRET", "secret")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			_, err := LoadConfig(writeConfig(t, tt.yaml))
+			if err == nil {
+				t.Fatal("未返回错误")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("错误 %q 中没有 %q", err, tt.want)
+			}
+		})
+	}
+
+	// 校验错误汇总所有问题
+	t.Setenv(EnvConfigPath, "")
+	_, err := LoadConfig(writeConfig(t, "server:\n  port: 0\n  mode: prod\n"))
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Problems) != 3 {
+		t.Fatalf("校验错误 = %v, want 3 个问题", err)
+	}
+}
+
+func loadPort(t *testing.T, path string) int {
+	t.Helper()
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	return cfg.Server.Port
+}
+
+// writeConfig 将 YAML 内容写入临时目录中的配置文件并返回路径
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, content)
+	return path
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
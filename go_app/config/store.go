@@ -0,0 +1,111 @@
+package config
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"go_app/pkg/logger"
+)
+
+// Listener 配置变更回调，参数为已生效的新配置
+type Listener func(cfg *Config)
+
+// Store 持有当前生效的配置，支持原子替换和变更订阅
+type Store struct {
+	path      string // 启动时指定的配置文件路径，可能为空
+	current   atomic.Pointer[Config]
+	mu        sync.Mutex // 串行化重新加载与订阅
+	listeners []Listener
+}
+
+// NewStore 创建配置存储，path 与传给 LoadConfig 的路径一致
+func NewStore(path string, cfg *Config) *Store {
+	s := &Store{path: path}
+	s.current.Store(cfg)
+	return s
+}
+
+// Get 返回当前生效的配置，调用方不应修改返回值
+func (s *Store) Get() *Config {
+	return s.current.Load()
+}
+
+// Path 返回实际读取的配置文件路径
+func (s *Store) Path() string {
+	return ResolvePath(s.path)
+}
+
+// Subscribe 订阅配置变更，订阅时立即以当前配置回调一次
+func (s *Store) Subscribe(listener Listener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listener)
+	s.notify(listener, s.Get())
+}
+
+// Reload 重新读取并校验配置文件，仅替换可在运行时安全变更的配置项。
+// 结构性配置项的修改会被忽略并记录警告，校验失败时保持原配置不变
+func (s *Store) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, err := LoadConfig(s.path)
+	if err != nil {
+		return err
+	}
+
+	old := s.Get()
+	for _, field := range next.keepStatic(old) {
+		logger.Warnf("配置项 %s 需要重启服务才能生效，本次热加载已忽略该修改", field)
+	}
+	if reflect.DeepEqual(old, next) {
+		logger.Infof("配置文件 %s 无可热加载的变更", s.Path())
+		return nil
+	}
+
+	s.current.Store(next)
+	for _, listener := range s.listeners {
+		s.notify(listener, next)
+	}
+	logger.Infof("配置已重新加载: %s", s.Path())
+	return nil
+}
+
+// notify 调用单个订阅者，避免某个订阅者 panic 影响其他订阅者和服务本身
+func (s *Store) notify(listener Listener, cfg *Config) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("配置变更回调异常: %v", r)
+		}
+	}()
+	listener(cfg)
+}
+
+// keepStatic 将不能在运行时变更的配置项恢复为 old 中的值，返回被恢复的配置项名称
+func (c *Config) keepStatic(old *Config) []string {
+	var changed []string
+	keep := func(name string, dst, src interface{}) {
+		d, o := reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem()
+		if !reflect.DeepEqual(d.Interface(), o.Interface()) {
+			changed = append(changed, name)
+			d.Set(o)
+		}
+	}
+
+	keep("server.port", &c.Server.Port, &old.Server.Port)
+	keep("server.mode", &c.Server.Mode, &old.Server.Mode)
+	keep("server.trusted_proxies", &c.Server.TrustedProxies, &old.Server.TrustedProxies)
+	keep("database", &c.Database, &old.Database)
+	keep("jwt.algorithm", &c.JWT.Algorithm, &old.JWT.Algorithm)
+	keep("jwt.secret", &c.JWT.Secret, &old.JWT.Secret)
+	keep("jwt.signing_key", &c.JWT.SigningKey, &old.JWT.SigningKey)
+	keep("jwt.verification_keys", &c.JWT.VerificationKeys, &old.JWT.VerificationKeys)
+	keep("mail", &c.Mail, &old.Mail)
+	keep("redis", &c.Redis, &old.Redis)
+	keep("login_throttle.store", &c.LoginThrottle.Store, &old.LoginThrottle.Store)
+	keep("rate_limit.store", &c.RateLimit.Store, &old.RateLimit.Store)
+	keep("websocket.backplane", &c.WebSocket.Backplane, &old.WebSocket.Backplane)
+	keep("oauth_server.enabled", &c.OAuthServer.Enabled, &old.OAuthServer.Enabled)
+	return changed
+}
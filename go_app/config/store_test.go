@@ -0,0 +1,237 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+const storeConfig = `
+server:
+  port: 8080
+database:
+  host: db-1
+jwt:
+  secret: secret-1
+log:
+  level: info
+session:
+  policy: per_platform
+`
+
+// TestStoreReload 热加载只替换运行时可变更的配置项，结构性配置项保持原值
+func TestStoreReload(t *testing.T) {
+	t.Setenv(EnvConfigPath, "")
+	path := writeConfig(t, storeConfig)
+	store, notified := newTestStore(t, path)
+	old := store.Get()
+
+	writeFile(t, path, `
+server:
+  port: 9090
+database:
+  host: db-2
+jwt:
+  secret: secret-2
+log:
+  level: debug
+session:
+  policy: single
+`)
+	if err := store.Reload(); err != nil {
+		t.Fatalf("重新加载失败: %v", err)
+	}
+
+	cfg := store.Get()
+	if cfg == old {
+		t.Fatal("重新加载后配置未替换")
+	}
+	if cfg.Log.Level != "debug" || cfg.Session.Policy != SessionPolicySingle {
+		t.Fatalf("可热加载的配置项未生效: log.level = %s, session.policy = %s", cfg.Log.Level, cfg.Session.Policy)
+	}
+	if cfg.Server.Port != 8080 || cfg.Database.Host != "db-1" || cfg.JWT.Secret != "secret-1" {
+		t.Fatalf("结构性配置项被修改: server.port = %d, database.host = %s, jwt.secret = %s",
+			cfg.Server.Port, cfg.Database.Host, cfg.JWT.Secret)
+	}
+	if old.Log.Level != "info" {
+		t.Fatal("重新加载修改了旧的配置")
+	}
+	notified.expect(t, cfg)
+
+	// 只修改了结构性配置项时不通知订阅者
+	writeFile(t, path, `
+server:
+  port: 7070
+database:
+  host: db-2
+jwt:
+  secret: secret-2
+log:
+  level: debug
+session:
+  policy: single
+`)
+	if err := store.Reload(); err != nil {
+		t.Fatalf("重新加载失败: %v", err)
+	}
+	if store.Get() != cfg {
+		t.Fatal("没有可热加载的变更时替换了配置")
+	}
+	notified.expect(t)
+}
+
+// TestStoreReloadInvalid 配置文件无效时返回错误，继续使用原配置且不通知订阅者
+func TestStoreReloadInvalid(t *testing.T) {
+	t.Setenv(EnvConfigPath, "")
+	path := writeConfig(t, storeConfig)
+	store, notified := newTestStore(t, path)
+	old := store.Get()
+
+	for name, content := range map[string]string{
+		"Malformed":    "log: [\n",
+		"UnknownField": storeConfig + "unknown: 1\n",
+		"Invalid":      strings.Replace(storeConfig, "port: 8080", "port: 0", 1),
+	} {
+		writeFile(t, path, content)
+		if err := store.Reload(); err == nil {
+			t.Fatalf("%s: 未返回错误", name)
+		}
+		if store.Get() != old {
+			t.Fatalf("%s: 加载失败后配置被替换", name)
+		}
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Reload(); err == nil {
+		t.Fatal("配置文件被删除时未返回错误")
+	}
+	if store.Get() != old {
+		t.Fatal("配置文件被删除后配置被替换")
+	}
+	notified.expect(t)
+}
+
+// TestStoreSubscribe 订阅时立即回调一次，某个订阅者 panic 不影响其他订阅者
+func TestStoreSubscribe(t *testing.T) {
+	t.Setenv(EnvConfigPath, "")
+	path := writeConfig(t, storeConfig)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(path, cfg)
+	store.Subscribe(func(*Config) { panic("listener") })
+	notified := &listener{}
+	store.Subscribe(notified.record)
+	notified.expect(t, cfg)
+
+	writeFile(t, path, storeConfig+"two_factor:\n  max_attempts: 3\n")
+	if err := store.Reload(); err != nil {
+		t.Fatalf("重新加载失败: %v", err)
+	}
+	notified.expect(t, store.Get())
+}
+
+// TestStoreWatch 配置文件变更后自动热加载，无效的修改不影响当前配置
+func TestStoreWatch(t *testing.T) {
+	t.Setenv(EnvConfigPath, "")
+	path := writeConfig(t, storeConfig)
+	store, _ := newTestStore(t, path)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := store.Watch(ctx); err != nil {
+		t.Fatalf("监听配置失败: %v", err)
+	}
+
+	// 编辑器先写临时文件再重命名
+	tmp := filepath.Join(filepath.Dir(path), ".config.yaml.tmp")
+	writeFile(t, tmp, storeConfig+"two_factor:\n  max_attempts: 3\n")
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "文件变更后热加载", func() bool { return store.Get().TwoFactor.MaxAttempts == 3 })
+
+	writeFile(t, path, "log: [\n")
+	time.Sleep(2 * reloadDebounce)
+	if store.Get().TwoFactor.MaxAttempts != 3 {
+		t.Fatal("无效的配置文件替换了当前配置")
+	}
+}
+
+// TestStoreWatchSignal 配置文件在启动时不存在时只能通过 SIGHUP 热加载
+func TestStoreWatchSignal(t *testing.T) {
+	t.Setenv(EnvConfigPath, "")
+	t.Setenv("GO_APP_JWT_SECRET", "secret")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	store := NewStore(path, Default())
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := store.Watch(ctx); err != nil {
+		t.Fatalf("监听配置失败: %v", err)
+	}
+
+	writeFile(t, path, "two_factor:\n  max_attempts: 4\n")
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "SIGHUP 后热加载", func() bool { return store.Get().TwoFactor.MaxAttempts == 4 })
+}
+
+// listener 记录收到的配置
+type listener struct {
+	mu      sync.Mutex
+	configs []*Config
+}
+
+func (l *listener) record(cfg *Config) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.configs = append(l.configs, cfg)
+}
+
+// expect 校验上次调用后收到的配置
+func (l *listener) expect(t *testing.T, want ...*Config) {
+	t.Helper()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.configs) != len(want) {
+		t.Fatalf("收到 %d 次通知, want %d", len(l.configs), len(want))
+	}
+	for i := range want {
+		if l.configs[i] != want[i] {
+			t.Fatalf("第 %d 次通知的配置不是当前配置", i+1)
+		}
+	}
+	l.configs = nil
+}
+
+// newTestStore 加载 path 并创建配置存储，返回的订阅者已消费订阅时的回调
+func newTestStore(t *testing.T, path string) (*Store, *listener) {
+	t.Helper()
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	store := NewStore(path, cfg)
+	l := &listener{}
+	store.Subscribe(l.record)
+	l.expect(t, cfg)
+	return store, l
+}
+
+func waitFor(t *testing.T, what string, ok func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !ok() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待%s超时", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
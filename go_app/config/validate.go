@@ -0,0 +1,240 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// 开发环境默认的 HS256 密钥，生产环境禁止使用
+const insecureJWTSecret = "your-secret-key"
+
+// ValidationError 配置校验错误，汇总所有问题一次性报告
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "配置校验失败:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate 校验配置的完整性和取值范围
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	// 服务器
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port 必须在 1-65535 之间，当前为 %d", c.Server.Port)
+	check(oneOf(c.Server.Mode, "debug", "release", "test"), "server.mode 只能是 debug、release 或 test，当前为 %q", c.Server.Mode)
+	for i, proxy := range c.Server.TrustedProxies {
+		_, _, cidrErr := net.ParseCIDR(proxy)
+		check(cidrErr == nil || net.ParseIP(proxy) != nil, "server.trusted_proxies[%d] 必须是 IP 或 CIDR，当前为 %q", i, proxy)
+	}
+
+	// 数据库
+	switch c.Database.Driver {
+	case DatabaseDriverMySQL, DatabaseDriverPostgres:
+		check(c.Database.Host != "", "database.host 不能为空")
+		check(c.Database.Port > 0 && c.Database.Port <= 65535, "database.port 必须在 1-65535 之间，当前为 %d", c.Database.Port)
+		check(c.Database.User != "", "database.user 不能为空")
+		check(c.Database.DBName != "", "database.dbname 不能为空")
+	case DatabaseDriverSQLite:
+		check(c.Database.Path != "", "database.driver 为 sqlite 时必须配置 database.path（内存数据库使用 %q）", SQLiteMemory)
+	default:
+		check(false, "database.driver 只能是 mysql、postgres 或 sqlite，当前为 %q", c.Database.Driver)
+	}
+	check(c.Database.MaxOpenConns >= 0, "database.max_open_conns 不能为负数")
+	check(c.Database.MaxIdleConns >= 0, "database.max_idle_conns 不能为负数")
+	check(c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+		"database.max_idle_conns 不能大于 database.max_open_conns")
+	check(c.Database.ConnMaxLifetime >= 0, "database.conn_max_lifetime 不能为负数")
+	check(c.Database.ConnMaxIdleTime >= 0, "database.conn_max_idle_time 不能为负数")
+	check(!c.Database.AutoMigrate || c.Server.Mode != "release", "生产环境不能开启 database.auto_migrate，请使用 migrate up 执行迁移")
+
+	// JWT
+	switch c.JWT.Algorithm {
+	case "", JWTAlgorithmHS256:
+		check(c.JWT.Secret != "", "jwt.secret 不能为空（可通过 GO_APP_JWT_SECRET 或 GO_APP_JWT_SECRET_FILE 提供）")
+		check(len(c.JWT.Secret) >= 32 || c.Server.Mode != "release", "生产环境 jwt.secret 长度不能少于 32 个字符")
+		check(c.JWT.Secret != insecureJWTSecret || c.Server.Mode != "release", "生产环境不能使用默认的 jwt.secret")
+	case JWTAlgorithmRS256, JWTAlgorithmEdDSA:
+		check(c.JWT.SigningKey.File != "", "jwt.algorithm 为 %s 时必须配置 jwt.signing_key.file", c.JWT.Algorithm)
+	default:
+		check(false, "jwt.algorithm 只能是 HS256、RS256 或 EdDSA，当前为 %q", c.JWT.Algorithm)
+	}
+	for i, key := range c.JWT.VerificationKeys {
+		check(key.File != "", "jwt.verification_keys[%d].file 不能为空", i)
+	}
+	check(c.JWT.Expire > 0, "jwt.expire 必须大于 0（小时）")
+	check(c.JWT.AccessExpire > 0, "jwt.access_expire 必须大于 0（分钟）")
+	check(c.JWT.AccessExpire <= c.JWT.Expire*60, "jwt.access_expire 不能超过刷新令牌有效期 jwt.expire")
+
+	// 会话
+	check(oneOf(c.Session.Policy, SessionPolicySingle, SessionPolicyPerPlatform, SessionPolicyUnlimited),
+		"session.policy 只能是 single、per_platform 或 unlimited，当前为 %q", c.Session.Policy)
+
+	// 邮件
+	switch c.Mail.Driver {
+	case MailDriverSMTP:
+		check(c.Mail.SMTP.Host != "", "mail.driver 为 smtp 时必须配置 mail.smtp.host")
+		check(c.Mail.SMTP.Port > 0 && c.Mail.SMTP.Port <= 65535, "mail.smtp.port 必须在 1-65535 之间，当前为 %d", c.Mail.SMTP.Port)
+		check(oneOf(c.Mail.SMTP.Encryption, SMTPEncryptionNone, SMTPEncryptionSTARTTLS, SMTPEncryptionTLS),
+			"mail.smtp.encryption 只能是 none、starttls 或 tls，当前为 %q", c.Mail.SMTP.Encryption)
+	case MailDriverFile:
+		check(c.Mail.Dir != "", "mail.driver 为 file 时必须配置 mail.dir")
+	case MailDriverMemory:
+		check(c.Server.Mode != "release", "生产环境不能使用 memory 邮件驱动")
+	default:
+		check(false, "mail.driver 只能是 smtp、file 或 memory，当前为 %q", c.Mail.Driver)
+	}
+	check(c.Mail.From != "", "mail.from 不能为空")
+	check(c.PasswordReset.Expire > 0, "password_reset.expire 必须大于 0（分钟）")
+	check(c.PasswordReset.Cooldown >= 0, "password_reset.cooldown 不能小于 0（分钟）")
+	check(c.EmailVerification.Expire > 0, "email_verification.expire 必须大于 0（分钟）")
+	check(c.EmailVerification.Cooldown >= 0, "email_verification.cooldown 不能小于 0（分钟）")
+	check(c.TwoFactor.Issuer != "", "two_factor.issuer 不能为空")
+	check(!strings.Contains(c.TwoFactor.Issuer, ":"), "two_factor.issuer 不能包含冒号")
+	check(c.TwoFactor.ChallengeExpire > 0, "two_factor.challenge_expire 必须大于 0（分钟）")
+	check(c.TwoFactor.MaxAttempts > 0, "two_factor.max_attempts 必须大于 0")
+	check(c.TwoFactor.Lockout > 0, "two_factor.lockout 必须大于 0（分钟）")
+	check(c.TwoFactor.RecoveryCodes > 0 && c.TwoFactor.RecoveryCodes <= 100, "two_factor.recovery_codes 必须在 1-100 之间")
+	check(c.WebAuthn.RPID != "" && !strings.Contains(c.WebAuthn.RPID, "/") && !strings.Contains(c.WebAuthn.RPID, ":"),
+		"webauthn.rp_id 必须是不含协议和端口的域名，当前为 %q", c.WebAuthn.RPID)
+	check(c.WebAuthn.RPDisplayName != "", "webauthn.rp_display_name 不能为空")
+	check(len(c.WebAuthn.RPOrigins) > 0, "webauthn.rp_origins 至少需要配置一个来源")
+	for i, origin := range c.WebAuthn.RPOrigins {
+		u, err := url.Parse(origin)
+		check(err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != "" && (u.Path == "" || u.Path == "/"),
+			"webauthn.rp_origins[%d] 必须是 scheme://host[:port] 格式，当前为 %q", i, origin)
+	}
+	check(c.WebAuthn.Timeout > 0, "webauthn.timeout 必须大于 0（秒）")
+	check(c.APIKey.MaxPerUser > 0, "api_key.max_per_user 必须大于 0")
+	check(c.OIDC.FlowExpire > 0, "oidc.flow_expire 必须大于 0（分钟）")
+	if len(c.OIDC.Providers) > 0 {
+		u, err := url.Parse(c.OIDC.RedirectURL)
+		check(err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != "",
+			"配置了 oidc.providers 时 oidc.redirect_url 必须是完整的 http(s) 地址，当前为 %q", c.OIDC.RedirectURL)
+	}
+	providers := make(map[string]bool)
+	for i, p := range c.OIDC.Providers {
+		name := fmt.Sprintf("oidc.providers[%d]", i)
+		check(p.Name != "" && strings.Trim(p.Name, "abcdefghijklmnopqrstuvwxyz0123456789-_") == "",
+			"%s.name 只能包含小写字母、数字、- 和 _，当前为 %q", name, p.Name)
+		check(!providers[p.Name], "%s.name %q 重复", name, p.Name)
+		providers[p.Name] = true
+		u, err := url.Parse(p.Issuer)
+		check(err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != "", "%s.issuer 必须是完整的 http(s) 地址，当前为 %q", name, p.Issuer)
+		check(p.ClientID != "", "%s.client_id 不能为空", name)
+		check(p.ClientSecret == "" || p.ClientSecretFile == "", "%s.client_secret 和 client_secret_file 只能配置一个", name)
+	}
+	check(c.OAuthServer.CodeExpire > 0, "oauth_server.code_expire 必须大于 0（分钟）")
+	check(c.OAuthServer.AccessExpire > 0, "oauth_server.access_expire 必须大于 0（分钟）")
+	check(c.OAuthServer.RefreshExpire > 0, "oauth_server.refresh_expire 必须大于 0（小时）")
+	if c.OAuthServer.Enabled {
+		check(c.JWT.Algorithm == JWTAlgorithmRS256 || c.JWT.Algorithm == JWTAlgorithmEdDSA,
+			"开启 oauth_server 时 jwt.algorithm 必须是 RS256 或 EdDSA，其他应用需要通过公钥验证 ID Token")
+		u, err := url.Parse(c.OAuthServer.Issuer)
+		check(err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != "" && u.RawQuery == "" && u.Fragment == "",
+			"oauth_server.issuer 必须是不含查询参数的 http(s) 地址，当前为 %q", c.OAuthServer.Issuer)
+		u, err = url.Parse(c.OAuthServer.ConsentURL)
+		check(err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != "",
+			"oauth_server.consent_url 必须是完整的 http(s) 地址，当前为 %q", c.OAuthServer.ConsentURL)
+	}
+	switch c.LoginThrottle.Store {
+	case LoginThrottleStoreMemory:
+	case LoginThrottleStoreRedis:
+		check(c.Redis.Addr != "", "login_throttle.store 为 redis 时必须配置 redis.addr")
+	default:
+		check(false, "login_throttle.store 只能是 memory 或 redis，当前为 %q", c.LoginThrottle.Store)
+	}
+	check(c.LoginThrottle.MaxFailures > 0, "login_throttle.max_failures 必须大于 0")
+	check(c.LoginThrottle.IPMaxFailures > 0, "login_throttle.ip_max_failures 必须大于 0")
+	check(c.LoginThrottle.Lockout > 0, "login_throttle.lockout 必须大于 0（分钟）")
+	check(c.LoginThrottle.Window > 0, "login_throttle.window 必须大于 0（分钟）")
+	check(c.LoginThrottle.BaseDelay >= 0, "login_throttle.base_delay 不能为负数")
+	check(c.LoginThrottle.MaxDelay >= c.LoginThrottle.BaseDelay, "login_throttle.max_delay 不能小于 login_throttle.base_delay")
+	switch c.RateLimit.Store {
+	case RateLimitStoreMemory:
+	case RateLimitStoreRedis:
+		check(c.Redis.Addr != "", "rate_limit.store 为 redis 时必须配置 redis.addr")
+	default:
+		check(false, "rate_limit.store 只能是 memory 或 redis，当前为 %q", c.RateLimit.Store)
+	}
+	checkRateLimit := func(name string, r RateLimitRule) {
+		check(oneOf(r.Algorithm, RateLimitTokenBucket, RateLimitSlidingWindow),
+			"%s.algorithm 只能是 token_bucket 或 sliding_window，当前为 %q", name, r.Algorithm)
+		check(r.Limit > 0, "%s.limit 必须大于 0", name)
+		check(r.Window > 0, "%s.window 必须大于 0（秒）", name)
+		check(oneOf(r.Key, RateLimitKeyIP, RateLimitKeyUser, RateLimitKeyAPIKey), "%s.key 只能是 ip、user 或 api_key，当前为 %q", name, r.Key)
+	}
+	if c.RateLimit.Default.Limit != 0 {
+		checkRateLimit("rate_limit.default", c.RateLimit.Default)
+	}
+	for i, rule := range c.RateLimit.Routes {
+		name := fmt.Sprintf("rate_limit.routes[%d]", i)
+		_, _, err := ParseRouteRule(rule.Route)
+		check(err == nil, "%s.route %v", name, err)
+		checkRateLimit(name, rule)
+	}
+	for i, rule := range c.EmailVerification.Restricted {
+		_, _, err := ParseRouteRule(rule)
+		check(err == nil, "email_verification.restricted[%d] %v", i, err)
+	}
+
+	switch c.WebSocket.Backplane {
+	case WebSocketBackplaneMemory:
+	case WebSocketBackplaneRedis:
+		check(c.Redis.Addr != "", "websocket.backplane 为 redis 时必须配置 redis.addr")
+	default:
+		check(false, "websocket.backplane 只能是 memory 或 redis，当前为 %q", c.WebSocket.Backplane)
+	}
+	check(c.WebSocket.MaxConnectionsPerUser > 0, "websocket.max_connections_per_user 必须大于 0")
+	// 补发的消息一次放入连接的发送缓冲区（256 条）
+	check(c.WebSocket.OutboxSize > 0 && c.WebSocket.OutboxSize <= 200, "websocket.outbox_size 必须在 1 到 200 之间")
+	check(c.WebSocket.OutboxRetention > 0, "websocket.outbox_retention 必须大于 0（分钟）")
+	check(c.WebSocket.PresenceGracePeriod > 0, "websocket.presence_grace_period 必须大于 0（秒）")
+	// 在线记录每 presence_ttl/3 续期一次
+	check(c.WebSocket.PresenceTTL >= 3, "websocket.presence_ttl 不能小于 3（秒）")
+
+	// 日志
+	check(oneOf(c.Log.Level, "debug", "info", "warn", "error"), "log.level 只能是 debug、info、warn 或 error，当前为 %q", c.Log.Level)
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// ParseRouteRule 解析 "METHOD /path" 或 "/path" 格式的路由规则，method 为空表示不限方法
+func ParseRouteRule(rule string) (method, path string, err error) {
+	fields := strings.Fields(rule)
+	switch len(fields) {
+	case 1:
+		path = fields[0]
+	case 2:
+		method, path = strings.ToUpper(fields[0]), fields[1]
+	default:
+		return "", "", fmt.Errorf("格式应为 \"METHOD /path\" 或 \"/path\"，当前为 %q", rule)
+	}
+	if !strings.HasPrefix(path, "/") {
+		return "", "", fmt.Errorf("路径必须以 / 开头，当前为 %q", rule)
+	}
+	if i := strings.Index(path, "*"); i >= 0 && i != len(path)-1 {
+		return "", "", fmt.Errorf("* 只能出现在路径末尾，当前为 %q", rule)
+	}
+	return method, path, nil
+}
+
+func oneOf(value string, options ...string) bool {
+	for _, option := range options {
+		if value == option {
+			return true
+		}
+	}
+	return false
+}
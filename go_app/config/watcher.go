@@ -0,0 +1,88 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"go_app/pkg/logger"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// 编辑器保存文件时通常会连续触发多个事件，合并短时间内的事件只加载一次
+const reloadDebounce = 300 * time.Millisecond
+
+// Watch 监听配置文件变更和 SIGHUP 信号并热加载配置，ctx 取消后停止监听
+func (s *Store) Watch(ctx context.Context) error {
+	path := s.Path()
+
+	// 监听所在目录而不是文件本身，兼容编辑器"写临时文件再重命名"及 K8s ConfigMap 的符号链接替换
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if _, statErr := os.Stat(path); statErr == nil {
+		if err := watcher.Add(filepath.Dir(path)); err != nil {
+			watcher.Close()
+			return err
+		}
+	} else {
+		logger.Warnf("配置文件 %s 不存在，仅支持通过 SIGHUP 重新加载", path)
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer watcher.Close()
+		defer signal.Stop(hup)
+
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				logger.Infof("收到 SIGHUP，重新加载配置")
+				s.reloadAndLog()
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !isConfigEvent(event, path) {
+					continue
+				}
+				debounce = time.After(reloadDebounce)
+			case <-debounce:
+				debounce = nil
+				s.reloadAndLog()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Errorf("监听配置文件失败: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *Store) reloadAndLog() {
+	if err := s.Reload(); err != nil {
+		logger.Errorf("重新加载配置失败，继续使用原配置: %v", err)
+	}
+}
+
+// isConfigEvent 判断目录事件是否与配置文件相关
+func isConfigEvent(event fsnotify.Event, path string) bool {
+	if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+		return false
+	}
+	name := filepath.Base(event.Name)
+	// ConfigMap 通过替换 ..data 符号链接更新文件
+	return filepath.Clean(event.Name) == filepath.Clean(path) || name == "..data"
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyController struct {
+	apiKeyService *services.APIKeyService
+}
+
+func NewAPIKeyController(apiKeyService *services.APIKeyService) *APIKeyController {
+	return &APIKeyController{apiKeyService: apiKeyService}
+}
+
+// List godoc
+// @Summary API Key 列表
+// @Description 列出当前用户创建的全部 API Key，不包含明文
+// @Tags API Key
+// @Produce json
+// @Success 200 {object} models.Response{data=[]models.APIKeyInfo} "获取成功"
+// @Failure 2008 {object} models.Response "该操作需要登录，不支持使用 API Key"
+// @Security ApiKeyAuth
+// @Router /api/users/api-keys [get]
+func (ac *APIKeyController) List(ctx *gin.Context) {
+	keys, err := ac.apiKeyService.List(ctx.GetUint("userId"))
+	if err != nil {
+		respondAPIKeyError(ctx, err)
+		return
+	}
+	list := make([]*models.APIKeyInfo, 0, len(keys))
+	for _, k := range keys {
+		list = append(list, k.ToAPIKeyInfo())
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(list, "获取成功"))
+}
+
+// Create godoc
+// @Summary 创建 API Key
+// @Description 创建供脚本和第三方集成使用的 API Key，明文只在本次响应中返回。调用接口时通过 X-API-Key 请求头携带，
+// @Description 代替 Authorization 请求头。授权范围 read 允许调用 GET 接口，write 允许调用全部接口；
+// @Description 访问需要权限的接口还需授予对应的权限标识（如 users:list），实际权限为用户权限与授权范围的交集。
+// @Description 密码、邮箱、会话、两步验证、通行密钥和 API Key 管理接口不能使用 API Key 调用
+// @Tags API Key
+// @Accept json
+// @Produce json
+// @Param request body models.APIKeyCreateRequest true "名称、授权范围和过期时间"
+// @Success 200 {object} models.Response{data=models.APIKeyCreateResponse} "创建成功"
+// @Failure 400 {object} models.Response "请求参数错误或过期时间早于当前时间"
+// @Failure 1024 {object} models.Response "无效的 API Key 授权范围"
+// @Failure 1025 {object} models.Response "API Key 数量已达上限"
+// @Failure 2008 {object} models.Response "该操作需要登录，不支持使用 API Key"
+// @Security ApiKeyAuth
+// @Router /api/users/api-keys/create [post]
+func (ac *APIKeyController) Create(ctx *gin.Context) {
+	var req models.APIKeyCreateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	key, plain, err := ac.apiKeyService.Create(ctx.GetUint("userId"), req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		respondAPIKeyError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(&models.APIKeyCreateResponse{APIKeyInfo: key.ToAPIKeyInfo(), Key: plain}, "创建成功"))
+}
+
+// Revoke godoc
+// @Summary 撤销 API Key
+// @Description 撤销后立即失效，使用该 API Key 的请求返回 2006
+// @Tags API Key
+// @Accept json
+// @Produce json
+// @Param request body models.APIKeyRevokeRequest true "API Key ID"
+// @Success 200 {object} models.Response "撤销成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 1023 {object} models.Response "API Key 不存在"
+// @Failure 2008 {object} models.Response "该操作需要登录，不支持使用 API Key"
+// @Security ApiKeyAuth
+// @Router /api/users/api-keys/revoke [post]
+func (ac *APIKeyController) Revoke(ctx *gin.Context) {
+	var req models.APIKeyRevokeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	if err := ac.apiKeyService.Revoke(ctx.GetUint("userId"), req.ID); err != nil {
+		respondAPIKeyError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(nil, "撤销成功"))
+}
+
+// respondAPIKeyError 将 API Key 服务返回的错误写入响应，非业务错误统一返回服务器内部错误
+func respondAPIKeyError(ctx *gin.Context, err error) {
+	if e, ok := err.(*errcode.ErrorCode); ok {
+		ctx.JSON(http.StatusOK, models.NewError(e))
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+}
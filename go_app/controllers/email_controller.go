@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EmailController struct {
+	emailVerificationService *services.EmailVerificationService
+}
+
+func NewEmailController(emailVerificationService *services.EmailVerificationService) *EmailController {
+	return &EmailController{emailVerificationService: emailVerificationService}
+}
+
+// VerifyEmail godoc
+// @Summary 验证邮箱
+// @Description 使用邮件中的一次性令牌验证邮箱；修改邮箱时验证通过后新邮箱替换当前邮箱
+// @Tags 用户管理
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body models.EmailVerifyRequest true "验证令牌"
+// @Success 200 {object} models.Response{data=models.UserInfo} "邮箱验证成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 1002 {object} models.Response "邮箱已被其他用户使用"
+// @Failure 1009 {object} models.Response "验证链接无效或已过期"
+// @Router /api/email/verify [post]
+func (ec *EmailController) VerifyEmail(ctx *gin.Context) {
+	var req models.EmailVerifyRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	user, err := ec.emailVerificationService.Verify(req.Token)
+	if err != nil {
+		respondEmailError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(user.ToUserInfo(), "邮箱验证成功"))
+}
+
+// ResendVerification godoc
+// @Summary 重新发送验证邮件
+// @Description 向待验证的新邮箱或未验证的当前邮箱重新发送验证链接，之前发送的链接随即失效
+// @Tags 用户管理
+// @Produce json
+// @Success 200 {object} models.Response "验证邮件已发送"
+// @Failure 429 {object} models.Response "验证邮件发送过于频繁"
+// @Failure 1010 {object} models.Response "邮箱已验证"
+// @Security ApiKeyAuth
+// @Router /api/users/email/resend [post]
+func (ec *EmailController) ResendVerification(ctx *gin.Context) {
+	if err := ec.emailVerificationService.SendVerification(ctx.GetUint("userId")); err != nil {
+		respondEmailError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(nil, "验证邮件已发送，请查收邮件"))
+}
+
+// respondEmailError 将邮箱验证服务返回的错误写入响应，发送过于频繁时返回 429，非业务错误统一返回服务器内部错误
+func respondEmailError(ctx *gin.Context, err error) {
+	e, ok := err.(*errcode.ErrorCode)
+	if !ok {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+		return
+	}
+	status := http.StatusOK
+	if e == errcode.EmailVerificationCooldown || e == errcode.TooManyRequests {
+		status = http.StatusTooManyRequests
+	}
+	ctx.JSON(status, models.NewError(e))
+}
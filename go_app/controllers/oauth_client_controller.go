@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OAuthClientController struct {
+	oauthService *services.OAuthService
+}
+
+func NewOAuthClientController(oauthService *services.OAuthService) *OAuthClientController {
+	return &OAuthClientController{oauthService: oauthService}
+}
+
+// List godoc
+// @Summary OAuth 客户端列表
+// @Description 列出接入本服务登录的全部 OAuth 客户端，不包含密钥，需要 oauth:manage 权限
+// @Tags OAuth 授权服务
+// @Produce json
+// @Success 200 {object} models.Response{data=[]models.OAuthClientInfo} "获取成功"
+// @Failure 403 {object} models.Response "没有操作权限"
+// @Security ApiKeyAuth
+// @Router /api/admin/oauth/clients [get]
+func (oc *OAuthClientController) List(ctx *gin.Context) {
+	clients, err := oc.oauthService.ListClients()
+	if err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+		return
+	}
+	list := make([]*models.OAuthClientInfo, 0, len(clients))
+	for _, client := range clients {
+		list = append(list, client.ToOAuthClientInfo())
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(list, "获取成功"))
+}
+
+// Create godoc
+// @Summary 登记 OAuth 客户端
+// @Description 登记使用本服务账号登录的应用，客户端密钥只在本次响应中返回，需要 oauth:manage 权限。
+// @Description 公开客户端（单页应用、移动端）不签发密钥，授权时必须使用 PKCE，且不能使用 client_credentials。
+// @Description 回调地址必须是不含片段（#）的完整地址，授权请求中的 redirect_uri 必须与其中之一完全一致
+// @Tags OAuth 授权服务
+// @Accept json
+// @Produce json
+// @Param request body models.OAuthClientCreateRequest true "名称、回调地址、授权范围和授权类型"
+// @Success 200 {object} models.Response{data=models.OAuthClientCreateResponse} "登记成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 403 {object} models.Response "没有操作权限"
+// @Failure 1034 {object} models.Response "OAuth 客户端的回调地址、授权范围或授权类型无效"
+// @Security ApiKeyAuth
+// @Router /api/admin/oauth/clients/create [post]
+func (oc *OAuthClientController) Create(ctx *gin.Context) {
+	var req models.OAuthClientCreateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	client, secret, err := oc.oauthService.CreateClient(ctx.GetUint("userId"), &req)
+	if err != nil {
+		respondOAuthClientError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(&models.OAuthClientCreateResponse{
+		OAuthClientInfo: client.ToOAuthClientInfo(),
+		ClientSecret:    secret,
+	}, "登记成功"))
+}
+
+// Delete godoc
+// @Summary 删除 OAuth 客户端
+// @Description 删除客户端，同时删除用户对它的授权记录并吊销已签发的令牌，需要 oauth:manage 权限
+// @Tags OAuth 授权服务
+// @Accept json
+// @Produce json
+// @Param request body models.OAuthClientIDRequest true "客户端ID"
+// @Success 200 {object} models.Response "删除成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 403 {object} models.Response "没有操作权限"
+// @Failure 1033 {object} models.Response "OAuth 客户端不存在"
+// @Security ApiKeyAuth
+// @Router /api/admin/oauth/clients/delete [post]
+func (oc *OAuthClientController) Delete(ctx *gin.Context) {
+	var req models.OAuthClientIDRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	if err := oc.oauthService.DeleteClient(req.ClientID); err != nil {
+		respondOAuthClientError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(nil, "删除成功"))
+}
+
+// respondOAuthClientError 将客户端管理和授权确认接口的错误写入响应，非业务错误统一返回服务器内部错误
+func respondOAuthClientError(ctx *gin.Context, err error) {
+	switch e := err.(type) {
+	case *errcode.ErrorCode:
+		ctx.JSON(http.StatusOK, models.NewError(e))
+	case *services.OAuthRequestError:
+		ctx.JSON(http.StatusOK, models.NewError(errcode.OAuthRequestInvalid).WithDetails(e.Description))
+	default:
+		ctx.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+	}
+}
@@ -0,0 +1,334 @@
+package controllers
+
+import (
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/pkg/logger"
+	"go_app/services"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OAuthController OAuth2 / OIDC 授权服务。/oauth/* 协议接口面向第三方客户端，
+// 按 RFC 6749 使用 HTTP 状态码和 {"error": ..., "error_description": ...} 返回错误；
+// /api/users/oauth/* 是前端授权确认页面调用的接口，使用统一响应格式
+type OAuthController struct {
+	oauthService *services.OAuthService
+}
+
+func NewOAuthController(oauthService *services.OAuthService) *OAuthController {
+	return &OAuthController{oauthService: oauthService}
+}
+
+// Discovery godoc
+// @Summary OIDC 发现文档
+// @Description 返回授权服务的端点地址和支持的能力（OpenID Connect Discovery 1.0），仅在 oauth_server.enabled 时注册
+// @Tags OAuth 授权服务
+// @Produce json
+// @Success 200 {object} models.OpenIDConfiguration "发现文档"
+// @Router /.well-known/openid-configuration [get]
+func (oc *OAuthController) Discovery(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	oauthJSON(c, http.StatusOK, oc.oauthService.Discovery())
+}
+
+// Authorize godoc
+// @Summary 授权端点
+// @Description 校验授权请求后携带原查询参数跳转到 oauth_server.consent_url 指定的前端授权确认页面。
+// @Description client_id 或 redirect_uri 无效时返回 400，其余参数错误时携带 error 和 state 跳转回 redirect_uri。
+// @Description 公开客户端必须使用 PKCE，code_challenge_method 仅支持 S256
+// @Tags OAuth 授权服务
+// @Param client_id query string true "客户端ID"
+// @Param redirect_uri query string true "回调地址，必须与登记的地址完全一致"
+// @Param response_type query string true "固定为 code"
+// @Param scope query string true "授权范围，空格分隔"
+// @Param state query string false "客户端状态，原样带回"
+// @Param nonce query string false "写入 ID Token 的随机串"
+// @Param code_challenge query string false "PKCE 校验值"
+// @Param code_challenge_method query string false "固定为 S256"
+// @Success 302 "跳转到授权确认页面"
+// @Failure 400 {object} services.OAuthError "客户端或回调地址无效"
+// @Router /oauth/authorize [get]
+func (oc *OAuthController) Authorize(c *gin.Context) {
+	var req models.OAuthAuthorizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondOAuthError(c, &services.OAuthError{Code: services.OAuthErrInvalidRequest})
+		return
+	}
+	client, err := oc.oauthService.AuthorizationClient(req.ClientID, req.RedirectURI)
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+	if _, err := oc.oauthService.AuthorizationScopes(client, &req); err != nil {
+		if e, ok := err.(*services.OAuthError); ok {
+			c.Redirect(http.StatusFound, services.AuthorizationErrorURL(req.RedirectURI, req.State, e))
+			return
+		}
+		respondOAuthError(c, err)
+		return
+	}
+	c.Redirect(http.StatusFound, oc.oauthService.ConsentURL(c.Request.URL.RawQuery))
+}
+
+// Token godoc
+// @Summary 令牌端点
+// @Description 使用授权码（authorization_code）、刷新令牌（refresh_token）或客户端凭证（client_credentials）换取令牌。
+// @Description 客户端凭证通过 HTTP Basic 或表单字段 client_id、client_secret 提交，公开客户端只提交 client_id。
+// @Description 授权包含 offline_access 时签发刷新令牌，每次刷新都会签发新的刷新令牌，旧令牌被再次使用时吊销整个授权；
+// @Description 授权包含 openid 时签发 ID Token
+// @Tags OAuth 授权服务
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "授权类型"
+// @Param code formData string false "授权码"
+// @Param redirect_uri formData string false "与授权请求一致的回调地址"
+// @Param code_verifier formData string false "PKCE 校验值原文"
+// @Param refresh_token formData string false "刷新令牌"
+// @Param scope formData string false "授权范围，刷新时只能缩小"
+// @Success 200 {object} models.OAuthTokenResponse "签发成功"
+// @Failure 400 {object} services.OAuthError "请求错误"
+// @Failure 401 {object} services.OAuthError "客户端认证失败"
+// @Router /oauth/token [post]
+func (oc *OAuthController) Token(c *gin.Context) {
+	var req models.OAuthTokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		respondOAuthError(c, &services.OAuthError{Code: services.OAuthErrInvalidRequest})
+		return
+	}
+	clientID, secret := clientCredentials(c)
+	resp, err := oc.oauthService.Token(clientID, secret, &req)
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+	oauthJSON(c, http.StatusOK, resp)
+}
+
+// UserInfo godoc
+// @Summary 用户信息端点
+// @Description 使用包含 openid 的访问令牌获取用户信息（OIDC Core 5.3），按授权的 profile、email 返回对应的声明
+// @Tags OAuth 授权服务
+// @Produce json
+// @Param Authorization header string true "Bearer 访问令牌"
+// @Success 200 {object} map[string]interface{} "用户信息"
+// @Failure 401 {object} services.OAuthError "访问令牌无效"
+// @Failure 403 {object} services.OAuthError "访问令牌未包含 openid"
+// @Router /oauth/userinfo [get]
+// @Router /oauth/userinfo [post]
+func (oc *OAuthController) UserInfo(c *gin.Context) {
+	claims, err := oc.oauthService.UserInfo(c.GetHeader("Authorization"))
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	oauthJSON(c, http.StatusOK, claims)
+}
+
+// Revoke godoc
+// @Summary 吊销令牌
+// @Description 吊销访问令牌或刷新令牌（RFC 7009），吊销刷新令牌时同时吊销同一授权的全部令牌。
+// @Description 令牌不存在或不属于该客户端时同样返回 200
+// @Tags OAuth 授权服务
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "要吊销的令牌"
+// @Param token_type_hint formData string false "access_token 或 refresh_token"
+// @Success 200 "吊销成功"
+// @Failure 400 {object} services.OAuthError "请求错误"
+// @Failure 401 {object} services.OAuthError "客户端认证失败"
+// @Router /oauth/revoke [post]
+func (oc *OAuthController) Revoke(c *gin.Context) {
+	var req models.OAuthTokenActionRequest
+	if err := c.ShouldBind(&req); err != nil {
+		respondOAuthError(c, &services.OAuthError{Code: services.OAuthErrInvalidRequest})
+		return
+	}
+	clientID, secret := clientCredentials(c)
+	if err := oc.oauthService.Revoke(clientID, secret, &req); err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// Introspect godoc
+// @Summary 令牌内省
+// @Description 资源服务校验令牌是否有效并获取授权信息（RFC 7662），只允许有密钥的客户端调用。令牌无效时只返回 active=false
+// @Tags OAuth 授权服务
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "要校验的令牌"
+// @Param token_type_hint formData string false "access_token 或 refresh_token"
+// @Success 200 {object} models.OAuthIntrospection "内省结果"
+// @Failure 400 {object} services.OAuthError "请求错误"
+// @Failure 401 {object} services.OAuthError "客户端认证失败"
+// @Router /oauth/introspect [post]
+func (oc *OAuthController) Introspect(c *gin.Context) {
+	var req models.OAuthTokenActionRequest
+	if err := c.ShouldBind(&req); err != nil {
+		respondOAuthError(c, &services.OAuthError{Code: services.OAuthErrInvalidRequest})
+		return
+	}
+	clientID, secret := clientCredentials(c)
+	result, err := oc.oauthService.Introspect(clientID, secret, &req)
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	oauthJSON(c, http.StatusOK, result)
+}
+
+// AuthorizeInfo godoc
+// @Summary 获取授权请求信息
+// @Description 授权确认页面使用 /oauth/authorize 转发的查询参数获取客户端名称和申请的授权范围。
+// @Description consented 为 true 表示用户已同意过全部授权范围，页面可以直接提交同意
+// @Tags OAuth 授权服务
+// @Produce json
+// @Param client_id query string true "客户端ID"
+// @Param redirect_uri query string true "回调地址"
+// @Param response_type query string true "固定为 code"
+// @Param scope query string true "授权范围，空格分隔"
+// @Param code_challenge query string false "PKCE 校验值"
+// @Param code_challenge_method query string false "固定为 S256"
+// @Success 200 {object} models.Response{data=models.OAuthAuthorizeInfo} "获取成功"
+// @Failure 1035 {object} models.Response "无效的授权请求"
+// @Security ApiKeyAuth
+// @Router /api/users/oauth/authorize [get]
+func (oc *OAuthController) AuthorizeInfo(ctx *gin.Context) {
+	var req models.OAuthAuthorizeRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	info, err := oc.oauthService.PrepareAuthorization(ctx.GetUint("userId"), &req)
+	if err != nil {
+		respondOAuthClientError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(info, "获取成功"))
+}
+
+// Consent godoc
+// @Summary 同意或拒绝授权
+// @Description 提交授权请求参数和用户的选择，返回需要跳转的客户端回调地址：同意时携带授权码，拒绝时携带 error=access_denied
+// @Tags OAuth 授权服务
+// @Accept json
+// @Produce json
+// @Param request body models.OAuthConsentRequest true "授权请求参数和是否同意"
+// @Success 200 {object} models.Response{data=models.OAuthRedirect} "提交成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 1035 {object} models.Response "无效的授权请求"
+// @Security ApiKeyAuth
+// @Router /api/users/oauth/authorize [post]
+func (oc *OAuthController) Consent(ctx *gin.Context) {
+	var req models.OAuthConsentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	redirectURL, err := oc.oauthService.Authorize(ctx.GetUint("userId"), &req.OAuthAuthorizeRequest, req.Approve)
+	if err != nil {
+		respondOAuthClientError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(&models.OAuthRedirect{RedirectURL: redirectURL}, "提交成功"))
+}
+
+// Consents godoc
+// @Summary 已授权的应用
+// @Description 列出当前用户已授权的 OAuth 客户端及授权范围
+// @Tags OAuth 授权服务
+// @Produce json
+// @Success 200 {object} models.Response{data=[]models.OAuthConsentInfo} "获取成功"
+// @Security ApiKeyAuth
+// @Router /api/users/oauth/consents [get]
+func (oc *OAuthController) Consents(ctx *gin.Context) {
+	list, err := oc.oauthService.ListConsents(ctx.GetUint("userId"))
+	if err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(list, "获取成功"))
+}
+
+// RevokeConsent godoc
+// @Summary 撤销授权
+// @Description 撤销对 OAuth 客户端的授权，同时吊销已签发给该客户端的令牌，之后客户端需要重新申请授权
+// @Tags OAuth 授权服务
+// @Accept json
+// @Produce json
+// @Param request body models.OAuthClientIDRequest true "客户端ID"
+// @Success 200 {object} models.Response "撤销成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 1036 {object} models.Response "授权记录不存在"
+// @Security ApiKeyAuth
+// @Router /api/users/oauth/consents/revoke [post]
+func (oc *OAuthController) RevokeConsent(ctx *gin.Context) {
+	var req models.OAuthClientIDRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	if err := oc.oauthService.RevokeConsent(ctx.GetUint("userId"), req.ClientID); err != nil {
+		respondOAuthClientError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(nil, "撤销成功"))
+}
+
+// clientCredentials 读取客户端凭证，优先使用 HTTP Basic（RFC 6749 2.3.1，用户名和密码先经过表单编码），其次使用表单字段
+func clientCredentials(c *gin.Context) (string, string) {
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		if decoded, err := url.QueryUnescape(id); err == nil {
+			id = decoded
+		}
+		if decoded, err := url.QueryUnescape(secret); err == nil {
+			secret = decoded
+		}
+		return id, secret
+	}
+	return c.PostForm("client_id"), c.PostForm("client_secret")
+}
+
+// respondOAuthError 按 RFC 6749 5.2 和 RFC 6750 3.1 返回协议错误，非协议错误返回 500 server_error
+func respondOAuthError(c *gin.Context, err error) {
+	e, ok := err.(*services.OAuthError)
+	if !ok {
+		logger.Errorf("OAuth 授权服务内部错误: %v", err)
+		oauthJSON(c, http.StatusInternalServerError, &services.OAuthError{Code: "server_error"})
+		return
+	}
+	status := http.StatusBadRequest
+	switch e.Code {
+	case services.OAuthErrInvalidClient:
+		status = http.StatusUnauthorized
+		if c.GetHeader("Authorization") != "" {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+	case services.OAuthErrInvalidToken:
+		status = http.StatusUnauthorized
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+	case services.OAuthErrInsufficientScope:
+		status = http.StatusForbidden
+		c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+	}
+	c.Header("Cache-Control", "no-store")
+	oauthJSON(c, status, e)
+}
+
+// oauthJSON 写入 JSON 响应。全局中间件会按请求的 Content-Type 预先设置响应类型，
+// 令牌端点等接口以表单提交，需要显式覆盖，否则客户端会按表单解析响应
+func oauthJSON(c *gin.Context, status int, obj interface{}) {
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.JSON(status, obj)
+}
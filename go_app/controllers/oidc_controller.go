@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OIDCController struct {
+	oidcService      *services.OIDCService
+	userService      *services.UserService
+	twoFactorService *services.TwoFactorService
+}
+
+func NewOIDCController(oidcService *services.OIDCService, userService *services.UserService, twoFactorService *services.TwoFactorService) *OIDCController {
+	return &OIDCController{oidcService: oidcService, userService: userService, twoFactorService: twoFactorService}
+}
+
+// Providers godoc
+// @Summary 第三方登录提供方列表
+// @Description 列出配置的 OIDC 提供方，用于在登录页面显示登录按钮
+// @Tags 第三方登录
+// @Produce json
+// @Success 200 {object} models.Response{data=[]models.OIDCProviderInfo} "获取成功"
+// @Router /api/login/oidc/providers [get]
+func (oc *OIDCController) Providers(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, models.NewSuccess(oc.oidcService.Providers(), "获取成功"))
+}
+
+// Begin godoc
+// @Summary 开始第三方登录
+// @Description 返回提供方登录页面地址，浏览器跳转后由提供方回调 oidc.redirect_url 配置的前端页面，
+// @Description 前端核对 state 后将 state 和 code 提交到 /api/login/oidc。使用授权码模式和 PKCE，流程只能使用一次
+// @Tags 第三方登录
+// @Accept json
+// @Produce json
+// @Param request body models.OIDCBeginRequest true "提供方标识"
+// @Success 200 {object} models.Response{data=models.OIDCAuthorization} "获取成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 1026 {object} models.Response "不支持该第三方登录方式"
+// @Failure 1028 {object} models.Response "第三方登录验证失败"
+// @Router /api/login/oidc/begin [post]
+func (oc *OIDCController) Begin(ctx *gin.Context) {
+	var req models.OIDCBeginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	authorization, err := oc.oidcService.Begin(req.Provider)
+	if err != nil {
+		respondOIDCError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(authorization, "获取成功"))
+}
+
+// Login godoc
+// @Summary 第三方登录
+// @Description 提交提供方回调带回的 state 和 code 完成登录，签发的令牌与密码登录相同。
+// @Description 第三方账号首次登录时按提供方已验证的邮箱关联已有用户，邮箱未注册时自动创建用户（oidc.allow_signup）。
+// @Description 开启两步验证的账号返回挑战令牌，需要继续提交到 /api/login/2fa
+// @Tags 第三方登录
+// @Accept json
+// @Produce json
+// @Param request body models.OIDCLoginRequest true "state、授权码和设备信息"
+// @Success 200 {object} models.Response{data=models.LoginResponse} "登录成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 1006 {object} models.Response "账号已被禁用"
+// @Failure 1027 {object} models.Response "第三方登录已失效，请重试"
+// @Failure 1028 {object} models.Response "第三方登录验证失败"
+// @Failure 1029 {object} models.Response "第三方账号的邮箱未验证，无法登录"
+// @Failure 1030 {object} models.Response "该邮箱已注册但尚未验证，请先使用密码登录并完成邮箱验证"
+// @Failure 1031 {object} models.Response "该邮箱未注册"
+// @Router /api/login/oidc [post]
+func (oc *OIDCController) Login(ctx *gin.Context) {
+	var req models.OIDCLoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	meta := models.SessionMeta{
+		Platform:  req.Platform,
+		DeviceID:  req.DeviceID,
+		UserAgent: ctx.Request.UserAgent(),
+		IP:        ctx.ClientIP(),
+	}
+
+	user, err := oc.oidcService.FinishLogin(req.State, req.Code)
+	if err != nil {
+		respondOIDCError(ctx, err)
+		return
+	}
+
+	// 与密码登录一致，开启两步验证的账号先签发挑战令牌
+	enabled, err := oc.twoFactorService.Enabled(user.ID)
+	if err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+		return
+	}
+	if enabled {
+		challenge, err := oc.twoFactorService.Challenge(user, meta)
+		if err != nil {
+			ctx.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+			return
+		}
+		ctx.JSON(http.StatusOK, models.NewSuccess(challenge, "请输入两步验证码"))
+		return
+	}
+
+	user, tokens, err := oc.userService.CompleteLogin(user, meta)
+	if err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+		return
+	}
+	respondLogin(ctx, user, tokens)
+}
+
+// Identities godoc
+// @Summary 关联的第三方账号
+// @Description 列出当前用户关联的第三方账号
+// @Tags 第三方登录
+// @Produce json
+// @Success 200 {object} models.Response{data=[]models.UserIdentityInfo} "获取成功"
+// @Failure 2008 {object} models.Response "该操作需要登录，不支持使用 API Key"
+// @Security ApiKeyAuth
+// @Router /api/users/identities [get]
+func (oc *OIDCController) Identities(ctx *gin.Context) {
+	identities, err := oc.oidcService.ListIdentities(ctx.GetUint("userId"))
+	if err != nil {
+		respondOIDCError(ctx, err)
+		return
+	}
+	list := make([]*models.UserIdentityInfo, 0, len(identities))
+	for _, i := range identities {
+		list = append(list, i.ToUserIdentityInfo())
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(list, "获取成功"))
+}
+
+// Unlink godoc
+// @Summary 解除第三方账号关联
+// @Description 解除后不能再使用该第三方账号直接登录，再次登录时会按邮箱重新关联
+// @Tags 第三方登录
+// @Accept json
+// @Produce json
+// @Param request body models.UserIdentityUnlinkRequest true "关联ID"
+// @Success 200 {object} models.Response "已解除关联"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 1032 {object} models.Response "关联的第三方账号不存在"
+// @Failure 2008 {object} models.Response "该操作需要登录，不支持使用 API Key"
+// @Security ApiKeyAuth
+// @Router /api/users/identities/unlink [post]
+func (oc *OIDCController) Unlink(ctx *gin.Context) {
+	var req models.UserIdentityUnlinkRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	if err := oc.oidcService.Unlink(ctx.GetUint("userId"), req.ID); err != nil {
+		respondOIDCError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(nil, "已解除关联"))
+}
+
+// respondOIDCError 将第三方登录服务返回的错误写入响应，非业务错误统一返回服务器内部错误
+func respondOIDCError(ctx *gin.Context, err error) {
+	if e, ok := err.(*errcode.ErrorCode); ok {
+		ctx.JSON(http.StatusOK, models.NewError(e))
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+}
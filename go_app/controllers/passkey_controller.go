@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PasskeyController struct {
+	passkeyService *services.PasskeyService
+	userService    *services.UserService
+}
+
+func NewPasskeyController(passkeyService *services.PasskeyService, userService *services.UserService) *PasskeyController {
+	return &PasskeyController{passkeyService: passkeyService, userService: userService}
+}
+
+// BeginLogin godoc
+// @Summary 开始通行密钥登录
+// @Description 返回流程令牌和 WebAuthn 参数，将 options.publicKey 传给 navigator.credentials.get()，
+// @Description 再将返回的凭证和流程令牌提交到 /api/login/passkey。不需要提供邮箱，由浏览器列出可用的通行密钥
+// @Tags 通行密钥
+// @Produce json
+// @Success 200 {object} models.Response{data=models.PasskeyOptions} "获取成功"
+// @Router /api/login/passkey/begin [post]
+func (pc *PasskeyController) BeginLogin(ctx *gin.Context) {
+	options, err := pc.passkeyService.BeginLogin()
+	if err != nil {
+		respondPasskeyError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(options, "获取成功"))
+}
+
+// Login godoc
+// @Summary 通行密钥登录
+// @Description 提交 navigator.credentials.get() 返回的凭证完成登录，签发的令牌与密码登录相同。
+// @Description 通行密钥已在设备上验证用户本人，开启两步验证的账号也不需要再输入验证码
+// @Tags 通行密钥
+// @Accept json
+// @Produce json
+// @Param request body models.PasskeyLoginRequest true "流程令牌和凭证"
+// @Success 200 {object} models.Response{data=models.LoginResponse} "登录成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 1006 {object} models.Response "账号已被禁用"
+// @Failure 1016 {object} models.Response "通行密钥验证已失效，请重试"
+// @Failure 1017 {object} models.Response "通行密钥验证失败"
+// @Router /api/login/passkey [post]
+func (pc *PasskeyController) Login(ctx *gin.Context) {
+	var req models.PasskeyLoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	user, err := pc.passkeyService.FinishLogin(req.CeremonyToken, req.Credential)
+	if err != nil {
+		respondPasskeyError(ctx, err)
+		return
+	}
+	user, tokens, err := pc.userService.CompleteLogin(user, models.SessionMeta{
+		Platform:  req.Platform,
+		DeviceID:  req.DeviceID,
+		UserAgent: ctx.Request.UserAgent(),
+		IP:        ctx.ClientIP(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+		return
+	}
+	respondLogin(ctx, user, tokens)
+}
+
+// List godoc
+// @Summary 通行密钥列表
+// @Description 列出当前用户注册的全部通行密钥
+// @Tags 通行密钥
+// @Produce json
+// @Success 200 {object} models.Response{data=[]models.PasskeyInfo} "获取成功"
+// @Security ApiKeyAuth
+// @Router /api/users/passkeys [get]
+func (pc *PasskeyController) List(ctx *gin.Context) {
+	passkeys, err := pc.passkeyService.List(ctx.GetUint("userId"))
+	if err != nil {
+		respondPasskeyError(ctx, err)
+		return
+	}
+	list := make([]*models.PasskeyInfo, 0, len(passkeys))
+	for _, p := range passkeys {
+		list = append(list, p.ToPasskeyInfo())
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(list, "获取成功"))
+}
+
+// BeginRegistration godoc
+// @Summary 开始注册通行密钥
+// @Description 返回流程令牌和 WebAuthn 参数，将 options.publicKey 传给 navigator.credentials.create()，
+// @Description 再将返回的凭证和流程令牌提交到 /api/users/passkeys/register。一个账号可以注册多个通行密钥
+// @Tags 通行密钥
+// @Produce json
+// @Success 200 {object} models.Response{data=models.PasskeyOptions} "获取成功"
+// @Security ApiKeyAuth
+// @Router /api/users/passkeys/register/begin [post]
+func (pc *PasskeyController) BeginRegistration(ctx *gin.Context) {
+	options, err := pc.passkeyService.BeginRegistration(ctx.GetUint("userId"))
+	if err != nil {
+		respondPasskeyError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(options, "获取成功"))
+}
+
+// Register godoc
+// @Summary 注册通行密钥
+// @Description 提交 navigator.credentials.create() 返回的凭证，校验通过后保存通行密钥
+// @Tags 通行密钥
+// @Accept json
+// @Produce json
+// @Param request body models.PasskeyRegisterRequest true "流程令牌、凭证和名称"
+// @Success 200 {object} models.Response{data=models.PasskeyInfo} "注册成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 1016 {object} models.Response "通行密钥验证已失效，请重试"
+// @Failure 1017 {object} models.Response "通行密钥验证失败"
+// @Failure 1018 {object} models.Response "该通行密钥已注册"
+// @Security ApiKeyAuth
+// @Router /api/users/passkeys/register [post]
+func (pc *PasskeyController) Register(ctx *gin.Context) {
+	var req models.PasskeyRegisterRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	passkey, err := pc.passkeyService.FinishRegistration(ctx.GetUint("userId"), req.CeremonyToken, req.Name, req.Credential)
+	if err != nil {
+		respondPasskeyError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(passkey.ToPasskeyInfo(), "注册成功"))
+}
+
+// Rename godoc
+// @Summary 修改通行密钥名称
+// @Tags 通行密钥
+// @Accept json
+// @Produce json
+// @Param request body models.PasskeyRenameRequest true "通行密钥ID和新名称"
+// @Success 200 {object} models.Response "修改成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 1019 {object} models.Response "通行密钥不存在"
+// @Security ApiKeyAuth
+// @Router /api/users/passkeys/rename [post]
+func (pc *PasskeyController) Rename(ctx *gin.Context) {
+	var req models.PasskeyRenameRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	if err := pc.passkeyService.Rename(ctx.GetUint("userId"), req.ID, req.Name); err != nil {
+		respondPasskeyError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(nil, "修改成功"))
+}
+
+// Delete godoc
+// @Summary 删除通行密钥
+// @Description 删除后不能再使用该通行密钥登录，设备上保存的通行密钥需要用户自行删除
+// @Tags 通行密钥
+// @Accept json
+// @Produce json
+// @Param request body models.PasskeyDeleteRequest true "通行密钥ID"
+// @Success 200 {object} models.Response "删除成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 1019 {object} models.Response "通行密钥不存在"
+// @Security ApiKeyAuth
+// @Router /api/users/passkeys/delete [post]
+func (pc *PasskeyController) Delete(ctx *gin.Context) {
+	var req models.PasskeyDeleteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	if err := pc.passkeyService.Delete(ctx.GetUint("userId"), req.ID); err != nil {
+		respondPasskeyError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(nil, "删除成功"))
+}
+
+// respondPasskeyError 将通行密钥服务返回的错误写入响应，非业务错误统一返回服务器内部错误
+func respondPasskeyError(ctx *gin.Context, err error) {
+	if e, ok := err.(*errcode.ErrorCode); ok {
+		ctx.JSON(http.StatusOK, models.NewError(e))
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PasswordController struct {
+	passwordResetService *services.PasswordResetService
+}
+
+func NewPasswordController(passwordResetService *services.PasswordResetService) *PasswordController {
+	return &PasswordController{passwordResetService: passwordResetService}
+}
+
+// ForgotPassword godoc
+// @Summary 找回密码
+// @Description 向注册邮箱发送重置密码链接。无论邮箱是否注册都返回相同的响应，避免泄露账号是否存在
+// @Tags 用户管理
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body models.PasswordForgotRequest true "注册邮箱"
+// @Success 200 {object} models.Response "如果该邮箱已注册，重置链接已发送"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Router /api/password/forgot [post]
+func (pc *PasswordController) ForgotPassword(ctx *gin.Context) {
+	var req models.PasswordForgotRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	// 交给后台任务生成令牌并发送邮件，响应时间不随邮箱是否存在而变化
+	pc.passwordResetService.Enqueue(req.Email)
+
+	ctx.JSON(http.StatusOK, models.NewSuccess(nil, "如果该邮箱已注册，重置链接已发送，请查收邮件"))
+}
+
+// ResetPassword godoc
+// @Summary 重置密码
+// @Description 使用邮件中的一次性令牌设置新密码，重置后所有已登录的设备需要重新登录
+// @Tags 用户管理
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body models.PasswordResetRequest true "重置令牌和新密码"
+// @Success 200 {object} models.Response "密码重置成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 1007 {object} models.Response "重置链接无效或已过期"
+// @Router /api/password/reset [post]
+func (pc *PasswordController) ResetPassword(ctx *gin.Context) {
+	var req models.PasswordResetRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	if err := pc.passwordResetService.Reset(req.Token, req.NewPassword); err != nil {
+		if err == errcode.PasswordResetTokenInvalid {
+			ctx.JSON(http.StatusOK, models.NewError(errcode.PasswordResetTokenInvalid))
+			return
+		}
+		ctx.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(nil, "密码重置成功，请使用新密码登录"))
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/services"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PresenceController struct {
+	presenceService *services.PresenceService
+}
+
+func NewPresenceController(presenceService *services.PresenceService) *PresenceController {
+	return &PresenceController{presenceService: presenceService}
+}
+
+// Get godoc
+// @Summary 查询用户在线状态
+// @Description 查询指定用户的在线状态（online / away / offline）和最后在线时间，一次最多 100 个用户，不存在的用户不返回。
+// @Description 状态变化可通过 WebSocket 订阅主题 presence:{id} 实时接收
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param ids query string true "用户ID，多个用逗号分隔"
+// @Success 200 {object} models.Response{data=[]models.UserPresence} "获取成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 1043 {object} models.Response "一次查询的用户过多"
+// @Security ApiKeyAuth
+// @Router /api/users/presence [get]
+func (pc *PresenceController) Get(ctx *gin.Context) {
+	var ids []uint
+	for _, s := range strings.Split(ctx.Query("ids"), ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(s), 10, 32)
+		if err != nil || id == 0 {
+			ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+			return
+		}
+		ids = append(ids, uint(id))
+	}
+
+	presences, err := pc.presenceService.Get(ids)
+	if err != nil {
+		if err == errcode.PresenceTooManyUsers {
+			ctx.JSON(http.StatusOK, models.NewError(errcode.PresenceTooManyUsers))
+			return
+		}
+		ctx.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(presences, "获取成功"))
+}
@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoleController struct {
+	roleService *services.RoleService
+}
+
+func NewRoleController(roleService *services.RoleService) *RoleController {
+	return &RoleController{roleService: roleService}
+}
+
+// ListRoles godoc
+// @Summary 获取角色列表
+// @Description 获取全部角色及其权限，需要 roles:manage 权限
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.Response{data=[]models.Role} "获取成功"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "没有操作权限"
+// @Security ApiKeyAuth
+// @Router /api/admin/roles [get]
+func (rc *RoleController) ListRoles(ctx *gin.Context) {
+	roles, err := rc.roleService.ListRoles()
+	if err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(roles, "获取成功"))
+}
+
+// UserRoles godoc
+// @Summary 获取用户角色
+// @Description 获取指定用户拥有的角色，需要 roles:manage 权限
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Param userId query int true "用户ID"
+// @Success 200 {object} models.Response{data=[]models.Role} "获取成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 403 {object} models.Response "没有操作权限"
+// @Failure 1000 {object} models.Response "用户不存在"
+// @Security ApiKeyAuth
+// @Router /api/admin/users/roles [get]
+func (rc *RoleController) UserRoles(ctx *gin.Context) {
+	userID, err := strconv.ParseUint(ctx.Query("userId"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	roles, err := rc.roleService.UserRoles(uint(userID))
+	if err != nil {
+		respondRoleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(roles, "获取成功"))
+}
+
+// AssignRole godoc
+// @Summary 分配角色
+// @Description 为指定用户分配角色，已拥有该角色时不做修改，需要 roles:manage 权限
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Param request body models.RoleAssignRequest true "用户ID和角色名称"
+// @Success 200 {object} models.Response "分配成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 403 {object} models.Response "没有操作权限"
+// @Failure 1000 {object} models.Response "用户不存在"
+// @Failure 3000 {object} models.Response "角色不存在"
+// @Security ApiKeyAuth
+// @Router /api/admin/users/roles/assign [post]
+func (rc *RoleController) AssignRole(ctx *gin.Context) {
+	var req models.RoleAssignRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	if err := rc.roleService.AssignRole(req.UserID, req.Role); err != nil {
+		respondRoleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(nil, "分配成功"))
+}
+
+// RevokeRole godoc
+// @Summary 移除角色
+// @Description 移除指定用户的角色，不允许移除最后一个管理员，需要 roles:manage 权限
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Param request body models.RoleAssignRequest true "用户ID和角色名称"
+// @Success 200 {object} models.Response "移除成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 403 {object} models.Response "没有操作权限"
+// @Failure 1000 {object} models.Response "用户不存在"
+// @Failure 3000 {object} models.Response "角色不存在"
+// @Failure 3001 {object} models.Response "不能移除最后一个管理员"
+// @Security ApiKeyAuth
+// @Router /api/admin/users/roles/revoke [post]
+func (rc *RoleController) RevokeRole(ctx *gin.Context) {
+	var req models.RoleAssignRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	if err := rc.roleService.RevokeRole(req.UserID, req.Role); err != nil {
+		respondRoleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(nil, "移除成功"))
+}
+
+// respondRoleError 业务错误码原样返回，其余错误按服务器内部错误处理
+func respondRoleError(ctx *gin.Context, err error) {
+	if e, ok := err.(*errcode.ErrorCode); ok {
+		ctx.JSON(http.StatusOK, models.NewError(e))
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+}
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionController struct {
+	sessionService *services.SessionService
+}
+
+func NewSessionController(sessionService *services.SessionService) *SessionController {
+	return &SessionController{sessionService: sessionService}
+}
+
+// ListSessions godoc
+// @Summary 获取登录会话列表
+// @Description 获取当前用户所有有效的登录会话（设备）
+// @Tags 会话管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.Response{data=[]models.SessionInfo} "获取成功"
+// @Failure 401 {object} models.Response "未授权"
+// @Failure 500 {object} models.Response "服务器内部错误"
+// @Security ApiKeyAuth
+// @Router /api/users/sessions [get]
+func (sc *SessionController) ListSessions(ctx *gin.Context) {
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.Unauthorized))
+		return
+	}
+
+	sessions, err := sc.sessionService.List(userId.(uint))
+	if err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+		return
+	}
+
+	currentID := ctx.GetUint("sessionId")
+	infos := make([]*models.SessionInfo, len(sessions))
+	for i := range sessions {
+		infos[i] = sessions[i].ToSessionInfo(currentID)
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccess(infos, "获取成功"))
+}
+
+// RevokeSession godoc
+// @Summary 注销指定会话
+// @Description 注销当前用户的指定登录会话，对应设备需重新登录
+// @Tags 会话管理
+// @Accept json
+// @Produce json
+// @Param request body models.SessionRevokeRequest true "会话ID"
+// @Success 200 {object} models.Response "注销成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 2004 {object} models.Response "会话不存在"
+// @Security ApiKeyAuth
+// @Router /api/users/sessions/revoke [post]
+func (sc *SessionController) RevokeSession(ctx *gin.Context) {
+	var req models.SessionRevokeRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.Unauthorized))
+		return
+	}
+
+	if err := sc.sessionService.Revoke(userId.(uint), req.SessionID); err != nil {
+		if err == errcode.SessionNotFound {
+			ctx.JSON(http.StatusOK, models.NewError(errcode.SessionNotFound))
+			return
+		}
+		ctx.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(nil, "注销成功"))
+}
+
+// RevokeAllSessions godoc
+// @Summary 注销全部会话
+// @Description 注销当前用户的全部登录会话（包括当前会话），所有设备需重新登录
+// @Tags 会话管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.Response "注销成功"
+// @Failure 401 {object} models.Response "未授权"
+// @Security ApiKeyAuth
+// @Router /api/users/sessions/revoke-all [post]
+func (sc *SessionController) RevokeAllSessions(ctx *gin.Context) {
+	userId, exists := ctx.Get("userId")
+	if !exists {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.Unauthorized))
+		return
+	}
+
+	if err := sc.sessionService.RevokeAll(userId.(uint)); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(nil, "注销成功"))
+}
+
+// RefreshToken godoc
+// @Summary 刷新令牌
+// @Description 使用刷新令牌换取新的访问令牌和刷新令牌，旧刷新令牌立即失效；重复使用已失效的刷新令牌会注销整个会话
+// @Tags 会话管理
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body models.RefreshTokenRequest true "刷新令牌"
+// @Success 200 {object} models.Response{data=models.TokenResponse} "刷新成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 2000 {object} models.Response "无效的Token"
+// @Failure 2001 {object} models.Response "Token已过期"
+// @Failure 2003 {object} models.Response "Token已失效，请重新登录"
+// @Failure 2005 {object} models.Response "刷新令牌已被使用，会话已注销"
+// @Router /api/token/refresh [post]
+func (sc *SessionController) RefreshToken(ctx *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	tokens, err := sc.sessionService.Refresh(req.RefreshToken)
+	if err != nil {
+		if e, ok := err.(*errcode.ErrorCode); ok {
+			ctx.JSON(http.StatusOK, models.NewError(e))
+			return
+		}
+		ctx.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(tokens, "刷新成功"))
+}
@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TwoFactorController struct {
+	twoFactorService *services.TwoFactorService
+	userService      *services.UserService
+}
+
+func NewTwoFactorController(twoFactorService *services.TwoFactorService, userService *services.UserService) *TwoFactorController {
+	return &TwoFactorController{twoFactorService: twoFactorService, userService: userService}
+}
+
+// Login godoc
+// @Summary 两步登录
+// @Description 提交登录接口返回的挑战令牌和身份验证器中的验证码（或恢复码）完成登录。
+// @Description 挑战令牌只能使用一次，验证码错误次数达到 two_factor.max_attempts 后需要重新输入密码
+// @Tags 两步验证
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body models.TwoFactorLoginRequest true "挑战令牌和验证码"
+// @Success 200 {object} models.Response{data=models.LoginResponse} "登录成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 1006 {object} models.Response "账号已被禁用"
+// @Failure 1014 {object} models.Response "验证码错误"
+// @Failure 1015 {object} models.Response "登录验证已失效，请重新登录"
+// @Router /api/login/2fa [post]
+func (tc *TwoFactorController) Login(ctx *gin.Context) {
+	var req models.TwoFactorLoginRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	user, meta, err := tc.twoFactorService.VerifyChallenge(req.ChallengeToken, req.Code)
+	if err != nil {
+		respondTwoFactorError(ctx, err)
+		return
+	}
+	meta.UserAgent = ctx.Request.UserAgent()
+	meta.IP = ctx.ClientIP()
+
+	user, tokens, err := tc.userService.CompleteLogin(user, meta)
+	if err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+		return
+	}
+	respondLogin(ctx, user, tokens)
+}
+
+// Status godoc
+// @Summary 两步验证状态
+// @Description 获取当前用户是否开启两步验证及剩余恢复码数量
+// @Tags 两步验证
+// @Produce json
+// @Success 200 {object} models.Response{data=models.TwoFactorStatus} "获取成功"
+// @Security ApiKeyAuth
+// @Router /api/users/2fa [get]
+func (tc *TwoFactorController) Status(ctx *gin.Context) {
+	status, err := tc.twoFactorService.Status(ctx.GetUint("userId"))
+	if err != nil {
+		respondTwoFactorError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(status, "获取成功"))
+}
+
+// Enroll godoc
+// @Summary 绑定身份验证器
+// @Description 生成新的 TOTP 密钥，返回 otpauth URI 和二维码；使用身份验证器扫码后调用 /api/users/2fa/confirm 提交验证码，两步验证才会生效。
+// @Description 确认前重复调用会生成新的密钥，之前的密钥失效
+// @Tags 两步验证
+// @Produce json
+// @Success 200 {object} models.Response{data=models.TwoFactorEnrollment} "获取成功"
+// @Failure 1011 {object} models.Response "两步验证已开启"
+// @Security ApiKeyAuth
+// @Router /api/users/2fa/enroll [post]
+func (tc *TwoFactorController) Enroll(ctx *gin.Context) {
+	enrollment, err := tc.twoFactorService.Enroll(ctx.GetUint("userId"))
+	if err != nil {
+		respondTwoFactorError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(enrollment, "请使用身份验证器扫描二维码"))
+}
+
+// Confirm godoc
+// @Summary 开启两步验证
+// @Description 提交身份验证器中的验证码确认绑定，两步验证随即生效；返回的恢复码只显示这一次，请妥善保存
+// @Tags 两步验证
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body models.TwoFactorCodeRequest true "验证码"
+// @Success 200 {object} models.Response{data=models.TwoFactorRecoveryCodes} "两步验证已开启"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 1011 {object} models.Response "两步验证已开启"
+// @Failure 1013 {object} models.Response "请先绑定身份验证器"
+// @Failure 1014 {object} models.Response "验证码错误"
+// @Failure 1044 {object} models.Response "验证码错误次数过多"
+// @Security ApiKeyAuth
+// @Router /api/users/2fa/confirm [post]
+func (tc *TwoFactorController) Confirm(ctx *gin.Context) {
+	var req models.TwoFactorCodeRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	codes, err := tc.twoFactorService.Confirm(ctx.GetUint("userId"), req.Code)
+	if err != nil {
+		respondTwoFactorError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(models.TwoFactorRecoveryCodes{RecoveryCodes: codes}, "两步验证已开启"))
+}
+
+// Disable godoc
+// @Summary 关闭两步验证
+// @Description 提交验证码或恢复码关闭两步验证，密钥和恢复码全部删除
+// @Tags 两步验证
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body models.TwoFactorCodeRequest true "验证码或恢复码"
+// @Success 200 {object} models.Response "两步验证已关闭"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 1012 {object} models.Response "两步验证未开启"
+// @Failure 1014 {object} models.Response "验证码错误"
+// @Failure 1044 {object} models.Response "验证码错误次数过多，请稍后再试"
+// @Security ApiKeyAuth
+// @Router /api/users/2fa/disable [post]
+func (tc *TwoFactorController) Disable(ctx *gin.Context) {
+	var req models.TwoFactorCodeRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	if err := tc.twoFactorService.Disable(ctx.GetUint("userId"), req.Code); err != nil {
+		respondTwoFactorError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(nil, "两步验证已关闭"))
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary 重新生成恢复码
+// @Description 提交验证码或恢复码重新生成恢复码，之前的恢复码全部失效
+// @Tags 两步验证
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body models.TwoFactorCodeRequest true "验证码或恢复码"
+// @Success 200 {object} models.Response{data=models.TwoFactorRecoveryCodes} "恢复码已重新生成"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 1012 {object} models.Response "两步验证未开启"
+// @Failure 1014 {object} models.Response "验证码错误"
+// @Failure 1044 {object} models.Response "验证码错误次数过多，请稍后再试"
+// @Security ApiKeyAuth
+// @Router /api/users/2fa/recovery-codes [post]
+func (tc *TwoFactorController) RegenerateRecoveryCodes(ctx *gin.Context) {
+	var req models.TwoFactorCodeRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	codes, err := tc.twoFactorService.RegenerateRecoveryCodes(ctx.GetUint("userId"), req.Code)
+	if err != nil {
+		respondTwoFactorError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(models.TwoFactorRecoveryCodes{RecoveryCodes: codes}, "恢复码已重新生成"))
+}
+
+// Reset godoc
+// @Summary 重置用户两步验证
+// @Description 管理员重置用户的两步验证，用于用户丢失身份验证器和恢复码的情况，需要 users:update 权限
+// @Tags 两步验证
+// @Accept json
+// @Produce json
+// @Param request body models.UserIDRequest true "用户ID"
+// @Success 200 {object} models.Response "两步验证已重置"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 403 {object} models.Response "没有操作权限"
+// @Failure 1000 {object} models.Response "用户不存在"
+// @Failure 1012 {object} models.Response "两步验证未开启"
+// @Security ApiKeyAuth
+// @Router /api/admin/users/2fa/reset [post]
+func (tc *TwoFactorController) Reset(ctx *gin.Context) {
+	var req models.UserIDRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	if err := tc.twoFactorService.Reset(req.UserID); err != nil {
+		respondTwoFactorError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(nil, "两步验证已重置"))
+}
+
+// respondTwoFactorError 将两步验证服务返回的错误写入响应，非业务错误统一返回服务器内部错误
+func respondTwoFactorError(ctx *gin.Context, err error) {
+	if e, ok := err.(*errcode.ErrorCode); ok {
+		ctx.JSON(http.StatusOK, models.NewError(e))
+		return
+	}
+	ctx.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+}
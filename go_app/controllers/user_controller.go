@@ -1,10 +1,12 @@
 package controllers
 
 import (
-	"fmt"
+	"go_app/middleware"
 	"go_app/models"
 	"go_app/pkg/errcode"
+	"go_app/pkg/logger"
 	"go_app/services"
+	"math"
 	"net/http"
 	"strconv"
 
@@ -15,25 +17,58 @@ import (
 )
 
 type UserController struct {
-	userService *services.UserService
+	userService              *services.UserService
+	roleService              *services.RoleService
+	emailVerificationService *services.EmailVerificationService
+	twoFactorService         *services.TwoFactorService
 }
 
-func NewUserController(userService *services.UserService) *UserController {
-	return &UserController{userService: userService}
+func NewUserController(userService *services.UserService, roleService *services.RoleService, emailVerificationService *services.EmailVerificationService, twoFactorService *services.TwoFactorService) *UserController {
+	return &UserController{
+		userService:              userService,
+		roleService:              roleService,
+		emailVerificationService: emailVerificationService,
+		twoFactorService:         twoFactorService,
+	}
+}
+
+// authorize 校验当前用户能否操作 targetID 的数据，不能操作时写入错误响应并返回 false。
+// 使用 API Key 操作其他用户的数据时，API Key 还必须授予对应权限
+func (uc *UserController) authorize(c *gin.Context, targetID uint, permission string) bool {
+	if targetID != c.GetUint("userId") && !middleware.AllowedByAPIKey(c, permission) {
+		c.JSON(http.StatusOK, models.NewError(errcode.APIKeyScopeDenied))
+		return false
+	}
+	err := uc.roleService.Authorize(c.GetUint("userId"), targetID, permission)
+	if err == nil {
+		return true
+	}
+	if err == errcode.Forbidden {
+		c.JSON(http.StatusOK, models.NewError(errcode.Forbidden))
+	} else {
+		c.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+	}
+	return false
 }
 
 // Login godoc
 // @Summary 用户登录
-// @Description 用户登录并获取token，同一用户重复登录会使之前的token失效
+// @Description 用户登录并获取token，每次登录创建一个设备会话，旧会话是否失效由会话策略（session.policy）决定。
+// @Description 账号开启两步验证时不返回 token，而是返回 twoFactorRequired 和挑战令牌，需通过 /api/login/2fa 提交验证码完成登录。
+// @Description 邮箱不存在和密码错误返回相同的错误；同一账号或 IP 失败次数过多时需等待 Retry-After 响应头给出的秒数后再试
 // @Tags 用户管理
 // @Accept json,x-www-form-urlencoded
 // @Produce json
 // @Param request body models.LoginRequest true "用户登录信息"
 // @Success 200 {object} models.Response{data=models.LoginResponse} "登录成功"
+// @Success 200 {object} models.Response{data=models.TwoFactorChallengeResponse} "需要两步验证"
 // @Failure 400 {object} models.Response "请求参数错误"
-// @Failure 1000 {object} models.Response "用户不存在"
-// @Failure 1001 {object} models.Response "密码错误"
+// @Failure 1006 {object} models.Response "账号已被禁用"
+// @Failure 1020 {object} models.Response "邮箱或密码错误"
+// @Failure 1021 {object} models.Response "登录尝试过于频繁，请稍后再试"
+// @Failure 1022 {object} models.Response "登录失败次数过多，已暂时锁定，请稍后再试"
 // @Failure 500 {object} models.Response "服务器内部错误"
+// @Header 200 {integer} Retry-After "被限制时需要等待的秒数"
 // @Router /api/login [post]
 func (uc *UserController) Login(c *gin.Context) {
 	var req models.LoginRequest
@@ -42,31 +77,93 @@ func (uc *UserController) Login(c *gin.Context) {
 		return
 	}
 
-	user, token, err := uc.userService.Login(req.Email, req.Password)
+	meta := models.SessionMeta{
+		Platform:  req.Platform,
+		DeviceID:  req.DeviceID,
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}
+
+	user, err := uc.userService.Authenticate(req.Email, req.Password, meta.IP)
 	if err != nil {
-		if err.Error() == "用户不存在" {
-			c.JSON(http.StatusOK, models.NewError(errcode.UserNotFound))
-			return
-		}
-		if err.Error() == "密码错误" {
-			c.JSON(http.StatusOK, models.NewError(errcode.UserPasswordError))
+		respondLoginError(c, err)
+		return
+	}
+
+	// 开启两步验证的账号先签发挑战令牌，验证码校验通过后才创建会话
+	enabled, err := uc.twoFactorService.Enabled(user.ID)
+	if err != nil {
+		c.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+		return
+	}
+	if enabled {
+		challenge, err := uc.twoFactorService.Challenge(user, meta)
+		if err != nil {
+			c.JSON(http.StatusOK, models.NewError(errcode.ServerError))
 			return
 		}
+		c.JSON(http.StatusOK, models.NewSuccess(challenge, "请输入两步验证码"))
+		return
+	}
+
+	user, tokens, err := uc.userService.CompleteLogin(user, meta)
+	if err != nil {
 		c.JSON(http.StatusOK, models.NewError(errcode.ServerError))
 		return
 	}
+	respondLogin(c, user, tokens)
+}
 
-	// 删除这两行，因为它们是多余的
-	// // 将 token 设置到 user 对象中
-	// user.Token = token
-	
-	// 保持这个响应结构
+// UnlockLogin godoc
+// @Summary 解除登录锁定
+// @Description 清除账号或客户端 IP 的登录失败记录并解除临时锁定，需要 users:update 权限；userId 和 ip 至少指定一个
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body models.LoginUnlockRequest true "用户ID和/或客户端 IP"
+// @Success 200 {object} models.Response "已解除锁定"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 403 {object} models.Response "没有操作权限"
+// @Failure 1000 {object} models.Response "用户不存在"
+// @Security ApiKeyAuth
+// @Router /api/admin/users/unlock [post]
+func (uc *UserController) UnlockLogin(c *gin.Context) {
+	var req models.LoginUnlockRequest
+	if err := c.ShouldBind(&req); err != nil || (req.UserID == 0 && req.IP == "") {
+		c.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
+		return
+	}
+
+	if err := uc.userService.UnlockLogin(req.UserID, req.IP); err != nil {
+		respondLoginError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.NewSuccess(nil, "已解除锁定"))
+}
+
+// respondLoginError 将密码登录的错误写入响应，被限制时通过 Retry-After 告知需要等待的秒数
+func respondLoginError(c *gin.Context, err error) {
+	if e, ok := err.(*services.LoginThrottledError); ok {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(e.RetryAfter.Seconds()))))
+		c.JSON(http.StatusOK, models.NewError(e.ErrorCode))
+		return
+	}
+	if e, ok := err.(*errcode.ErrorCode); ok {
+		c.JSON(http.StatusOK, models.NewError(e))
+		return
+	}
+	logger.Errorf("登录失败: %v", err)
+	c.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+}
+
+// respondLogin 写入登录成功响应，用户信息与令牌平铺在 data 中
+func respondLogin(c *gin.Context, user *models.User, tokens *models.TokenResponse) {
 	response := struct {
 	    *models.UserInfo
-	    Token string `json:"token"`
+	    *models.TokenResponse
 	}{
-	    UserInfo: user.ToUserInfo(),
-	    Token:    token,
+	    UserInfo:      user.ToUserInfo(),
+	    TokenResponse: tokens,
 	}
 	
 	c.JSON(http.StatusOK, models.NewSuccess(response, "登录成功"))
@@ -74,7 +171,7 @@ func (uc *UserController) Login(c *gin.Context) {
 
 // Register godoc
 // @Summary 用户注册
-// @Description 新用户注册
+// @Description 新用户注册，注册后向邮箱发送验证链接，邮箱验证前不能访问部分接口
 // @Tags 用户管理
 // @Accept json,x-www-form-urlencoded
 // @Produce json
@@ -112,18 +209,25 @@ func (uc *UserController) Register(c *gin.Context) {
 		c.JSON(http.StatusOK, models.NewError(errcode.UserCreateFailed))
 		return
 	}
+
+	// 验证邮件由后台任务发送，未能提交或发送失败时用户可以通过 /api/users/email/resend 重新发送
+	if err := uc.emailVerificationService.SendVerification(user.ID); err != nil {
+		logger.Errorf("提交邮箱验证邮件失败: %v", err)
+	}
+
 	c.JSON(http.StatusOK, models.NewSuccess(user.ToUserInfo(), "注册成功"))
 }
 
 // UpdateUser godoc
 // @Summary 更新用户信息
-// @Description 更新用户基本信息，包括用户名、生日、性别、爱好等
+// @Description 更新用户基本信息，包括用户名、生日、性别、爱好等；修改其他用户需要 users:update 权限
 // @Tags 用户管理
 // @Accept json
 // @Produce json
 // @Param request body models.UserUpdateRequest true "用户信息更新请求"
 // @Success 200 {object} models.Response{data=models.UserInfo} "更新成功"
 // @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 403 {object} models.Response "没有操作权限"
 // @Failure 404 {object} models.Response "用户不存在"
 // @Security ApiKeyAuth
 // @Router /api/users/update [post]
@@ -145,6 +249,9 @@ func (uc *UserController) UpdateUser(ctx *gin.Context) {
 		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
 		return
 	}
+	if !uc.authorize(ctx, req.UserID, models.PermUsersUpdate) {
+		return
+	}
 
 	// 获取现有用户信息
 	user, err := uc.userService.GetUserByID(req.UserID)
@@ -175,54 +282,85 @@ func (uc *UserController) UpdateUser(ctx *gin.Context) {
 
 // UpdateEmail godoc
 // @Summary 更新邮箱
-// @Description 更新用户邮箱
+// @Description 修改当前用户的邮箱：新邮箱保存为待验证邮箱并发送验证链接，同时通知原邮箱，验证通过后新邮箱才生效；
+// @Description 新邮箱与当前邮箱相同时取消进行中的修改。用户只从登录会话中获取，userId 不是当前用户时返回 403
 // @Tags 用户管理
 // @Accept json,x-www-form-urlencoded
 // @Produce json
 // @Param request body models.EmailUpdateRequest true "更新邮箱信息"
-// @Success 200 {object} models.Response{data=models.UserInfo} "更新成功"
+// @Success 200 {object} models.Response{data=models.UserInfo} "验证邮件已发送到新邮箱"
 // @Failure 400 {object} models.Response "请求参数错误"
-// @Failure 409 {object} models.Response "邮箱已被使用"
+// @Failure 403 {object} models.Response "没有操作权限"
+// @Failure 429 {object} models.Response "验证邮件发送过于频繁"
+// @Failure 1002 {object} models.Response "邮箱已被使用"
 // @Security ApiKeyAuth
 // @Router /api/users/email [post]
-// UpdateEmail 方法中
 func (uc *UserController) UpdateEmail(ctx *gin.Context) {
 	var req models.EmailUpdateRequest
 	if err := ctx.ShouldBind(&req); err != nil {
 		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
 		return
 	}
-
-	if _, err := uc.userService.GetUserByEmail(req.Email); err == nil {
-		ctx.JSON(http.StatusOK, models.NewError(errcode.UserAlreadyExists))
+	userID := ctx.GetUint("userId")
+	if req.UserID != 0 && req.UserID != userID {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.Forbidden))
 		return
 	}
 
-	user, err := uc.userService.GetUserByID(req.UserID)
-	if err != nil {
-		ctx.JSON(http.StatusOK, models.NewError(errcode.UserNotFound))
+	uc.requestEmailChange(ctx, userID, req.Email)
+}
+
+// AdminUpdateEmail godoc
+// @Summary 更新用户邮箱
+// @Description 管理员修改用户邮箱，需要 users:update 权限；与用户本人修改相同，新邮箱验证通过后才生效
+// @Tags 用户管理
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body models.AdminEmailUpdateRequest true "更新邮箱信息"
+// @Success 200 {object} models.Response{data=models.UserInfo} "验证邮件已发送到新邮箱"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 403 {object} models.Response "没有操作权限"
+// @Failure 429 {object} models.Response "验证邮件发送过于频繁"
+// @Failure 1000 {object} models.Response "用户不存在"
+// @Failure 1002 {object} models.Response "邮箱已被使用"
+// @Security ApiKeyAuth
+// @Router /api/admin/users/email [post]
+func (uc *UserController) AdminUpdateEmail(ctx *gin.Context) {
+	var req models.AdminEmailUpdateRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
 		return
 	}
 
-	user.Email = req.Email
-	if err := uc.userService.UpdateUserSafe(user); err != nil {
-		ctx.JSON(http.StatusOK, models.NewError(errcode.UserUpdateFailed))
+	uc.requestEmailChange(ctx, req.UserID, req.Email)
+}
+
+func (uc *UserController) requestEmailChange(ctx *gin.Context, userID uint, email string) {
+	user, err := uc.emailVerificationService.RequestEmailChange(userID, email)
+	if err != nil {
+		respondEmailError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, models.NewSuccess(user.ToUserInfo(), "邮箱更新成功"))
+	message := "验证邮件已发送到新邮箱，验证通过后生效"
+	if user.PendingEmail == "" {
+		message = "邮箱未修改"
+	}
+	ctx.JSON(http.StatusOK, models.NewSuccess(user.ToUserInfo(), message))
 }
 
 // ChangePassword godoc
 // @Summary 修改密码
-// @Description 用户修改密码
+// @Description 用户修改密码；旧密码错误次数按登录失败的规则限制，被限制时返回 1021 或 1022 并在 Retry-After 中给出需要等待的秒数
 // @Tags 用户管理
 // @Accept json,x-www-form-urlencoded
 // @Produce json
 // @Param request body models.PasswordChangeRequest true "修改密码信息"
 // @Success 200 {object} models.Response "密码修改成功"
+// @Header 200 {integer} Retry-After "被限制时需要等待的秒数"
 // @Failure 400 {object} models.Response "请求参数错误"
 // @Failure 401 {object} models.Response "未授权"
+// @Failure 1021 {object} models.Response "尝试过于频繁"
 // @Security ApiKeyAuth
 // @Router /api/users/password [post]
 func (uc *UserController) ChangePassword(ctx *gin.Context) {
@@ -239,6 +377,10 @@ func (uc *UserController) ChangePassword(ctx *gin.Context) {
 	}
 
 	if err := uc.userService.ChangePassword(userId.(uint), req.OldPassword, req.NewPassword); err != nil {
+		if _, ok := err.(*services.LoginThrottledError); ok {
+			respondLoginError(ctx, err)
+			return
+		}
 		ctx.JSON(http.StatusOK, models.NewError(errcode.UserPasswordError))
 		return
 	}
@@ -247,7 +389,7 @@ func (uc *UserController) ChangePassword(ctx *gin.Context) {
 
 // ListUsers godoc
 // @Summary 获取用户列表
-// @Description 获取用户列表，支持分页查询
+// @Description 获取用户列表，支持分页查询，需要 users:list 权限
 // @Tags 用户管理
 // @Accept json
 // @Produce json
@@ -255,6 +397,7 @@ func (uc *UserController) ChangePassword(ctx *gin.Context) {
 // @Param pageSize query int false "每页数量" minimum(1) maximum(100) default(10)
 // @Success 200 {object} models.Response{data=models.UserPageResponse} "获取成功"
 // @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "没有操作权限"
 // @Failure 500 {object} models.Response "服务器内部错误"
 // @Security ApiKeyAuth
 // @Router /api/users [get]
@@ -289,13 +432,15 @@ func (uc *UserController) ListUsers(ctx *gin.Context) {
 
 // DeleteUser godoc
 // @Summary 删除用户
-// @Description 删除指定用户
+// @Description 删除指定用户，删除其他用户需要 users:delete 权限，不允许删除最后一个管理员
 // @Tags 用户管理
 // @Accept json
 // @Produce json
 // @Param request body models.UserIDRequest true "用户ID"
 // @Success 200 {object} models.Response "删除成功"
 // @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 403 {object} models.Response "没有操作权限"
+// @Failure 3001 {object} models.Response "不能移除最后一个管理员"
 // @Failure 404 {object} models.Response "用户不存在"
 // @Security ApiKeyAuth
 // @Router /api/users/delete [post]
@@ -307,8 +452,15 @@ func (uc *UserController) DeleteUser(ctx *gin.Context) {
 		ctx.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
 		return
 	}
+	if !uc.authorize(ctx, req.UserID, models.PermUsersDelete) {
+		return
+	}
 
 	if err := uc.userService.DeleteUser(req.UserID); err != nil {
+		if err == errcode.RoleLastAdmin {
+			ctx.JSON(http.StatusOK, models.NewError(errcode.RoleLastAdmin))
+			return
+		}
 		ctx.JSON(http.StatusOK, models.NewError(errcode.UserDeleteFailed))
 		return
 	}
@@ -317,7 +469,7 @@ func (uc *UserController) DeleteUser(ctx *gin.Context) {
 
 // Logout godoc
 // @Summary 用户退出
-// @Description 用户退出登录
+// @Description 用户退出登录，仅注销当前设备会话
 // @Tags 用户管理
 // @Accept json
 // @Produce json
@@ -332,7 +484,7 @@ func (uc *UserController) Logout(ctx *gin.Context) {
 		return
 	}
 
-	if err := uc.userService.Logout(userId.(uint)); err != nil {
+	if err := uc.userService.Logout(userId.(uint), ctx.GetUint("sessionId")); err != nil {
 		ctx.JSON(http.StatusOK, models.NewError(errcode.ServerError))
 		return
 	}
@@ -341,7 +493,7 @@ func (uc *UserController) Logout(ctx *gin.Context) {
 
 // UploadAvatar godoc
 // @Summary 上传用户头像
-// @Description 上传并更新用户头像
+// @Description 上传并更新用户头像，修改其他用户的头像需要 users:update 权限
 // @Tags 用户管理
 // @Accept multipart/form-data
 // @Produce json
@@ -350,36 +502,33 @@ func (uc *UserController) Logout(ctx *gin.Context) {
 // @Success 200 {object} models.Response{data=map[string]string} "上传成功，返回头像URL"
 // @Failure 400 {object} models.Response "请求参数错误"
 // @Failure 401 {object} models.Response "未授权"
+// @Failure 403 {object} models.Response "没有操作权限"
 // @Failure 500 {object} models.Response "服务器内部错误"
 // @Security ApiKeyAuth
 // @Router /api/users/avatar [post]
 func (uc *UserController) UploadAvatar(c *gin.Context) {
 	// 从请求参数获取 userId
 	userIDStr := c.PostForm("userId")
-	fmt.Printf("接收到的 userId: %s\n", userIDStr)
-
 	if userIDStr == "" {
-		fmt.Printf("未找到 userId 参数\n")
 		c.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
 		return
 	}
 
 	userID, err := strconv.ParseUint(userIDStr, 10, 32)
 	if err != nil {
-		fmt.Printf("userId 转换失败: %v\n", err)
 		c.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
 		return
 	}
+	if !uc.authorize(c, uint(userID), models.PermUsersUpdate) {
+		return
+	}
 
 	file, err := c.FormFile("avatar")
 	if err != nil {
-		fmt.Printf("获取文件失败: %v\n", err)
 		c.JSON(http.StatusOK, models.NewError(errcode.InvalidParams).WithDetails("文件上传失败: "+err.Error()))
 		return
 	}
 
-	fmt.Printf("上传文件信息: 名称=%s, 大小=%d, 类型=%s\n", file.Filename, file.Size, file.Header.Get("Content-Type"))
-
 	if !isValidImageFile(file.Filename) {
 		c.JSON(http.StatusOK, models.NewError(errcode.InvalidParams).WithDetails("不支持的文件类型"))
 		return
@@ -397,7 +546,7 @@ func (uc *UserController) UploadAvatar(c *gin.Context) {
 
 	avatarURL, err := uc.userService.SaveAvatar(uint(userID), file)
 	if err != nil {
-		fmt.Printf("保存头像失败: %v\n", err)
+		logger.Errorf("保存用户 %d 的头像失败: %v", userID, err)
 		c.JSON(http.StatusOK, models.NewError(errcode.ServerError).WithDetails("保存头像失败: "+err.Error()))
 		return
 	}
@@ -430,13 +579,14 @@ func isValidImageFile(filename string) bool {
 
 // GetUser godoc
 // @Summary 获取用户信息
-// @Description 获取指定用户的详细信息
+// @Description 获取指定用户的详细信息，查看其他用户需要 users:read 权限
 // @Tags 用户管理
 // @Accept json
 // @Produce json
 // @Param userId query int true "用户ID"
 // @Success 200 {object} models.Response{data=models.UserInfo} "获取成功"
 // @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 403 {object} models.Response "没有操作权限"
 // @Failure 404 {object} models.Response "用户不存在"
 // @Security ApiKeyAuth
 // @Router /api/users/info [get]
@@ -452,6 +602,9 @@ func (uc *UserController) GetUser(c *gin.Context) {
         c.JSON(http.StatusOK, models.NewError(errcode.InvalidParams))
         return
     }
+    if !uc.authorize(c, uint(userID), models.PermUsersRead) {
+        return
+    }
 
     user, err := uc.userService.GetUserByID(uint(userID))
     if err != nil {
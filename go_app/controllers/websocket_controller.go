@@ -6,6 +6,7 @@ import (
     "go_app/pkg/websocket"
     "log"
     "net/http"
+    "time"
 
     "github.com/gin-gonic/gin"
     gorillaws "github.com/gorilla/websocket"
@@ -29,10 +30,10 @@ func (wc *WebSocketController) HandleConnection(c *gin.Context) {
         return
     }
 
-    // 检查用户是否已经连接
-    if _, ok := wc.manager.Clients[userID.(uint)]; ok {
-        log.Printf("用户 %d 已经连接", userID)
-        c.JSON(http.StatusBadRequest, models.NewError(errcode.InvalidRequest))
+    // 同一用户可以同时保持多个连接，超过上限时在升级协议前拒绝
+    if !wc.manager.CanConnect(userID.(uint)) {
+        log.Printf("用户 %d 的连接数已达上限", userID)
+        c.JSON(http.StatusTooManyRequests, models.NewError(errcode.WebSocketConnectionLimit))
         return
     }
 
@@ -48,16 +49,25 @@ func (wc *WebSocketController) HandleConnection(c *gin.Context) {
         return
     }
 
-    log.Printf("WebSocket 连接成功建立，用户ID: %v", userID)
+    client, err := wc.manager.NewClient(userID.(uint), conn)
+    if err != nil {
+        log.Printf("创建 WebSocket 连接失败: %v", err)
+        conn.Close()
+        return
+    }
 
-    client := &websocket.Client{
-        ID:     userID.(uint),
-        Socket: conn,
-        Send:   make(chan []byte, 256),
+    // 并发建立的连接可能在升级期间占满名额，此时以关闭帧告知客户端原因
+    if err := wc.manager.Register(client); err != nil {
+        log.Printf("用户 %d 的连接被拒绝: %v", userID, err)
+        conn.WriteControl(gorillaws.CloseMessage,
+            gorillaws.FormatCloseMessage(gorillaws.CloseTryAgainLater, errcode.WebSocketConnectionLimit.Message),
+            time.Now().Add(time.Second))
+        conn.Close()
+        return
     }
 
-    wc.manager.Register <- client
+    log.Printf("WebSocket 连接成功建立，用户ID: %v，连接ID: %s", userID, client.ID)
 
     go client.ReadPump()
     go client.WritePump()
-}
\ No newline at end of file
+}
@@ -0,0 +1,28 @@
+package controllers
+
+import (
+	"go_app/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WellKnownController struct {
+	tokenService services.TokenService
+}
+
+func NewWellKnownController(tokenService services.TokenService) *WellKnownController {
+	return &WellKnownController{tokenService: tokenService}
+}
+
+// JWKS godoc
+// @Summary 获取验签公钥
+// @Description 以 JWKS（RFC 7517）格式返回当前有效的 JWT 验签公钥，仅 RS256 / EdDSA 模式下有内容
+// @Tags 系统
+// @Produce json
+// @Success 200 {object} utils.JWKS "公钥集合"
+// @Router /.well-known/jwks.json [get]
+func (wc *WellKnownController) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, wc.tokenService.JWKS())
+}
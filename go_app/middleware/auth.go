@@ -4,14 +4,23 @@ import (
 	"go_app/models"
 	"go_app/services"
     "go_app/pkg/errcode"
-	"go_app/utils"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// APIKeyHeader 使用 API Key 认证时携带明文的请求头，按 API Key 限流时也读取该请求头
+const APIKeyHeader = "X-API-Key"
+
+// AuthMiddleware 认证请求，支持 Authorization 请求头中的 Bearer 访问令牌或 X-API-Key 请求头中的 API Key，
+// 两种方式都写入 userId；使用访问令牌时写入 sessionId，使用 API Key 时 sessionId 为 0 并写入 apiKey
+func AuthMiddleware(tokens services.TokenService, apiKeys *services.APIKeyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if plain := c.GetHeader(APIKeyHeader); plain != "" {
+			authenticateAPIKey(c, apiKeys, plain)
+			return
+		}
+
 		tokenString := c.GetHeader("Authorization")
 		if tokenString == "" {
 			c.JSON(http.StatusOK, models.NewError(errcode.TokenMissing))
@@ -19,32 +28,70 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		claims, err := utils.ParseToken(tokenString)
+		claims, err := tokens.Parse(tokenString)
 		if err != nil {
-			c.JSON(http.StatusOK, models.NewError(errcode.TokenInvalid))
-			c.Abort()
+			abortWithTokenError(c, err)
 			return
 		}
 
-		userID := claims.UserID
-		tokenVersion := claims.TokenVersion
+		c.Set("userId", claims.UserID)
+		c.Set("sessionId", claims.SessionID)
+		c.Next()
+	}
+}
 
-		// 验证 token 版本
-		db := services.GetDB()
-		var user models.User
-		if err := db.First(&user, userID).Error; err != nil {
-			c.JSON(http.StatusOK, models.NewError(errcode.UserNotFound))
-			c.Abort()
-			return
-		}
+// authenticateAPIKey 校验 API Key 并检查请求方法所需的授权范围：GET 等只读请求需要 read，其他请求需要 write
+func authenticateAPIKey(c *gin.Context, apiKeys *services.APIKeyService, plain string) {
+	key, err := apiKeys.Authenticate(plain, c.ClientIP())
+	if err != nil {
+		abortWithTokenError(c, err)
+		return
+	}
+	scope := models.APIKeyScopeWrite
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		scope = models.APIKeyScopeRead
+	}
+	if !key.HasScope(scope) {
+		c.JSON(http.StatusOK, models.NewError(errcode.APIKeyScopeDenied))
+		c.Abort()
+		return
+	}
+
+	c.Set("userId", key.UserID)
+	c.Set("sessionId", uint(0))
+	c.Set("apiKey", key)
+	c.Next()
+}
+
+// AllowedByAPIKey 请求使用 API Key 认证时，API Key 是否授予了指定范围；使用访问令牌认证时总是返回 true
+func AllowedByAPIKey(c *gin.Context, scope string) bool {
+	value, ok := c.Get("apiKey")
+	if !ok {
+		return true
+	}
+	return value.(*models.APIKey).HasScope(scope)
+}
 
-		if tokenVersion != user.TokenVersion {
-			c.JSON(http.StatusOK, models.NewError(errcode.TokenVersionError))
+// RequireSession 拒绝使用 API Key 认证的请求，用于密码、邮箱、会话、两步验证、通行密钥和 API Key 本身等
+// 账号安全相关的接口，避免泄露的 API Key 被用来接管账号。需在 AuthMiddleware 之后使用
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("apiKey"); ok {
+			c.JSON(http.StatusOK, models.NewError(errcode.APIKeyNotAllowed))
 			c.Abort()
 			return
 		}
-
-		c.Set("userID", userID)
 		c.Next()
 	}
 }
+
+// abortWithTokenError 将令牌校验错误转换为统一的错误响应并中断请求
+func abortWithTokenError(c *gin.Context, err error) {
+	if e, ok := err.(*errcode.ErrorCode); ok {
+		c.JSON(http.StatusOK, models.NewError(e))
+	} else {
+		c.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+	}
+	c.Abort()
+}
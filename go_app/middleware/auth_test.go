@@ -0,0 +1,239 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go_app/config"
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/repository"
+	"go_app/services"
+	"go_app/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "middleware-test-secret"
+
+// TestTokenRejection HTTP 接口和 WebSocket 握手使用同一个令牌服务，同一个无效令牌在两处得到相同的错误码
+func TestTokenRejection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name string
+		// token 返回要发送的令牌，可修改仓储中用户和会话的状态
+		token func(t *testing.T, env *authEnv) string
+		want  *errcode.ErrorCode
+	}{
+		{"Valid", func(t *testing.T, env *authEnv) string {
+			return env.issue(t, time.Now().Add(time.Hour))
+		}, nil},
+		{"TamperedSignature", func(t *testing.T, env *authEnv) string {
+			token := env.issue(t, time.Now().Add(time.Hour))
+			// 替换签名的第一个字符，最后一个字符可能只包含填充位
+			dot := strings.LastIndex(token, ".") + 1
+			c := byte('A')
+			if token[dot] == 'A' {
+				c = 'B'
+			}
+			return token[:dot] + string(c) + token[dot+1:]
+		}, errcode.TokenInvalid},
+		{"TamperedClaims", func(t *testing.T, env *authEnv) string {
+			// 用自己的密钥签发其他用户的令牌
+			claims := env.claims(time.Now().Add(time.Hour))
+			token, err := utils.NewHMACKeySet([]byte("attacker-secret")).Sign(claims)
+			if err != nil {
+				t.Fatalf("签发令牌失败: %v", err)
+			}
+			return token
+		}, errcode.TokenInvalid},
+		{"Expired", func(t *testing.T, env *authEnv) string {
+			return env.issue(t, time.Now().Add(-time.Minute))
+		}, errcode.TokenExpired},
+		{"LoggedOut", func(t *testing.T, env *authEnv) string {
+			token := env.issue(t, time.Now().Add(time.Hour))
+			if err := env.tokens.Revoke(token); err != nil {
+				t.Fatalf("吊销令牌失败: %v", err)
+			}
+			return token
+		}, errcode.TokenVersionError},
+		{"SessionDeleted", func(t *testing.T, env *authEnv) string {
+			token := env.issue(t, time.Now().Add(time.Hour))
+			if err := env.sessions.DeleteByUser(env.user.ID); err != nil {
+				t.Fatalf("删除会话失败: %v", err)
+			}
+			return token
+		}, errcode.TokenVersionError},
+		{"TokenVersionBumped", func(t *testing.T, env *authEnv) string {
+			token := env.issue(t, time.Now().Add(time.Hour))
+			if err := env.users.UpdatePassword(env.user.ID, "new-hash"); err != nil {
+				t.Fatalf("修改密码失败: %v", err)
+			}
+			return token
+		}, errcode.TokenVersionError},
+		{"AlgNone", func(t *testing.T, env *authEnv) string {
+			token, err := jwt.NewWithClaims(jwt.SigningMethodNone, env.claims(time.Now().Add(time.Hour))).
+				SignedString(jwt.UnsafeAllowNoneSignatureType)
+			if err != nil {
+				t.Fatalf("签发令牌失败: %v", err)
+			}
+			return token
+		}, errcode.TokenInvalid},
+		{"WrongAlg", func(t *testing.T, env *authEnv) string {
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS512, env.claims(time.Now().Add(time.Hour))).
+				SignedString([]byte(testSecret))
+			if err != nil {
+				t.Fatalf("签发令牌失败: %v", err)
+			}
+			return token
+		}, errcode.TokenInvalid},
+		{"Malformed", func(t *testing.T, env *authEnv) string {
+			return "not-a-jwt"
+		}, errcode.TokenInvalid},
+		{"UserDisabled", func(t *testing.T, env *authEnv) string {
+			token := env.issue(t, time.Now().Add(time.Hour))
+			now := time.Now()
+			if err := env.users.SetDisabled(env.user.ID, &now); err != nil {
+				t.Fatalf("禁用用户失败: %v", err)
+			}
+			return token
+		}, errcode.UserDisabled},
+		{"UserDeleted", func(t *testing.T, env *authEnv) string {
+			token := env.issue(t, time.Now().Add(time.Hour))
+			if err := env.users.Delete(env.user.ID); err != nil {
+				t.Fatalf("删除用户失败: %v", err)
+			}
+			return token
+		}, errcode.UserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newAuthEnv(t)
+			token := tt.token(t, env)
+
+			entries := []struct {
+				name    string
+				handler gin.HandlerFunc
+				request *http.Request
+			}{
+				{"HTTP", AuthMiddleware(env.tokens, env.apiKeys), env.request("/api/users/me", "Bearer "+token)},
+				{"WebSocketQuery", JWT(env.tokens), env.request("/api/ws?token="+url.QueryEscape(token), "")},
+				{"WebSocketHeader", JWT(env.tokens), env.request("/api/ws", "Bearer "+token)},
+			}
+			for _, entry := range entries {
+				reached, resp := serve(t, entry.handler, entry.request)
+				if tt.want == nil {
+					if !reached {
+						t.Fatalf("%s: 有效令牌被拒绝，响应: %d %s", entry.name, resp.Code, resp.Message)
+					}
+					continue
+				}
+				if reached {
+					t.Fatalf("%s: 无效令牌通过了认证", entry.name)
+				}
+				if resp.Code != tt.want.Code {
+					t.Fatalf("%s: 响应 %d %s, want %d", entry.name, resp.Code, resp.Message, tt.want.Code)
+				}
+			}
+		})
+	}
+}
+
+// authEnv 内存仓储上的令牌服务，以及一个已登录的用户
+type authEnv struct {
+	users    repository.UserRepository
+	sessions repository.SessionRepository
+	tokens   services.TokenService
+	apiKeys  *services.APIKeyService
+	user     *models.User
+	session  *models.UserToken
+}
+
+func newAuthEnv(t *testing.T) *authEnv {
+	t.Helper()
+	users := repository.NewMemoryUserRepository()
+	sessions := repository.NewMemorySessionRepository()
+	tokens, err := services.NewTokenService(users, sessions, config.JWTConfig{Algorithm: config.JWTAlgorithmHS256, Secret: testSecret})
+	if err != nil {
+		t.Fatalf("创建令牌服务失败: %v", err)
+	}
+
+	user := &models.User{Username: "alice", Email: "alice@example.com", Password: "hash"}
+	if err := users.Create(user); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	now := time.Now()
+	session := &models.UserToken{Token: "session-1", Platform: "web", LastActiveAt: now, ExpiredAt: now.Add(24 * time.Hour)}
+	refresh := &models.RefreshToken{TokenHash: utils.HashToken("refresh-1"), ExpiredAt: session.ExpiredAt}
+	session.UserID = user.ID
+	if err := sessions.Create(session, refresh, repository.SessionReplace{}); err != nil {
+		t.Fatalf("创建会话失败: %v", err)
+	}
+
+	return &authEnv{
+		users:    users,
+		sessions: sessions,
+		tokens:   tokens,
+		apiKeys:  services.NewAPIKeyService(users, repository.NewMemoryAPIKeyRepository(), config.Default().APIKey),
+		user:     user,
+		session:  session,
+	}
+}
+
+func (env *authEnv) claims(expiresAt time.Time) *utils.Claims {
+	return &utils.Claims{
+		UserID:       env.user.ID,
+		TokenVersion: env.user.TokenVersion,
+		SessionID:    env.session.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        env.session.Token,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+}
+
+// issue 通过令牌服务为已登录的会话签发访问令牌
+func (env *authEnv) issue(t *testing.T, expiresAt time.Time) string {
+	t.Helper()
+	token, err := env.tokens.Issue(env.session, env.user.TokenVersion, expiresAt)
+	if err != nil {
+		t.Fatalf("签发令牌失败: %v", err)
+	}
+	return token
+}
+
+func (env *authEnv) request(target, authorization string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return req
+}
+
+// serve 经过认证中间件处理请求，返回是否到达了后续的处理函数以及被拒绝时的响应
+func serve(t *testing.T, auth gin.HandlerFunc, req *http.Request) (bool, models.Response) {
+	t.Helper()
+	reached := false
+	r := gin.New()
+	r.GET("/*path", auth, func(c *gin.Context) {
+		reached = true
+		c.JSON(http.StatusOK, models.NewSuccess(nil, "ok"))
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp models.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v, body: %s", err, w.Body.String())
+	}
+	return reached, resp
+}
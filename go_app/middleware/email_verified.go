@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireVerifiedEmail 邮箱未验证的用户不能访问配置中的受限接口（email_verification.restricted），
+// 需在 AuthMiddleware 之后使用。只有请求命中受限接口时才查询用户
+func RequireVerifiedEmail(verifier *services.EmailVerificationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !verifier.Restricted(c.Request.Method, c.FullPath()) {
+			c.Next()
+			return
+		}
+		ok, err := verifier.IsVerified(c.GetUint("userId"))
+		if err != nil {
+			c.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+			c.Abort()
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusOK, models.NewError(errcode.EmailNotVerified))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
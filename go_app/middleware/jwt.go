@@ -3,46 +3,37 @@ package middleware
 import (
 	"go_app/models"
 	"go_app/pkg/errcode"
+	"go_app/services"
 	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-func JWT() gin.HandlerFunc {
+// JWT WebSocket 握手认证，浏览器无法自定义握手请求头，因此优先从 query 参数读取 token
+func JWT(tokens services.TokenService) gin.HandlerFunc {
     return func(c *gin.Context) {
-        // 打印请求信息
-        log.Printf("请求路径: %s", c.Request.URL.Path)
-        log.Printf("请求方法: %s", c.Request.Method)
-        
-        // 打印所有请求头
-        for k, v := range c.Request.Header {
-            log.Printf("Header: %s = %v", k, v)
-        }
-
         token := c.Query("token")
         if token == "" {
             token = c.GetHeader("Authorization")
-            log.Printf("从 Header 获取到的 token: %s", token)
         }
         
         if token == "" {
-            log.Printf("未找到 token，请检查 Authorization header 是否正确设置")
-            c.JSON(http.StatusOK, models.NewError(errcode.Unauthorized))
+            log.Printf("WebSocket 握手未携带 token: %s", c.ClientIP())
+            c.JSON(http.StatusOK, models.NewError(errcode.TokenMissing))
             c.Abort()
             return
         }
 
-        userID, err := models.ParseToken(token)
+        claims, err := tokens.Parse(token)
         if err != nil {
-            log.Printf("token 解析失败，错误: %v\ntoken 值: %s", err, token)
-            c.JSON(http.StatusOK, models.NewError(errcode.Unauthorized))
-            c.Abort()
+            log.Printf("WebSocket token 校验失败: %v", err)
+            abortWithTokenError(c, err)
             return
         }
 
-        log.Printf("token 验证成功，用户 ID: %d", userID)
-        c.Set("userId", userID)
+        c.Set("userId", claims.UserID)
+        c.Set("sessionId", claims.SessionID)
         c.Next()
     }
-}
\ No newline at end of file
+}
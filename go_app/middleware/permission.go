@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission 要求当前用户拥有指定权限，使用 API Key 认证时 API Key 还必须授予该权限，需在 AuthMiddleware 之后使用。
+// 只能用于不区分数据归属的接口，操作单个用户数据的接口在控制器中通过 RoleService.Authorize 校验
+func RequirePermission(roles *services.RoleService, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !AllowedByAPIKey(c, permission) {
+			c.JSON(http.StatusOK, models.NewError(errcode.APIKeyScopeDenied))
+			c.Abort()
+			return
+		}
+		ok, err := roles.HasPermission(c.GetUint("userId"), permission)
+		if err != nil {
+			c.JSON(http.StatusOK, models.NewError(errcode.ServerError))
+			c.Abort()
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusOK, models.NewError(errcode.Forbidden))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
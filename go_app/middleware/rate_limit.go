@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"fmt"
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/services"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit 按 rate_limit 配置对接口限流，并通过 RateLimit-* 响应头返回配额信息，
+// 超出限制时返回 errcode.TooManyRequests 和 Retry-After 响应头。
+// 需要按用户限流的路由组应在 AuthMiddleware 之后使用，否则按 IP 计数
+func RateLimit(limiter *services.RateLimitService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		decision := limiter.Allow(c.Request.Method, c.FullPath(), services.RateLimitClient{
+			IP:     c.ClientIP(),
+			UserID: c.GetUint("userId"),
+			APIKey: c.GetHeader(APIKeyHeader),
+		})
+		if decision == nil {
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(decision.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		c.Header("RateLimit-Reset", seconds(decision.Reset))
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", decision.Limit, int(decision.Window.Seconds())))
+		if !decision.Allowed {
+			c.Header("Retry-After", seconds(decision.RetryAfter))
+			c.JSON(http.StatusOK, models.NewError(errcode.TooManyRequests))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// seconds 向上取整的秒数
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,97 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 初始表结构。迁移中使用表结构快照而不是 models 中的结构体，
+// 保证之后修改模型不会改变已发布迁移的行为。
+// 使用 AutoMigrate 创建，之前通过 AutoMigrate 建好表的数据库可以直接执行此迁移完成接入。
+func init() {
+	type user struct {
+		gorm.Model
+		Username     string `gorm:"size:50;not null"`
+		Email        string `gorm:"size:100;unique;not null"`
+		Password     string `gorm:"size:255;not null"`
+		AvatarURL    string `gorm:"size:255"`
+		Birthday     *time.Time
+		Gender       string `gorm:"size:10"`
+		Hobbies      string `gorm:"size:500"`
+		TokenVersion int    `gorm:"default:0"`
+	}
+	type userToken struct {
+		ID           uint   `gorm:"primarykey"`
+		UserID       uint   `gorm:"not null;index"`
+		Token        string `gorm:"type:varchar(255);not null;uniqueIndex"`
+		Platform     string `gorm:"type:varchar(20);not null"`
+		DeviceID     string `gorm:"type:varchar(100)"`
+		UserAgent    string `gorm:"type:varchar(255)"`
+		IP           string `gorm:"type:varchar(45)"`
+		LastActiveAt time.Time
+		ExpiredAt    time.Time `gorm:"not null"`
+		CreatedAt    time.Time
+		UpdatedAt    time.Time
+	}
+	type refreshToken struct {
+		ID           uint      `gorm:"primarykey"`
+		UserID       uint      `gorm:"not null;index"`
+		SessionID    uint      `gorm:"not null;index"`
+		TokenHash    string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+		TokenVersion int       `gorm:"not null"`
+		ExpiredAt    time.Time `gorm:"not null"`
+		UsedAt       *time.Time
+		CreatedAt    time.Time
+	}
+	type activity struct {
+		ID          uint `gorm:"primaryKey"`
+		Title       string
+		Description string
+		StartTime   time.Time
+		EndTime     time.Time
+		CreatedAt   time.Time
+		UpdatedAt   time.Time
+	}
+	type notification struct {
+		ID        uint `gorm:"primaryKey"`
+		UserID    uint
+		Title     string
+		Content   string
+		Read      bool `gorm:"default:false"`
+		CreatedAt time.Time
+		UpdatedAt time.Time
+	}
+
+	tables := []struct {
+		name  string
+		model interface{}
+	}{
+		{"users", &user{}},
+		{"user_tokens", &userToken{}},
+		{"refresh_tokens", &refreshToken{}},
+		{"activities", &activity{}},
+		{"notifications", &notification{}},
+	}
+
+	register(&Migration{
+		Version: 1,
+		Name:    "create_initial_tables",
+		Up: func(tx *gorm.DB) error {
+			for _, t := range tables {
+				if err := tx.Table(t.name).AutoMigrate(t.model); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for i := len(tables) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropTable(tables[i].name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
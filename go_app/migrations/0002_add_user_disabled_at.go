@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 用户禁用状态，被禁用的账号不能登录，已签发的令牌同时失效
+func init() {
+	type user struct {
+		DisabledAt *time.Time `gorm:"index"`
+	}
+
+	register(&Migration{
+		Version: 2,
+		Name:    "add_user_disabled_at",
+		Up: func(tx *gorm.DB) error {
+			// 开发环境开启 auto_migrate 时字段可能已存在
+			m := tx.Migrator()
+			if !m.HasColumn(&user{}, "DisabledAt") {
+				if err := m.AddColumn(&user{}, "DisabledAt"); err != nil {
+					return err
+				}
+			}
+			if m.HasIndex(&user{}, "DisabledAt") {
+				return nil
+			}
+			return m.CreateIndex(&user{}, "DisabledAt")
+		},
+		Down: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if err := m.DropIndex(&user{}, "DisabledAt"); err != nil {
+				return err
+			}
+			return m.DropColumn(&user{}, "DisabledAt")
+		},
+	})
+}
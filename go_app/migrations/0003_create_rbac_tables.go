@@ -0,0 +1,98 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 角色与权限：创建 roles、role_permissions、user_roles 表，写入内置角色，
+// 并为已有用户分配默认的 user 角色。第一个管理员需要通过 go_app user grant-role 指定
+func init() {
+	type role struct {
+		ID          uint   `gorm:"primaryKey"`
+		Name        string `gorm:"size:50;uniqueIndex;not null"`
+		Description string `gorm:"size:255"`
+		CreatedAt   time.Time
+	}
+	type rolePermission struct {
+		RoleID     uint   `gorm:"primaryKey"`
+		Permission string `gorm:"size:100;primaryKey"`
+	}
+	type userRole struct {
+		UserID    uint `gorm:"primaryKey"`
+		RoleID    uint `gorm:"primaryKey;index"`
+		CreatedAt time.Time
+	}
+
+	tables := []struct {
+		name  string
+		model interface{}
+	}{
+		{"roles", &role{}},
+		{"role_permissions", &rolePermission{}},
+		{"user_roles", &userRole{}},
+	}
+
+	builtin := []struct {
+		name        string
+		description string
+		permissions []string
+	}{
+		{"admin", "管理员，可以管理全部用户和角色", []string{"users:list", "users:read", "users:update", "users:delete", "roles:manage"}},
+		{"moderator", "协管员，可以查看全部用户", []string{"users:list", "users:read"}},
+		{"user", "普通用户，只能查看和修改自己的数据", nil},
+	}
+
+	register(&Migration{
+		Version: 3,
+		Name:    "create_rbac_tables",
+		Up: func(tx *gorm.DB) error {
+			for _, t := range tables {
+				if err := tx.Table(t.name).AutoMigrate(t.model); err != nil {
+					return err
+				}
+			}
+
+			now := time.Now()
+			for _, b := range builtin {
+				// 开发环境开启 auto_migrate 时表可能已存在，已有的角色不再重复写入
+				var count int64
+				if err := tx.Table("roles").Where("name = ?", b.name).Count(&count).Error; err != nil {
+					return err
+				}
+				if count > 0 {
+					continue
+				}
+				r := role{Name: b.name, Description: b.description, CreatedAt: now}
+				if err := tx.Table("roles").Create(&r).Error; err != nil {
+					return err
+				}
+				for _, p := range b.permissions {
+					if err := tx.Table("role_permissions").Create(&rolePermission{RoleID: r.ID, Permission: p}).Error; err != nil {
+						return err
+					}
+				}
+			}
+
+			var defaultRole role
+			if err := tx.Table("roles").Where("name = ?", "user").First(&defaultRole).Error; err != nil {
+				return err
+			}
+			return tx.Exec(
+				"INSERT INTO user_roles (user_id, role_id, created_at) "+
+					"SELECT id, ?, ? FROM users WHERE deleted_at IS NULL "+
+					"AND id NOT IN (SELECT user_id FROM user_roles WHERE role_id = ?)",
+				defaultRole.ID, now, defaultRole.ID,
+			).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			for i := len(tables) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropTable(tables[i].name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 找回密码令牌
+func init() {
+	type passwordResetToken struct {
+		ID        uint      `gorm:"primarykey"`
+		UserID    uint      `gorm:"not null;index"`
+		TokenHash string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+		ExpiredAt time.Time `gorm:"not null"`
+		UsedAt    *time.Time
+		CreatedAt time.Time
+	}
+
+	register(&Migration{
+		Version: 4,
+		Name:    "create_password_reset_tokens",
+		Up: func(tx *gorm.DB) error {
+			return tx.Table("password_reset_tokens").AutoMigrate(&passwordResetToken{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("password_reset_tokens")
+		},
+	})
+}
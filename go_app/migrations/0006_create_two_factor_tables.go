@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TOTP 两步验证：验证器密钥、恢复码和两步登录的挑战令牌
+func init() {
+	type twoFactor struct {
+		UserID       uint   `gorm:"primarykey;autoIncrement:false"`
+		Secret       string `gorm:"size:64;not null"`
+		ConfirmedAt  *time.Time
+		LastUsedStep int64 `gorm:"not null;default:0"`
+		CreatedAt    time.Time
+		UpdatedAt    time.Time
+	}
+	type twoFactorRecoveryCode struct {
+		ID        uint   `gorm:"primarykey"`
+		UserID    uint   `gorm:"not null;uniqueIndex:idx_two_factor_recovery_codes_user_code"`
+		CodeHash  string `gorm:"type:varchar(64);not null;uniqueIndex:idx_two_factor_recovery_codes_user_code"`
+		UsedAt    *time.Time
+		CreatedAt time.Time
+	}
+	type twoFactorChallenge struct {
+		ID        uint      `gorm:"primarykey"`
+		UserID    uint      `gorm:"not null;index"`
+		TokenHash string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+		Platform  string    `gorm:"type:varchar(20)"`
+		DeviceID  string    `gorm:"type:varchar(100)"`
+		Attempts  int       `gorm:"not null;default:0"`
+		ExpiredAt time.Time `gorm:"not null"`
+		UsedAt    *time.Time
+		CreatedAt time.Time
+	}
+
+	register(&Migration{
+		Version: 6,
+		Name:    "create_two_factor_tables",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Table("two_factors").AutoMigrate(&twoFactor{}); err != nil {
+				return err
+			}
+			if err := tx.Table("two_factor_recovery_codes").AutoMigrate(&twoFactorRecoveryCode{}); err != nil {
+				return err
+			}
+			return tx.Table("two_factor_challenges").AutoMigrate(&twoFactorChallenge{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("two_factor_challenges", "two_factor_recovery_codes", "two_factors")
+		},
+	})
+}
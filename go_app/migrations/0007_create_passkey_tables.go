@@ -0,0 +1,52 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 通行密钥（WebAuthn）：用户凭证和注册/登录流程
+func init() {
+	type passkey struct {
+		ID              uint   `gorm:"primarykey"`
+		UserID          uint   `gorm:"not null;index"`
+		CredentialID    string `gorm:"type:varchar(255);not null;uniqueIndex"`
+		PublicKey       []byte `gorm:"not null"`
+		AttestationType string `gorm:"type:varchar(32)"`
+		Transports      string `gorm:"type:varchar(100)"`
+		AAGUID          []byte
+		SignCount       uint32 `gorm:"not null;default:0"`
+		UserVerified    bool   `gorm:"not null;default:false"`
+		BackupEligible  bool   `gorm:"not null;default:false"`
+		BackupState     bool   `gorm:"not null;default:false"`
+		Name            string `gorm:"type:varchar(100)"`
+		LastUsedAt      *time.Time
+		CreatedAt       time.Time
+		UpdatedAt       time.Time
+	}
+	type passkeyCeremony struct {
+		ID        uint      `gorm:"primarykey"`
+		UserID    uint      `gorm:"not null;default:0;index"`
+		TokenHash string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+		Type      string    `gorm:"type:varchar(20);not null"`
+		Data      string    `gorm:"type:text;not null"`
+		ExpiredAt time.Time `gorm:"not null;index"`
+		UsedAt    *time.Time
+		CreatedAt time.Time
+	}
+
+	register(&Migration{
+		Version: 7,
+		Name:    "create_passkey_tables",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Table("passkeys").AutoMigrate(&passkey{}); err != nil {
+				return err
+			}
+			return tx.Table("passkey_ceremonies").AutoMigrate(&passkeyCeremony{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("passkey_ceremonies", "passkeys")
+		},
+	})
+}
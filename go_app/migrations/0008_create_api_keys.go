@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 用户的个人 API Key
+func init() {
+	type apiKey struct {
+		ID         uint   `gorm:"primarykey"`
+		UserID     uint   `gorm:"not null;index"`
+		Name       string `gorm:"type:varchar(100);not null"`
+		Prefix     string `gorm:"type:varchar(20);not null"`
+		KeyHash    string `gorm:"type:varchar(64);not null;uniqueIndex"`
+		Scopes     string `gorm:"type:varchar(255);not null"`
+		ExpiresAt  *time.Time
+		LastUsedAt *time.Time
+		LastUsedIP string `gorm:"type:varchar(45)"`
+		CreatedAt  time.Time
+		UpdatedAt  time.Time
+	}
+
+	register(&Migration{
+		Version: 8,
+		Name:    "create_api_keys",
+		Up: func(tx *gorm.DB) error {
+			return tx.Table("api_keys").AutoMigrate(&apiKey{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("api_keys")
+		},
+	})
+}
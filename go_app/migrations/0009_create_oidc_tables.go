@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 第三方（OIDC）登录：关联账号和登录流程
+func init() {
+	type userIdentity struct {
+		ID          uint   `gorm:"primarykey"`
+		UserID      uint   `gorm:"not null;index"`
+		Provider    string `gorm:"type:varchar(50);not null;uniqueIndex:idx_user_identities_subject"`
+		Subject     string `gorm:"type:varchar(255);not null;uniqueIndex:idx_user_identities_subject"`
+		Email       string `gorm:"type:varchar(100)"`
+		LastLoginAt *time.Time
+		CreatedAt   time.Time
+		UpdatedAt   time.Time
+	}
+	type oidcFlow struct {
+		ID           uint      `gorm:"primarykey"`
+		StateHash    string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+		Provider     string    `gorm:"type:varchar(50);not null"`
+		Nonce        string    `gorm:"type:varchar(100);not null"`
+		CodeVerifier string    `gorm:"type:varchar(128);not null"`
+		ExpiredAt    time.Time `gorm:"not null;index"`
+		UsedAt       *time.Time
+		CreatedAt    time.Time
+	}
+
+	register(&Migration{
+		Version: 9,
+		Name:    "create_oidc_tables",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Table("user_identities").AutoMigrate(&userIdentity{}); err != nil {
+				return err
+			}
+			return tx.Table("oidc_flows").AutoMigrate(&oidcFlow{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("oidc_flows", "user_identities")
+		},
+	})
+}
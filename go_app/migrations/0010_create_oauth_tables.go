@@ -0,0 +1,97 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OAuth 授权服务：客户端、授权同意记录、授权码和令牌，并为 admin 角色授予 oauth:manage 权限
+func init() {
+	type oauthClient struct {
+		ID           uint   `gorm:"primarykey"`
+		ClientID     string `gorm:"type:varchar(64);not null;uniqueIndex"`
+		SecretHash   string `gorm:"type:varchar(64)"`
+		Name         string `gorm:"type:varchar(100);not null"`
+		RedirectURIs string `gorm:"type:varchar(2000);not null"`
+		Scopes       string `gorm:"type:varchar(500);not null"`
+		GrantTypes   string `gorm:"type:varchar(100);not null"`
+		CreatedBy    uint
+		CreatedAt    time.Time
+		UpdatedAt    time.Time
+	}
+	type oauthConsent struct {
+		ID        uint   `gorm:"primarykey"`
+		UserID    uint   `gorm:"not null;uniqueIndex:idx_oauth_consents_user_client"`
+		ClientID  string `gorm:"type:varchar(64);not null;uniqueIndex:idx_oauth_consents_user_client;index"`
+		Scopes    string `gorm:"type:varchar(500);not null"`
+		CreatedAt time.Time
+		UpdatedAt time.Time
+	}
+	type oauthAuthorizationCode struct {
+		ID            uint      `gorm:"primarykey"`
+		CodeHash      string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+		ClientID      string    `gorm:"type:varchar(64);not null"`
+		UserID        uint      `gorm:"not null"`
+		RedirectURI   string    `gorm:"type:varchar(500);not null"`
+		Scopes        string    `gorm:"type:varchar(500);not null"`
+		Nonce         string    `gorm:"type:varchar(255)"`
+		CodeChallenge string    `gorm:"type:varchar(128)"`
+		ExpiredAt     time.Time `gorm:"not null;index"`
+		UsedAt        *time.Time
+		CreatedAt     time.Time
+	}
+	type oauthToken struct {
+		ID               uint    `gorm:"primarykey"`
+		FamilyID         string  `gorm:"type:varchar(64);not null;index"`
+		ClientID         string  `gorm:"type:varchar(64);not null;index"`
+		UserID           uint    `gorm:"not null;index"`
+		Scopes           string  `gorm:"type:varchar(500);not null"`
+		AccessTokenHash  string  `gorm:"type:varchar(64);not null;uniqueIndex"`
+		RefreshTokenHash *string `gorm:"type:varchar(64);uniqueIndex"`
+		AccessExpiredAt  time.Time
+		RefreshExpiredAt *time.Time `gorm:"index"`
+		RevokedAt        *time.Time
+		CreatedAt        time.Time
+	}
+
+	tables := []struct {
+		name  string
+		model interface{}
+	}{
+		{"oauth_clients", &oauthClient{}},
+		{"oauth_consents", &oauthConsent{}},
+		{"oauth_authorization_codes", &oauthAuthorizationCode{}},
+		{"oauth_tokens", &oauthToken{}},
+	}
+	const permission = "oauth:manage"
+
+	register(&Migration{
+		Version: 10,
+		Name:    "create_oauth_tables",
+		Up: func(tx *gorm.DB) error {
+			for _, t := range tables {
+				if err := tx.Table(t.name).AutoMigrate(t.model); err != nil {
+					return err
+				}
+			}
+			return tx.Exec(
+				"INSERT INTO role_permissions (role_id, permission) "+
+					"SELECT id, ? FROM roles WHERE name = ? "+
+					"AND id NOT IN (SELECT role_id FROM role_permissions WHERE permission = ?)",
+				permission, "admin", permission,
+			).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Exec("DELETE FROM role_permissions WHERE permission = ?", permission).Error; err != nil {
+				return err
+			}
+			for i := len(tables) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropTable(tables[i].name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebSocket 发件箱：发给用户的消息和每个用户的消息序号
+func init() {
+	type websocketMessage struct {
+		ID        uint   `gorm:"primarykey"`
+		UserID    uint   `gorm:"not null;uniqueIndex:idx_websocket_messages_user_seq"`
+		Seq       uint64 `gorm:"not null;uniqueIndex:idx_websocket_messages_user_seq"`
+		Type      string `gorm:"type:varchar(50);not null"`
+		Data      string `gorm:"type:text"`
+		CreatedAt time.Time
+	}
+	type websocketCursor struct {
+		UserID    uint   `gorm:"primarykey;autoIncrement:false"`
+		LastSeq   uint64 `gorm:"not null;default:0"`
+		AckedSeq  uint64 `gorm:"not null;default:0"`
+		UpdatedAt time.Time
+	}
+
+	register(&Migration{
+		Version: 11,
+		Name:    "create_websocket_outbox",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Table("websocket_messages").AutoMigrate(&websocketMessage{}); err != nil {
+				return err
+			}
+			return tx.Table("websocket_cursors").AutoMigrate(&websocketCursor{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable("websocket_cursors"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable("websocket_messages")
+		},
+	})
+}
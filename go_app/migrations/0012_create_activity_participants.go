@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 活动参与者，用于限制活动实时主题的订阅
+func init() {
+	type activityParticipant struct {
+		ID         uint `gorm:"primarykey"`
+		ActivityID uint `gorm:"not null;uniqueIndex:idx_activity_participants_activity_user"`
+		UserID     uint `gorm:"not null;uniqueIndex:idx_activity_participants_activity_user;index"`
+		CreatedAt  time.Time
+	}
+
+	register(&Migration{
+		Version: 12,
+		Name:    "create_activity_participants",
+		Up: func(tx *gorm.DB) error {
+			return tx.Table("activity_participants").AutoMigrate(&activityParticipant{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("activity_participants")
+		},
+	})
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 用户最后在线时间，实时连接建立和断开时更新
+func init() {
+	type user struct {
+		LastSeenAt *time.Time
+	}
+
+	register(&Migration{
+		Version: 13,
+		Name:    "add_user_last_seen_at",
+		Up: func(tx *gorm.DB) error {
+			// 开发环境开启 auto_migrate 时字段可能已存在
+			m := tx.Migrator()
+			if m.HasColumn(&user{}, "LastSeenAt") {
+				return nil
+			}
+			return m.AddColumn(&user{}, "LastSeenAt")
+		},
+		Down: func(tx *gorm.DB) error {
+			// 与 0005 相同，不使用会重建表的 Migrator.DropColumn
+			return tx.Exec("ALTER TABLE users DROP COLUMN last_seen_at").Error
+		},
+	})
+}
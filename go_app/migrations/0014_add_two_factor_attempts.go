@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 两步验证的校验次数：关闭两步验证和重新生成恢复码时连续输错验证码的次数及最近一次校验的时间
+func init() {
+	type twoFactor struct {
+		Attempts    int `gorm:"not null;default:0"`
+		AttemptedAt *time.Time
+	}
+
+	register(&Migration{
+		Version: 14,
+		Name:    "add_two_factor_attempts",
+		Up: func(tx *gorm.DB) error {
+			// 开发环境开启 auto_migrate 时字段可能已存在
+			m := tx.Migrator()
+			for _, field := range []string{"Attempts", "AttemptedAt"} {
+				if m.HasColumn(&twoFactor{}, field) {
+					continue
+				}
+				if err := m.AddColumn(&twoFactor{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			// 与 0005 相同，不使用会重建表的 Migrator.DropColumn
+			for _, column := range []string{"attempted_at", "attempts"} {
+				if err := tx.Exec("ALTER TABLE two_factors DROP COLUMN " + column).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
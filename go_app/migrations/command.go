@@ -0,0 +1,94 @@
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"gorm.io/gorm"
+)
+
+const usage = `用法: go_app [-config path] migrate <命令>
+
+命令:
+  up             执行全部未执行的迁移
+  down [n]       回滚最近执行的 n 个迁移，默认为 1
+  status         查看迁移执行状态
+  create <name>  在 migrations 目录下生成新的迁移文件`
+
+// Run 执行 migrate 子命令，args 为 migrate 之后的参数，返回进程退出码
+func Run(db *gorm.DB, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	migrator := NewMigrator(db)
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, m := range applied {
+			fmt.Printf("已执行 %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("没有需要执行的迁移")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				fmt.Fprintf(os.Stderr, "回滚数量无效: %s\n", args[1])
+				return 2
+			}
+			steps = n
+		}
+		reverted, err := migrator.Down(steps)
+		for _, m := range reverted {
+			fmt.Printf("已回滚 %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(reverted) == 0 {
+			fmt.Println("没有可回滚的迁移")
+		}
+	case "status":
+		list, err := migrator.Status()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range list {
+			appliedAt := "未执行"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		w.Flush()
+	case "create":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, "请指定迁移名称，如: migrate create add_user_phone")
+			return 2
+		}
+		path, err := Create(DefaultDir, args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Println("已创建", path)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,73 @@
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// DefaultDir 迁移文件默认目录
+const DefaultDir = "migrations"
+
+var migrationNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+var migrationTemplate = template.Must(template.New("migration").Parse(`package migrations
+
+import "gorm.io/gorm"
+
+// 在迁移中定义表结构快照，不要直接引用 models 中的结构体
+func init() {
+	register(&Migration{
+		Version: {{.Version}},
+		Name:    "{{.Name}}",
+		Up: func(tx *gorm.DB) error {
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
+`))
+
+// Create 在 dir 下生成下一个版本号的迁移文件模板，返回文件路径。
+// name 只能包含小写字母、数字和下划线
+func Create(dir, name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !migrationNamePattern.MatchString(name) {
+		return "", fmt.Errorf("迁移名称 %q 无效，只能包含小写字母、数字和下划线，且以字母开头", name)
+	}
+
+	// 同时参考已注册的迁移和目录中的文件，避免与尚未编译进来的新迁移重号
+	var latest int64
+	if all := All(); len(all) > 0 {
+		latest = all[len(all)-1].Version
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "[0-9][0-9][0-9][0-9]_*.go"))
+	for _, f := range files {
+		var v int64
+		if _, err := fmt.Sscanf(filepath.Base(f), "%d_", &v); err == nil && v > latest {
+			latest = v
+		}
+	}
+	version := latest + 1
+
+	path := filepath.Join(dir, fmt.Sprintf("%04d_%s.go", version, name))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	data := struct {
+		Version int64
+		Name    string
+	}{version, name}
+	if err := migrationTemplate.Execute(file, data); err != nil {
+		return "", err
+	}
+	return path, nil
+}
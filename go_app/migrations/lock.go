@@ -0,0 +1,93 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go_app/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// 迁移锁名称，同一数据库上的所有实例共用
+const lockName = "go_app_schema_migrations"
+
+// PostgreSQL 咨询锁使用 64 位整数作为键
+const pgLockKey int64 = 0x676f5f6170705f6d // "go_app_m" 的 ASCII 编码
+
+// SQLite 锁记录超过该时间视为持有者已崩溃，可以被抢占
+const sqliteStaleLock = 10 * time.Minute
+
+// acquireLock 获取数据库级别的迁移锁，返回释放函数。
+// MySQL 使用 GET_LOCK，PostgreSQL 使用 pg_advisory_lock，二者都是会话级锁，连接断开时自动释放；
+// SQLite 没有咨询锁，使用单行锁表实现
+func acquireLock(conn *gorm.DB, timeout time.Duration) (func(), error) {
+	switch conn.Dialector.Name() {
+	case "mysql":
+		var acquired int
+		if err := conn.Raw("SELECT GET_LOCK(?, ?)", lockName, int(timeout.Seconds())).Scan(&acquired).Error; err != nil {
+			return nil, err
+		}
+		if acquired != 1 {
+			return nil, fmt.Errorf("等待迁移锁超时（%s），可能有其他实例正在执行迁移", timeout)
+		}
+		return func() {
+			if err := conn.Exec("SELECT RELEASE_LOCK(?)", lockName).Error; err != nil {
+				logger.Errorf("释放迁移锁失败: %v", err)
+			}
+		}, nil
+	case "postgres":
+		deadline := time.Now().Add(timeout)
+		for {
+			var acquired bool
+			if err := conn.Raw("SELECT pg_try_advisory_lock(?)", pgLockKey).Scan(&acquired).Error; err != nil {
+				return nil, err
+			}
+			if acquired {
+				break
+			}
+			if time.Now().After(deadline) {
+				return nil, fmt.Errorf("等待迁移锁超时（%s），可能有其他实例正在执行迁移", timeout)
+			}
+			time.Sleep(500 * time.Millisecond)
+		}
+		return func() {
+			if err := conn.Exec("SELECT pg_advisory_unlock(?)", pgLockKey).Error; err != nil {
+				logger.Errorf("释放迁移锁失败: %v", err)
+			}
+		}, nil
+	default:
+		return acquireTableLock(conn, timeout)
+	}
+}
+
+// acquireTableLock 通过向锁表插入固定主键的记录实现互斥
+func acquireTableLock(conn *gorm.DB, timeout time.Duration) (func(), error) {
+	if err := conn.Exec("CREATE TABLE IF NOT EXISTS schema_migrations_lock (id INTEGER PRIMARY KEY, locked_at DATETIME NOT NULL)").Error; err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		// 清理崩溃进程遗留的锁
+		conn.Exec("DELETE FROM schema_migrations_lock WHERE id = 1 AND locked_at < ?", time.Now().Add(-sqliteStaleLock))
+
+		result := conn.Exec("INSERT INTO schema_migrations_lock (id, locked_at) VALUES (1, ?) ON CONFLICT (id) DO NOTHING", time.Now())
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			return func() {
+				if err := conn.Exec("DELETE FROM schema_migrations_lock WHERE id = 1").Error; err != nil {
+					logger.Errorf("释放迁移锁失败: %v", err)
+				}
+			}, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.New("等待迁移锁超时，可能有其他实例正在执行迁移；如确认没有，可删除 schema_migrations_lock 表中的记录")
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
@@ -0,0 +1,226 @@
+// Package migrations 版本化、可回滚的数据库结构迁移。
+//
+// 每个迁移是一个独立的 Go 文件（NNNN_name.go），在 init 中调用 register 注册 Up/Down 函数。
+// 已执行的迁移记录在 schema_migrations 表中，执行期间持有数据库级别的锁，
+// 多个实例同时启动时只有一个会真正执行迁移。
+package migrations
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration 单个迁移
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration schema_migrations 表记录
+type SchemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:255;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status 迁移状态
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"appliedAt"` // 为空表示未执行
+}
+
+var registry = map[int64]*Migration{}
+
+// register 注册迁移，版本号重复时直接 panic，避免合并分支时静默覆盖
+func register(m *Migration) {
+	if _, exists := registry[m.Version]; exists {
+		panic(fmt.Sprintf("迁移版本号重复: %d", m.Version))
+	}
+	registry[m.Version] = m
+}
+
+// All 返回按版本号升序排列的全部迁移
+func All() []*Migration {
+	list := make([]*Migration, 0, len(registry))
+	for _, m := range registry {
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list
+}
+
+// Migrator 迁移执行器
+type Migrator struct {
+	db          *gorm.DB
+	lockTimeout time.Duration
+}
+
+func NewMigrator(db *gorm.DB) *Migrator {
+	return &Migrator{db: db, lockTimeout: time.Minute}
+}
+
+// Up 执行全部未执行的迁移，返回本次执行的迁移
+func (m *Migrator) Up() ([]*Migration, error) {
+	var applied []*Migration
+	err := m.withLock(func(conn *gorm.DB) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range All() {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if err := run(conn, migration, true); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down 按版本号倒序回滚最近执行的 steps 个迁移，返回本次回滚的迁移
+func (m *Migrator) Down(steps int) ([]*Migration, error) {
+	var reverted []*Migration
+	err := m.withLock(func(conn *gorm.DB) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		all := All()
+		for i := len(all) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := all[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == nil {
+				return fmt.Errorf("迁移 %04d_%s 不支持回滚", migration.Version, migration.Name)
+			}
+			if err := run(conn, migration, false); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status 返回全部迁移的执行状态，包括数据库中存在但代码中已不存在的迁移
+func (m *Migrator) Status() ([]Status, error) {
+	done := map[int64]SchemaMigration{}
+	if m.db.Migrator().HasTable(&SchemaMigration{}) {
+		var err error
+		if done, err = appliedVersions(m.db); err != nil {
+			return nil, err
+		}
+	}
+
+	var list []Status
+	for _, migration := range All() {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if record, ok := done[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.AppliedAt = &appliedAt
+			delete(done, migration.Version)
+		}
+		list = append(list, status)
+	}
+	for _, record := range done {
+		appliedAt := record.AppliedAt
+		list = append(list, Status{Version: record.Version, Name: record.Name + "（代码中不存在）", AppliedAt: &appliedAt})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// Pending 返回未执行的迁移数量
+func (m *Migrator) Pending() (int, error) {
+	list, err := m.Status()
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, status := range list {
+		if status.AppliedAt == nil {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// withLock 在同一个数据库连接上加锁后执行 fn，会话级锁必须在同一连接上加锁和释放
+func (m *Migrator) withLock(fn func(conn *gorm.DB) error) error {
+	return m.db.Connection(func(conn *gorm.DB) error {
+		// Connection 返回的实例会在链式调用间共享查询条件（如表名），
+		// 开启新会话使每次调用都从干净的语句开始，同时保留固定的连接
+		conn = conn.Session(&gorm.Session{NewDB: true})
+
+		unlock, err := acquireLock(conn, m.lockTimeout)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		if err := ensureTable(conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+// run 在事务中执行单个迁移并更新 schema_migrations
+func run(conn *gorm.DB, migration *Migration, up bool) error {
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if up {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		}
+		if err := migration.Down(tx); err != nil {
+			return err
+		}
+		return tx.Delete(&SchemaMigration{}, migration.Version).Error
+	})
+	if err != nil {
+		direction := "执行"
+		if !up {
+			direction = "回滚"
+		}
+		return fmt.Errorf("%s迁移 %04d_%s 失败: %v", direction, migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+func ensureTable(db *gorm.DB) error {
+	return db.Migrator().AutoMigrate(&SchemaMigration{})
+}
+
+func appliedVersions(db *gorm.DB) (map[int64]SchemaMigration, error) {
+	var records []SchemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	done := make(map[int64]SchemaMigration, len(records))
+	for _, record := range records {
+		done[record.Version] = record
+	}
+	return done, nil
+}
@@ -0,0 +1,151 @@
+package migrations
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestUpDownUp 全部迁移可以执行、全部回滚后再次执行，回滚后只剩下迁移记录表和 SQLite 的内部表
+func TestUpDownUp(t *testing.T) {
+	db := openSQLite(t, filepath.Join(t.TempDir(), "app.db"))
+	m := NewMigrator(db)
+	all := All()
+
+	expectApplied(t, m, all, nil)
+	expectPending(t, m, 0)
+	if !db.Migrator().HasTable("users") {
+		t.Fatal("执行迁移后 users 表不存在")
+	}
+	expectApplied(t, m, nil, nil)
+
+	// 回滚最近两个迁移后再执行
+	reverted, err := m.Down(2)
+	if err != nil {
+		t.Fatalf("回滚失败: %v", err)
+	}
+	if len(reverted) != 2 || reverted[0] != all[len(all)-1] || reverted[1] != all[len(all)-2] {
+		t.Fatalf("回滚了 %v, want 最近的两个迁移", names(reverted))
+	}
+	expectPending(t, m, 2)
+	expectApplied(t, m, all[len(all)-2:], nil)
+
+	reverted, err = m.Down(len(all) + 1)
+	if err != nil {
+		t.Fatalf("全部回滚失败: %v", err)
+	}
+	if len(reverted) != len(all) {
+		t.Fatalf("回滚了 %d 个迁移, want %d", len(reverted), len(all))
+	}
+	expectPending(t, m, len(all))
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range tables {
+		switch table {
+		case "schema_migrations", "schema_migrations_lock", "sqlite_sequence":
+		default:
+			t.Errorf("全部回滚后仍存在表 %s", table)
+		}
+	}
+
+	expectApplied(t, m, all, nil)
+	expectPending(t, m, 0)
+}
+
+// TestLock 迁移期间持有锁，其他实例等待超时后返回错误，锁释放后可以继续执行
+func TestLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.db")
+	holder := openSQLite(t, path)
+	waiter := NewMigrator(openSQLite(t, path))
+	waiter.lockTimeout = 300 * time.Millisecond
+
+	unlock, err := acquireLock(holder, time.Second)
+	if err != nil {
+		t.Fatalf("获取迁移锁失败: %v", err)
+	}
+	start := time.Now()
+	expectApplied(t, waiter, nil, func(err error) bool { return strings.Contains(err.Error(), "等待迁移锁超时") })
+	if elapsed := time.Since(start); elapsed < waiter.lockTimeout {
+		t.Fatalf("未等待迁移锁就返回: %s", elapsed)
+	}
+	expectPending(t, waiter, len(All()))
+
+	unlock()
+
+	// 等待期间锁被释放时继续执行
+	unlock, err = acquireLock(holder, time.Second)
+	if err != nil {
+		t.Fatalf("获取迁移锁失败: %v", err)
+	}
+	time.AfterFunc(200*time.Millisecond, unlock)
+	waiter.lockTimeout = 5 * time.Second
+	expectApplied(t, waiter, All(), nil)
+
+	// 崩溃进程遗留的过期锁可以被抢占
+	if err := holder.Exec("INSERT INTO schema_migrations_lock (id, locked_at) VALUES (1, ?)", time.Now().Add(-2*sqliteStaleLock)).Error; err != nil {
+		t.Fatal(err)
+	}
+	expectApplied(t, waiter, nil, nil)
+}
+
+// openSQLite 打开 SQLite 数据库文件，同一文件可以打开多次模拟多个实例
+func openSQLite(t *testing.T, path string) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(path+"?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"), &gorm.Config{
+		TranslateError: true,
+		Logger:         logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("打开 SQLite 失败: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// expectApplied 执行 Up 并校验本次执行的迁移，isErr 不为空时期望返回满足条件的错误
+func expectApplied(t *testing.T, m *Migrator, want []*Migration, isErr func(error) bool) {
+	t.Helper()
+	applied, err := m.Up()
+	if isErr != nil {
+		if err == nil || !isErr(err) {
+			t.Fatalf("执行迁移的错误 = %v", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("执行迁移失败: %v", err)
+	}
+	if strings.Join(names(applied), ",") != strings.Join(names(want), ",") {
+		t.Fatalf("执行了 %v, want %v", names(applied), names(want))
+	}
+}
+
+func expectPending(t *testing.T, m *Migrator, want int) {
+	t.Helper()
+	pending, err := m.Pending()
+	if err != nil {
+		t.Fatalf("获取迁移状态失败: %v", err)
+	}
+	if pending != want {
+		t.Fatalf("未执行的迁移 = %d, want %d", pending, want)
+	}
+}
+
+func names(list []*Migration) []string {
+	out := make([]string, len(list))
+	for i, m := range list {
+		out[i] = m.Name
+	}
+	return out
+}
@@ -10,4 +10,12 @@ type Activity struct {
     EndTime     time.Time `json:"endTime"`
     CreatedAt   time.Time `json:"createdAt"`
     UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// ActivityParticipant 活动参与者，只有参与者可以订阅活动的实时主题 activity:{id}
+type ActivityParticipant struct {
+    ID         uint      `json:"id" gorm:"primaryKey"`
+    ActivityID uint      `json:"activityId" gorm:"not null;uniqueIndex:idx_activity_participants_activity_user"`
+    UserID     uint      `json:"userId" gorm:"not null;uniqueIndex:idx_activity_participants_activity_user;index"`
+    CreatedAt  time.Time `json:"createdAt"`
 }
\ No newline at end of file
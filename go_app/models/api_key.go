@@ -0,0 +1,59 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// API Key 的基础授权范围，除此之外还可以授予权限标识（如 users:list），
+// 用于访问需要对应权限的接口，实际生效的权限是用户权限与授权范围的交集
+const (
+	APIKeyScopeRead  = "read"  // 调用 GET 接口
+	APIKeyScopeWrite = "write" // 调用其他方法的接口，包含 read
+)
+
+// APIKey 用户创建的个人 API Key，供脚本和第三方集成代替密码登录调用接口。
+// 明文只在创建时返回一次，数据库只保存哈希值
+type APIKey struct {
+	ID     uint   `gorm:"primarykey"`
+	UserID uint   `gorm:"not null;index"`
+	Name   string `gorm:"type:varchar(100);not null"`
+	// Prefix 明文开头的可见部分，便于在列表中辨认
+	Prefix  string `gorm:"type:varchar(20);not null"`
+	KeyHash string `gorm:"type:varchar(64);not null;uniqueIndex"`
+	// Scopes 授权范围，逗号分隔
+	Scopes string `gorm:"type:varchar(255);not null"`
+	// ExpiresAt 过期时间，为空时永不过期
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	LastUsedIP string `gorm:"type:varchar(45)"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (k *APIKey) IsExpired() bool {
+	return k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt)
+}
+
+// ScopeList 授权范围列表
+func (k *APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+// HasScope 是否授予了指定范围，write 包含 read
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.ScopeList() {
+		if s == scope || (scope == APIKeyScopeRead && s == APIKeyScopeWrite) {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyScopes 可以授予 API Key 的全部授权范围
+func APIKeyScopes() []string {
+	return append([]string{APIKeyScopeRead, APIKeyScopeWrite}, AllPermissions()...)
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// EmailVerificationToken 邮箱验证令牌，数据库只保存令牌的哈希值，使用一次后失效。
+// Email 为令牌要验证的地址：注册时为当前邮箱，修改邮箱时为待验证的新邮箱
+type EmailVerificationToken struct {
+	ID        uint      `gorm:"primarykey"`
+	UserID    uint      `gorm:"not null;index"`
+	Email     string    `gorm:"size:100;not null"`
+	TokenHash string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiredAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (t *EmailVerificationToken) IsExpired() bool {
+	return time.Now().After(t.ExpiredAt)
+}
+
+func (t *EmailVerificationToken) IsUsed() bool {
+	return t.UsedAt != nil
+}
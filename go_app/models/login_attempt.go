@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// LoginAttempt 登录失败记录，按账号或客户端 IP 统计，只保存在限流存储中，不落库
+type LoginAttempt struct {
+	// Failures 统计窗口内的失败次数，包括正在校验密码的尝试；登录成功或管理员解锁后清零
+	Failures int
+	// LastFailedAt 最近一次尝试的时间
+	LastFailedAt time.Time
+	// LockedUntil 临时锁定的截止时间，零值表示未锁定
+	LockedUntil time.Time
+}
+
+// IsLocked 在 now 时刻是否处于锁定状态
+func (a *LoginAttempt) IsLocked(now time.Time) bool {
+	return now.Before(a.LockedUntil)
+}
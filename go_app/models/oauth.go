@@ -0,0 +1,200 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// 授权服务内置的授权范围，openid、profile、email 决定 ID Token 和 /oauth/userinfo 返回的用户信息，
+// offline_access 用于申请刷新令牌。客户端还可以登记自定义的授权范围，供资源服务通过令牌内省校验
+const (
+	OAuthScopeOpenID        = "openid"
+	OAuthScopeProfile       = "profile"
+	OAuthScopeEmail         = "email"
+	OAuthScopeOfflineAccess = "offline_access"
+)
+
+// 授权服务支持的授权类型
+const (
+	OAuthGrantAuthorizationCode = "authorization_code"
+	OAuthGrantRefreshToken      = "refresh_token"
+	OAuthGrantClientCredentials = "client_credentials"
+)
+
+// OAuthScopes 内置的授权范围
+func OAuthScopes() []string {
+	return []string{OAuthScopeOpenID, OAuthScopeProfile, OAuthScopeEmail, OAuthScopeOfflineAccess}
+}
+
+// OAuthGrantTypes 支持的授权类型
+func OAuthGrantTypes() []string {
+	return []string{OAuthGrantAuthorizationCode, OAuthGrantRefreshToken, OAuthGrantClientCredentials}
+}
+
+// OAuthClient 使用本服务账号登录的应用。客户端密钥只在创建时返回一次，数据库只保存哈希值；
+// 没有密钥的公开客户端（单页应用、移动端）必须使用 PKCE，且不能使用 client_credentials
+type OAuthClient struct {
+	ID         uint   `gorm:"primarykey"`
+	ClientID   string `gorm:"type:varchar(64);not null;uniqueIndex"`
+	SecretHash string `gorm:"type:varchar(64)"`
+	Name       string `gorm:"type:varchar(100);not null"`
+	// RedirectURIs 允许的回调地址，空格分隔，授权请求中的地址必须与其中之一完全一致
+	RedirectURIs string `gorm:"type:varchar(2000);not null"`
+	// Scopes 允许申请的授权范围，空格分隔
+	Scopes string `gorm:"type:varchar(500);not null"`
+	// GrantTypes 允许使用的授权类型，空格分隔
+	GrantTypes string `gorm:"type:varchar(100);not null"`
+	CreatedBy  uint
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// TableName GORM 默认会把 OAuth 拆成 o_auth，本文件的模型统一使用 oauth_ 前缀
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// IsPublic 是否为没有密钥的公开客户端
+func (c *OAuthClient) IsPublic() bool {
+	return c.SecretHash == ""
+}
+
+func (c *OAuthClient) RedirectURIList() []string {
+	return strings.Fields(c.RedirectURIs)
+}
+
+func (c *OAuthClient) ScopeList() []string {
+	return strings.Fields(c.Scopes)
+}
+
+func (c *OAuthClient) GrantTypeList() []string {
+	return strings.Fields(c.GrantTypes)
+}
+
+// AllowsRedirectURI 回调地址是否已登记，按字符串完全匹配
+func (c *OAuthClient) AllowsRedirectURI(uri string) bool {
+	return containsString(c.RedirectURIList(), uri)
+}
+
+func (c *OAuthClient) AllowsScope(scope string) bool {
+	return containsString(c.ScopeList(), scope)
+}
+
+func (c *OAuthClient) AllowsGrant(grantType string) bool {
+	return containsString(c.GrantTypeList(), grantType)
+}
+
+// OAuthConsent 用户同意客户端访问的授权范围，再次申请已同意的范围时前端可以跳过确认页面
+type OAuthConsent struct {
+	ID       uint   `gorm:"primarykey"`
+	UserID   uint   `gorm:"not null;uniqueIndex:idx_oauth_consents_user_client"`
+	ClientID string `gorm:"type:varchar(64);not null;uniqueIndex:idx_oauth_consents_user_client;index"`
+	// Scopes 已同意的授权范围，空格分隔
+	Scopes    string `gorm:"type:varchar(500);not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (OAuthConsent) TableName() string {
+	return "oauth_consents"
+}
+
+func (c *OAuthConsent) ScopeList() []string {
+	return strings.Fields(c.Scopes)
+}
+
+// Covers 是否已同意全部指定的授权范围
+func (c *OAuthConsent) Covers(scopes []string) bool {
+	granted := c.ScopeList()
+	for _, s := range scopes {
+		if !containsString(granted, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// OAuthAuthorizationCode 用户同意授权后签发的授权码，只能换取一次令牌。数据库只保存哈希值
+type OAuthAuthorizationCode struct {
+	ID          uint   `gorm:"primarykey"`
+	CodeHash    string `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ClientID    string `gorm:"type:varchar(64);not null"`
+	UserID      uint   `gorm:"not null"`
+	RedirectURI string `gorm:"type:varchar(500);not null"`
+	Scopes      string `gorm:"type:varchar(500);not null"`
+	Nonce       string `gorm:"type:varchar(255)"`
+	// CodeChallenge PKCE 校验值的 S256 摘要，换取令牌时核对 code_verifier
+	CodeChallenge string    `gorm:"type:varchar(128)"`
+	ExpiredAt     time.Time `gorm:"not null;index"`
+	UsedAt        *time.Time
+	CreatedAt     time.Time
+}
+
+func (OAuthAuthorizationCode) TableName() string {
+	return "oauth_authorization_codes"
+}
+
+func (c *OAuthAuthorizationCode) IsExpired() bool {
+	return time.Now().After(c.ExpiredAt)
+}
+
+func (c *OAuthAuthorizationCode) IsUsed() bool {
+	return c.UsedAt != nil
+}
+
+func (c *OAuthAuthorizationCode) ScopeList() []string {
+	return strings.Fields(c.Scopes)
+}
+
+// OAuthToken 签发给客户端的访问令牌及对应的刷新令牌，数据库只保存哈希值。
+// 使用刷新令牌后签发新记录并吊销旧记录，同一次授权签发的记录 FamilyID 相同，
+// 已吊销的刷新令牌被再次使用时吊销整个授权
+type OAuthToken struct {
+	ID       uint   `gorm:"primarykey"`
+	FamilyID string `gorm:"type:varchar(64);not null;index"`
+	ClientID string `gorm:"type:varchar(64);not null;index"`
+	// UserID 授权的用户，client_credentials 签发的令牌为 0
+	UserID           uint    `gorm:"not null;index"`
+	Scopes           string  `gorm:"type:varchar(500);not null"`
+	AccessTokenHash  string  `gorm:"type:varchar(64);not null;uniqueIndex"`
+	RefreshTokenHash *string `gorm:"type:varchar(64);uniqueIndex"`
+	AccessExpiredAt  time.Time
+	RefreshExpiredAt *time.Time `gorm:"index"`
+	RevokedAt        *time.Time
+	CreatedAt        time.Time
+}
+
+func (OAuthToken) TableName() string {
+	return "oauth_tokens"
+}
+
+func (t *OAuthToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
+
+func (t *OAuthToken) HasScope(scope string) bool {
+	return containsString(t.ScopeList(), scope)
+}
+
+func (t *OAuthToken) IsRevoked() bool {
+	return t.RevokedAt != nil
+}
+
+// AccessActive 访问令牌是否有效
+func (t *OAuthToken) AccessActive() bool {
+	return !t.IsRevoked() && time.Now().Before(t.AccessExpiredAt)
+}
+
+// RefreshActive 刷新令牌是否有效
+func (t *OAuthToken) RefreshActive() bool {
+	return !t.IsRevoked() && t.RefreshExpiredAt != nil && time.Now().Before(*t.RefreshExpiredAt)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Passkey 用户注册的通行密钥（WebAuthn 凭证），一个用户可以注册多个
+type Passkey struct {
+	ID     uint `gorm:"primarykey"`
+	UserID uint `gorm:"not null;index"`
+	// CredentialID 凭证ID，base64url（无填充）编码
+	CredentialID string `gorm:"type:varchar(255);not null;uniqueIndex"`
+	// PublicKey COSE 格式的凭证公钥
+	PublicKey       []byte `gorm:"not null"`
+	AttestationType string `gorm:"type:varchar(32)"`
+	// Transports 身份验证器支持的传输方式，逗号分隔，如 "internal,hybrid"
+	Transports string `gorm:"type:varchar(100)"`
+	AAGUID     []byte
+	// SignCount 身份验证器签名计数器，登录时计数器未增长说明凭证可能被克隆
+	SignCount      uint32 `gorm:"not null;default:0"`
+	UserVerified   bool   `gorm:"not null;default:false"`
+	BackupEligible bool   `gorm:"not null;default:false"`
+	BackupState    bool   `gorm:"not null;default:false"`
+	// Name 用户为通行密钥设置的名称，便于在列表中区分设备
+	Name       string `gorm:"type:varchar(100)"`
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// 通行密钥流程类型
+const (
+	PasskeyCeremonyRegistration = "registration"
+	PasskeyCeremonyLogin        = "login"
+)
+
+// PasskeyCeremony 通行密钥注册或登录流程：开始时保存挑战等会话数据，
+// 客户端凭流程令牌提交身份验证器的响应完成流程，令牌只能使用一次。数据库只保存令牌的哈希值
+type PasskeyCeremony struct {
+	ID uint `gorm:"primarykey"`
+	// UserID 注册流程为当前用户，登录流程在验证前不知道用户，为 0
+	UserID    uint   `gorm:"not null;default:0;index"`
+	TokenHash string `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Type      string `gorm:"type:varchar(20);not null"`
+	// Data JSON 编码的 webauthn.SessionData，包含挑战值
+	Data      string    `gorm:"type:text;not null"`
+	ExpiredAt time.Time `gorm:"not null;index"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (c *PasskeyCeremony) IsExpired() bool {
+	return time.Now().After(c.ExpiredAt)
+}
+
+func (c *PasskeyCeremony) IsUsed() bool {
+	return c.UsedAt != nil
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// PasswordResetToken 找回密码令牌，数据库只保存令牌的哈希值，使用一次后失效
+type PasswordResetToken struct {
+	ID        uint      `gorm:"primarykey"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiredAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (t *PasswordResetToken) IsExpired() bool {
+	return time.Now().After(t.ExpiredAt)
+}
+
+func (t *PasswordResetToken) IsUsed() bool {
+	return t.UsedAt != nil
+}
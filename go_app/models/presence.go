@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// 用户在线状态
+const (
+	PresenceOnline  = "online"  // 至少有一个连接处于前台
+	PresenceAway    = "away"    // 全部连接都上报了离开（如切到后台、长时间无操作）
+	PresenceOffline = "offline" // 没有连接
+)
+
+// UserPresence 用户的在线状态，同时用于查询接口的返回值和状态变化事件
+type UserPresence struct {
+	UserID     uint       `json:"userId"`
+	Status     string     `json:"status"`
+	LastSeenAt *time.Time `json:"lastSeenAt"` // 从未连接过时为空
+}
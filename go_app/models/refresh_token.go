@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// RefreshToken 刷新令牌，数据库只保存令牌的哈希值
+// 同一会话内轮换产生的刷新令牌属于同一令牌族（SessionID），
+// 已轮换的令牌被再次使用时整族作废
+type RefreshToken struct {
+	ID           uint       `gorm:"primarykey"`
+	UserID       uint       `gorm:"not null;index"`
+	SessionID    uint       `gorm:"not null;index"` // 所属会话 user_tokens.id
+	TokenHash    string     `gorm:"type:varchar(64);not null;uniqueIndex"`
+	TokenVersion int        `gorm:"not null"` // 签发时的用户 token 版本
+	ExpiredAt    time.Time  `gorm:"not null"`
+	UsedAt       *time.Time // 已轮换（使用）时间
+	CreatedAt    time.Time
+}
+
+func (rt *RefreshToken) IsExpired() bool {
+	return time.Now().After(rt.ExpiredAt)
+}
+
+func (rt *RefreshToken) IsUsed() bool {
+	return rt.UsedAt != nil
+}
@@ -1,11 +1,16 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // LoginRequest 登录请求参数
 type LoginRequest struct {
 	Email    string `json:"email" form:"email" binding:"required,email" example:"zhangsan@example.com"`
 	Password string `json:"password" form:"password" binding:"required" example:"123456"`
+	Platform string `json:"platform" form:"platform" binding:"omitempty,oneof=web ios android desktop" example:"web"` // 登录平台，默认 web
+	DeviceID string `json:"deviceId" form:"deviceId" binding:"omitempty,max=100" example:"iPhone-15-ABCD"`          // 设备标识，可选
 }
 
 // RegisterRequest 注册请求
@@ -26,6 +31,12 @@ type UserIDRequest struct {
     UserID uint `json:"userId" binding:"required" example:"1" description:"用户ID"`
 }
 
+// LoginUnlockRequest 解除登录锁定请求，userId 和 ip 至少指定一个
+type LoginUnlockRequest struct {
+    UserID uint   `json:"userId" example:"1" description:"解除该用户账号的锁定"`
+    IP     string `json:"ip" binding:"omitempty,ip" example:"203.0.113.10" description:"解除该客户端 IP 的锁定"`
+}
+
 // UserUpdateRequest 用户更新请求
 type UserUpdateRequest struct {
     UserID    uint       `json:"userId" binding:"required" example:"1" description:"用户ID"`
@@ -35,8 +46,161 @@ type UserUpdateRequest struct {
     Hobbies   string     `json:"hobbies" example:"读书,游泳,旅行" description:"爱好，多个爱好用逗号分隔"`
 }
 
-// EmailUpdateRequest 更新邮箱请求
+// EmailUpdateRequest 更新当前用户邮箱请求
 type EmailUpdateRequest struct {
+    UserID uint   `json:"userId" example:"0" description:"已废弃，只能为空或当前用户ID；修改其他用户的邮箱使用 /api/admin/users/email"`
+    Email  string `json:"email" binding:"required,email" example:"newemail@example.com" description:"新邮箱地址，验证通过后生效"`
+}
+
+// AdminEmailUpdateRequest 管理员更新用户邮箱请求
+type AdminEmailUpdateRequest struct {
     UserID uint   `json:"userId" binding:"required" example:"1" description:"用户ID"`
-    Email  string `json:"email" binding:"required,email" example:"newemail@example.com" description:"新邮箱地址"`
+    Email  string `json:"email" binding:"required,email" example:"newemail@example.com" description:"新邮箱地址，用户验证通过后生效"`
+}
+
+// SessionRevokeRequest 注销指定会话请求
+type SessionRevokeRequest struct {
+    SessionID uint `json:"sessionId" binding:"required" example:"1" description:"会话ID"`
+}
+
+// RefreshTokenRequest 刷新令牌请求
+type RefreshTokenRequest struct {
+    RefreshToken string `json:"refreshToken" form:"refreshToken" binding:"required" example:"3f1c0a6e9b..." description:"刷新令牌"`
+}
+
+// RoleAssignRequest 分配或移除用户角色请求
+type RoleAssignRequest struct {
+    UserID uint   `json:"userId" binding:"required" example:"1" description:"用户ID"`
+    Role   string `json:"role" binding:"required" example:"moderator" description:"角色名称"`
+}
+
+// PasswordForgotRequest 找回密码请求
+type PasswordForgotRequest struct {
+    Email string `json:"email" form:"email" binding:"required,email" example:"zhangsan@example.com" description:"注册邮箱"`
+}
+
+// PasswordResetRequest 重置密码请求
+type PasswordResetRequest struct {
+    Token       string `json:"token" form:"token" binding:"required" example:"9c1f3b..." description:"邮件中的重置令牌"`
+    NewPassword string `json:"newPassword" form:"newPassword" binding:"required,min=6" example:"654321" description:"新密码"`
+}
+
+// EmailVerifyRequest 验证邮箱请求
+type EmailVerifyRequest struct {
+    Token string `json:"token" form:"token" binding:"required" example:"9c1f3b..." description:"邮件中的验证令牌"`
+}
+
+// TwoFactorLoginRequest 两步登录请求
+type TwoFactorLoginRequest struct {
+    ChallengeToken string `json:"challengeToken" form:"challengeToken" binding:"required" example:"5d2a8c..." description:"登录接口返回的挑战令牌"`
+    Code           string `json:"code" form:"code" binding:"required" example:"123456" description:"身份验证器中的 6 位验证码或恢复码"`
+}
+
+// TwoFactorCodeRequest 两步验证码请求
+type TwoFactorCodeRequest struct {
+    Code string `json:"code" form:"code" binding:"required" example:"123456" description:"身份验证器中的 6 位验证码，关闭两步验证和重新生成恢复码时也可以使用恢复码"`
+}
+
+// PasskeyRegisterRequest 完成通行密钥注册请求
+type PasskeyRegisterRequest struct {
+    CeremonyToken string          `json:"ceremonyToken" binding:"required" example:"9b1e4f..." description:"开始注册接口返回的流程令牌"`
+    Credential    json.RawMessage `json:"credential" binding:"required" swaggertype:"object" description:"navigator.credentials.create() 返回的 PublicKeyCredential（JSON 格式）"`
+    Name          string          `json:"name" binding:"omitempty,max=100" example:"MacBook Pro" description:"通行密钥名称，可选"`
+}
+
+// PasskeyLoginRequest 通行密钥登录请求
+type PasskeyLoginRequest struct {
+    CeremonyToken string          `json:"ceremonyToken" binding:"required" example:"9b1e4f..." description:"开始登录接口返回的流程令牌"`
+    Credential    json.RawMessage `json:"credential" binding:"required" swaggertype:"object" description:"navigator.credentials.get() 返回的 PublicKeyCredential（JSON 格式）"`
+    Platform      string          `json:"platform" binding:"omitempty,oneof=web ios android desktop" example:"web"` // 登录平台，默认 web
+    DeviceID      string          `json:"deviceId" binding:"omitempty,max=100" example:"iPhone-15-ABCD"`          // 设备标识，可选
+}
+
+// PasskeyRenameRequest 修改通行密钥名称请求
+type PasskeyRenameRequest struct {
+    ID   uint   `json:"id" binding:"required" example:"1" description:"通行密钥ID"`
+    Name string `json:"name" binding:"required,max=100" example:"iPhone" description:"新名称"`
+}
+
+// PasskeyDeleteRequest 删除通行密钥请求
+type PasskeyDeleteRequest struct {
+    ID uint `json:"id" binding:"required" example:"1" description:"通行密钥ID"`
+}
+
+// APIKeyCreateRequest 创建 API Key 请求
+type APIKeyCreateRequest struct {
+    Name      string     `json:"name" binding:"required,max=100" example:"CI 部署" description:"名称"`
+    Scopes    []string   `json:"scopes" binding:"required,min=1" example:"read,users:list" description:"授权范围：read（GET 接口）、write（全部接口）以及权限标识"`
+    ExpiresAt *time.Time `json:"expiresAt" example:"2025-01-01T00:00:00+08:00" description:"过期时间，为空表示永不过期"`
+}
+
+// APIKeyRevokeRequest 撤销 API Key 请求
+type APIKeyRevokeRequest struct {
+    ID uint `json:"id" binding:"required" example:"1" description:"API Key ID"`
+}
+
+// OIDCBeginRequest 开始第三方登录请求
+type OIDCBeginRequest struct {
+    Provider string `json:"provider" binding:"required" example:"google" description:"提供方标识"`
+}
+
+// OIDCLoginRequest 完成第三方登录请求
+type OIDCLoginRequest struct {
+    State    string `json:"state" binding:"required" example:"Zk3t..." description:"提供方回调时带回的 state"`
+    Code     string `json:"code" binding:"required" example:"4/0AX4..." description:"提供方回调时带回的授权码"`
+    Platform string `json:"platform" binding:"omitempty,oneof=web ios android desktop" example:"web"` // 登录平台，默认 web
+    DeviceID string `json:"deviceId" binding:"omitempty,max=100" example:"iPhone-15-ABCD"`          // 设备标识，可选
+}
+
+// UserIdentityUnlinkRequest 解除第三方账号关联请求
+type UserIdentityUnlinkRequest struct {
+    ID uint `json:"id" binding:"required" example:"1" description:"关联ID"`
+}
+
+// OAuthClientCreateRequest 登记 OAuth 客户端请求
+type OAuthClientCreateRequest struct {
+    Name         string   `json:"name" binding:"required,max=100" example:"工单系统" description:"客户端名称，授权确认页面展示给用户"`
+    RedirectURIs []string `json:"redirectUris" example:"https://tickets.example.com/oauth/callback" description:"回调地址，使用 authorization_code 时必填"`
+    Scopes       []string `json:"scopes" example:"openid,profile,email,offline_access" description:"允许申请的授权范围，默认 openid profile email offline_access"`
+    GrantTypes   []string `json:"grantTypes" example:"authorization_code,refresh_token" description:"允许使用的授权类型，默认 authorization_code refresh_token"`
+    Public       bool     `json:"public" example:"false" description:"公开客户端（单页应用、移动端）不签发密钥，必须使用 PKCE"`
+}
+
+// OAuthClientIDRequest 指定 OAuth 客户端的请求
+type OAuthClientIDRequest struct {
+    ClientID string `json:"clientId" binding:"required" example:"8f14e45fceea167a5a36dedd4bea2543" description:"客户端ID"`
+}
+
+// OAuthAuthorizeRequest 授权请求，字段与 /oauth/authorize 的查询参数一致，授权确认页面原样转发
+type OAuthAuthorizeRequest struct {
+    ClientID            string `form:"client_id" json:"client_id" example:"8f14e45fceea167a5a36dedd4bea2543"`
+    RedirectURI         string `form:"redirect_uri" json:"redirect_uri" example:"https://tickets.example.com/oauth/callback"`
+    ResponseType        string `form:"response_type" json:"response_type" example:"code"`
+    Scope               string `form:"scope" json:"scope" example:"openid profile email"`
+    State               string `form:"state" json:"state" example:"af0ifjsldkj"`
+    Nonce               string `form:"nonce" json:"nonce" example:"n-0S6_WzA2Mj"`
+    CodeChallenge       string `form:"code_challenge" json:"code_challenge" example:"E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"`
+    CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method" example:"S256"`
+}
+
+// OAuthConsentRequest 同意或拒绝授权请求
+type OAuthConsentRequest struct {
+    OAuthAuthorizeRequest
+    Approve bool `json:"approve" example:"true" description:"是否同意授权"`
+}
+
+// OAuthTokenRequest 令牌端点请求，按 RFC 6749 使用 application/x-www-form-urlencoded 提交
+type OAuthTokenRequest struct {
+    GrantType    string `form:"grant_type"`
+    Code         string `form:"code"`
+    RedirectURI  string `form:"redirect_uri"`
+    CodeVerifier string `form:"code_verifier"`
+    RefreshToken string `form:"refresh_token"`
+    Scope        string `form:"scope"`
+}
+
+// OAuthTokenActionRequest 吊销令牌（RFC 7009）和令牌内省（RFC 7662）请求
+type OAuthTokenActionRequest struct {
+    Token         string `form:"token"`
+    TokenTypeHint string `form:"token_type_hint"`
 }
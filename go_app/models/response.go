@@ -2,6 +2,7 @@ package models
 
 import (
 	"go_app/pkg/errcode"
+	"strings"
 	"time"
 )
 
@@ -17,15 +18,17 @@ type Response struct {
 // UserInfo 用户信息响应结构体
 // @Description 用户详细信息响应结构
 type UserInfo struct {
-	UserID    uint       `json:"userId" example:"1" description:"用户ID"`
-	Username  string     `json:"username" example:"张三" description:"用户名"`
-	Email     string     `json:"email" example:"zhangsan@example.com" description:"邮箱地址"`
-	AvatarURL string     `json:"avatarUrl" example:"https://example.com/avatar.jpg" description:"头像URL"`
-	Birthday  *time.Time `json:"birthday" example:"1990-01-01T00:00:00+08:00" description:"生日"`
-	Gender    string     `json:"gender" example:"male" enums:"male,female,other" description:"性别"`
-	Hobbies   string     `json:"hobbies" example:"读书,游泳,旅行" description:"用户爱好，多个爱好用逗号分隔"`
-	CreatedAt time.Time  `json:"createdAt" example:"2024-01-01T00:00:00+08:00" description:"创建时间"`
-	UpdatedAt time.Time  `json:"updatedAt" example:"2024-01-01T00:00:00+08:00" description:"更新时间"`
+	UserID        uint       `json:"userId" example:"1" description:"用户ID"`
+	Username      string     `json:"username" example:"张三" description:"用户名"`
+	Email         string     `json:"email" example:"zhangsan@example.com" description:"邮箱地址"`
+	EmailVerified bool       `json:"emailVerified" example:"true" description:"邮箱是否已验证"`
+	PendingEmail  string     `json:"pendingEmail,omitempty" example:"new@example.com" description:"待验证的新邮箱，验证通过后生效"`
+	AvatarURL     string     `json:"avatarUrl" example:"https://example.com/avatar.jpg" description:"头像URL"`
+	Birthday      *time.Time `json:"birthday" example:"1990-01-01T00:00:00+08:00" description:"生日"`
+	Gender        string     `json:"gender" example:"male" enums:"male,female,other" description:"性别"`
+	Hobbies       string     `json:"hobbies" example:"读书,游泳,旅行" description:"用户爱好，多个爱好用逗号分隔"`
+	CreatedAt     time.Time  `json:"createdAt" example:"2024-01-01T00:00:00+08:00" description:"创建时间"`
+	UpdatedAt     time.Time  `json:"updatedAt" example:"2024-01-01T00:00:00+08:00" description:"更新时间"`
 }
 
 // LoginResponse 登录响应
@@ -34,6 +37,209 @@ type LoginResponse struct {
 	UserInfo *UserInfo `json:"userInfo" description:"用户信息"`
 }
 
+// TokenResponse 令牌响应
+// @Description 访问令牌与刷新令牌
+type TokenResponse struct {
+	Token        string `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..." description:"访问令牌"`
+	RefreshToken string `json:"refreshToken" example:"3f1c0a6e9b..." description:"刷新令牌，每次刷新后旧令牌失效"`
+	ExpiresIn    int64  `json:"expiresIn" example:"900" description:"访问令牌有效期（秒）"`
+}
+
+// TwoFactorChallengeResponse 两步登录响应
+// @Description 账号开启两步验证时，登录接口返回挑战令牌而不是访问令牌，需通过 /api/login/2fa 提交验证码完成登录
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool      `json:"twoFactorRequired" example:"true" description:"是否需要两步验证"`
+	ChallengeToken    string    `json:"challengeToken" example:"5d2a8c..." description:"挑战令牌，只能使用一次"`
+	ExpiredAt         time.Time `json:"expiredAt" example:"2024-01-01T00:05:00+08:00" description:"挑战令牌过期时间"`
+}
+
+// TwoFactorStatus 两步验证状态
+// @Description 两步验证状态
+type TwoFactorStatus struct {
+	Enabled                bool  `json:"enabled" example:"true" description:"是否已开启两步验证"`
+	RecoveryCodesRemaining int64 `json:"recoveryCodesRemaining" example:"10" description:"剩余可用的恢复码数量"`
+}
+
+// TwoFactorEnrollment 绑定身份验证器的信息
+// @Description 身份验证器密钥，扫描二维码或手动输入密钥后提交验证码确认
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret" example:"JBSWY3DPEHPK3PXP" description:"Base32 编码的密钥，用于手动输入"`
+	OTPAuthURI string `json:"otpauthUri" example:"otpauth://totp/Go%20App:zhangsan@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Go%20App" description:"otpauth URI"`
+	QRCode     string `json:"qrCode" example:"data:image/png;base64,iVBORw0KGgo..." description:"otpauth URI 的二维码，PNG 格式的 data URI"`
+}
+
+// TwoFactorRecoveryCodes 恢复码
+// @Description 恢复码只在生成时返回一次，每个恢复码只能使用一次
+type TwoFactorRecoveryCodes struct {
+	RecoveryCodes []string `json:"recoveryCodes" example:"k3v9q-7xm2a,p8d4t-w6n1c" description:"恢复码"`
+}
+
+// PasskeyOptions 通行密钥注册/登录流程的参数
+// @Description Options 直接传给 navigator.credentials.create() 或 navigator.credentials.get()，完成时提交 CeremonyToken
+type PasskeyOptions struct {
+	CeremonyToken string      `json:"ceremonyToken" example:"9b1e4f..." description:"流程令牌，只能使用一次"`
+	ExpiredAt     time.Time   `json:"expiredAt" example:"2024-01-01T00:05:00+08:00" description:"流程过期时间"`
+	Options       interface{} `json:"options" swaggertype:"object" description:"WebAuthn 参数，包含 publicKey 字段"`
+}
+
+// PasskeyInfo 通行密钥信息
+// @Description 用户注册的通行密钥
+type PasskeyInfo struct {
+	ID             uint       `json:"id" example:"1" description:"通行密钥ID"`
+	Name           string     `json:"name" example:"MacBook Pro" description:"名称"`
+	Transports     []string   `json:"transports" example:"internal,hybrid" description:"身份验证器支持的传输方式"`
+	BackupEligible bool       `json:"backupEligible" example:"true" description:"是否可以同步到其他设备"`
+	BackupState    bool       `json:"backupState" example:"true" description:"是否已同步到其他设备"`
+	LastUsedAt     *time.Time `json:"lastUsedAt" example:"2024-01-01T00:00:00+08:00" description:"最近登录时间"`
+	CreatedAt      time.Time  `json:"createdAt" example:"2024-01-01T00:00:00+08:00" description:"注册时间"`
+}
+
+// OIDCProviderInfo 第三方登录提供方
+// @Description 可用于登录的 OIDC 提供方
+type OIDCProviderInfo struct {
+	Name        string `json:"name" example:"google" description:"提供方标识，开始登录时提交"`
+	DisplayName string `json:"displayName" example:"Google" description:"显示名称"`
+}
+
+// OIDCAuthorization 开始第三方登录的响应
+// @Description 浏览器跳转到 AuthorizationURL，提供方回调前端页面时带回 state 和 code
+type OIDCAuthorization struct {
+	AuthorizationURL string    `json:"authorizationUrl" example:"https://accounts.google.com/o/oauth2/v2/auth?..." description:"提供方登录页面地址"`
+	State            string    `json:"state" example:"Zk3t..." description:"流程标识，提供方回调时原样带回，前端应核对一致"`
+	ExpiredAt        time.Time `json:"expiredAt" example:"2024-01-01T00:10:00+08:00" description:"流程过期时间"`
+}
+
+// UserIdentityInfo 关联的第三方账号信息
+// @Description 用户关联的第三方账号
+type UserIdentityInfo struct {
+	ID          uint       `json:"id" example:"1" description:"关联ID"`
+	Provider    string     `json:"provider" example:"google" description:"提供方标识"`
+	Email       string     `json:"email" example:"zhangsan@gmail.com" description:"第三方账号的邮箱"`
+	LastLoginAt *time.Time `json:"lastLoginAt" example:"2024-01-01T00:00:00+08:00" description:"最近登录时间"`
+	CreatedAt   time.Time  `json:"createdAt" example:"2024-01-01T00:00:00+08:00" description:"关联时间"`
+}
+
+// APIKeyInfo API Key 信息，不包含明文
+// @Description 用户创建的 API Key
+type APIKeyInfo struct {
+	ID         uint       `json:"id" example:"1" description:"API Key ID"`
+	Name       string     `json:"name" example:"CI 部署" description:"名称"`
+	Prefix     string     `json:"prefix" example:"gapp_3f9a2c1d" description:"明文开头的可见部分"`
+	Scopes     []string   `json:"scopes" example:"read,users:list" description:"授权范围"`
+	ExpiresAt  *time.Time `json:"expiresAt" example:"2025-01-01T00:00:00+08:00" description:"过期时间，为空表示永不过期"`
+	LastUsedAt *time.Time `json:"lastUsedAt" example:"2024-01-01T00:00:00+08:00" description:"最近使用时间"`
+	LastUsedIP string     `json:"lastUsedIp" example:"203.0.113.10" description:"最近使用的客户端 IP"`
+	CreatedAt  time.Time  `json:"createdAt" example:"2024-01-01T00:00:00+08:00" description:"创建时间"`
+}
+
+// APIKeyCreateResponse 创建 API Key 响应
+// @Description 新创建的 API Key，明文只返回这一次
+type APIKeyCreateResponse struct {
+	*APIKeyInfo
+	Key string `json:"key" example:"gapp_3f9a2c1d_8b7e..." description:"API Key 明文，请立即保存，之后无法再次查看"`
+}
+
+// OAuthClientInfo OAuth 客户端信息，不包含密钥
+// @Description 接入本服务登录的 OAuth 客户端
+type OAuthClientInfo struct {
+	ClientID     string    `json:"clientId" example:"8f14e45fceea167a5a36dedd4bea2543" description:"客户端ID"`
+	Name         string    `json:"name" example:"工单系统" description:"客户端名称"`
+	Public       bool      `json:"public" example:"false" description:"是否为没有密钥的公开客户端"`
+	RedirectURIs []string  `json:"redirectUris" example:"https://tickets.example.com/oauth/callback" description:"回调地址"`
+	Scopes       []string  `json:"scopes" example:"openid,profile,email" description:"允许申请的授权范围"`
+	GrantTypes   []string  `json:"grantTypes" example:"authorization_code,refresh_token" description:"允许使用的授权类型"`
+	CreatedAt    time.Time `json:"createdAt" example:"2024-01-01T00:00:00+08:00" description:"创建时间"`
+}
+
+// OAuthClientCreateResponse 登记 OAuth 客户端响应
+// @Description 新登记的客户端，密钥只返回这一次
+type OAuthClientCreateResponse struct {
+	*OAuthClientInfo
+	ClientSecret string `json:"clientSecret,omitempty" example:"3c59dc048e8850243be8079a5c74d079..." description:"客户端密钥，请立即保存，之后无法再次查看；公开客户端没有密钥"`
+}
+
+// OAuthAuthorizeInfo 授权请求信息
+// @Description 授权确认页面展示的客户端和授权范围
+type OAuthAuthorizeInfo struct {
+	ClientID   string   `json:"clientId" example:"8f14e45fceea167a5a36dedd4bea2543" description:"客户端ID"`
+	ClientName string   `json:"clientName" example:"工单系统" description:"客户端名称"`
+	Scopes     []string `json:"scopes" example:"openid,profile,email" description:"申请的授权范围"`
+	Consented  bool     `json:"consented" example:"false" description:"用户是否已同意过全部授权范围，为 true 时可以直接提交同意"`
+}
+
+// OAuthRedirect 授权结果
+// @Description 授权确认后需要跳转的客户端回调地址
+type OAuthRedirect struct {
+	RedirectURL string `json:"redirectUrl" example:"https://tickets.example.com/oauth/callback?code=...&state=af0ifjsldkj" description:"客户端回调地址，携带授权码或错误信息"`
+}
+
+// OAuthConsentInfo 授权记录信息
+// @Description 用户已授权的客户端
+type OAuthConsentInfo struct {
+	ClientID   string    `json:"clientId" example:"8f14e45fceea167a5a36dedd4bea2543" description:"客户端ID"`
+	ClientName string    `json:"clientName" example:"工单系统" description:"客户端名称"`
+	Scopes     []string  `json:"scopes" example:"openid,profile,email" description:"已同意的授权范围"`
+	CreatedAt  time.Time `json:"createdAt" example:"2024-01-01T00:00:00+08:00" description:"首次授权时间"`
+	UpdatedAt  time.Time `json:"updatedAt" example:"2024-01-01T00:00:00+08:00" description:"最近授权时间"`
+}
+
+// OAuthTokenResponse 令牌端点响应，字段按 RFC 6749 命名
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope"`
+}
+
+// OAuthIntrospection 令牌内省响应，字段按 RFC 7662 命名，令牌无效时只返回 active=false
+type OAuthIntrospection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Aud       string `json:"aud,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+}
+
+// OpenIDConfiguration OIDC 发现文档，字段按 OpenID Connect Discovery 1.0 命名
+type OpenIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+}
+
+// SessionInfo 会话信息响应结构体
+// @Description 登录会话（设备）信息
+type SessionInfo struct {
+	SessionID    uint      `json:"sessionId" example:"1" description:"会话ID"`
+	Platform     string    `json:"platform" example:"web" enums:"web,ios,android,desktop" description:"登录平台"`
+	DeviceID     string    `json:"deviceId" example:"iPhone-15-ABCD" description:"设备标识"`
+	UserAgent    string    `json:"userAgent" example:"Mozilla/5.0" description:"客户端 User-Agent"`
+	IP           string    `json:"ip" example:"127.0.0.1" description:"登录IP"`
+	Current      bool      `json:"current" example:"true" description:"是否为当前请求所用会话"`
+	LastActiveAt time.Time `json:"lastActiveAt" example:"2024-01-01T00:00:00+08:00" description:"最近活跃时间"`
+	ExpiredAt    time.Time `json:"expiredAt" example:"2024-01-02T00:00:00+08:00" description:"过期时间"`
+	CreatedAt    time.Time `json:"createdAt" example:"2024-01-01T00:00:00+08:00" description:"登录时间"`
+}
+
 // Pagination 分页信息
 // @Description 分页查询信息
 type Pagination struct {
@@ -120,14 +326,86 @@ func (r *Response) WithDetails(details string) *Response {
 // ToUserInfo 用户模型转换为用户信息
 func (u *User) ToUserInfo() *UserInfo {
 	return &UserInfo{
-		UserID:    u.ID,
-		Username:  u.Username,
-		Email:     u.Email,
-		AvatarURL: u.AvatarURL,
-		Birthday:  u.Birthday,
-		Gender:    u.Gender,
-		Hobbies:   u.Hobbies,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		UserID:        u.ID,
+		Username:      u.Username,
+		Email:         u.Email,
+		EmailVerified: u.IsEmailVerified(),
+		PendingEmail:  u.PendingEmail,
+		AvatarURL:     u.AvatarURL,
+		Birthday:      u.Birthday,
+		Gender:        u.Gender,
+		Hobbies:       u.Hobbies,
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
+	}
+}
+
+// ToPasskeyInfo 通行密钥模型转换为通行密钥信息
+func (p *Passkey) ToPasskeyInfo() *PasskeyInfo {
+	transports := []string{}
+	if p.Transports != "" {
+		transports = strings.Split(p.Transports, ",")
+	}
+	return &PasskeyInfo{
+		ID:             p.ID,
+		Name:           p.Name,
+		Transports:     transports,
+		BackupEligible: p.BackupEligible,
+		BackupState:    p.BackupState,
+		LastUsedAt:     p.LastUsedAt,
+		CreatedAt:      p.CreatedAt,
+	}
+}
+
+// ToUserIdentityInfo 第三方账号关联模型转换为关联信息
+func (i *UserIdentity) ToUserIdentityInfo() *UserIdentityInfo {
+	return &UserIdentityInfo{
+		ID:          i.ID,
+		Provider:    i.Provider,
+		Email:       i.Email,
+		LastLoginAt: i.LastLoginAt,
+		CreatedAt:   i.CreatedAt,
+	}
+}
+
+// ToAPIKeyInfo API Key 模型转换为 API Key 信息
+func (k *APIKey) ToAPIKeyInfo() *APIKeyInfo {
+	return &APIKeyInfo{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.ScopeList(),
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		LastUsedIP: k.LastUsedIP,
+		CreatedAt:  k.CreatedAt,
+	}
+}
+
+// ToOAuthClientInfo OAuth 客户端模型转换为客户端信息
+func (c *OAuthClient) ToOAuthClientInfo() *OAuthClientInfo {
+	return &OAuthClientInfo{
+		ClientID:     c.ClientID,
+		Name:         c.Name,
+		Public:       c.IsPublic(),
+		RedirectURIs: c.RedirectURIList(),
+		Scopes:       c.ScopeList(),
+		GrantTypes:   c.GrantTypeList(),
+		CreatedAt:    c.CreatedAt,
+	}
+}
+
+// ToSessionInfo 会话模型转换为会话信息，currentID 为当前请求所用会话ID
+func (ut *UserToken) ToSessionInfo(currentID uint) *SessionInfo {
+	return &SessionInfo{
+		SessionID:    ut.ID,
+		Platform:     ut.Platform,
+		DeviceID:     ut.DeviceID,
+		UserAgent:    ut.UserAgent,
+		IP:           ut.IP,
+		Current:      ut.ID == currentID,
+		LastActiveAt: ut.LastActiveAt,
+		ExpiredAt:    ut.ExpiredAt,
+		CreatedAt:    ut.CreatedAt,
 	}
 }
@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+// 权限标识，格式为 资源:操作。
+// 用户对自己的数据总是有操作权限，以下权限用于操作其他用户的数据或访问管理接口
+const (
+	PermUsersList   = "users:list"   // 查看用户列表
+	PermUsersRead   = "users:read"   // 查看其他用户的信息
+	PermUsersUpdate = "users:update" // 修改其他用户的信息、邮箱和头像
+	PermUsersDelete = "users:delete" // 删除其他用户
+	PermRolesManage = "roles:manage" // 查看角色、为用户分配和移除角色
+	PermOAuthManage = "oauth:manage" // 管理接入本服务登录的 OAuth 客户端
+)
+
+// AllPermissions 全部权限标识
+func AllPermissions() []string {
+	return []string{PermUsersList, PermUsersRead, PermUsersUpdate, PermUsersDelete, PermRolesManage, PermOAuthManage}
+}
+
+// 内置角色，由迁移写入数据库
+const (
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+	RoleUser      = "user"
+)
+
+// DefaultRole 新用户默认分配的角色
+const DefaultRole = RoleUser
+
+// Role 角色，权限列表保存在 role_permissions 表中
+type Role struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"size:50;uniqueIndex;not null" json:"name"`
+	Description string    `gorm:"size:255" json:"description"`
+	Permissions []string  `gorm:"-" json:"permissions"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// RolePermission 角色拥有的权限
+type RolePermission struct {
+	RoleID     uint   `gorm:"primaryKey"`
+	Permission string `gorm:"size:100;primaryKey"`
+}
+
+// UserRole 用户与角色的对应关系
+type UserRole struct {
+	UserID    uint `gorm:"primaryKey"`
+	RoleID    uint `gorm:"primaryKey;index"`
+	CreatedAt time.Time
+}
+
+// HasPermission 角色是否拥有指定权限
+func (r *Role) HasPermission(permission string) bool {
+	for _, p := range r.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// BuiltinRoles 内置角色及其权限，与迁移写入的数据一致
+func BuiltinRoles() []Role {
+	return []Role{
+		{
+			Name:        RoleAdmin,
+			Description: "管理员，可以管理全部用户和角色",
+			Permissions: []string{PermUsersList, PermUsersRead, PermUsersUpdate, PermUsersDelete, PermRolesManage, PermOAuthManage},
+		},
+		{
+			Name:        RoleModerator,
+			Description: "协管员，可以查看全部用户",
+			Permissions: []string{PermUsersList, PermUsersRead},
+		},
+		{
+			Name:        RoleUser,
+			Description: "普通用户，只能查看和修改自己的数据",
+			Permissions: []string{},
+		},
+	}
+}
@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// TwoFactor 用户的 TOTP 两步验证（RFC 6238）配置。
+// 绑定身份验证器后 ConfirmedAt 为空，用户使用验证码确认后两步验证才生效
+type TwoFactor struct {
+	UserID uint `gorm:"primarykey;autoIncrement:false"`
+	// Secret Base32 编码的 TOTP 密钥，校验验证码需要原文，因此不做哈希
+	Secret      string `gorm:"size:64;not null"`
+	ConfirmedAt *time.Time
+	// LastUsedStep 最近一次成功使用的验证码时间步，同一验证码不能重复使用
+	LastUsedStep int64 `gorm:"not null;default:0"`
+	// Attempts 关闭两步验证和重新生成恢复码时未通过的校验次数，校验前占用，校验通过后清零；
+	// AttemptedAt 为最近一次占用的时间
+	Attempts    int `gorm:"not null;default:0"`
+	AttemptedAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// IsEnabled 两步验证是否已生效
+func (t *TwoFactor) IsEnabled() bool {
+	return t.ConfirmedAt != nil
+}
+
+// TwoFactorRecoveryCode 两步验证恢复码，只保存哈希值，每个恢复码只能使用一次
+type TwoFactorRecoveryCode struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"not null;uniqueIndex:idx_two_factor_recovery_codes_user_code"`
+	CodeHash  string `gorm:"type:varchar(64);not null;uniqueIndex:idx_two_factor_recovery_codes_user_code"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// TwoFactorChallenge 两步登录的挑战令牌：密码校验通过后签发，凭令牌和验证码完成登录。
+// 数据库只保存令牌的哈希值，Platform 和 DeviceID 为第一步登录请求中的设备信息
+type TwoFactorChallenge struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"not null;index"`
+	TokenHash string `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Platform  string `gorm:"type:varchar(20)"`
+	DeviceID  string `gorm:"type:varchar(100)"`
+	// Attempts 验证码错误次数，达到上限后令牌失效
+	Attempts  int       `gorm:"not null;default:0"`
+	ExpiredAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (c *TwoFactorChallenge) IsExpired() bool {
+	return time.Now().After(c.ExpiredAt)
+}
+
+func (c *TwoFactorChallenge) IsUsed() bool {
+	return c.UsedAt != nil
+}
@@ -17,4 +17,18 @@ type User struct {
 	Hobbies   string     `gorm:"size:500" json:"hobbies"`  // 爱好，用逗号分隔
 	TokenVersion int        `gorm:"default:0" json:"-"`  // 添加 token 版本字段
 	Token     string     `gorm:"-" json:"token,omitempty"` // 临时存储token
+	DisabledAt *time.Time `gorm:"index" json:"-"`          // 禁用时间，为空表示账号正常
+	EmailVerifiedAt *time.Time `json:"-"`                   // 邮箱验证时间，为空表示邮箱未验证
+	PendingEmail    string     `gorm:"size:100" json:"-"`    // 待验证的新邮箱，验证通过后替换 Email
+	LastSeenAt      *time.Time `json:"-"`                   // 最后在线时间，实时连接建立和断开时更新，通过在线状态接口查询
+}
+
+// IsDisabled 账号是否已被禁用
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
+
+// IsEmailVerified 当前邮箱是否已验证
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
 }
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// UserIdentity 用户关联的第三方（OIDC 提供方）账号，一个用户可以关联多个提供方
+type UserIdentity struct {
+	ID     uint `gorm:"primarykey"`
+	UserID uint `gorm:"not null;index"`
+	// Provider 配置中的提供方名称，与 Subject 一起唯一确定第三方账号
+	Provider string `gorm:"type:varchar(50);not null;uniqueIndex:idx_user_identities_subject"`
+	Subject  string `gorm:"type:varchar(255);not null;uniqueIndex:idx_user_identities_subject"`
+	// Email 最近一次登录时提供方返回的邮箱，仅用于展示
+	Email       string `gorm:"type:varchar(100)"`
+	LastLoginAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// OIDCFlow 第三方登录流程：跳转到提供方前保存 nonce 和 PKCE 校验值，
+// 提供方回调后凭 state 取回并换取令牌，只能使用一次。数据库只保存 state 的哈希值
+type OIDCFlow struct {
+	ID        uint   `gorm:"primarykey"`
+	StateHash string `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Provider  string `gorm:"type:varchar(50);not null"`
+	Nonce     string `gorm:"type:varchar(100);not null"`
+	// CodeVerifier PKCE 校验值，换取令牌时提交给提供方
+	CodeVerifier string    `gorm:"type:varchar(128);not null"`
+	ExpiredAt    time.Time `gorm:"not null;index"`
+	UsedAt       *time.Time
+	CreatedAt    time.Time
+}
+
+// TableName GORM 默认会把 OIDC 拆成 o_id_c
+func (OIDCFlow) TableName() string {
+	return "oidc_flows"
+}
+
+func (f *OIDCFlow) IsExpired() bool {
+	return time.Now().After(f.ExpiredAt)
+}
+
+func (f *OIDCFlow) IsUsed() bool {
+	return f.UsedAt != nil
+}
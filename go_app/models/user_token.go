@@ -2,16 +2,37 @@ package models
 
 import "time"
 
+// 登录平台
+const (
+    PlatformWeb     = "web"
+    PlatformIOS     = "ios"
+    PlatformAndroid = "android"
+    PlatformDesktop = "desktop"
+)
+
+// UserToken 用户会话，每次登录创建一条记录
 type UserToken struct {
-    ID        uint      `gorm:"primarykey"`
-    UserID    uint      `gorm:"not null"`
-    Token     string    `gorm:"type:varchar(255);not null"`
-    Platform  string    `gorm:"type:varchar(20);not null"`
-    ExpiredAt time.Time `gorm:"not null"`
-    CreatedAt time.Time
-    UpdatedAt time.Time
+    ID           uint      `gorm:"primarykey"`
+    UserID       uint      `gorm:"not null;index"`
+    Token        string    `gorm:"type:varchar(255);not null;uniqueIndex"` // 会话标识，对应 JWT 的 jti
+    Platform     string    `gorm:"type:varchar(20);not null"`
+    DeviceID     string    `gorm:"type:varchar(100)"`
+    UserAgent    string    `gorm:"type:varchar(255)"`
+    IP           string    `gorm:"type:varchar(45)"`
+    LastActiveAt time.Time
+    ExpiredAt    time.Time `gorm:"not null"`
+    CreatedAt    time.Time
+    UpdatedAt    time.Time
+}
+
+// SessionMeta 创建会话时携带的设备信息
+type SessionMeta struct {
+    Platform  string
+    DeviceID  string
+    UserAgent string
+    IP        string
 }
 
 func (ut *UserToken) IsExpired() bool {
     return time.Now().After(ut.ExpiredAt)
-}
\ No newline at end of file
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// WebSocketMessage 发给用户的 WebSocket 消息，保存在用户的发件箱中供客户端重连后补发。
+// Seq 为用户内单调递增的消息ID，即推送给客户端的消息中的 id
+type WebSocketMessage struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"not null;uniqueIndex:idx_websocket_messages_user_seq"`
+	Seq       uint64 `gorm:"not null;uniqueIndex:idx_websocket_messages_user_seq"`
+	Type      string `gorm:"type:varchar(50);not null"`
+	Data      string `gorm:"type:text"` // 消息内容的 JSON
+	CreatedAt time.Time
+}
+
+// TableName GORM 默认会把 WebSocket 拆成 web_socket
+func (WebSocketMessage) TableName() string {
+	return "websocket_messages"
+}
+
+// WebSocketCursor 用户的消息序号：LastSeq 为最后分配的消息ID，AckedSeq 为客户端确认过的最大消息ID
+type WebSocketCursor struct {
+	UserID    uint   `gorm:"primarykey;autoIncrement:false"`
+	LastSeq   uint64 `gorm:"not null;default:0"`
+	AckedSeq  uint64 `gorm:"not null;default:0"`
+	UpdatedAt time.Time
+}
+
+func (WebSocketCursor) TableName() string {
+	return "websocket_cursors"
+}
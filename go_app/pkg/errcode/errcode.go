@@ -13,6 +13,7 @@ var (
 	InvalidParams   = &ErrorCode{Code: 400, Message: "请求参数错误"}
 	NotFound        = &ErrorCode{Code: 404, Message: "资源不存在"}
 	Unauthorized    = &ErrorCode{Code: 401, Message: "未授权"}
+	Forbidden       = &ErrorCode{Code: 403, Message: "没有操作权限"}
 	TooManyRequests = &ErrorCode{Code: 429, Message: "请求过于频繁"}
 
 	// 用户模块错误码 (1000-1999)
@@ -22,12 +23,69 @@ var (
 	UserCreateFailed  = &ErrorCode{Code: 1003, Message: "创建用户失败"}
 	UserUpdateFailed  = &ErrorCode{Code: 1004, Message: "更新用户信息失败"}
 	UserDeleteFailed  = &ErrorCode{Code: 1005, Message: "删除用户失败"}
+	UserDisabled      = &ErrorCode{Code: 1006, Message: "账号已被禁用"}
+
+	PasswordResetTokenInvalid     = &ErrorCode{Code: 1007, Message: "重置链接无效或已过期"}
+	EmailNotVerified              = &ErrorCode{Code: 1008, Message: "邮箱未验证，请先完成邮箱验证"}
+	EmailVerificationTokenInvalid = &ErrorCode{Code: 1009, Message: "验证链接无效或已过期"}
+	EmailAlreadyVerified          = &ErrorCode{Code: 1010, Message: "邮箱已验证，无需重复验证"}
+
+	TwoFactorAlreadyEnabled   = &ErrorCode{Code: 1011, Message: "两步验证已开启"}
+	TwoFactorNotEnabled       = &ErrorCode{Code: 1012, Message: "两步验证未开启"}
+	TwoFactorNotEnrolled      = &ErrorCode{Code: 1013, Message: "请先绑定身份验证器"}
+	TwoFactorCodeInvalid      = &ErrorCode{Code: 1014, Message: "验证码错误"}
+	TwoFactorChallengeInvalid = &ErrorCode{Code: 1015, Message: "登录验证已失效，请重新登录"}
+
+	PasskeyCeremonyInvalid = &ErrorCode{Code: 1016, Message: "通行密钥验证已失效，请重试"}
+	PasskeyVerifyFailed    = &ErrorCode{Code: 1017, Message: "通行密钥验证失败"}
+	PasskeyAlreadyExists   = &ErrorCode{Code: 1018, Message: "该通行密钥已注册"}
+	PasskeyNotFound        = &ErrorCode{Code: 1019, Message: "通行密钥不存在"}
+
+	LoginFailed          = &ErrorCode{Code: 1020, Message: "邮箱或密码错误"}
+	LoginTooManyAttempts = &ErrorCode{Code: 1021, Message: "登录尝试过于频繁，请稍后再试"}
+	LoginLocked          = &ErrorCode{Code: 1022, Message: "登录失败次数过多，已暂时锁定，请稍后再试"}
+
+	APIKeyNotFound      = &ErrorCode{Code: 1023, Message: "API Key 不存在"}
+	APIKeyScopeInvalid  = &ErrorCode{Code: 1024, Message: "无效的 API Key 授权范围"}
+	APIKeyLimitExceeded = &ErrorCode{Code: 1025, Message: "API Key 数量已达上限"}
+
+	OIDCProviderNotFound  = &ErrorCode{Code: 1026, Message: "不支持该第三方登录方式"}
+	OIDCFlowInvalid       = &ErrorCode{Code: 1027, Message: "第三方登录已失效，请重试"}
+	OIDCLoginFailed       = &ErrorCode{Code: 1028, Message: "第三方登录验证失败"}
+	OIDCEmailUnverified   = &ErrorCode{Code: 1029, Message: "第三方账号的邮箱未验证，无法登录"}
+	OIDCAccountUnverified = &ErrorCode{Code: 1030, Message: "该邮箱已注册但尚未验证，请先使用密码登录并完成邮箱验证"}
+	OIDCSignupDisabled    = &ErrorCode{Code: 1031, Message: "该邮箱未注册"}
+	UserIdentityNotFound  = &ErrorCode{Code: 1032, Message: "关联的第三方账号不存在"}
+
+	OAuthClientNotFound  = &ErrorCode{Code: 1033, Message: "OAuth 客户端不存在"}
+	OAuthClientInvalid   = &ErrorCode{Code: 1034, Message: "OAuth 客户端的回调地址、授权范围或授权类型无效"}
+	OAuthRequestInvalid  = &ErrorCode{Code: 1035, Message: "无效的授权请求"}
+	OAuthConsentNotFound = &ErrorCode{Code: 1036, Message: "授权记录不存在"}
+
+	WebSocketConnectionLimit   = &ErrorCode{Code: 1037, Message: "实时连接数已达上限，请关闭其他设备或页面后重试"}
+	WebSocketTopicInvalid      = &ErrorCode{Code: 1038, Message: "无效的订阅主题"}
+	WebSocketTopicForbidden    = &ErrorCode{Code: 1039, Message: "没有订阅该主题的权限"}
+	WebSocketRequestInvalid    = &ErrorCode{Code: 1040, Message: "无效的实时消息请求"}
+	WebSocketSubscriptionLimit = &ErrorCode{Code: 1041, Message: "订阅的主题数已达上限"}
+	PresenceStatusInvalid      = &ErrorCode{Code: 1042, Message: "无效的在线状态"}
+	PresenceTooManyUsers       = &ErrorCode{Code: 1043, Message: "一次最多查询 100 个用户的在线状态"}
+	TwoFactorLocked            = &ErrorCode{Code: 1044, Message: "验证码错误次数过多，请稍后再试"}
+	EmailVerificationCooldown  = &ErrorCode{Code: 1045, Message: "验证邮件发送过于频繁，请稍后再试"}
 
 	// Token相关错误码 (2000-2999)
 	TokenInvalid      = &ErrorCode{Code: 2000, Message: "无效的Token"}
 	TokenExpired      = &ErrorCode{Code: 2001, Message: "Token已过期"}
 	TokenMissing      = &ErrorCode{Code: 2002, Message: "请提供认证Token"}
 	TokenVersionError = &ErrorCode{Code: 2003, Message: "Token已失效，请重新登录"}
+	SessionNotFound   = &ErrorCode{Code: 2004, Message: "会话不存在"}
+	TokenReused       = &ErrorCode{Code: 2005, Message: "刷新令牌已被使用，会话已注销，请重新登录"}
+	APIKeyInvalid     = &ErrorCode{Code: 2006, Message: "API Key 无效或已过期"}
+	APIKeyScopeDenied = &ErrorCode{Code: 2007, Message: "API Key 未授权该操作"}
+	APIKeyNotAllowed  = &ErrorCode{Code: 2008, Message: "该操作需要登录，不支持使用 API Key"}
+
+	// 角色权限相关错误码 (3000-3999)
+	RoleNotFound  = &ErrorCode{Code: 3000, Message: "角色不存在"}
+	RoleLastAdmin = &ErrorCode{Code: 3001, Message: "不能移除最后一个管理员"}
 
 	InvalidRequest = &ErrorCode{Code: 40001, Message: "无效的请求"}
 )
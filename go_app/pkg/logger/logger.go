@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// Level 日志级别
+type Level int32
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "DEBUG",
+	LevelInfo:  "INFO",
+	LevelWarn:  "WARN",
+	LevelError: "ERROR",
+}
+
+var current atomic.Int32
+
+func init() {
+	current.Store(int32(LevelInfo))
+}
+
+// ParseLevel 解析日志级别字符串（debug/info/warn/error）
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LevelDebug, nil
+	case "", "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("未知的日志级别: %s", s)
+}
+
+// SetLevel 设置全局日志级别，可在运行时安全调用
+func SetLevel(level Level) {
+	current.Store(int32(level))
+}
+
+// GetLevel 获取当前日志级别
+func GetLevel() Level {
+	return Level(current.Load())
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+func output(level Level, format string, args ...interface{}) {
+	if level < GetLevel() {
+		return
+	}
+	log.Output(3, "["+level.String()+"] "+fmt.Sprintf(format, args...))
+}
+
+func Debugf(format string, args ...interface{}) { output(LevelDebug, format, args...) }
+func Infof(format string, args ...interface{})  { output(LevelInfo, format, args...) }
+func Warnf(format string, args ...interface{})  { output(LevelWarn, format, args...) }
+func Errorf(format string, args ...interface{}) { output(LevelError, format, args...) }
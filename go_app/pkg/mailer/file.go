@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go_app/utils"
+)
+
+// FileMailer 将邮件保存为目录中的 .eml 文件，用于开发环境查看邮件内容
+type FileMailer struct {
+	from string
+	dir  string
+}
+
+func NewFileMailer(from, dir string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建邮件目录失败: %v", err)
+	}
+	return &FileMailer{from: from, dir: dir}, nil
+}
+
+func (m *FileMailer) Send(msg *Message) error {
+	data, err := encode(m.from, msg)
+	if err != nil {
+		return err
+	}
+	suffix, err := utils.RandomHex(4)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405.000"), suffix)
+	// 邮件中可能包含重置令牌等敏感信息，仅允许当前用户读取
+	return os.WriteFile(filepath.Join(m.dir, name), data, 0o600)
+}
@@ -0,0 +1,85 @@
+// Package mailer 邮件发送。
+//
+// 业务代码只依赖 Mailer 接口，按配置选择 SMTP 实现、写入本地目录的 file 实现，
+// 或保存在内存中的 memory 实现（测试中读取已发送的邮件）。
+package mailer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/mail"
+	"strings"
+	"time"
+
+	"go_app/config"
+	"go_app/utils"
+)
+
+// Message 纯文本邮件
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer 邮件发送接口
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+// New 按配置创建邮件发送实现
+func New(cfg config.MailConfig) (Mailer, error) {
+	if _, err := mail.ParseAddress(cfg.From); err != nil {
+		return nil, fmt.Errorf("无效的发件人地址 %q: %v", cfg.From, err)
+	}
+	switch cfg.Driver {
+	case config.MailDriverSMTP:
+		return NewSMTPMailer(cfg.From, cfg.SMTP), nil
+	case config.MailDriverFile:
+		return NewFileMailer(cfg.From, cfg.Dir)
+	case config.MailDriverMemory:
+		return NewMemoryMailer(), nil
+	}
+	return nil, fmt.Errorf("不支持的邮件驱动 %q", cfg.Driver)
+}
+
+// encode 生成 RFC 5322 格式的邮件内容，正文使用 base64 编码以支持中文
+func encode(from string, msg *Message) ([]byte, error) {
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("无效的发件人地址 %q: %v", from, err)
+	}
+	recipient, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return nil, fmt.Errorf("无效的收件人地址 %q: %v", msg.To, err)
+	}
+	id, err := utils.RandomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	domain := sender.Address[strings.LastIndex(sender.Address, "@")+1:]
+
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	header("From", sender.String())
+	header("To", recipient.String())
+	header("Subject", mime.BEncoding.Encode("UTF-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", fmt.Sprintf("<%s@%s>", id, domain))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=UTF-8")
+	header("Content-Transfer-Encoding", "base64")
+	buf.WriteString("\r\n")
+
+	body := base64.StdEncoding.EncodeToString([]byte(msg.Body))
+	for len(body) > 76 {
+		buf.WriteString(body[:76] + "\r\n")
+		body = body[76:]
+	}
+	buf.WriteString(body + "\r\n")
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,40 @@
+package mailer
+
+import "sync"
+
+// MemoryMailer 将邮件保存在内存中，测试中通过 Messages 读取已发送的邮件
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Send(msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, *msg)
+	return nil
+}
+
+// Messages 按发送顺序返回已发送的邮件
+func (m *MemoryMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
+
+// Last 返回最后一封发送给 to 的邮件，没有时返回 nil
+func (m *MemoryMailer) Last(to string) *Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To == to {
+			msg := m.messages[i]
+			return &msg
+		}
+	}
+	return nil
+}
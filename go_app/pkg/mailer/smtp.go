@@ -0,0 +1,108 @@
+package mailer
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"go_app/config"
+)
+
+const (
+	// 建立 SMTP 连接的超时时间
+	smtpDialTimeout = 10 * time.Second
+	// 发送一封邮件的超时时间，从建立连接开始计算，避免服务器无响应时后台任务一直阻塞
+	smtpSendTimeout = time.Minute
+)
+
+// SMTPMailer 通过 SMTP 服务器发送邮件，每封邮件使用一个新连接
+type SMTPMailer struct {
+	from string
+	cfg  config.SMTPConfig
+}
+
+func NewSMTPMailer(from string, cfg config.SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{from: from, cfg: cfg}
+}
+
+func (m *SMTPMailer) Send(msg *Message) error {
+	data, err := encode(m.from, msg)
+	if err != nil {
+		return err
+	}
+	sender, _ := mail.ParseAddress(m.from)
+	recipient, _ := mail.ParseAddress(msg.To)
+
+	client, err := m.dial()
+	if err != nil {
+		return fmt.Errorf("连接 SMTP 服务器失败: %v", err)
+	}
+	defer client.Close()
+
+	if m.cfg.Username != "" {
+		auth := smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP 认证失败: %v", err)
+		}
+	}
+	if err := client.Mail(sender.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(recipient.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// dial 按加密方式建立连接：tls 直接使用 TLS 连接（通常为 465 端口），starttls 在明文连接上升级
+func (m *SMTPMailer) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	tlsConfig := &tls.Config{ServerName: m.cfg.Host}
+	dialer := &net.Dialer{Timeout: smtpDialTimeout}
+
+	if m.cfg.Encryption == config.SMTPEncryptionTLS {
+		conn, err := tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		conn.SetDeadline(time.Now().Add(smtpSendTimeout))
+		client, err := smtp.NewClient(conn, m.cfg.Host)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return client, nil
+	}
+
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(smtpSendTimeout))
+	client, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if m.cfg.Encryption == config.SMTPEncryptionSTARTTLS {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	return client, nil
+}
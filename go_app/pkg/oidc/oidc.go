@@ -0,0 +1,169 @@
+// Package oidc 与提供方无关的 OpenID Connect 客户端：通过发现文档获取端点，
+// 使用授权码模式 + PKCE（RFC 7636）登录，并使用提供方发布的 JWKS 校验 ID Token。
+//
+// 一次登录分两步：
+//
+//	req, _ := oidc.NewAuthRequest()
+//	redirect(client.AuthCodeURL(req)) // 保存 req，提供方回调时带回 req.State 和授权码
+//	identity, err := client.Exchange(ctx, code, req)
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// DefaultScopes 未配置 Scopes 时请求的授权范围
+var DefaultScopes = []string{gooidc.ScopeOpenID, "email", "profile"}
+
+// Config 提供方配置
+type Config struct {
+	Issuer       string // 提供方标识，发现文档地址为 <Issuer>/.well-known/openid-configuration
+	ClientID     string
+	ClientSecret string // 公开客户端可以为空，只使用 PKCE
+	RedirectURL  string // 在提供方登记的回调地址
+	Scopes       []string
+	// HTTPClient 访问提供方使用的客户端，为空时使用 http.DefaultClient
+	HTTPClient *http.Client
+}
+
+// Client 一个提供方的 OIDC 客户端，可以并发使用
+type Client struct {
+	oauth      oauth2.Config
+	verifier   *gooidc.IDTokenVerifier
+	httpClient *http.Client
+}
+
+// NewClient 读取提供方的发现文档并创建客户端
+func NewClient(ctx context.Context, cfg Config) (*Client, error) {
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	provider, err := gooidc.NewProvider(gooidc.ClientContext(ctx, httpClient), cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 发现文档失败: %w", cfg.Issuer, err)
+	}
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = DefaultScopes
+	}
+	return &Client{
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		},
+		verifier:   provider.Verifier(&gooidc.Config{ClientID: cfg.ClientID}),
+		httpClient: httpClient,
+	}, nil
+}
+
+// AuthRequest 一次登录的随机参数，跳转到提供方前生成并保存在服务端，回调时按 State 取回
+type AuthRequest struct {
+	State        string // 防止 CSRF，提供方原样带回
+	Nonce        string // 写入 ID Token，防止重放
+	CodeVerifier string // PKCE 校验值，换取令牌时提交
+}
+
+// NewAuthRequest 生成新的登录参数
+func NewAuthRequest() (*AuthRequest, error) {
+	state, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+	return &AuthRequest{State: state, Nonce: nonce, CodeVerifier: oauth2.GenerateVerifier()}, nil
+}
+
+// AuthCodeURL 用户登录和授权的提供方页面地址
+func (c *Client) AuthCodeURL(req *AuthRequest) string {
+	return c.oauth.AuthCodeURL(req.State, gooidc.Nonce(req.Nonce), oauth2.S256ChallengeOption(req.CodeVerifier))
+}
+
+// Identity ID Token 中的用户身份
+type Identity struct {
+	Issuer        string
+	Subject       string // 用户在提供方的唯一标识
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+}
+
+// ErrNonceMismatch ID Token 中的 nonce 与登录请求不一致
+var ErrNonceMismatch = errors.New("ID Token nonce 不匹配")
+
+// Exchange 使用授权码和 PKCE 校验值换取令牌，校验 ID Token 的签名、签发方、受众、有效期和 nonce 后返回用户身份
+func (c *Client) Exchange(ctx context.Context, code string, req *AuthRequest) (*Identity, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, c.httpClient)
+	token, err := c.oauth.Exchange(ctx, code, oauth2.VerifierOption(req.CodeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("换取令牌失败: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("令牌响应中没有 id_token")
+	}
+	idToken, err := c.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("ID Token 校验失败: %w", err)
+	}
+	if idToken.Nonce != req.Nonce {
+		return nil, ErrNonceMismatch
+	}
+	if idToken.AccessTokenHash != "" {
+		if err := idToken.VerifyAccessToken(token.AccessToken); err != nil {
+			return nil, fmt.Errorf("at_hash 校验失败: %w", err)
+		}
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified *bool  `json:"email_verified"`
+		Name          string `json:"name"`
+		Picture       string `json:"picture"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		// 部分提供方将 email_verified 写成字符串
+		var loose struct {
+			Email         string `json:"email"`
+			EmailVerified string `json:"email_verified"`
+			Name          string `json:"name"`
+			Picture       string `json:"picture"`
+		}
+		if err := idToken.Claims(&loose); err != nil {
+			return nil, fmt.Errorf("解析 ID Token 失败: %w", err)
+		}
+		verified := loose.EmailVerified == "true"
+		claims.Email, claims.EmailVerified, claims.Name, claims.Picture = loose.Email, &verified, loose.Name, loose.Picture
+	}
+	return &Identity{
+		Issuer:        idToken.Issuer,
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified != nil && *claims.EmailVerified,
+		Name:          claims.Name,
+		Picture:       claims.Picture,
+	}, nil
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,253 @@
+// Package oidctest 用于测试的本地 OIDC 提供方：发布发现文档和 JWKS，
+// 授权端点不显示登录页面，直接以 Server.User 的身份同意授权并跳转回客户端；
+// 令牌端点校验客户端凭据、回调地址和 PKCE，签发 RS256 ID Token。
+//
+//	srv := oidctest.NewServer(t)
+//	srv.User = oidctest.User{Subject: "1001", Email: "a@example.com", EmailVerified: true}
+//	code, state, err := srv.Authorize(authURL) // 模拟浏览器完成授权
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// User 在提供方登录的用户
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Server 本地 OIDC 提供方，测试结束时自动关闭
+type Server struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	// User 授权端点以该用户身份同意授权
+	User User
+	// ExtraClaims 覆盖或追加到之后签发的 ID Token 中，用于构造 nonce、aud 等不正确的令牌
+	ExtraClaims map[string]interface{}
+	// SigningKey 为 ID Token 签名的私钥，替换为 JWKS 之外的私钥可以构造签名无效的令牌
+	SigningKey *rsa.PrivateKey
+
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]*grant
+}
+
+// grant 授权端点签发的授权码
+type grant struct {
+	user        User
+	redirectURI string
+	nonce       string
+	challenge   string
+	expiredAt   time.Time
+}
+
+const keyID = "oidctest"
+
+// NewServer 启动本地 OIDC 提供方
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("生成签名密钥失败: %v", err)
+	}
+	s := &Server{
+		ClientID:     "go-app-test",
+		ClientSecret: "oidctest-secret",
+		User:         User{Subject: "oidctest-user", Email: "oidc@example.com", EmailVerified: true, Name: "OIDC User"},
+		SigningKey:   key,
+		key:          key,
+		codes:        make(map[string]*grant),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Issuer 提供方标识
+func (s *Server) Issuer() string {
+	return s.URL
+}
+
+// Authorize 模拟浏览器打开授权地址，返回跳转回客户端时携带的授权码和 state
+func (s *Server) Authorize(authURL string) (code, state string, err error) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		return "", "", fmt.Errorf("授权端点返回 %d", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return "", "", err
+	}
+	query := location.Query()
+	if e := query.Get("error"); e != "" {
+		return "", "", errors.New(e)
+	}
+	return query.Get("code"), query.Get("state"), nil
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.URL,
+		"authorization_endpoint":                s.URL + "/authorize",
+		"token_endpoint":                        s.URL + "/token",
+		"jwks_uri":                              s.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := s.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirectURI := q.Get("redirect_uri")
+	target, err := url.Parse(redirectURI)
+	if err != nil || redirectURI == "" || q.Get("client_id") != s.ClientID {
+		http.Error(w, "invalid client_id or redirect_uri", http.StatusBadRequest)
+		return
+	}
+	redirect := func(values url.Values) {
+		values.Set("state", q.Get("state"))
+		target.RawQuery = values.Encode()
+		http.Redirect(w, r, target.String(), http.StatusFound)
+	}
+	if q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		redirect(url.Values{"error": {"invalid_request"}})
+		return
+	}
+
+	code := randomString()
+	s.mu.Lock()
+	s.codes[code] = &grant{
+		user:        s.User,
+		redirectURI: redirectURI,
+		nonce:       q.Get("nonce"),
+		challenge:   q.Get("code_challenge"),
+		expiredAt:   time.Now().Add(time.Minute),
+	}
+	s.mu.Unlock()
+	redirect(url.Values{"code": {code}})
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != s.ClientID || clientSecret != s.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type")
+		return
+	}
+
+	// 授权码只能使用一次
+	s.mu.Lock()
+	g := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+	if g == nil || time.Now().After(g.expiredAt) || g.redirectURI != r.PostForm.Get("redirect_uri") {
+		tokenError(w, "invalid_grant")
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            s.URL,
+		"sub":            g.user.Subject,
+		"aud":            s.ClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          g.nonce,
+		"email":          g.user.Email,
+		"email_verified": g.user.EmailVerified,
+		"name":           g.user.Name,
+	}
+	for k, v := range s.ExtraClaims {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	idToken, err := token.SignedString(s.SigningKey)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
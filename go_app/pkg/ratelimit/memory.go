@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryPurgeInterval 清理过期计数的最小间隔
+const memoryPurgeInterval = time.Minute
+
+type memoryEntry struct {
+	// 令牌桶
+	tokens  float64
+	updated time.Time
+	// 滑动窗口
+	index      int64
+	prev, curr int64
+
+	expiresAt time.Time
+}
+
+type memoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastPurge time.Time
+}
+
+// NewMemoryStore 进程内的限流计数存储，只适用于单实例部署
+func NewMemoryStore() Store {
+	return &memoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+func (s *memoryStore) Allow(_ context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.purge(now)
+	key = string(limit.Algorithm) + ":" + key
+	entry, ok := s.entries[key]
+	if ok && !now.Before(entry.expiresAt) {
+		ok = false
+	}
+
+	switch limit.Algorithm {
+	case SlidingWindow:
+		index, elapsed := windowStart(limit.Window, now)
+		if !ok {
+			entry = &memoryEntry{index: index}
+			s.entries[key] = entry
+		}
+		switch entry.index {
+		case index:
+		case index - 1:
+			entry.index, entry.prev, entry.curr = index, entry.curr, 0
+		default:
+			entry.index, entry.prev, entry.curr = index, 0, 0
+		}
+		allowed := slidingWindowCount(limit, entry.prev, entry.curr+1, elapsed) <= float64(limit.Limit)
+		result := slidingWindowResult(limit, entry.prev, entry.curr, elapsed, allowed)
+		if allowed {
+			entry.curr++
+		}
+		entry.expiresAt = now.Add(2 * limit.Window)
+		return result, nil
+	default:
+		if !ok {
+			entry = &memoryEntry{tokens: float64(limit.Limit), updated: now}
+			s.entries[key] = entry
+		}
+		if now.After(entry.updated) {
+			entry.tokens = refill(limit, entry.tokens, now.Sub(entry.updated))
+			entry.updated = now
+		}
+		allowed := entry.tokens >= 1
+		if allowed {
+			entry.tokens--
+		}
+		entry.expiresAt = now.Add(limit.Window)
+		return tokenBucketResult(limit, entry.tokens, allowed), nil
+	}
+}
+
+func (s *memoryStore) purge(now time.Time) {
+	if now.Sub(s.lastPurge) < memoryPurgeInterval {
+		return
+	}
+	s.lastPurge = now
+	for key, entry := range s.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Package ratelimit 请求限流。
+//
+// 支持令牌桶和滑动窗口两种算法，计数保存在 Store 中：进程内的 memory 实现只适用于单实例部署，
+// 多实例部署使用 Redis 实现共享计数：
+//
+//	store := ratelimit.NewMemoryStore()
+//	result, err := store.Allow(ctx, "ip:203.0.113.10", ratelimit.Limit{
+//		Algorithm: ratelimit.SlidingWindow, Limit: 10, Window: time.Minute,
+//	}, time.Now())
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Algorithm 限流算法
+type Algorithm string
+
+const (
+	// TokenBucket 令牌桶：桶容量为 Limit，每 Window/Limit 补充一个令牌，允许短时间内的突发请求
+	TokenBucket Algorithm = "token_bucket"
+	// SlidingWindow 滑动窗口：按当前窗口和上一个窗口的计数加权估算最近 Window 内的请求数
+	SlidingWindow Algorithm = "sliding_window"
+)
+
+// Limit 限流规则：Window 时间内最多 Limit 个请求
+type Limit struct {
+	Algorithm Algorithm
+	Limit     int
+	Window    time.Duration
+}
+
+// Result 一次请求的限流结果
+type Result struct {
+	Allowed bool
+	// Remaining 本次请求之后剩余的配额
+	Remaining int
+	// Reset 配额恢复所需的时间：令牌桶为补满所需的时间，滑动窗口为当前窗口结束的时间
+	Reset time.Duration
+	// RetryAfter 被拒绝时需要等待的时间
+	RetryAfter time.Duration
+}
+
+// Store 限流计数存储
+type Store interface {
+	// Allow 记录 key 在 now 时刻的一次请求并返回是否允许，被拒绝的请求不消耗配额
+	Allow(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// refill 令牌桶经过 elapsed 后的令牌数，不超过桶容量
+func refill(limit Limit, tokens float64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return tokens
+	}
+	tokens += float64(elapsed) * float64(limit.Limit) / float64(limit.Window)
+	return math.Min(tokens, float64(limit.Limit))
+}
+
+// tokenBucketResult tokens 为处理本次请求之后桶中的令牌数
+func tokenBucketResult(limit Limit, tokens float64, allowed bool) Result {
+	perToken := float64(limit.Window) / float64(limit.Limit)
+	result := Result{
+		Allowed:   allowed,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(limit.Limit) - tokens) * perToken),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) * perToken)
+	}
+	return result
+}
+
+// slidingWindowCount 上一个窗口计数 prev 按剩余比例加权后与当前窗口计数 curr 之和，elapsed 为当前窗口已经过的时间
+func slidingWindowCount(limit Limit, prev, curr int64, elapsed time.Duration) float64 {
+	weight := float64(limit.Window-elapsed) / float64(limit.Window)
+	return float64(prev)*weight + float64(curr)
+}
+
+// slidingWindowResult curr 为本次请求之前当前窗口的计数
+func slidingWindowResult(limit Limit, prev, curr int64, elapsed time.Duration, allowed bool) Result {
+	window := float64(limit.Window)
+	max := float64(limit.Limit)
+	result := Result{Allowed: allowed, Reset: limit.Window - elapsed}
+	if allowed {
+		remaining := max - slidingWindowCount(limit, prev, curr+1, elapsed)
+		result.Remaining = int(math.Max(0, math.Floor(remaining)))
+		return result
+	}
+
+	// 计算加权计数降到 Limit-1 以下所需的时间
+	var wait float64
+	if curr+1 <= int64(limit.Limit) && prev > 0 {
+		// 当前窗口内上一个窗口的权重继续下降即可
+		wait = window*(1-(max-float64(curr)-1)/float64(prev)) - float64(elapsed)
+	} else {
+		// 需要等到下一个窗口，届时当前窗口成为上一个窗口
+		wait = float64(limit.Window - elapsed)
+		if curr > 0 {
+			wait += math.Max(0, window*(1-(max-1)/float64(curr)))
+		}
+	}
+	result.RetryAfter = time.Duration(math.Max(0, wait))
+	return result
+}
+
+// windowStart now 所在窗口的编号和已经过的时间
+func windowStart(window time.Duration, now time.Time) (int64, time.Duration) {
+	ms := now.UnixMilli()
+	size := window.Milliseconds()
+	index := ms / size
+	return index, time.Duration(ms-index*size) * time.Millisecond
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisKeyPrefix Redis 中限流计数的键前缀
+const redisKeyPrefix = "go_app:rate_limit:"
+
+// tokenBucketScript 补充令牌并尝试取出一个，返回 {是否允许, 剩余令牌数}
+var tokenBucketScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1])
+local updated = tonumber(state[2])
+if tokens == nil or updated == nil then
+	tokens = limit
+	updated = now
+end
+if now > updated then
+	tokens = math.min(limit, tokens + (now - updated) * limit / window)
+	updated = now
+end
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', updated)
+redis.call('PEXPIRE', KEYS[1], window)
+return {allowed, tostring(tokens)}
+`)
+
+// slidingWindowScript KEYS[1] 为上一个窗口的计数，KEYS[2] 为当前窗口的计数；
+// 允许时当前窗口计数加一，返回 {是否允许, 上一个窗口计数, 本次请求之前的当前窗口计数}
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local elapsed = tonumber(ARGV[3])
+local prev = tonumber(redis.call('GET', KEYS[1]) or '0')
+local curr = tonumber(redis.call('GET', KEYS[2]) or '0')
+if prev * (window - elapsed) / window + curr + 1 > limit then
+	return {0, prev, curr}
+end
+redis.call('INCR', KEYS[2])
+redis.call('PEXPIRE', KEYS[2], window * 2)
+return {1, prev, curr}
+`)
+
+type redisStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisStore 基于 Redis 的限流计数存储，多个实例共享计数。判断和计数在 Lua 脚本中原子执行，
+// 时间使用调用方传入的 now，各实例的时钟需要保持同步
+func NewRedisStore(client redis.UniversalClient) Store {
+	return &redisStore{client: client}
+}
+
+func (s *redisStore) Allow(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	window := limit.Window.Milliseconds()
+	switch limit.Algorithm {
+	case SlidingWindow:
+		index, elapsed := windowStart(limit.Window, now)
+		prefix := redisKeyPrefix + string(SlidingWindow) + ":" + key + ":"
+		keys := []string{prefix + strconv.FormatInt(index-1, 10), prefix + strconv.FormatInt(index, 10)}
+		values, err := slidingWindowScript.Run(ctx, s.client, keys, limit.Limit, window, elapsed.Milliseconds()).Int64Slice()
+		if err != nil {
+			return Result{}, err
+		}
+		return slidingWindowResult(limit, values[1], values[2], elapsed, values[0] == 1), nil
+	default:
+		keys := []string{redisKeyPrefix + string(TokenBucket) + ":" + key}
+		values, err := tokenBucketScript.Run(ctx, s.client, keys, limit.Limit, window, now.UnixMilli()).Slice()
+		if err != nil {
+			return Result{}, err
+		}
+		allowed, _ := values[0].(int64)
+		text, _ := values[1].(string)
+		tokens, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return Result{}, err
+		}
+		return tokenBucketResult(limit, tokens, allowed == 1), nil
+	}
+}
@@ -0,0 +1,130 @@
+package ratelimit_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go_app/pkg/ratelimit"
+	"go_app/repository/repotest"
+)
+
+func TestStore(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		testStore(t, func(t *testing.T) ratelimit.Store {
+			return ratelimit.NewMemoryStore()
+		})
+	})
+	t.Run("Redis", func(t *testing.T) {
+		testStore(t, func(t *testing.T) ratelimit.Store {
+			return ratelimit.NewRedisStore(repotest.OpenRedis(t))
+		})
+	})
+}
+
+// testStore 限流存储行为测试，newStore 每次返回一个空的存储
+func testStore(t *testing.T, newStore func(t *testing.T) ratelimit.Store) {
+	// 从一个窗口的起点开始，便于计算滑动窗口的权重
+	base := time.UnixMilli((time.Now().UnixMilli()/10000 + 1) * 10000)
+
+	t.Run("TokenBucket", func(t *testing.T) {
+		store := newStore(t)
+		limit := ratelimit.Limit{Algorithm: ratelimit.TokenBucket, Limit: 3, Window: 3 * time.Second}
+
+		for i := 2; i >= 0; i-- {
+			expect(t, allow(t, store, "a", limit, base), ratelimit.Result{Allowed: true, Remaining: i, Reset: time.Duration(3-i) * time.Second})
+		}
+		// 桶空后拒绝，每秒补充一个令牌
+		expect(t, allow(t, store, "a", limit, base), ratelimit.Result{Reset: 3 * time.Second, RetryAfter: time.Second})
+		expect(t, allow(t, store, "a", limit, base.Add(500*time.Millisecond)),
+			ratelimit.Result{Reset: 2500 * time.Millisecond, RetryAfter: 500 * time.Millisecond})
+		expect(t, allow(t, store, "a", limit, base.Add(time.Second)), ratelimit.Result{Allowed: true, Reset: 3 * time.Second})
+
+		// 长时间空闲后补满但不超过容量
+		expect(t, allow(t, store, "a", limit, base.Add(time.Minute)), ratelimit.Result{Allowed: true, Remaining: 2, Reset: time.Second})
+
+		// 不同 key 独立计数
+		expect(t, allow(t, store, "b", limit, base), ratelimit.Result{Allowed: true, Remaining: 2, Reset: time.Second})
+	})
+
+	t.Run("SlidingWindow", func(t *testing.T) {
+		store := newStore(t)
+		limit := ratelimit.Limit{Algorithm: ratelimit.SlidingWindow, Limit: 4, Window: 10 * time.Second}
+
+		for i := 3; i >= 0; i-- {
+			expect(t, allow(t, store, "a", limit, base), ratelimit.Result{Allowed: true, Remaining: i, Reset: 10 * time.Second})
+		}
+		// 当前窗口已满，需等到下一个窗口中上一个窗口的权重降到 3/4
+		expect(t, allow(t, store, "a", limit, base.Add(time.Second)), ratelimit.Result{Reset: 9 * time.Second, RetryAfter: 11500 * time.Millisecond})
+
+		next := base.Add(10 * time.Second)
+		expect(t, allow(t, store, "a", limit, next.Add(2*time.Second)), ratelimit.Result{Reset: 8 * time.Second, RetryAfter: 500 * time.Millisecond})
+		expect(t, allow(t, store, "a", limit, next.Add(2500*time.Millisecond)), ratelimit.Result{Allowed: true, Reset: 7500 * time.Millisecond})
+		expect(t, allow(t, store, "a", limit, next.Add(5*time.Second)), ratelimit.Result{Allowed: true, Reset: 5 * time.Second})
+
+		// 间隔超过两个窗口后重新计数
+		expect(t, allow(t, store, "a", limit, base.Add(time.Minute)), ratelimit.Result{Allowed: true, Remaining: 3, Reset: 10 * time.Second})
+	})
+
+	t.Run("AlgorithmsIndependent", func(t *testing.T) {
+		store := newStore(t)
+		bucket := ratelimit.Limit{Algorithm: ratelimit.TokenBucket, Limit: 1, Window: time.Minute}
+		window := ratelimit.Limit{Algorithm: ratelimit.SlidingWindow, Limit: 1, Window: time.Minute}
+		if !allow(t, store, "a", bucket, base).Allowed || !allow(t, store, "a", window, base).Allowed {
+			t.Fatal("同一 key 的不同算法应分别计数")
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		store := newStore(t)
+		for _, limit := range []ratelimit.Limit{
+			{Algorithm: ratelimit.TokenBucket, Limit: 20, Window: time.Minute},
+			{Algorithm: ratelimit.SlidingWindow, Limit: 20, Window: time.Minute},
+		} {
+			var (
+				wg      sync.WaitGroup
+				mu      sync.Mutex
+				allowed int
+			)
+			for i := 0; i < 50; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					result, err := store.Allow(context.Background(), "concurrent", limit, base)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					if result.Allowed {
+						mu.Lock()
+						allowed++
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+			if allowed != limit.Limit {
+				t.Fatalf("%s 并发请求通过 %d 个, want %d", limit.Algorithm, allowed, limit.Limit)
+			}
+		}
+	})
+}
+
+func allow(t *testing.T, store ratelimit.Store, key string, limit ratelimit.Limit, now time.Time) ratelimit.Result {
+	t.Helper()
+	result, err := store.Allow(context.Background(), key, limit, now)
+	if err != nil {
+		t.Fatalf("Allow 失败: %v", err)
+	}
+	return result
+}
+
+func expect(t *testing.T, got, want ratelimit.Result) {
+	t.Helper()
+	// 浮点计算允许 1ms 误差
+	near := func(a, b time.Duration) bool { return a-b < time.Millisecond && b-a < time.Millisecond }
+	if got.Allowed != want.Allowed || got.Remaining != want.Remaining || !near(got.Reset, want.Reset) || !near(got.RetryAfter, want.RetryAfter) {
+		t.Fatalf("Allow = %+v, want %+v", got, want)
+	}
+}
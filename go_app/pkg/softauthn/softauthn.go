@@ -0,0 +1,294 @@
+// Package softauthn 软件实现的 WebAuthn 身份验证器，用于在 Go 测试中完成通行密钥的注册和登录。
+//
+// Authenticator 模拟浏览器和平台身份验证器：接收服务端返回给浏览器的参数（JSON），
+// 返回浏览器提交给服务端的 PublicKeyCredential（JSON）。凭证使用 ES256 密钥对，
+// 证明格式为 "none"，每次登录签名计数器加一：
+//
+//	auth := softauthn.New("http://localhost:8080")
+//	credential, err := auth.Create(registrationOptions) // navigator.credentials.create()
+//	assertion, err := auth.Get(loginOptions)            // navigator.credentials.get()
+package softauthn
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+)
+
+// authenticatorData 中的标志位
+const (
+	flagUserPresent    byte = 0x01
+	flagUserVerified   byte = 0x04
+	flagBackupEligible byte = 0x08
+	flagBackupState    byte = 0x10
+	flagAttestedData   byte = 0x40
+)
+
+var (
+	// ErrNoCredential 没有可用于该依赖方的凭证，对应浏览器中用户取消选择
+	ErrNoCredential = errors.New("softauthn: 没有可用的凭证")
+	// ErrExcluded 身份验证器上已有 excludeCredentials 中的凭证，对应浏览器的 InvalidStateError
+	ErrExcluded = errors.New("softauthn: 凭证已在该身份验证器上注册")
+)
+
+// Credential 身份验证器保存的凭证
+type Credential struct {
+	ID         []byte
+	RPID       string
+	UserHandle []byte
+	PrivateKey *ecdsa.PrivateKey
+	// SignCount 签名计数器，测试可以修改它来模拟被克隆的凭证
+	SignCount uint32
+}
+
+// Authenticator 软件身份验证器，可以被多个 goroutine 同时使用
+type Authenticator struct {
+	// Origin 发起请求的页面来源，写入 clientDataJSON
+	Origin string
+	// AAGUID 身份验证器型号标识，默认为全 0
+	AAGUID [16]byte
+	// Synced 模拟可同步的通行密钥（如 iCloud 钥匙串），设置 BE 和 BS 标志
+	Synced bool
+
+	mu          sync.Mutex
+	credentials []*Credential
+}
+
+// New 创建在 origin 页面上使用的软件身份验证器
+func New(origin string) *Authenticator {
+	return &Authenticator{Origin: origin}
+}
+
+// Credentials 返回身份验证器上的全部凭证
+func (a *Authenticator) Credentials() []*Credential {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]*Credential(nil), a.credentials...)
+}
+
+// Create 模拟 navigator.credentials.create()：options 为 protocol.CredentialCreation 的 JSON
+// （包含 publicKey 字段），生成新凭证并返回注册响应的 JSON
+func (a *Authenticator) Create(options []byte) ([]byte, error) {
+	var creation protocol.CredentialCreation
+	if err := json.Unmarshal(options, &creation); err != nil {
+		return nil, fmt.Errorf("softauthn: 解析注册参数失败: %w", err)
+	}
+	opts := creation.Response
+
+	rpID, err := a.rpID(opts.RelyingParty.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !supportsES256(opts.Parameters) {
+		return nil, errors.New("softauthn: 依赖方不支持 ES256")
+	}
+	userHandle, err := decodeUserID(opts.User.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, excluded := range opts.CredentialExcludeList {
+		if a.find(rpID, excluded.CredentialID) != nil {
+			return nil, ErrExcluded
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	credential := &Credential{ID: make([]byte, 32), RPID: rpID, UserHandle: userHandle, PrivateKey: key}
+	if _, err := rand.Read(credential.ID); err != nil {
+		return nil, err
+	}
+	publicKey, err := webauthncbor.Marshal(&webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: key.X.FillBytes(make([]byte, 32)),
+		YCoord: key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// attestedCredentialData: aaguid(16) | credentialIdLength(2) | credentialId | credentialPublicKey
+	authData := a.authData(rpID, flagAttestedData, 0)
+	authData = append(authData, a.AAGUID[:]...)
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(credential.ID)))
+	authData = append(authData, credential.ID...)
+	authData = append(authData, publicKey...)
+
+	attestationObject, err := webauthncbor.Marshal(struct {
+		Format   string         `cbor:"fmt"`
+		AttStmt  map[string]any `cbor:"attStmt"`
+		AuthData []byte         `cbor:"authData"`
+	}{Format: "none", AttStmt: map[string]any{}, AuthData: authData})
+	if err != nil {
+		return nil, err
+	}
+	clientData, err := a.clientData(protocol.CreateCeremony, opts.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	a.credentials = append(a.credentials, credential)
+	return json.Marshal(map[string]any{
+		"id":                      encode(credential.ID),
+		"rawId":                   encode(credential.ID),
+		"type":                    "public-key",
+		"authenticatorAttachment": "platform",
+		"clientExtensionResults":  map[string]any{},
+		"response": map[string]any{
+			"clientDataJSON":    encode(clientData),
+			"attestationObject": encode(attestationObject),
+			"transports":        []string{"internal", "hybrid"},
+		},
+	})
+}
+
+// Get 模拟 navigator.credentials.get()：options 为 protocol.CredentialAssertion 的 JSON
+// （包含 publicKey 字段），使用 allowCredentials 中的凭证（为空时使用该依赖方最早注册的凭证）签名，
+// 返回登录响应的 JSON
+func (a *Authenticator) Get(options []byte) ([]byte, error) {
+	var assertion protocol.CredentialAssertion
+	if err := json.Unmarshal(options, &assertion); err != nil {
+		return nil, fmt.Errorf("softauthn: 解析登录参数失败: %w", err)
+	}
+	opts := assertion.Response
+
+	rpID, err := a.rpID(opts.RelyingPartyID)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var credential *Credential
+	if len(opts.AllowedCredentials) == 0 {
+		credential = a.find(rpID, nil)
+	}
+	for _, allowed := range opts.AllowedCredentials {
+		if credential = a.find(rpID, allowed.CredentialID); credential != nil {
+			break
+		}
+	}
+	if credential == nil {
+		return nil, ErrNoCredential
+	}
+
+	credential.SignCount++
+	authData := a.authData(rpID, 0, credential.SignCount)
+	clientData, err := a.clientData(protocol.AssertCeremony, opts.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, credential.PrivateKey, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(map[string]any{
+		"id":                      encode(credential.ID),
+		"rawId":                   encode(credential.ID),
+		"type":                    "public-key",
+		"authenticatorAttachment": "platform",
+		"clientExtensionResults":  map[string]any{},
+		"response": map[string]any{
+			"clientDataJSON":    encode(clientData),
+			"authenticatorData": encode(authData),
+			"signature":         encode(signature),
+			"userHandle":        encode(credential.UserHandle),
+		},
+	})
+}
+
+// find 查找依赖方的凭证，id 为空时返回最早注册的凭证
+func (a *Authenticator) find(rpID string, id []byte) *Credential {
+	for _, c := range a.credentials {
+		if c.RPID == rpID && (id == nil || bytes.Equal(c.ID, id)) {
+			return c
+		}
+	}
+	return nil
+}
+
+// rpID 依赖方ID为空时使用页面域名，并校验页面域名属于依赖方
+func (a *Authenticator) rpID(rpID string) (string, error) {
+	origin, err := url.Parse(a.Origin)
+	if err != nil {
+		return "", fmt.Errorf("softauthn: origin 无效: %w", err)
+	}
+	host := origin.Hostname()
+	if rpID == "" {
+		return host, nil
+	}
+	if host != rpID && !hasDomainSuffix(host, rpID) {
+		return "", fmt.Errorf("softauthn: 页面 %s 不能使用依赖方 %s", a.Origin, rpID)
+	}
+	return rpID, nil
+}
+
+// authData rpIdHash(32) | flags(1) | signCount(4)，用户已在身份验证器上完成验证
+func (a *Authenticator) authData(rpID string, flags byte, signCount uint32) []byte {
+	flags |= flagUserPresent | flagUserVerified
+	if a.Synced {
+		flags |= flagBackupEligible | flagBackupState
+	}
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(data, signCount)
+}
+
+func (a *Authenticator) clientData(ceremony protocol.CeremonyType, challenge protocol.URLEncodedBase64) ([]byte, error) {
+	return json.Marshal(protocol.CollectedClientData{
+		Type:      ceremony,
+		Challenge: encode(challenge),
+		Origin:    a.Origin,
+	})
+}
+
+// decodeUserID 解析 JSON 中 base64url 编码的 user.id
+func decodeUserID(id any) ([]byte, error) {
+	s, ok := id.(string)
+	if !ok || s == "" {
+		return nil, errors.New("softauthn: user.id 无效")
+	}
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+func supportsES256(params []protocol.CredentialParameter) bool {
+	for _, p := range params {
+		if p.Type == protocol.PublicKeyCredentialType && p.Algorithm == webauthncose.AlgES256 {
+			return true
+		}
+	}
+	return false
+}
+
+func hasDomainSuffix(host, domain string) bool {
+	return len(host) > len(domain) && host[len(host)-len(domain)-1:] == "."+domain
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,45 @@
+package websocket
+
+import (
+	"context"
+	"strconv"
+)
+
+// Backplane 节点间的消息通道。多实例部署时用户的连接可能在任意实例上，
+// 每个实例只订阅本地有连接的用户频道和有订阅者的主题频道，推送消息时发布到对应频道，由持有连接的实例投递
+type Backplane interface {
+	// Publish 向频道发布消息，订阅了该频道的全部节点（包括发布者自己）都会收到
+	Publish(ctx context.Context, channel string, payload []byte) error
+	// Subscribe 开始接收频道的消息，重复订阅不报错
+	Subscribe(ctx context.Context, channels ...string) error
+	// Unsubscribe 停止接收频道的消息，未订阅的频道忽略
+	Unsubscribe(ctx context.Context, channels ...string) error
+	// Listen 设置收到消息时的处理函数，由 Manager 创建时调用一次，之后才会收到消息
+	Listen(handler func(channel string, payload []byte))
+	// Close 取消全部订阅并释放连接
+	Close() error
+}
+
+// envelope 经消息通道转发的消息，Node 为发布者的节点ID，ID 用于丢弃重复收到的消息，
+// Topic 为主题广播的完整主题，接收方据此匹配本节点的订阅
+type envelope struct {
+	ID    string `json:"id"`
+	Node  string `json:"node"`
+	Topic string `json:"topic,omitempty"`
+	Data  []byte `json:"data"`
+}
+
+const (
+	userChannelPrefix  = "user:"
+	topicChannelPrefix = "topic:"
+)
+
+// userChannel 发送给用户全部连接的消息所在的频道
+func userChannel(userID uint) string {
+	return userChannelPrefix + strconv.FormatUint(uint64(userID), 10)
+}
+
+// topicChannel 主题广播消息所在的频道，同一个根下的主题共用一个频道，订阅通配符模式的节点只需订阅根的频道
+func topicChannel(topic string) string {
+	return topicChannelPrefix + TopicRoot(topic)
+}
@@ -5,6 +5,8 @@ import (
     "log"
     "time"
 
+    "go_app/pkg/errcode"
+
     "github.com/gorilla/websocket"
 )
 
@@ -18,7 +20,7 @@ const (
 // ReadPump 处理从客户端读取消息
 func (c *Client) ReadPump() {
     defer func() {
-        GlobalManager.Unregister <- c  // 添加这行，确保客户端断开时注销
+        c.manager.Unregister(c) // 连接断开时注销，同时取消该连接的全部订阅
         c.Socket.Close()
     }()
 
@@ -38,24 +40,65 @@ func (c *Client) ReadPump() {
             break
         }
 
-        // 处理接收到的消息
-        var msg struct {
-            Action string `json:"action"`
-            Topic  string `json:"topic"`
+        c.handle(message)
+    }
+}
+
+// request 客户端发送的请求，RequestID 由客户端生成，原样带回答复中
+type request struct {
+    Action    string  `json:"action"`
+    RequestID string  `json:"requestId"`
+    Topic     string  `json:"topic"`
+    ID        *uint64 `json:"id"`     // ack 为确认的消息ID，resume 为最后收到的消息ID
+    Status    string  `json:"status"` // presence 上报的连接状态：online / away
+}
+
+// handle 处理客户端的请求：订阅相关请求成功时答复结果，任何请求失败时答复 error 消息
+func (c *Client) handle(message []byte) {
+    var req request
+    if err := json.Unmarshal(message, &req); err != nil {
+        c.replyError("", errcode.WebSocketRequestInvalid)
+        return
+    }
+
+    var err error
+    switch req.Action {
+    case "subscribe":
+        if err = c.manager.Subscribe(c, req.Topic); err == nil {
+            c.manager.reply(c, MessageTypeSubscribed, &SubscriptionReply{RequestID: req.RequestID, Topic: req.Topic})
         }
-        if err := json.Unmarshal(message, &msg); err != nil {
-            log.Printf("消息解析错误: %v", err)
-            continue
+    case "unsubscribe":
+        if err = c.manager.Unsubscribe(c, req.Topic); err == nil {
+            c.manager.reply(c, MessageTypeUnsubscribed, &SubscriptionReply{RequestID: req.RequestID, Topic: req.Topic})
         }
-
-        // 处理订阅/取消订阅操作
-        switch msg.Action {
-        case "subscribe":
-            GlobalManager.Subscribe(c.ID, msg.Topic)
-        case "unsubscribe":
-            GlobalManager.Unsubscribe(c.ID, msg.Topic)
+    case "list_subscriptions":
+        c.manager.reply(c, MessageTypeSubscriptions, &SubscriptionsReply{RequestID: req.RequestID, Topics: c.manager.ClientSubscriptions(c)})
+    case "ack":
+        if req.ID == nil {
+            err = errcode.WebSocketRequestInvalid
+        } else {
+            err = c.manager.Ack(c, *req.ID)
         }
+    case "resume":
+        err = c.manager.Resume(c, req.ID)
+    case "presence":
+        err = c.manager.SetPresence(c, req.Status)
+    default:
+        err = errcode.WebSocketRequestInvalid
+    }
+    if err != nil {
+        c.replyError(req.RequestID, err)
+    }
+}
+
+// replyError 答复请求失败，非业务错误记录日志并按服务器内部错误答复
+func (c *Client) replyError(requestID string, err error) {
+    e, ok := err.(*errcode.ErrorCode)
+    if !ok {
+        log.Printf("处理 WebSocket 请求失败: %v", err)
+        e = errcode.ServerError
     }
+    c.manager.reply(c, MessageTypeError, &ErrorReply{RequestID: requestID, Code: e.Code, Message: e.Message})
 }
 
 // WritePump 处理向客户端发送消息
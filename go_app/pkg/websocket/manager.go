@@ -1,151 +1,550 @@
 package websocket
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
+
+	"go_app/config"
+	"go_app/pkg/errcode"
+	"go_app/utils"
 
 	"github.com/gorilla/websocket"
 )
 
+// ErrTooManyConnections 用户的连接数已达 websocket.max_connections_per_user
+var ErrTooManyConnections = errors.New("连接数已达上限")
+
+const (
+	// backplaneTimeout 发布消息和变更频道订阅的超时时间
+	backplaneTimeout = 5 * time.Second
+	// recentMessages 记录最近收到的消息ID数量，用于丢弃重复投递
+	recentMessages = 4096
+	// maxSubscriptions 每个连接最多订阅的主题和主题模式数
+	maxSubscriptions = 50
+)
+
+// Client 一个 WebSocket 连接。同一用户可以同时保持多个连接（手机、电脑、多个标签页），每个连接有独立的连接ID
 type Client struct {
-	ID     uint
+	ID     string // 连接ID，建立连接时随机生成
+	UserID uint
 	Socket *websocket.Conn
 	Send   chan []byte
+
+	manager *Manager
+	// topics 该连接订阅的主题和主题模式，由 manager.mutex 保护
+	topics map[string]bool
 }
 
+// Manager 管理本节点的全部连接和主题订阅。发送消息时缓冲区已满的连接视为失效并断开，不影响同一用户的其他连接。
+// 消息先投递给本节点的连接，再经 Backplane 发布，由其他节点投递给它们持有的连接。
+// 发给用户的格式化消息先保存到 Outbox，客户端重连后可补发；主题广播不保存
 type Manager struct {
-	Clients       map[uint]*Client
-	Register      chan *Client
-	Unregister    chan *Client
-	Broadcast     chan []byte
-	Subscriptions map[string]map[uint]bool
+	// Clients 用户ID → 连接ID → 连接
+	Clients map[uint]map[string]*Client
+	// Subscriptions 主题模式 → 连接ID → 连接，订阅属于连接，连接断开后自动取消
+	Subscriptions map[string]map[string]*Client
 	mutex         sync.RWMutex
+	cfg           config.WebSocketConfig
+
+	nodeID     string
+	backplane  Backplane
+	outbox     Outbox
+	authorizer TopicAuthorizer
+	presence   PresenceTracker
+	// subMu 串行化 Backplane 的订阅变更，channels 为当前已订阅的频道
+	subMu    sync.Mutex
+	channels map[string]bool
+	recent   *recentIDs
 }
 
-// 添加全局管理器
-var GlobalManager *Manager
+// NewManager 创建一个新的 WebSocket 管理器，节点ID由主机名和随机串组成
+func NewManager(cfg config.WebSocketConfig, backplane Backplane, outbox Outbox, authorizer TopicAuthorizer) (*Manager, error) {
+	suffix, err := utils.RandomHex(4)
+	if err != nil {
+		return nil, err
+	}
+	nodeID := suffix
+	if host, err := os.Hostname(); err == nil && host != "" {
+		nodeID = host + "-" + suffix
+	}
 
-// 保留这个完整实现的 Subscribe 方法
-func (m *Manager) Subscribe(userID uint, topic string) {
+	m := &Manager{
+		Clients:       make(map[uint]map[string]*Client),
+		Subscriptions: make(map[string]map[string]*Client),
+		cfg:           cfg,
+		nodeID:        nodeID,
+		backplane:     backplane,
+		outbox:        outbox,
+		authorizer:    authorizer,
+		channels:      make(map[string]bool),
+		recent:        newRecentIDs(recentMessages),
+	}
+	backplane.Listen(m.receive)
+	return m, nil
+}
+
+// NodeID 本节点的ID，随转发的消息一起发布，用于识别本节点发出的消息
+func (m *Manager) NodeID() string {
+	return m.nodeID
+}
+
+// Close 关闭 Backplane，之后不再收到其他节点转发的消息
+func (m *Manager) Close() error {
+	return m.backplane.Close()
+}
+
+// OnConfigChange 配置热加载回调，更新每个用户的连接数上限，已建立的连接不受影响
+func (m *Manager) OnConfigChange(cfg *config.Config) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
+	m.cfg = cfg.WebSocket
+}
 
-	if m.Subscriptions[topic] == nil {
-		m.Subscriptions[topic] = make(map[uint]bool)
+// NewClient 为用户的新连接创建客户端，调用 Register 后开始收发消息
+func (m *Manager) NewClient(userID uint, socket *websocket.Conn) (*Client, error) {
+	id, err := utils.RandomHex(8)
+	if err != nil {
+		return nil, err
 	}
-	m.Subscriptions[topic][userID] = true
+	return &Client{
+		ID:      id,
+		UserID:  userID,
+		Socket:  socket,
+		Send:    make(chan []byte, 256),
+		manager: m,
+		topics:  make(map[string]bool),
+	}, nil
 }
 
-// 保留这个完整实现的 Unsubscribe 方法
-func (m *Manager) Unsubscribe(userID uint, topic string) {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
+// CanConnect 用户是否还能建立新连接，用于在升级协议前提前拒绝；并发建立连接时以 Register 的结果为准。
+// 连接数上限按节点计算
+func (m *Manager) CanConnect(userID uint) bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return len(m.Clients[userID]) < m.cfg.MaxConnectionsPerUser
+}
 
-	if subs, exists := m.Subscriptions[topic]; exists {
-		delete(subs, userID)
+// Register 登记连接并发送连接成功消息，用户的连接数已达上限时返回 ErrTooManyConnections
+func (m *Manager) Register(client *Client) error {
+	msg, err := NewMessage(MessageTypeSystem, map[string]interface{}{
+		"message":      "WebSocket 连接成功",
+		"userId":       client.UserID,
+		"connectionId": client.ID,
+	}).ToJSON()
+	if err != nil {
+		return err
 	}
+
+	m.mutex.Lock()
+	conns := m.Clients[client.UserID]
+	if len(conns) >= m.cfg.MaxConnectionsPerUser {
+		m.mutex.Unlock()
+		return ErrTooManyConnections
+	}
+	if conns == nil {
+		conns = make(map[string]*Client)
+		m.Clients[client.UserID] = conns
+	}
+	conns[client.ID] = client
+	m.mutex.Unlock()
+
+	m.syncChannels(userChannel(client.UserID))
+	m.deliver([]*Client{client}, msg)
+	m.connected(client)
+	return nil
 }
 
-// NewManager 创建一个新的 WebSocket 管理器
-func NewManager() *Manager {
-	return &Manager{
-		Clients:       make(map[uint]*Client),
-		Register:      make(chan *Client),
-		Unregister:    make(chan *Client),
-		Broadcast:     make(chan []byte),
-		Subscriptions: make(map[string]map[uint]bool),
+// Unregister 注销连接并取消它的全部订阅，连接已被注销时不做任何操作
+func (m *Manager) Unregister(client *Client) {
+	m.mutex.Lock()
+	channels := m.remove(client)
+	m.mutex.Unlock()
+	m.syncChannels(channels...)
+	if channels != nil {
+		m.disconnected(client)
 	}
 }
 
-// Start 启动 WebSocket 管理器
-func (m *Manager) Start() {
-	for {
-		select {
-		case client := <-m.Register:
-			m.mutex.Lock()
-			m.Clients[client.ID] = client
-			m.mutex.Unlock()
-			
-			// 发送连接成功消息
-			m.SendFormattedMessage(client.ID, MessageTypeSystem, map[string]interface{}{
-				"message": "WebSocket 连接成功",
-				"userId": client.ID,
-			})
-
-		case client := <-m.Unregister:
-			if _, ok := m.Clients[client.ID]; ok {
-				m.mutex.Lock()
-				delete(m.Clients, client.ID)
-				close(client.Send)
-				m.mutex.Unlock()
+// remove 移除连接并关闭发送通道，返回可能不再需要订阅的频道，连接已被移除时返回 nil，调用方需持有写锁。
+// 发送通道只在写锁下关闭、只在读锁下写入，不会向已关闭的通道发送消息
+func (m *Manager) remove(client *Client) []string {
+	conns := m.Clients[client.UserID]
+	if conns[client.ID] != client {
+		return nil
+	}
+	delete(conns, client.ID)
+	if len(conns) == 0 {
+		delete(m.Clients, client.UserID)
+	}
+	channels := []string{userChannel(client.UserID)}
+	for topic := range client.topics {
+		if subs := m.Subscriptions[topic]; subs != nil {
+			delete(subs, client.ID)
+			if len(subs) == 0 {
+				delete(m.Subscriptions, topic)
 			}
+		}
+		channels = append(channels, topicChannel(topic))
+	}
+	client.topics = nil
+	close(client.Send)
+	return channels
+}
 
-		case message := <-m.Broadcast:
-			m.mutex.RLock()
-			for _, client := range m.Clients {
-				select {
-				case client.Send <- message:
-				default:
-					close(client.Send)
-					delete(m.Clients, client.ID)
-				}
-			}
-			m.mutex.RUnlock()
+// Subscribe 连接订阅主题或主题模式。格式无效、没有权限或连接的订阅数已达上限时返回对应的错误码，
+// 重复订阅同一模式不报错
+func (m *Manager) Subscribe(client *Client, pattern string) error {
+	if err := ValidatePattern(pattern); err != nil {
+		return err
+	}
+	if err := m.authorize(client.UserID, pattern); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	// 已注销的连接不再订阅，避免残留在订阅表中
+	if m.Clients[client.UserID][client.ID] != client {
+		m.mutex.Unlock()
+		return nil
+	}
+	if !client.topics[pattern] && len(client.topics) >= maxSubscriptions {
+		m.mutex.Unlock()
+		return errcode.WebSocketSubscriptionLimit
+	}
+	if m.Subscriptions[pattern] == nil {
+		m.Subscriptions[pattern] = make(map[string]*Client)
+	}
+	m.Subscriptions[pattern][client.ID] = client
+	client.topics[pattern] = true
+	m.mutex.Unlock()
+
+	m.syncChannels(topicChannel(pattern))
+	return nil
+}
+
+// Unsubscribe 连接取消订阅主题或主题模式，只取消完全相同的模式，未订阅时不报错
+func (m *Manager) Unsubscribe(client *Client, pattern string) error {
+	if err := ValidatePattern(pattern); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	if subs, exists := m.Subscriptions[pattern]; exists {
+		delete(subs, client.ID)
+		if len(subs) == 0 {
+			delete(m.Subscriptions, pattern)
 		}
 	}
+	delete(client.topics, pattern)
+	m.mutex.Unlock()
+
+	m.syncChannels(topicChannel(pattern))
+	return nil
 }
 
-func (m *Manager) SendFormattedMessage(userID uint, msgType string, data interface{}) error {
-	msg := NewMessage(msgType, data)
-	jsonData, err := msg.ToJSON()
+// ClientSubscriptions 连接订阅的主题和主题模式，按字母顺序
+func (m *Manager) ClientSubscriptions(client *Client) []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	topics := make([]string, 0, len(client.topics))
+	for topic := range client.topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// authorize 检查用户能否订阅，没有设置 TopicAuthorizer 时全部拒绝
+func (m *Manager) authorize(userID uint, pattern string) error {
+	if m.authorizer == nil {
+		return errcode.WebSocketTopicForbidden
+	}
+	return m.authorizer(userID, pattern)
+}
+
+// Ack 记录客户端确认收到的消息ID
+func (m *Manager) Ack(client *Client, id uint64) error {
+	return m.outbox.Ack(client.UserID, id)
+}
+
+// Resume 向连接补发用户在 lastID 之后的消息，lastID 为空时从用户确认过的最大消息ID之后开始。
+// 有消息无法补发时先发送一条 gap 消息。补发的消息可能与实时推送的消息交错，客户端按消息ID去重和排序
+func (m *Manager) Resume(client *Client, lastID *uint64) error {
+	var after uint64
+	if lastID != nil {
+		after = *lastID
+	} else {
+		acked, err := m.outbox.Acked(client.UserID)
+		if err != nil {
+			return err
+		}
+		after = acked
+	}
+
+	messages, gap, err := m.outbox.Replay(client.UserID, after)
 	if err != nil {
 		return err
 	}
-	m.SendToUser(userID, jsonData)
+	if gap != nil {
+		messages = append([]*Message{NewMessage(MessageTypeGap, gap)}, messages...)
+	}
+	for _, msg := range messages {
+		jsonData, err := msg.ToJSON()
+		if err != nil {
+			return err
+		}
+		m.deliver([]*Client{client}, jsonData)
+	}
 	return nil
 }
 
-func (m *Manager) SendToUser(userID uint, jsonData []byte) {
+// reply 答复连接发送的请求
+func (m *Manager) reply(client *Client, msgType string, data interface{}) {
+	jsonData, err := NewMessage(msgType, data).ToJSON()
+	if err != nil {
+		log.Printf("生成 WebSocket 答复失败: %v", err)
+		return
+	}
+	m.deliver([]*Client{client}, jsonData)
+}
+
+// ConnectionCount 用户在本节点的连接数
+func (m *Manager) ConnectionCount(userID uint) int {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
+	return len(m.Clients[userID])
+}
 
-	if client, ok := m.Clients[userID]; ok {
-		select {
-		case client.Send <- jsonData:
-			// 消息已发送到客户端的缓冲通道
-		default:
-			// 客户端缓冲区已满，关闭连接并清理资源
-			close(client.Send)
-			delete(m.Clients, userID)
-		}
+// SendFormattedMessage 保存消息到用户的发件箱并分配消息ID，再向用户的全部连接发送，包括其他节点上的连接。
+// 保存失败时不发送
+func (m *Manager) SendFormattedMessage(userID uint, msgType string, data interface{}) error {
+	msg := NewMessage(msgType, data)
+	if err := m.outbox.Append(userID, msg); err != nil {
+		return err
+	}
+	jsonData, err := msg.ToJSON()
+	if err != nil {
+		return err
+	}
+	return m.send(userChannel(userID), "", m.userClients(userID), jsonData)
+}
+
+// SendToUser 向用户的全部连接发送消息，包括其他节点上的连接。消息不保存到发件箱，错过后无法补发
+func (m *Manager) SendToUser(userID uint, jsonData []byte) {
+	if err := m.send(userChannel(userID), "", m.userClients(userID), jsonData); err != nil {
+		log.Printf("转发用户 %d 的 WebSocket 消息失败: %v", userID, err)
 	}
 }
 
+// BroadcastToTopic 向订阅了匹配该主题的模式的全部连接发送消息，包括其他节点上的连接。主题不能包含通配符
 func (m *Manager) BroadcastToTopic(topic string, msgType string, data interface{}) error {
+	if err := ValidateTopic(topic); err != nil {
+		return err
+	}
 	msg := NewMessage(msgType, data)
 	jsonData, err := msg.ToJSON()
 	if err != nil {
 		return err
 	}
+	return m.send(topicChannel(topic), topic, m.topicClients(topic), jsonData)
+}
+
+// send 投递给本节点的连接，再发布到 Backplane。发布失败时本节点的连接已收到消息
+func (m *Manager) send(channel, topic string, local []*Client, jsonData []byte) error {
+	m.deliver(local, jsonData)
+
+	id, err := utils.RandomHex(8)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(&envelope{ID: id, Node: m.nodeID, Topic: topic, Data: jsonData})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+	return m.backplane.Publish(ctx, channel, payload)
+}
 
+// receive 处理 Backplane 转发的消息。本节点发布的消息已直接投递，重复收到的消息只投递一次
+func (m *Manager) receive(channel string, payload []byte) {
+	var env envelope
+	if err := json.Unmarshal(payload, &env); err != nil {
+		log.Printf("解析频道 %s 的 WebSocket 消息失败: %v", channel, err)
+		return
+	}
+	if env.Node == m.nodeID || !m.recent.add(env.ID) {
+		return
+	}
+	if env.Topic != "" {
+		m.deliver(m.topicClients(env.Topic), env.Data)
+		return
+	}
+	if rest, ok := strings.CutPrefix(channel, userChannelPrefix); ok {
+		if userID, err := strconv.ParseUint(rest, 10, 0); err == nil {
+			m.deliver(m.userClients(uint(userID)), env.Data)
+		}
+	}
+}
+
+// userClients 用户在本节点的连接
+func (m *Manager) userClients(userID uint) []*Client {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	clients := make([]*Client, 0, len(m.Clients[userID]))
+	for _, client := range m.Clients[userID] {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// topicClients 本节点上订阅了匹配主题的模式的连接，订阅了多个匹配模式的连接只出现一次
+func (m *Manager) topicClients(topic string) []*Client {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
 
-	if subs, exists := m.Subscriptions[topic]; exists {
-		for userID := range subs {
-			if client, ok := m.Clients[userID]; ok {
-				select {
-				case client.Send <- jsonData:
-					// 消息已发送
-				default:
-					// 客户端缓冲区已满
-					close(client.Send)
-					delete(m.Clients, userID)
-					delete(subs, userID)
-				}
-			} else {
-				delete(subs, userID)
+	seen := make(map[string]bool)
+	var clients []*Client
+	for pattern, subs := range m.Subscriptions {
+		if !MatchTopic(pattern, topic) {
+			continue
+		}
+		for id, client := range subs {
+			if !seen[id] {
+				seen[id] = true
+				clients = append(clients, client)
+			}
+		}
+	}
+	return clients
+}
+
+// wantsChannel 本节点是否需要接收频道的消息：用户频道在用户有连接时需要，主题频道在有以该根开头的订阅时需要。
+// 调用方需持有读锁
+func (m *Manager) wantsChannel(channel string) bool {
+	if root, ok := strings.CutPrefix(channel, topicChannelPrefix); ok {
+		for pattern := range m.Subscriptions {
+			if TopicRoot(pattern) == root {
+				return true
 			}
 		}
+		return false
 	}
-	return nil
+	if rest, ok := strings.CutPrefix(channel, userChannelPrefix); ok {
+		if userID, err := strconv.ParseUint(rest, 10, 0); err == nil {
+			return len(m.Clients[uint(userID)]) > 0
+		}
+	}
+	return false
+}
+
+// syncChannels 按本节点当前的连接和订阅更新 Backplane 上的频道订阅：
+// 用户有连接时订阅用户频道，有以某个根开头的主题订阅时订阅该根的主题频道。
+// 每次都按最新状态判断并串行执行，并发的注册和注销最终会收敛；失败的变更在该频道下次变化时重试
+func (m *Manager) syncChannels(channels ...string) {
+	m.subMu.Lock()
+	defer m.subMu.Unlock()
+
+	for _, channel := range channels {
+		m.mutex.RLock()
+		want := m.wantsChannel(channel)
+		m.mutex.RUnlock()
+		if want == m.channels[channel] {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+		var err error
+		if want {
+			err = m.backplane.Subscribe(ctx, channel)
+		} else {
+			err = m.backplane.Unsubscribe(ctx, channel)
+		}
+		cancel()
+		if err != nil {
+			log.Printf("更新 WebSocket 频道 %s 的订阅失败: %v", channel, err)
+			continue
+		}
+		if want {
+			m.channels[channel] = true
+		} else {
+			delete(m.channels, channel)
+		}
+	}
+}
+
+// deliver 将消息放入各连接的发送缓冲区，缓冲区已满的连接被断开
+func (m *Manager) deliver(clients []*Client, jsonData []byte) {
+	var slow []*Client
+	m.mutex.RLock()
+	for _, client := range clients {
+		// 取得读锁前连接可能已被注销，发送通道已关闭
+		if m.Clients[client.UserID][client.ID] != client {
+			continue
+		}
+		select {
+		case client.Send <- jsonData:
+			// 消息已发送到客户端的缓冲通道
+		default:
+			slow = append(slow, client)
+		}
+	}
+	m.mutex.RUnlock()
+
+	if len(slow) == 0 {
+		return
+	}
+	var channels []string
+	var removed []*Client
+	m.mutex.Lock()
+	for _, client := range slow {
+		if ch := m.remove(client); ch != nil {
+			channels = append(channels, ch...)
+			removed = append(removed, client)
+		}
+	}
+	m.mutex.Unlock()
+	m.syncChannels(channels...)
+	for _, client := range removed {
+		m.disconnected(client)
+	}
+}
+
+// recentIDs 最近收到的消息ID，超出容量时淘汰最早记录的
+type recentIDs struct {
+	mu   sync.Mutex
+	ids  map[string]bool
+	ring []string
+	next int
+}
+
+func newRecentIDs(size int) *recentIDs {
+	return &recentIDs{ids: make(map[string]bool, size), ring: make([]string, size)}
+}
+
+// add 记录消息ID，已记录过时返回 false
+func (r *recentIDs) add(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.ids[id] {
+		return false
+	}
+	if old := r.ring[r.next]; old != "" {
+		delete(r.ids, old)
+	}
+	r.ring[r.next] = id
+	r.next = (r.next + 1) % len(r.ring)
+	r.ids[id] = true
+	return true
 }
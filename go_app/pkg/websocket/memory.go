@@ -0,0 +1,81 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryHub 进程内的消息总线，连接到同一个 MemoryHub 的 Backplane 互相转发消息。
+// 单实例部署时只有一个节点；测试中可以在同一进程里用多个 Manager 模拟多个实例
+type MemoryHub struct {
+	mu    sync.RWMutex
+	nodes map[*memoryBackplane]bool
+}
+
+// NewMemoryHub 创建进程内的消息总线
+func NewMemoryHub() *MemoryHub {
+	return &MemoryHub{nodes: make(map[*memoryBackplane]bool)}
+}
+
+type memoryBackplane struct {
+	hub      *MemoryHub
+	channels map[string]bool // 由 hub.mu 保护
+	handler  func(channel string, payload []byte)
+}
+
+// NewMemoryBackplane 在进程内消息总线上创建一个节点，Publish 在调用方的 goroutine 中同步投递
+func NewMemoryBackplane(hub *MemoryHub) Backplane {
+	b := &memoryBackplane{hub: hub, channels: make(map[string]bool)}
+	hub.mu.Lock()
+	hub.nodes[b] = true
+	hub.mu.Unlock()
+	return b
+}
+
+func (b *memoryBackplane) Publish(ctx context.Context, channel string, payload []byte) error {
+	// 复制订阅者后再投递，处理函数中可能再次订阅或取消订阅
+	b.hub.mu.RLock()
+	var handlers []func(string, []byte)
+	for node := range b.hub.nodes {
+		if node.channels[channel] && node.handler != nil {
+			handlers = append(handlers, node.handler)
+		}
+	}
+	b.hub.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(channel, payload)
+	}
+	return nil
+}
+
+func (b *memoryBackplane) Subscribe(ctx context.Context, channels ...string) error {
+	b.hub.mu.Lock()
+	defer b.hub.mu.Unlock()
+	for _, channel := range channels {
+		b.channels[channel] = true
+	}
+	return nil
+}
+
+func (b *memoryBackplane) Unsubscribe(ctx context.Context, channels ...string) error {
+	b.hub.mu.Lock()
+	defer b.hub.mu.Unlock()
+	for _, channel := range channels {
+		delete(b.channels, channel)
+	}
+	return nil
+}
+
+func (b *memoryBackplane) Listen(handler func(channel string, payload []byte)) {
+	b.hub.mu.Lock()
+	defer b.hub.mu.Unlock()
+	b.handler = handler
+}
+
+func (b *memoryBackplane) Close() error {
+	b.hub.mu.Lock()
+	defer b.hub.mu.Unlock()
+	delete(b.hub.nodes, b)
+	return nil
+}
@@ -8,12 +8,42 @@ import (
 // 消息类型常量
 const (
 	MessageTypeNotification = "notification"
-	MessageTypeActivity    = "activity"
-	MessageTypeSystem      = "system"
+	MessageTypeActivity     = "activity"
+	MessageTypeSystem       = "system"
+	MessageTypeGap          = "gap"      // 补发时部分消息已超出保留期限，data 为 Gap
+	MessageTypePresence     = "presence" // 用户在线状态变化，发布到该用户的在线状态主题 presence:{id}
 )
 
-// Message WebSocket消息结构
+// 答复客户端请求的消息类型
+const (
+	MessageTypeSubscribed    = "subscribed"    // 订阅成功，data 为 SubscriptionReply
+	MessageTypeUnsubscribed  = "unsubscribed"  // 取消订阅成功，data 为 SubscriptionReply
+	MessageTypeSubscriptions = "subscriptions" // 当前连接的订阅，data 为 SubscriptionsReply
+	MessageTypeError         = "error"         // 请求失败，data 为 ErrorReply
+)
+
+// SubscriptionReply 订阅和取消订阅的答复，RequestID 为请求中的 requestId
+type SubscriptionReply struct {
+	RequestID string `json:"requestId,omitempty"`
+	Topic     string `json:"topic"`
+}
+
+// SubscriptionsReply list_subscriptions 的答复
+type SubscriptionsReply struct {
+	RequestID string   `json:"requestId,omitempty"`
+	Topics    []string `json:"topics"`
+}
+
+// ErrorReply 请求失败的答复，Code 和 Message 与 HTTP 接口的错误码一致
+type ErrorReply struct {
+	RequestID string `json:"requestId,omitempty"`
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+}
+
+// Message WebSocket消息结构，ID 为发给用户的消息在该用户发件箱中的ID，主题广播和系统消息没有ID
 type Message struct {
+	ID   uint64      `json:"id,omitempty"`
 	Type string      `json:"type"`
 	Data interface{} `json:"data"`
 	Time int64       `json:"time"`
@@ -0,0 +1,21 @@
+package websocket
+
+// Outbox 用户消息的持久化发件箱。发给用户的格式化消息先保存并分配用户内单调递增的消息ID，
+// 连接断开或发送缓冲区已满而错过消息的客户端重连后发送 resume 补发
+type Outbox interface {
+	// Append 保存发给用户的消息并填充 msg.ID
+	Append(userID uint, msg *Message) error
+	// Replay 按消息ID升序返回用户在 afterID 之后的消息；
+	// 其中一段消息超出保留时长或数量上限、无法补发时 gap 不为空
+	Replay(userID uint, afterID uint64) (messages []*Message, gap *Gap, err error)
+	// Ack 记录客户端确认收到的消息ID，同一用户的全部连接共享
+	Ack(userID uint, id uint64) error
+	// Acked 用户确认过的最大消息ID
+	Acked(userID uint) (uint64, error)
+}
+
+// Gap 无法补发的消息ID范围，包含两端
+type Gap struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+}
@@ -0,0 +1,48 @@
+package websocket
+
+import (
+	"strconv"
+
+	"go_app/pkg/errcode"
+)
+
+// PresenceTracker 跟踪用户的在线状态。Manager 在本节点的连接登记成功、被注销以及客户端上报状态时调用，
+// 调用时不持有 Manager 的锁
+type PresenceTracker interface {
+	// Connected 连接已登记
+	Connected(userID uint, connectionID string)
+	// Disconnected 连接已注销，包括因发送缓冲区已满被断开的连接
+	Disconnected(userID uint, connectionID string)
+	// SetStatus 客户端上报连接的状态（如页面切到后台时为 away），状态无效时返回错误码
+	SetStatus(userID uint, connectionID, status string) error
+}
+
+// PresenceTopic 用户的在线状态主题，状态变化时向该主题广播 presence 消息
+func PresenceTopic(userID uint) string {
+	return "presence:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// SetPresenceTracker 设置在线状态跟踪，需要在开始接受连接前调用
+func (m *Manager) SetPresenceTracker(tracker PresenceTracker) {
+	m.presence = tracker
+}
+
+// SetPresence 客户端上报连接的在线状态，没有设置 PresenceTracker 时不支持该请求
+func (m *Manager) SetPresence(client *Client, status string) error {
+	if m.presence == nil {
+		return errcode.WebSocketRequestInvalid
+	}
+	return m.presence.SetStatus(client.UserID, client.ID, status)
+}
+
+func (m *Manager) connected(client *Client) {
+	if m.presence != nil {
+		m.presence.Connected(client.UserID, client.ID)
+	}
+}
+
+func (m *Manager) disconnected(client *Client) {
+	if m.presence != nil {
+		m.presence.Disconnected(client.UserID, client.ID)
+	}
+}
@@ -0,0 +1,201 @@
+package websocket_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/pkg/websocket"
+	"go_app/repository"
+	"go_app/repository/repotest"
+	"go_app/services"
+)
+
+func TestPresence(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		testPresence(t, func(t *testing.T) (func() websocket.Backplane, repository.PresenceRepository) {
+			hub := websocket.NewMemoryHub()
+			return func() websocket.Backplane { return websocket.NewMemoryBackplane(hub) }, repository.NewMemoryPresenceRepository()
+		})
+	})
+	t.Run("Redis", func(t *testing.T) {
+		testPresence(t, func(t *testing.T) (func() websocket.Backplane, repository.PresenceRepository) {
+			client := repotest.OpenRedis(t)
+			return func() websocket.Backplane { return websocket.NewRedisBackplane(client) }, repository.NewRedisPresenceRepository(client)
+		})
+	})
+}
+
+// testPresence 在线状态行为测试，newCluster 每次返回一个新的消息总线和共享的在线状态存储，
+// 调用返回的函数在该总线上创建一个节点。宽限期为 1 秒
+func testPresence(t *testing.T, newCluster func(t *testing.T) (func() websocket.Backplane, repository.PresenceRepository)) {
+	grace := time.Duration(testConfig().PresenceGracePeriod) * time.Second
+
+	// node 一个实例：Manager 和设置为其 PresenceTracker 的在线状态服务
+	type node struct {
+		m        *websocket.Manager
+		presence *services.PresenceService
+	}
+	// newNodes 创建共享消息总线、在线状态存储和用户仓储的多个实例，以及用户 1（被观察者）和 2（观察者）
+	newNodes := func(t *testing.T, n int) ([]node, repository.PresenceRepository) {
+		newNode, store := newCluster(t)
+		users := repository.NewMemoryUserRepository()
+		for _, name := range []string{"watched", "watcher"} {
+			if err := users.Create(&models.User{Username: name, Email: name + "@example.com"}); err != nil {
+				t.Fatalf("创建用户失败: %v", err)
+			}
+		}
+		outbox := newMemoryOutbox()
+		nodes := make([]node, n)
+		for i := range nodes {
+			m := newManager(t, newNode(), outbox)
+			nodes[i] = node{m: m, presence: services.NewPresenceService(store, users, m, testConfig())}
+			m.SetPresenceTracker(nodes[i].presence)
+		}
+		return nodes, store
+	}
+	// watch 在第一个实例上订阅用户 1 的在线状态
+	watch := func(t *testing.T, nodes []node) *websocket.Client {
+		t.Helper()
+		watcher := connect(t, nodes[0].m, 2)
+		subscribe(t, nodes[0].m, watcher, websocket.PresenceTopic(1))
+		time.Sleep(settle)
+		return watcher
+	}
+
+	t.Run("OnlineOffline", func(t *testing.T) {
+		nodes, _ := newNodes(t, 2)
+		watcher := watch(t, nodes)
+
+		client := connect(t, nodes[1].m, 1)
+		expectPresence(t, watcher, models.PresenceOnline)
+		expectStatus(t, nodes[0].presence, models.PresenceOnline)
+
+		// 宽限期内仍为在线
+		nodes[1].m.Unregister(client)
+		expectPresence(t, watcher)
+		expectStatus(t, nodes[0].presence, models.PresenceOnline)
+
+		time.Sleep(grace)
+		presence := expectPresence(t, watcher, models.PresenceOffline)
+		if presence.LastSeenAt == nil {
+			t.Fatal("离线事件应带有最后在线时间")
+		}
+		got := expectStatus(t, nodes[0].presence, models.PresenceOffline)
+		if got.LastSeenAt == nil || !got.LastSeenAt.Equal(*presence.LastSeenAt) {
+			t.Fatalf("LastSeenAt = %v, want %v", got.LastSeenAt, presence.LastSeenAt)
+		}
+	})
+
+	t.Run("ReconnectWithinGrace", func(t *testing.T) {
+		nodes, _ := newNodes(t, 2)
+		watcher := watch(t, nodes)
+
+		client := connect(t, nodes[1].m, 1)
+		expectPresence(t, watcher, models.PresenceOnline)
+
+		// 断开后在宽限期内连到另一个实例，不产生下线和上线事件
+		nodes[1].m.Unregister(client)
+		connect(t, nodes[0].m, 1)
+		time.Sleep(grace)
+		expectPresence(t, watcher)
+		expectStatus(t, nodes[1].presence, models.PresenceOnline)
+	})
+
+	t.Run("Away", func(t *testing.T) {
+		nodes, _ := newNodes(t, 2)
+		watcher := watch(t, nodes)
+
+		c1 := connect(t, nodes[0].m, 1)
+		c2 := connect(t, nodes[1].m, 1)
+		expectPresence(t, watcher, models.PresenceOnline)
+
+		// 全部连接都离开时才为 away
+		setPresence(t, nodes[0].m, c1, models.PresenceAway)
+		expectPresence(t, watcher)
+		setPresence(t, nodes[1].m, c2, models.PresenceAway)
+		expectPresence(t, watcher, models.PresenceAway)
+		expectStatus(t, nodes[0].presence, models.PresenceAway)
+
+		setPresence(t, nodes[0].m, c1, models.PresenceOnline)
+		expectPresence(t, watcher, models.PresenceOnline)
+
+		for _, status := range []string{models.PresenceOffline, "busy", ""} {
+			expectCode(t, nodes[0].m.SetPresence(c1, status), errcode.PresenceStatusInvalid)
+		}
+	})
+
+	t.Run("ExpiredNode", func(t *testing.T) {
+		nodes, store := newNodes(t, 2)
+		watcher := watch(t, nodes)
+
+		client := connect(t, nodes[1].m, 1)
+		expectPresence(t, watcher, models.PresenceOnline)
+
+		// 模拟第二个实例异常退出：它的连接记录不再续期，到期后由其他实例在续期时发现
+		if err := store.Touch(1, client.ID, models.PresenceOnline, time.Now().Add(100*time.Millisecond)); err != nil {
+			t.Fatalf("Touch 失败: %v", err)
+		}
+		nodes[0].presence.Heartbeat()
+		expectPresence(t, watcher)
+
+		time.Sleep(200 * time.Millisecond)
+		nodes[0].presence.Heartbeat()
+		expectPresence(t, watcher, models.PresenceOffline)
+	})
+
+	t.Run("Heartbeat", func(t *testing.T) {
+		nodes, store := newNodes(t, 1)
+		client := connect(t, nodes[0].m, 1)
+
+		// 续期覆盖连接记录的失效时间
+		if err := store.Touch(1, client.ID, models.PresenceOnline, time.Now().Add(100*time.Millisecond)); err != nil {
+			t.Fatalf("Touch 失败: %v", err)
+		}
+		nodes[0].presence.Heartbeat()
+		time.Sleep(200 * time.Millisecond)
+		expectStatus(t, nodes[0].presence, models.PresenceOnline)
+	})
+}
+
+func setPresence(t *testing.T, m *websocket.Manager, client *websocket.Client, status string) {
+	t.Helper()
+	if err := m.SetPresence(client, status); err != nil {
+		t.Fatalf("SetPresence(%q) 失败: %v", status, err)
+	}
+}
+
+// expectPresence 按顺序检查连接收到的用户 1 的在线状态事件，之后不再收到其他消息，返回最后一个事件
+func expectPresence(t *testing.T, client *websocket.Client, want ...string) *models.UserPresence {
+	t.Helper()
+	var presence *models.UserPresence
+	for i, data := range receive(t, client, len(want)) {
+		var msg struct {
+			Type string               `json:"type"`
+			Data *models.UserPresence `json:"data"`
+		}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("解析消息失败: %v", err)
+		}
+		if msg.Type != websocket.MessageTypePresence || msg.Data == nil || msg.Data.UserID != 1 || msg.Data.Status != want[i] {
+			t.Fatalf("连接收到 %s, want 用户 1 的 %s 事件", data, want[i])
+		}
+		presence = msg.Data
+	}
+	return presence
+}
+
+// expectStatus 检查接口查询到的用户 1 的在线状态
+func expectStatus(t *testing.T, presence *services.PresenceService, want string) *models.UserPresence {
+	t.Helper()
+	presences, err := presence.Get([]uint{1})
+	if err != nil {
+		t.Fatalf("Get 失败: %v", err)
+	}
+	if len(presences) != 1 || presences[0].Status != want {
+		t.Fatalf("Get = %+v, want %s", presences, want)
+	}
+	return presences[0]
+}
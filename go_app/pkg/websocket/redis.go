@@ -0,0 +1,60 @@
+package websocket
+
+import (
+	"context"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisChannelPrefix Redis 中 WebSocket 消息频道的前缀
+const redisChannelPrefix = "go_app:ws:"
+
+type redisBackplane struct {
+	client redis.UniversalClient
+	pubsub *redis.PubSub
+}
+
+// NewRedisBackplane 基于 Redis 发布订阅的消息通道，多个实例通过同一个 Redis 转发消息。
+// 订阅使用一条独立的连接，断线后由客户端自动重连并恢复订阅，断线期间发布的消息会丢失
+func NewRedisBackplane(client redis.UniversalClient) Backplane {
+	return &redisBackplane{
+		client: client,
+		pubsub: client.Subscribe(context.Background()),
+	}
+}
+
+func (b *redisBackplane) Publish(ctx context.Context, channel string, payload []byte) error {
+	return b.client.Publish(ctx, redisChannelPrefix+channel, payload).Err()
+}
+
+func (b *redisBackplane) Subscribe(ctx context.Context, channels ...string) error {
+	return b.pubsub.Subscribe(ctx, prefixChannels(channels)...)
+}
+
+func (b *redisBackplane) Unsubscribe(ctx context.Context, channels ...string) error {
+	return b.pubsub.Unsubscribe(ctx, prefixChannels(channels)...)
+}
+
+func (b *redisBackplane) Listen(handler func(channel string, payload []byte)) {
+	messages := b.pubsub.Channel()
+	go func() {
+		for msg := range messages {
+			if channel, ok := strings.CutPrefix(msg.Channel, redisChannelPrefix); ok {
+				handler(channel, []byte(msg.Payload))
+			}
+		}
+	}()
+}
+
+func (b *redisBackplane) Close() error {
+	return b.pubsub.Close()
+}
+
+func prefixChannels(channels []string) []string {
+	prefixed := make([]string, len(channels))
+	for i, channel := range channels {
+		prefixed[i] = redisChannelPrefix + channel
+	}
+	return prefixed
+}
@@ -0,0 +1,84 @@
+package websocket
+
+import (
+	"strings"
+
+	"go_app/pkg/errcode"
+)
+
+// 主题由 . 分隔的若干段组成，如 activity、activity.login、user:42.security，每段只能包含字母、数字和 _ : -。
+// 订阅时可以使用通配符：* 匹配一段，# 只能作为最后一段、匹配零段或多段。
+// 第一段（根）不能是通配符，订阅权限按根判断，跨节点转发也按根划分频道
+const (
+	topicSeparator = "."
+	wildcardOne    = "*"
+	wildcardMany   = "#"
+	maxTopicLength = 200
+)
+
+// TopicAuthorizer 判断用户能否订阅主题或主题模式，pattern 已通过格式校验。
+// 拒绝时返回 errcode.WebSocketTopicForbidden，其他错误按服务器内部错误答复客户端
+type TopicAuthorizer func(userID uint, pattern string) error
+
+// ValidateTopic 校验发布消息使用的主题，不能包含通配符
+func ValidateTopic(topic string) error {
+	return validateTopic(topic, false)
+}
+
+// ValidatePattern 校验订阅使用的主题模式
+func ValidatePattern(pattern string) error {
+	return validateTopic(pattern, true)
+}
+
+func validateTopic(topic string, wildcard bool) error {
+	if topic == "" || len(topic) > maxTopicLength {
+		return errcode.WebSocketTopicInvalid
+	}
+	segments := strings.Split(topic, topicSeparator)
+	for i, segment := range segments {
+		switch {
+		case segment == wildcardOne && wildcard && i > 0:
+		case segment == wildcardMany && wildcard && i > 0 && i == len(segments)-1:
+		case validSegment(segment):
+		default:
+			return errcode.WebSocketTopicInvalid
+		}
+	}
+	return nil
+}
+
+func validSegment(segment string) bool {
+	if segment == "" {
+		return false
+	}
+	for _, r := range segment {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == ':' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// TopicRoot 主题或主题模式的第一段
+func TopicRoot(topic string) string {
+	root, _, _ := strings.Cut(topic, topicSeparator)
+	return root
+}
+
+// MatchTopic 主题模式是否匹配主题，不含通配符的模式只匹配相同的主题
+func MatchTopic(pattern, topic string) bool {
+	if pattern == topic {
+		return true
+	}
+	patternSegments := strings.Split(pattern, topicSeparator)
+	topicSegments := strings.Split(topic, topicSeparator)
+	for i, segment := range patternSegments {
+		if segment == wildcardMany {
+			return true
+		}
+		if i >= len(topicSegments) || segment != wildcardOne && segment != topicSegments[i] {
+			return false
+		}
+	}
+	return len(patternSegments) == len(topicSegments)
+}
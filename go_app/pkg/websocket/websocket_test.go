@@ -0,0 +1,626 @@
+package websocket_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go_app/config"
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/pkg/websocket"
+	"go_app/repository"
+	"go_app/repository/repotest"
+	"go_app/services"
+)
+
+// settle 等待订阅变更生效。Redis 的订阅命令不等待确认，紧接着发布的消息可能收不到
+const settle = 100 * time.Millisecond
+
+func TestBackplane(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		testBackplane(t, func(t *testing.T) func() websocket.Backplane {
+			hub := websocket.NewMemoryHub()
+			return func() websocket.Backplane { return websocket.NewMemoryBackplane(hub) }
+		})
+	})
+	t.Run("Redis", func(t *testing.T) {
+		testBackplane(t, func(t *testing.T) func() websocket.Backplane {
+			client := repotest.OpenRedis(t)
+			return func() websocket.Backplane { return websocket.NewRedisBackplane(client) }
+		})
+	})
+}
+
+// testBackplane 消息通道行为测试，newCluster 每次返回一个新的消息总线，调用返回的函数在该总线上创建一个节点
+func testBackplane(t *testing.T, newCluster func(t *testing.T) func() websocket.Backplane) {
+	t.Run("PublishSubscribe", func(t *testing.T) {
+		newNode := newCluster(t)
+		a, b := newNode(), newNode()
+		t.Cleanup(func() { a.Close(); b.Close() })
+
+		received := make(chan string, 10)
+		a.Listen(func(channel string, payload []byte) { received <- "a " + channel + " " + string(payload) })
+		b.Listen(func(channel string, payload []byte) { received <- "b " + channel + " " + string(payload) })
+		ctx := context.Background()
+		if err := b.Subscribe(ctx, "user:1", "topic:news"); err != nil {
+			t.Fatalf("Subscribe 失败: %v", err)
+		}
+		time.Sleep(settle)
+
+		publish(t, a, "user:1", "hello")
+		publish(t, a, "user:2", "ignored")
+		publish(t, b, "topic:news", "self")
+		expectReceived(t, received, "b user:1 hello", "b topic:news self")
+
+		if err := b.Unsubscribe(ctx, "user:1"); err != nil {
+			t.Fatalf("Unsubscribe 失败: %v", err)
+		}
+		time.Sleep(settle)
+		publish(t, a, "user:1", "after")
+		expectReceived(t, received)
+	})
+
+	t.Run("SendToUser", func(t *testing.T) {
+		newNode, outbox := newCluster(t), newMemoryOutbox()
+		m1, m2 := newManager(t, newNode(), outbox), newManager(t, newNode(), outbox)
+		if m1.NodeID() == m2.NodeID() {
+			t.Fatal("节点ID重复")
+		}
+		// 用户 1 在两个节点各有连接，用户 2 只在节点 2
+		c1 := connect(t, m1, 1)
+		c2 := connect(t, m2, 1)
+		other := connect(t, m2, 2)
+		time.Sleep(settle)
+
+		m1.SendToUser(1, []byte("from-1"))
+		expectMessages(t, c1, "from-1")
+		expectMessages(t, c2, "from-1")
+		expectMessages(t, other)
+
+		if err := m2.SendFormattedMessage(2, websocket.MessageTypeNotification, "n"); err != nil {
+			t.Fatalf("SendFormattedMessage 失败: %v", err)
+		}
+		expectTypes(t, other, websocket.MessageTypeNotification)
+		expectMessages(t, c1)
+		expectMessages(t, c2)
+
+		// 用户在节点 2 的连接全部断开后，节点 1 发送的消息只到达本节点
+		m2.Unregister(c2)
+		time.Sleep(settle)
+		m1.SendToUser(1, []byte("again"))
+		expectMessages(t, c1, "again")
+	})
+
+	t.Run("BroadcastToTopic", func(t *testing.T) {
+		newNode, outbox := newCluster(t), newMemoryOutbox()
+		m1, m2 := newManager(t, newNode(), outbox), newManager(t, newNode(), outbox)
+		c1 := connect(t, m1, 1)
+		c2 := connect(t, m2, 2)
+		c3 := connect(t, m2, 3)
+		subscribe(t, m1, c1, "activity")
+		subscribe(t, m2, c2, "activity")
+		time.Sleep(settle)
+
+		if err := m2.BroadcastToTopic("activity", websocket.MessageTypeActivity, "a"); err != nil {
+			t.Fatalf("BroadcastToTopic 失败: %v", err)
+		}
+		expectTypes(t, c1, websocket.MessageTypeActivity)
+		expectTypes(t, c2, websocket.MessageTypeActivity)
+		expectMessages(t, c3)
+
+		unsubscribe(t, m1, c1, "activity")
+		time.Sleep(settle)
+		if err := m2.BroadcastToTopic("activity", websocket.MessageTypeActivity, "b"); err != nil {
+			t.Fatalf("BroadcastToTopic 失败: %v", err)
+		}
+		expectMessages(t, c1)
+		expectTypes(t, c2, websocket.MessageTypeActivity)
+	})
+
+	t.Run("Wildcard", func(t *testing.T) {
+		newNode, outbox := newCluster(t), newMemoryOutbox()
+		m1, m2 := newManager(t, newNode(), outbox), newManager(t, newNode(), outbox)
+		one := connect(t, m1, 1)
+		many := connect(t, m1, 2)
+		subscribe(t, m1, one, "activity.*")
+		subscribe(t, m1, many, "activity.#")
+		time.Sleep(settle)
+
+		// 其他节点发布的消息按主题的根转发，由订阅所在的节点匹配通配符
+		broadcast(t, m2, "activity")
+		broadcast(t, m2, "activity.login")
+		broadcast(t, m2, "activity.login.failed")
+		expectData(t, one, "activity.login")
+		expectData(t, many, "activity", "activity.login", "activity.login.failed")
+	})
+
+	t.Run("Deduplicate", func(t *testing.T) {
+		newNode := newCluster(t)
+		m := newManager(t, newNode(), newMemoryOutbox())
+		c := connect(t, m, 1)
+		publisher := newNode()
+		t.Cleanup(func() { publisher.Close() })
+		time.Sleep(settle)
+
+		// 同一条消息重复发布只投递一次，不同ID的消息各自投递
+		first := envelope(t, "m1", "other", "x")
+		publishRaw(t, publisher, "user:1", first)
+		publishRaw(t, publisher, "user:1", first)
+		publishRaw(t, publisher, "user:1", envelope(t, "m2", "other", "y"))
+		// 节点ID与接收方相同的消息视为本节点发出，已直接投递
+		publishRaw(t, publisher, "user:1", envelope(t, "m3", m.NodeID(), "z"))
+		expectMessages(t, c, "x", "y")
+	})
+}
+
+// TestConnectionLimit 用户的连接数达到上限后新连接被拒绝，已登记的每个连接都收到发给该用户和所订阅主题的每条消息
+func TestConnectionLimit(t *testing.T) {
+	m := newManager(t, websocket.NewMemoryBackplane(websocket.NewMemoryHub()), newMemoryOutbox())
+	limit := testConfig().MaxConnectionsPerUser
+	var clients []*websocket.Client
+	for i := 0; i < limit; i++ {
+		clients = append(clients, connect(t, m, 1))
+	}
+	if m.CanConnect(1) {
+		t.Fatal("连接数已达上限时 CanConnect = true")
+	}
+	rejected, err := m.NewClient(1, nil)
+	if err != nil {
+		t.Fatalf("NewClient 失败: %v", err)
+	}
+	if err := m.Register(rejected); !errors.Is(err, websocket.ErrTooManyConnections) {
+		t.Fatalf("Register = %v, want ErrTooManyConnections", err)
+	}
+	if got := m.ConnectionCount(1); got != limit {
+		t.Fatalf("ConnectionCount = %d, want %d", got, limit)
+	}
+	// 上限按用户计算
+	other := connect(t, m, 2)
+
+	for _, client := range clients {
+		subscribe(t, m, client, "activity")
+	}
+	for _, msg := range []string{"first", "second"} {
+		m.SendToUser(1, []byte(msg))
+	}
+	for _, client := range clients {
+		expectMessages(t, client, "first", "second")
+	}
+	broadcast(t, m, "activity")
+	for _, client := range clients {
+		expectData(t, client, "activity")
+	}
+	expectMessages(t, rejected)
+	expectMessages(t, other)
+
+	// 断开一个连接后可以建立新连接，消息发送到新连接和其余的连接
+	m.Unregister(clients[0])
+	clients = append(clients[1:], connect(t, m, 1))
+	m.SendToUser(1, []byte("third"))
+	for _, client := range clients {
+		expectMessages(t, client, "third")
+	}
+}
+
+func TestTopics(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		testTopics(t, func(t *testing.T) repository.ActivityRepository {
+			return repository.NewMemoryActivityRepository()
+		})
+	})
+	t.Run("GORM", func(t *testing.T) {
+		testTopics(t, func(t *testing.T) repository.ActivityRepository {
+			return repository.NewActivityRepository(repotest.OpenSQLite(t))
+		})
+	})
+}
+
+// testTopics 主题订阅行为测试，使用 services.WebSocketTopicAuthorizer 判断订阅权限，newActivities 每次返回一个空的活动仓储
+func testTopics(t *testing.T, newActivities func(t *testing.T) repository.ActivityRepository) {
+	newTopicManager := func(t *testing.T) (*websocket.Manager, repository.ActivityRepository) {
+		activities := newActivities(t)
+		authorizer := services.NewWebSocketTopicAuthorizer(activities)
+		return newManagerWith(t, websocket.NewMemoryBackplane(websocket.NewMemoryHub()), newMemoryOutbox(), authorizer.Authorize), activities
+	}
+
+	t.Run("Validate", func(t *testing.T) {
+		m, _ := newTopicManager(t)
+		client := connect(t, m, 1)
+		for _, pattern := range []string{"", "*", "#", "*.login", "activity..login", "activity.#.login", "activity.log in", "activity.**"} {
+			expectCode(t, m.Subscribe(client, pattern), errcode.WebSocketTopicInvalid)
+		}
+		for _, topic := range []string{"activity.*", "activity.#", ""} {
+			expectCode(t, m.BroadcastToTopic(topic, websocket.MessageTypeActivity, topic), errcode.WebSocketTopicInvalid)
+		}
+		if got := m.ClientSubscriptions(client); len(got) != 0 {
+			t.Fatalf("订阅失败后仍有订阅: %q", got)
+		}
+	})
+
+	t.Run("Authorize", func(t *testing.T) {
+		m, activities := newTopicManager(t)
+		client := connect(t, m, 1)
+		activity := &models.Activity{Title: "launch"}
+		if err := activities.Create(activity); err != nil {
+			t.Fatalf("Create 失败: %v", err)
+		}
+		own := fmt.Sprintf("activity:%d", activity.ID)
+
+		subscribe(t, m, client, "activity")
+		subscribe(t, m, client, "user:1.#")
+		expectCode(t, m.Subscribe(client, "user:2"), errcode.WebSocketTopicForbidden)
+		expectCode(t, m.Subscribe(client, own), errcode.WebSocketTopicForbidden)
+		expectCode(t, m.Subscribe(client, "activity:abc"), errcode.WebSocketTopicForbidden)
+		expectCode(t, m.Subscribe(client, "system"), errcode.WebSocketTopicForbidden)
+		// 所有用户都可以订阅其他用户的在线状态
+		subscribe(t, m, client, "presence:2")
+		expectCode(t, m.Subscribe(client, "presence:abc"), errcode.WebSocketTopicForbidden)
+		expectCode(t, m.Subscribe(client, "presence"), errcode.WebSocketTopicForbidden)
+
+		// 加入活动后才能订阅活动主题
+		if err := activities.AddParticipant(activity.ID, 1); err != nil {
+			t.Fatalf("AddParticipant 失败: %v", err)
+		}
+		subscribe(t, m, client, own+".*")
+		want := []string{"activity", own + ".*", "presence:2", "user:1.#"}
+		if got := m.ClientSubscriptions(client); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("ClientSubscriptions = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("Match", func(t *testing.T) {
+		m, _ := newTopicManager(t)
+		exact := connect(t, m, 1)
+		one := connect(t, m, 2)
+		many := connect(t, m, 3)
+		both := connect(t, m, 4)
+		subscribe(t, m, exact, "activity.login")
+		subscribe(t, m, one, "activity.*.failed")
+		subscribe(t, m, many, "activity.#")
+		subscribe(t, m, both, "activity.*")
+		subscribe(t, m, both, "activity.#")
+
+		broadcast(t, m, "activity")
+		broadcast(t, m, "activity.login")
+		broadcast(t, m, "activity.login.failed")
+		broadcast(t, m, "activityx.login")
+		expectData(t, exact, "activity.login")
+		expectData(t, one, "activity.login.failed")
+		expectData(t, many, "activity", "activity.login", "activity.login.failed")
+		// 同一连接的多个模式匹配同一条消息时只收到一次
+		expectData(t, both, "activity", "activity.login", "activity.login.failed")
+
+		unsubscribe(t, m, both, "activity.#")
+		broadcast(t, m, "activity.logout")
+		expectData(t, both, "activity.logout")
+		unsubscribe(t, m, both, "activity.#")
+		expectCode(t, m.Unsubscribe(both, "#"), errcode.WebSocketTopicInvalid)
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		m, _ := newTopicManager(t)
+		client := connect(t, m, 1)
+		var err error
+		for i := 0; err == nil && i <= 1000; i++ {
+			err = m.Subscribe(client, fmt.Sprintf("activity.%d", i))
+		}
+		expectCode(t, err, errcode.WebSocketSubscriptionLimit)
+		// 重复订阅已有的主题不占用名额
+		subscribe(t, m, client, "activity.0")
+	})
+}
+
+// expectCode 检查返回的错误码
+func expectCode(t *testing.T, err error, want *errcode.ErrorCode) {
+	t.Helper()
+	var code *errcode.ErrorCode
+	if !errors.As(err, &code) || code.Code != want.Code {
+		t.Fatalf("err = %v, want %v", err, want)
+	}
+}
+
+func TestOutbox(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		testOutbox(t, func(t *testing.T, cfg config.WebSocketConfig) websocket.Outbox {
+			return services.NewWebSocketOutboxService(repository.NewMemoryWebSocketOutboxRepository(), cfg)
+		})
+	})
+	t.Run("GORM", func(t *testing.T) {
+		testOutbox(t, func(t *testing.T, cfg config.WebSocketConfig) websocket.Outbox {
+			return services.NewWebSocketOutboxService(repository.NewWebSocketOutboxRepository(repotest.OpenSQLite(t)), cfg)
+		})
+	})
+}
+
+// testOutbox 发件箱行为测试，newOutbox 每次返回一个空的发件箱
+func testOutbox(t *testing.T, newOutbox func(t *testing.T, cfg config.WebSocketConfig) websocket.Outbox) {
+	cfg := testConfig()
+	cfg.OutboxSize = 3
+
+	t.Run("MessageIDs", func(t *testing.T) {
+		hub, outbox := websocket.NewMemoryHub(), newOutbox(t, cfg)
+		m1 := newManager(t, websocket.NewMemoryBackplane(hub), outbox)
+		m2 := newManager(t, websocket.NewMemoryBackplane(hub), outbox)
+		c1 := connect(t, m1, 1)
+		c2 := connect(t, m2, 1)
+		other := connect(t, m1, 2)
+
+		// 消息ID按用户递增，不同节点发出的消息共用同一个序列
+		sendNotification(t, m1, 1)
+		sendNotification(t, m2, 1)
+		sendNotification(t, m1, 2)
+		expectIDs(t, c1, 1, 2)
+		expectIDs(t, c2, 1, 2)
+		expectIDs(t, other, 1)
+
+		// 主题广播不保存，没有消息ID
+		subscribe(t, m1, c1, "activity")
+		if err := m1.BroadcastToTopic("activity", websocket.MessageTypeActivity, "a"); err != nil {
+			t.Fatalf("BroadcastToTopic 失败: %v", err)
+		}
+		expectIDs(t, c1, 0)
+	})
+
+	t.Run("Resume", func(t *testing.T) {
+		m := newManager(t, websocket.NewMemoryBackplane(websocket.NewMemoryHub()), newOutbox(t, cfg))
+		for i := 0; i < 2; i++ {
+			sendNotification(t, m, 1)
+		}
+
+		// 重连后从最后收到的消息之后补发，已是最新时不补发
+		c := connect(t, m, 1)
+		resume(t, m, c, ptr(1))
+		expectIDs(t, c, 2)
+		resume(t, m, c, ptr(2))
+		expectIDs(t, c)
+
+		// 不带消息ID时从确认过的最大消息ID之后补发
+		resume(t, m, c, nil)
+		expectIDs(t, c, 1, 2)
+		if err := m.Ack(c, 1); err != nil {
+			t.Fatalf("Ack 失败: %v", err)
+		}
+		resume(t, m, c, nil)
+		expectIDs(t, c, 2)
+	})
+
+	t.Run("Gap", func(t *testing.T) {
+		m := newManager(t, websocket.NewMemoryBackplane(websocket.NewMemoryHub()), newOutbox(t, cfg))
+		for i := 0; i < 5; i++ {
+			sendNotification(t, m, 1)
+		}
+
+		// 发件箱只保留最近 3 条，1、2 无法补发
+		c := connect(t, m, 1)
+		resume(t, m, c, ptr(0))
+		messages := receive(t, c, 4)
+		var gap struct {
+			Type string        `json:"type"`
+			Data websocket.Gap `json:"data"`
+		}
+		if err := json.Unmarshal(messages[0], &gap); err != nil {
+			t.Fatalf("解析消息失败: %v", err)
+		}
+		if gap.Type != websocket.MessageTypeGap || gap.Data != (websocket.Gap{From: 1, To: 2}) {
+			t.Fatalf("补发的第一条消息 = %s, want 1 到 2 的 gap", messages[0])
+		}
+		for i, data := range messages[1:] {
+			if id := messageID(t, data); id != uint64(i+3) {
+				t.Fatalf("补发的消息ID = %d, want %d", id, i+3)
+			}
+		}
+
+		resume(t, m, c, ptr(3))
+		expectIDs(t, c, 4, 5)
+	})
+}
+
+func testConfig() config.WebSocketConfig {
+	return config.WebSocketConfig{MaxConnectionsPerUser: 5, OutboxSize: 100, OutboxRetention: 60, PresenceGracePeriod: 1, PresenceTTL: 60}
+}
+
+func newMemoryOutbox() websocket.Outbox {
+	return services.NewWebSocketOutboxService(repository.NewMemoryWebSocketOutboxRepository(), testConfig())
+}
+
+func newManager(t *testing.T, backplane websocket.Backplane, outbox websocket.Outbox) *websocket.Manager {
+	t.Helper()
+	return newManagerWith(t, backplane, outbox, allowAll)
+}
+
+func newManagerWith(t *testing.T, backplane websocket.Backplane, outbox websocket.Outbox, authorizer websocket.TopicAuthorizer) *websocket.Manager {
+	t.Helper()
+	m, err := websocket.NewManager(testConfig(), backplane, outbox, authorizer)
+	if err != nil {
+		t.Fatalf("NewManager 失败: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func allowAll(userID uint, pattern string) error {
+	return nil
+}
+
+func subscribe(t *testing.T, m *websocket.Manager, client *websocket.Client, pattern string) {
+	t.Helper()
+	if err := m.Subscribe(client, pattern); err != nil {
+		t.Fatalf("Subscribe(%q) 失败: %v", pattern, err)
+	}
+}
+
+func unsubscribe(t *testing.T, m *websocket.Manager, client *websocket.Client, pattern string) {
+	t.Helper()
+	if err := m.Unsubscribe(client, pattern); err != nil {
+		t.Fatalf("Unsubscribe(%q) 失败: %v", pattern, err)
+	}
+}
+
+func broadcast(t *testing.T, m *websocket.Manager, topic string) {
+	t.Helper()
+	if err := m.BroadcastToTopic(topic, websocket.MessageTypeActivity, topic); err != nil {
+		t.Fatalf("BroadcastToTopic(%q) 失败: %v", topic, err)
+	}
+}
+
+// connect 登记一个不带网络连接的客户端，并取走连接成功消息
+func connect(t *testing.T, m *websocket.Manager, userID uint) *websocket.Client {
+	t.Helper()
+	client, err := m.NewClient(userID, nil)
+	if err != nil {
+		t.Fatalf("NewClient 失败: %v", err)
+	}
+	if err := m.Register(client); err != nil {
+		t.Fatalf("Register 失败: %v", err)
+	}
+	expectTypes(t, client, websocket.MessageTypeSystem)
+	return client
+}
+
+func sendNotification(t *testing.T, m *websocket.Manager, userID uint) {
+	t.Helper()
+	if err := m.SendFormattedMessage(userID, websocket.MessageTypeNotification, "n"); err != nil {
+		t.Fatalf("SendFormattedMessage 失败: %v", err)
+	}
+}
+
+func resume(t *testing.T, m *websocket.Manager, client *websocket.Client, lastID *uint64) {
+	t.Helper()
+	if err := m.Resume(client, lastID); err != nil {
+		t.Fatalf("Resume 失败: %v", err)
+	}
+}
+
+func ptr(id uint64) *uint64 {
+	return &id
+}
+
+func publish(t *testing.T, b websocket.Backplane, channel, payload string) {
+	t.Helper()
+	publishRaw(t, b, channel, []byte(payload))
+}
+
+func publishRaw(t *testing.T, b websocket.Backplane, channel string, payload []byte) {
+	t.Helper()
+	if err := b.Publish(context.Background(), channel, payload); err != nil {
+		t.Fatalf("Publish 失败: %v", err)
+	}
+}
+
+// envelope 按 Manager 的转发格式构造消息
+func envelope(t *testing.T, id, node, data string) []byte {
+	t.Helper()
+	payload, err := json.Marshal(map[string]interface{}{"id": id, "node": node, "data": []byte(data)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+// expectReceived 检查收到的消息（不要求顺序），之后不再收到其他消息
+func expectReceived(t *testing.T, received <-chan string, want ...string) {
+	t.Helper()
+	pending := make(map[string]int)
+	for _, w := range want {
+		pending[w]++
+	}
+	for i := 0; i < len(want); i++ {
+		select {
+		case got := <-received:
+			if pending[got] == 0 {
+				t.Fatalf("收到意外的消息 %q, want %q", got, want)
+			}
+			pending[got]--
+		case <-time.After(2 * time.Second):
+			t.Fatalf("等待消息超时, want %q", want)
+		}
+	}
+	select {
+	case got := <-received:
+		t.Fatalf("收到多余的消息 %q", got)
+	case <-time.After(settle):
+	}
+}
+
+// expectMessages 按顺序检查连接收到的消息，之后不再收到其他消息
+func expectMessages(t *testing.T, client *websocket.Client, want ...string) {
+	t.Helper()
+	for _, w := range receive(t, client, len(want)) {
+		if string(w) != want[0] {
+			t.Fatalf("连接收到 %q, want %q", w, want[0])
+		}
+		want = want[1:]
+	}
+}
+
+// expectTypes 按顺序检查连接收到的格式化消息的类型，之后不再收到其他消息
+func expectTypes(t *testing.T, client *websocket.Client, want ...string) {
+	t.Helper()
+	for i, data := range receive(t, client, len(want)) {
+		var msg websocket.Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("解析消息失败: %v", err)
+		}
+		if msg.Type != want[i] {
+			t.Fatalf("连接收到 %s 消息, want %s", msg.Type, want[i])
+		}
+	}
+}
+
+// expectData 按顺序检查连接收到的格式化消息中的字符串数据，之后不再收到其他消息
+func expectData(t *testing.T, client *websocket.Client, want ...string) {
+	t.Helper()
+	for i, data := range receive(t, client, len(want)) {
+		var msg struct {
+			Data string `json:"data"`
+		}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("解析消息失败: %v", err)
+		}
+		if msg.Data != want[i] {
+			t.Fatalf("连接收到 %q, want %q", msg.Data, want[i])
+		}
+	}
+}
+
+// expectIDs 按顺序检查连接收到的消息ID，之后不再收到其他消息，没有ID的消息为 0
+func expectIDs(t *testing.T, client *websocket.Client, want ...uint64) {
+	t.Helper()
+	for i, data := range receive(t, client, len(want)) {
+		if id := messageID(t, data); id != want[i] {
+			t.Fatalf("连接收到消息ID %d, want %d", id, want[i])
+		}
+	}
+}
+
+func messageID(t *testing.T, data []byte) uint64 {
+	t.Helper()
+	var msg websocket.Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("解析消息失败: %v", err)
+	}
+	return msg.ID
+}
+
+func receive(t *testing.T, client *websocket.Client, n int) [][]byte {
+	t.Helper()
+	var messages [][]byte
+	for len(messages) < n {
+		select {
+		case data := <-client.Send:
+			messages = append(messages, data)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("等待消息超时，已收到 %d 条, want %d", len(messages), n)
+		}
+	}
+	select {
+	case data := <-client.Send:
+		t.Fatalf("连接收到多余的消息 %q", data)
+	case <-time.After(settle):
+	}
+	return messages
+}
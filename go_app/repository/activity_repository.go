@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"time"
+
+	"go_app/models"
+
+	"gorm.io/gorm"
+)
+
+// ActivityRepository 活动数据访问
+type ActivityRepository interface {
+	Create(activity *models.Activity) error
+	FindByID(id uint) (*models.Activity, error)
+	// List 按开始时间倒序分页获取活动，同时返回总数
+	List(page Page) ([]models.Activity, int64, error)
+	// ListOngoing 按开始时间升序返回 at 时刻正在进行的活动
+	ListOngoing(at time.Time) ([]models.Activity, error)
+	// Update 更新活动的标题、描述和起止时间，活动不存在时返回 ErrNotFound
+	Update(activity *models.Activity) error
+	// Delete 删除活动及其参与者，活动不存在时返回 ErrNotFound
+	Delete(id uint) error
+	// AddParticipant 将用户加入活动，活动不存在时返回 ErrNotFound，已参与时返回 ErrDuplicate
+	AddParticipant(activityID, userID uint) error
+	// RemoveParticipant 将用户移出活动，用户未参与时返回 ErrNotFound
+	RemoveParticipant(activityID, userID uint) error
+	IsParticipant(activityID, userID uint) (bool, error)
+}
+
+type gormActivityRepository struct {
+	db *gorm.DB
+}
+
+func NewActivityRepository(db *gorm.DB) ActivityRepository {
+	return &gormActivityRepository{db: db}
+}
+
+func (r *gormActivityRepository) Create(activity *models.Activity) error {
+	return translate(r.db.Create(activity).Error)
+}
+
+func (r *gormActivityRepository) FindByID(id uint) (*models.Activity, error) {
+	var activity models.Activity
+	if err := r.db.First(&activity, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &activity, nil
+}
+
+func (r *gormActivityRepository) List(page Page) ([]models.Activity, int64, error) {
+	var total int64
+	if err := r.db.Model(&models.Activity{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var activities []models.Activity
+	if err := page.apply(r.db.Order("start_time DESC, id DESC")).Find(&activities).Error; err != nil {
+		return nil, 0, err
+	}
+	return activities, total, nil
+}
+
+func (r *gormActivityRepository) ListOngoing(at time.Time) ([]models.Activity, error) {
+	var activities []models.Activity
+	err := r.db.Where("start_time <= ? AND end_time >= ?", at, at).
+		Order("start_time, id").
+		Find(&activities).Error
+	return activities, err
+}
+
+func (r *gormActivityRepository) Update(activity *models.Activity) error {
+	result := r.db.Model(activity).
+		Select("title", "description", "start_time", "end_time", "updated_at").
+		Updates(activity)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormActivityRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.Activity{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return tx.Where("activity_id = ?", id).Delete(&models.ActivityParticipant{}).Error
+	})
+}
+
+func (r *gormActivityRepository) AddParticipant(activityID, userID uint) error {
+	return translate(r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id").First(&models.Activity{}, activityID).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.ActivityParticipant{ActivityID: activityID, UserID: userID}).Error
+	}))
+}
+
+func (r *gormActivityRepository) RemoveParticipant(activityID, userID uint) error {
+	result := r.db.Where("activity_id = ? AND user_id = ?", activityID, userID).Delete(&models.ActivityParticipant{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormActivityRepository) IsParticipant(activityID, userID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.ActivityParticipant{}).
+		Where("activity_id = ? AND user_id = ?", activityID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"time"
+
+	"go_app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// APIKeyRepository API Key 数据访问
+type APIKeyRepository interface {
+	// Create 保存新创建的 API Key，用户已有 limit 个 API Key 时不创建，返回 ErrConflict；
+	// 数量检查和插入在同一事务中完成，并发创建不会超过上限。哈希值已存在时返回 ErrDuplicate
+	Create(key *models.APIKey, limit int) error
+	// FindByHash 按明文的哈希值查找 API Key
+	FindByHash(keyHash string) (*models.APIKey, error)
+	// ListByUser 按创建时间列出用户的全部 API Key
+	ListByUser(userID uint) ([]*models.APIKey, error)
+	// CountByUser 统计用户的 API Key 数量
+	CountByUser(userID uint) (int64, error)
+	// Touch 更新最近使用时间和 IP
+	Touch(id uint, at time.Time, ip string) error
+	// Delete 删除 API Key，不属于该用户时返回 ErrNotFound
+	Delete(userID, id uint) error
+	// DeleteByUser 删除用户的全部 API Key
+	DeleteByUser(userID uint) error
+}
+
+type gormAPIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &gormAPIKeyRepository{db: db}
+}
+
+func (r *gormAPIKeyRepository) Create(key *models.APIKey, limit int) error {
+	return translate(r.db.Transaction(func(tx *gorm.DB) error {
+		// 锁定用户记录，同一用户的并发创建在此串行执行（SQLite 不支持行锁，由单连接保证串行）
+		var ids []uint
+		if err := tx.Model(&models.User{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", key.UserID).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.APIKey{}).Where("user_id = ?", key.UserID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= int64(limit) {
+			return ErrConflict
+		}
+		return tx.Create(key).Error
+	}))
+}
+
+func (r *gormAPIKeyRepository) FindByHash(keyHash string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.Where("key_hash = ?", keyHash).First(&key).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &key, nil
+}
+
+func (r *gormAPIKeyRepository) ListByUser(userID uint) ([]*models.APIKey, error) {
+	var keys []*models.APIKey
+	err := r.db.Where("user_id = ?", userID).Order("id").Find(&keys).Error
+	return keys, err
+}
+
+func (r *gormAPIKeyRepository) CountByUser(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.APIKey{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *gormAPIKeyRepository) Touch(id uint, at time.Time, ip string) error {
+	// 只更新使用记录，不修改 updated_at
+	result := r.db.Model(&models.APIKey{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"last_used_at": at,
+		"last_used_ip": ip,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormAPIKeyRepository) Delete(userID, id uint) error {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.APIKey{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormAPIKeyRepository) DeleteByUser(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.APIKey{}).Error
+}
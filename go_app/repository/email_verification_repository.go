@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"time"
+
+	"go_app/models"
+
+	"gorm.io/gorm"
+)
+
+// EmailVerificationRepository 邮箱验证令牌数据访问
+type EmailVerificationRepository interface {
+	// Create 保存新令牌并删除该用户之前的全部令牌，每个用户同时只有最近一次发送的令牌有效
+	Create(token *models.EmailVerificationToken) error
+	// CreateUnlessRecent 与 Create 相同，但用户在 since 之后发送的令牌仍未使用且未过期时不创建，返回 ErrConflict
+	CreateUnlessRecent(token *models.EmailVerificationToken, since time.Time) error
+	// FindByHash 按哈希值查找令牌
+	FindByHash(tokenHash string) (*models.EmailVerificationToken, error)
+	// Consume 将令牌标记为已使用，令牌已被使用时返回 ErrConflict
+	Consume(id uint) error
+	// DeleteByUser 删除用户的全部令牌
+	DeleteByUser(userID uint) error
+}
+
+type gormEmailVerificationRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailVerificationRepository(db *gorm.DB) EmailVerificationRepository {
+	return &gormEmailVerificationRepository{db: db}
+}
+
+func (r *gormEmailVerificationRepository) Create(token *models.EmailVerificationToken) error {
+	return translate(r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", token.UserID).Delete(&models.EmailVerificationToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	}))
+}
+
+func (r *gormEmailVerificationRepository) CreateUnlessRecent(token *models.EmailVerificationToken, since time.Time) error {
+	return translate(r.db.Transaction(func(tx *gorm.DB) error {
+		// 先删除可以替换的令牌再检查剩余的令牌，删除语句使并发的发送在同一用户上串行执行
+		now := time.Now()
+		if err := tx.Where("user_id = ? AND NOT (created_at > ? AND used_at IS NULL AND expired_at > ?)", token.UserID, since, now).
+			Delete(&models.EmailVerificationToken{}).Error; err != nil {
+			return err
+		}
+		var recent int64
+		if err := tx.Model(&models.EmailVerificationToken{}).Where("user_id = ?", token.UserID).Count(&recent).Error; err != nil {
+			return err
+		}
+		if recent > 0 {
+			return ErrConflict
+		}
+		return tx.Create(token).Error
+	}))
+}
+
+func (r *gormEmailVerificationRepository) FindByHash(tokenHash string) (*models.EmailVerificationToken, error) {
+	var token models.EmailVerificationToken
+	if err := r.db.Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &token, nil
+}
+
+func (r *gormEmailVerificationRepository) Consume(id uint) error {
+	// 条件更新保证同一令牌只能被成功使用一次
+	result := r.db.Model(&models.EmailVerificationToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r *gormEmailVerificationRepository) DeleteByUser(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.EmailVerificationToken{}).Error
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"go_app/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LoginAttemptRepository 登录失败记录存储，key 由调用方区分账号和 IP。
+// 记录在有效期（ttl）过后自动清除，多实例部署时需使用共享存储
+type LoginAttemptRepository interface {
+	// Get 查询失败记录，没有记录时返回零值记录
+	Get(key string) (*models.LoginAttempt, error)
+	// Reserve 失败次数仍为 failures 时加一并记录尝试时间，记录有效期重置为 ttl，返回更新后的记录；
+	// 失败次数已被其他请求修改时返回 ErrConflict。登录时在校验密码之前调用，并发的请求只有一个能占用同一次尝试
+	Reserve(key string, failures int, at time.Time, ttl time.Duration) (*models.LoginAttempt, error)
+	// Release 失败次数减一，退还登录成功的请求占用的尝试；记录不存在或次数为零时忽略
+	Release(key string) error
+	// Lock 锁定到 until，记录有效期重置为 ttl
+	Lock(key string, until time.Time, ttl time.Duration) error
+	// Reset 删除记录
+	Reset(key string) error
+}
+
+// redisLoginAttemptKeyPrefix Redis 中失败记录的键前缀
+const redisLoginAttemptKeyPrefix = "go_app:login_attempt:"
+
+// reserveLoginAttemptScript 失败次数等于 ARGV[1] 时加一并记录尝试时间，返回更新后的记录，否则返回 nil
+var reserveLoginAttemptScript = redis.NewScript(`
+local failures = tonumber(redis.call('HGET', KEYS[1], 'failures') or '0')
+if failures ~= tonumber(ARGV[1]) then
+	return nil
+end
+redis.call('HSET', KEYS[1], 'failures', failures + 1, 'last_failed_at', ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return redis.call('HGETALL', KEYS[1])
+`)
+
+// releaseLoginAttemptScript 失败次数大于零时减一，不修改有效期
+var releaseLoginAttemptScript = redis.NewScript(`
+local failures = tonumber(redis.call('HGET', KEYS[1], 'failures') or '0')
+if failures > 0 then
+	redis.call('HINCRBY', KEYS[1], 'failures', -1)
+end
+return failures
+`)
+
+type redisLoginAttemptRepository struct {
+	client redis.UniversalClient
+}
+
+// NewRedisLoginAttemptRepository 基于 Redis 的失败记录存储，多个实例共享失败次数和锁定状态。
+// 每条记录保存为一个哈希，时间以毫秒时间戳保存
+func NewRedisLoginAttemptRepository(client redis.UniversalClient) LoginAttemptRepository {
+	return &redisLoginAttemptRepository{client: client}
+}
+
+func (r *redisLoginAttemptRepository) Get(key string) (*models.LoginAttempt, error) {
+	values, err := r.client.HGetAll(context.Background(), redisLoginAttemptKeyPrefix+key).Result()
+	if err != nil {
+		return nil, err
+	}
+	return parseLoginAttempt(values), nil
+}
+
+func (r *redisLoginAttemptRepository) Reserve(key string, failures int, at time.Time, ttl time.Duration) (*models.LoginAttempt, error) {
+	// 比较和递增在 Lua 脚本中原子执行，多个实例同时占用时只有一个成功
+	fields, err := reserveLoginAttemptScript.Run(context.Background(), r.client, []string{redisLoginAttemptKeyPrefix + key},
+		failures, at.UnixMilli(), ttl.Milliseconds()).StringSlice()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrConflict
+		}
+		return nil, err
+	}
+	values := make(map[string]string, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		values[fields[i]] = fields[i+1]
+	}
+	return parseLoginAttempt(values), nil
+}
+
+func (r *redisLoginAttemptRepository) Release(key string) error {
+	return releaseLoginAttemptScript.Run(context.Background(), r.client, []string{redisLoginAttemptKeyPrefix + key}).Err()
+}
+
+func (r *redisLoginAttemptRepository) Lock(key string, until time.Time, ttl time.Duration) error {
+	ctx := context.Background()
+	key = redisLoginAttemptKeyPrefix + key
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, "locked_until", until.UnixMilli())
+		pipe.PExpire(ctx, key, ttl)
+		return nil
+	})
+	return err
+}
+
+func (r *redisLoginAttemptRepository) Reset(key string) error {
+	return r.client.Del(context.Background(), redisLoginAttemptKeyPrefix+key).Err()
+}
+
+func parseLoginAttempt(values map[string]string) *models.LoginAttempt {
+	var attempt models.LoginAttempt
+	attempt.Failures, _ = strconv.Atoi(values["failures"])
+	if ms, err := strconv.ParseInt(values["last_failed_at"], 10, 64); err == nil {
+		attempt.LastFailedAt = time.UnixMilli(ms)
+	}
+	if ms, err := strconv.ParseInt(values["locked_until"], 10, 64); err == nil {
+		attempt.LockedUntil = time.UnixMilli(ms)
+	}
+	return &attempt
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go_app/models"
+)
+
+type memoryActivityRepository struct {
+	mu           sync.RWMutex
+	nextID       uint
+	activities   map[uint]models.Activity
+	participants map[uint]map[uint]bool // 活动ID → 用户ID
+}
+
+func NewMemoryActivityRepository() ActivityRepository {
+	return &memoryActivityRepository{
+		activities:   make(map[uint]models.Activity),
+		participants: make(map[uint]map[uint]bool),
+	}
+}
+
+func (r *memoryActivityRepository) Create(activity *models.Activity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	now := time.Now()
+	activity.ID = r.nextID
+	activity.CreatedAt = now
+	activity.UpdatedAt = now
+	r.activities[activity.ID] = *activity
+	return nil
+}
+
+func (r *memoryActivityRepository) FindByID(id uint) (*models.Activity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	activity, ok := r.activities[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &activity, nil
+}
+
+func (r *memoryActivityRepository) List(page Page) ([]models.Activity, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	activities := make([]models.Activity, 0, len(r.activities))
+	for _, a := range r.activities {
+		activities = append(activities, a)
+	}
+	sort.Slice(activities, func(i, j int) bool {
+		a, b := activities[i], activities[j]
+		if !a.StartTime.Equal(b.StartTime) {
+			return a.StartTime.After(b.StartTime)
+		}
+		return a.ID > b.ID
+	})
+	return slice(activities, page), int64(len(activities)), nil
+}
+
+func (r *memoryActivityRepository) ListOngoing(at time.Time) ([]models.Activity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var activities []models.Activity
+	for _, a := range r.activities {
+		if !a.StartTime.After(at) && !a.EndTime.Before(at) {
+			activities = append(activities, a)
+		}
+	}
+	sort.Slice(activities, func(i, j int) bool {
+		a, b := activities[i], activities[j]
+		if !a.StartTime.Equal(b.StartTime) {
+			return a.StartTime.Before(b.StartTime)
+		}
+		return a.ID < b.ID
+	})
+	return activities, nil
+}
+
+func (r *memoryActivityRepository) Update(activity *models.Activity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.activities[activity.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.Title = activity.Title
+	stored.Description = activity.Description
+	stored.StartTime = activity.StartTime
+	stored.EndTime = activity.EndTime
+	stored.UpdatedAt = time.Now()
+	activity.UpdatedAt = stored.UpdatedAt
+	r.activities[activity.ID] = stored
+	return nil
+}
+
+func (r *memoryActivityRepository) Delete(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.activities[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.activities, id)
+	delete(r.participants, id)
+	return nil
+}
+
+func (r *memoryActivityRepository) AddParticipant(activityID, userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.activities[activityID]; !ok {
+		return ErrNotFound
+	}
+	if r.participants[activityID][userID] {
+		return ErrDuplicate
+	}
+	if r.participants[activityID] == nil {
+		r.participants[activityID] = make(map[uint]bool)
+	}
+	r.participants[activityID][userID] = true
+	return nil
+}
+
+func (r *memoryActivityRepository) RemoveParticipant(activityID, userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.participants[activityID][userID] {
+		return ErrNotFound
+	}
+	delete(r.participants[activityID], userID)
+	return nil
+}
+
+func (r *memoryActivityRepository) IsParticipant(activityID, userID uint) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.participants[activityID][userID], nil
+}
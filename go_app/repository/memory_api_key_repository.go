@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go_app/models"
+)
+
+type memoryAPIKeyRepository struct {
+	mu     sync.RWMutex
+	nextID uint
+	keys   map[uint]models.APIKey
+}
+
+func NewMemoryAPIKeyRepository() APIKeyRepository {
+	return &memoryAPIKeyRepository{keys: make(map[uint]models.APIKey)}
+}
+
+func (r *memoryAPIKeyRepository) Create(key *models.APIKey, limit int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := 0
+	for _, k := range r.keys {
+		if k.KeyHash == key.KeyHash {
+			return ErrDuplicate
+		}
+		if k.UserID == key.UserID {
+			count++
+		}
+	}
+	if count >= limit {
+		return ErrConflict
+	}
+	r.nextID++
+	now := time.Now()
+	key.ID = r.nextID
+	key.CreatedAt, key.UpdatedAt = now, now
+	r.keys[key.ID] = *key
+	return nil
+}
+
+func (r *memoryAPIKeyRepository) FindByHash(keyHash string) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, k := range r.keys {
+		if k.KeyHash == keyHash {
+			return &k, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryAPIKeyRepository) ListByUser(userID uint) ([]*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var keys []*models.APIKey
+	for _, k := range r.keys {
+		if k.UserID == userID {
+			k := k
+			keys = append(keys, &k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+func (r *memoryAPIKeyRepository) CountByUser(userID uint) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, k := range r.keys {
+		if k.UserID == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryAPIKeyRepository) Touch(id uint, at time.Time, ip string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k, ok := r.keys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	k.LastUsedAt = &at
+	k.LastUsedIP = ip
+	r.keys[id] = k
+	return nil
+}
+
+func (r *memoryAPIKeyRepository) Delete(userID, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k, ok := r.keys[id]
+	if !ok || k.UserID != userID {
+		return ErrNotFound
+	}
+	delete(r.keys, id)
+	return nil
+}
+
+func (r *memoryAPIKeyRepository) DeleteByUser(userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, k := range r.keys {
+		if k.UserID == userID {
+			delete(r.keys, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"go_app/models"
+)
+
+type memoryEmailVerificationRepository struct {
+	mu     sync.RWMutex
+	nextID uint
+	tokens map[uint]models.EmailVerificationToken
+}
+
+func NewMemoryEmailVerificationRepository() EmailVerificationRepository {
+	return &memoryEmailVerificationRepository{tokens: make(map[uint]models.EmailVerificationToken)}
+}
+
+func (r *memoryEmailVerificationRepository) Create(token *models.EmailVerificationToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.create(token)
+}
+
+func (r *memoryEmailVerificationRepository) CreateUnlessRecent(token *models.EmailVerificationToken, since time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, t := range r.tokens {
+		if t.UserID == token.UserID && t.CreatedAt.After(since) && t.UsedAt == nil && t.ExpiredAt.After(now) {
+			return ErrConflict
+		}
+	}
+	return r.create(token)
+}
+
+// create 保存令牌并删除该用户之前的令牌，调用方需持有写锁
+func (r *memoryEmailVerificationRepository) create(token *models.EmailVerificationToken) error {
+	for _, t := range r.tokens {
+		if t.TokenHash == token.TokenHash && t.UserID != token.UserID {
+			return ErrDuplicate
+		}
+	}
+	for id, t := range r.tokens {
+		if t.UserID == token.UserID {
+			delete(r.tokens, id)
+		}
+	}
+	r.nextID++
+	token.ID = r.nextID
+	token.CreatedAt = time.Now()
+	r.tokens[token.ID] = *token
+	return nil
+}
+
+func (r *memoryEmailVerificationRepository) FindByHash(tokenHash string) (*models.EmailVerificationToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, t := range r.tokens {
+		if t.TokenHash == tokenHash {
+			return &t, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryEmailVerificationRepository) Consume(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.tokens[id]
+	if !ok || t.UsedAt != nil {
+		return ErrConflict
+	}
+	now := time.Now()
+	t.UsedAt = &now
+	r.tokens[id] = t
+	return nil
+}
+
+func (r *memoryEmailVerificationRepository) DeleteByUser(userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, t := range r.tokens {
+		if t.UserID == userID {
+			delete(r.tokens, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"go_app/models"
+)
+
+// memoryLoginAttemptPurgeInterval 清理过期记录的最小间隔，避免每次失败都遍历全部记录
+const memoryLoginAttemptPurgeInterval = time.Minute
+
+type memoryLoginAttempt struct {
+	attempt   models.LoginAttempt
+	expiresAt time.Time
+}
+
+type memoryLoginAttemptRepository struct {
+	mu        sync.Mutex
+	attempts  map[string]*memoryLoginAttempt
+	lastPurge time.Time
+}
+
+// NewMemoryLoginAttemptRepository 进程内的失败记录存储，只适用于单实例部署
+func NewMemoryLoginAttemptRepository() LoginAttemptRepository {
+	return &memoryLoginAttemptRepository{attempts: make(map[string]*memoryLoginAttempt)}
+}
+
+func (r *memoryLoginAttemptRepository) Get(key string) (*models.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt := r.attempt(key, time.Now()).attempt
+	return &attempt, nil
+}
+
+func (r *memoryLoginAttemptRepository) Reserve(key string, failures int, at time.Time, ttl time.Duration) (*models.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.purge(now)
+	entry := r.attempt(key, now)
+	if entry.attempt.Failures != failures {
+		return nil, ErrConflict
+	}
+	entry.attempt.Failures++
+	entry.attempt.LastFailedAt = at
+	entry.expiresAt = now.Add(ttl)
+	r.attempts[key] = entry
+	attempt := entry.attempt
+	return &attempt, nil
+}
+
+func (r *memoryLoginAttemptRepository) Release(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry, ok := r.attempts[key]; ok && time.Now().Before(entry.expiresAt) && entry.attempt.Failures > 0 {
+		entry.attempt.Failures--
+	}
+	return nil
+}
+
+func (r *memoryLoginAttemptRepository) Lock(key string, until time.Time, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	entry := r.attempt(key, now)
+	entry.attempt.LockedUntil = until
+	entry.expiresAt = now.Add(ttl)
+	r.attempts[key] = entry
+	return nil
+}
+
+func (r *memoryLoginAttemptRepository) Reset(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+	return nil
+}
+
+// attempt 返回未过期的记录，不存在时返回新的空记录（未保存）
+func (r *memoryLoginAttemptRepository) attempt(key string, now time.Time) *memoryLoginAttempt {
+	if entry, ok := r.attempts[key]; ok && now.Before(entry.expiresAt) {
+		return entry
+	}
+	return &memoryLoginAttempt{}
+}
+
+func (r *memoryLoginAttemptRepository) purge(now time.Time) {
+	if now.Sub(r.lastPurge) < memoryLoginAttemptPurgeInterval {
+		return
+	}
+	r.lastPurge = now
+	for key, entry := range r.attempts {
+		if !now.Before(entry.expiresAt) {
+			delete(r.attempts, key)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go_app/models"
+)
+
+type memoryNotificationRepository struct {
+	mu            sync.RWMutex
+	nextID        uint
+	notifications map[uint]models.Notification
+}
+
+func NewMemoryNotificationRepository() NotificationRepository {
+	return &memoryNotificationRepository{notifications: make(map[uint]models.Notification)}
+}
+
+func (r *memoryNotificationRepository) Create(notification *models.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	now := time.Now()
+	notification.ID = r.nextID
+	notification.CreatedAt = now
+	notification.UpdatedAt = now
+	r.notifications[notification.ID] = *notification
+	return nil
+}
+
+func (r *memoryNotificationRepository) FindByID(id uint) (*models.Notification, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	notification, ok := r.notifications[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &notification, nil
+}
+
+func (r *memoryNotificationRepository) ListByUser(userID uint, unreadOnly bool, page Page) ([]models.Notification, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var notifications []models.Notification
+	for _, n := range r.notifications {
+		if n.UserID == userID && (!unreadOnly || !n.Read) {
+			notifications = append(notifications, n)
+		}
+	}
+	sort.Slice(notifications, func(i, j int) bool {
+		a, b := notifications[i], notifications[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+	return slice(notifications, page), int64(len(notifications)), nil
+}
+
+func (r *memoryNotificationRepository) CountUnread(userID uint) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, n := range r.notifications {
+		if n.UserID == userID && !n.Read {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryNotificationRepository) MarkRead(userID, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, ok := r.notifications[id]
+	if !ok || n.UserID != userID {
+		return ErrNotFound
+	}
+	if !n.Read {
+		n.Read = true
+		n.UpdatedAt = time.Now()
+		r.notifications[id] = n
+	}
+	return nil
+}
+
+func (r *memoryNotificationRepository) MarkAllRead(userID uint) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	now := time.Now()
+	for id, n := range r.notifications {
+		if n.UserID == userID && !n.Read {
+			n.Read = true
+			n.UpdatedAt = now
+			r.notifications[id] = n
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryNotificationRepository) Delete(userID, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, ok := r.notifications[id]
+	if !ok || n.UserID != userID {
+		return ErrNotFound
+	}
+	delete(r.notifications, id)
+	return nil
+}
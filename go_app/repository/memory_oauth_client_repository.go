@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go_app/models"
+)
+
+type memoryOAuthClientRepository struct {
+	mu      sync.RWMutex
+	nextID  uint
+	clients map[uint]models.OAuthClient
+}
+
+func NewMemoryOAuthClientRepository() OAuthClientRepository {
+	return &memoryOAuthClientRepository{clients: make(map[uint]models.OAuthClient)}
+}
+
+func (r *memoryOAuthClientRepository) Create(client *models.OAuthClient) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range r.clients {
+		if c.ClientID == client.ClientID {
+			return ErrDuplicate
+		}
+	}
+	r.nextID++
+	now := time.Now()
+	client.ID = r.nextID
+	client.CreatedAt, client.UpdatedAt = now, now
+	r.clients[client.ID] = *client
+	return nil
+}
+
+func (r *memoryOAuthClientRepository) FindByClientID(clientID string) (*models.OAuthClient, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, c := range r.clients {
+		if c.ClientID == clientID {
+			return &c, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryOAuthClientRepository) List() ([]*models.OAuthClient, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var clients []*models.OAuthClient
+	for _, c := range r.clients {
+		c := c
+		clients = append(clients, &c)
+	}
+	sort.Slice(clients, func(i, j int) bool { return clients[i].ID < clients[j].ID })
+	return clients, nil
+}
+
+func (r *memoryOAuthClientRepository) Delete(clientID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, c := range r.clients {
+		if c.ClientID == clientID {
+			delete(r.clients, id)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
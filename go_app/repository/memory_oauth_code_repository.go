@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"go_app/models"
+)
+
+type memoryOAuthCodeRepository struct {
+	mu     sync.RWMutex
+	nextID uint
+	codes  map[uint]models.OAuthAuthorizationCode
+}
+
+func NewMemoryOAuthCodeRepository() OAuthCodeRepository {
+	return &memoryOAuthCodeRepository{codes: make(map[uint]models.OAuthAuthorizationCode)}
+}
+
+func (r *memoryOAuthCodeRepository) Create(code *models.OAuthAuthorizationCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range r.codes {
+		if c.CodeHash == code.CodeHash {
+			return ErrDuplicate
+		}
+	}
+	for id, c := range r.codes {
+		if c.IsExpired() {
+			delete(r.codes, id)
+		}
+	}
+	r.nextID++
+	code.ID = r.nextID
+	code.CreatedAt = time.Now()
+	r.codes[code.ID] = *code
+	return nil
+}
+
+func (r *memoryOAuthCodeRepository) FindByHash(codeHash string) (*models.OAuthAuthorizationCode, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, c := range r.codes {
+		if c.CodeHash == codeHash {
+			return &c, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryOAuthCodeRepository) Consume(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.codes[id]
+	if !ok || c.UsedAt != nil {
+		return ErrConflict
+	}
+	now := time.Now()
+	c.UsedAt = &now
+	r.codes[id] = c
+	return nil
+}
+
+func (r *memoryOAuthCodeRepository) ConsumeByUser(userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, c := range r.codes {
+		if c.UserID == userID && c.UsedAt == nil {
+			c.UsedAt = &now
+			r.codes[id] = c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go_app/models"
+)
+
+type memoryOAuthConsentRepository struct {
+	mu       sync.RWMutex
+	nextID   uint
+	consents map[uint]models.OAuthConsent
+}
+
+func NewMemoryOAuthConsentRepository() OAuthConsentRepository {
+	return &memoryOAuthConsentRepository{consents: make(map[uint]models.OAuthConsent)}
+}
+
+func (r *memoryOAuthConsentRepository) Find(userID uint, clientID string) (*models.OAuthConsent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, c := range r.consents {
+		if c.UserID == userID && c.ClientID == clientID {
+			return &c, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryOAuthConsentRepository) Save(consent *models.OAuthConsent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, c := range r.consents {
+		if c.UserID == consent.UserID && c.ClientID == consent.ClientID {
+			c.Scopes = consent.Scopes
+			c.UpdatedAt = now
+			r.consents[id] = c
+			*consent = c
+			return nil
+		}
+	}
+	r.nextID++
+	consent.ID = r.nextID
+	consent.CreatedAt, consent.UpdatedAt = now, now
+	r.consents[consent.ID] = *consent
+	return nil
+}
+
+func (r *memoryOAuthConsentRepository) ListByUser(userID uint) ([]*models.OAuthConsent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var consents []*models.OAuthConsent
+	for _, c := range r.consents {
+		if c.UserID == userID {
+			c := c
+			consents = append(consents, &c)
+		}
+	}
+	sort.Slice(consents, func(i, j int) bool { return consents[i].ID < consents[j].ID })
+	return consents, nil
+}
+
+func (r *memoryOAuthConsentRepository) Delete(userID uint, clientID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, c := range r.consents {
+		if c.UserID == userID && c.ClientID == clientID {
+			delete(r.consents, id)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *memoryOAuthConsentRepository) DeleteByClient(clientID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, c := range r.consents {
+		if c.ClientID == clientID {
+			delete(r.consents, id)
+		}
+	}
+	return nil
+}
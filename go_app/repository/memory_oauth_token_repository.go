@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"go_app/models"
+)
+
+type memoryOAuthTokenRepository struct {
+	mu     sync.RWMutex
+	nextID uint
+	tokens map[uint]models.OAuthToken
+}
+
+func NewMemoryOAuthTokenRepository() OAuthTokenRepository {
+	return &memoryOAuthTokenRepository{tokens: make(map[uint]models.OAuthToken)}
+}
+
+func (r *memoryOAuthTokenRepository) Create(token *models.OAuthToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, t := range r.tokens {
+		if t.AccessTokenHash == token.AccessTokenHash ||
+			(token.RefreshTokenHash != nil && t.RefreshTokenHash != nil && *t.RefreshTokenHash == *token.RefreshTokenHash) {
+			return ErrDuplicate
+		}
+	}
+	now := time.Now()
+	for id, t := range r.tokens {
+		if t.AccessExpiredAt.Before(now) && (t.RefreshExpiredAt == nil || t.RefreshExpiredAt.Before(now)) {
+			delete(r.tokens, id)
+		}
+	}
+	r.nextID++
+	token.ID = r.nextID
+	token.CreatedAt = now
+	stored := *token
+	if token.RefreshTokenHash != nil {
+		hash := *token.RefreshTokenHash
+		stored.RefreshTokenHash = &hash
+	}
+	r.tokens[token.ID] = stored
+	return nil
+}
+
+func (r *memoryOAuthTokenRepository) FindByAccessHash(accessHash string) (*models.OAuthToken, error) {
+	return r.find(func(t *models.OAuthToken) bool { return t.AccessTokenHash == accessHash })
+}
+
+func (r *memoryOAuthTokenRepository) FindByRefreshHash(refreshHash string) (*models.OAuthToken, error) {
+	return r.find(func(t *models.OAuthToken) bool {
+		return t.RefreshTokenHash != nil && *t.RefreshTokenHash == refreshHash
+	})
+}
+
+func (r *memoryOAuthTokenRepository) find(match func(t *models.OAuthToken) bool) (*models.OAuthToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, t := range r.tokens {
+		if match(&t) {
+			return &t, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryOAuthTokenRepository) Revoke(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.tokens[id]
+	if !ok || t.RevokedAt != nil {
+		return ErrConflict
+	}
+	now := time.Now()
+	t.RevokedAt = &now
+	r.tokens[id] = t
+	return nil
+}
+
+func (r *memoryOAuthTokenRepository) RevokeFamily(familyID string) error {
+	return r.revokeWhere(func(t *models.OAuthToken) bool { return t.FamilyID == familyID })
+}
+
+func (r *memoryOAuthTokenRepository) RevokeByUserClient(userID uint, clientID string) error {
+	return r.revokeWhere(func(t *models.OAuthToken) bool { return t.UserID == userID && t.ClientID == clientID })
+}
+
+func (r *memoryOAuthTokenRepository) RevokeByUser(userID uint) error {
+	return r.revokeWhere(func(t *models.OAuthToken) bool { return t.UserID == userID })
+}
+
+func (r *memoryOAuthTokenRepository) RevokeByClient(clientID string) error {
+	return r.revokeWhere(func(t *models.OAuthToken) bool { return t.ClientID == clientID })
+}
+
+func (r *memoryOAuthTokenRepository) revokeWhere(match func(t *models.OAuthToken) bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, t := range r.tokens {
+		if t.RevokedAt == nil && match(&t) {
+			t.RevokedAt = &now
+			r.tokens[id] = t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"go_app/models"
+)
+
+type memoryOIDCFlowRepository struct {
+	mu     sync.RWMutex
+	nextID uint
+	flows  map[uint]models.OIDCFlow
+}
+
+func NewMemoryOIDCFlowRepository() OIDCFlowRepository {
+	return &memoryOIDCFlowRepository{flows: make(map[uint]models.OIDCFlow)}
+}
+
+func (r *memoryOIDCFlowRepository) Create(flow *models.OIDCFlow) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, f := range r.flows {
+		if f.StateHash == flow.StateHash {
+			return ErrDuplicate
+		}
+	}
+	for id, f := range r.flows {
+		if f.IsExpired() {
+			delete(r.flows, id)
+		}
+	}
+	r.nextID++
+	flow.ID = r.nextID
+	flow.CreatedAt = time.Now()
+	r.flows[flow.ID] = *flow
+	return nil
+}
+
+func (r *memoryOIDCFlowRepository) FindByHash(stateHash string) (*models.OIDCFlow, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, f := range r.flows {
+		if f.StateHash == stateHash {
+			return &f, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryOIDCFlowRepository) Consume(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.flows[id]
+	if !ok || f.UsedAt != nil {
+		return ErrConflict
+	}
+	now := time.Now()
+	f.UsedAt = &now
+	r.flows[id] = f
+	return nil
+}
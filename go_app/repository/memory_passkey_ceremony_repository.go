@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"go_app/models"
+)
+
+type memoryPasskeyCeremonyRepository struct {
+	mu         sync.RWMutex
+	nextID     uint
+	ceremonies map[uint]models.PasskeyCeremony
+}
+
+func NewMemoryPasskeyCeremonyRepository() PasskeyCeremonyRepository {
+	return &memoryPasskeyCeremonyRepository{ceremonies: make(map[uint]models.PasskeyCeremony)}
+}
+
+func (r *memoryPasskeyCeremonyRepository) Create(ceremony *models.PasskeyCeremony) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range r.ceremonies {
+		if c.TokenHash == ceremony.TokenHash {
+			return ErrDuplicate
+		}
+	}
+	for id, c := range r.ceremonies {
+		if c.IsExpired() {
+			delete(r.ceremonies, id)
+		}
+	}
+	r.nextID++
+	ceremony.ID = r.nextID
+	ceremony.CreatedAt = time.Now()
+	r.ceremonies[ceremony.ID] = *ceremony
+	return nil
+}
+
+func (r *memoryPasskeyCeremonyRepository) FindByHash(tokenHash string) (*models.PasskeyCeremony, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, c := range r.ceremonies {
+		if c.TokenHash == tokenHash {
+			return &c, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryPasskeyCeremonyRepository) Consume(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.ceremonies[id]
+	if !ok || c.UsedAt != nil {
+		return ErrConflict
+	}
+	now := time.Now()
+	c.UsedAt = &now
+	r.ceremonies[id] = c
+	return nil
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go_app/models"
+)
+
+type memoryPasskeyRepository struct {
+	mu       sync.RWMutex
+	nextID   uint
+	passkeys map[uint]models.Passkey
+}
+
+func NewMemoryPasskeyRepository() PasskeyRepository {
+	return &memoryPasskeyRepository{passkeys: make(map[uint]models.Passkey)}
+}
+
+func (r *memoryPasskeyRepository) Create(passkey *models.Passkey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, p := range r.passkeys {
+		if p.CredentialID == passkey.CredentialID {
+			return ErrDuplicate
+		}
+	}
+	r.nextID++
+	now := time.Now()
+	passkey.ID = r.nextID
+	passkey.CreatedAt, passkey.UpdatedAt = now, now
+	r.passkeys[passkey.ID] = *passkey
+	return nil
+}
+
+func (r *memoryPasskeyRepository) FindByCredentialID(credentialID string) (*models.Passkey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, p := range r.passkeys {
+		if p.CredentialID == credentialID {
+			return &p, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryPasskeyRepository) ListByUser(userID uint) ([]*models.Passkey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var passkeys []*models.Passkey
+	for _, p := range r.passkeys {
+		if p.UserID == userID {
+			p := p
+			passkeys = append(passkeys, &p)
+		}
+	}
+	sort.Slice(passkeys, func(i, j int) bool { return passkeys[i].ID < passkeys[j].ID })
+	return passkeys, nil
+}
+
+func (r *memoryPasskeyRepository) RecordUse(id uint, signCount uint32, backupState bool, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.passkeys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	p.SignCount = signCount
+	p.BackupState = backupState
+	p.LastUsedAt = &at
+	p.UpdatedAt = time.Now()
+	r.passkeys[id] = p
+	return nil
+}
+
+func (r *memoryPasskeyRepository) Rename(userID, id uint, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.passkeys[id]
+	if !ok || p.UserID != userID {
+		return ErrNotFound
+	}
+	p.Name = name
+	p.UpdatedAt = time.Now()
+	r.passkeys[id] = p
+	return nil
+}
+
+func (r *memoryPasskeyRepository) Delete(userID, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.passkeys[id]
+	if !ok || p.UserID != userID {
+		return ErrNotFound
+	}
+	delete(r.passkeys, id)
+	return nil
+}
+
+func (r *memoryPasskeyRepository) DeleteByUser(userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, p := range r.passkeys {
+		if p.UserID == userID {
+			delete(r.passkeys, id)
+		}
+	}
+	return nil
+}
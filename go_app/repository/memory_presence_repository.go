@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"go_app/models"
+)
+
+type memoryPresenceConn struct {
+	status    string
+	expiresAt time.Time
+}
+
+type memoryPresenceRepository struct {
+	mu sync.Mutex
+	// conns 用户ID → 连接ID → 连接记录，失效的记录在续期或查询时清理
+	conns     map[uint]map[string]memoryPresenceConn
+	published map[uint]string
+}
+
+// NewMemoryPresenceRepository 进程内的在线状态存储，只适用于单实例部署
+func NewMemoryPresenceRepository() PresenceRepository {
+	return &memoryPresenceRepository{
+		conns:     make(map[uint]map[string]memoryPresenceConn),
+		published: make(map[uint]string),
+	}
+}
+
+func (r *memoryPresenceRepository) Touch(userID uint, connectionID, status string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conns := r.conns[userID]
+	if conns == nil {
+		conns = make(map[string]memoryPresenceConn)
+		r.conns[userID] = conns
+	}
+	conns[connectionID] = memoryPresenceConn{status: status, expiresAt: expiresAt}
+	r.purge(userID, time.Now())
+	return nil
+}
+
+func (r *memoryPresenceRepository) Statuses(userIDs []uint, now time.Time) (map[uint][]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	statuses := make(map[uint][]string)
+	for _, userID := range userIDs {
+		if _, done := statuses[userID]; done {
+			continue
+		}
+		for _, conn := range r.conns[userID] {
+			if conn.expiresAt.After(now) {
+				statuses[userID] = append(statuses[userID], conn.status)
+			}
+		}
+		r.purge(userID, time.Now())
+	}
+	return statuses, nil
+}
+
+// purge 清理用户已失效的连接记录，调用方需持有锁
+func (r *memoryPresenceRepository) purge(userID uint, now time.Time) {
+	for id, conn := range r.conns[userID] {
+		if !conn.expiresAt.After(now) {
+			delete(r.conns[userID], id)
+		}
+	}
+	if len(r.conns[userID]) == 0 {
+		delete(r.conns, userID)
+	}
+}
+
+func (r *memoryPresenceRepository) SwapStatus(userID uint, status string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous, ok := r.published[userID]
+	if !ok {
+		previous = models.PresenceOffline
+	}
+	if status == models.PresenceOffline {
+		delete(r.published, userID)
+	} else {
+		r.published[userID] = status
+	}
+	return previous, nil
+}
+
+func (r *memoryPresenceRepository) Published() ([]uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userIDs := make([]uint, 0, len(r.published))
+	for userID := range r.published {
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, nil
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go_app/models"
+)
+
+// memoryRoleRepository 内存实现，创建时写入内置角色，与执行过迁移的数据库一致
+type memoryRoleRepository struct {
+	mu        sync.RWMutex
+	roles     []models.Role
+	userRoles map[uint]map[uint]time.Time // userID -> roleID -> 分配时间
+}
+
+func NewMemoryRoleRepository() RoleRepository {
+	r := &memoryRoleRepository{userRoles: make(map[uint]map[uint]time.Time)}
+	now := time.Now()
+	for i, role := range models.BuiltinRoles() {
+		role.ID = uint(i + 1)
+		role.CreatedAt = now
+		sort.Strings(role.Permissions)
+		r.roles = append(r.roles, role)
+	}
+	return r
+}
+
+func (r *memoryRoleRepository) List() ([]*models.Role, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	roles := make([]*models.Role, 0, len(r.roles))
+	for i := range r.roles {
+		roles = append(roles, copyRole(&r.roles[i]))
+	}
+	return roles, nil
+}
+
+func (r *memoryRoleRepository) FindByName(name string) (*models.Role, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i := range r.roles {
+		if r.roles[i].Name == name {
+			return copyRole(&r.roles[i]), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryRoleRepository) ListByUser(userID uint) ([]*models.Role, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	roles := make([]*models.Role, 0, len(r.userRoles[userID]))
+	for i := range r.roles {
+		if _, ok := r.userRoles[userID][r.roles[i].ID]; ok {
+			roles = append(roles, copyRole(&r.roles[i]))
+		}
+	}
+	return roles, nil
+}
+
+func (r *memoryRoleRepository) Permissions(userID uint) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	permissions := []string{}
+	for i := range r.roles {
+		if _, ok := r.userRoles[userID][r.roles[i].ID]; !ok {
+			continue
+		}
+		for _, p := range r.roles[i].Permissions {
+			if !contains(permissions, p) {
+				permissions = append(permissions, p)
+			}
+		}
+	}
+	sort.Strings(permissions)
+	return permissions, nil
+}
+
+func (r *memoryRoleRepository) Assign(userID, roleID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.userRoles[userID][roleID]; ok {
+		return nil
+	}
+	if r.userRoles[userID] == nil {
+		r.userRoles[userID] = make(map[uint]time.Time)
+	}
+	r.userRoles[userID][roleID] = time.Now()
+	return nil
+}
+
+func (r *memoryRoleRepository) Revoke(userID, roleID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.userRoles[userID], roleID)
+	return nil
+}
+
+func (r *memoryRoleRepository) DeleteByUser(userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.userRoles, userID)
+	return nil
+}
+
+func (r *memoryRoleRepository) CountUsers(roleID uint) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, roles := range r.userRoles {
+		if _, ok := roles[roleID]; ok {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func copyRole(role *models.Role) *models.Role {
+	copied := *role
+	copied.Permissions = append([]string{}, role.Permissions...)
+	return &copied
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go_app/models"
+)
+
+type memorySessionRepository struct {
+	mu            sync.RWMutex
+	nextSessionID uint
+	nextRefreshID uint
+	sessions      map[uint]models.UserToken
+	refreshTokens map[uint]models.RefreshToken
+}
+
+func NewMemorySessionRepository() SessionRepository {
+	return &memorySessionRepository{
+		sessions:      make(map[uint]models.UserToken),
+		refreshTokens: make(map[uint]models.RefreshToken),
+	}
+}
+
+func (r *memorySessionRepository) Create(session *models.UserToken, refresh *models.RefreshToken, replace SessionReplace) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var evicted []uint
+	for id, old := range r.sessions {
+		if old.UserID == session.UserID && replace.matches(&old, now) {
+			evicted = append(evicted, id)
+		}
+	}
+	// 先校验唯一约束再修改数据，模拟事务回滚
+	for id, old := range r.sessions {
+		if old.Token == session.Token && !containsID(evicted, id) {
+			return ErrDuplicate
+		}
+	}
+	if r.refreshHashExists(refresh.TokenHash) {
+		return ErrDuplicate
+	}
+
+	r.deleteSessions(evicted...)
+
+	r.nextSessionID++
+	session.ID = r.nextSessionID
+	session.CreatedAt = now
+	session.UpdatedAt = now
+	r.sessions[session.ID] = *session
+
+	refresh.UserID = session.UserID
+	refresh.SessionID = session.ID
+	r.createRefreshToken(refresh, now)
+	return nil
+}
+
+func (r *memorySessionRepository) FindByID(id uint) (*models.UserToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &session, nil
+}
+
+func (r *memorySessionRepository) FindByToken(token string) (*models.UserToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, session := range r.sessions {
+		if session.Token == token {
+			return &session, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memorySessionRepository) ListActive(userID uint) ([]models.UserToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	var sessions []models.UserToken
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.ExpiredAt.After(now) {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastActiveAt.After(sessions[j].LastActiveAt) })
+	return sessions, nil
+}
+
+func (r *memorySessionRepository) Touch(id uint, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if session, ok := r.sessions[id]; ok {
+		session.LastActiveAt = at
+		r.sessions[id] = session
+	}
+	return nil
+}
+
+func (r *memorySessionRepository) Delete(ids ...uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deleteSessions(ids...)
+	return nil
+}
+
+func (r *memorySessionRepository) DeleteByUser(userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, session := range r.sessions {
+		if session.UserID == userID {
+			delete(r.sessions, id)
+		}
+	}
+	for id, rt := range r.refreshTokens {
+		if rt.UserID == userID {
+			delete(r.refreshTokens, id)
+		}
+	}
+	return nil
+}
+
+func (r *memorySessionRepository) FindRefreshToken(tokenHash string) (*models.RefreshToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, rt := range r.refreshTokens {
+		if rt.TokenHash == tokenHash {
+			return &rt, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memorySessionRepository) Rotate(usedID uint, session *models.UserToken, next *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	used, ok := r.refreshTokens[usedID]
+	if !ok || used.UsedAt != nil {
+		return ErrConflict
+	}
+	stored, ok := r.sessions[session.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if r.refreshHashExists(next.TokenHash) {
+		return ErrDuplicate
+	}
+
+	now := time.Now()
+	used.UsedAt = &now
+	r.refreshTokens[usedID] = used
+
+	stored.LastActiveAt = session.LastActiveAt
+	stored.ExpiredAt = session.ExpiredAt
+	stored.UpdatedAt = now
+	r.sessions[session.ID] = stored
+
+	next.UserID = session.UserID
+	next.SessionID = session.ID
+	r.createRefreshToken(next, now)
+	return nil
+}
+
+func (r *memorySessionRepository) createRefreshToken(rt *models.RefreshToken, now time.Time) {
+	r.nextRefreshID++
+	rt.ID = r.nextRefreshID
+	rt.CreatedAt = now
+	r.refreshTokens[rt.ID] = *rt
+}
+
+func (r *memorySessionRepository) refreshHashExists(hash string) bool {
+	for _, rt := range r.refreshTokens {
+		if rt.TokenHash == hash {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memorySessionRepository) deleteSessions(ids ...uint) {
+	for _, id := range ids {
+		delete(r.sessions, id)
+	}
+	for rid, rt := range r.refreshTokens {
+		if containsID(ids, rt.SessionID) {
+			delete(r.refreshTokens, rid)
+		}
+	}
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, item := range ids {
+		if item == id {
+			return true
+		}
+	}
+	return false
+}
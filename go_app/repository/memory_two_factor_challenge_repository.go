@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"go_app/models"
+)
+
+type memoryTwoFactorChallengeRepository struct {
+	mu         sync.RWMutex
+	nextID     uint
+	challenges map[uint]models.TwoFactorChallenge
+}
+
+func NewMemoryTwoFactorChallengeRepository() TwoFactorChallengeRepository {
+	return &memoryTwoFactorChallengeRepository{challenges: make(map[uint]models.TwoFactorChallenge)}
+}
+
+func (r *memoryTwoFactorChallengeRepository) Create(challenge *models.TwoFactorChallenge) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range r.challenges {
+		if c.TokenHash == challenge.TokenHash {
+			return ErrDuplicate
+		}
+	}
+	for id, c := range r.challenges {
+		if c.UserID == challenge.UserID && c.IsExpired() {
+			delete(r.challenges, id)
+		}
+	}
+	r.nextID++
+	challenge.ID = r.nextID
+	challenge.CreatedAt = time.Now()
+	r.challenges[challenge.ID] = *challenge
+	return nil
+}
+
+func (r *memoryTwoFactorChallengeRepository) FindByHash(tokenHash string) (*models.TwoFactorChallenge, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, c := range r.challenges {
+		if c.TokenHash == tokenHash {
+			return &c, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryTwoFactorChallengeRepository) Attempt(id uint, maxAttempts int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.challenges[id]
+	if !ok || c.UsedAt != nil || c.Attempts >= maxAttempts {
+		return ErrConflict
+	}
+	c.Attempts++
+	r.challenges[id] = c
+	return nil
+}
+
+func (r *memoryTwoFactorChallengeRepository) Consume(id uint, maxAttempts int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.challenges[id]
+	if !ok || c.UsedAt != nil || c.Attempts > maxAttempts {
+		return ErrConflict
+	}
+	now := time.Now()
+	c.UsedAt = &now
+	r.challenges[id] = c
+	return nil
+}
+
+func (r *memoryTwoFactorChallengeRepository) DeleteByUser(userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, c := range r.challenges {
+		if c.UserID == userID {
+			delete(r.challenges, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"go_app/models"
+)
+
+type memoryTwoFactorRepository struct {
+	mu         sync.RWMutex
+	twoFactors map[uint]models.TwoFactor
+	// codes 用户ID -> 恢复码哈希 -> 是否已使用
+	codes map[uint]map[string]bool
+}
+
+func NewMemoryTwoFactorRepository() TwoFactorRepository {
+	return &memoryTwoFactorRepository{
+		twoFactors: make(map[uint]models.TwoFactor),
+		codes:      make(map[uint]map[string]bool),
+	}
+}
+
+func (r *memoryTwoFactorRepository) Find(userID uint) (*models.TwoFactor, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.twoFactors[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &t, nil
+}
+
+func (r *memoryTwoFactorRepository) Save(twoFactor *models.TwoFactor) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	twoFactor.ConfirmedAt = nil
+	twoFactor.LastUsedStep = 0
+	twoFactor.Attempts = 0
+	twoFactor.AttemptedAt = nil
+	twoFactor.CreatedAt = now
+	twoFactor.UpdatedAt = now
+	r.twoFactors[twoFactor.UserID] = *twoFactor
+	return nil
+}
+
+func (r *memoryTwoFactorRepository) Enable(userID uint, at time.Time, codeHashes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.twoFactors[userID]
+	if !ok || t.ConfirmedAt != nil {
+		return ErrConflict
+	}
+	t.ConfirmedAt = &at
+	t.UpdatedAt = time.Now()
+	r.twoFactors[userID] = t
+	r.replaceCodes(userID, codeHashes)
+	return nil
+}
+
+func (r *memoryTwoFactorRepository) Attempt(userID uint, maxAttempts int, since, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.twoFactors[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	if t.AttemptedAt == nil || t.AttemptedAt.Before(since) {
+		t.Attempts = 0
+	}
+	if t.Attempts >= maxAttempts {
+		return ErrConflict
+	}
+	t.Attempts++
+	t.AttemptedAt = &at
+	r.twoFactors[userID] = t
+	return nil
+}
+
+func (r *memoryTwoFactorRepository) ResetAttempts(userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if t, ok := r.twoFactors[userID]; ok {
+		t.Attempts = 0
+		r.twoFactors[userID] = t
+	}
+	return nil
+}
+
+func (r *memoryTwoFactorRepository) UseStep(userID uint, step int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.twoFactors[userID]
+	if !ok || t.LastUsedStep >= step {
+		return ErrConflict
+	}
+	t.LastUsedStep = step
+	r.twoFactors[userID] = t
+	return nil
+}
+
+func (r *memoryTwoFactorRepository) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.replaceCodes(userID, codeHashes)
+	return nil
+}
+
+func (r *memoryTwoFactorRepository) UseRecoveryCode(userID uint, codeHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	used, ok := r.codes[userID][codeHash]
+	if !ok || used {
+		return ErrNotFound
+	}
+	r.codes[userID][codeHash] = true
+	return nil
+}
+
+func (r *memoryTwoFactorRepository) CountRecoveryCodes(userID uint) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, used := range r.codes[userID] {
+		if !used {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryTwoFactorRepository) Delete(userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.twoFactors, userID)
+	delete(r.codes, userID)
+	return nil
+}
+
+// replaceCodes 调用方需持有写锁
+func (r *memoryTwoFactorRepository) replaceCodes(userID uint, codeHashes []string) {
+	codes := make(map[string]bool, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes[hash] = false
+	}
+	r.codes[userID] = codes
+}
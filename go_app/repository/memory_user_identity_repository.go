@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go_app/models"
+)
+
+type memoryUserIdentityRepository struct {
+	mu         sync.RWMutex
+	nextID     uint
+	identities map[uint]models.UserIdentity
+}
+
+func NewMemoryUserIdentityRepository() UserIdentityRepository {
+	return &memoryUserIdentityRepository{identities: make(map[uint]models.UserIdentity)}
+}
+
+func (r *memoryUserIdentityRepository) Create(identity *models.UserIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, i := range r.identities {
+		if i.Provider == identity.Provider && i.Subject == identity.Subject {
+			return ErrDuplicate
+		}
+	}
+	r.nextID++
+	now := time.Now()
+	identity.ID = r.nextID
+	identity.CreatedAt, identity.UpdatedAt = now, now
+	r.identities[identity.ID] = *identity
+	return nil
+}
+
+func (r *memoryUserIdentityRepository) FindBySubject(provider, subject string) (*models.UserIdentity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, i := range r.identities {
+		if i.Provider == provider && i.Subject == subject {
+			return &i, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryUserIdentityRepository) ListByUser(userID uint) ([]*models.UserIdentity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var identities []*models.UserIdentity
+	for _, i := range r.identities {
+		if i.UserID == userID {
+			i := i
+			identities = append(identities, &i)
+		}
+	}
+	sort.Slice(identities, func(a, b int) bool { return identities[a].ID < identities[b].ID })
+	return identities, nil
+}
+
+func (r *memoryUserIdentityRepository) RecordLogin(id uint, email string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.identities[id]
+	if !ok {
+		return ErrNotFound
+	}
+	i.Email = email
+	i.LastLoginAt = &at
+	i.UpdatedAt = time.Now()
+	r.identities[id] = i
+	return nil
+}
+
+func (r *memoryUserIdentityRepository) Delete(userID, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.identities[id]
+	if !ok || i.UserID != userID {
+		return ErrNotFound
+	}
+	delete(r.identities, id)
+	return nil
+}
@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go_app/models"
+)
+
+// memoryUserRepository 内存实现，读写均复制对象，调用方修改返回值不会影响已保存的数据
+type memoryUserRepository struct {
+	mu     sync.RWMutex
+	nextID uint
+	users  map[uint]models.User
+}
+
+func NewMemoryUserRepository() UserRepository {
+	return &memoryUserRepository{users: make(map[uint]models.User)}
+}
+
+func (r *memoryUserRepository) Create(user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.findByEmail(user.Email) != nil {
+		return ErrDuplicate
+	}
+	r.nextID++
+	now := time.Now()
+	user.ID = r.nextID
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *memoryUserRepository) FindByID(id uint) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *memoryUserRepository) FindByEmail(email string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user := r.findByEmail(email)
+	if user == nil {
+		return nil, ErrNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *memoryUserRepository) FindByIDs(ids []uint) ([]*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := []*models.User{}
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		if user, ok := r.users[id]; ok && !seen[id] {
+			seen[id] = true
+			users = append(users, &user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+func (r *memoryUserRepository) EmailExists(email string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.findByEmail(email) != nil, nil
+}
+
+func (r *memoryUserRepository) List(page Page) ([]*models.User, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]*models.User, 0, len(r.users))
+	for _, user := range r.users {
+		copied := user
+		users = append(users, &copied)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return slice(users, page), int64(len(users)), nil
+}
+
+func (r *memoryUserRepository) Update(user *models.User, fields ...string) error {
+	if err := checkFields(fields, UserUpdatableFields); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[user.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	for _, field := range fields {
+		switch field {
+		case "username":
+			stored.Username = user.Username
+		case "email":
+			if other := r.findByEmail(user.Email); other != nil && other.ID != user.ID {
+				return ErrDuplicate
+			}
+			stored.Email = user.Email
+		case "avatar_url":
+			stored.AvatarURL = user.AvatarURL
+		case "birthday":
+			stored.Birthday = user.Birthday
+		case "gender":
+			stored.Gender = user.Gender
+		case "hobbies":
+			stored.Hobbies = user.Hobbies
+		}
+	}
+	stored.UpdatedAt = time.Now()
+	user.UpdatedAt = stored.UpdatedAt
+	r.users[user.ID] = stored
+	return nil
+}
+
+func (r *memoryUserRepository) UpdatePassword(id uint, hashedPassword string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	user.Password = hashedPassword
+	user.TokenVersion++
+	user.UpdatedAt = time.Now()
+	r.users[id] = user
+	return nil
+}
+
+func (r *memoryUserRepository) SetDisabled(id uint, disabledAt *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	user.DisabledAt = disabledAt
+	user.UpdatedAt = time.Now()
+	r.users[id] = user
+	return nil
+}
+
+func (r *memoryUserRepository) SetPendingEmail(id uint, email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	user.PendingEmail = email
+	user.UpdatedAt = time.Now()
+	r.users[id] = user
+	return nil
+}
+
+func (r *memoryUserRepository) VerifyEmail(id uint, email string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	switch {
+	case user.Email == email:
+	case user.PendingEmail != "" && user.PendingEmail == email:
+		if other := r.findByEmail(email); other != nil && other.ID != id {
+			return ErrDuplicate
+		}
+		user.Email = email
+		user.PendingEmail = ""
+	default:
+		return ErrConflict
+	}
+	user.EmailVerifiedAt = &at
+	user.UpdatedAt = time.Now()
+	r.users[id] = user
+	return nil
+}
+
+func (r *memoryUserRepository) SetLastSeen(id uint, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	user.LastSeenAt = &at
+	r.users[id] = user
+	return nil
+}
+
+func (r *memoryUserRepository) Delete(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.users, id)
+	return nil
+}
+
+func (r *memoryUserRepository) findByEmail(email string) *models.User {
+	for _, user := range r.users {
+		if user.Email == email {
+			return &user
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"go_app/models"
+)
+
+type memoryWebSocketOutboxRepository struct {
+	mu       sync.RWMutex
+	nextID   uint
+	messages map[uint][]models.WebSocketMessage // 用户ID → 按 Seq 升序的消息
+	cursors  map[uint]models.WebSocketCursor
+}
+
+func NewMemoryWebSocketOutboxRepository() WebSocketOutboxRepository {
+	return &memoryWebSocketOutboxRepository{
+		messages: make(map[uint][]models.WebSocketMessage),
+		cursors:  make(map[uint]models.WebSocketCursor),
+	}
+}
+
+func (r *memoryWebSocketOutboxRepository) Append(msg *models.WebSocketMessage, keep int, cutoff time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	cursor := r.cursors[msg.UserID]
+	cursor.UserID = msg.UserID
+	cursor.LastSeq++
+	cursor.UpdatedAt = now
+	r.cursors[msg.UserID] = cursor
+
+	r.nextID++
+	msg.ID = r.nextID
+	msg.Seq = cursor.LastSeq
+	msg.CreatedAt = now
+
+	var kept []models.WebSocketMessage
+	for _, m := range append(r.messages[msg.UserID], *msg) {
+		if m.Seq+uint64(keep) > msg.Seq && !m.CreatedAt.Before(cutoff) {
+			kept = append(kept, m)
+		}
+	}
+	r.messages[msg.UserID] = kept
+	return nil
+}
+
+func (r *memoryWebSocketOutboxRepository) ListAfter(userID uint, afterSeq uint64, cutoff time.Time, limit int) ([]models.WebSocketMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var messages []models.WebSocketMessage
+	for _, m := range r.messages[userID] {
+		if len(messages) == limit {
+			break
+		}
+		if m.Seq > afterSeq && !m.CreatedAt.Before(cutoff) {
+			messages = append(messages, m)
+		}
+	}
+	return messages, nil
+}
+
+func (r *memoryWebSocketOutboxRepository) Cursor(userID uint) (*models.WebSocketCursor, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cursor := r.cursors[userID]
+	cursor.UserID = userID
+	return &cursor, nil
+}
+
+func (r *memoryWebSocketOutboxRepository) Ack(userID uint, seq uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cursor, ok := r.cursors[userID]
+	if !ok || seq <= cursor.AckedSeq || seq > cursor.LastSeq {
+		return nil
+	}
+	cursor.AckedSeq = seq
+	cursor.UpdatedAt = time.Now()
+	r.cursors[userID] = cursor
+	return nil
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"go_app/models"
+
+	"gorm.io/gorm"
+)
+
+// NotificationRepository 用户通知数据访问
+type NotificationRepository interface {
+	Create(notification *models.Notification) error
+	FindByID(id uint) (*models.Notification, error)
+	// ListByUser 按创建时间倒序分页获取用户的通知，同时返回总数
+	ListByUser(userID uint, unreadOnly bool, page Page) ([]models.Notification, int64, error)
+	CountUnread(userID uint) (int64, error)
+	// MarkRead 将用户的指定通知标记为已读，通知不存在或不属于该用户时返回 ErrNotFound
+	MarkRead(userID, id uint) error
+	// MarkAllRead 将用户全部未读通知标记为已读，返回标记的数量
+	MarkAllRead(userID uint) (int64, error)
+	// Delete 删除用户的指定通知，通知不存在或不属于该用户时返回 ErrNotFound
+	Delete(userID, id uint) error
+}
+
+type gormNotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &gormNotificationRepository{db: db}
+}
+
+func (r *gormNotificationRepository) Create(notification *models.Notification) error {
+	return translate(r.db.Create(notification).Error)
+}
+
+func (r *gormNotificationRepository) FindByID(id uint) (*models.Notification, error) {
+	var notification models.Notification
+	if err := r.db.First(&notification, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &notification, nil
+}
+
+// read 是 MySQL 保留字，条件统一使用 map 形式由 GORM 负责转义列名
+func (r *gormNotificationRepository) byUser(userID uint, unreadOnly bool) *gorm.DB {
+	conds := map[string]interface{}{"user_id": userID}
+	if unreadOnly {
+		conds["read"] = false
+	}
+	return r.db.Model(&models.Notification{}).Where(conds)
+}
+
+func (r *gormNotificationRepository) ListByUser(userID uint, unreadOnly bool, page Page) ([]models.Notification, int64, error) {
+	var total int64
+	if err := r.byUser(userID, unreadOnly).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var notifications []models.Notification
+	if err := page.apply(r.byUser(userID, unreadOnly).Order("created_at DESC, id DESC")).Find(&notifications).Error; err != nil {
+		return nil, 0, err
+	}
+	return notifications, total, nil
+}
+
+func (r *gormNotificationRepository) CountUnread(userID uint) (int64, error) {
+	var count int64
+	err := r.byUser(userID, true).Count(&count).Error
+	return count, err
+}
+
+func (r *gormNotificationRepository) MarkRead(userID, id uint) error {
+	// MySQL 的 RowsAffected 不包含值未变化的行，已读通知需要先确认是否存在
+	var count int64
+	if err := r.byUser(userID, false).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+	return r.byUser(userID, true).Where("id = ?", id).Update("read", true).Error
+}
+
+func (r *gormNotificationRepository) MarkAllRead(userID uint) (int64, error) {
+	result := r.byUser(userID, true).Update("read", true)
+	return result.RowsAffected, result.Error
+}
+
+func (r *gormNotificationRepository) Delete(userID, id uint) error {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Notification{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"go_app/models"
+
+	"gorm.io/gorm"
+)
+
+// OAuthClientRepository OAuth 客户端数据访问
+type OAuthClientRepository interface {
+	// Create 登记客户端，client_id 已存在时返回 ErrDuplicate
+	Create(client *models.OAuthClient) error
+	FindByClientID(clientID string) (*models.OAuthClient, error)
+	// List 按创建顺序列出全部客户端
+	List() ([]*models.OAuthClient, error)
+	// Delete 删除客户端，不存在时返回 ErrNotFound
+	Delete(clientID string) error
+}
+
+type gormOAuthClientRepository struct {
+	db *gorm.DB
+}
+
+func NewOAuthClientRepository(db *gorm.DB) OAuthClientRepository {
+	return &gormOAuthClientRepository{db: db}
+}
+
+func (r *gormOAuthClientRepository) Create(client *models.OAuthClient) error {
+	return translate(r.db.Create(client).Error)
+}
+
+func (r *gormOAuthClientRepository) FindByClientID(clientID string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	if err := r.db.Where("client_id = ?", clientID).First(&client).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &client, nil
+}
+
+func (r *gormOAuthClientRepository) List() ([]*models.OAuthClient, error) {
+	var clients []*models.OAuthClient
+	err := r.db.Order("id").Find(&clients).Error
+	return clients, err
+}
+
+func (r *gormOAuthClientRepository) Delete(clientID string) error {
+	result := r.db.Where("client_id = ?", clientID).Delete(&models.OAuthClient{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
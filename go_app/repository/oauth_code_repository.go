@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"time"
+
+	"go_app/models"
+
+	"gorm.io/gorm"
+)
+
+// OAuthCodeRepository 授权码数据访问
+type OAuthCodeRepository interface {
+	// Create 保存新授权码并清理所有已过期的授权码
+	Create(code *models.OAuthAuthorizationCode) error
+	// FindByHash 按授权码的哈希值查找
+	FindByHash(codeHash string) (*models.OAuthAuthorizationCode, error)
+	// Consume 将授权码标记为已使用，已被使用时返回 ErrConflict
+	Consume(id uint) error
+	// ConsumeByUser 将用户尚未使用的授权码全部标记为已使用
+	ConsumeByUser(userID uint) error
+}
+
+type gormOAuthCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewOAuthCodeRepository(db *gorm.DB) OAuthCodeRepository {
+	return &gormOAuthCodeRepository{db: db}
+}
+
+func (r *gormOAuthCodeRepository) Create(code *models.OAuthAuthorizationCode) error {
+	return translate(r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expired_at < ?", time.Now()).Delete(&models.OAuthAuthorizationCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(code).Error
+	}))
+}
+
+func (r *gormOAuthCodeRepository) FindByHash(codeHash string) (*models.OAuthAuthorizationCode, error) {
+	var code models.OAuthAuthorizationCode
+	if err := r.db.Where("code_hash = ?", codeHash).First(&code).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &code, nil
+}
+
+func (r *gormOAuthCodeRepository) Consume(id uint) error {
+	// 条件更新保证并发换取令牌时只有一次成功
+	result := r.db.Model(&models.OAuthAuthorizationCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r *gormOAuthCodeRepository) ConsumeByUser(userID uint) error {
+	return r.db.Model(&models.OAuthAuthorizationCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"go_app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OAuthConsentRepository 授权同意记录数据访问
+type OAuthConsentRepository interface {
+	// Find 查找用户对客户端的授权同意记录
+	Find(userID uint, clientID string) (*models.OAuthConsent, error)
+	// Save 保存授权同意记录，已存在时更新授权范围
+	Save(consent *models.OAuthConsent) error
+	// ListByUser 按同意时间列出用户的全部授权同意记录
+	ListByUser(userID uint) ([]*models.OAuthConsent, error)
+	// Delete 删除授权同意记录，不存在时返回 ErrNotFound
+	Delete(userID uint, clientID string) error
+	// DeleteByClient 删除客户端的全部授权同意记录
+	DeleteByClient(clientID string) error
+}
+
+type gormOAuthConsentRepository struct {
+	db *gorm.DB
+}
+
+func NewOAuthConsentRepository(db *gorm.DB) OAuthConsentRepository {
+	return &gormOAuthConsentRepository{db: db}
+}
+
+func (r *gormOAuthConsentRepository) Find(userID uint, clientID string) (*models.OAuthConsent, error) {
+	var consent models.OAuthConsent
+	if err := r.db.Where("user_id = ? AND client_id = ?", userID, clientID).First(&consent).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &consent, nil
+}
+
+func (r *gormOAuthConsentRepository) Save(consent *models.OAuthConsent) error {
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "client_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"scopes", "updated_at"}),
+	}).Create(consent).Error
+	if err != nil {
+		return translate(err)
+	}
+	// 更新已有记录时部分数据库不返回主键，重新读取保证返回值完整
+	saved, err := r.Find(consent.UserID, consent.ClientID)
+	if err != nil {
+		return err
+	}
+	*consent = *saved
+	return nil
+}
+
+func (r *gormOAuthConsentRepository) ListByUser(userID uint) ([]*models.OAuthConsent, error) {
+	var consents []*models.OAuthConsent
+	err := r.db.Where("user_id = ?", userID).Order("id").Find(&consents).Error
+	return consents, err
+}
+
+func (r *gormOAuthConsentRepository) Delete(userID uint, clientID string) error {
+	result := r.db.Where("user_id = ? AND client_id = ?", userID, clientID).Delete(&models.OAuthConsent{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormOAuthConsentRepository) DeleteByClient(clientID string) error {
+	return r.db.Where("client_id = ?", clientID).Delete(&models.OAuthConsent{}).Error
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"time"
+
+	"go_app/models"
+
+	"gorm.io/gorm"
+)
+
+// OAuthTokenRepository OAuth 令牌数据访问
+type OAuthTokenRepository interface {
+	// Create 保存新令牌，并清理访问令牌和刷新令牌都已过期的记录
+	Create(token *models.OAuthToken) error
+	// FindByAccessHash 按访问令牌的哈希值查找
+	FindByAccessHash(accessHash string) (*models.OAuthToken, error)
+	// FindByRefreshHash 按刷新令牌的哈希值查找
+	FindByRefreshHash(refreshHash string) (*models.OAuthToken, error)
+	// Revoke 吊销令牌，已被吊销时返回 ErrConflict
+	Revoke(id uint) error
+	// RevokeFamily 吊销同一次授权签发的全部令牌
+	RevokeFamily(familyID string) error
+	// RevokeByUserClient 吊销用户授权给客户端的全部令牌
+	RevokeByUserClient(userID uint, clientID string) error
+	// RevokeByUser 吊销用户授权给所有客户端的令牌
+	RevokeByUser(userID uint) error
+	// RevokeByClient 吊销签发给客户端的全部令牌
+	RevokeByClient(clientID string) error
+}
+
+type gormOAuthTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewOAuthTokenRepository(db *gorm.DB) OAuthTokenRepository {
+	return &gormOAuthTokenRepository{db: db}
+}
+
+func (r *gormOAuthTokenRepository) Create(token *models.OAuthToken) error {
+	return translate(r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		// 刷新令牌未过期的记录即使已吊销也要保留，用于识别被重放的刷新令牌
+		err := tx.Where("access_expired_at < ? AND (refresh_expired_at IS NULL OR refresh_expired_at < ?)", now, now).
+			Delete(&models.OAuthToken{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	}))
+}
+
+func (r *gormOAuthTokenRepository) FindByAccessHash(accessHash string) (*models.OAuthToken, error) {
+	var token models.OAuthToken
+	if err := r.db.Where("access_token_hash = ?", accessHash).First(&token).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &token, nil
+}
+
+func (r *gormOAuthTokenRepository) FindByRefreshHash(refreshHash string) (*models.OAuthToken, error) {
+	var token models.OAuthToken
+	if err := r.db.Where("refresh_token_hash = ?", refreshHash).First(&token).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &token, nil
+}
+
+func (r *gormOAuthTokenRepository) Revoke(id uint) error {
+	// 条件更新保证同一刷新令牌并发使用时只有一次成功
+	result := r.db.Model(&models.OAuthToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r *gormOAuthTokenRepository) RevokeFamily(familyID string) error {
+	return r.revokeWhere("family_id = ?", familyID)
+}
+
+func (r *gormOAuthTokenRepository) RevokeByUserClient(userID uint, clientID string) error {
+	return r.revokeWhere("user_id = ? AND client_id = ?", userID, clientID)
+}
+
+func (r *gormOAuthTokenRepository) RevokeByUser(userID uint) error {
+	return r.revokeWhere("user_id = ?", userID)
+}
+
+func (r *gormOAuthTokenRepository) RevokeByClient(clientID string) error {
+	return r.revokeWhere("client_id = ?", clientID)
+}
+
+func (r *gormOAuthTokenRepository) revokeWhere(query string, args ...interface{}) error {
+	return r.db.Model(&models.OAuthToken{}).
+		Where(query, args...).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"time"
+
+	"go_app/models"
+
+	"gorm.io/gorm"
+)
+
+// OIDCFlowRepository 第三方登录流程数据访问
+type OIDCFlowRepository interface {
+	// Create 保存新流程并清理所有已过期的流程
+	Create(flow *models.OIDCFlow) error
+	// FindByHash 按 state 哈希值查找流程
+	FindByHash(stateHash string) (*models.OIDCFlow, error)
+	// Consume 将流程标记为已使用，已被使用时返回 ErrConflict
+	Consume(id uint) error
+}
+
+type gormOIDCFlowRepository struct {
+	db *gorm.DB
+}
+
+func NewOIDCFlowRepository(db *gorm.DB) OIDCFlowRepository {
+	return &gormOIDCFlowRepository{db: db}
+}
+
+func (r *gormOIDCFlowRepository) Create(flow *models.OIDCFlow) error {
+	return translate(r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expired_at < ?", time.Now()).Delete(&models.OIDCFlow{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(flow).Error
+	}))
+}
+
+func (r *gormOIDCFlowRepository) FindByHash(stateHash string) (*models.OIDCFlow, error) {
+	var flow models.OIDCFlow
+	if err := r.db.Where("state_hash = ?", stateHash).First(&flow).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &flow, nil
+}
+
+func (r *gormOIDCFlowRepository) Consume(id uint) error {
+	// 条件更新保证同一流程只能完成一次，授权码和 PKCE 校验值不会被重放
+	result := r.db.Model(&models.OIDCFlow{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"time"
+
+	"go_app/models"
+
+	"gorm.io/gorm"
+)
+
+// PasskeyCeremonyRepository 通行密钥注册/登录流程数据访问
+type PasskeyCeremonyRepository interface {
+	// Create 保存新流程并清理所有已过期的流程
+	Create(ceremony *models.PasskeyCeremony) error
+	// FindByHash 按令牌哈希值查找流程
+	FindByHash(tokenHash string) (*models.PasskeyCeremony, error)
+	// Consume 将流程标记为已使用，已被使用时返回 ErrConflict
+	Consume(id uint) error
+}
+
+type gormPasskeyCeremonyRepository struct {
+	db *gorm.DB
+}
+
+func NewPasskeyCeremonyRepository(db *gorm.DB) PasskeyCeremonyRepository {
+	return &gormPasskeyCeremonyRepository{db: db}
+}
+
+func (r *gormPasskeyCeremonyRepository) Create(ceremony *models.PasskeyCeremony) error {
+	return translate(r.db.Transaction(func(tx *gorm.DB) error {
+		// 登录流程开始时还不知道用户，只能按过期时间统一清理
+		if err := tx.Where("expired_at < ?", time.Now()).Delete(&models.PasskeyCeremony{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(ceremony).Error
+	}))
+}
+
+func (r *gormPasskeyCeremonyRepository) FindByHash(tokenHash string) (*models.PasskeyCeremony, error) {
+	var ceremony models.PasskeyCeremony
+	if err := r.db.Where("token_hash = ?", tokenHash).First(&ceremony).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &ceremony, nil
+}
+
+func (r *gormPasskeyCeremonyRepository) Consume(id uint) error {
+	// 条件更新保证同一流程只能完成一次，挑战值不会被重放
+	result := r.db.Model(&models.PasskeyCeremony{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
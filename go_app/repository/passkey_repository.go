@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"time"
+
+	"go_app/models"
+
+	"gorm.io/gorm"
+)
+
+// PasskeyRepository 通行密钥数据访问
+type PasskeyRepository interface {
+	// Create 保存新注册的通行密钥，凭证ID已存在时返回 ErrDuplicate
+	Create(passkey *models.Passkey) error
+	// FindByCredentialID 按凭证ID查找通行密钥
+	FindByCredentialID(credentialID string) (*models.Passkey, error)
+	// ListByUser 按注册时间列出用户的全部通行密钥
+	ListByUser(userID uint) ([]*models.Passkey, error)
+	// RecordUse 登录成功后更新签名计数器、备份状态和最近使用时间
+	RecordUse(id uint, signCount uint32, backupState bool, at time.Time) error
+	// Rename 修改通行密钥名称，不属于该用户时返回 ErrNotFound
+	Rename(userID, id uint, name string) error
+	// Delete 删除通行密钥，不属于该用户时返回 ErrNotFound
+	Delete(userID, id uint) error
+	// DeleteByUser 删除用户的全部通行密钥
+	DeleteByUser(userID uint) error
+}
+
+type gormPasskeyRepository struct {
+	db *gorm.DB
+}
+
+func NewPasskeyRepository(db *gorm.DB) PasskeyRepository {
+	return &gormPasskeyRepository{db: db}
+}
+
+func (r *gormPasskeyRepository) Create(passkey *models.Passkey) error {
+	return translate(r.db.Create(passkey).Error)
+}
+
+func (r *gormPasskeyRepository) FindByCredentialID(credentialID string) (*models.Passkey, error) {
+	var passkey models.Passkey
+	if err := r.db.Where("credential_id = ?", credentialID).First(&passkey).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &passkey, nil
+}
+
+func (r *gormPasskeyRepository) ListByUser(userID uint) ([]*models.Passkey, error) {
+	var passkeys []*models.Passkey
+	err := r.db.Where("user_id = ?", userID).Order("id").Find(&passkeys).Error
+	return passkeys, err
+}
+
+func (r *gormPasskeyRepository) RecordUse(id uint, signCount uint32, backupState bool, at time.Time) error {
+	result := r.db.Model(&models.Passkey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"sign_count":   signCount,
+		"backup_state": backupState,
+		"last_used_at": at,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormPasskeyRepository) Rename(userID, id uint, name string) error {
+	result := r.db.Model(&models.Passkey{}).Where("id = ? AND user_id = ?", id, userID).Update("name", name)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormPasskeyRepository) Delete(userID, id uint) error {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Passkey{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormPasskeyRepository) DeleteByUser(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.Passkey{}).Error
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_app/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PresenceRepository 在线状态存储：记录每个连接最近上报的状态，以及每个用户对外公布的状态。
+// 连接记录在 expiresAt 之后失效，由持有连接的实例定期续期；多实例部署时需使用共享存储
+type PresenceRepository interface {
+	// Touch 记录连接的状态，记录在 expiresAt 之后失效；已有记录时覆盖
+	Touch(userID uint, connectionID, status string, expiresAt time.Time) error
+	// Statuses 查询用户在 now 时未失效的连接状态，没有未失效连接的用户不出现在结果中
+	Statuses(userIDs []uint, now time.Time) (map[uint][]string, error)
+	// SwapStatus 设置对外公布的用户状态并返回之前的状态，之前未公布时返回 models.PresenceOffline。
+	// 多个实例同时设置相同的状态时只有一个实例看到状态变化
+	SwapStatus(userID uint, status string) (string, error)
+	// Published 对外公布的状态不是离线的用户
+	Published() ([]uint, error)
+}
+
+// Redis 中在线状态的键：每个用户的连接记录为一个哈希（连接ID → 状态:失效时间毫秒时间戳），
+// 公布的状态为一个字符串，未离线的用户记录在一个集合中
+const (
+	redisPresenceConnKeyPrefix   = "go_app:presence:conn:"
+	redisPresenceStatusKeyPrefix = "go_app:presence:status:"
+	redisPresencePublishedKey    = "go_app:presence:published"
+	// redisPresenceConnKeyTTL 连接记录哈希的有效期，每次续期时重置。
+	// 哈希中单条记录的失效按记录中的时间判断，这里只用于清理长时间没有连接的用户
+	redisPresenceConnKeyTTL = 24 * time.Hour
+)
+
+type redisPresenceRepository struct {
+	client redis.UniversalClient
+}
+
+// NewRedisPresenceRepository 基于 Redis 的在线状态存储，多个实例共享连接记录和公布的状态
+func NewRedisPresenceRepository(client redis.UniversalClient) PresenceRepository {
+	return &redisPresenceRepository{client: client}
+}
+
+func (r *redisPresenceRepository) Touch(userID uint, connectionID, status string, expiresAt time.Time) error {
+	ctx := context.Background()
+	key := redisPresenceConnKeyPrefix + strconv.FormatUint(uint64(userID), 10)
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, connectionID, status+":"+strconv.FormatInt(expiresAt.UnixMilli(), 10))
+		pipe.PExpire(ctx, key, redisPresenceConnKeyTTL)
+		return nil
+	})
+	return err
+}
+
+func (r *redisPresenceRepository) Statuses(userIDs []uint, now time.Time) (map[uint][]string, error) {
+	ctx := context.Background()
+	cmds := make([]*redis.MapStringStringCmd, len(userIDs))
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, userID := range userIDs {
+			cmds[i] = pipe.HGetAll(ctx, redisPresenceConnKeyPrefix+strconv.FormatUint(uint64(userID), 10))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[uint][]string)
+	for i, cmd := range cmds {
+		for _, value := range cmd.Val() {
+			status, expires, ok := strings.Cut(value, ":")
+			if !ok {
+				continue
+			}
+			ms, err := strconv.ParseInt(expires, 10, 64)
+			if err != nil || !time.UnixMilli(ms).After(now) {
+				continue
+			}
+			statuses[userIDs[i]] = append(statuses[userIDs[i]], status)
+		}
+	}
+	return statuses, nil
+}
+
+func (r *redisPresenceRepository) SwapStatus(userID uint, status string) (string, error) {
+	ctx := context.Background()
+	id := strconv.FormatUint(uint64(userID), 10)
+	key := redisPresenceStatusKeyPrefix + id
+	// 离线时删除记录，只保存未离线用户的状态
+	// SET ... GET 返回 StatusCmd，GETDEL 返回 StringCmd，两者的 Val 都是之前的值
+	var previous interface{ Val() string }
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if status == models.PresenceOffline {
+			previous = pipe.GetDel(ctx, key)
+			pipe.SRem(ctx, redisPresencePublishedKey, id)
+		} else {
+			previous = pipe.SetArgs(ctx, key, status, redis.SetArgs{Get: true})
+			pipe.SAdd(ctx, redisPresencePublishedKey, id)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return "", err
+	}
+	if previous.Val() == "" {
+		return models.PresenceOffline, nil
+	}
+	return previous.Val(), nil
+}
+
+func (r *redisPresenceRepository) Published() ([]uint, error) {
+	members, err := r.client.SMembers(context.Background(), redisPresencePublishedKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]uint, 0, len(members))
+	for _, member := range members {
+		if id, err := strconv.ParseUint(member, 10, 0); err == nil {
+			userIDs = append(userIDs, uint(id))
+		}
+	}
+	return userIDs, nil
+}
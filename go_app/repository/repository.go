@@ -0,0 +1,85 @@
+// Package repository 数据访问层。
+//
+// 每类数据提供一个接口及两种实现：基于 GORM 的数据库实现（NewXxxRepository）
+// 和基于内存的实现（NewMemoryXxxRepository）。服务层只依赖接口，
+// 单元测试可以直接使用内存实现而不需要数据库；repotest 包中的行为测试保证两种实现行为一致。
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound 记录不存在
+	ErrNotFound = errors.New("记录不存在")
+	// ErrDuplicate 违反唯一约束，如邮箱已被注册
+	ErrDuplicate = errors.New("记录已存在")
+	// ErrConflict 条件更新未命中，如刷新令牌已被使用
+	ErrConflict = errors.New("记录已被修改")
+)
+
+// translate 将 GORM 错误转换为仓储层错误
+func translate(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDuplicate
+	}
+	return err
+}
+
+// Page 分页参数，Limit <= 0 表示不分页
+type Page struct {
+	Offset int
+	Limit  int
+}
+
+func (p Page) apply(db *gorm.DB) *gorm.DB {
+	if p.Offset > 0 {
+		db = db.Offset(p.Offset)
+	}
+	if p.Limit > 0 {
+		db = db.Limit(p.Limit)
+	}
+	return db
+}
+
+// slice 对内存结果分页
+func slice[T any](items []T, p Page) []T {
+	if p.Offset >= len(items) {
+		return items[:0]
+	}
+	items = items[p.Offset:]
+	if p.Limit > 0 && p.Limit < len(items) {
+		items = items[:p.Limit]
+	}
+	return items
+}
+
+// checkFields 校验要更新的列均在允许范围内
+func checkFields(fields, allowed []string) error {
+	if len(fields) == 0 {
+		return errors.New("未指定要更新的字段")
+	}
+	for _, field := range fields {
+		if !contains(allowed, field) {
+			return fmt.Errorf("不允许更新字段 %q", field)
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,281 @@
+package repository_test
+
+import (
+	"testing"
+
+	"go_app/repository"
+	"go_app/repository/repotest"
+)
+
+func TestUserRepository(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestUserRepository(t, func(t *testing.T) repository.UserRepository {
+			return repository.NewMemoryUserRepository()
+		})
+	})
+	t.Run("GORM", func(t *testing.T) {
+		repotest.TestUserRepository(t, func(t *testing.T) repository.UserRepository {
+			return repository.NewUserRepository(repotest.OpenSQLite(t))
+		})
+	})
+}
+
+func TestRoleRepository(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestRoleRepository(t, func(t *testing.T) repository.RoleRepository {
+			return repository.NewMemoryRoleRepository()
+		})
+	})
+	t.Run("GORM", func(t *testing.T) {
+		repotest.TestRoleRepository(t, func(t *testing.T) repository.RoleRepository {
+			return repository.NewRoleRepository(repotest.OpenSQLite(t))
+		})
+	})
+}
+
+func TestSessionRepository(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestSessionRepository(t, func(t *testing.T) repository.SessionRepository {
+			return repository.NewMemorySessionRepository()
+		})
+	})
+	t.Run("GORM", func(t *testing.T) {
+		repotest.TestSessionRepository(t, func(t *testing.T) repository.SessionRepository {
+			return repository.NewSessionRepository(repotest.OpenSQLite(t))
+		})
+	})
+}
+
+func TestActivityRepository(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestActivityRepository(t, func(t *testing.T) repository.ActivityRepository {
+			return repository.NewMemoryActivityRepository()
+		})
+	})
+	t.Run("GORM", func(t *testing.T) {
+		repotest.TestActivityRepository(t, func(t *testing.T) repository.ActivityRepository {
+			return repository.NewActivityRepository(repotest.OpenSQLite(t))
+		})
+	})
+}
+
+func TestNotificationRepository(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestNotificationRepository(t, func(t *testing.T) repository.NotificationRepository {
+			return repository.NewMemoryNotificationRepository()
+		})
+	})
+	t.Run("GORM", func(t *testing.T) {
+		repotest.TestNotificationRepository(t, func(t *testing.T) repository.NotificationRepository {
+			return repository.NewNotificationRepository(repotest.OpenSQLite(t))
+		})
+	})
+}
+
+func TestEmailVerificationRepository(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestEmailVerificationRepository(t, func(t *testing.T) repository.EmailVerificationRepository {
+			return repository.NewMemoryEmailVerificationRepository()
+		})
+	})
+	t.Run("GORM", func(t *testing.T) {
+		repotest.TestEmailVerificationRepository(t, func(t *testing.T) repository.EmailVerificationRepository {
+			return repository.NewEmailVerificationRepository(repotest.OpenSQLite(t))
+		})
+	})
+}
+
+func TestPasswordResetRepository(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestPasswordResetRepository(t, func(t *testing.T) repository.PasswordResetRepository {
+			return repository.NewMemoryPasswordResetRepository()
+		})
+	})
+	t.Run("GORM", func(t *testing.T) {
+		repotest.TestPasswordResetRepository(t, func(t *testing.T) repository.PasswordResetRepository {
+			return repository.NewPasswordResetRepository(repotest.OpenSQLite(t))
+		})
+	})
+}
+
+func TestTwoFactorRepository(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestTwoFactorRepository(t, func(t *testing.T) repository.TwoFactorRepository {
+			return repository.NewMemoryTwoFactorRepository()
+		})
+	})
+	t.Run("GORM", func(t *testing.T) {
+		repotest.TestTwoFactorRepository(t, func(t *testing.T) repository.TwoFactorRepository {
+			return repository.NewTwoFactorRepository(repotest.OpenSQLite(t))
+		})
+	})
+}
+
+func TestTwoFactorChallengeRepository(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestTwoFactorChallengeRepository(t, func(t *testing.T) repository.TwoFactorChallengeRepository {
+			return repository.NewMemoryTwoFactorChallengeRepository()
+		})
+	})
+	t.Run("GORM", func(t *testing.T) {
+		repotest.TestTwoFactorChallengeRepository(t, func(t *testing.T) repository.TwoFactorChallengeRepository {
+			return repository.NewTwoFactorChallengeRepository(repotest.OpenSQLite(t))
+		})
+	})
+}
+
+func TestPasskeyRepository(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestPasskeyRepository(t, func(t *testing.T) repository.PasskeyRepository {
+			return repository.NewMemoryPasskeyRepository()
+		})
+	})
+	t.Run("GORM", func(t *testing.T) {
+		repotest.TestPasskeyRepository(t, func(t *testing.T) repository.PasskeyRepository {
+			return repository.NewPasskeyRepository(repotest.OpenSQLite(t))
+		})
+	})
+}
+
+func TestPasskeyCeremonyRepository(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestPasskeyCeremonyRepository(t, func(t *testing.T) repository.PasskeyCeremonyRepository {
+			return repository.NewMemoryPasskeyCeremonyRepository()
+		})
+	})
+	t.Run("GORM", func(t *testing.T) {
+		repotest.TestPasskeyCeremonyRepository(t, func(t *testing.T) repository.PasskeyCeremonyRepository {
+			return repository.NewPasskeyCeremonyRepository(repotest.OpenSQLite(t))
+		})
+	})
+}
+
+func TestLoginAttemptRepository(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestLoginAttemptRepository(t, func(t *testing.T) repository.LoginAttemptRepository {
+			return repository.NewMemoryLoginAttemptRepository()
+		})
+	})
+	t.Run("Redis", func(t *testing.T) {
+		repotest.TestLoginAttemptRepository(t, func(t *testing.T) repository.LoginAttemptRepository {
+			return repository.NewRedisLoginAttemptRepository(repotest.OpenRedis(t))
+		})
+	})
+}
+
+func TestAPIKeyRepository(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestAPIKeyRepository(t, func(t *testing.T) repository.APIKeyRepository {
+			return repository.NewMemoryAPIKeyRepository()
+		})
+	})
+	t.Run("GORM", func(t *testing.T) {
+		repotest.TestAPIKeyRepository(t, func(t *testing.T) repository.APIKeyRepository {
+			return repository.NewAPIKeyRepository(repotest.OpenSQLite(t))
+		})
+	})
+}
+
+func TestUserIdentityRepository(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestUserIdentityRepository(t, func(t *testing.T) repository.UserIdentityRepository {
+			return repository.NewMemoryUserIdentityRepository()
+		})
+	})
+	t.Run("GORM", func(t *testing.T) {
+		repotest.TestUserIdentityRepository(t, func(t *testing.T) repository.UserIdentityRepository {
+			return repository.NewUserIdentityRepository(repotest.OpenSQLite(t))
+		})
+	})
+}
+
+func TestOIDCFlowRepository(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestOIDCFlowRepository(t, func(t *testing.T) repository.OIDCFlowRepository {
+			return repository.NewMemoryOIDCFlowRepository()
+		})
+	})
+	t.Run("GORM", func(t *testing.T) {
+		repotest.TestOIDCFlowRepository(t, func(t *testing.T) repository.OIDCFlowRepository {
+			return repository.NewOIDCFlowRepository(repotest.OpenSQLite(t))
+		})
+	})
+}
+
+func TestOAuthClientRepository(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestOAuthClientRepository(t, func(t *testing.T) repository.OAuthClientRepository {
+			return repository.NewMemoryOAuthClientRepository()
+		})
+	})
+	t.Run("GORM", func(t *testing.T) {
+		repotest.TestOAuthClientRepository(t, func(t *testing.T) repository.OAuthClientRepository {
+			return repository.NewOAuthClientRepository(repotest.OpenSQLite(t))
+		})
+	})
+}
+
+func TestOAuthConsentRepository(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestOAuthConsentRepository(t, func(t *testing.T) repository.OAuthConsentRepository {
+			return repository.NewMemoryOAuthConsentRepository()
+		})
+	})
+	t.Run("GORM", func(t *testing.T) {
+		repotest.TestOAuthConsentRepository(t, func(t *testing.T) repository.OAuthConsentRepository {
+			return repository.NewOAuthConsentRepository(repotest.OpenSQLite(t))
+		})
+	})
+}
+
+func TestOAuthCodeRepository(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestOAuthCodeRepository(t, func(t *testing.T) repository.OAuthCodeRepository {
+			return repository.NewMemoryOAuthCodeRepository()
+		})
+	})
+	t.Run("GORM", func(t *testing.T) {
+		repotest.TestOAuthCodeRepository(t, func(t *testing.T) repository.OAuthCodeRepository {
+			return repository.NewOAuthCodeRepository(repotest.OpenSQLite(t))
+		})
+	})
+}
+
+func TestOAuthTokenRepository(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestOAuthTokenRepository(t, func(t *testing.T) repository.OAuthTokenRepository {
+			return repository.NewMemoryOAuthTokenRepository()
+		})
+	})
+	t.Run("GORM", func(t *testing.T) {
+		repotest.TestOAuthTokenRepository(t, func(t *testing.T) repository.OAuthTokenRepository {
+			return repository.NewOAuthTokenRepository(repotest.OpenSQLite(t))
+		})
+	})
+}
+
+func TestWebSocketOutboxRepository(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestWebSocketOutboxRepository(t, func(t *testing.T) repository.WebSocketOutboxRepository {
+			return repository.NewMemoryWebSocketOutboxRepository()
+		})
+	})
+	t.Run("GORM", func(t *testing.T) {
+		repotest.TestWebSocketOutboxRepository(t, func(t *testing.T) repository.WebSocketOutboxRepository {
+			return repository.NewWebSocketOutboxRepository(repotest.OpenSQLite(t))
+		})
+	})
+}
+
+func TestPresenceRepository(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		repotest.TestPresenceRepository(t, func(t *testing.T) repository.PresenceRepository {
+			return repository.NewMemoryPresenceRepository()
+		})
+	})
+	t.Run("Redis", func(t *testing.T) {
+		repotest.TestPresenceRepository(t, func(t *testing.T) repository.PresenceRepository {
+			return repository.NewRedisPresenceRepository(repotest.OpenRedis(t))
+		})
+	})
+}
@@ -0,0 +1,128 @@
+package repotest
+
+import (
+	"testing"
+	"time"
+
+	"go_app/models"
+	"go_app/repository"
+)
+
+// TestActivityRepository 活动仓储行为测试，newRepo 每次返回一个空的仓储
+func TestActivityRepository(t *testing.T, newRepo func(t *testing.T) repository.ActivityRepository) {
+	base := time.Now().Truncate(time.Second)
+	create := func(t *testing.T, repo repository.ActivityRepository, title string, start, end time.Duration) *models.Activity {
+		t.Helper()
+		a := &models.Activity{Title: title, StartTime: base.Add(start), EndTime: base.Add(end)}
+		must(t, repo.Create(a))
+		return a
+	}
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		repo := newRepo(t)
+		a := create(t, repo, "launch", 0, time.Hour)
+		if a.ID == 0 || a.CreatedAt.IsZero() {
+			t.Fatalf("Create 未填充 ID 和创建时间: %+v", a)
+		}
+		found, err := repo.FindByID(a.ID)
+		must(t, err)
+		if found.Title != "launch" || !found.StartTime.Equal(a.StartTime) {
+			t.Fatalf("FindByID = %+v", found)
+		}
+		_, err = repo.FindByID(999)
+		expectErr(t, err, repository.ErrNotFound)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		repo := newRepo(t)
+		early := create(t, repo, "early", -2*time.Hour, -time.Hour)
+		late := create(t, repo, "late", time.Hour, 2*time.Hour)
+		middle := create(t, repo, "middle", 0, time.Hour)
+
+		list, total, err := repo.List(repository.Page{})
+		must(t, err)
+		if total != 3 || len(list) != 3 || list[0].ID != late.ID || list[1].ID != middle.ID || list[2].ID != early.ID {
+			t.Fatalf("List 应按开始时间倒序返回: %+v", list)
+		}
+
+		list, total, err = repo.List(repository.Page{Offset: 1, Limit: 1})
+		must(t, err)
+		if total != 3 || len(list) != 1 || list[0].ID != middle.ID {
+			t.Fatalf("List 分页 = %+v（总数 %d）", list, total)
+		}
+	})
+
+	t.Run("ListOngoing", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, "past", -2*time.Hour, -time.Hour)
+		b := create(t, repo, "b", -30*time.Minute, time.Hour)
+		a := create(t, repo, "a", -time.Hour, 30*time.Minute)
+		create(t, repo, "future", time.Hour, 2*time.Hour)
+
+		list, err := repo.ListOngoing(base)
+		must(t, err)
+		if len(list) != 2 || list[0].ID != a.ID || list[1].ID != b.ID {
+			t.Fatalf("ListOngoing 应按开始时间升序返回进行中的活动: %+v", list)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repo := newRepo(t)
+		a := create(t, repo, "draft", 0, time.Hour)
+
+		a.Title = "final"
+		a.Description = ""
+		a.EndTime = base.Add(3 * time.Hour)
+		must(t, repo.Update(a))
+
+		found, err := repo.FindByID(a.ID)
+		must(t, err)
+		if found.Title != "final" || !found.EndTime.Equal(a.EndTime) {
+			t.Fatalf("Update 未生效: %+v", found)
+		}
+
+		missing := &models.Activity{ID: 999, Title: "missing"}
+		expectErr(t, repo.Update(missing), repository.ErrNotFound)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo := newRepo(t)
+		a := create(t, repo, "a", 0, time.Hour)
+		must(t, repo.Delete(a.ID))
+		_, err := repo.FindByID(a.ID)
+		expectErr(t, err, repository.ErrNotFound)
+		expectErr(t, repo.Delete(a.ID), repository.ErrNotFound)
+	})
+
+	t.Run("Participants", func(t *testing.T) {
+		repo := newRepo(t)
+		a := create(t, repo, "a", 0, time.Hour)
+		b := create(t, repo, "b", 0, time.Hour)
+		isParticipant := func(activityID, userID uint) bool {
+			t.Helper()
+			ok, err := repo.IsParticipant(activityID, userID)
+			must(t, err)
+			return ok
+		}
+
+		must(t, repo.AddParticipant(a.ID, 1))
+		expectErr(t, repo.AddParticipant(a.ID, 1), repository.ErrDuplicate)
+		expectErr(t, repo.AddParticipant(999, 1), repository.ErrNotFound)
+		must(t, repo.AddParticipant(b.ID, 2))
+		if !isParticipant(a.ID, 1) || isParticipant(a.ID, 2) || isParticipant(b.ID, 1) {
+			t.Fatal("IsParticipant 应按活动和用户判断")
+		}
+
+		must(t, repo.RemoveParticipant(a.ID, 1))
+		expectErr(t, repo.RemoveParticipant(a.ID, 1), repository.ErrNotFound)
+		if isParticipant(a.ID, 1) {
+			t.Fatal("RemoveParticipant 后仍是参与者")
+		}
+
+		// 删除活动时同时删除参与者
+		must(t, repo.Delete(b.ID))
+		if isParticipant(b.ID, 2) {
+			t.Fatal("删除活动后仍是参与者")
+		}
+	})
+}
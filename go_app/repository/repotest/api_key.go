@@ -0,0 +1,149 @@
+package repotest
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go_app/models"
+	"go_app/repository"
+)
+
+// TestAPIKeyRepository API Key 仓储行为测试，newRepo 每次返回一个空的仓储
+func TestAPIKeyRepository(t *testing.T, newRepo func(t *testing.T) repository.APIKeyRepository) {
+	create := func(t *testing.T, repo repository.APIKeyRepository, userID uint, hash string) *models.APIKey {
+		t.Helper()
+		key := &models.APIKey{
+			UserID:  userID,
+			Name:    hash,
+			Prefix:  "gapp_" + hash,
+			KeyHash: hash,
+			Scopes:  "read,users:list",
+		}
+		must(t, repo.Create(key, 10))
+		return key
+	}
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		repo := newRepo(t)
+		expires := time.Now().Add(time.Hour).Truncate(time.Second)
+		key := &models.APIKey{UserID: 1, Name: "ci", Prefix: "gapp_1234", KeyHash: "hash-1", Scopes: "write", ExpiresAt: &expires}
+		must(t, repo.Create(key, 10))
+		if key.ID == 0 || key.CreatedAt.IsZero() {
+			t.Fatalf("Create 未填充 ID 和创建时间: %+v", key)
+		}
+		found, err := repo.FindByHash("hash-1")
+		must(t, err)
+		if found.ID != key.ID || found.UserID != 1 || found.Name != "ci" || found.Prefix != "gapp_1234" || found.Scopes != "write" ||
+			found.ExpiresAt == nil || !found.ExpiresAt.Equal(expires) || found.LastUsedAt != nil {
+			t.Fatalf("FindByHash = %+v", found)
+		}
+		_, err = repo.FindByHash("missing")
+		expectErr(t, err, repository.ErrNotFound)
+
+		// 哈希值全局唯一
+		expectErr(t, repo.Create(&models.APIKey{UserID: 2, Name: "x", Prefix: "gapp_x", KeyHash: "hash-1", Scopes: "read"}, 10), repository.ErrDuplicate)
+	})
+
+	t.Run("ListAndCount", func(t *testing.T) {
+		repo := newRepo(t)
+		first := create(t, repo, 1, "a")
+		create(t, repo, 2, "b")
+		second := create(t, repo, 1, "c")
+
+		list, err := repo.ListByUser(1)
+		must(t, err)
+		if len(list) != 2 || list[0].ID != first.ID || list[1].ID != second.ID {
+			t.Fatalf("ListByUser = %+v", list)
+		}
+		count, err := repo.CountByUser(1)
+		must(t, err)
+		if count != 2 {
+			t.Fatalf("CountByUser = %d, want 2", count)
+		}
+		count, err = repo.CountByUser(3)
+		must(t, err)
+		if count != 0 {
+			t.Fatalf("没有 API Key 的用户 CountByUser = %d", count)
+		}
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		repo := newRepo(t)
+		for _, hash := range []string{"a", "b"} {
+			must(t, repo.Create(&models.APIKey{UserID: 1, Name: hash, Prefix: "gapp_" + hash, KeyHash: hash, Scopes: "read"}, 2))
+		}
+		expectErr(t, repo.Create(&models.APIKey{UserID: 1, Name: "c", Prefix: "gapp_c", KeyHash: "c", Scopes: "read"}, 2), repository.ErrConflict)
+		// 上限按用户计算
+		must(t, repo.Create(&models.APIKey{UserID: 2, Name: "d", Prefix: "gapp_d", KeyHash: "d", Scopes: "read"}, 2))
+		count, err := repo.CountByUser(1)
+		must(t, err)
+		if count != 2 {
+			t.Fatalf("CountByUser = %d, want 2", count)
+		}
+	})
+
+	t.Run("LimitConcurrent", func(t *testing.T) {
+		repo := newRepo(t)
+		// 并发创建的数量不超过上限
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			created int
+		)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				hash := "hash-" + strconv.Itoa(i)
+				if err := repo.Create(&models.APIKey{UserID: 1, Name: hash, Prefix: "gapp_" + hash, KeyHash: hash, Scopes: "read"}, 3); err == nil {
+					mu.Lock()
+					created++
+					mu.Unlock()
+				}
+			}(i)
+		}
+		wg.Wait()
+		if created != 3 {
+			t.Fatalf("并发创建成功 %d 次, want 3", created)
+		}
+	})
+
+	t.Run("Touch", func(t *testing.T) {
+		repo := newRepo(t)
+		key := create(t, repo, 1, "hash")
+		at := time.Now().Truncate(time.Second)
+		must(t, repo.Touch(key.ID, at, "203.0.113.10"))
+
+		found, err := repo.FindByHash("hash")
+		must(t, err)
+		if found.LastUsedAt == nil || !found.LastUsedAt.Equal(at) || found.LastUsedIP != "203.0.113.10" {
+			t.Fatalf("Touch 后 = %+v", found)
+		}
+		expectErr(t, repo.Touch(key.ID+100, at, ""), repository.ErrNotFound)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo := newRepo(t)
+		key := create(t, repo, 1, "a")
+		create(t, repo, 1, "b")
+		create(t, repo, 2, "c")
+
+		// 不能删除其他用户的 API Key
+		expectErr(t, repo.Delete(2, key.ID), repository.ErrNotFound)
+		must(t, repo.Delete(1, key.ID))
+		expectErr(t, repo.Delete(1, key.ID), repository.ErrNotFound)
+		_, err := repo.FindByHash("a")
+		expectErr(t, err, repository.ErrNotFound)
+
+		must(t, repo.DeleteByUser(1))
+		list, err := repo.ListByUser(1)
+		must(t, err)
+		if len(list) != 0 {
+			t.Fatalf("DeleteByUser 后 ListByUser = %+v", list)
+		}
+		_, err = repo.FindByHash("c")
+		must(t, err)
+	})
+}
@@ -0,0 +1,117 @@
+package repotest
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go_app/models"
+	"go_app/repository"
+)
+
+// TestEmailVerificationRepository 邮箱验证令牌仓储行为测试，newRepo 每次返回一个空的仓储
+func TestEmailVerificationRepository(t *testing.T, newRepo func(t *testing.T) repository.EmailVerificationRepository) {
+	create := func(t *testing.T, repo repository.EmailVerificationRepository, userID uint, email, hash string) *models.EmailVerificationToken {
+		t.Helper()
+		token := &models.EmailVerificationToken{UserID: userID, Email: email, TokenHash: hash, ExpiredAt: time.Now().Add(time.Hour)}
+		must(t, repo.Create(token))
+		return token
+	}
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		repo := newRepo(t)
+		token := create(t, repo, 1, "a@example.com", "hash-1")
+		if token.ID == 0 || token.CreatedAt.IsZero() {
+			t.Fatalf("Create 未填充 ID 和创建时间: %+v", token)
+		}
+		found, err := repo.FindByHash("hash-1")
+		must(t, err)
+		if found.ID != token.ID || found.Email != "a@example.com" || found.IsUsed() {
+			t.Fatalf("FindByHash = %+v", found)
+		}
+		_, err = repo.FindByHash("missing")
+		expectErr(t, err, repository.ErrNotFound)
+	})
+
+	t.Run("CreateReplacesPrevious", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, 1, "a@example.com", "old")
+		create(t, repo, 2, "b@example.com", "other")
+		create(t, repo, 1, "a2@example.com", "new")
+
+		_, err := repo.FindByHash("old")
+		expectErr(t, err, repository.ErrNotFound)
+		_, err = repo.FindByHash("new")
+		must(t, err)
+		_, err = repo.FindByHash("other")
+		must(t, err)
+	})
+
+	t.Run("CreateUnlessRecent", func(t *testing.T) {
+		repo := newRepo(t)
+		since := time.Now().Add(-time.Minute)
+		first := &models.EmailVerificationToken{UserID: 1, Email: "a@example.com", TokenHash: "first", ExpiredAt: time.Now().Add(time.Hour)}
+		must(t, repo.CreateUnlessRecent(first, since))
+
+		// 冷却时间内已有有效的令牌，不创建也不替换，发送到其他邮箱也一样
+		second := &models.EmailVerificationToken{UserID: 1, Email: "a2@example.com", TokenHash: "second", ExpiredAt: time.Now().Add(time.Hour)}
+		expectErr(t, repo.CreateUnlessRecent(second, since), repository.ErrConflict)
+		_, err := repo.FindByHash("first")
+		must(t, err)
+		_, err = repo.FindByHash("second")
+		expectErr(t, err, repository.ErrNotFound)
+
+		// 其他用户不受影响
+		must(t, repo.CreateUnlessRecent(&models.EmailVerificationToken{UserID: 2, Email: "b@example.com", TokenHash: "other", ExpiredAt: time.Now().Add(time.Hour)}, since))
+
+		// 超过冷却时间后替换之前的令牌
+		must(t, repo.CreateUnlessRecent(second, time.Now()))
+		_, err = repo.FindByHash("first")
+		expectErr(t, err, repository.ErrNotFound)
+
+		// 已使用或已过期的令牌不影响重新发送
+		must(t, repo.Consume(second.ID))
+		must(t, repo.CreateUnlessRecent(&models.EmailVerificationToken{UserID: 1, Email: "a@example.com", TokenHash: "third", ExpiredAt: time.Now().Add(-time.Second)}, since))
+		must(t, repo.CreateUnlessRecent(&models.EmailVerificationToken{UserID: 1, Email: "a@example.com", TokenHash: "fourth", ExpiredAt: time.Now().Add(time.Hour)}, since))
+		_, err = repo.FindByHash("fourth")
+		must(t, err)
+	})
+
+	t.Run("CreateUnlessRecentConcurrent", func(t *testing.T) {
+		repo := newRepo(t)
+		// 并发的发送只有一个创建成功
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			created int
+		)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				token := &models.EmailVerificationToken{UserID: 1, Email: "a@example.com", TokenHash: "hash-" + strconv.Itoa(i), ExpiredAt: time.Now().Add(time.Hour)}
+				if err := repo.CreateUnlessRecent(token, time.Now().Add(-time.Minute)); err == nil {
+					mu.Lock()
+					created++
+					mu.Unlock()
+				}
+			}(i)
+		}
+		wg.Wait()
+		if created != 1 {
+			t.Fatalf("并发发送成功 %d 次, want 1", created)
+		}
+	})
+
+	t.Run("ConsumeAndDelete", func(t *testing.T) {
+		repo := newRepo(t)
+		token := create(t, repo, 1, "a@example.com", "hash")
+		must(t, repo.Consume(token.ID))
+		expectErr(t, repo.Consume(token.ID), repository.ErrConflict)
+
+		must(t, repo.DeleteByUser(1))
+		_, err := repo.FindByHash("hash")
+		expectErr(t, err, repository.ErrNotFound)
+	})
+}
@@ -0,0 +1,161 @@
+package repotest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"go_app/repository"
+)
+
+// TestLoginAttemptRepository 登录失败记录存储行为测试，newRepo 每次返回一个空的存储
+func TestLoginAttemptRepository(t *testing.T, newRepo func(t *testing.T) repository.LoginAttemptRepository) {
+	// 存储按毫秒保存时间
+	now := time.Now().Truncate(time.Millisecond)
+
+	t.Run("Empty", func(t *testing.T) {
+		repo := newRepo(t)
+		attempt, err := repo.Get("account:missing")
+		must(t, err)
+		if attempt.Failures != 0 || !attempt.LastFailedAt.IsZero() || attempt.IsLocked(now) {
+			t.Fatalf("Get = %+v, want 零值", attempt)
+		}
+	})
+
+	t.Run("Reserve", func(t *testing.T) {
+		repo := newRepo(t)
+		for i := 1; i <= 3; i++ {
+			at := now.Add(time.Duration(i) * time.Second)
+			attempt, err := repo.Reserve("account:a", i-1, at, time.Hour)
+			must(t, err)
+			if attempt.Failures != i || !attempt.LastFailedAt.Equal(at) {
+				t.Fatalf("第 %d 次 Reserve = %+v", i, attempt)
+			}
+		}
+		attempt, err := repo.Get("account:a")
+		must(t, err)
+		if attempt.Failures != 3 || !attempt.LastFailedAt.Equal(now.Add(3*time.Second)) {
+			t.Fatalf("Get = %+v", attempt)
+		}
+
+		// 失败次数已被修改时不占用
+		_, err = repo.Reserve("account:a", 2, now, time.Hour)
+		expectErr(t, err, repository.ErrConflict)
+		_, err = repo.Reserve("account:missing", 1, now, time.Hour)
+		expectErr(t, err, repository.ErrConflict)
+
+		// 不同 key 独立计数
+		attempt, err = repo.Reserve("ip:192.0.2.1", 0, now, time.Hour)
+		must(t, err)
+		if attempt.Failures != 1 {
+			t.Fatalf("Reserve(ip) = %+v", attempt)
+		}
+	})
+
+	t.Run("ReserveConcurrent", func(t *testing.T) {
+		repo := newRepo(t)
+		// 基于同一次读取的并发占用只有一个成功
+		var (
+			wg       sync.WaitGroup
+			mu       sync.Mutex
+			reserved int
+		)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := repo.Reserve("account:a", 0, now, time.Hour); err == nil {
+					mu.Lock()
+					reserved++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		attempt, err := repo.Get("account:a")
+		must(t, err)
+		if reserved != 1 || attempt.Failures != 1 {
+			t.Fatalf("并发占用成功 %d 次，Failures = %d, want 1", reserved, attempt.Failures)
+		}
+	})
+
+	t.Run("Release", func(t *testing.T) {
+		repo := newRepo(t)
+		_, err := repo.Reserve("ip:192.0.2.1", 0, now, time.Hour)
+		must(t, err)
+		_, err = repo.Reserve("ip:192.0.2.1", 1, now, time.Hour)
+		must(t, err)
+		must(t, repo.Release("ip:192.0.2.1"))
+		attempt, err := repo.Get("ip:192.0.2.1")
+		must(t, err)
+		if attempt.Failures != 1 {
+			t.Fatalf("Release 后 Get = %+v", attempt)
+		}
+
+		// 次数为零或记录不存在时忽略
+		must(t, repo.Release("ip:192.0.2.1"))
+		must(t, repo.Release("ip:192.0.2.1"))
+		must(t, repo.Release("ip:missing"))
+		attempt, err = repo.Get("ip:192.0.2.1")
+		must(t, err)
+		if attempt.Failures != 0 {
+			t.Fatalf("Release 后 Get = %+v", attempt)
+		}
+	})
+
+	t.Run("Lock", func(t *testing.T) {
+		repo := newRepo(t)
+		_, err := repo.Reserve("account:a", 0, now, time.Hour)
+		must(t, err)
+		until := now.Add(15 * time.Minute)
+		must(t, repo.Lock("account:a", until, time.Hour))
+
+		attempt, err := repo.Get("account:a")
+		must(t, err)
+		if attempt.Failures != 1 || !attempt.LockedUntil.Equal(until) || !attempt.IsLocked(now) || attempt.IsLocked(until) {
+			t.Fatalf("Get = %+v", attempt)
+		}
+
+		// 继续占用不影响锁定时间
+		attempt, err = repo.Reserve("account:a", 1, now, time.Hour)
+		must(t, err)
+		if attempt.Failures != 2 || !attempt.LockedUntil.Equal(until) {
+			t.Fatalf("Reserve = %+v", attempt)
+		}
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		repo := newRepo(t)
+		_, err := repo.Reserve("account:a", 0, now, time.Hour)
+		must(t, err)
+		must(t, repo.Lock("account:a", now.Add(time.Hour), time.Hour))
+		_, err = repo.Reserve("account:b", 0, now, time.Hour)
+		must(t, err)
+
+		must(t, repo.Reset("account:a"))
+		attempt, err := repo.Get("account:a")
+		must(t, err)
+		if attempt.Failures != 0 || attempt.IsLocked(now) {
+			t.Fatalf("Reset 后 Get = %+v", attempt)
+		}
+		attempt, err = repo.Get("account:b")
+		must(t, err)
+		if attempt.Failures != 1 {
+			t.Fatalf("Reset 不应影响其他 key，Get = %+v", attempt)
+		}
+		// 删除不存在的记录不报错
+		must(t, repo.Reset("account:missing"))
+	})
+
+	t.Run("Expire", func(t *testing.T) {
+		repo := newRepo(t)
+		_, err := repo.Reserve("account:a", 0, now, 50*time.Millisecond)
+		must(t, err)
+		time.Sleep(100 * time.Millisecond)
+		attempt, err := repo.Get("account:a")
+		must(t, err)
+		if attempt.Failures != 0 {
+			t.Fatalf("超过有效期后 Get = %+v, want 零值", attempt)
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package repotest
+
+import (
+	"testing"
+
+	"go_app/models"
+	"go_app/repository"
+)
+
+// TestNotificationRepository 通知仓储行为测试，newRepo 每次返回一个空的仓储
+func TestNotificationRepository(t *testing.T, newRepo func(t *testing.T) repository.NotificationRepository) {
+	create := func(t *testing.T, repo repository.NotificationRepository, userID uint, title string) *models.Notification {
+		t.Helper()
+		n := &models.Notification{UserID: userID, Title: title, Content: title + " content"}
+		must(t, repo.Create(n))
+		return n
+	}
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		repo := newRepo(t)
+		n := create(t, repo, 1, "hello")
+		if n.ID == 0 || n.CreatedAt.IsZero() {
+			t.Fatalf("Create 未填充 ID 和创建时间: %+v", n)
+		}
+		found, err := repo.FindByID(n.ID)
+		must(t, err)
+		if found.Title != "hello" || found.Read {
+			t.Fatalf("FindByID = %+v", found)
+		}
+		_, err = repo.FindByID(999)
+		expectErr(t, err, repository.ErrNotFound)
+	})
+
+	t.Run("ListByUser", func(t *testing.T) {
+		repo := newRepo(t)
+		first := create(t, repo, 1, "first")
+		second := create(t, repo, 1, "second")
+		third := create(t, repo, 1, "third")
+		create(t, repo, 2, "other")
+		must(t, repo.MarkRead(1, second.ID))
+
+		list, total, err := repo.ListByUser(1, false, repository.Page{})
+		must(t, err)
+		if total != 3 || len(list) != 3 || list[0].ID != third.ID || list[2].ID != first.ID {
+			t.Fatalf("ListByUser 应按创建时间倒序返回该用户的通知: %+v", list)
+		}
+
+		list, total, err = repo.ListByUser(1, true, repository.Page{Limit: 1})
+		must(t, err)
+		if total != 2 || len(list) != 1 || list[0].ID != third.ID {
+			t.Fatalf("ListByUser(unreadOnly) = %+v（总数 %d）", list, total)
+		}
+
+		list, _, err = repo.ListByUser(1, true, repository.Page{Offset: 1, Limit: 1})
+		must(t, err)
+		if len(list) != 1 || list[0].ID != first.ID {
+			t.Fatalf("ListByUser 第二页 = %+v", list)
+		}
+	})
+
+	t.Run("MarkRead", func(t *testing.T) {
+		repo := newRepo(t)
+		a := create(t, repo, 1, "a")
+		create(t, repo, 1, "b")
+		other := create(t, repo, 2, "other")
+
+		expectErr(t, repo.MarkRead(1, other.ID), repository.ErrNotFound)
+		expectErr(t, repo.MarkRead(1, 999), repository.ErrNotFound)
+
+		must(t, repo.MarkRead(1, a.ID))
+		// 重复标记已读不应报错
+		must(t, repo.MarkRead(1, a.ID))
+
+		unread, err := repo.CountUnread(1)
+		must(t, err)
+		if unread != 1 {
+			t.Fatalf("CountUnread = %d, want 1", unread)
+		}
+
+		marked, err := repo.MarkAllRead(1)
+		must(t, err)
+		if marked != 1 {
+			t.Fatalf("MarkAllRead = %d, want 1", marked)
+		}
+		unread, err = repo.CountUnread(1)
+		must(t, err)
+		if unread != 0 {
+			t.Fatalf("CountUnread = %d, want 0", unread)
+		}
+		unread, err = repo.CountUnread(2)
+		must(t, err)
+		if unread != 1 {
+			t.Fatal("MarkAllRead 不应影响其他用户的通知")
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo := newRepo(t)
+		n := create(t, repo, 1, "a")
+		expectErr(t, repo.Delete(2, n.ID), repository.ErrNotFound)
+		must(t, repo.Delete(1, n.ID))
+		_, err := repo.FindByID(n.ID)
+		expectErr(t, err, repository.ErrNotFound)
+		expectErr(t, repo.Delete(1, n.ID), repository.ErrNotFound)
+	})
+}
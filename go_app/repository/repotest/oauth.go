@@ -0,0 +1,301 @@
+package repotest
+
+import (
+	"testing"
+	"time"
+
+	"go_app/models"
+	"go_app/repository"
+)
+
+// TestOAuthClientRepository OAuth 客户端仓储行为测试，newRepo 每次返回一个空的仓储
+func TestOAuthClientRepository(t *testing.T, newRepo func(t *testing.T) repository.OAuthClientRepository) {
+	create := func(t *testing.T, repo repository.OAuthClientRepository, clientID string) *models.OAuthClient {
+		t.Helper()
+		client := &models.OAuthClient{
+			ClientID:     clientID,
+			SecretHash:   "hash-" + clientID,
+			Name:         "App " + clientID,
+			RedirectURIs: "https://app.example.com/callback http://localhost:3000/callback",
+			Scopes:       "openid profile",
+			GrantTypes:   "authorization_code refresh_token",
+		}
+		must(t, repo.Create(client))
+		return client
+	}
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		repo := newRepo(t)
+		client := create(t, repo, "app-1")
+		if client.ID == 0 || client.CreatedAt.IsZero() {
+			t.Fatalf("Create 未填充 ID 和创建时间: %+v", client)
+		}
+		found, err := repo.FindByClientID("app-1")
+		must(t, err)
+		if found.ID != client.ID || found.Name != "App app-1" || !found.AllowsRedirectURI("http://localhost:3000/callback") ||
+			!found.AllowsScope("profile") || !found.AllowsGrant("refresh_token") || found.IsPublic() {
+			t.Fatalf("FindByClientID = %+v", found)
+		}
+		_, err = repo.FindByClientID("missing")
+		expectErr(t, err, repository.ErrNotFound)
+		expectErr(t, repo.Create(&models.OAuthClient{ClientID: "app-1", Name: "x"}), repository.ErrDuplicate)
+	})
+
+	t.Run("ListAndDelete", func(t *testing.T) {
+		repo := newRepo(t)
+		first := create(t, repo, "app-1")
+		second := create(t, repo, "app-2")
+
+		list, err := repo.List()
+		must(t, err)
+		if len(list) != 2 || list[0].ID != first.ID || list[1].ID != second.ID {
+			t.Fatalf("List = %+v", list)
+		}
+
+		must(t, repo.Delete("app-1"))
+		expectErr(t, repo.Delete("app-1"), repository.ErrNotFound)
+		list, err = repo.List()
+		must(t, err)
+		if len(list) != 1 || list[0].ID != second.ID {
+			t.Fatalf("Delete 后 List = %+v", list)
+		}
+	})
+}
+
+// TestOAuthConsentRepository 授权同意记录仓储行为测试，newRepo 每次返回一个空的仓储
+func TestOAuthConsentRepository(t *testing.T, newRepo func(t *testing.T) repository.OAuthConsentRepository) {
+	t.Run("SaveAndFind", func(t *testing.T) {
+		repo := newRepo(t)
+		consent := &models.OAuthConsent{UserID: 1, ClientID: "app", Scopes: "openid"}
+		must(t, repo.Save(consent))
+		if consent.ID == 0 || consent.CreatedAt.IsZero() {
+			t.Fatalf("Save 未填充 ID 和创建时间: %+v", consent)
+		}
+
+		// 再次保存更新授权范围，记录不变
+		updated := &models.OAuthConsent{UserID: 1, ClientID: "app", Scopes: "openid email"}
+		must(t, repo.Save(updated))
+		if updated.ID != consent.ID {
+			t.Fatalf("再次 Save 的 ID = %d，want %d", updated.ID, consent.ID)
+		}
+		found, err := repo.Find(1, "app")
+		must(t, err)
+		if found.ID != consent.ID || !found.Covers([]string{"email", "openid"}) || found.Covers([]string{"profile"}) {
+			t.Fatalf("Find = %+v", found)
+		}
+		_, err = repo.Find(2, "app")
+		expectErr(t, err, repository.ErrNotFound)
+	})
+
+	t.Run("ListAndDelete", func(t *testing.T) {
+		repo := newRepo(t)
+		must(t, repo.Save(&models.OAuthConsent{UserID: 1, ClientID: "a", Scopes: "openid"}))
+		must(t, repo.Save(&models.OAuthConsent{UserID: 2, ClientID: "a", Scopes: "openid"}))
+		must(t, repo.Save(&models.OAuthConsent{UserID: 1, ClientID: "b", Scopes: "openid"}))
+
+		list, err := repo.ListByUser(1)
+		must(t, err)
+		if len(list) != 2 || list[0].ClientID != "a" || list[1].ClientID != "b" {
+			t.Fatalf("ListByUser = %+v", list)
+		}
+
+		expectErr(t, repo.Delete(3, "a"), repository.ErrNotFound)
+		must(t, repo.Delete(1, "b"))
+		expectErr(t, repo.Delete(1, "b"), repository.ErrNotFound)
+
+		must(t, repo.DeleteByClient("a"))
+		for _, userID := range []uint{1, 2} {
+			_, err := repo.Find(userID, "a")
+			expectErr(t, err, repository.ErrNotFound)
+		}
+	})
+}
+
+// TestOAuthCodeRepository 授权码仓储行为测试，newRepo 每次返回一个空的仓储
+func TestOAuthCodeRepository(t *testing.T, newRepo func(t *testing.T) repository.OAuthCodeRepository) {
+	create := func(t *testing.T, repo repository.OAuthCodeRepository, hash string, expire time.Duration) *models.OAuthAuthorizationCode {
+		t.Helper()
+		code := &models.OAuthAuthorizationCode{
+			CodeHash:      hash,
+			ClientID:      "app",
+			UserID:        1,
+			RedirectURI:   "https://app.example.com/callback",
+			Scopes:        "openid email",
+			Nonce:         "nonce",
+			CodeChallenge: "challenge",
+			ExpiredAt:     time.Now().Add(expire),
+		}
+		must(t, repo.Create(code))
+		return code
+	}
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		repo := newRepo(t)
+		code := create(t, repo, "hash-1", time.Minute)
+		if code.ID == 0 || code.CreatedAt.IsZero() {
+			t.Fatalf("Create 未填充 ID 和创建时间: %+v", code)
+		}
+		found, err := repo.FindByHash("hash-1")
+		must(t, err)
+		if found.ID != code.ID || found.ClientID != "app" || found.UserID != 1 || found.Nonce != "nonce" ||
+			found.CodeChallenge != "challenge" || len(found.ScopeList()) != 2 || found.IsUsed() || found.IsExpired() {
+			t.Fatalf("FindByHash = %+v", found)
+		}
+		_, err = repo.FindByHash("missing")
+		expectErr(t, err, repository.ErrNotFound)
+	})
+
+	t.Run("CreatePurgesExpired", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, "expired", -time.Minute)
+		create(t, repo, "active", time.Minute)
+		create(t, repo, "new", time.Minute)
+
+		_, err := repo.FindByHash("expired")
+		expectErr(t, err, repository.ErrNotFound)
+		_, err = repo.FindByHash("active")
+		must(t, err)
+	})
+
+	t.Run("Consume", func(t *testing.T) {
+		repo := newRepo(t)
+		code := create(t, repo, "hash", time.Minute)
+		must(t, repo.Consume(code.ID))
+		expectErr(t, repo.Consume(code.ID), repository.ErrConflict)
+
+		found, err := repo.FindByHash("hash")
+		must(t, err)
+		if !found.IsUsed() {
+			t.Fatal("Consume 后授权码应被标记为已使用")
+		}
+	})
+
+	t.Run("ConsumeByUser", func(t *testing.T) {
+		repo := newRepo(t)
+		first := create(t, repo, "first", time.Minute)
+		second := create(t, repo, "second", time.Minute)
+		other := &models.OAuthAuthorizationCode{CodeHash: "other", ClientID: "app", UserID: 2, RedirectURI: "https://app.example.com/callback",
+			Scopes: "openid", ExpiredAt: time.Now().Add(time.Minute)}
+		must(t, repo.Create(other))
+		must(t, repo.Consume(first.ID))
+		must(t, repo.ConsumeByUser(1))
+
+		expectErr(t, repo.Consume(second.ID), repository.ErrConflict)
+		must(t, repo.Consume(other.ID))
+	})
+}
+
+// TestOAuthTokenRepository OAuth 令牌仓储行为测试，newRepo 每次返回一个空的仓储
+func TestOAuthTokenRepository(t *testing.T, newRepo func(t *testing.T) repository.OAuthTokenRepository) {
+	create := func(t *testing.T, repo repository.OAuthTokenRepository, family, access string, userID uint, clientID string, refresh *time.Duration) *models.OAuthToken {
+		t.Helper()
+		token := &models.OAuthToken{
+			FamilyID:        family,
+			ClientID:        clientID,
+			UserID:          userID,
+			Scopes:          "openid offline_access",
+			AccessTokenHash: access,
+			AccessExpiredAt: time.Now().Add(time.Minute),
+		}
+		if refresh != nil {
+			hash := "refresh-" + access
+			expiredAt := time.Now().Add(*refresh)
+			token.RefreshTokenHash, token.RefreshExpiredAt = &hash, &expiredAt
+		}
+		must(t, repo.Create(token))
+		return token
+	}
+	hour := time.Hour
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		repo := newRepo(t)
+		token := create(t, repo, "family", "access-1", 1, "app", &hour)
+		if token.ID == 0 || token.CreatedAt.IsZero() {
+			t.Fatalf("Create 未填充 ID 和创建时间: %+v", token)
+		}
+		// 没有刷新令牌的记录可以有多条
+		create(t, repo, "other", "access-2", 0, "app", nil)
+		create(t, repo, "other", "access-3", 0, "app", nil)
+
+		found, err := repo.FindByAccessHash("access-1")
+		must(t, err)
+		if found.ID != token.ID || !found.AccessActive() || !found.RefreshActive() || !found.HasScope("offline_access") {
+			t.Fatalf("FindByAccessHash = %+v", found)
+		}
+		found, err = repo.FindByRefreshHash("refresh-access-1")
+		must(t, err)
+		if found.ID != token.ID {
+			t.Fatalf("FindByRefreshHash = %+v", found)
+		}
+		_, err = repo.FindByRefreshHash("refresh-access-2")
+		expectErr(t, err, repository.ErrNotFound)
+		_, err = repo.FindByAccessHash("missing")
+		expectErr(t, err, repository.ErrNotFound)
+		expectErr(t, repo.Create(&models.OAuthToken{FamilyID: "x", ClientID: "app", AccessTokenHash: "access-1",
+			AccessExpiredAt: time.Now().Add(time.Minute)}), repository.ErrDuplicate)
+	})
+
+	t.Run("CreatePurgesExpired", func(t *testing.T) {
+		repo := newRepo(t)
+		expired := &models.OAuthToken{FamilyID: "f", ClientID: "app", AccessTokenHash: "expired", AccessExpiredAt: time.Now().Add(-time.Minute)}
+		must(t, repo.Create(expired))
+		// 访问令牌已过期但刷新令牌仍然有效的记录需要保留
+		refreshable := create(t, repo, "f", "refreshable", 1, "app", &hour)
+		must(t, repo.Revoke(refreshable.ID))
+		create(t, repo, "f", "new", 1, "app", nil)
+
+		_, err := repo.FindByAccessHash("expired")
+		expectErr(t, err, repository.ErrNotFound)
+		_, err = repo.FindByAccessHash("refreshable")
+		must(t, err)
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		repo := newRepo(t)
+		token := create(t, repo, "family", "access", 1, "app", &hour)
+		must(t, repo.Revoke(token.ID))
+		expectErr(t, repo.Revoke(token.ID), repository.ErrConflict)
+
+		found, err := repo.FindByAccessHash("access")
+		must(t, err)
+		if !found.IsRevoked() || found.AccessActive() || found.RefreshActive() {
+			t.Fatalf("Revoke 后 = %+v", found)
+		}
+	})
+
+	t.Run("RevokeMany", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, "f1", "a", 1, "app", &hour)
+		create(t, repo, "f1", "b", 1, "app", &hour)
+		create(t, repo, "f2", "c", 1, "app", nil)
+		create(t, repo, "f3", "d", 2, "app", nil)
+		create(t, repo, "f4", "e", 1, "other", nil)
+		create(t, repo, "f5", "f", 0, "service", nil)
+
+		active := func(hashes ...string) {
+			t.Helper()
+			for _, hash := range []string{"a", "b", "c", "d", "e", "f"} {
+				found, err := repo.FindByAccessHash(hash)
+				must(t, err)
+				want := false
+				for _, h := range hashes {
+					want = want || h == hash
+				}
+				if found.AccessActive() != want {
+					t.Fatalf("令牌 %s 有效 = %v，want %v", hash, found.AccessActive(), want)
+				}
+			}
+		}
+
+		must(t, repo.RevokeFamily("f1"))
+		active("c", "d", "e", "f")
+		must(t, repo.RevokeByUserClient(1, "app"))
+		active("d", "e", "f")
+		must(t, repo.RevokeByUser(1))
+		active("d", "f")
+		must(t, repo.RevokeByClient("app"))
+		active("f")
+		must(t, repo.RevokeByClient("service"))
+		active()
+	})
+}
@@ -0,0 +1,136 @@
+package repotest
+
+import (
+	"testing"
+	"time"
+
+	"go_app/models"
+	"go_app/repository"
+)
+
+// TestUserIdentityRepository 第三方账号关联仓储行为测试，newRepo 每次返回一个空的仓储
+func TestUserIdentityRepository(t *testing.T, newRepo func(t *testing.T) repository.UserIdentityRepository) {
+	create := func(t *testing.T, repo repository.UserIdentityRepository, userID uint, provider, subject string) *models.UserIdentity {
+		t.Helper()
+		identity := &models.UserIdentity{UserID: userID, Provider: provider, Subject: subject, Email: subject + "@example.com"}
+		must(t, repo.Create(identity))
+		return identity
+	}
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		repo := newRepo(t)
+		identity := create(t, repo, 1, "google", "1001")
+		if identity.ID == 0 || identity.CreatedAt.IsZero() {
+			t.Fatalf("Create 未填充 ID 和创建时间: %+v", identity)
+		}
+		found, err := repo.FindBySubject("google", "1001")
+		must(t, err)
+		if found.ID != identity.ID || found.UserID != 1 || found.Email != "1001@example.com" || found.LastLoginAt != nil {
+			t.Fatalf("FindBySubject = %+v", found)
+		}
+		_, err = repo.FindBySubject("github", "1001")
+		expectErr(t, err, repository.ErrNotFound)
+
+		// 同一提供方的账号只能关联一个用户，不同提供方的标识互不影响
+		expectErr(t, repo.Create(&models.UserIdentity{UserID: 2, Provider: "google", Subject: "1001"}), repository.ErrDuplicate)
+		create(t, repo, 2, "github", "1001")
+	})
+
+	t.Run("ListByUser", func(t *testing.T) {
+		repo := newRepo(t)
+		first := create(t, repo, 1, "google", "a")
+		create(t, repo, 2, "google", "b")
+		second := create(t, repo, 1, "github", "c")
+
+		list, err := repo.ListByUser(1)
+		must(t, err)
+		if len(list) != 2 || list[0].ID != first.ID || list[1].ID != second.ID {
+			t.Fatalf("ListByUser = %+v", list)
+		}
+	})
+
+	t.Run("RecordLogin", func(t *testing.T) {
+		repo := newRepo(t)
+		identity := create(t, repo, 1, "google", "1001")
+		at := time.Now().Truncate(time.Second)
+		must(t, repo.RecordLogin(identity.ID, "new@example.com", at))
+
+		found, err := repo.FindBySubject("google", "1001")
+		must(t, err)
+		if found.Email != "new@example.com" || found.LastLoginAt == nil || !found.LastLoginAt.Equal(at) {
+			t.Fatalf("RecordLogin 后 = %+v", found)
+		}
+		expectErr(t, repo.RecordLogin(identity.ID+100, "", at), repository.ErrNotFound)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo := newRepo(t)
+		identity := create(t, repo, 1, "google", "1001")
+
+		expectErr(t, repo.Delete(2, identity.ID), repository.ErrNotFound)
+		must(t, repo.Delete(1, identity.ID))
+		expectErr(t, repo.Delete(1, identity.ID), repository.ErrNotFound)
+		_, err := repo.FindBySubject("google", "1001")
+		expectErr(t, err, repository.ErrNotFound)
+
+		// 解除关联后可以重新关联
+		create(t, repo, 2, "google", "1001")
+	})
+}
+
+// TestOIDCFlowRepository 第三方登录流程仓储行为测试，newRepo 每次返回一个空的仓储
+func TestOIDCFlowRepository(t *testing.T, newRepo func(t *testing.T) repository.OIDCFlowRepository) {
+	create := func(t *testing.T, repo repository.OIDCFlowRepository, hash string, expire time.Duration) *models.OIDCFlow {
+		t.Helper()
+		flow := &models.OIDCFlow{
+			StateHash:    hash,
+			Provider:     "google",
+			Nonce:        "nonce",
+			CodeVerifier: "verifier",
+			ExpiredAt:    time.Now().Add(expire),
+		}
+		must(t, repo.Create(flow))
+		return flow
+	}
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		repo := newRepo(t)
+		flow := create(t, repo, "hash-1", time.Minute)
+		if flow.ID == 0 || flow.CreatedAt.IsZero() {
+			t.Fatalf("Create 未填充 ID 和创建时间: %+v", flow)
+		}
+		found, err := repo.FindByHash("hash-1")
+		must(t, err)
+		if found.ID != flow.ID || found.Provider != "google" || found.Nonce != "nonce" || found.CodeVerifier != "verifier" ||
+			found.IsUsed() || found.IsExpired() {
+			t.Fatalf("FindByHash = %+v", found)
+		}
+		_, err = repo.FindByHash("missing")
+		expectErr(t, err, repository.ErrNotFound)
+	})
+
+	t.Run("CreatePurgesExpired", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, "expired", -time.Minute)
+		create(t, repo, "active", time.Minute)
+		create(t, repo, "new", time.Minute)
+
+		_, err := repo.FindByHash("expired")
+		expectErr(t, err, repository.ErrNotFound)
+		_, err = repo.FindByHash("active")
+		must(t, err)
+	})
+
+	t.Run("Consume", func(t *testing.T) {
+		repo := newRepo(t)
+		flow := create(t, repo, "hash", time.Minute)
+		must(t, repo.Consume(flow.ID))
+		expectErr(t, repo.Consume(flow.ID), repository.ErrConflict)
+
+		found, err := repo.FindByHash("hash")
+		must(t, err)
+		if !found.IsUsed() {
+			t.Fatal("Consume 后流程应被标记为已使用")
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package repotest
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"go_app/models"
+	"go_app/repository"
+)
+
+// TestPasskeyRepository 通行密钥仓储行为测试，newRepo 每次返回一个空的仓储
+func TestPasskeyRepository(t *testing.T, newRepo func(t *testing.T) repository.PasskeyRepository) {
+	create := func(t *testing.T, repo repository.PasskeyRepository, userID uint, credentialID string) *models.Passkey {
+		t.Helper()
+		passkey := &models.Passkey{
+			UserID:         userID,
+			CredentialID:   credentialID,
+			PublicKey:      []byte{0xa5, 0x01, 0x02},
+			Transports:     "internal,hybrid",
+			SignCount:      1,
+			BackupEligible: true,
+			Name:           credentialID,
+		}
+		must(t, repo.Create(passkey))
+		return passkey
+	}
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		repo := newRepo(t)
+		passkey := create(t, repo, 1, "cred-1")
+		if passkey.ID == 0 || passkey.CreatedAt.IsZero() {
+			t.Fatalf("Create 未填充 ID 和创建时间: %+v", passkey)
+		}
+		found, err := repo.FindByCredentialID("cred-1")
+		must(t, err)
+		if found.ID != passkey.ID || found.UserID != 1 || !bytes.Equal(found.PublicKey, passkey.PublicKey) ||
+			found.Transports != "internal,hybrid" || found.SignCount != 1 || !found.BackupEligible || found.LastUsedAt != nil {
+			t.Fatalf("FindByCredentialID = %+v", found)
+		}
+		_, err = repo.FindByCredentialID("missing")
+		expectErr(t, err, repository.ErrNotFound)
+
+		// 凭证ID全局唯一
+		expectErr(t, repo.Create(&models.Passkey{UserID: 2, CredentialID: "cred-1", PublicKey: []byte{1}}), repository.ErrDuplicate)
+	})
+
+	t.Run("ListByUser", func(t *testing.T) {
+		repo := newRepo(t)
+		first := create(t, repo, 1, "a")
+		create(t, repo, 2, "b")
+		second := create(t, repo, 1, "c")
+
+		list, err := repo.ListByUser(1)
+		must(t, err)
+		if len(list) != 2 || list[0].ID != first.ID || list[1].ID != second.ID {
+			t.Fatalf("ListByUser = %+v", list)
+		}
+		list, err = repo.ListByUser(3)
+		must(t, err)
+		if len(list) != 0 {
+			t.Fatalf("没有通行密钥的用户 ListByUser = %+v", list)
+		}
+	})
+
+	t.Run("RecordUse", func(t *testing.T) {
+		repo := newRepo(t)
+		passkey := create(t, repo, 1, "cred")
+		at := time.Now().Truncate(time.Second)
+		must(t, repo.RecordUse(passkey.ID, 7, true, at))
+
+		found, err := repo.FindByCredentialID("cred")
+		must(t, err)
+		if found.SignCount != 7 || !found.BackupState || found.LastUsedAt == nil || !found.LastUsedAt.Equal(at) {
+			t.Fatalf("RecordUse 后 = %+v", found)
+		}
+		expectErr(t, repo.RecordUse(passkey.ID+100, 1, false, at), repository.ErrNotFound)
+	})
+
+	t.Run("Rename", func(t *testing.T) {
+		repo := newRepo(t)
+		passkey := create(t, repo, 1, "cred")
+		must(t, repo.Rename(1, passkey.ID, "MacBook"))
+		// 不能修改其他用户的通行密钥
+		expectErr(t, repo.Rename(2, passkey.ID, "other"), repository.ErrNotFound)
+
+		found, err := repo.FindByCredentialID("cred")
+		must(t, err)
+		if found.Name != "MacBook" {
+			t.Fatalf("Rename 后 Name = %q", found.Name)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo := newRepo(t)
+		passkey := create(t, repo, 1, "a")
+		create(t, repo, 1, "b")
+		create(t, repo, 2, "c")
+
+		expectErr(t, repo.Delete(2, passkey.ID), repository.ErrNotFound)
+		must(t, repo.Delete(1, passkey.ID))
+		expectErr(t, repo.Delete(1, passkey.ID), repository.ErrNotFound)
+		_, err := repo.FindByCredentialID("a")
+		expectErr(t, err, repository.ErrNotFound)
+
+		must(t, repo.DeleteByUser(1))
+		list, err := repo.ListByUser(1)
+		must(t, err)
+		if len(list) != 0 {
+			t.Fatalf("DeleteByUser 后 ListByUser = %+v", list)
+		}
+		_, err = repo.FindByCredentialID("c")
+		must(t, err)
+	})
+}
+
+// TestPasskeyCeremonyRepository 通行密钥流程仓储行为测试，newRepo 每次返回一个空的仓储
+func TestPasskeyCeremonyRepository(t *testing.T, newRepo func(t *testing.T) repository.PasskeyCeremonyRepository) {
+	create := func(t *testing.T, repo repository.PasskeyCeremonyRepository, userID uint, hash string, expire time.Duration) *models.PasskeyCeremony {
+		t.Helper()
+		ceremony := &models.PasskeyCeremony{
+			UserID:    userID,
+			TokenHash: hash,
+			Type:      models.PasskeyCeremonyLogin,
+			Data:      `{"challenge":"abc"}`,
+			ExpiredAt: time.Now().Add(expire),
+		}
+		must(t, repo.Create(ceremony))
+		return ceremony
+	}
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		repo := newRepo(t)
+		ceremony := create(t, repo, 0, "hash-1", time.Minute)
+		if ceremony.ID == 0 || ceremony.CreatedAt.IsZero() {
+			t.Fatalf("Create 未填充 ID 和创建时间: %+v", ceremony)
+		}
+		found, err := repo.FindByHash("hash-1")
+		must(t, err)
+		if found.ID != ceremony.ID || found.Type != models.PasskeyCeremonyLogin || found.Data != ceremony.Data || found.IsUsed() || found.IsExpired() {
+			t.Fatalf("FindByHash = %+v", found)
+		}
+		_, err = repo.FindByHash("missing")
+		expectErr(t, err, repository.ErrNotFound)
+	})
+
+	t.Run("CreatePurgesExpired", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, 1, "expired", -time.Minute)
+		create(t, repo, 0, "active", time.Minute)
+		create(t, repo, 2, "new", time.Minute)
+
+		_, err := repo.FindByHash("expired")
+		expectErr(t, err, repository.ErrNotFound)
+		_, err = repo.FindByHash("active")
+		must(t, err)
+	})
+
+	t.Run("Consume", func(t *testing.T) {
+		repo := newRepo(t)
+		ceremony := create(t, repo, 1, "hash", time.Minute)
+		must(t, repo.Consume(ceremony.ID))
+		expectErr(t, repo.Consume(ceremony.ID), repository.ErrConflict)
+
+		found, err := repo.FindByHash("hash")
+		must(t, err)
+		if !found.IsUsed() {
+			t.Fatal("Consume 后流程应被标记为已使用")
+		}
+	})
+}
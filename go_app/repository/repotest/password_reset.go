@@ -0,0 +1,132 @@
+package repotest
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go_app/models"
+	"go_app/repository"
+)
+
+// TestPasswordResetRepository 找回密码令牌仓储行为测试，newRepo 每次返回一个空的仓储
+func TestPasswordResetRepository(t *testing.T, newRepo func(t *testing.T) repository.PasswordResetRepository) {
+	create := func(t *testing.T, repo repository.PasswordResetRepository, userID uint, hash string) *models.PasswordResetToken {
+		t.Helper()
+		token := &models.PasswordResetToken{UserID: userID, TokenHash: hash, ExpiredAt: time.Now().Add(time.Hour)}
+		must(t, repo.Create(token))
+		return token
+	}
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		repo := newRepo(t)
+		token := create(t, repo, 1, "hash-1")
+		if token.ID == 0 || token.CreatedAt.IsZero() {
+			t.Fatalf("Create 未填充 ID 和创建时间: %+v", token)
+		}
+		found, err := repo.FindByHash("hash-1")
+		must(t, err)
+		if found.ID != token.ID || found.UserID != 1 || found.IsUsed() {
+			t.Fatalf("FindByHash = %+v", found)
+		}
+		_, err = repo.FindByHash("missing")
+		expectErr(t, err, repository.ErrNotFound)
+	})
+
+	t.Run("CreateReplacesPrevious", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, 1, "old")
+		create(t, repo, 2, "other")
+		create(t, repo, 1, "new")
+
+		_, err := repo.FindByHash("old")
+		expectErr(t, err, repository.ErrNotFound)
+		_, err = repo.FindByHash("new")
+		must(t, err)
+		_, err = repo.FindByHash("other")
+		must(t, err)
+	})
+
+	t.Run("CreateUnlessRecent", func(t *testing.T) {
+		repo := newRepo(t)
+		first := &models.PasswordResetToken{UserID: 1, TokenHash: "first", ExpiredAt: time.Now().Add(time.Hour)}
+		must(t, repo.CreateUnlessRecent(first, time.Now().Add(-time.Minute)))
+
+		// 冷却时间内已有有效的令牌，不创建也不替换
+		second := &models.PasswordResetToken{UserID: 1, TokenHash: "second", ExpiredAt: time.Now().Add(time.Hour)}
+		expectErr(t, repo.CreateUnlessRecent(second, time.Now().Add(-time.Minute)), repository.ErrConflict)
+		_, err := repo.FindByHash("first")
+		must(t, err)
+		_, err = repo.FindByHash("second")
+		expectErr(t, err, repository.ErrNotFound)
+
+		// 其他用户不受影响
+		must(t, repo.CreateUnlessRecent(&models.PasswordResetToken{UserID: 2, TokenHash: "other", ExpiredAt: time.Now().Add(time.Hour)}, time.Now().Add(-time.Minute)))
+
+		// 超过冷却时间后替换之前的令牌
+		must(t, repo.CreateUnlessRecent(second, time.Now()))
+		_, err = repo.FindByHash("first")
+		expectErr(t, err, repository.ErrNotFound)
+		_, err = repo.FindByHash("second")
+		must(t, err)
+
+		// 已使用或已过期的令牌不影响重新申请
+		must(t, repo.Consume(second.ID))
+		must(t, repo.CreateUnlessRecent(&models.PasswordResetToken{UserID: 1, TokenHash: "third", ExpiredAt: time.Now().Add(-time.Second)}, time.Now().Add(-time.Minute)))
+		must(t, repo.CreateUnlessRecent(&models.PasswordResetToken{UserID: 1, TokenHash: "fourth", ExpiredAt: time.Now().Add(time.Hour)}, time.Now().Add(-time.Minute)))
+		_, err = repo.FindByHash("fourth")
+		must(t, err)
+	})
+
+	t.Run("CreateUnlessRecentConcurrent", func(t *testing.T) {
+		repo := newRepo(t)
+		// 并发的申请只有一个创建成功
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			created int
+		)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				token := &models.PasswordResetToken{UserID: 1, TokenHash: "hash-" + strconv.Itoa(i), ExpiredAt: time.Now().Add(time.Hour)}
+				if err := repo.CreateUnlessRecent(token, time.Now().Add(-time.Minute)); err == nil {
+					mu.Lock()
+					created++
+					mu.Unlock()
+				}
+			}(i)
+		}
+		wg.Wait()
+		if created != 1 {
+			t.Fatalf("并发申请成功 %d 次, want 1", created)
+		}
+	})
+
+	t.Run("Consume", func(t *testing.T) {
+		repo := newRepo(t)
+		token := create(t, repo, 1, "hash")
+		must(t, repo.Consume(token.ID))
+		expectErr(t, repo.Consume(token.ID), repository.ErrConflict)
+
+		found, err := repo.FindByHash("hash")
+		must(t, err)
+		if !found.IsUsed() {
+			t.Fatal("Consume 后令牌应被标记为已使用")
+		}
+	})
+
+	t.Run("DeleteByUser", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, 1, "a")
+		create(t, repo, 2, "b")
+		must(t, repo.DeleteByUser(1))
+
+		_, err := repo.FindByHash("a")
+		expectErr(t, err, repository.ErrNotFound)
+		_, err = repo.FindByHash("b")
+		must(t, err)
+	})
+}
@@ -0,0 +1,96 @@
+package repotest
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"go_app/models"
+	"go_app/repository"
+)
+
+// TestPresenceRepository 在线状态存储行为测试，newRepo 每次返回一个空的存储
+func TestPresenceRepository(t *testing.T, newRepo func(t *testing.T) repository.PresenceRepository) {
+	// 存储按毫秒保存失效时间
+	now := time.Now().Truncate(time.Millisecond)
+
+	t.Run("Statuses", func(t *testing.T) {
+		repo := newRepo(t)
+		must(t, repo.Touch(1, "a", models.PresenceOnline, now.Add(time.Minute)))
+		must(t, repo.Touch(1, "b", models.PresenceOnline, now.Add(time.Minute)))
+		must(t, repo.Touch(1, "b", models.PresenceAway, now.Add(time.Minute)))
+		must(t, repo.Touch(2, "c", models.PresenceOnline, now.Add(time.Second)))
+
+		statuses, err := repo.Statuses([]uint{1, 2, 3}, now)
+		must(t, err)
+		got := statuses[1]
+		sort.Strings(got)
+		if len(statuses) != 2 || len(got) != 2 || got[0] != models.PresenceAway || got[1] != models.PresenceOnline ||
+			len(statuses[2]) != 1 || statuses[2][0] != models.PresenceOnline {
+			t.Fatalf("Statuses = %v", statuses)
+		}
+
+		// 失效时间到达后记录不再返回
+		statuses, err = repo.Statuses([]uint{1, 2}, now.Add(time.Second))
+		must(t, err)
+		if len(statuses) != 1 || len(statuses[1]) != 2 {
+			t.Fatalf("Statuses = %v, want 只有用户 1", statuses)
+		}
+
+		// 续期后恢复
+		must(t, repo.Touch(2, "c", models.PresenceOnline, now.Add(time.Hour)))
+		statuses, err = repo.Statuses([]uint{2}, now.Add(time.Minute))
+		must(t, err)
+		if len(statuses[2]) != 1 {
+			t.Fatalf("续期后 Statuses = %v", statuses)
+		}
+	})
+
+	t.Run("Expires", func(t *testing.T) {
+		repo := newRepo(t)
+		must(t, repo.Touch(1, "a", models.PresenceOnline, time.Now().Add(50*time.Millisecond)))
+		time.Sleep(100 * time.Millisecond)
+		statuses, err := repo.Statuses([]uint{1}, time.Now())
+		must(t, err)
+		if len(statuses) != 0 {
+			t.Fatalf("Statuses = %v, want 空", statuses)
+		}
+	})
+
+	t.Run("SwapStatus", func(t *testing.T) {
+		repo := newRepo(t)
+		steps := []struct{ status, previous string }{
+			{models.PresenceOnline, models.PresenceOffline},
+			{models.PresenceOnline, models.PresenceOnline},
+			{models.PresenceAway, models.PresenceOnline},
+			{models.PresenceOffline, models.PresenceAway},
+			{models.PresenceOffline, models.PresenceOffline},
+		}
+		for i, step := range steps {
+			previous, err := repo.SwapStatus(1, step.status)
+			must(t, err)
+			if previous != step.previous {
+				t.Fatalf("第 %d 次 SwapStatus(%s) = %s, want %s", i+1, step.status, previous, step.previous)
+			}
+		}
+	})
+
+	t.Run("Published", func(t *testing.T) {
+		repo := newRepo(t)
+		for _, id := range []uint{1, 2, 3} {
+			_, err := repo.SwapStatus(id, models.PresenceOnline)
+			must(t, err)
+		}
+		_, err := repo.SwapStatus(2, models.PresenceAway)
+		must(t, err)
+		_, err = repo.SwapStatus(3, models.PresenceOffline)
+		must(t, err)
+
+		published, err := repo.Published()
+		must(t, err)
+		sort.Slice(published, func(i, j int) bool { return published[i] < published[j] })
+		if len(published) != 2 || published[0] != 1 || published[1] != 2 {
+			t.Fatalf("Published = %v, want [1 2]", published)
+		}
+	})
+}
@@ -0,0 +1,93 @@
+// Package repotest 仓储接口的行为测试。
+//
+// 同一组测试同时用于 GORM 实现和内存实现，保证两者行为一致，例如：
+//
+//	func TestUserRepository(t *testing.T) {
+//		repotest.TestUserRepository(t, func(t *testing.T) repository.UserRepository {
+//			return repository.NewMemoryUserRepository()
+//		})
+//		repotest.TestUserRepository(t, func(t *testing.T) repository.UserRepository {
+//			return repository.NewUserRepository(repotest.OpenSQLite(t))
+//		})
+//	}
+//
+// 基于 Redis 的实现使用 OpenRedis 返回的内存 Redis 服务测试，不需要真实的 Redis 服务器
+package repotest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go_app/migrations"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/glebarez/sqlite"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// OpenSQLite 打开执行过全部迁移的 SQLite 内存数据库，测试结束时自动关闭
+func OpenSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:?_pragma=foreign_keys(1)"), &gorm.Config{
+		TranslateError: true,
+		Logger:         logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("打开 SQLite 失败: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("获取数据库连接失败: %v", err)
+	}
+	// 内存数据库的数据只存在于单个连接中
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if _, err := migrations.NewMigrator(db).Up(); err != nil {
+		t.Fatalf("执行迁移失败: %v", err)
+	}
+	return db
+}
+
+// OpenRedis 启动内存中的 Redis 服务并返回连接它的客户端，测试结束时自动关闭
+func OpenRedis(t *testing.T) *redis.Client {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	// miniredis 的键不会随真实时间过期，定时推进它的时钟，使过期行为与真实 Redis 一致
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				server.FastForward(10 * time.Millisecond)
+			case <-done:
+				return
+			}
+		}
+	}()
+	t.Cleanup(func() { close(done) })
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func expectErr(t *testing.T, got, want error) {
+	t.Helper()
+	if !errors.Is(got, want) {
+		t.Fatalf("error = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,121 @@
+package repotest
+
+import (
+	"reflect"
+	"testing"
+
+	"go_app/models"
+	"go_app/repository"
+)
+
+// TestRoleRepository 角色仓储行为测试，newRepo 每次返回一个只包含内置角色的仓储
+func TestRoleRepository(t *testing.T, newRepo func(t *testing.T) repository.RoleRepository) {
+	find := func(t *testing.T, repo repository.RoleRepository, name string) *models.Role {
+		t.Helper()
+		role, err := repo.FindByName(name)
+		must(t, err)
+		return role
+	}
+
+	t.Run("BuiltinRoles", func(t *testing.T) {
+		repo := newRepo(t)
+		roles, err := repo.List()
+		must(t, err)
+		builtin := models.BuiltinRoles()
+		if len(roles) != len(builtin) {
+			t.Fatalf("List 返回 %d 个角色，want %d", len(roles), len(builtin))
+		}
+		for i, role := range roles {
+			if role.Name != builtin[i].Name || len(role.Permissions) != len(builtin[i].Permissions) {
+				t.Fatalf("List[%d] = %+v, want %+v", i, role, builtin[i])
+			}
+		}
+
+		admin := find(t, repo, models.RoleAdmin)
+		if !admin.HasPermission(models.PermRolesManage) || admin.HasPermission("unknown") {
+			t.Fatalf("admin 权限 = %v", admin.Permissions)
+		}
+		_, err = repo.FindByName("missing")
+		expectErr(t, err, repository.ErrNotFound)
+	})
+
+	t.Run("AssignAndRevoke", func(t *testing.T) {
+		repo := newRepo(t)
+		admin := find(t, repo, models.RoleAdmin)
+		moderator := find(t, repo, models.RoleModerator)
+
+		must(t, repo.Assign(1, moderator.ID))
+		must(t, repo.Assign(1, admin.ID))
+		// 重复分配不应报错
+		must(t, repo.Assign(1, admin.ID))
+		must(t, repo.Assign(2, moderator.ID))
+
+		roles, err := repo.ListByUser(1)
+		must(t, err)
+		if len(roles) != 2 || roles[0].ID != admin.ID || roles[1].ID != moderator.ID {
+			t.Fatalf("ListByUser = %+v", roles)
+		}
+		count, err := repo.CountUsers(moderator.ID)
+		must(t, err)
+		if count != 2 {
+			t.Fatalf("CountUsers = %d, want 2", count)
+		}
+
+		must(t, repo.Revoke(1, admin.ID))
+		// 重复移除不应报错
+		must(t, repo.Revoke(1, admin.ID))
+		roles, err = repo.ListByUser(1)
+		must(t, err)
+		if len(roles) != 1 || roles[0].ID != moderator.ID {
+			t.Fatalf("Revoke 后 ListByUser = %+v", roles)
+		}
+	})
+
+	t.Run("Permissions", func(t *testing.T) {
+		repo := newRepo(t)
+		user := find(t, repo, models.RoleUser)
+		moderator := find(t, repo, models.RoleModerator)
+		admin := find(t, repo, models.RoleAdmin)
+
+		permissions, err := repo.Permissions(1)
+		must(t, err)
+		if len(permissions) != 0 {
+			t.Fatalf("未分配角色的用户不应有权限: %v", permissions)
+		}
+
+		must(t, repo.Assign(1, user.ID))
+		must(t, repo.Assign(1, moderator.ID))
+		permissions, err = repo.Permissions(1)
+		must(t, err)
+		if want := []string{models.PermUsersList, models.PermUsersRead}; !reflect.DeepEqual(permissions, want) {
+			t.Fatalf("Permissions = %v, want %v", permissions, want)
+		}
+
+		// admin 与 moderator 的权限有重叠，结果应去重
+		must(t, repo.Assign(1, admin.ID))
+		permissions, err = repo.Permissions(1)
+		must(t, err)
+		if len(permissions) != len(admin.Permissions) {
+			t.Fatalf("Permissions = %v, want %v", permissions, admin.Permissions)
+		}
+	})
+
+	t.Run("DeleteByUser", func(t *testing.T) {
+		repo := newRepo(t)
+		admin := find(t, repo, models.RoleAdmin)
+		must(t, repo.Assign(1, admin.ID))
+		must(t, repo.Assign(2, admin.ID))
+
+		must(t, repo.DeleteByUser(1))
+		roles, err := repo.ListByUser(1)
+		must(t, err)
+		if len(roles) != 0 {
+			t.Fatalf("DeleteByUser 后 ListByUser = %+v", roles)
+		}
+		count, err := repo.CountUsers(admin.ID)
+		must(t, err)
+		if count != 1 {
+			t.Fatalf("DeleteByUser 不应影响其他用户: CountUsers = %d", count)
+		}
+	})
+}
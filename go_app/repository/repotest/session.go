@@ -0,0 +1,216 @@
+package repotest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go_app/models"
+	"go_app/repository"
+)
+
+// TestSessionRepository 会话仓储行为测试，newRepo 每次返回一个空的仓储
+func TestSessionRepository(t *testing.T, newRepo func(t *testing.T) repository.SessionRepository) {
+	seq := 0
+	newSession := func(userID uint, platform, deviceID string, ttl time.Duration) (*models.UserToken, *models.RefreshToken) {
+		seq++
+		now := time.Now()
+		session := &models.UserToken{
+			UserID:       userID,
+			Token:        fmt.Sprintf("session-%d", seq),
+			Platform:     platform,
+			DeviceID:     deviceID,
+			LastActiveAt: now,
+			ExpiredAt:    now.Add(ttl),
+		}
+		refresh := &models.RefreshToken{
+			TokenHash: fmt.Sprintf("refresh-%d", seq),
+			ExpiredAt: session.ExpiredAt,
+		}
+		return session, refresh
+	}
+	create := func(t *testing.T, repo repository.SessionRepository, userID uint, platform, deviceID string, replace repository.SessionReplace) *models.UserToken {
+		t.Helper()
+		session, refresh := newSession(userID, platform, deviceID, time.Hour)
+		must(t, repo.Create(session, refresh, replace))
+		return session
+	}
+	exists := func(t *testing.T, repo repository.SessionRepository, id uint) bool {
+		t.Helper()
+		_, err := repo.FindByID(id)
+		if errors.Is(err, repository.ErrNotFound) {
+			return false
+		}
+		must(t, err)
+		return true
+	}
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		repo := newRepo(t)
+		session, refresh := newSession(1, models.PlatformWeb, "", time.Hour)
+		must(t, repo.Create(session, refresh, repository.SessionReplace{}))
+		if session.ID == 0 || refresh.ID == 0 {
+			t.Fatalf("Create 未填充 ID: session=%d refresh=%d", session.ID, refresh.ID)
+		}
+		if refresh.SessionID != session.ID || refresh.UserID != 1 {
+			t.Fatalf("刷新令牌未关联会话: %+v", refresh)
+		}
+
+		byToken, err := repo.FindByToken(session.Token)
+		must(t, err)
+		if byToken.ID != session.ID {
+			t.Fatalf("FindByToken ID = %d, want %d", byToken.ID, session.ID)
+		}
+		rt, err := repo.FindRefreshToken(refresh.TokenHash)
+		must(t, err)
+		if rt.SessionID != session.ID || rt.IsUsed() {
+			t.Fatalf("FindRefreshToken = %+v", rt)
+		}
+
+		_, err = repo.FindByID(999)
+		expectErr(t, err, repository.ErrNotFound)
+		_, err = repo.FindByToken("missing")
+		expectErr(t, err, repository.ErrNotFound)
+		_, err = repo.FindRefreshToken("missing")
+		expectErr(t, err, repository.ErrNotFound)
+	})
+
+	t.Run("DuplicateToken", func(t *testing.T) {
+		repo := newRepo(t)
+		first := create(t, repo, 1, models.PlatformWeb, "", repository.SessionReplace{})
+		session, refresh := newSession(1, models.PlatformIOS, "", time.Hour)
+		session.Token = first.Token
+		expectErr(t, repo.Create(session, refresh, repository.SessionReplace{}), repository.ErrDuplicate)
+	})
+
+	t.Run("ReplaceScopes", func(t *testing.T) {
+		repo := newRepo(t)
+		web := create(t, repo, 1, models.PlatformWeb, "", repository.SessionReplace{})
+		iosA := create(t, repo, 1, models.PlatformIOS, "a", repository.SessionReplace{})
+		iosB := create(t, repo, 1, models.PlatformIOS, "b", repository.SessionReplace{})
+		other := create(t, repo, 2, models.PlatformIOS, "a", repository.SessionReplace{})
+
+		// 同平台同设备
+		iosA2 := create(t, repo, 1, models.PlatformIOS, "a", repository.SessionReplace{Platform: models.PlatformIOS, DeviceID: "a"})
+		if exists(t, repo, iosA.ID) || !exists(t, repo, iosB.ID) || !exists(t, repo, web.ID) {
+			t.Fatal("按设备替换应只删除同平台同设备的会话")
+		}
+
+		// 同平台
+		ios := create(t, repo, 1, models.PlatformIOS, "c", repository.SessionReplace{Platform: models.PlatformIOS})
+		if exists(t, repo, iosA2.ID) || exists(t, repo, iosB.ID) || !exists(t, repo, web.ID) {
+			t.Fatal("按平台替换应删除同平台的全部会话")
+		}
+
+		// 全部
+		create(t, repo, 1, models.PlatformAndroid, "", repository.SessionReplace{All: true})
+		if exists(t, repo, ios.ID) || exists(t, repo, web.ID) {
+			t.Fatal("替换全部会话后旧会话应被删除")
+		}
+		if !exists(t, repo, other.ID) {
+			t.Fatal("不应删除其他用户的会话")
+		}
+	})
+
+	t.Run("ReplaceRemovesExpired", func(t *testing.T) {
+		repo := newRepo(t)
+		expired, refresh := newSession(1, models.PlatformWeb, "", -time.Minute)
+		must(t, repo.Create(expired, refresh, repository.SessionReplace{}))
+		create(t, repo, 1, models.PlatformIOS, "", repository.SessionReplace{})
+		if exists(t, repo, expired.ID) {
+			t.Fatal("创建新会话时应清理已过期的会话")
+		}
+		_, err := repo.FindRefreshToken(refresh.TokenHash)
+		expectErr(t, err, repository.ErrNotFound)
+	})
+
+	t.Run("ListActive", func(t *testing.T) {
+		repo := newRepo(t)
+		older := create(t, repo, 1, models.PlatformWeb, "", repository.SessionReplace{})
+		newer := create(t, repo, 1, models.PlatformIOS, "", repository.SessionReplace{})
+		create(t, repo, 2, models.PlatformWeb, "", repository.SessionReplace{})
+		must(t, repo.Touch(older.ID, time.Now().Add(-time.Hour)))
+
+		sessions, err := repo.ListActive(1)
+		must(t, err)
+		if len(sessions) != 2 || sessions[0].ID != newer.ID || sessions[1].ID != older.ID {
+			t.Fatalf("ListActive 应按最近活跃时间倒序返回该用户的会话: %+v", sessions)
+		}
+	})
+
+	t.Run("DeleteRemovesRefreshTokens", func(t *testing.T) {
+		repo := newRepo(t)
+		session, refresh := newSession(1, models.PlatformWeb, "", time.Hour)
+		must(t, repo.Create(session, refresh, repository.SessionReplace{}))
+		keep := create(t, repo, 1, models.PlatformIOS, "", repository.SessionReplace{})
+
+		must(t, repo.Delete(session.ID, 999))
+		if exists(t, repo, session.ID) || !exists(t, repo, keep.ID) {
+			t.Fatal("Delete 应只删除指定的会话")
+		}
+		_, err := repo.FindRefreshToken(refresh.TokenHash)
+		expectErr(t, err, repository.ErrNotFound)
+		must(t, repo.Delete())
+	})
+
+	t.Run("DeleteByUser", func(t *testing.T) {
+		repo := newRepo(t)
+		a := create(t, repo, 1, models.PlatformWeb, "", repository.SessionReplace{})
+		b := create(t, repo, 1, models.PlatformIOS, "", repository.SessionReplace{})
+		other := create(t, repo, 2, models.PlatformWeb, "", repository.SessionReplace{})
+
+		must(t, repo.DeleteByUser(1))
+		if exists(t, repo, a.ID) || exists(t, repo, b.ID) || !exists(t, repo, other.ID) {
+			t.Fatal("DeleteByUser 应只删除该用户的会话")
+		}
+	})
+
+	t.Run("Rotate", func(t *testing.T) {
+		repo := newRepo(t)
+		session, refresh := newSession(1, models.PlatformWeb, "", time.Hour)
+		must(t, repo.Create(session, refresh, repository.SessionReplace{}))
+
+		now := time.Now()
+		session.LastActiveAt = now
+		session.ExpiredAt = now.Add(2 * time.Hour)
+		next := &models.RefreshToken{TokenHash: "rotated", ExpiredAt: session.ExpiredAt}
+		must(t, repo.Rotate(refresh.ID, session, next))
+		if next.ID == 0 || next.SessionID != session.ID || next.UserID != session.UserID {
+			t.Fatalf("Rotate 未保存新的刷新令牌: %+v", next)
+		}
+
+		used, err := repo.FindRefreshToken(refresh.TokenHash)
+		must(t, err)
+		if !used.IsUsed() {
+			t.Fatal("轮换后旧刷新令牌应标记为已使用")
+		}
+		stored, err := repo.FindByID(session.ID)
+		must(t, err)
+		if stored.ExpiredAt.Sub(session.ExpiredAt).Abs() > time.Second {
+			t.Fatalf("会话有效期未更新: %v, want %v", stored.ExpiredAt, session.ExpiredAt)
+		}
+
+		// 同一刷新令牌不能被使用两次
+		again := &models.RefreshToken{TokenHash: "rotated-again", ExpiredAt: session.ExpiredAt}
+		expectErr(t, repo.Rotate(refresh.ID, session, again), repository.ErrConflict)
+		_, err = repo.FindRefreshToken(again.TokenHash)
+		expectErr(t, err, repository.ErrNotFound)
+	})
+
+	t.Run("RotateDeletedSession", func(t *testing.T) {
+		repo := newRepo(t)
+		session, refresh := newSession(1, models.PlatformWeb, "", time.Hour)
+		must(t, repo.Create(session, refresh, repository.SessionReplace{}))
+		rt, err := repo.FindRefreshToken(refresh.TokenHash)
+		must(t, err)
+		must(t, repo.Delete(session.ID))
+
+		next := &models.RefreshToken{TokenHash: "rotated", ExpiredAt: session.ExpiredAt}
+		if err := repo.Rotate(rt.ID, session, next); err == nil {
+			t.Fatal("会话已删除时 Rotate 应失败")
+		}
+		_, err = repo.FindRefreshToken(next.TokenHash)
+		expectErr(t, err, repository.ErrNotFound)
+	})
+}
@@ -0,0 +1,286 @@
+package repotest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"go_app/models"
+	"go_app/repository"
+)
+
+// TestTwoFactorRepository 两步验证仓储行为测试，newRepo 每次返回一个空的仓储
+func TestTwoFactorRepository(t *testing.T, newRepo func(t *testing.T) repository.TwoFactorRepository) {
+	count := func(t *testing.T, repo repository.TwoFactorRepository, userID uint) int64 {
+		t.Helper()
+		n, err := repo.CountRecoveryCodes(userID)
+		must(t, err)
+		return n
+	}
+
+	t.Run("SaveAndFind", func(t *testing.T) {
+		repo := newRepo(t)
+		_, err := repo.Find(1)
+		expectErr(t, err, repository.ErrNotFound)
+
+		must(t, repo.Save(&models.TwoFactor{UserID: 1, Secret: "SECRET1"}))
+		found, err := repo.Find(1)
+		must(t, err)
+		if found.Secret != "SECRET1" || found.IsEnabled() {
+			t.Fatalf("Find = %+v", found)
+		}
+
+		// 重新绑定覆盖之前的密钥，已确认的配置也变为未确认
+		must(t, repo.Enable(1, time.Now(), []string{"a"}))
+		must(t, repo.UseStep(1, 10))
+		must(t, repo.Save(&models.TwoFactor{UserID: 1, Secret: "SECRET2"}))
+		found, err = repo.Find(1)
+		must(t, err)
+		if found.Secret != "SECRET2" || found.IsEnabled() || found.LastUsedStep != 0 {
+			t.Fatalf("重新绑定后 Find = %+v", found)
+		}
+	})
+
+	t.Run("Enable", func(t *testing.T) {
+		repo := newRepo(t)
+		expectErr(t, repo.Enable(1, time.Now(), nil), repository.ErrConflict)
+
+		must(t, repo.Save(&models.TwoFactor{UserID: 1, Secret: "SECRET"}))
+		must(t, repo.Enable(1, time.Now(), []string{"a", "b", "c"}))
+		expectErr(t, repo.Enable(1, time.Now(), nil), repository.ErrConflict)
+
+		found, err := repo.Find(1)
+		must(t, err)
+		if !found.IsEnabled() {
+			t.Fatal("Enable 后两步验证应已生效")
+		}
+		if n := count(t, repo, 1); n != 3 {
+			t.Fatalf("CountRecoveryCodes = %d, want 3", n)
+		}
+	})
+
+	t.Run("UseStep", func(t *testing.T) {
+		repo := newRepo(t)
+		expectErr(t, repo.UseStep(1, 1), repository.ErrConflict)
+
+		must(t, repo.Save(&models.TwoFactor{UserID: 1, Secret: "SECRET"}))
+		must(t, repo.UseStep(1, 100))
+		// 同一时间步和更早的时间步不能再次使用
+		expectErr(t, repo.UseStep(1, 100), repository.ErrConflict)
+		expectErr(t, repo.UseStep(1, 99), repository.ErrConflict)
+		must(t, repo.UseStep(1, 101))
+	})
+
+	t.Run("Attempt", func(t *testing.T) {
+		repo := newRepo(t)
+		now := time.Now()
+		expectErr(t, repo.Attempt(1, 3, now.Add(-time.Minute), now), repository.ErrNotFound)
+
+		must(t, repo.Save(&models.TwoFactor{UserID: 1, Secret: "SECRET"}))
+		for i := 0; i < 3; i++ {
+			must(t, repo.Attempt(1, 3, now.Add(-time.Minute), now))
+		}
+		expectErr(t, repo.Attempt(1, 3, now.Add(-time.Minute), now), repository.ErrConflict)
+
+		// 最近一次占用早于 since 时重新计数
+		later := now.Add(2 * time.Minute)
+		must(t, repo.Attempt(1, 3, later.Add(-time.Minute), later))
+		found, err := repo.Find(1)
+		must(t, err)
+		if found.Attempts != 1 || found.AttemptedAt == nil || !found.AttemptedAt.Equal(later) {
+			t.Fatalf("重新计数后 Find = %+v", found)
+		}
+
+		// 校验通过后清零
+		must(t, repo.Attempt(1, 3, later.Add(-time.Minute), later))
+		must(t, repo.ResetAttempts(1))
+		for i := 0; i < 3; i++ {
+			must(t, repo.Attempt(1, 3, later.Add(-time.Minute), later))
+		}
+
+		// 重新绑定后清零
+		must(t, repo.Save(&models.TwoFactor{UserID: 1, Secret: "SECRET2"}))
+		must(t, repo.Attempt(1, 1, later.Add(-time.Minute), later))
+	})
+
+	t.Run("AttemptConcurrent", func(t *testing.T) {
+		repo := newRepo(t)
+		must(t, repo.Save(&models.TwoFactor{UserID: 1, Secret: "SECRET"}))
+		now := time.Now()
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			allowed int
+		)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := repo.Attempt(1, 5, now.Add(-time.Minute), now); err == nil {
+					mu.Lock()
+					allowed++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if allowed != 5 {
+			t.Fatalf("并发占用成功 %d 次, want 5", allowed)
+		}
+	})
+
+	t.Run("RecoveryCodes", func(t *testing.T) {
+		repo := newRepo(t)
+		must(t, repo.Save(&models.TwoFactor{UserID: 1, Secret: "SECRET"}))
+		must(t, repo.Enable(1, time.Now(), []string{"a", "b"}))
+		must(t, repo.Save(&models.TwoFactor{UserID: 2, Secret: "SECRET"}))
+		must(t, repo.Enable(2, time.Now(), []string{"a"}))
+
+		must(t, repo.UseRecoveryCode(1, "a"))
+		expectErr(t, repo.UseRecoveryCode(1, "a"), repository.ErrNotFound)
+		expectErr(t, repo.UseRecoveryCode(1, "missing"), repository.ErrNotFound)
+		if n := count(t, repo, 1); n != 1 {
+			t.Fatalf("CountRecoveryCodes = %d, want 1", n)
+		}
+		// 恢复码属于各自的用户
+		must(t, repo.UseRecoveryCode(2, "a"))
+
+		must(t, repo.ReplaceRecoveryCodes(1, []string{"c", "d", "e"}))
+		expectErr(t, repo.UseRecoveryCode(1, "b"), repository.ErrNotFound)
+		if n := count(t, repo, 1); n != 3 {
+			t.Fatalf("ReplaceRecoveryCodes 后 CountRecoveryCodes = %d, want 3", n)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo := newRepo(t)
+		must(t, repo.Save(&models.TwoFactor{UserID: 1, Secret: "SECRET"}))
+		must(t, repo.Enable(1, time.Now(), []string{"a"}))
+		must(t, repo.Save(&models.TwoFactor{UserID: 2, Secret: "SECRET"}))
+
+		must(t, repo.Delete(1))
+		_, err := repo.Find(1)
+		expectErr(t, err, repository.ErrNotFound)
+		if n := count(t, repo, 1); n != 0 {
+			t.Fatalf("Delete 后 CountRecoveryCodes = %d", n)
+		}
+		_, err = repo.Find(2)
+		must(t, err)
+	})
+}
+
+// TestTwoFactorChallengeRepository 两步登录挑战令牌仓储行为测试，newRepo 每次返回一个空的仓储
+func TestTwoFactorChallengeRepository(t *testing.T, newRepo func(t *testing.T) repository.TwoFactorChallengeRepository) {
+	create := func(t *testing.T, repo repository.TwoFactorChallengeRepository, userID uint, hash string, expire time.Duration) *models.TwoFactorChallenge {
+		t.Helper()
+		challenge := &models.TwoFactorChallenge{UserID: userID, TokenHash: hash, Platform: models.PlatformWeb, ExpiredAt: time.Now().Add(expire)}
+		must(t, repo.Create(challenge))
+		return challenge
+	}
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		repo := newRepo(t)
+		challenge := create(t, repo, 1, "hash-1", time.Minute)
+		if challenge.ID == 0 || challenge.CreatedAt.IsZero() {
+			t.Fatalf("Create 未填充 ID 和创建时间: %+v", challenge)
+		}
+		found, err := repo.FindByHash("hash-1")
+		must(t, err)
+		if found.ID != challenge.ID || found.UserID != 1 || found.Platform != models.PlatformWeb || found.IsUsed() || found.IsExpired() {
+			t.Fatalf("FindByHash = %+v", found)
+		}
+		_, err = repo.FindByHash("missing")
+		expectErr(t, err, repository.ErrNotFound)
+	})
+
+	t.Run("CreateKeepsOtherDevices", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, 1, "expired", -time.Minute)
+		create(t, repo, 1, "active", time.Minute)
+		create(t, repo, 1, "new", time.Minute)
+
+		_, err := repo.FindByHash("expired")
+		expectErr(t, err, repository.ErrNotFound)
+		_, err = repo.FindByHash("active")
+		must(t, err)
+	})
+
+	t.Run("Attempt", func(t *testing.T) {
+		repo := newRepo(t)
+		challenge := create(t, repo, 1, "hash", time.Minute)
+		for i := 0; i < 3; i++ {
+			must(t, repo.Attempt(challenge.ID, 3))
+		}
+		expectErr(t, repo.Attempt(challenge.ID, 3), repository.ErrConflict)
+		found, err := repo.FindByHash("hash")
+		must(t, err)
+		if found.Attempts != 3 {
+			t.Fatalf("Attempts = %d, want 3", found.Attempts)
+		}
+		expectErr(t, repo.Attempt(challenge.ID+100, 3), repository.ErrConflict)
+
+		// 已使用的令牌不能再占用次数
+		used := create(t, repo, 1, "used", time.Minute)
+		must(t, repo.Attempt(used.ID, 3))
+		must(t, repo.Consume(used.ID, 3))
+		expectErr(t, repo.Attempt(used.ID, 3), repository.ErrConflict)
+	})
+
+	t.Run("AttemptConcurrent", func(t *testing.T) {
+		repo := newRepo(t)
+		challenge := create(t, repo, 1, "hash", time.Minute)
+		// 并发请求中最多 maxAttempts 个占用成功
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			allowed int
+		)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := repo.Attempt(challenge.ID, 5); err == nil {
+					mu.Lock()
+					allowed++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if allowed != 5 {
+			t.Fatalf("并发占用成功 %d 次, want 5", allowed)
+		}
+	})
+
+	t.Run("Consume", func(t *testing.T) {
+		repo := newRepo(t)
+		challenge := create(t, repo, 1, "hash", time.Minute)
+		must(t, repo.Attempt(challenge.ID, 3))
+		must(t, repo.Consume(challenge.ID, 3))
+		expectErr(t, repo.Consume(challenge.ID, 3), repository.ErrConflict)
+
+		found, err := repo.FindByHash("hash")
+		must(t, err)
+		if !found.IsUsed() {
+			t.Fatal("Consume 后令牌应被标记为已使用")
+		}
+
+		// 次数超过上限（如上限被调低）的令牌不能使用
+		exhausted := create(t, repo, 1, "exhausted", time.Minute)
+		must(t, repo.Attempt(exhausted.ID, 3))
+		must(t, repo.Attempt(exhausted.ID, 3))
+		expectErr(t, repo.Consume(exhausted.ID, 1), repository.ErrConflict)
+	})
+
+	t.Run("DeleteByUser", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, 1, "a", time.Minute)
+		create(t, repo, 2, "b", time.Minute)
+		must(t, repo.DeleteByUser(1))
+
+		_, err := repo.FindByHash("a")
+		expectErr(t, err, repository.ErrNotFound)
+		_, err = repo.FindByHash("b")
+		must(t, err)
+	})
+}
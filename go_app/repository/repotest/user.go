@@ -0,0 +1,275 @@
+package repotest
+
+import (
+	"testing"
+	"time"
+
+	"go_app/models"
+	"go_app/repository"
+)
+
+// TestUserRepository 用户仓储行为测试，newRepo 每次返回一个空的仓储
+func TestUserRepository(t *testing.T, newRepo func(t *testing.T) repository.UserRepository) {
+	newUser := func(name string) *models.User {
+		return &models.User{Username: name, Email: name + "@example.com", Password: "hash"}
+	}
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		repo := newRepo(t)
+		user := newUser("alice")
+		must(t, repo.Create(user))
+		if user.ID == 0 || user.CreatedAt.IsZero() {
+			t.Fatalf("Create 未填充 ID 和创建时间: %+v", user)
+		}
+
+		byID, err := repo.FindByID(user.ID)
+		must(t, err)
+		if byID.Email != user.Email || byID.Password != "hash" {
+			t.Fatalf("FindByID = %+v", byID)
+		}
+		byEmail, err := repo.FindByEmail("alice@example.com")
+		must(t, err)
+		if byEmail.ID != user.ID {
+			t.Fatalf("FindByEmail ID = %d, want %d", byEmail.ID, user.ID)
+		}
+
+		exists, err := repo.EmailExists("alice@example.com")
+		must(t, err)
+		if !exists {
+			t.Fatal("EmailExists = false, want true")
+		}
+		exists, err = repo.EmailExists("bob@example.com")
+		must(t, err)
+		if exists {
+			t.Fatal("EmailExists = true, want false")
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := newRepo(t)
+		_, err := repo.FindByID(42)
+		expectErr(t, err, repository.ErrNotFound)
+		_, err = repo.FindByEmail("nobody@example.com")
+		expectErr(t, err, repository.ErrNotFound)
+		missing := newUser("missing")
+		missing.ID = 42
+		expectErr(t, repo.Update(missing, "username"), repository.ErrNotFound)
+		expectErr(t, repo.UpdatePassword(42, "hash"), repository.ErrNotFound)
+		expectErr(t, repo.SetDisabled(42, nil), repository.ErrNotFound)
+		expectErr(t, repo.SetPendingEmail(42, "new@example.com"), repository.ErrNotFound)
+		expectErr(t, repo.VerifyEmail(42, "new@example.com", time.Now()), repository.ErrNotFound)
+		expectErr(t, repo.Delete(42), repository.ErrNotFound)
+	})
+
+	t.Run("DuplicateEmail", func(t *testing.T) {
+		repo := newRepo(t)
+		must(t, repo.Create(newUser("alice")))
+		expectErr(t, repo.Create(newUser("alice")), repository.ErrDuplicate)
+
+		bob := newUser("bob")
+		must(t, repo.Create(bob))
+		bob.Email = "alice@example.com"
+		expectErr(t, repo.Update(bob, "email"), repository.ErrDuplicate)
+	})
+
+	t.Run("ReturnsCopies", func(t *testing.T) {
+		repo := newRepo(t)
+		user := newUser("alice")
+		must(t, repo.Create(user))
+		user.Username = "changed"
+
+		found, err := repo.FindByID(user.ID)
+		must(t, err)
+		found.Username = "changed again"
+
+		found, err = repo.FindByID(user.ID)
+		must(t, err)
+		if found.Username != "alice" {
+			t.Fatalf("未调用 Update 的修改不应生效，Username = %q", found.Username)
+		}
+	})
+
+	t.Run("UpdateOnlySelectedFields", func(t *testing.T) {
+		repo := newRepo(t)
+		user := newUser("alice")
+		user.Gender = "female"
+		must(t, repo.Create(user))
+
+		update := *user
+		update.Username = "alice2"
+		update.Gender = "other"
+		update.Password = "ignored"
+		must(t, repo.Update(&update, "username"))
+
+		found, err := repo.FindByID(user.ID)
+		must(t, err)
+		if found.Username != "alice2" || found.Gender != "female" || found.Password != "hash" {
+			t.Fatalf("Update 修改了未指定的字段: %+v", found)
+		}
+
+		if err := repo.Update(&update, "password"); err == nil {
+			t.Fatal("Update 不应允许修改 password")
+		}
+		if err := repo.Update(&update); err == nil {
+			t.Fatal("Update 未指定字段时应返回错误")
+		}
+	})
+
+	t.Run("UpdatePasswordBumpsTokenVersion", func(t *testing.T) {
+		repo := newRepo(t)
+		user := newUser("alice")
+		must(t, repo.Create(user))
+
+		must(t, repo.UpdatePassword(user.ID, "new-hash"))
+		must(t, repo.UpdatePassword(user.ID, "newer-hash"))
+
+		found, err := repo.FindByID(user.ID)
+		must(t, err)
+		if found.Password != "newer-hash" || found.TokenVersion != user.TokenVersion+2 {
+			t.Fatalf("Password = %q, TokenVersion = %d", found.Password, found.TokenVersion)
+		}
+	})
+
+	t.Run("SetDisabled", func(t *testing.T) {
+		repo := newRepo(t)
+		user := newUser("alice")
+		must(t, repo.Create(user))
+
+		at := time.Now().Truncate(time.Second)
+		must(t, repo.SetDisabled(user.ID, &at))
+		found, err := repo.FindByID(user.ID)
+		must(t, err)
+		if !found.IsDisabled() || !found.DisabledAt.Equal(at) {
+			t.Fatalf("DisabledAt = %v, want %v", found.DisabledAt, at)
+		}
+
+		must(t, repo.SetDisabled(user.ID, nil))
+		found, err = repo.FindByID(user.ID)
+		must(t, err)
+		if found.IsDisabled() {
+			t.Fatal("解除禁用后 IsDisabled 应为 false")
+		}
+	})
+
+	t.Run("SetLastSeen", func(t *testing.T) {
+		repo := newRepo(t)
+		user := newUser("alice")
+		must(t, repo.Create(user))
+		created, err := repo.FindByID(user.ID)
+		must(t, err)
+
+		at := time.Now().Add(time.Hour).Truncate(time.Second)
+		must(t, repo.SetLastSeen(user.ID, at))
+		found, err := repo.FindByID(user.ID)
+		must(t, err)
+		if found.LastSeenAt == nil || !found.LastSeenAt.Equal(at) {
+			t.Fatalf("LastSeenAt = %v, want %v", found.LastSeenAt, at)
+		}
+		if !found.UpdatedAt.Equal(created.UpdatedAt) {
+			t.Fatalf("SetLastSeen 不应修改 UpdatedAt: %v → %v", created.UpdatedAt, found.UpdatedAt)
+		}
+		expectErr(t, repo.SetLastSeen(999, at), repository.ErrNotFound)
+	})
+
+	t.Run("FindByIDs", func(t *testing.T) {
+		repo := newRepo(t)
+		var ids []uint
+		for _, name := range []string{"a", "b", "c"} {
+			user := newUser(name)
+			must(t, repo.Create(user))
+			ids = append(ids, user.ID)
+		}
+
+		users, err := repo.FindByIDs([]uint{ids[2], 999, ids[0], ids[2]})
+		must(t, err)
+		if len(users) != 2 || users[0].ID != ids[0] || users[1].ID != ids[2] {
+			t.Fatalf("FindByIDs = %+v", users)
+		}
+		users, err = repo.FindByIDs(nil)
+		must(t, err)
+		if len(users) != 0 {
+			t.Fatalf("FindByIDs(nil) = %+v", users)
+		}
+	})
+
+	t.Run("VerifyEmail", func(t *testing.T) {
+		repo := newRepo(t)
+		user := newUser("alice")
+		must(t, repo.Create(user))
+		must(t, repo.Create(newUser("bob")))
+
+		at := time.Now().Truncate(time.Second)
+		must(t, repo.VerifyEmail(user.ID, "alice@example.com", at))
+		found, err := repo.FindByID(user.ID)
+		must(t, err)
+		if !found.IsEmailVerified() || !found.EmailVerifiedAt.Equal(at) {
+			t.Fatalf("EmailVerifiedAt = %v, want %v", found.EmailVerifiedAt, at)
+		}
+
+		// 不是当前邮箱也不是待验证邮箱
+		expectErr(t, repo.VerifyEmail(user.ID, "other@example.com", at), repository.ErrConflict)
+
+		must(t, repo.SetPendingEmail(user.ID, "bob@example.com"))
+		expectErr(t, repo.VerifyEmail(user.ID, "bob@example.com", at), repository.ErrDuplicate)
+
+		must(t, repo.SetPendingEmail(user.ID, "alice2@example.com"))
+		found, err = repo.FindByID(user.ID)
+		must(t, err)
+		if found.Email != "alice@example.com" || found.PendingEmail != "alice2@example.com" {
+			t.Fatalf("验证前不应替换邮箱: %+v", found)
+		}
+		must(t, repo.VerifyEmail(user.ID, "alice2@example.com", at.Add(time.Second)))
+		found, err = repo.FindByID(user.ID)
+		must(t, err)
+		if found.Email != "alice2@example.com" || found.PendingEmail != "" || !found.EmailVerifiedAt.Equal(at.Add(time.Second)) {
+			t.Fatalf("验证新邮箱后 = %+v", found)
+		}
+		_, err = repo.FindByEmail("alice@example.com")
+		expectErr(t, err, repository.ErrNotFound)
+	})
+
+	t.Run("ListPaginates", func(t *testing.T) {
+		repo := newRepo(t)
+		for _, name := range []string{"a", "b", "c", "d", "e"} {
+			must(t, repo.Create(newUser(name)))
+		}
+
+		users, total, err := repo.List(repository.Page{Offset: 2, Limit: 2})
+		must(t, err)
+		if total != 5 || len(users) != 2 || users[0].Username != "c" || users[1].Username != "d" {
+			t.Fatalf("List = %d 条（总数 %d），首条 %+v", len(users), total, users)
+		}
+
+		users, _, err = repo.List(repository.Page{})
+		must(t, err)
+		if len(users) != 5 {
+			t.Fatalf("不分页时应返回全部用户，got %d", len(users))
+		}
+
+		users, _, err = repo.List(repository.Page{Offset: 10, Limit: 2})
+		must(t, err)
+		if len(users) != 0 {
+			t.Fatalf("超出范围的分页应返回空列表，got %d", len(users))
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo := newRepo(t)
+		user := newUser("alice")
+		must(t, repo.Create(user))
+		must(t, repo.Delete(user.ID))
+
+		_, err := repo.FindByID(user.ID)
+		expectErr(t, err, repository.ErrNotFound)
+		exists, err := repo.EmailExists(user.Email)
+		must(t, err)
+		if exists {
+			t.Fatal("已删除用户的邮箱不应计入 EmailExists")
+		}
+		_, total, err := repo.List(repository.Page{})
+		must(t, err)
+		if total != 0 {
+			t.Fatalf("List 总数 = %d, want 0", total)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package repotest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"go_app/models"
+	"go_app/repository"
+)
+
+// TestWebSocketOutboxRepository WebSocket 发件箱仓储行为测试，newRepo 每次返回一个空的仓储
+func TestWebSocketOutboxRepository(t *testing.T, newRepo func(t *testing.T) repository.WebSocketOutboxRepository) {
+	past := time.Now().Add(-time.Hour)
+	appendN := func(t *testing.T, repo repository.WebSocketOutboxRepository, userID uint, n, keep int) []uint64 {
+		t.Helper()
+		var seqs []uint64
+		for i := 0; i < n; i++ {
+			msg := &models.WebSocketMessage{UserID: userID, Type: "notification", Data: `{"n":1}`}
+			must(t, repo.Append(msg, keep, past))
+			if msg.ID == 0 || msg.CreatedAt.IsZero() {
+				t.Fatalf("Append 未填充 ID 和创建时间: %+v", msg)
+			}
+			seqs = append(seqs, msg.Seq)
+		}
+		return seqs
+	}
+	list := func(t *testing.T, repo repository.WebSocketOutboxRepository, userID uint, after uint64, cutoff time.Time, limit int) []uint64 {
+		t.Helper()
+		messages, err := repo.ListAfter(userID, after, cutoff, limit)
+		must(t, err)
+		seqs := make([]uint64, 0, len(messages))
+		for _, m := range messages {
+			seqs = append(seqs, m.Seq)
+		}
+		return seqs
+	}
+	expectSeqs := func(t *testing.T, name string, got []uint64, want ...uint64) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("%s = %v, want %v", name, got, want)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("%s = %v, want %v", name, got, want)
+			}
+		}
+	}
+
+	t.Run("AppendAndList", func(t *testing.T) {
+		repo := newRepo(t)
+		expectSeqs(t, "用户 1 的消息ID", appendN(t, repo, 1, 3, 10), 1, 2, 3)
+		expectSeqs(t, "用户 2 的消息ID", appendN(t, repo, 2, 1, 10), 1)
+
+		messages, err := repo.ListAfter(1, 1, past, 10)
+		must(t, err)
+		if len(messages) != 2 || messages[0].Seq != 2 || messages[0].Type != "notification" || messages[0].Data != `{"n":1}` {
+			t.Fatalf("ListAfter = %+v", messages)
+		}
+		expectSeqs(t, "ListAfter(limit)", list(t, repo, 1, 0, past, 2), 1, 2)
+		expectSeqs(t, "ListAfter(最新之后)", list(t, repo, 1, 3, past, 10))
+		// 早于 cutoff 的消息不返回
+		expectSeqs(t, "ListAfter(cutoff)", list(t, repo, 1, 0, time.Now().Add(time.Hour), 10))
+	})
+
+	t.Run("Trim", func(t *testing.T) {
+		repo := newRepo(t)
+		appendN(t, repo, 1, 5, 3)
+		expectSeqs(t, "超出数量上限后", list(t, repo, 1, 0, past, 10), 3, 4, 5)
+
+		// 过期消息在下次追加时删除，删除全部旧消息后ID继续递增
+		cutoff := time.Now()
+		time.Sleep(10 * time.Millisecond)
+		msg := &models.WebSocketMessage{UserID: 1, Type: "system"}
+		must(t, repo.Append(msg, 3, cutoff))
+		if msg.Seq != 6 {
+			t.Fatalf("Append 分配的ID = %d, want 6", msg.Seq)
+		}
+		expectSeqs(t, "过期消息删除后", list(t, repo, 1, 0, past, 10), 6)
+	})
+
+	t.Run("Cursor", func(t *testing.T) {
+		repo := newRepo(t)
+		cursor, err := repo.Cursor(1)
+		must(t, err)
+		if cursor.UserID != 1 || cursor.LastSeq != 0 || cursor.AckedSeq != 0 {
+			t.Fatalf("没有消息时 Cursor = %+v", cursor)
+		}
+		must(t, repo.Ack(1, 1))
+
+		appendN(t, repo, 1, 3, 10)
+		must(t, repo.Ack(1, 2))
+		must(t, repo.Ack(1, 1)) // 不会回退
+		must(t, repo.Ack(1, 9)) // 超过已分配的ID
+		cursor, err = repo.Cursor(1)
+		must(t, err)
+		if cursor.LastSeq != 3 || cursor.AckedSeq != 2 {
+			t.Fatalf("Cursor = %+v, want LastSeq 3 AckedSeq 2", cursor)
+		}
+	})
+
+	t.Run("ConcurrentAppend", func(t *testing.T) {
+		repo := newRepo(t)
+		var (
+			wg   sync.WaitGroup
+			mu   sync.Mutex
+			seen = make(map[uint64]bool)
+		)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				msg := &models.WebSocketMessage{UserID: 1, Type: "notification"}
+				if err := repo.Append(msg, 100, past); err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				seen[msg.Seq] = true
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+		for seq := uint64(1); seq <= 20; seq++ {
+			if !seen[seq] {
+				t.Fatalf("并发追加的消息ID不连续: %v", seen)
+			}
+		}
+	})
+}
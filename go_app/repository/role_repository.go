@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"go_app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RoleRepository 角色（roles、role_permissions）及用户角色（user_roles）数据访问，
+// 返回的角色均已填充 Permissions
+type RoleRepository interface {
+	// List 按 ID 升序返回全部角色
+	List() ([]*models.Role, error)
+	FindByName(name string) (*models.Role, error)
+	// ListByUser 按 ID 升序返回用户拥有的角色
+	ListByUser(userID uint) ([]*models.Role, error)
+	// Permissions 返回用户全部角色的权限并集，按字母序排列
+	Permissions(userID uint) ([]string, error)
+	// Assign 为用户分配角色，已分配时直接忽略
+	Assign(userID, roleID uint) error
+	// Revoke 移除用户的角色，未分配时直接忽略
+	Revoke(userID, roleID uint) error
+	// DeleteByUser 移除用户的全部角色
+	DeleteByUser(userID uint) error
+	// CountUsers 统计拥有该角色的用户数
+	CountUsers(roleID uint) (int64, error)
+}
+
+type gormRoleRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &gormRoleRepository{db: db}
+}
+
+func (r *gormRoleRepository) List() ([]*models.Role, error) {
+	var roles []*models.Role
+	if err := r.db.Order("id").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, r.loadPermissions(roles)
+}
+
+func (r *gormRoleRepository) FindByName(name string) (*models.Role, error) {
+	var role models.Role
+	if err := r.db.Where("name = ?", name).First(&role).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &role, r.loadPermissions([]*models.Role{&role})
+}
+
+func (r *gormRoleRepository) ListByUser(userID uint) ([]*models.Role, error) {
+	var roles []*models.Role
+	err := r.db.Where("id IN (?)", r.db.Model(&models.UserRole{}).Select("role_id").Where("user_id = ?", userID)).
+		Order("id").
+		Find(&roles).Error
+	if err != nil {
+		return nil, err
+	}
+	return roles, r.loadPermissions(roles)
+}
+
+func (r *gormRoleRepository) Permissions(userID uint) ([]string, error) {
+	var permissions []string
+	err := r.db.Model(&models.RolePermission{}).
+		Distinct("permission").
+		Where("role_id IN (?)", r.db.Model(&models.UserRole{}).Select("role_id").Where("user_id = ?", userID)).
+		Order("permission").
+		Pluck("permission", &permissions).Error
+	return permissions, err
+}
+
+func (r *gormRoleRepository) Assign(userID, roleID uint) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.UserRole{UserID: userID, RoleID: roleID}).Error
+}
+
+func (r *gormRoleRepository) Revoke(userID, roleID uint) error {
+	return r.db.Where("user_id = ? AND role_id = ?", userID, roleID).Delete(&models.UserRole{}).Error
+}
+
+func (r *gormRoleRepository) DeleteByUser(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.UserRole{}).Error
+}
+
+func (r *gormRoleRepository) CountUsers(roleID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.UserRole{}).Where("role_id = ?", roleID).Count(&count).Error
+	return count, err
+}
+
+// loadPermissions 一次查询填充多个角色的权限列表
+func (r *gormRoleRepository) loadPermissions(roles []*models.Role) error {
+	if len(roles) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(roles))
+	byID := make(map[uint]*models.Role, len(roles))
+	for _, role := range roles {
+		role.Permissions = []string{}
+		ids = append(ids, role.ID)
+		byID[role.ID] = role
+	}
+
+	var rows []models.RolePermission
+	if err := r.db.Where("role_id IN ?", ids).Order("permission").Find(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		byID[row.RoleID].Permissions = append(byID[row.RoleID].Permissions, row.Permission)
+	}
+	return nil
+}
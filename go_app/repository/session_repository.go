@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"time"
+
+	"go_app/models"
+
+	"gorm.io/gorm"
+)
+
+// SessionReplace 新会话需要替换的旧会话范围，该用户已过期的会话总是一并清理
+type SessionReplace struct {
+	All      bool   // 替换该用户的全部会话
+	Platform string // 替换同平台的会话
+	DeviceID string // 与 Platform 同时指定时只替换同平台同设备的会话
+}
+
+// matches 判断旧会话是否在替换范围内
+func (r SessionReplace) matches(session *models.UserToken, now time.Time) bool {
+	switch {
+	case r.All:
+		return true
+	case session.ExpiredAt.Before(now):
+		return true
+	case r.Platform == "":
+		return false
+	case r.DeviceID != "":
+		return session.Platform == r.Platform && session.DeviceID == r.DeviceID
+	}
+	return session.Platform == r.Platform
+}
+
+// SessionRepository 登录会话（user_tokens）及刷新令牌（refresh_tokens）数据访问
+type SessionRepository interface {
+	// Create 在同一事务中清理被替换的旧会话、创建新会话及其首个刷新令牌，
+	// refresh.SessionID 和 refresh.UserID 由新会话填充
+	Create(session *models.UserToken, refresh *models.RefreshToken, replace SessionReplace) error
+	FindByID(id uint) (*models.UserToken, error)
+	// FindByToken 按会话标识（访问令牌 jti）查找会话
+	FindByToken(token string) (*models.UserToken, error)
+	// ListActive 按最近活跃时间倒序返回用户未过期的会话
+	ListActive(userID uint) ([]models.UserToken, error)
+	// Touch 更新会话最近活跃时间
+	Touch(id uint, at time.Time) error
+	// Delete 删除会话及其名下全部刷新令牌，不存在的会话直接忽略
+	Delete(ids ...uint) error
+	// DeleteByUser 删除用户的全部会话及刷新令牌
+	DeleteByUser(userID uint) error
+
+	// FindRefreshToken 按哈希值查找刷新令牌
+	FindRefreshToken(tokenHash string) (*models.RefreshToken, error)
+	// Rotate 在同一事务中将刷新令牌 usedID 标记为已使用、按 session 更新会话的活跃时间和有效期，
+	// 并保存新的刷新令牌；刷新令牌已被使用时返回 ErrConflict
+	Rotate(usedID uint, session *models.UserToken, next *models.RefreshToken) error
+}
+
+type gormSessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &gormSessionRepository{db: db}
+}
+
+func (r *gormSessionRepository) Create(session *models.UserToken, refresh *models.RefreshToken, replace SessionReplace) error {
+	return translate(r.db.Transaction(func(tx *gorm.DB) error {
+		if err := r.evict(tx, session.UserID, replace); err != nil {
+			return err
+		}
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+		refresh.UserID = session.UserID
+		refresh.SessionID = session.ID
+		return tx.Create(refresh).Error
+	}))
+}
+
+// evict 删除被新会话替换的旧会话
+func (r *gormSessionRepository) evict(tx *gorm.DB, userID uint, replace SessionReplace) error {
+	now := time.Now()
+	query := tx.Model(&models.UserToken{}).Where("user_id = ?", userID)
+	switch {
+	case replace.All:
+	case replace.Platform == "":
+		query = query.Where("expired_at < ?", now)
+	case replace.DeviceID != "":
+		query = query.Where("((platform = ? AND device_id = ?) OR expired_at < ?)", replace.Platform, replace.DeviceID, now)
+	default:
+		query = query.Where("(platform = ? OR expired_at < ?)", replace.Platform, now)
+	}
+
+	var ids []uint
+	if err := query.Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	return deleteSessions(tx, ids...)
+}
+
+func (r *gormSessionRepository) FindByID(id uint) (*models.UserToken, error) {
+	var session models.UserToken
+	if err := r.db.First(&session, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &session, nil
+}
+
+func (r *gormSessionRepository) FindByToken(token string) (*models.UserToken, error) {
+	var session models.UserToken
+	if err := r.db.Where("token = ?", token).First(&session).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &session, nil
+}
+
+func (r *gormSessionRepository) ListActive(userID uint) ([]models.UserToken, error) {
+	var sessions []models.UserToken
+	err := r.db.Where("user_id = ? AND expired_at > ?", userID, time.Now()).
+		Order("last_active_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *gormSessionRepository) Touch(id uint, at time.Time) error {
+	return r.db.Model(&models.UserToken{}).Where("id = ?", id).UpdateColumn("last_active_at", at).Error
+}
+
+func (r *gormSessionRepository) Delete(ids ...uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return deleteSessions(tx, ids...)
+	})
+}
+
+func (r *gormSessionRepository) DeleteByUser(userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RefreshToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.UserToken{}).Error
+	})
+}
+
+func (r *gormSessionRepository) FindRefreshToken(tokenHash string) (*models.RefreshToken, error) {
+	var rt models.RefreshToken
+	if err := r.db.Where("token_hash = ?", tokenHash).First(&rt).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &rt, nil
+}
+
+func (r *gormSessionRepository) Rotate(usedID uint, session *models.UserToken, next *models.RefreshToken) error {
+	return translate(r.db.Transaction(func(tx *gorm.DB) error {
+		// 条件更新保证同一刷新令牌只能被成功使用一次
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", usedID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrConflict
+		}
+
+		result = tx.Model(&models.UserToken{}).Where("id = ?", session.ID).Updates(map[string]interface{}{
+			"last_active_at": session.LastActiveAt,
+			"expired_at":     session.ExpiredAt,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		next.UserID = session.UserID
+		next.SessionID = session.ID
+		return tx.Create(next).Error
+	}))
+}
+
+// deleteSessions 删除会话及其名下全部刷新令牌
+func deleteSessions(tx *gorm.DB, ids ...uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := tx.Where("session_id IN ?", ids).Delete(&models.RefreshToken{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("id IN ?", ids).Delete(&models.UserToken{}).Error
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"time"
+
+	"go_app/models"
+
+	"gorm.io/gorm"
+)
+
+// TwoFactorChallengeRepository 两步登录挑战令牌数据访问
+type TwoFactorChallengeRepository interface {
+	// Create 保存新令牌并清理该用户已过期的令牌，同一用户可以同时在多个设备上进行两步登录
+	Create(challenge *models.TwoFactorChallenge) error
+	// FindByHash 按哈希值查找令牌
+	FindByHash(tokenHash string) (*models.TwoFactorChallenge, error)
+	// Attempt 在校验验证码之前占用一次验证次数，令牌不存在、已被使用或次数已达 maxAttempts 时返回 ErrConflict。
+	// 条件更新保证并发请求不会超过次数上限
+	Attempt(id uint, maxAttempts int) error
+	// Consume 将令牌标记为已使用，令牌已被使用或次数超过 maxAttempts 时返回 ErrConflict
+	Consume(id uint, maxAttempts int) error
+	// DeleteByUser 删除用户的全部令牌
+	DeleteByUser(userID uint) error
+}
+
+type gormTwoFactorChallengeRepository struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorChallengeRepository(db *gorm.DB) TwoFactorChallengeRepository {
+	return &gormTwoFactorChallengeRepository{db: db}
+}
+
+func (r *gormTwoFactorChallengeRepository) Create(challenge *models.TwoFactorChallenge) error {
+	return translate(r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND expired_at < ?", challenge.UserID, time.Now()).Delete(&models.TwoFactorChallenge{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(challenge).Error
+	}))
+}
+
+func (r *gormTwoFactorChallengeRepository) FindByHash(tokenHash string) (*models.TwoFactorChallenge, error) {
+	var challenge models.TwoFactorChallenge
+	if err := r.db.Where("token_hash = ?", tokenHash).First(&challenge).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &challenge, nil
+}
+
+func (r *gormTwoFactorChallengeRepository) Attempt(id uint, maxAttempts int) error {
+	result := r.db.Model(&models.TwoFactorChallenge{}).
+		Where("id = ? AND used_at IS NULL AND attempts < ?", id, maxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r *gormTwoFactorChallengeRepository) Consume(id uint, maxAttempts int) error {
+	// 条件更新保证同一令牌只能被成功使用一次
+	result := r.db.Model(&models.TwoFactorChallenge{}).
+		Where("id = ? AND used_at IS NULL AND attempts <= ?", id, maxAttempts).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r *gormTwoFactorChallengeRepository) DeleteByUser(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.TwoFactorChallenge{}).Error
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"time"
+
+	"go_app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TwoFactorRepository 两步验证配置及恢复码数据访问
+type TwoFactorRepository interface {
+	// Find 获取用户的两步验证配置，未绑定时返回 ErrNotFound
+	Find(userID uint) (*models.TwoFactor, error)
+	// Save 保存新绑定的密钥，覆盖该用户之前的配置，两步验证变为未确认状态
+	Save(twoFactor *models.TwoFactor) error
+	// Enable 确认两步验证并替换全部恢复码，已确认或未绑定时返回 ErrConflict
+	Enable(userID uint, at time.Time, codeHashes []string) error
+	// Attempt 在校验验证码之前占用一次次数，最近一次占用早于 since 时重新计数；
+	// 次数已达 maxAttempts 时返回 ErrConflict，未绑定时返回 ErrNotFound
+	Attempt(userID uint, maxAttempts int, since, at time.Time) error
+	// ResetAttempts 验证码校验通过后清零次数
+	ResetAttempts(userID uint) error
+	// UseStep 记录成功使用的验证码时间步，step 不大于上次使用的时间步时返回 ErrConflict
+	UseStep(userID uint, step int64) error
+	// ReplaceRecoveryCodes 删除用户的全部恢复码并保存新的恢复码
+	ReplaceRecoveryCodes(userID uint, codeHashes []string) error
+	// UseRecoveryCode 将恢复码标记为已使用，不存在或已使用时返回 ErrNotFound
+	UseRecoveryCode(userID uint, codeHash string) error
+	// CountRecoveryCodes 统计用户未使用的恢复码
+	CountRecoveryCodes(userID uint) (int64, error)
+	// Delete 删除用户的两步验证配置和全部恢复码
+	Delete(userID uint) error
+}
+
+type gormTwoFactorRepository struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorRepository(db *gorm.DB) TwoFactorRepository {
+	return &gormTwoFactorRepository{db: db}
+}
+
+func (r *gormTwoFactorRepository) Find(userID uint) (*models.TwoFactor, error) {
+	// 大多数用户没有启用两步验证，使用 Find 避免每次登录都记录 record not found 日志
+	var twoFactor models.TwoFactor
+	result := r.db.Where("user_id = ?", userID).Limit(1).Find(&twoFactor)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return &twoFactor, nil
+}
+
+func (r *gormTwoFactorRepository) Save(twoFactor *models.TwoFactor) error {
+	twoFactor.ConfirmedAt = nil
+	twoFactor.LastUsedStep = 0
+	twoFactor.Attempts = 0
+	twoFactor.AttemptedAt = nil
+	return translate(r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"secret", "confirmed_at", "last_used_step", "attempts", "attempted_at", "created_at", "updated_at"}),
+	}).Create(twoFactor).Error)
+}
+
+func (r *gormTwoFactorRepository) Enable(userID uint, at time.Time, codeHashes []string) error {
+	return translate(r.db.Transaction(func(tx *gorm.DB) error {
+		// 条件更新保证并发确认时只有一次成功
+		result := tx.Model(&models.TwoFactor{}).
+			Where("user_id = ? AND confirmed_at IS NULL", userID).
+			Update("confirmed_at", at)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrConflict
+		}
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	}))
+}
+
+func (r *gormTwoFactorRepository) Attempt(userID uint, maxAttempts int, since, at time.Time) error {
+	// 条件更新保证并发请求不会超过次数上限
+	result := r.db.Model(&models.TwoFactor{}).
+		Where("user_id = ? AND (attempts < ? OR attempted_at IS NULL OR attempted_at < ?)", userID, maxAttempts, since).
+		Updates(map[string]interface{}{
+			"attempts":     gorm.Expr("CASE WHEN attempted_at IS NULL OR attempted_at < ? THEN 1 ELSE attempts + 1 END", since),
+			"attempted_at": at,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := r.db.Model(&models.TwoFactor{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrNotFound
+		}
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r *gormTwoFactorRepository) ResetAttempts(userID uint) error {
+	return r.db.Model(&models.TwoFactor{}).
+		Where("user_id = ? AND attempts > 0", userID).
+		Update("attempts", 0).Error
+}
+
+func (r *gormTwoFactorRepository) UseStep(userID uint, step int64) error {
+	result := r.db.Model(&models.TwoFactor{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r *gormTwoFactorRepository) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	return translate(r.db.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	}))
+}
+
+func (r *gormTwoFactorRepository) UseRecoveryCode(userID uint, codeHash string) error {
+	result := r.db.Model(&models.TwoFactorRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormTwoFactorRepository) CountRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.TwoFactorRecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *gormTwoFactorRepository) Delete(userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.TwoFactorRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.TwoFactor{}).Error
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.TwoFactorRecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if len(codeHashes) == 0 {
+		return nil
+	}
+	codes := make([]models.TwoFactorRecoveryCode, 0, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes = append(codes, models.TwoFactorRecoveryCode{UserID: userID, CodeHash: hash})
+	}
+	return tx.Create(&codes).Error
+}
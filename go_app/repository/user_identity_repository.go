@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"time"
+
+	"go_app/models"
+
+	"gorm.io/gorm"
+)
+
+// UserIdentityRepository 第三方账号关联数据访问
+type UserIdentityRepository interface {
+	// Create 关联第三方账号，该提供方的账号已关联时返回 ErrDuplicate
+	Create(identity *models.UserIdentity) error
+	// FindBySubject 按提供方和第三方账号标识查找关联
+	FindBySubject(provider, subject string) (*models.UserIdentity, error)
+	// ListByUser 按关联时间列出用户关联的全部第三方账号
+	ListByUser(userID uint) ([]*models.UserIdentity, error)
+	// RecordLogin 登录成功后更新邮箱和最近登录时间
+	RecordLogin(id uint, email string, at time.Time) error
+	// Delete 解除关联，不属于该用户时返回 ErrNotFound
+	Delete(userID, id uint) error
+}
+
+type gormUserIdentityRepository struct {
+	db *gorm.DB
+}
+
+func NewUserIdentityRepository(db *gorm.DB) UserIdentityRepository {
+	return &gormUserIdentityRepository{db: db}
+}
+
+func (r *gormUserIdentityRepository) Create(identity *models.UserIdentity) error {
+	return translate(r.db.Create(identity).Error)
+}
+
+func (r *gormUserIdentityRepository) FindBySubject(provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	if err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &identity, nil
+}
+
+func (r *gormUserIdentityRepository) ListByUser(userID uint) ([]*models.UserIdentity, error) {
+	var identities []*models.UserIdentity
+	err := r.db.Where("user_id = ?", userID).Order("id").Find(&identities).Error
+	return identities, err
+}
+
+func (r *gormUserIdentityRepository) RecordLogin(id uint, email string, at time.Time) error {
+	result := r.db.Model(&models.UserIdentity{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"email":         email,
+		"last_login_at": at,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormUserIdentityRepository) Delete(userID, id uint) error {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.UserIdentity{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
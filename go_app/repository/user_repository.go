@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"time"
+
+	"go_app/models"
+
+	"gorm.io/gorm"
+)
+
+// UserRepository 用户数据访问
+type UserRepository interface {
+	// Create 创建用户，邮箱已存在时返回 ErrDuplicate
+	Create(user *models.User) error
+	FindByID(id uint) (*models.User, error)
+	FindByEmail(email string) (*models.User, error)
+	// FindByIDs 按 ID 升序返回存在的用户，不存在的 ID 忽略
+	FindByIDs(ids []uint) ([]*models.User, error)
+	EmailExists(email string) (bool, error)
+	// List 按 ID 升序分页获取用户，同时返回总数
+	List(page Page) ([]*models.User, int64, error)
+	// Update 只更新 fields 指定的列（如 "username"、"avatar_url"），用户不存在时返回 ErrNotFound
+	Update(user *models.User, fields ...string) error
+	// UpdatePassword 更新密码哈希并递增 token 版本，使已签发的令牌全部失效
+	UpdatePassword(id uint, hashedPassword string) error
+	// SetDisabled 设置禁用时间，nil 表示解除禁用，用户不存在时返回 ErrNotFound
+	SetDisabled(id uint, disabledAt *time.Time) error
+	// SetPendingEmail 设置待验证的新邮箱，空字符串表示取消修改，用户不存在时返回 ErrNotFound
+	SetPendingEmail(id uint, email string) error
+	// VerifyEmail 将 email 标记为已验证：email 为当前邮箱时只记录验证时间；
+	// 为待验证的新邮箱时替换当前邮箱并清空待验证邮箱，新邮箱已被其他用户使用时返回 ErrDuplicate；
+	// 与两者都不符（如已再次修改）时返回 ErrConflict
+	VerifyEmail(id uint, email string, at time.Time) error
+	// SetLastSeen 更新最后在线时间，不修改 updated_at，用户不存在时返回 ErrNotFound
+	SetLastSeen(id uint, at time.Time) error
+	Delete(id uint) error
+}
+
+// UserUpdatableFields 允许通过 Update 修改的列
+var UserUpdatableFields = []string{"username", "email", "avatar_url", "birthday", "gender", "hobbies"}
+
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return &gormUserRepository{db: db}
+}
+
+func (r *gormUserRepository) Create(user *models.User) error {
+	return translate(r.db.Create(user).Error)
+}
+
+func (r *gormUserRepository) FindByID(id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.First(&user, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) FindByEmail(email string) (*models.User, error) {
+	var user models.User
+	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) FindByIDs(ids []uint) ([]*models.User, error) {
+	users := []*models.User{}
+	if len(ids) == 0 {
+		return users, nil
+	}
+	if err := r.db.Where("id IN ?", ids).Order("id").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *gormUserRepository) EmailExists(email string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).Where("email = ?", email).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *gormUserRepository) List(page Page) ([]*models.User, int64, error) {
+	var total int64
+	if err := r.db.Model(&models.User{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []*models.User
+	if err := page.apply(r.db.Order("id")).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+func (r *gormUserRepository) Update(user *models.User, fields ...string) error {
+	if err := checkFields(fields, UserUpdatableFields); err != nil {
+		return err
+	}
+	result := r.db.Model(user).Select(append(fields, "updated_at")).Updates(user)
+	if result.Error != nil {
+		return translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormUserRepository) UpdatePassword(id uint, hashedPassword string) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"password":      hashedPassword,
+		"token_version": gorm.Expr("token_version + 1"),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormUserRepository) SetDisabled(id uint, disabledAt *time.Time) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", id).Update("disabled_at", disabledAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormUserRepository) SetPendingEmail(id uint, email string) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", id).Update("pending_email", email)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormUserRepository) VerifyEmail(id uint, email string, at time.Time) error {
+	result := r.db.Model(&models.User{}).Where("id = ? AND email = ?", id, email).Update("email_verified_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	result = r.db.Model(&models.User{}).Where("id = ? AND pending_email = ?", id, email).Updates(map[string]interface{}{
+		"email":             email,
+		"pending_email":     "",
+		"email_verified_at": at,
+	})
+	if result.Error != nil {
+		return translate(result.Error)
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+	if _, err := r.FindByID(id); err != nil {
+		return err
+	}
+	return ErrConflict
+}
+
+func (r *gormUserRepository) SetLastSeen(id uint, at time.Time) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", id).UpdateColumn("last_seen_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormUserRepository) Delete(id uint) error {
+	result := r.db.Delete(&models.User{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
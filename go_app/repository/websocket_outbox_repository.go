@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"time"
+
+	"go_app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WebSocketOutboxRepository 用户 WebSocket 发件箱数据访问
+type WebSocketOutboxRepository interface {
+	// Append 为消息分配用户内单调递增的消息ID（Seq）并保存，
+	// 同时删除该用户最近 keep 条以外或早于 cutoff 的消息。删除旧消息不影响之后分配的ID
+	Append(msg *models.WebSocketMessage, keep int, cutoff time.Time) error
+	// ListAfter 按消息ID升序返回用户ID大于 afterSeq 且不早于 cutoff 的消息，最多 limit 条
+	ListAfter(userID uint, afterSeq uint64, cutoff time.Time, limit int) ([]models.WebSocketMessage, error)
+	// Cursor 返回用户的消息序号，用户没有收到过消息时返回零值
+	Cursor(userID uint) (*models.WebSocketCursor, error)
+	// Ack 记录客户端确认的消息ID，小于等于已确认ID或大于已分配ID时忽略
+	Ack(userID uint, seq uint64) error
+}
+
+type gormWebSocketOutboxRepository struct {
+	db *gorm.DB
+}
+
+func NewWebSocketOutboxRepository(db *gorm.DB) WebSocketOutboxRepository {
+	return &gormWebSocketOutboxRepository{db: db}
+}
+
+func (r *gormWebSocketOutboxRepository) Append(msg *models.WebSocketMessage, keep int, cutoff time.Time) error {
+	return translate(r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.WebSocketCursor{UserID: msg.UserID}).Error; err != nil {
+			return err
+		}
+		// 自增更新锁住用户的序号行，并发追加的消息依次分配ID
+		if err := tx.Model(&models.WebSocketCursor{}).Where("user_id = ?", msg.UserID).
+			Updates(map[string]interface{}{"last_seq": gorm.Expr("last_seq + 1"), "updated_at": time.Now()}).Error; err != nil {
+			return err
+		}
+		var cursor models.WebSocketCursor
+		if err := tx.First(&cursor, "user_id = ?", msg.UserID).Error; err != nil {
+			return err
+		}
+
+		msg.Seq = cursor.LastSeq
+		if err := tx.Create(msg).Error; err != nil {
+			return err
+		}
+		query := tx.Where("user_id = ?", msg.UserID)
+		if msg.Seq > uint64(keep) {
+			query = query.Where("(seq <= ? OR created_at < ?)", msg.Seq-uint64(keep), cutoff)
+		} else {
+			query = query.Where("created_at < ?", cutoff)
+		}
+		return query.Delete(&models.WebSocketMessage{}).Error
+	}))
+}
+
+func (r *gormWebSocketOutboxRepository) ListAfter(userID uint, afterSeq uint64, cutoff time.Time, limit int) ([]models.WebSocketMessage, error) {
+	var messages []models.WebSocketMessage
+	err := r.db.Where("user_id = ? AND seq > ? AND created_at >= ?", userID, afterSeq, cutoff).
+		Order("seq").Limit(limit).Find(&messages).Error
+	return messages, err
+}
+
+func (r *gormWebSocketOutboxRepository) Cursor(userID uint) (*models.WebSocketCursor, error) {
+	var cursor models.WebSocketCursor
+	err := r.db.Where("user_id = ?", userID).Limit(1).Find(&cursor).Error
+	if err != nil {
+		return nil, err
+	}
+	cursor.UserID = userID
+	return &cursor, nil
+}
+
+func (r *gormWebSocketOutboxRepository) Ack(userID uint, seq uint64) error {
+	// 条件更新保证并发确认时已确认ID只增不减
+	return r.db.Model(&models.WebSocketCursor{}).
+		Where("user_id = ? AND acked_seq < ? AND last_seq >= ?", userID, seq, seq).
+		Updates(map[string]interface{}{"acked_seq": seq, "updated_at": time.Now()}).Error
+}
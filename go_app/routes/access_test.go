@@ -0,0 +1,461 @@
+package routes_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go_app/config"
+	"go_app/controllers"
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/pkg/mailer"
+	"go_app/pkg/ratelimit"
+	"go_app/pkg/websocket"
+	"go_app/repository"
+	"go_app/routes"
+	"go_app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 发起请求的用户，目标数据均属于 Owner
+const (
+	Anonymous = "anonymous" // 未携带 token
+	Owner     = "owner"     // 目标数据的所有者，普通用户
+	User      = "user"      // 其他普通用户
+	Moderator = "moderator"
+	Admin     = "admin"
+)
+
+// Actors 矩阵中的全部请求方
+var Actors = []string{Anonymous, Owner, User, Moderator, Admin}
+
+var (
+	public        = Actors
+	authenticated = []string{Owner, User, Moderator, Admin}
+)
+
+// Route 矩阵中的一个路由，Allowed 之外的请求方应被拒绝：
+// 未登录返回 errcode.TokenMissing，已登录返回 errcode.Forbidden
+type Route struct {
+	Method  string
+	Path    string
+	Allowed []string
+	// Request 构造请求，target 为目标用户（Owner）的ID，targetSession 为其会话ID
+	Request func(target, targetSession uint) *http.Request
+}
+
+// Matrix 与 routes.SetupRoutes 对应的访问控制矩阵
+func Matrix() []Route {
+	return []Route{
+		{"POST", "/api/register", public, jsonBody("POST", "/api/register", func(uint, uint) interface{} {
+			return models.RegisterRequest{Username: "new", Email: "new@example.com", Password: "123456"}
+		})},
+		{"POST", "/api/login", public, jsonBody("POST", "/api/login", func(uint, uint) interface{} {
+			return models.LoginRequest{Email: "owner@example.com", Password: "wrong-password"}
+		})},
+		{"POST", "/api/login/2fa", public, jsonBody("POST", "/api/login/2fa", func(uint, uint) interface{} {
+			return models.TwoFactorLoginRequest{ChallengeToken: "invalid", Code: "123456"}
+		})},
+		{"POST", "/api/login/passkey/begin", public, jsonBody("POST", "/api/login/passkey/begin", nil)},
+		{"POST", "/api/login/passkey", public, jsonBody("POST", "/api/login/passkey", func(uint, uint) interface{} {
+			return models.PasskeyLoginRequest{CeremonyToken: "invalid", Credential: []byte(`{}`)}
+		})},
+		{"GET", "/api/login/oidc/providers", public, query("/api/login/oidc/providers", nil)},
+		{"POST", "/api/login/oidc/begin", public, jsonBody("POST", "/api/login/oidc/begin", func(uint, uint) interface{} {
+			return models.OIDCBeginRequest{Provider: "unknown"}
+		})},
+		{"POST", "/api/login/oidc", public, jsonBody("POST", "/api/login/oidc", func(uint, uint) interface{} {
+			return models.OIDCLoginRequest{State: "invalid", Code: "invalid"}
+		})},
+		{"POST", "/api/token/refresh", public, jsonBody("POST", "/api/token/refresh", func(uint, uint) interface{} {
+			return models.RefreshTokenRequest{RefreshToken: "invalid"}
+		})},
+		{"POST", "/api/password/forgot", public, jsonBody("POST", "/api/password/forgot", func(uint, uint) interface{} {
+			return models.PasswordForgotRequest{Email: "owner@example.com"}
+		})},
+		{"POST", "/api/password/reset", public, jsonBody("POST", "/api/password/reset", func(uint, uint) interface{} {
+			return models.PasswordResetRequest{Token: "invalid", NewPassword: "654321"}
+		})},
+		{"POST", "/api/email/verify", public, jsonBody("POST", "/api/email/verify", func(uint, uint) interface{} {
+			return models.EmailVerifyRequest{Token: "invalid"}
+		})},
+
+		{"GET", "/api/users", []string{Moderator, Admin}, query("/api/users", nil)},
+		{"GET", "/api/users/info", []string{Owner, Moderator, Admin}, query("/api/users/info", func(target, _ uint) string {
+			return fmt.Sprintf("userId=%d", target)
+		})},
+		{"GET", "/api/users/presence", authenticated, query("/api/users/presence", func(target, _ uint) string {
+			return fmt.Sprintf("ids=%d", target)
+		})},
+		{"POST", "/api/users/update", []string{Owner, Admin}, jsonBody("POST", "/api/users/update", func(target, _ uint) interface{} {
+			return models.UserUpdateRequest{UserID: target, Username: "renamed"}
+		})},
+		{"POST", "/api/users/delete", []string{Owner, Admin}, jsonBody("POST", "/api/users/delete", func(target, _ uint) interface{} {
+			return models.UserIDRequest{UserID: target}
+		})},
+		{"POST", "/api/users/email", []string{Owner}, jsonBody("POST", "/api/users/email", func(target, _ uint) interface{} {
+			return models.EmailUpdateRequest{UserID: target, Email: "changed@example.com"}
+		})},
+		// 以下接口只操作当前用户自己的数据
+		{"POST", "/api/users/email/resend", authenticated, jsonBody("POST", "/api/users/email/resend", nil)},
+		{"POST", "/api/users/password", authenticated, jsonBody("POST", "/api/users/password", func(uint, uint) interface{} {
+			return models.PasswordChangeRequest{OldPassword: "wrong-password", NewPassword: "654321"}
+		})},
+		{"POST", "/api/users/logout", authenticated, jsonBody("POST", "/api/users/logout", nil)},
+		{"POST", "/api/users/avatar", []string{Owner, Admin}, avatar},
+		{"GET", "/api/users/sessions", authenticated, query("/api/users/sessions", nil)},
+		{"POST", "/api/users/sessions/revoke", authenticated, jsonBody("POST", "/api/users/sessions/revoke", func(_, session uint) interface{} {
+			return models.SessionRevokeRequest{SessionID: session}
+		})},
+		{"POST", "/api/users/sessions/revoke-all", authenticated, jsonBody("POST", "/api/users/sessions/revoke-all", nil)},
+		{"GET", "/api/users/2fa", authenticated, query("/api/users/2fa", nil)},
+		{"POST", "/api/users/2fa/enroll", authenticated, jsonBody("POST", "/api/users/2fa/enroll", nil)},
+		{"POST", "/api/users/2fa/confirm", authenticated, jsonBody("POST", "/api/users/2fa/confirm", twoFactorCode)},
+		{"POST", "/api/users/2fa/disable", authenticated, jsonBody("POST", "/api/users/2fa/disable", twoFactorCode)},
+		{"POST", "/api/users/2fa/recovery-codes", authenticated, jsonBody("POST", "/api/users/2fa/recovery-codes", twoFactorCode)},
+		{"GET", "/api/users/passkeys", authenticated, query("/api/users/passkeys", nil)},
+		{"POST", "/api/users/passkeys/register/begin", authenticated, jsonBody("POST", "/api/users/passkeys/register/begin", nil)},
+		{"POST", "/api/users/passkeys/register", authenticated, jsonBody("POST", "/api/users/passkeys/register", func(uint, uint) interface{} {
+			return models.PasskeyRegisterRequest{CeremonyToken: "invalid", Credential: []byte(`{}`)}
+		})},
+		{"POST", "/api/users/passkeys/rename", authenticated, jsonBody("POST", "/api/users/passkeys/rename", func(uint, uint) interface{} {
+			return models.PasskeyRenameRequest{ID: 1, Name: "renamed"}
+		})},
+		{"POST", "/api/users/passkeys/delete", authenticated, jsonBody("POST", "/api/users/passkeys/delete", func(uint, uint) interface{} {
+			return models.PasskeyDeleteRequest{ID: 1}
+		})},
+		{"GET", "/api/users/api-keys", authenticated, query("/api/users/api-keys", nil)},
+		{"POST", "/api/users/api-keys/create", authenticated, jsonBody("POST", "/api/users/api-keys/create", func(uint, uint) interface{} {
+			return models.APIKeyCreateRequest{Name: "ci", Scopes: []string{models.APIKeyScopeRead}}
+		})},
+		{"POST", "/api/users/api-keys/revoke", authenticated, jsonBody("POST", "/api/users/api-keys/revoke", func(uint, uint) interface{} {
+			return models.APIKeyRevokeRequest{ID: 1}
+		})},
+		{"GET", "/api/users/identities", authenticated, query("/api/users/identities", nil)},
+		{"POST", "/api/users/identities/unlink", authenticated, jsonBody("POST", "/api/users/identities/unlink", func(uint, uint) interface{} {
+			return models.UserIdentityUnlinkRequest{ID: 1}
+		})},
+		{"GET", "/api/users/oauth/authorize", authenticated, query("/api/users/oauth/authorize", func(uint, uint) string {
+			return "client_id=unknown&redirect_uri=https%3A%2F%2Fapp.example.com%2Fcallback&response_type=code&scope=openid"
+		})},
+		{"POST", "/api/users/oauth/authorize", authenticated, jsonBody("POST", "/api/users/oauth/authorize", func(uint, uint) interface{} {
+			return models.OAuthConsentRequest{OAuthAuthorizeRequest: models.OAuthAuthorizeRequest{ClientID: "unknown", RedirectURI: "https://app.example.com/callback"}}
+		})},
+		{"GET", "/api/users/oauth/consents", authenticated, query("/api/users/oauth/consents", nil)},
+		{"POST", "/api/users/oauth/consents/revoke", authenticated, jsonBody("POST", "/api/users/oauth/consents/revoke", func(uint, uint) interface{} {
+			return models.OAuthClientIDRequest{ClientID: "unknown"}
+		})},
+
+		{"GET", "/api/admin/roles", []string{Admin}, query("/api/admin/roles", nil)},
+		{"GET", "/api/admin/users/roles", []string{Admin}, query("/api/admin/users/roles", func(target, _ uint) string {
+			return fmt.Sprintf("userId=%d", target)
+		})},
+		{"POST", "/api/admin/users/roles/assign", []string{Admin}, jsonBody("POST", "/api/admin/users/roles/assign", func(target, _ uint) interface{} {
+			return models.RoleAssignRequest{UserID: target, Role: models.RoleModerator}
+		})},
+		{"POST", "/api/admin/users/roles/revoke", []string{Admin}, jsonBody("POST", "/api/admin/users/roles/revoke", func(target, _ uint) interface{} {
+			return models.RoleAssignRequest{UserID: target, Role: models.RoleUser}
+		})},
+		{"POST", "/api/admin/users/email", []string{Admin}, jsonBody("POST", "/api/admin/users/email", func(target, _ uint) interface{} {
+			return models.AdminEmailUpdateRequest{UserID: target, Email: "changed@example.com"}
+		})},
+		{"POST", "/api/admin/users/2fa/reset", []string{Admin}, jsonBody("POST", "/api/admin/users/2fa/reset", func(target, _ uint) interface{} {
+			return models.UserIDRequest{UserID: target}
+		})},
+		{"POST", "/api/admin/users/unlock", []string{Admin}, jsonBody("POST", "/api/admin/users/unlock", func(target, _ uint) interface{} {
+			return models.LoginUnlockRequest{UserID: target}
+		})},
+		{"GET", "/api/admin/oauth/clients", []string{Admin}, query("/api/admin/oauth/clients", nil)},
+		{"POST", "/api/admin/oauth/clients/create", []string{Admin}, jsonBody("POST", "/api/admin/oauth/clients/create", func(uint, uint) interface{} {
+			return models.OAuthClientCreateRequest{Name: "app", RedirectURIs: []string{"https://app.example.com/callback"}}
+		})},
+		{"POST", "/api/admin/oauth/clients/delete", []string{Admin}, jsonBody("POST", "/api/admin/oauth/clients/delete", func(uint, uint) interface{} {
+			return models.OAuthClientIDRequest{ClientID: "unknown"}
+		})},
+	}
+}
+
+// TestAccessMatrix 校验每个请求方对每个路由的访问结果，每个请求使用独立的数据，互不影响
+func TestAccessMatrix(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	matrix := Matrix()
+
+	t.Run("Coverage", func(t *testing.T) {
+		listed := make(map[string]bool, len(matrix))
+		for _, route := range matrix {
+			listed[route.Method+" "+route.Path] = true
+		}
+		registered := make(map[string]bool)
+		for _, info := range newApp(t).engine.Routes() {
+			key := info.Method + " " + info.Path
+			registered[key] = true
+			if !listed[key] {
+				t.Errorf("路由 %s 未加入访问控制矩阵", key)
+			}
+		}
+		for key := range listed {
+			if !registered[key] {
+				t.Errorf("矩阵中的路由 %s 未注册", key)
+			}
+		}
+	})
+
+	t.Run("OAuthServerDisabled", func(t *testing.T) {
+		// 授权服务关闭时不注册授权确认和 OAuth 客户端管理接口
+		engine := gin.New()
+		newApp(t).setup(engine.Group("/api"), false)
+		registered := make(map[string]bool)
+		for _, info := range engine.Routes() {
+			registered[info.Method+" "+info.Path] = true
+		}
+		for _, route := range matrix {
+			key := route.Method + " " + route.Path
+			oauth := strings.HasPrefix(route.Path, "/api/users/oauth/") || strings.HasPrefix(route.Path, "/api/admin/oauth/")
+			if registered[key] == oauth {
+				t.Errorf("授权服务关闭时路由 %s 已注册 = %v", key, registered[key])
+			}
+		}
+	})
+
+	for _, route := range matrix {
+		for _, actor := range Actors {
+			route, actor := route, actor
+			t.Run(fmt.Sprintf("%s %s as %s", route.Method, route.Path, actor), func(t *testing.T) {
+				app := newApp(t)
+				req := route.Request(app.users[Owner].ID, app.sessions[Owner])
+				if token := app.tokens[actor]; token != "" {
+					req.Header.Set("Authorization", "Bearer "+token)
+				}
+				w := httptest.NewRecorder()
+				app.engine.ServeHTTP(w, req)
+
+				var resp models.Response
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("解析响应失败: %v, body: %s", err, w.Body.String())
+				}
+
+				allowed := contains(route.Allowed, actor)
+				switch {
+				case allowed && (resp.Code == errcode.Forbidden.Code || resp.Code == errcode.TokenMissing.Code):
+					t.Fatalf("%s 应可以访问，响应: %d %s", actor, resp.Code, resp.Message)
+				case !allowed && actor == Anonymous && resp.Code != errcode.TokenMissing.Code:
+					t.Fatalf("未登录请求应返回 %d，响应: %d %s", errcode.TokenMissing.Code, resp.Code, resp.Message)
+				case !allowed && actor != Anonymous && resp.Code != errcode.Forbidden.Code:
+					t.Fatalf("%s 应被拒绝，响应: %d %s", actor, resp.Code, resp.Message)
+				}
+			})
+		}
+	}
+}
+
+// app 使用内存仓储搭建的完整路由及各请求方的登录状态
+type app struct {
+	engine   *gin.Engine
+	userRepo repository.UserRepository
+	throttle *services.LoginThrottleService
+	limiter  *services.RateLimitService
+	apiKeys  *services.APIKeyService
+	emails   *services.EmailVerificationService
+	mail     *mailer.MemoryMailer
+	oidc     *services.OIDCService
+	oauth    *services.OAuthService
+	// oauthKey 授权服务签发 ID Token 的 Ed25519 公钥
+	oauthKey ed25519.PublicKey
+	// setup 在 api 路由组上注册 SetupRoutes 的全部路由，oauthServer 对应 oauth_server.enabled
+	setup    func(api *gin.RouterGroup, oauthServer bool)
+	users    map[string]*models.User
+	tokens   map[string]string
+	sessions map[string]uint
+}
+
+func newApp(t *testing.T) *app {
+	t.Helper()
+
+	userRepo := repository.NewMemoryUserRepository()
+	sessionRepo := repository.NewMemorySessionRepository()
+	jwtCfg := config.JWTConfig{Algorithm: config.JWTAlgorithmHS256, Secret: "routetest-secret", Expire: 24, AccessExpire: 15}
+	tokenService, err := services.NewTokenService(userRepo, sessionRepo, jwtCfg)
+	if err != nil {
+		t.Fatalf("创建令牌服务失败: %v", err)
+	}
+	sessionService := services.NewSessionService(userRepo, sessionRepo, tokenService, config.SessionConfig{Policy: config.SessionPolicyUnlimited}, jwtCfg)
+	roleService := services.NewRoleService(repository.NewMemoryRoleRepository(), userRepo)
+	throttle := services.NewLoginThrottleService(repository.NewMemoryLoginAttemptRepository(), config.Default().LoginThrottle)
+	userService := services.NewUserService(userRepo, sessionService, roleService, throttle, config.ImageHostConfig{})
+	mail := mailer.NewMemoryMailer()
+	passwordResetService := services.NewPasswordResetService(userService, repository.NewMemoryPasswordResetRepository(), mail, config.Default().PasswordReset)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go passwordResetService.Run(ctx)
+	emailVerificationService := services.NewEmailVerificationService(userRepo, repository.NewMemoryEmailVerificationRepository(), mail, config.Default().EmailVerification)
+	go emailVerificationService.Run(ctx)
+	twoFactorService := services.NewTwoFactorService(userRepo, repository.NewMemoryTwoFactorRepository(), repository.NewMemoryTwoFactorChallengeRepository(), throttle, config.Default().TwoFactor)
+	// 限流默认关闭，避免矩阵和流程测试中的大量请求被限制，限流测试通过配置热加载开启
+	rateLimitCfg := config.Default().RateLimit
+	rateLimitCfg.Enabled = false
+	limiter := services.NewRateLimitService(ratelimit.NewMemoryStore(), rateLimitCfg)
+	passkeyService, err := services.NewPasskeyService(userRepo, repository.NewMemoryPasskeyRepository(), repository.NewMemoryPasskeyCeremonyRepository(), config.Default().WebAuthn)
+	if err != nil {
+		t.Fatalf("创建通行密钥服务失败: %v", err)
+	}
+	apiKeyService := services.NewAPIKeyService(userRepo, repository.NewMemoryAPIKeyRepository(), config.Default().APIKey)
+	oidcService := services.NewOIDCService(userService, repository.NewMemoryUserIdentityRepository(), repository.NewMemoryOIDCFlowRepository(), config.Default().OIDC)
+	// 授权服务要求非对称签名，使用临时生成的 Ed25519 密钥签发 ID Token
+	oauthKey, oauthJWT := ed25519Key(t)
+	oauthService, err := services.NewOAuthService(userRepo, repository.NewMemoryOAuthClientRepository(), repository.NewMemoryOAuthConsentRepository(),
+		repository.NewMemoryOAuthCodeRepository(), repository.NewMemoryOAuthTokenRepository(), oauthJWT, config.Default().OAuthServer)
+	if err != nil {
+		t.Fatalf("创建授权服务失败: %v", err)
+	}
+	userService.SetOAuthService(oauthService)
+	wsCfg := config.Default().WebSocket
+	wsManager, err := websocket.NewManager(wsCfg, websocket.NewMemoryBackplane(websocket.NewMemoryHub()),
+		services.NewWebSocketOutboxService(repository.NewMemoryWebSocketOutboxRepository(), wsCfg), nil)
+	if err != nil {
+		t.Fatalf("创建 WebSocket 管理器失败: %v", err)
+	}
+	presenceService := services.NewPresenceService(repository.NewMemoryPresenceRepository(), userRepo, wsManager, wsCfg)
+
+	a := &app{
+		engine:   gin.New(),
+		userRepo: userRepo,
+		throttle: throttle,
+		limiter:  limiter,
+		apiKeys:  apiKeyService,
+		emails:   emailVerificationService,
+		mail:     mail,
+		oidc:     oidcService,
+		oauth:    oauthService,
+		oauthKey: oauthKey,
+		users:    make(map[string]*models.User),
+		tokens:   make(map[string]string),
+		sessions: make(map[string]uint),
+	}
+	a.setup = func(api *gin.RouterGroup, oauthServer bool) {
+		routes.SetupRoutes(
+			api,
+			controllers.NewUserController(userService, roleService, emailVerificationService, twoFactorService),
+			controllers.NewSessionController(sessionService),
+			controllers.NewRoleController(roleService),
+			controllers.NewPasswordController(passwordResetService),
+			controllers.NewEmailController(emailVerificationService),
+			controllers.NewTwoFactorController(twoFactorService, userService),
+			controllers.NewPasskeyController(passkeyService, userService),
+			controllers.NewAPIKeyController(apiKeyService),
+			controllers.NewOIDCController(oidcService, userService, twoFactorService),
+			controllers.NewOAuthController(oauthService),
+			controllers.NewOAuthClientController(oauthService),
+			controllers.NewPresenceController(presenceService),
+			tokenService,
+			apiKeyService,
+			roleService,
+			emailVerificationService,
+			limiter,
+			oauthServer,
+		)
+	}
+	a.setup(a.engine.Group("/api"), true)
+
+	// 密码哈希不参与访问控制，使用固定值避免每个用例都计算 bcrypt；
+	// 邮箱均已验证，受限接口的访问结果只取决于角色
+	now := time.Now()
+	roles := map[string]string{Owner: "", User: "", Moderator: models.RoleModerator, Admin: models.RoleAdmin}
+	for _, actor := range authenticated {
+		user := &models.User{Username: actor, Email: actor + "@example.com", Password: "-", EmailVerifiedAt: &now}
+		if err := userService.CreateUser(user); err != nil {
+			t.Fatalf("创建用户 %s 失败: %v", actor, err)
+		}
+		if role := roles[actor]; role != "" {
+			if err := roleService.AssignRole(user.ID, role); err != nil {
+				t.Fatalf("分配角色 %s 失败: %v", role, err)
+			}
+		}
+		tokens, session, err := sessionService.Create(user, models.SessionMeta{Platform: models.PlatformWeb})
+		if err != nil {
+			t.Fatalf("登录 %s 失败: %v", actor, err)
+		}
+		a.users[actor] = user
+		a.tokens[actor] = tokens.Token
+		a.sessions[actor] = session.ID
+	}
+	return a
+}
+
+// ed25519Key 生成 Ed25519 密钥并写入临时 PEM 文件，返回公钥和对应的 JWT 配置
+func ed25519Key(t *testing.T) (ed25519.PublicKey, config.JWTConfig) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatalf("编码密钥失败: %v", err)
+	}
+	file := filepath.Join(t.TempDir(), "ed25519.pem")
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("写入密钥失败: %v", err)
+	}
+	return pub, config.JWTConfig{Algorithm: config.JWTAlgorithmEdDSA, SigningKey: config.JWTKeyConfig{File: file}, Expire: 24, AccessExpire: 15}
+}
+
+func jsonBody(method, path string, body func(target, targetSession uint) interface{}) func(uint, uint) *http.Request {
+	return func(target, targetSession uint) *http.Request {
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body(target, targetSession))
+		}
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+}
+
+func query(path string, values func(target, targetSession uint) string) func(uint, uint) *http.Request {
+	return func(target, targetSession uint) *http.Request {
+		url := path
+		if values != nil {
+			url += "?" + values(target, targetSession)
+		}
+		return httptest.NewRequest(http.MethodGet, url, nil)
+	}
+}
+
+// avatar 只携带 userId 不携带文件，通过访问控制后返回参数错误，不会请求图床
+func avatar(target, _ uint) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("userId", fmt.Sprint(target))
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/users/avatar", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func twoFactorCode(uint, uint) interface{} {
+	return models.TwoFactorCodeRequest{Code: "123456"}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
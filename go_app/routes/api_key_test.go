@@ -0,0 +1,176 @@
+package routes_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go_app/config"
+	"go_app/middleware"
+	"go_app/models"
+	"go_app/pkg/errcode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestAPIKeys 校验个人 API Key：创建和撤销、X-API-Key 认证、授权范围与用户权限的交集、
+// 账号安全接口拒绝 API Key、过期和禁用用户以及最近使用记录
+func TestAPIKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := newApp(t)
+
+	create := func(t *testing.T, actor string, scopes ...string) *models.APIKeyCreateResponse {
+		t.Helper()
+		var created models.APIKeyCreateResponse
+		expectCode(t, app.call(t, "/api/users/api-keys/create", app.tokens[actor], models.APIKeyCreateRequest{Name: actor, Scopes: scopes}, &created), 200)
+		return &created
+	}
+	ownerInfo := fmt.Sprintf("/api/users/info?userId=%d", app.users[Owner].ID)
+	update := func(actor string) models.UserUpdateRequest {
+		return models.UserUpdateRequest{UserID: app.users[actor].ID, Username: "renamed"}
+	}
+
+	t.Run("CreateAndList", func(t *testing.T) {
+		created := create(t, Owner, "read", "write", "read")
+		if !strings.HasPrefix(created.Key, created.Prefix+"_") || !strings.HasPrefix(created.Prefix, "gapp_") ||
+			strings.Join(created.Scopes, ",") != "read,write" || created.ExpiresAt != nil {
+			t.Fatalf("创建结果 = %+v", created)
+		}
+
+		var list []*models.APIKeyInfo
+		expectCode(t, app.get(t, "/api/users/api-keys", app.tokens[Owner], &list), 200)
+		if len(list) != 1 || list[0].ID != created.ID || list[0].Prefix != created.Prefix || list[0].LastUsedAt != nil {
+			t.Fatalf("API Key 列表 = %+v", list)
+		}
+		expectCode(t, app.get(t, "/api/users/api-keys", app.tokens[User], &list), 200)
+		if len(list) != 0 {
+			t.Fatalf("其他用户的 API Key 列表 = %+v", list)
+		}
+	})
+
+	t.Run("InvalidRequest", func(t *testing.T) {
+		invalid := models.APIKeyCreateRequest{Name: "x", Scopes: []string{"admin"}}
+		expectCode(t, app.call(t, "/api/users/api-keys/create", app.tokens[Owner], invalid, nil), errcode.APIKeyScopeInvalid.Code)
+		past := time.Now().Add(-time.Minute)
+		expired := models.APIKeyCreateRequest{Name: "x", Scopes: []string{"read"}, ExpiresAt: &past}
+		expectCode(t, app.call(t, "/api/users/api-keys/create", app.tokens[Owner], expired, nil), errcode.InvalidParams.Code)
+	})
+
+	t.Run("Authenticate", func(t *testing.T) {
+		readOnly := create(t, Owner, models.APIKeyScopeRead)
+		var user models.UserInfo
+		expectCode(t, app.withKey(t, "GET", ownerInfo, readOnly.Key, nil, &user), 200)
+		if user.UserID != app.users[Owner].ID {
+			t.Fatalf("API Key 认证的用户 = %d", user.UserID)
+		}
+		// read 只能调用 GET 接口
+		expectCode(t, app.withKey(t, "POST", "/api/users/update", readOnly.Key, update(Owner), nil), errcode.APIKeyScopeDenied.Code)
+
+		writable := create(t, Owner, models.APIKeyScopeWrite)
+		expectCode(t, app.withKey(t, "POST", "/api/users/update", writable.Key, update(Owner), nil), 200)
+		expectCode(t, app.withKey(t, "GET", ownerInfo, writable.Key, nil, nil), 200)
+
+		expectCode(t, app.withKey(t, "GET", ownerInfo, readOnly.Key+"0", nil, nil), errcode.APIKeyInvalid.Code)
+		expectCode(t, app.withKey(t, "GET", ownerInfo, "not-an-api-key", nil, nil), errcode.APIKeyInvalid.Code)
+	})
+
+	t.Run("SessionOnly", func(t *testing.T) {
+		key := create(t, Owner, models.APIKeyScopeWrite)
+		for _, path := range []string{"/api/users/sessions", "/api/users/2fa", "/api/users/passkeys", "/api/users/api-keys"} {
+			expectCode(t, app.withKey(t, "GET", path, key.Key, nil, nil), errcode.APIKeyNotAllowed.Code)
+		}
+		expectCode(t, app.withKey(t, "POST", "/api/users/api-keys/create", key.Key,
+			models.APIKeyCreateRequest{Name: "x", Scopes: []string{"write"}}, nil), errcode.APIKeyNotAllowed.Code)
+		expectCode(t, app.withKey(t, "POST", "/api/users/password", key.Key,
+			models.PasswordChangeRequest{OldPassword: "x", NewPassword: "654321"}, nil), errcode.APIKeyNotAllowed.Code)
+	})
+
+	t.Run("Permissions", func(t *testing.T) {
+		// 管理员的 API Key 只有授予对应权限才能访问管理接口和其他用户的数据
+		plain := create(t, Admin, models.APIKeyScopeWrite)
+		expectCode(t, app.withKey(t, "GET", "/api/users", plain.Key, nil, nil), errcode.APIKeyScopeDenied.Code)
+		expectCode(t, app.withKey(t, "GET", ownerInfo, plain.Key, nil, nil), errcode.APIKeyScopeDenied.Code)
+		expectCode(t, app.withKey(t, "POST", "/api/users/update", plain.Key, update(Owner), nil), errcode.APIKeyScopeDenied.Code)
+		expectCode(t, app.withKey(t, "POST", "/api/users/update", plain.Key, update(Admin), nil), 200)
+
+		scoped := create(t, Admin, models.APIKeyScopeRead, models.PermUsersList, models.PermUsersRead)
+		expectCode(t, app.withKey(t, "GET", "/api/users", scoped.Key, nil, nil), 200)
+		expectCode(t, app.withKey(t, "GET", ownerInfo, scoped.Key, nil, nil), 200)
+		expectCode(t, app.withKey(t, "GET", "/api/admin/roles", scoped.Key, nil, nil), errcode.APIKeyScopeDenied.Code)
+
+		// 授权范围不能超出用户本身的权限
+		owner := create(t, Owner, models.APIKeyScopeRead, models.PermUsersList)
+		expectCode(t, app.withKey(t, "GET", "/api/users", owner.Key, nil, nil), errcode.Forbidden.Code)
+	})
+
+	t.Run("LastUsed", func(t *testing.T) {
+		key := create(t, User, models.APIKeyScopeRead)
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/users/info?userId=%d", app.users[User].ID), nil)
+		req.RemoteAddr = "203.0.113.10:40000"
+		req.Header.Set(middleware.APIKeyHeader, key.Key)
+		expectCode(t, app.serve(t, req, "", nil), 200)
+
+		var list []*models.APIKeyInfo
+		expectCode(t, app.get(t, "/api/users/api-keys", app.tokens[User], &list), 200)
+		if len(list) != 1 || list[0].LastUsedAt == nil || list[0].LastUsedIP != "203.0.113.10" {
+			t.Fatalf("使用后 API Key 列表 = %+v", list)
+		}
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		key := create(t, User, models.APIKeyScopeRead)
+		// 只能撤销自己的 API Key
+		expectCode(t, app.call(t, "/api/users/api-keys/revoke", app.tokens[Owner], models.APIKeyRevokeRequest{ID: key.ID}, nil), errcode.APIKeyNotFound.Code)
+		expectCode(t, app.call(t, "/api/users/api-keys/revoke", app.tokens[User], models.APIKeyRevokeRequest{ID: key.ID}, nil), 200)
+		expectCode(t, app.call(t, "/api/users/api-keys/revoke", app.tokens[User], models.APIKeyRevokeRequest{ID: key.ID}, nil), errcode.APIKeyNotFound.Code)
+		expectCode(t, app.withKey(t, "GET", "/api/users/info", key.Key, nil, nil), errcode.APIKeyInvalid.Code)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		expiresAt := time.Now().Add(100 * time.Millisecond)
+		var created models.APIKeyCreateResponse
+		expectCode(t, app.call(t, "/api/users/api-keys/create", app.tokens[User],
+			models.APIKeyCreateRequest{Name: "short", Scopes: []string{"read"}, ExpiresAt: &expiresAt}, &created), 200)
+		path := fmt.Sprintf("/api/users/info?userId=%d", app.users[User].ID)
+		expectCode(t, app.withKey(t, "GET", path, created.Key, nil, nil), 200)
+		time.Sleep(150 * time.Millisecond)
+		expectCode(t, app.withKey(t, "GET", path, created.Key, nil, nil), errcode.APIKeyInvalid.Code)
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.APIKey.MaxPerUser = 1
+		app.apiKeys.OnConfigChange(cfg)
+		defer app.apiKeys.OnConfigChange(config.Default())
+
+		create(t, Moderator, models.APIKeyScopeRead)
+		expectCode(t, app.call(t, "/api/users/api-keys/create", app.tokens[Moderator],
+			models.APIKeyCreateRequest{Name: "x", Scopes: []string{"read"}}, nil), errcode.APIKeyLimitExceeded.Code)
+	})
+
+	t.Run("DisabledUser", func(t *testing.T) {
+		key := create(t, Moderator, models.APIKeyScopeRead)
+		now := time.Now()
+		if err := app.userRepo.SetDisabled(app.users[Moderator].ID, &now); err != nil {
+			t.Fatal(err)
+		}
+		expectCode(t, app.withKey(t, "GET", "/api/users", key.Key, nil, nil), errcode.UserDisabled.Code)
+	})
+}
+
+// withKey 使用 API Key 认证发起请求，body 不为 nil 时以 JSON 提交，返回响应码并将 data 解析到 out
+func (a *app) withKey(t *testing.T, method, path, key string, body, out interface{}) int {
+	t.Helper()
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.APIKeyHeader, key)
+	return a.serve(t, req, "", out)
+}
@@ -0,0 +1,241 @@
+package routes_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go_app/config"
+	"go_app/models"
+	"go_app/pkg/errcode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestEmailChange 校验修改邮箱：新邮箱验证通过前只保存为待验证邮箱，验证后替换当前邮箱；
+// 冷却时间内不能再次发送，只能修改当前登录用户的邮箱
+func TestEmailChange(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("VerifyThenSwap", func(t *testing.T) {
+		app := newApp(t)
+		owner := app.users[Owner]
+
+		var info models.UserInfo
+		expectCode(t, app.call(t, "/api/users/email", app.tokens[Owner], models.EmailUpdateRequest{Email: "new@example.com"}, &info), 200)
+		if info.Email != owner.Email || info.PendingEmail != "new@example.com" {
+			t.Fatalf("提交后 email = %s, pendingEmail = %s", info.Email, info.PendingEmail)
+		}
+		token := app.waitVerificationMail(t, "new@example.com", 1)
+		app.waitMail(t, owner.Email, "邮箱修改通知")
+
+		// 验证前仍使用原邮箱登录
+		app.expectUser(t, owner.ID, owner.Email, "new@example.com")
+		expectCode(t, app.call(t, "/api/login", "", models.LoginRequest{Email: "new@example.com", Password: "123456"}, nil), errcode.LoginFailed.Code)
+
+		var verified models.UserInfo
+		expectCode(t, app.call(t, "/api/email/verify", "", models.EmailVerifyRequest{Token: token}, &verified), 200)
+		if verified.Email != "new@example.com" || verified.PendingEmail != "" || !verified.EmailVerified {
+			t.Fatalf("验证后用户信息 = %+v", verified)
+		}
+		app.expectUser(t, owner.ID, "new@example.com", "")
+		expectCode(t, app.call(t, "/api/email/verify", "", models.EmailVerifyRequest{Token: token}, nil), errcode.EmailVerificationTokenInvalid.Code)
+		expectCode(t, app.call(t, "/api/users/email/resend", app.tokens[Owner], nil, nil), errcode.EmailAlreadyVerified.Code)
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		app := newApp(t)
+		owner := app.users[Owner]
+		expectCode(t, app.call(t, "/api/users/email", app.tokens[Owner], models.EmailUpdateRequest{Email: "new@example.com"}, nil), 200)
+		token := app.waitVerificationMail(t, "new@example.com", 1)
+
+		// 提交当前邮箱取消修改，之前的验证链接失效
+		var info models.UserInfo
+		expectCode(t, app.call(t, "/api/users/email", app.tokens[Owner], models.EmailUpdateRequest{Email: owner.Email}, &info), 200)
+		if info.PendingEmail != "" {
+			t.Fatalf("取消后 pendingEmail = %s", info.PendingEmail)
+		}
+		expectCode(t, app.call(t, "/api/email/verify", "", models.EmailVerifyRequest{Token: token}, nil), errcode.EmailVerificationTokenInvalid.Code)
+		app.expectUser(t, owner.ID, owner.Email, "")
+	})
+
+	t.Run("Cooldown", func(t *testing.T) {
+		app := newApp(t)
+		owner := app.users[Owner]
+		expectCode(t, app.call(t, "/api/users/email", app.tokens[Owner], models.EmailUpdateRequest{Email: "new@example.com"}, nil), 200)
+		token := app.waitVerificationMail(t, "new@example.com", 1)
+
+		// 冷却时间内重新发送和修改为其他邮箱都返回 429，待验证邮箱和已发送的链接不变
+		status, code := app.post(t, "/api/users/email/resend", app.tokens[Owner], nil)
+		expectStatus(t, status, http.StatusTooManyRequests)
+		expectCode(t, code, errcode.EmailVerificationCooldown.Code)
+		status, code = app.post(t, "/api/users/email", app.tokens[Owner], models.EmailUpdateRequest{Email: "other@example.com"})
+		expectStatus(t, status, http.StatusTooManyRequests)
+		expectCode(t, code, errcode.EmailVerificationCooldown.Code)
+		time.Sleep(100 * time.Millisecond)
+		if n := app.verificationMails("new@example.com"); n != 1 {
+			t.Fatalf("冷却时间内发送了 %d 封验证邮件, want 1", n)
+		}
+		if n := app.verificationMails("other@example.com"); n != 0 {
+			t.Fatalf("冷却时间内向其他邮箱发送了 %d 封验证邮件", n)
+		}
+		app.expectUser(t, owner.ID, owner.Email, "new@example.com")
+
+		// 冷却时间不影响其他用户
+		expectCode(t, app.call(t, "/api/users/email", app.tokens[User], models.EmailUpdateRequest{Email: "user2@example.com"}, nil), 200)
+
+		expectCode(t, app.call(t, "/api/email/verify", "", models.EmailVerifyRequest{Token: token}, nil), 200)
+	})
+
+	t.Run("BodyUserID", func(t *testing.T) {
+		app := newApp(t)
+		owner, other := app.users[Owner], app.users[User]
+
+		// 请求体中的 userId 不是当前用户时拒绝，管理员也一样
+		status, code := app.post(t, "/api/users/email", app.tokens[Owner], models.EmailUpdateRequest{UserID: other.ID, Email: "hijack@example.com"})
+		expectStatus(t, status, http.StatusOK)
+		expectCode(t, code, errcode.Forbidden.Code)
+		expectCode(t, app.call(t, "/api/users/email", app.tokens[Admin], models.EmailUpdateRequest{UserID: other.ID, Email: "hijack@example.com"}, nil), errcode.Forbidden.Code)
+		app.expectUser(t, other.ID, other.Email, "")
+		app.expectUser(t, owner.ID, owner.Email, "")
+		if n := app.verificationMails("hijack@example.com"); n != 0 {
+			t.Fatalf("向 hijack@example.com 发送了 %d 封验证邮件", n)
+		}
+
+		// userId 为当前用户时与不传相同
+		expectCode(t, app.call(t, "/api/users/email", app.tokens[Owner], models.EmailUpdateRequest{UserID: owner.ID, Email: "new@example.com"}, nil), 200)
+		app.expectUser(t, owner.ID, owner.Email, "new@example.com")
+
+		// 管理员通过管理接口修改其他用户的邮箱，同样需要用户验证新邮箱
+		expectCode(t, app.call(t, "/api/admin/users/email", app.tokens[Admin], models.AdminEmailUpdateRequest{UserID: other.ID, Email: "user-new@example.com"}, nil), 200)
+		app.expectUser(t, other.ID, other.Email, "user-new@example.com")
+		app.waitVerificationMail(t, "user-new@example.com", 1)
+		expectCode(t, app.call(t, "/api/admin/users/email", app.tokens[User], models.AdminEmailUpdateRequest{UserID: owner.ID, Email: "hijack@example.com"}, nil), errcode.Forbidden.Code)
+	})
+}
+
+// TestEmailVerification 校验注册后的邮箱验证：验证前不能访问受限接口，重新发送受冷却时间限制
+func TestEmailVerification(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := newApp(t)
+
+	var info models.UserInfo
+	expectCode(t, app.call(t, "/api/register", "", models.RegisterRequest{Username: "carol", Email: "carol@example.com", Password: "123456"}, &info), 200)
+	if info.EmailVerified {
+		t.Fatal("注册后邮箱已验证")
+	}
+	first := app.waitVerificationMail(t, "carol@example.com", 1)
+
+	var login struct {
+		Token string `json:"token"`
+	}
+	expectCode(t, app.call(t, "/api/login", "", models.LoginRequest{Email: "carol@example.com", Password: "123456"}, &login), 200)
+	token := login.Token
+
+	// 未验证的账号不能访问受限接口，其他接口不受影响
+	expectCode(t, app.get(t, "/api/admin/roles", token, nil), errcode.EmailNotVerified.Code)
+	expectCode(t, app.call(t, "/api/users/avatar", token, nil, nil), errcode.EmailNotVerified.Code)
+	expectCode(t, app.get(t, fmt.Sprintf("/api/users/info?userId=%d", info.UserID), token, nil), 200)
+
+	// 注册时发送的链接仍在冷却时间内，重新发送返回 429
+	status, code := app.post(t, "/api/users/email/resend", token, nil)
+	expectStatus(t, status, http.StatusTooManyRequests)
+	expectCode(t, code, errcode.EmailVerificationCooldown.Code)
+
+	// 链接过了冷却时间后可以重新发送，之前的链接失效
+	cfg := config.Default()
+	cfg.EmailVerification.Cooldown = 0
+	app.emails.OnConfigChange(cfg)
+	expectCode(t, app.call(t, "/api/users/email/resend", token, nil, nil), 200)
+	second := app.waitVerificationMail(t, "carol@example.com", 2)
+	expectCode(t, app.call(t, "/api/email/verify", "", models.EmailVerifyRequest{Token: first}, nil), errcode.EmailVerificationTokenInvalid.Code)
+
+	expectCode(t, app.call(t, "/api/email/verify", "", models.EmailVerifyRequest{Token: second}, &info), 200)
+	if !info.EmailVerified {
+		t.Fatal("验证后邮箱未验证")
+	}
+	expectCode(t, app.get(t, "/api/admin/roles", token, nil), errcode.Forbidden.Code)
+}
+
+// post 发起 JSON POST 请求，返回 HTTP 状态码和响应码
+func (a *app) post(t *testing.T, path, token string, body interface{}) (int, int) {
+	t.Helper()
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	a.engine.ServeHTTP(w, req)
+
+	var resp models.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v, body: %s", err, w.Body.String())
+	}
+	return w.Code, resp.Code
+}
+
+// expectUser 校验用户当前的邮箱和待验证邮箱
+func (a *app) expectUser(t *testing.T, userID uint, email, pending string) {
+	t.Helper()
+	user, err := a.userRepo.FindByID(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != email || user.PendingEmail != pending {
+		t.Fatalf("用户 %d 的 email = %s, pendingEmail = %s, want %s, %s", userID, user.Email, user.PendingEmail, email, pending)
+	}
+}
+
+// waitVerificationMail 等待发送给 email 的第 n 封验证邮件，返回其中的令牌
+func (a *app) waitVerificationMail(t *testing.T, email string, n int) string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for a.verificationMails(email) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待第 %d 封验证邮件超时", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	token := resetTokenPattern.FindString(a.mail.Last(email).Body)
+	if token == "" {
+		t.Fatal("验证邮件中没有令牌")
+	}
+	return token
+}
+
+// waitMail 等待发送给 email 的主题为 subject 的邮件
+func (a *app) waitMail(t *testing.T, email, subject string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		for _, msg := range a.mail.Messages() {
+			if msg.To == email && msg.Subject == subject {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("等待发送给 %s 的邮件 %q 超时", email, subject)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// verificationMails 已发送给 email 的验证邮件数
+func (a *app) verificationMails(email string) int {
+	n := 0
+	for _, msg := range a.mail.Messages() {
+		if msg.To == email && msg.Subject == "验证邮箱" {
+			n++
+		}
+	}
+	return n
+}
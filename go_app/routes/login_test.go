@@ -0,0 +1,200 @@
+package routes_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"go_app/config"
+	"go_app/models"
+	"go_app/pkg/errcode"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestLoginThrottle 校验密码登录的失败限制：统一的错误响应、失败后的等待时间、
+// 账号和 IP 的临时锁定以及管理员解除锁定
+func TestLoginThrottle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := newApp(t)
+	const password = "correct-password"
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, actor := range []string{Owner, User} {
+		if err := app.userRepo.UpdatePassword(app.users[actor].ID, string(hashed)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	owner, user := app.users[Owner].Email, app.users[User].Email
+
+	// withConfig 通过配置热加载调整限制，测试结束后恢复默认配置
+	withConfig := func(t *testing.T, change func(c *config.LoginThrottleConfig)) {
+		cfg := config.Default()
+		change(&cfg.LoginThrottle)
+		app.throttle.OnConfigChange(cfg)
+		t.Cleanup(func() { app.throttle.OnConfigChange(config.Default()) })
+	}
+	noDelay := func(c *config.LoginThrottleConfig) { c.BaseDelay, c.MaxDelay = 0, 0 }
+
+	t.Run("UnifiedError", func(t *testing.T) {
+		withConfig(t, noDelay)
+		wrong, _ := app.login(t, owner, "wrong-password", "192.0.2.1")
+		missing, _ := app.login(t, "missing@example.com", password, "192.0.2.1")
+		expectCode(t, wrong, errcode.LoginFailed.Code)
+		expectCode(t, missing, errcode.LoginFailed.Code)
+		code, _ := app.login(t, owner, password, "192.0.2.1")
+		expectCode(t, code, 200)
+	})
+
+	t.Run("Backoff", func(t *testing.T) {
+		code, _ := app.login(t, user, "wrong-password", "192.0.2.2")
+		expectCode(t, code, errcode.LoginFailed.Code)
+		// 失败后需等待 base_delay 才能再次尝试，密码正确也不例外
+		code, retryAfter := app.login(t, user, password, "192.0.2.3")
+		expectCode(t, code, errcode.LoginTooManyAttempts.Code)
+		if retryAfter != 1 {
+			t.Fatalf("Retry-After = %d, want 1", retryAfter)
+		}
+		expectCode(t, app.call(t, "/api/admin/users/unlock", app.tokens[Admin], models.LoginUnlockRequest{UserID: app.users[User].ID}, nil), 200)
+	})
+
+	t.Run("AccountLockout", func(t *testing.T) {
+		withConfig(t, noDelay)
+		for _, email := range []string{owner, "nobody@example.com"} {
+			for i := 0; i < 5; i++ {
+				code, _ := app.login(t, email, "wrong-password", "192.0.2.4")
+				expectCode(t, code, errcode.LoginFailed.Code)
+			}
+			// 不存在的邮箱同样会被锁定，锁定状态不暴露邮箱是否已注册
+			code, retryAfter := app.login(t, email, password, "192.0.2.5")
+			expectCode(t, code, errcode.LoginLocked.Code)
+			if retryAfter != 15*60 {
+				t.Fatalf("Retry-After = %d, want %d", retryAfter, 15*60)
+			}
+		}
+
+		// 锁定只针对账号，其他账号不受影响
+		code, _ := app.login(t, user, password, "192.0.2.5")
+		expectCode(t, code, 200)
+
+		expectCode(t, app.call(t, "/api/admin/users/unlock", app.tokens[Admin], models.LoginUnlockRequest{}, nil), errcode.InvalidParams.Code)
+		expectCode(t, app.call(t, "/api/admin/users/unlock", app.tokens[Admin], models.LoginUnlockRequest{UserID: 9999}, nil), errcode.UserNotFound.Code)
+		expectCode(t, app.call(t, "/api/admin/users/unlock", app.tokens[Admin], models.LoginUnlockRequest{UserID: app.users[Owner].ID}, nil), 200)
+		code, _ = app.login(t, owner, password, "192.0.2.5")
+		expectCode(t, code, 200)
+	})
+
+	t.Run("SuccessResetsAccount", func(t *testing.T) {
+		withConfig(t, noDelay)
+		for round := 0; round < 2; round++ {
+			for i := 0; i < 4; i++ {
+				code, _ := app.login(t, owner, "wrong-password", "192.0.2.6")
+				expectCode(t, code, errcode.LoginFailed.Code)
+			}
+			code, _ := app.login(t, owner, password, "192.0.2.6")
+			expectCode(t, code, 200)
+		}
+	})
+
+	t.Run("ConcurrentBurst", func(t *testing.T) {
+		withConfig(t, noDelay)
+		// 并发的错误密码请求总共只能校验 max_failures 次密码
+		var (
+			wg     sync.WaitGroup
+			mu     sync.Mutex
+			failed int
+		)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				code, _ := app.login(t, owner, "wrong-password", "192.0.2."+strconv.Itoa(100+i))
+				if code == errcode.LoginFailed.Code {
+					mu.Lock()
+					failed++
+					mu.Unlock()
+				}
+			}(i)
+		}
+		wg.Wait()
+		if failed > 5 {
+			t.Fatalf("并发请求校验了 %d 次密码, want <= 5", failed)
+		}
+		expectCode(t, app.call(t, "/api/admin/users/unlock", app.tokens[Admin], models.LoginUnlockRequest{UserID: app.users[Owner].ID}, nil), 200)
+	})
+
+	t.Run("IPLockout", func(t *testing.T) {
+		withConfig(t, func(c *config.LoginThrottleConfig) {
+			noDelay(c)
+			c.IPMaxFailures = 3
+		})
+		// 同一 IP 对不同账号的尝试累计计数
+		for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+			code, _ := app.login(t, email, "wrong-password", "192.0.2.7")
+			expectCode(t, code, errcode.LoginFailed.Code)
+		}
+		code, _ := app.login(t, owner, password, "192.0.2.7")
+		expectCode(t, code, errcode.LoginLocked.Code)
+		code, _ = app.login(t, owner, password, "192.0.2.8")
+		expectCode(t, code, 200)
+
+		expectCode(t, app.call(t, "/api/admin/users/unlock", app.tokens[Admin], models.LoginUnlockRequest{IP: "192.0.2.7"}, nil), 200)
+		code, _ = app.login(t, owner, password, "192.0.2.7")
+		expectCode(t, code, 200)
+	})
+
+	t.Run("ChangePassword", func(t *testing.T) {
+		// 修改密码时旧密码的错误次数与登录按相同规则限制，失败记录与登录相互独立
+		var login struct {
+			Token string `json:"token"`
+		}
+		expectCode(t, app.call(t, "/api/login", "", models.LoginRequest{Email: user, Password: password}, &login), 200)
+		change := func(old string) (int, http.Header) {
+			return app.request(t, http.MethodPost, "/api/users/password", login.Token, "192.0.2.9", nil,
+				models.PasswordChangeRequest{OldPassword: old, NewPassword: "new-password"})
+		}
+		code, _ := change("wrong-password")
+		expectCode(t, code, errcode.UserPasswordError.Code)
+		code, header := change(password)
+		expectCode(t, code, errcode.LoginTooManyAttempts.Code)
+		expectHeader(t, header, "Retry-After", "1")
+		code, _ = app.login(t, user, password, "192.0.2.9")
+		expectCode(t, code, 200)
+
+		withConfig(t, func(c *config.LoginThrottleConfig) {
+			noDelay(c)
+			c.MaxFailures = 3
+		})
+		for i := 0; i < 2; i++ {
+			code, _ = change("wrong-password")
+			expectCode(t, code, errcode.UserPasswordError.Code)
+		}
+		code, _ = change(password)
+		expectCode(t, code, errcode.LoginTooManyAttempts.Code)
+	})
+}
+
+// login 从客户端 IP ip 发起密码登录，返回响应码和 Retry-After 响应头
+func (a *app) login(t *testing.T, email, password, ip string) (int, int) {
+	t.Helper()
+	data, _ := json.Marshal(models.LoginRequest{Email: email, Password: password})
+	req := httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = ip + ":40000"
+	w := httptest.NewRecorder()
+	a.engine.ServeHTTP(w, req)
+
+	var resp models.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v, body: %s", err, w.Body.String())
+	}
+	retryAfter, _ := strconv.Atoi(w.Header().Get("Retry-After"))
+	return resp.Code, retryAfter
+}
@@ -0,0 +1,367 @@
+package routes_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go_app/controllers"
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/routes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TestOAuthServer 走完授权服务的流程：客户端登记、授权请求校验、用户确认、授权码 + PKCE 换取令牌、
+// ID Token 和 /oauth/userinfo、刷新令牌轮换与重复使用检测、客户端凭证、令牌吊销和内省以及撤销授权
+func TestOAuthServer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := newApp(t)
+	routes.SetupOAuthRoutes(app.engine, controllers.NewOAuthController(app.oauth), app.limiter)
+
+	const callback = "https://app.example.com/callback"
+	var confidential, public models.OAuthClientCreateResponse
+	expectCode(t, app.call(t, "/api/admin/oauth/clients/create", app.tokens[Admin], models.OAuthClientCreateRequest{
+		Name:         "工单系统",
+		RedirectURIs: []string{callback},
+		Scopes:       []string{"openid", "profile", "email", "offline_access", "tickets:read"},
+		GrantTypes:   []string{"authorization_code", "refresh_token", "client_credentials"},
+	}, &confidential), 200)
+	if confidential.ClientID == "" || confidential.ClientSecret == "" || confidential.Public {
+		t.Fatalf("登记的客户端 = %+v", confidential)
+	}
+	expectCode(t, app.call(t, "/api/admin/oauth/clients/create", app.tokens[Admin], models.OAuthClientCreateRequest{
+		Name:         "移动端",
+		RedirectURIs: []string{"com.example.app:/callback"},
+		Public:       true,
+	}, &public), 200)
+	if public.ClientSecret != "" || !public.Public {
+		t.Fatalf("公开客户端 = %+v", public)
+	}
+	secret := [2]string{confidential.ClientID, confidential.ClientSecret}
+
+	verifier := strings.Repeat("v", 43)
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	authorizeRequest := func(scope string) models.OAuthAuthorizeRequest {
+		return models.OAuthAuthorizeRequest{
+			ClientID:            confidential.ClientID,
+			RedirectURI:         callback,
+			ResponseType:        "code",
+			Scope:               scope,
+			State:               "xyz",
+			Nonce:               "n-0S6",
+			CodeChallenge:       challenge,
+			CodeChallengeMethod: "S256",
+		}
+	}
+	// approve 以 Owner 身份同意授权，返回回调地址中的授权码
+	approve := func(t *testing.T, req models.OAuthAuthorizeRequest) string {
+		t.Helper()
+		var redirect models.OAuthRedirect
+		expectCode(t, app.call(t, "/api/users/oauth/authorize", app.tokens[Owner], models.OAuthConsentRequest{OAuthAuthorizeRequest: req, Approve: true}, &redirect), 200)
+		u, err := url.Parse(redirect.RedirectURL)
+		if err != nil || !strings.HasPrefix(redirect.RedirectURL, req.RedirectURI+"?") {
+			t.Fatalf("回调地址 = %q", redirect.RedirectURL)
+		}
+		if u.Query().Get("state") != req.State || u.Query().Get("code") == "" {
+			t.Fatalf("回调参数 = %v", u.Query())
+		}
+		return u.Query().Get("code")
+	}
+	exchange := func(t *testing.T, code string) *models.OAuthTokenResponse {
+		t.Helper()
+		var tokens models.OAuthTokenResponse
+		status := app.oauthCall(t, "/oauth/token", secret, url.Values{
+			"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {callback}, "code_verifier": {verifier},
+		}, &tokens)
+		expectStatus(t, status, http.StatusOK)
+		return &tokens
+	}
+
+	t.Run("Discovery", func(t *testing.T) {
+		var doc models.OpenIDConfiguration
+		expectStatus(t, app.oauthGet(t, "/.well-known/openid-configuration", "", &doc), http.StatusOK)
+		if doc.Issuer != "http://localhost:8080" || doc.TokenEndpoint != doc.Issuer+"/oauth/token" ||
+			doc.JWKSURI != doc.Issuer+"/.well-known/jwks.json" || !contains(doc.IDTokenSigningAlgValuesSupported, "EdDSA") {
+			t.Fatalf("发现文档 = %+v", doc)
+		}
+	})
+
+	t.Run("Authorize", func(t *testing.T) {
+		authorize := func(params url.Values) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			app.engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oauth/authorize?"+params.Encode(), nil))
+			return w
+		}
+		params := url.Values{
+			"client_id": {confidential.ClientID}, "redirect_uri": {callback}, "response_type": {"code"},
+			"scope": {"openid profile"}, "state": {"xyz"}, "code_challenge": {challenge}, "code_challenge_method": {"S256"},
+		}
+		w := authorize(params)
+		if w.Code != http.StatusFound || w.Header().Get("Location") != "http://localhost:8080/oauth/consent?"+params.Encode() {
+			t.Fatalf("授权请求跳转 = %d %q", w.Code, w.Header().Get("Location"))
+		}
+
+		// 回调地址未登记时不能跳转回客户端
+		params.Set("redirect_uri", "https://evil.example.com/callback")
+		if w := authorize(params); w.Code != http.StatusBadRequest {
+			t.Fatalf("未登记的回调地址响应 = %d", w.Code)
+		}
+		// 其他参数错误携带 error 和 state 跳转回客户端
+		params.Set("redirect_uri", callback)
+		params.Set("scope", "openid admin")
+		w = authorize(params)
+		location, _ := url.Parse(w.Header().Get("Location"))
+		if w.Code != http.StatusFound || location.Query().Get("error") != "invalid_scope" || location.Query().Get("state") != "xyz" {
+			t.Fatalf("无效授权范围的跳转 = %d %q", w.Code, w.Header().Get("Location"))
+		}
+		// 公开客户端必须使用 PKCE
+		params = url.Values{"client_id": {public.ClientID}, "redirect_uri": {"com.example.app:/callback"}, "response_type": {"code"}, "scope": {"openid"}}
+		location, _ = url.Parse(authorize(params).Header().Get("Location"))
+		if location.Query().Get("error") != "invalid_request" {
+			t.Fatalf("公开客户端未使用 PKCE 的跳转 = %q", location)
+		}
+	})
+
+	t.Run("Consent", func(t *testing.T) {
+		req := authorizeRequest("openid profile")
+		var info models.OAuthAuthorizeInfo
+		query := url.Values{"client_id": {req.ClientID}, "redirect_uri": {req.RedirectURI}, "response_type": {"code"}, "scope": {req.Scope},
+			"code_challenge": {challenge}, "code_challenge_method": {"S256"}}
+		expectCode(t, app.get(t, "/api/users/oauth/authorize?"+query.Encode(), app.tokens[User], &info), 200)
+		if info.ClientName != "工单系统" || info.Consented || len(info.Scopes) != 2 {
+			t.Fatalf("授权请求信息 = %+v", info)
+		}
+
+		// 拒绝授权携带 access_denied 跳转回客户端
+		var redirect models.OAuthRedirect
+		expectCode(t, app.call(t, "/api/users/oauth/authorize", app.tokens[User], models.OAuthConsentRequest{OAuthAuthorizeRequest: req}, &redirect), 200)
+		if !strings.Contains(redirect.RedirectURL, "error=access_denied") || !strings.Contains(redirect.RedirectURL, "state=xyz") {
+			t.Fatalf("拒绝授权的回调地址 = %q", redirect.RedirectURL)
+		}
+
+		approve(t, req)
+		expectCode(t, app.get(t, "/api/users/oauth/authorize?"+query.Encode(), app.tokens[Owner], &info), 200)
+		if !info.Consented {
+			t.Fatal("同意后再次申请相同授权范围应标记为已同意")
+		}
+
+		bad := req
+		bad.RedirectURI = "https://evil.example.com/callback"
+		expectCode(t, app.call(t, "/api/users/oauth/authorize", app.tokens[Owner], models.OAuthConsentRequest{OAuthAuthorizeRequest: bad, Approve: true}, nil), errcode.OAuthRequestInvalid.Code)
+	})
+
+	t.Run("AuthorizationCode", func(t *testing.T) {
+		code := approve(t, authorizeRequest("openid profile email offline_access"))
+
+		// code_verifier 错误不会作废授权码
+		status := app.oauthCall(t, "/oauth/token", secret, url.Values{
+			"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {callback}, "code_verifier": {strings.Repeat("x", 43)},
+		}, nil)
+		expectStatus(t, status, http.StatusBadRequest)
+		status = app.oauthCall(t, "/oauth/token", [2]string{confidential.ClientID, "wrong"}, url.Values{
+			"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {callback}, "code_verifier": {verifier},
+		}, nil)
+		expectStatus(t, status, http.StatusUnauthorized)
+
+		tokens := exchange(t, code)
+		if tokens.AccessToken == "" || tokens.RefreshToken == "" || tokens.IDToken == "" || tokens.TokenType != "Bearer" {
+			t.Fatalf("令牌响应 = %+v", tokens)
+		}
+
+		claims := jwt.MapClaims{}
+		if _, err := jwt.ParseWithClaims(tokens.IDToken, claims, func(*jwt.Token) (interface{}, error) {
+			return app.oauthKey, nil
+		}, jwt.WithValidMethods([]string{"EdDSA"}), jwt.WithAudience(confidential.ClientID), jwt.WithIssuer("http://localhost:8080")); err != nil {
+			t.Fatalf("ID Token 校验失败: %v", err)
+		}
+		owner := app.users[Owner]
+		if claims["sub"] != strconv.FormatUint(uint64(owner.ID), 10) || claims["nonce"] != "n-0S6" ||
+			claims["email"] != owner.Email || claims["email_verified"] != true || claims["name"] != owner.Username {
+			t.Fatalf("ID Token 声明 = %v", claims)
+		}
+
+		var info map[string]interface{}
+		expectStatus(t, app.oauthGet(t, "/oauth/userinfo", tokens.AccessToken, &info), http.StatusOK)
+		if info["sub"] != claims["sub"] || info["email"] != owner.Email {
+			t.Fatalf("用户信息 = %v", info)
+		}
+		expectStatus(t, app.oauthGet(t, "/oauth/userinfo", "invalid", nil), http.StatusUnauthorized)
+
+		// 授权码只能使用一次，重复使用时吊销已用它换取的令牌
+		status = app.oauthCall(t, "/oauth/token", secret, url.Values{
+			"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {callback}, "code_verifier": {verifier},
+		}, nil)
+		expectStatus(t, status, http.StatusBadRequest)
+		expectStatus(t, app.oauthGet(t, "/oauth/userinfo", tokens.AccessToken, nil), http.StatusUnauthorized)
+	})
+
+	t.Run("Refresh", func(t *testing.T) {
+		tokens := exchange(t, approve(t, authorizeRequest("openid offline_access")))
+
+		var rotated models.OAuthTokenResponse
+		refresh := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {tokens.RefreshToken}}
+		expectStatus(t, app.oauthCall(t, "/oauth/token", secret, refresh, &rotated), http.StatusOK)
+		if rotated.RefreshToken == "" || rotated.RefreshToken == tokens.RefreshToken {
+			t.Fatalf("刷新后的令牌 = %+v", rotated)
+		}
+		expectStatus(t, app.oauthGet(t, "/oauth/userinfo", rotated.AccessToken, nil), http.StatusOK)
+
+		// 旧刷新令牌被再次使用，吊销整个授权
+		expectStatus(t, app.oauthCall(t, "/oauth/token", secret, refresh, nil), http.StatusBadRequest)
+		expectStatus(t, app.oauthGet(t, "/oauth/userinfo", rotated.AccessToken, nil), http.StatusUnauthorized)
+		refresh.Set("refresh_token", rotated.RefreshToken)
+		expectStatus(t, app.oauthCall(t, "/oauth/token", secret, refresh, nil), http.StatusBadRequest)
+
+		// 没有申请 offline_access 时不签发刷新令牌
+		if tokens := exchange(t, approve(t, authorizeRequest("openid"))); tokens.RefreshToken != "" {
+			t.Fatalf("未申请 offline_access 的令牌 = %+v", tokens)
+		}
+	})
+
+	t.Run("ClientCredentials", func(t *testing.T) {
+		var tokens models.OAuthTokenResponse
+		expectStatus(t, app.oauthCall(t, "/oauth/token", secret, url.Values{"grant_type": {"client_credentials"}}, &tokens), http.StatusOK)
+		if tokens.Scope != "tickets:read" || tokens.RefreshToken != "" || tokens.IDToken != "" {
+			t.Fatalf("客户端凭证令牌 = %+v", tokens)
+		}
+		// 客户端凭证令牌不代表用户，不能获取用户信息
+		expectStatus(t, app.oauthGet(t, "/oauth/userinfo", tokens.AccessToken, nil), http.StatusForbidden)
+		expectStatus(t, app.oauthCall(t, "/oauth/token", secret, url.Values{"grant_type": {"client_credentials"}, "scope": {"openid"}}, nil), http.StatusBadRequest)
+		expectStatus(t, app.oauthCall(t, "/oauth/token", [2]string{public.ClientID, ""}, url.Values{"grant_type": {"client_credentials"}}, nil), http.StatusBadRequest)
+	})
+
+	t.Run("IntrospectAndRevoke", func(t *testing.T) {
+		tokens := exchange(t, approve(t, authorizeRequest("openid offline_access")))
+
+		var result models.OAuthIntrospection
+		expectStatus(t, app.oauthCall(t, "/oauth/introspect", secret, url.Values{"token": {tokens.AccessToken}}, &result), http.StatusOK)
+		if !result.Active || result.ClientID != confidential.ClientID || result.Username != app.users[Owner].Username || result.TokenType != "Bearer" {
+			t.Fatalf("内省结果 = %+v", result)
+		}
+		// 公开客户端不能调用内省
+		expectStatus(t, app.oauthCall(t, "/oauth/introspect", [2]string{public.ClientID, ""}, url.Values{"token": {tokens.AccessToken}}, nil), http.StatusUnauthorized)
+
+		// 吊销刷新令牌时同时吊销访问令牌，未知令牌同样返回 200
+		expectStatus(t, app.oauthCall(t, "/oauth/revoke", secret, url.Values{"token": {"unknown"}}, nil), http.StatusOK)
+		expectStatus(t, app.oauthCall(t, "/oauth/revoke", secret, url.Values{"token": {tokens.RefreshToken}, "token_type_hint": {"refresh_token"}}, nil), http.StatusOK)
+		result = models.OAuthIntrospection{}
+		expectStatus(t, app.oauthCall(t, "/oauth/introspect", secret, url.Values{"token": {tokens.AccessToken}}, &result), http.StatusOK)
+		if result.Active {
+			t.Fatalf("吊销后的内省结果 = %+v", result)
+		}
+	})
+
+	t.Run("RevokeConsent", func(t *testing.T) {
+		tokens := exchange(t, approve(t, authorizeRequest("openid")))
+
+		var consents []*models.OAuthConsentInfo
+		expectCode(t, app.get(t, "/api/users/oauth/consents", app.tokens[Owner], &consents), 200)
+		if len(consents) != 1 || consents[0].ClientName != "工单系统" {
+			t.Fatalf("已授权的应用 = %+v", consents)
+		}
+		expectCode(t, app.call(t, "/api/users/oauth/consents/revoke", app.tokens[User], models.OAuthClientIDRequest{ClientID: confidential.ClientID}, nil), errcode.OAuthConsentNotFound.Code)
+		expectCode(t, app.call(t, "/api/users/oauth/consents/revoke", app.tokens[Owner], models.OAuthClientIDRequest{ClientID: confidential.ClientID}, nil), 200)
+		expectStatus(t, app.oauthGet(t, "/oauth/userinfo", tokens.AccessToken, nil), http.StatusUnauthorized)
+	})
+
+	t.Run("PasswordReset", func(t *testing.T) {
+		// 重置密码后已授权给其他应用的令牌和尚未使用的授权码全部失效
+		tokens := exchange(t, approve(t, authorizeRequest("openid offline_access")))
+		code := approve(t, authorizeRequest("openid"))
+
+		email := app.users[Owner].Email
+		expectCode(t, app.call(t, "/api/password/forgot", "", models.PasswordForgotRequest{Email: email}, nil), 200)
+		token := app.waitResetMail(t, email, 1)
+		expectCode(t, app.call(t, "/api/password/reset", "", models.PasswordResetRequest{Token: token, NewPassword: "654321"}, nil), 200)
+
+		expectStatus(t, app.oauthGet(t, "/oauth/userinfo", tokens.AccessToken, nil), http.StatusUnauthorized)
+		refresh := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {tokens.RefreshToken}}
+		expectStatus(t, app.oauthCall(t, "/oauth/token", secret, refresh, nil), http.StatusBadRequest)
+		status := app.oauthCall(t, "/oauth/token", secret, url.Values{
+			"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {callback}, "code_verifier": {verifier},
+		}, nil)
+		expectStatus(t, status, http.StatusBadRequest)
+	})
+
+	t.Run("Clients", func(t *testing.T) {
+		// 公开客户端不能使用 client_credentials，回调地址不能包含片段
+		expectCode(t, app.call(t, "/api/admin/oauth/clients/create", app.tokens[Admin], models.OAuthClientCreateRequest{
+			Name: "invalid", RedirectURIs: []string{callback}, GrantTypes: []string{"client_credentials"}, Public: true,
+		}, nil), errcode.OAuthClientInvalid.Code)
+		expectCode(t, app.call(t, "/api/admin/oauth/clients/create", app.tokens[Admin], models.OAuthClientCreateRequest{
+			Name: "invalid", RedirectURIs: []string{callback + "#fragment"},
+		}, nil), errcode.OAuthClientInvalid.Code)
+
+		var clients []*models.OAuthClientInfo
+		expectCode(t, app.get(t, "/api/admin/oauth/clients", app.tokens[Admin], &clients), 200)
+		if len(clients) != 2 {
+			t.Fatalf("客户端列表 = %+v", clients)
+		}
+
+		// 删除客户端后已签发的令牌失效
+		var tokens models.OAuthTokenResponse
+		expectStatus(t, app.oauthCall(t, "/oauth/token", secret, url.Values{"grant_type": {"client_credentials"}}, &tokens), http.StatusOK)
+		expectCode(t, app.call(t, "/api/admin/oauth/clients/delete", app.tokens[Admin], models.OAuthClientIDRequest{ClientID: confidential.ClientID}, nil), 200)
+		expectCode(t, app.call(t, "/api/admin/oauth/clients/delete", app.tokens[Admin], models.OAuthClientIDRequest{ClientID: confidential.ClientID}, nil), errcode.OAuthClientNotFound.Code)
+		expectStatus(t, app.oauthCall(t, "/oauth/token", secret, url.Values{"grant_type": {"client_credentials"}}, nil), http.StatusUnauthorized)
+	})
+}
+
+// oauthCall 以表单提交授权服务的协议接口，client 为 HTTP Basic 使用的客户端ID和密钥，返回 HTTP 状态码并将响应解析到 out
+func (a *app) oauthCall(t *testing.T, path string, client [2]string, form url.Values, out interface{}) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if client[1] != "" {
+		req.SetBasicAuth(url.QueryEscape(client[0]), url.QueryEscape(client[1]))
+	} else if client[0] != "" {
+		form.Set("client_id", client[0])
+		req = httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	return a.oauthServe(t, req, out)
+}
+
+// oauthGet 使用 Bearer 访问令牌请求授权服务的协议接口
+func (a *app) oauthGet(t *testing.T, path, accessToken string, out interface{}) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	return a.oauthServe(t, req, out)
+}
+
+func (a *app) oauthServe(t *testing.T, req *http.Request, out interface{}) int {
+	t.Helper()
+	w := httptest.NewRecorder()
+	a.engine.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Logf("%s %s: %d %s", req.Method, req.URL.Path, w.Code, w.Body.String())
+	} else if out != nil {
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+			t.Fatalf("%s 的响应类型 = %q", req.URL.Path, ct)
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("解析响应失败: %v, body: %s", err, w.Body.String())
+		}
+	}
+	return w.Code
+}
+
+func expectStatus(t *testing.T, got, want int) {
+	t.Helper()
+	if got != want {
+		t.Fatalf("HTTP 状态码 = %d, want %d", got, want)
+	}
+}
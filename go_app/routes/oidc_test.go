@@ -0,0 +1,211 @@
+package routes_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"go_app/config"
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/pkg/oidc/oidctest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pquerna/otp/totp"
+)
+
+// TestOIDCLogin 使用 oidctest 本地提供方走完第三方登录流程：自动注册、按已验证邮箱关联已有用户、
+// ID Token 校验（签名、nonce）、PKCE 与流程的绑定、两步验证以及关联账号的管理
+func TestOIDCLogin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := newApp(t)
+	srv := oidctest.NewServer(t)
+
+	cfg := config.Default()
+	cfg.OIDC.Providers = []config.OIDCProviderConfig{{
+		Name:         "mock",
+		DisplayName:  "Mock",
+		Issuer:       srv.Issuer(),
+		ClientID:     srv.ClientID,
+		ClientSecret: srv.ClientSecret,
+	}}
+	app.oidc.OnConfigChange(cfg)
+
+	begin := func(t *testing.T) *models.OIDCAuthorization {
+		t.Helper()
+		var authorization models.OIDCAuthorization
+		expectCode(t, app.call(t, "/api/login/oidc/begin", "", models.OIDCBeginRequest{Provider: "mock"}, &authorization), 200)
+		return &authorization
+	}
+	authorize := func(t *testing.T, authorization *models.OIDCAuthorization) string {
+		t.Helper()
+		code, state, err := srv.Authorize(authorization.AuthorizationURL)
+		if err != nil {
+			t.Fatalf("提供方授权失败: %v", err)
+		}
+		if state != authorization.State {
+			t.Fatalf("回调 state = %q，应为 %q", state, authorization.State)
+		}
+		return code
+	}
+	login := func(t *testing.T, user oidctest.User, out interface{}) int {
+		t.Helper()
+		srv.User = user
+		authorization := begin(t)
+		code := authorize(t, authorization)
+		return app.call(t, "/api/login/oidc", "", models.OIDCLoginRequest{State: authorization.State, Code: code}, out)
+	}
+
+	t.Run("Providers", func(t *testing.T) {
+		var providers []*models.OIDCProviderInfo
+		expectCode(t, app.get(t, "/api/login/oidc/providers", "", &providers), 200)
+		if len(providers) != 1 || providers[0].Name != "mock" || providers[0].DisplayName != "Mock" {
+			t.Fatalf("提供方列表 = %+v", providers)
+		}
+		expectCode(t, app.call(t, "/api/login/oidc/begin", "", models.OIDCBeginRequest{Provider: "unknown"}, nil), errcode.OIDCProviderNotFound.Code)
+	})
+
+	t.Run("Signup", func(t *testing.T) {
+		var first, second oidcLogin
+		expectCode(t, login(t, oidctest.User{Subject: "new-1", Email: "fresh@example.com", EmailVerified: true, Name: "Fresh"}, &first), 200)
+		if first.Token == "" || first.Email != "fresh@example.com" || first.Username != "Fresh" || !first.EmailVerified {
+			t.Fatalf("注册登录结果 = %+v", first)
+		}
+		// 再次登录按提供方账号标识找到同一个用户，提供方更换邮箱不影响关联
+		expectCode(t, login(t, oidctest.User{Subject: "new-1", Email: "changed@example.com", EmailVerified: true}, &second), 200)
+		if second.UserID != first.UserID {
+			t.Fatalf("再次登录的用户 = %d，应为 %d", second.UserID, first.UserID)
+		}
+	})
+
+	t.Run("LinkByEmail", func(t *testing.T) {
+		var resp oidcLogin
+		expectCode(t, login(t, oidctest.User{Subject: "user-1", Email: app.users[User].Email, EmailVerified: true}, &resp), 200)
+		if resp.UserID != app.users[User].ID {
+			t.Fatalf("关联的用户 = %d，应为 %d", resp.UserID, app.users[User].ID)
+		}
+
+		var identities []*models.UserIdentityInfo
+		expectCode(t, app.get(t, "/api/users/identities", app.tokens[User], &identities), 200)
+		if len(identities) != 1 || identities[0].Provider != "mock" || identities[0].Email != app.users[User].Email || identities[0].LastLoginAt == nil {
+			t.Fatalf("关联的第三方账号 = %+v", identities)
+		}
+	})
+
+	t.Run("UnverifiedEmail", func(t *testing.T) {
+		// 提供方未验证的邮箱不能用于关联或注册，否则可以冒用他人邮箱接管账号
+		expectCode(t, login(t, oidctest.User{Subject: "attacker", Email: app.users[Owner].Email}, nil), errcode.OIDCEmailUnverified.Code)
+
+		// 本地邮箱未验证的账号不自动关联
+		pending := &models.User{Username: "pending", Email: "pending@example.com", Password: "-"}
+		if err := app.userRepo.Create(pending); err != nil {
+			t.Fatal(err)
+		}
+		expectCode(t, login(t, oidctest.User{Subject: "pending", Email: pending.Email, EmailVerified: true}, nil), errcode.OIDCAccountUnverified.Code)
+	})
+
+	t.Run("SignupDisabled", func(t *testing.T) {
+		disabled := config.Default()
+		disabled.OIDC = cfg.OIDC
+		disabled.OIDC.AllowSignup = false
+		app.oidc.OnConfigChange(disabled)
+		defer app.oidc.OnConfigChange(cfg)
+
+		expectCode(t, login(t, oidctest.User{Subject: "nobody", Email: "nobody@example.com", EmailVerified: true}, nil), errcode.OIDCSignupDisabled.Code)
+	})
+
+	t.Run("InvalidToken", func(t *testing.T) {
+		user := oidctest.User{Subject: "owner-1", Email: app.users[Owner].Email, EmailVerified: true}
+
+		srv.ExtraClaims = map[string]interface{}{"nonce": "forged"}
+		expectCode(t, login(t, user, nil), errcode.OIDCLoginFailed.Code)
+		srv.ExtraClaims = map[string]interface{}{"aud": "another-client"}
+		expectCode(t, login(t, user, nil), errcode.OIDCLoginFailed.Code)
+		srv.ExtraClaims = nil
+
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := srv.SigningKey
+		srv.SigningKey = other
+		expectCode(t, login(t, user, nil), errcode.OIDCLoginFailed.Code)
+		srv.SigningKey = key
+	})
+
+	t.Run("Flow", func(t *testing.T) {
+		srv.User = oidctest.User{Subject: "owner-1", Email: app.users[Owner].Email, EmailVerified: true}
+
+		// 授权码与发起流程的 PKCE 校验值绑定，不能配合其他流程的 state 使用
+		first, second := begin(t), begin(t)
+		code := authorize(t, first)
+		expectCode(t, app.call(t, "/api/login/oidc", "", models.OIDCLoginRequest{State: second.State, Code: code}, nil), errcode.OIDCLoginFailed.Code)
+		// 流程只能使用一次，失败后也不能重试
+		expectCode(t, app.call(t, "/api/login/oidc", "", models.OIDCLoginRequest{State: second.State, Code: code}, nil), errcode.OIDCFlowInvalid.Code)
+		expectCode(t, app.call(t, "/api/login/oidc", "", models.OIDCLoginRequest{State: "unknown", Code: code}, nil), errcode.OIDCFlowInvalid.Code)
+
+		// 提供方的授权码已在上面的请求中作废，重新授权后使用原流程登录
+		code = authorize(t, first)
+		expectCode(t, app.call(t, "/api/login/oidc", "", models.OIDCLoginRequest{State: first.State, Code: code}, nil), 200)
+		expectCode(t, app.call(t, "/api/login/oidc", "", models.OIDCLoginRequest{State: first.State, Code: code}, nil), errcode.OIDCFlowInvalid.Code)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		short := config.Default()
+		short.OIDC = cfg.OIDC
+		short.OIDC.FlowExpire = 0
+		app.oidc.OnConfigChange(short)
+		authorization := begin(t)
+		app.oidc.OnConfigChange(cfg)
+
+		code := authorize(t, authorization)
+		expectCode(t, app.call(t, "/api/login/oidc", "", models.OIDCLoginRequest{State: authorization.State, Code: code}, nil), errcode.OIDCFlowInvalid.Code)
+	})
+
+	t.Run("TwoFactor", func(t *testing.T) {
+		var enrollment models.TwoFactorEnrollment
+		expectCode(t, app.call(t, "/api/users/2fa/enroll", app.tokens[Moderator], nil, &enrollment), 200)
+		code, err := totp.GenerateCode(enrollment.Secret, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectCode(t, app.call(t, "/api/users/2fa/confirm", app.tokens[Moderator], models.TwoFactorCodeRequest{Code: code}, nil), 200)
+
+		// 第三方登录不能绕过两步验证
+		var challenge models.TwoFactorChallengeResponse
+		expectCode(t, login(t, oidctest.User{Subject: "moderator-1", Email: app.users[Moderator].Email, EmailVerified: true}, &challenge), 200)
+		if !challenge.TwoFactorRequired || challenge.ChallengeToken == "" {
+			t.Fatalf("开启两步验证后登录结果 = %+v", challenge)
+		}
+	})
+
+	t.Run("Unlink", func(t *testing.T) {
+		var identities []*models.UserIdentityInfo
+		expectCode(t, app.get(t, "/api/users/identities", app.tokens[User], &identities), 200)
+		if len(identities) != 1 {
+			t.Fatalf("关联的第三方账号 = %+v", identities)
+		}
+		// 只能解除自己的关联
+		expectCode(t, app.call(t, "/api/users/identities/unlink", app.tokens[Owner], models.UserIdentityUnlinkRequest{ID: identities[0].ID}, nil), errcode.UserIdentityNotFound.Code)
+		expectCode(t, app.call(t, "/api/users/identities/unlink", app.tokens[User], models.UserIdentityUnlinkRequest{ID: identities[0].ID}, nil), 200)
+		expectCode(t, app.get(t, "/api/users/identities", app.tokens[User], &identities), 200)
+		if len(identities) != 0 {
+			t.Fatalf("解除后关联的第三方账号 = %+v", identities)
+		}
+	})
+
+	t.Run("DisabledUser", func(t *testing.T) {
+		now := time.Now()
+		if err := app.userRepo.SetDisabled(app.users[User].ID, &now); err != nil {
+			t.Fatal(err)
+		}
+		expectCode(t, login(t, oidctest.User{Subject: "user-1", Email: app.users[User].Email, EmailVerified: true}, nil), errcode.UserDisabled.Code)
+	})
+}
+
+// oidcLogin 登录成功的响应，用户信息与令牌平铺在 data 中
+type oidcLogin struct {
+	models.UserInfo
+	models.TokenResponse
+}
@@ -0,0 +1,235 @@
+package routes_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go_app/config"
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/pkg/softauthn"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestPasskeyFlow 使用 softauthn 软件身份验证器走完通行密钥的注册和免密码登录流程
+func TestPasskeyFlow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := newApp(t)
+	origin := config.Default().WebAuthn.RPOrigins[0]
+	laptop, phone := softauthn.New(origin), softauthn.New(origin)
+	phone.Synced = true
+
+	register := func(t *testing.T, auth *softauthn.Authenticator, actor, name string) *models.PasskeyInfo {
+		t.Helper()
+		options := begin(t, app, "/api/users/passkeys/register/begin", app.tokens[actor])
+		credential, err := auth.Create(options.Options)
+		if err != nil {
+			t.Fatalf("创建凭证失败: %v", err)
+		}
+		var info models.PasskeyInfo
+		expectCode(t, app.call(t, "/api/users/passkeys/register", app.tokens[actor], models.PasskeyRegisterRequest{
+			CeremonyToken: options.CeremonyToken, Credential: credential, Name: name,
+		}, &info), 200)
+		return &info
+	}
+	login := func(t *testing.T, auth *softauthn.Authenticator) (int, *models.UserInfo) {
+		t.Helper()
+		options := begin(t, app, "/api/login/passkey/begin", "")
+		assertion, err := auth.Get(options.Options)
+		if err != nil {
+			t.Fatalf("生成登录响应失败: %v", err)
+		}
+		var user models.UserInfo
+		code := app.call(t, "/api/login/passkey", "", models.PasskeyLoginRequest{CeremonyToken: options.CeremonyToken, Credential: assertion}, &user)
+		return code, &user
+	}
+
+	t.Run("RegisterAndLogin", func(t *testing.T) {
+		first := register(t, laptop, Owner, "Laptop")
+		second := register(t, phone, Owner, "")
+		if first.Name != "Laptop" || second.Name != "通行密钥 2" || !second.BackupEligible || !second.BackupState {
+			t.Fatalf("注册结果 = %+v, %+v", first, second)
+		}
+
+		var list []*models.PasskeyInfo
+		expectCode(t, app.get(t, "/api/users/passkeys", app.tokens[Owner], &list), 200)
+		if len(list) != 2 || list[0].ID != first.ID || list[1].ID != second.ID {
+			t.Fatalf("通行密钥列表 = %+v", list)
+		}
+
+		for _, auth := range []*softauthn.Authenticator{laptop, phone, laptop} {
+			code, user := login(t, auth)
+			expectCode(t, code, 200)
+			if user.UserID != app.users[Owner].ID {
+				t.Fatalf("登录用户 = %d, want %d", user.UserID, app.users[Owner].ID)
+			}
+		}
+		expectCode(t, app.get(t, "/api/users/passkeys", app.tokens[Owner], &list), 200)
+		if list[0].LastUsedAt == nil {
+			t.Fatal("登录后应记录最近使用时间")
+		}
+	})
+
+	t.Run("LoginIssuesSessionTokens", func(t *testing.T) {
+		options := begin(t, app, "/api/login/passkey/begin", "")
+		assertion, err := laptop.Get(options.Options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var tokens models.TokenResponse
+		expectCode(t, app.call(t, "/api/login/passkey", "", models.PasskeyLoginRequest{CeremonyToken: options.CeremonyToken, Credential: assertion}, &tokens), 200)
+		if tokens.Token == "" || tokens.RefreshToken == "" {
+			t.Fatalf("登录响应缺少令牌: %+v", tokens)
+		}
+		var sessions []*models.SessionInfo
+		expectCode(t, app.get(t, "/api/users/sessions", tokens.Token, &sessions), 200)
+
+		// 流程令牌只能使用一次
+		expectCode(t, app.call(t, "/api/login/passkey", "", models.PasskeyLoginRequest{CeremonyToken: options.CeremonyToken, Credential: assertion}, nil),
+			errcode.PasskeyCeremonyInvalid.Code)
+	})
+
+	t.Run("ExcludeRegistered", func(t *testing.T) {
+		options := begin(t, app, "/api/users/passkeys/register/begin", app.tokens[Owner])
+		if _, err := laptop.Create(options.Options); !errors.Is(err, softauthn.ErrExcluded) {
+			t.Fatalf("同一身份验证器重复注册应被排除，err = %v", err)
+		}
+	})
+
+	t.Run("CeremonyBelongsToUser", func(t *testing.T) {
+		options := begin(t, app, "/api/users/passkeys/register/begin", app.tokens[User])
+		credential, err := softauthn.New(origin).Create(options.Options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectCode(t, app.call(t, "/api/users/passkeys/register", app.tokens[Owner], models.PasskeyRegisterRequest{
+			CeremonyToken: options.CeremonyToken, Credential: credential,
+		}, nil), errcode.PasskeyCeremonyInvalid.Code)
+	})
+
+	t.Run("WrongOrigin", func(t *testing.T) {
+		options := begin(t, app, "/api/users/passkeys/register/begin", app.tokens[User])
+		credential, err := softauthn.New("http://localhost:9999").Create(options.Options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectCode(t, app.call(t, "/api/users/passkeys/register", app.tokens[User], models.PasskeyRegisterRequest{
+			CeremonyToken: options.CeremonyToken, Credential: credential,
+		}, nil), errcode.PasskeyVerifyFailed.Code)
+	})
+
+	t.Run("ClonedCredential", func(t *testing.T) {
+		clone := softauthn.New(origin)
+		register(t, clone, User, "clone")
+		code, _ := login(t, clone)
+		expectCode(t, code, 200)
+
+		// 签名计数器回退说明凭证可能被复制到了其他设备
+		clone.Credentials()[0].SignCount = 0
+		code, _ = login(t, clone)
+		expectCode(t, code, errcode.PasskeyVerifyFailed.Code)
+	})
+
+	t.Run("RenameAndDelete", func(t *testing.T) {
+		var list []*models.PasskeyInfo
+		expectCode(t, app.get(t, "/api/users/passkeys", app.tokens[Owner], &list), 200)
+		phoneID := list[1].ID
+
+		// 只能管理自己的通行密钥
+		expectCode(t, app.call(t, "/api/users/passkeys/rename", app.tokens[User], models.PasskeyRenameRequest{ID: phoneID, Name: "x"}, nil), errcode.PasskeyNotFound.Code)
+		expectCode(t, app.call(t, "/api/users/passkeys/delete", app.tokens[User], models.PasskeyDeleteRequest{ID: phoneID}, nil), errcode.PasskeyNotFound.Code)
+
+		expectCode(t, app.call(t, "/api/users/passkeys/rename", app.tokens[Owner], models.PasskeyRenameRequest{ID: phoneID, Name: "Phone"}, nil), 200)
+		expectCode(t, app.get(t, "/api/users/passkeys", app.tokens[Owner], &list), 200)
+		if list[1].Name != "Phone" {
+			t.Fatalf("修改后名称 = %q", list[1].Name)
+		}
+
+		expectCode(t, app.call(t, "/api/users/passkeys/delete", app.tokens[Owner], models.PasskeyDeleteRequest{ID: phoneID}, nil), 200)
+		code, _ := login(t, phone)
+		expectCode(t, code, errcode.PasskeyVerifyFailed.Code)
+		code, _ = login(t, laptop)
+		expectCode(t, code, 200)
+	})
+
+	t.Run("DisabledUser", func(t *testing.T) {
+		disabled := softauthn.New(origin)
+		register(t, disabled, Moderator, "")
+		now := time.Now()
+		if err := app.userRepo.SetDisabled(app.users[Moderator].ID, &now); err != nil {
+			t.Fatal(err)
+		}
+		code, _ := login(t, disabled)
+		expectCode(t, code, errcode.UserDisabled.Code)
+	})
+}
+
+// passkeyOptions 开始注册/登录接口的响应，Options 原样交给软件身份验证器
+type passkeyOptions struct {
+	CeremonyToken string          `json:"ceremonyToken"`
+	Options       json.RawMessage `json:"options"`
+}
+
+func begin(t *testing.T, app *app, path, token string) *passkeyOptions {
+	t.Helper()
+	var options passkeyOptions
+	expectCode(t, app.call(t, path, token, nil, &options), 200)
+	return &options
+}
+
+// call 发起 JSON POST 请求，返回响应码并将 data 解析到 out
+func (a *app) call(t *testing.T, path, token string, body, out interface{}) int {
+	t.Helper()
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	return a.serve(t, req, token, out)
+}
+
+func (a *app) get(t *testing.T, path, token string, out interface{}) int {
+	t.Helper()
+	return a.serve(t, httptest.NewRequest(http.MethodGet, path, nil), token, out)
+}
+
+func (a *app) serve(t *testing.T, req *http.Request, token string, out interface{}) int {
+	t.Helper()
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	a.engine.ServeHTTP(w, req)
+
+	var resp struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v, body: %s", err, w.Body.String())
+	}
+	if resp.Code == 200 && out != nil && len(resp.Data) > 0 {
+		if err := json.Unmarshal(resp.Data, out); err != nil {
+			t.Fatalf("解析响应数据失败: %v, data: %s", err, resp.Data)
+		}
+	}
+	if resp.Code != 200 {
+		t.Logf("%s %s: %d %s", req.Method, req.URL.Path, resp.Code, resp.Message)
+	}
+	return resp.Code
+}
+
+func expectCode(t *testing.T, got, want int) {
+	t.Helper()
+	if got != want {
+		t.Fatalf("响应码 = %d, want %d", got, want)
+	}
+}
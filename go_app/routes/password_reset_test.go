@@ -0,0 +1,74 @@
+package routes_test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"go_app/models"
+	"go_app/pkg/errcode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestPasswordReset 校验找回密码：重置邮件由后台任务发送，冷却时间内重复申请不再发送，
+// 链接使用后可以立即重新申请
+func TestPasswordReset(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := newApp(t)
+	email := app.users[Owner].Email
+
+	forgot := func() {
+		t.Helper()
+		expectCode(t, app.call(t, "/api/password/forgot", "", models.PasswordForgotRequest{Email: email}, nil), 200)
+	}
+
+	forgot()
+	token := app.waitResetMail(t, email, 1)
+
+	// 冷却时间内的申请不再发送邮件，响应与发送时相同
+	for i := 0; i < 3; i++ {
+		forgot()
+	}
+	expectCode(t, app.call(t, "/api/password/forgot", "", models.PasswordForgotRequest{Email: "missing@example.com"}, nil), 200)
+	time.Sleep(200 * time.Millisecond)
+	if n := app.resetMails(email); n != 1 {
+		t.Fatalf("冷却时间内发送了 %d 封重置邮件, want 1", n)
+	}
+
+	// 链接使用后可以立即重新申请
+	expectCode(t, app.call(t, "/api/password/reset", "", models.PasswordResetRequest{Token: token, NewPassword: "654321"}, nil), 200)
+	expectCode(t, app.call(t, "/api/password/reset", "", models.PasswordResetRequest{Token: token, NewPassword: "654321"}, nil), errcode.PasswordResetTokenInvalid.Code)
+	forgot()
+	app.waitResetMail(t, email, 2)
+}
+
+var resetTokenPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// waitResetMail 等待发送给 email 的第 n 封重置邮件，返回其中的令牌
+func (a *app) waitResetMail(t *testing.T, email string, n int) string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for a.resetMails(email) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待第 %d 封重置邮件超时", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	token := resetTokenPattern.FindString(a.mail.Last(email).Body)
+	if token == "" {
+		t.Fatal("重置邮件中没有令牌")
+	}
+	return token
+}
+
+// resetMails 已发送给 email 的重置邮件数
+func (a *app) resetMails(email string) int {
+	n := 0
+	for _, msg := range a.mail.Messages() {
+		if msg.To == email && msg.Subject == "重置密码" {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,136 @@
+package routes_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"go_app/config"
+	"go_app/middleware"
+	"go_app/models"
+	"go_app/pkg/errcode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestRateLimit 校验接口限流：按路由匹配规则、按 IP / 用户 / API Key 分别计数、
+// RateLimit-* 和 Retry-After 响应头以及配置热加载
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := newApp(t)
+	cfg := config.Default()
+	cfg.RateLimit = config.RateLimitConfig{
+		Enabled: true,
+		Store:   config.RateLimitStoreMemory,
+		Default: config.RateLimitRule{Algorithm: config.RateLimitTokenBucket, Limit: 5, Window: 60, Key: config.RateLimitKeyUser},
+		Routes: []config.RateLimitRule{
+			{Route: "POST /api/login*", Algorithm: config.RateLimitSlidingWindow, Limit: 3, Window: 60, Key: config.RateLimitKeyIP},
+			{Route: "POST /api/password/forgot", Algorithm: config.RateLimitSlidingWindow, Limit: 1, Window: 60, Key: config.RateLimitKeyAPIKey},
+		},
+	}
+	app.limiter.OnConfigChange(cfg)
+
+	// 每次使用不同的邮箱，避免触发登录失败限制
+	attempt := 0
+	login := func() models.LoginRequest {
+		attempt++
+		return models.LoginRequest{Email: "nobody" + strconv.Itoa(attempt) + "@example.com", Password: "wrong-password"}
+	}
+
+	t.Run("Route", func(t *testing.T) {
+		for i := 2; i >= 0; i-- {
+			code, header := app.request(t, "POST", "/api/login", "", "198.51.100.1", nil, login())
+			expectCode(t, code, errcode.LoginFailed.Code)
+			expectHeader(t, header, "RateLimit-Limit", "3")
+			expectHeader(t, header, "RateLimit-Remaining", strconv.Itoa(i))
+			expectHeader(t, header, "RateLimit-Policy", "3;w=60")
+		}
+		// 前缀规则匹配的接口共用计数
+		code, header := app.request(t, "POST", "/api/login/2fa", "", "198.51.100.1", nil,
+			models.TwoFactorLoginRequest{ChallengeToken: "invalid", Code: "123456"})
+		expectCode(t, code, errcode.TooManyRequests.Code)
+		if retryAfter, _ := strconv.Atoi(header.Get("Retry-After")); retryAfter <= 0 || retryAfter > 120 {
+			t.Fatalf("Retry-After = %q", header.Get("Retry-After"))
+		}
+
+		// 按 IP 计数，其他 IP 不受影响
+		code, _ = app.request(t, "POST", "/api/login", "", "198.51.100.2", nil, login())
+		expectCode(t, code, errcode.LoginFailed.Code)
+	})
+
+	t.Run("User", func(t *testing.T) {
+		for i := 4; i >= 0; i-- {
+			code, header := app.request(t, "GET", "/api/users/sessions", app.tokens[Owner], "198.51.100.3", nil, nil)
+			expectCode(t, code, 200)
+			expectHeader(t, header, "RateLimit-Remaining", strconv.Itoa(i))
+		}
+		code, _ := app.request(t, "GET", "/api/users/2fa", app.tokens[Owner], "198.51.100.3", nil, nil)
+		expectCode(t, code, errcode.TooManyRequests.Code)
+
+		// 按用户计数，同一 IP 的其他用户不受影响
+		code, _ = app.request(t, "GET", "/api/users/sessions", app.tokens[User], "198.51.100.3", nil, nil)
+		expectCode(t, code, 200)
+	})
+
+	t.Run("APIKey", func(t *testing.T) {
+		forgot := models.PasswordForgotRequest{Email: "owner@example.com"}
+		keyA := map[string]string{middleware.APIKeyHeader: "key-a"}
+		code, _ := app.request(t, "POST", "/api/password/forgot", "", "198.51.100.4", keyA, forgot)
+		expectCode(t, code, 200)
+		code, _ = app.request(t, "POST", "/api/password/forgot", "", "198.51.100.5", keyA, forgot)
+		expectCode(t, code, errcode.TooManyRequests.Code)
+
+		code, _ = app.request(t, "POST", "/api/password/forgot", "", "198.51.100.4", map[string]string{middleware.APIKeyHeader: "key-b"}, forgot)
+		expectCode(t, code, 200)
+		// 没有 API Key 时按 IP 计数
+		code, _ = app.request(t, "POST", "/api/password/forgot", "", "198.51.100.4", nil, forgot)
+		expectCode(t, code, 200)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		disabled := *cfg
+		disabled.RateLimit.Enabled = false
+		app.limiter.OnConfigChange(&disabled)
+		defer app.limiter.OnConfigChange(cfg)
+
+		code, header := app.request(t, "POST", "/api/login", "", "198.51.100.1", nil, login())
+		expectCode(t, code, errcode.LoginFailed.Code)
+		expectHeader(t, header, "RateLimit-Limit", "")
+	})
+}
+
+// request 从客户端 IP ip 发起请求，body 不为 nil 时以 JSON 提交，返回响应码和响应头
+func (a *app) request(t *testing.T, method, path, token, ip string, header map[string]string, body interface{}) (int, http.Header) {
+	t.Helper()
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = ip + ":40000"
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	a.engine.ServeHTTP(w, req)
+
+	var resp models.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v, body: %s", err, w.Body.String())
+	}
+	return resp.Code, w.Header()
+}
+
+func expectHeader(t *testing.T, header http.Header, name, want string) {
+	t.Helper()
+	if got := header.Get(name); got != want {
+		t.Fatalf("%s = %q, want %q", name, got, want)
+	}
+}
@@ -3,11 +3,13 @@ package routes
 import (
 	"go_app/controllers"
 	"go_app/middleware"
+	"go_app/models"
+	"go_app/services"
 
 	"github.com/gin-gonic/gin"
 )
 
-// SetupRoutes 设置路由
+// SetupRoutes 设置路由，oauthServer 为 oauth_server.enabled，关闭时不注册授权确认和 OAuth 客户端管理接口
 // @Summary 设置API路由
 // @Description 配置所有API路由和中间件
 // @Tags 系统
@@ -15,22 +17,118 @@ import (
 // @Produce json
 // @Param userController body controllers.UserController true "用户控制器"
 // @Router /api [post]
-func SetupRoutes(api *gin.RouterGroup, userController *controllers.UserController) {
+func SetupRoutes(api *gin.RouterGroup, userController *controllers.UserController, sessionController *controllers.SessionController, roleController *controllers.RoleController, passwordController *controllers.PasswordController, emailController *controllers.EmailController, twoFactorController *controllers.TwoFactorController, passkeyController *controllers.PasskeyController, apiKeyController *controllers.APIKeyController, oidcController *controllers.OIDCController, oauthController *controllers.OAuthController, oauthClientController *controllers.OAuthClientController, presenceController *controllers.PresenceController, tokenService services.TokenService, apiKeyService *services.APIKeyService, roleService *services.RoleService, emailVerificationService *services.EmailVerificationService, rateLimitService *services.RateLimitService, oauthServer bool) {
+    // 每个请求只经过一次限流；需要认证的路由组在认证之后限流，才能按用户计数
+    rateLimit := middleware.RateLimit(rateLimitService)
+
     // 无需认证的路由组
-    api.POST("/register", userController.Register)
-    api.POST("/login", userController.Login)
+    public := api.Group("", rateLimit)
+    public.POST("/register", userController.Register)
+    public.POST("/login", userController.Login)
+    public.POST("/login/2fa", twoFactorController.Login)
+    public.POST("/login/passkey/begin", passkeyController.BeginLogin)
+    public.POST("/login/passkey", passkeyController.Login)
+    public.GET("/login/oidc/providers", oidcController.Providers)
+    public.POST("/login/oidc/begin", oidcController.Begin)
+    public.POST("/login/oidc", oidcController.Login)
+    public.POST("/token/refresh", sessionController.RefreshToken)
+    public.POST("/password/forgot", passwordController.ForgotPassword)
+    public.POST("/password/reset", passwordController.ResetPassword)
+    public.POST("/email/verify", emailController.VerifyEmail)
 
-    // 需要认证的路由组
+    // 需要认证的路由组；操作单个用户数据的接口由控制器校验数据归属，本人以外需要对应权限。
+    // 邮箱未验证的用户不能访问 email_verification.restricted 中配置的接口
     users := api.Group("/users")
-    users.Use(middleware.AuthMiddleware())
+    users.Use(middleware.AuthMiddleware(tokenService, apiKeyService), rateLimit, middleware.RequireVerifiedEmail(emailVerificationService))
     {
-        users.GET("", userController.ListUsers)
+        users.GET("", middleware.RequirePermission(roleService, models.PermUsersList), userController.ListUsers)
         users.GET("/info", userController.GetUser)
+        users.GET("/presence", presenceController.Get)
         users.POST("/update", userController.UpdateUser)
         users.POST("/delete", userController.DeleteUser)
-        users.POST("/email", userController.UpdateEmail)
-        users.POST("/password", userController.ChangePassword)
-        users.POST("/logout", userController.Logout)
         users.POST("/avatar", userController.UploadAvatar)  // 添加头像上传路由
     }
+
+    // 账号安全相关的接口只能使用登录会话访问，不接受 API Key
+    account := users.Group("", middleware.RequireSession())
+    {
+        account.POST("/email", userController.UpdateEmail)
+        account.POST("/email/resend", emailController.ResendVerification)
+        account.POST("/password", userController.ChangePassword)
+        account.POST("/logout", userController.Logout)
+
+        // 登录会话（设备）管理
+        account.GET("/sessions", sessionController.ListSessions)
+        account.POST("/sessions/revoke", sessionController.RevokeSession)
+        account.POST("/sessions/revoke-all", sessionController.RevokeAllSessions)
+
+        // 两步验证
+        account.GET("/2fa", twoFactorController.Status)
+        account.POST("/2fa/enroll", twoFactorController.Enroll)
+        account.POST("/2fa/confirm", twoFactorController.Confirm)
+        account.POST("/2fa/disable", twoFactorController.Disable)
+        account.POST("/2fa/recovery-codes", twoFactorController.RegenerateRecoveryCodes)
+
+        // 通行密钥
+        account.GET("/passkeys", passkeyController.List)
+        account.POST("/passkeys/register/begin", passkeyController.BeginRegistration)
+        account.POST("/passkeys/register", passkeyController.Register)
+        account.POST("/passkeys/rename", passkeyController.Rename)
+        account.POST("/passkeys/delete", passkeyController.Delete)
+
+        // API Key
+        account.GET("/api-keys", apiKeyController.List)
+        account.POST("/api-keys/create", apiKeyController.Create)
+        account.POST("/api-keys/revoke", apiKeyController.Revoke)
+
+        // 关联的第三方账号
+        account.GET("/identities", oidcController.Identities)
+        account.POST("/identities/unlink", oidcController.Unlink)
+
+        // 授权其他应用使用本账号登录：授权确认页面和已授权的应用
+        if oauthServer {
+            account.GET("/oauth/authorize", oauthController.AuthorizeInfo)
+            account.POST("/oauth/authorize", oauthController.Consent)
+            account.GET("/oauth/consents", oauthController.Consents)
+            account.POST("/oauth/consents/revoke", oauthController.RevokeConsent)
+        }
+    }
+
+    // 管理接口，每个接口要求对应的权限
+    admin := api.Group("/admin")
+    admin.Use(middleware.AuthMiddleware(tokenService, apiKeyService), rateLimit, middleware.RequireVerifiedEmail(emailVerificationService))
+    {
+        // 角色管理
+        roles := admin.Group("", middleware.RequirePermission(roleService, models.PermRolesManage))
+        roles.GET("/roles", roleController.ListRoles)
+        roles.GET("/users/roles", roleController.UserRoles)
+        roles.POST("/users/roles/assign", roleController.AssignRole)
+        roles.POST("/users/roles/revoke", roleController.RevokeRole)
+
+        admin.POST("/users/email", middleware.RequirePermission(roleService, models.PermUsersUpdate), userController.AdminUpdateEmail)
+        admin.POST("/users/2fa/reset", middleware.RequirePermission(roleService, models.PermUsersUpdate), twoFactorController.Reset)
+        admin.POST("/users/unlock", middleware.RequirePermission(roleService, models.PermUsersUpdate), userController.UnlockLogin)
+
+        // OAuth 客户端管理
+        if oauthServer {
+            oauthClients := admin.Group("/oauth/clients", middleware.RequirePermission(roleService, models.PermOAuthManage))
+            oauthClients.GET("", oauthClientController.List)
+            oauthClients.POST("/create", oauthClientController.Create)
+            oauthClients.POST("/delete", oauthClientController.Delete)
+        }
+    }
+}
+
+// SetupOAuthRoutes 注册 OAuth2 / OIDC 授权服务的协议接口，仅在 oauth_server.enabled 时调用。
+// 路径由 OAuth 和 OIDC 规范约定，位于 /api 之外；客户端使用自己的凭证或访问令牌调用，不经过登录认证
+func SetupOAuthRoutes(r *gin.Engine, oauthController *controllers.OAuthController, rateLimitService *services.RateLimitService) {
+    r.GET("/.well-known/openid-configuration", oauthController.Discovery)
+
+    oauth := r.Group("/oauth", middleware.RateLimit(rateLimitService))
+    oauth.GET("/authorize", oauthController.Authorize)
+    oauth.POST("/token", oauthController.Token)
+    oauth.GET("/userinfo", oauthController.UserInfo)
+    oauth.POST("/userinfo", oauthController.UserInfo)
+    oauth.POST("/revoke", oauthController.Revoke)
+    oauth.POST("/introspect", oauthController.Introspect)
 }
@@ -3,22 +3,54 @@ package services
 import (
     "go_app/models"
     "go_app/pkg/websocket"
+    "go_app/repository"
 )
 
 type ActivityService struct {
-    wsManager *websocket.Manager
+    wsManager     *websocket.Manager
+    activities    repository.ActivityRepository
+    notifications repository.NotificationRepository
 }
 
-func NewActivityService(wsManager *websocket.Manager) *ActivityService {
+func NewActivityService(wsManager *websocket.Manager, activities repository.ActivityRepository, notifications repository.NotificationRepository) *ActivityService {
     return &ActivityService{
-        wsManager: wsManager,
+        wsManager:     wsManager,
+        activities:    activities,
+        notifications: notifications,
     }
 }
 
+// CreateActivity 保存活动并推送给订阅了 activity 主题的用户
+func (s *ActivityService) CreateActivity(activity *models.Activity) error {
+    if err := s.activities.Create(activity); err != nil {
+        return err
+    }
+    return s.PushActivity(activity)
+}
+
+// Notify 保存通知并推送给在线用户，离线用户上线后可通过通知列表查看
+func (s *ActivityService) Notify(userID uint, notification *models.Notification) error {
+    notification.UserID = userID
+    if err := s.notifications.Create(notification); err != nil {
+        return err
+    }
+    return s.PushNotification(userID, notification)
+}
+
+// Join 用户参与活动，参与者可以订阅活动的实时主题 activity:{id}
+func (s *ActivityService) Join(activityID, userID uint) error {
+    return s.activities.AddParticipant(activityID, userID)
+}
+
+// Leave 用户退出活动
+func (s *ActivityService) Leave(activityID, userID uint) error {
+    return s.activities.RemoveParticipant(activityID, userID)
+}
+
 func (s *ActivityService) PushActivity(activity *models.Activity) error {
     return s.wsManager.BroadcastToTopic("activity", websocket.MessageTypeActivity, activity)
 }
 
 func (s *ActivityService) PushNotification(userID uint, notification *models.Notification) error {
     return s.wsManager.SendFormattedMessage(userID, websocket.MessageTypeNotification, notification)
-}
\ No newline at end of file
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"errors"
+	"go_app/config"
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/pkg/logger"
+	"go_app/repository"
+	"go_app/utils"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// apiKeyPrefix API Key 明文的固定前缀，便于在代码和日志中识别泄露的密钥
+	apiKeyPrefix = "gapp_"
+	// apiKeyTouchInterval 最近使用时间的更新间隔，避免每个请求都写数据库
+	apiKeyTouchInterval = time.Minute
+)
+
+// APIKeyService 个人 API Key：用户为脚本和第三方集成创建的长期凭证，
+// 明文格式为 gapp_<8 位可见前缀>_<随机密钥>，只在创建时返回一次，数据库保存完整明文的哈希值
+type APIKeyService struct {
+	users repository.UserRepository
+	keys  repository.APIKeyRepository
+
+	mu  sync.RWMutex
+	cfg config.APIKeyConfig
+}
+
+func NewAPIKeyService(users repository.UserRepository, keys repository.APIKeyRepository, cfg config.APIKeyConfig) *APIKeyService {
+	return &APIKeyService{users: users, keys: keys, cfg: cfg}
+}
+
+// OnConfigChange 配置热加载回调，更新每个用户的 API Key 数量上限
+func (s *APIKeyService) OnConfigChange(cfg *config.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg.APIKey
+}
+
+func (s *APIKeyService) current() config.APIKeyConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
+
+// Create 创建 API Key，返回模型和明文
+func (s *APIKeyService) Create(userID uint, name string, scopes []string, expiresAt *time.Time) (*models.APIKey, string, error) {
+	scopeList, err := normalizeAPIKeyScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", errcode.InvalidParams
+	}
+	visible, err := utils.RandomHex(4)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := utils.RandomHex(24)
+	if err != nil {
+		return nil, "", err
+	}
+	plain := apiKeyPrefix + visible + "_" + secret
+	key := &models.APIKey{
+		UserID:    userID,
+		Name:      strings.TrimSpace(name),
+		Prefix:    apiKeyPrefix + visible,
+		KeyHash:   utils.HashToken(plain),
+		Scopes:    strings.Join(scopeList, ","),
+		ExpiresAt: expiresAt,
+	}
+	if err := s.keys.Create(key, s.current().MaxPerUser); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, "", errcode.APIKeyLimitExceeded
+		}
+		return nil, "", err
+	}
+	return key, plain, nil
+}
+
+// List 列出用户的全部 API Key
+func (s *APIKeyService) List(userID uint) ([]*models.APIKey, error) {
+	return s.keys.ListByUser(userID)
+}
+
+// Revoke 撤销 API Key，撤销后立即失效
+func (s *APIKeyService) Revoke(userID, id uint) error {
+	if err := s.keys.Delete(userID, id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return errcode.APIKeyNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Authenticate 校验 API Key 明文，返回 API Key 并记录最近使用时间和客户端 IP。
+// 不存在、已过期或所属用户已删除时返回 APIKeyInvalid，用户被禁用时返回 UserDisabled
+func (s *APIKeyService) Authenticate(plain, ip string) (*models.APIKey, error) {
+	if !strings.HasPrefix(plain, apiKeyPrefix) {
+		return nil, errcode.APIKeyInvalid
+	}
+	key, err := s.keys.FindByHash(utils.HashToken(plain))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errcode.APIKeyInvalid
+		}
+		return nil, err
+	}
+	if key.IsExpired() {
+		return nil, errcode.APIKeyInvalid
+	}
+	user, err := s.users.FindByID(key.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errcode.APIKeyInvalid
+		}
+		return nil, err
+	}
+	if user.IsDisabled() {
+		return nil, errcode.UserDisabled
+	}
+
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval || key.LastUsedIP != ip {
+		// 使用记录只用于展示，更新失败不影响本次请求
+		if err := s.keys.Touch(key.ID, now, ip); err != nil {
+			logger.Warnf("更新 API Key %d 使用记录失败: %v", key.ID, err)
+		}
+		key.LastUsedAt, key.LastUsedIP = &now, ip
+	}
+	return key, nil
+}
+
+// normalizeAPIKeyScopes 校验并去重授权范围，保持传入顺序
+func normalizeAPIKeyScopes(scopes []string) ([]string, error) {
+	valid := make(map[string]bool)
+	for _, scope := range models.APIKeyScopes() {
+		valid[scope] = true
+	}
+	seen := make(map[string]bool)
+	var list []string
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !valid[scope] {
+			return nil, errcode.APIKeyScopeInvalid
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			list = append(list, scope)
+		}
+	}
+	if len(list) == 0 {
+		return nil, errcode.APIKeyScopeInvalid
+	}
+	return list, nil
+}
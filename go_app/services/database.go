@@ -3,7 +3,12 @@ package services
 import (
     "fmt"
     "go_app/config"
+    "net/url"
+    "time"
+
+    "github.com/glebarez/sqlite"
     "gorm.io/driver/mysql"
+    "gorm.io/driver/postgres"
     "gorm.io/gorm"
 )
 
@@ -11,30 +16,82 @@ import (
 var DB *gorm.DB
 
 // ConnectDB 初始化数据库连接
-func ConnectDB() error {
-    cfg, err := config.LoadConfig()
+func ConnectDB(cfg config.DatabaseConfig) error {
+    dialector, err := newDialector(cfg)
     if err != nil {
         return err
     }
 
-    dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-        cfg.Database.User,
-        cfg.Database.Password,
-        cfg.Database.Host,
-        cfg.Database.Port,
-        cfg.Database.DBName,
-    )
-    
-    db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+    // 将各驱动的唯一约束等错误统一转换为 gorm.ErrDuplicatedKey，便于仓储层识别
+    db, err := gorm.Open(dialector, &gorm.Config{TranslateError: true})
     if err != nil {
         return err
     }
 
+    if err := configurePool(db, cfg); err != nil {
+        return err
+    }
+
     DB = db
     return nil
 }
 
+// newDialector 根据驱动类型创建 GORM 方言
+func newDialector(cfg config.DatabaseConfig) (gorm.Dialector, error) {
+    switch cfg.Driver {
+    case "", config.DatabaseDriverMySQL:
+        dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+            cfg.User,
+            cfg.Password,
+            cfg.Host,
+            cfg.Port,
+            cfg.DBName,
+        )
+        return mysql.Open(dsn), nil
+    case config.DatabaseDriverPostgres:
+        sslMode := cfg.SSLMode
+        if sslMode == "" {
+            sslMode = "disable"
+        }
+        dsn := (&url.URL{
+            Scheme:   "postgres",
+            User:     url.UserPassword(cfg.User, cfg.Password),
+            Host:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+            Path:     cfg.DBName,
+            RawQuery: url.Values{"sslmode": {sslMode}}.Encode(),
+        }).String()
+        return postgres.Open(dsn), nil
+    case config.DatabaseDriverSQLite:
+        // 纯 Go 实现的 SQLite 驱动，无需 CGO；开启外键约束并设置锁等待时间
+        return sqlite.Open(cfg.Path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"), nil
+    }
+    return nil, fmt.Errorf("不支持的数据库驱动: %s", cfg.Driver)
+}
+
+// configurePool 设置连接池参数
+func configurePool(db *gorm.DB, cfg config.DatabaseConfig) error {
+    sqlDB, err := db.DB()
+    if err != nil {
+        return err
+    }
+
+    // SQLite 内存数据库的数据只存在于单个连接中，必须固定使用同一个连接且不能回收
+    if cfg.Driver == config.DatabaseDriverSQLite && cfg.Path == config.SQLiteMemory {
+        sqlDB.SetMaxOpenConns(1)
+        sqlDB.SetMaxIdleConns(1)
+        sqlDB.SetConnMaxLifetime(0)
+        sqlDB.SetConnMaxIdleTime(0)
+        return nil
+    }
+
+    sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+    sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+    sqlDB.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime) * time.Second)
+    sqlDB.SetConnMaxIdleTime(time.Duration(cfg.ConnMaxIdleTime) * time.Second)
+    return nil
+}
+
 // GetDB 获取数据库实例
 func GetDB() *gorm.DB {
     return DB
-}
\ No newline at end of file
+}
@@ -0,0 +1,340 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_app/config"
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/pkg/logger"
+	"go_app/pkg/mailer"
+	"go_app/repository"
+	"go_app/utils"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// emailVerificationWorkers 发送验证邮件的后台任务数
+	emailVerificationWorkers = 2
+	// emailVerificationQueueSize 等待发送的邮件数上限，超出时拒绝新的发送请求
+	emailVerificationQueueSize = 100
+)
+
+// verificationMail 等待发送的邮件
+type verificationMail struct {
+	msg *mailer.Message
+	// userID 不为 0 时为验证邮件，发送失败后删除该用户的令牌并取消发往该邮箱的修改，用户可以立即重新发送
+	userID uint
+}
+
+// EmailVerificationService 注册和修改邮箱时通过邮件中的一次性令牌验证邮箱，
+// 并限制邮箱未验证的账号访问配置中的接口
+type EmailVerificationService struct {
+	users  repository.UserRepository
+	tokens repository.EmailVerificationRepository
+	mailer mailer.Mailer
+	queue  chan verificationMail
+
+	mu         sync.RWMutex
+	cfg        config.EmailVerificationConfig
+	restricted []routeRule
+}
+
+func NewEmailVerificationService(users repository.UserRepository, tokens repository.EmailVerificationRepository, m mailer.Mailer, cfg config.EmailVerificationConfig) *EmailVerificationService {
+	s := &EmailVerificationService{users: users, tokens: tokens, mailer: m, queue: make(chan verificationMail, emailVerificationQueueSize)}
+	s.apply(cfg)
+	return s
+}
+
+// OnConfigChange 配置热加载回调，更新令牌有效期、验证页面地址、发送间隔和受限接口
+func (s *EmailVerificationService) OnConfigChange(cfg *config.Config) {
+	s.apply(cfg.EmailVerification)
+}
+
+func (s *EmailVerificationService) apply(cfg config.EmailVerificationConfig) {
+	rules := make([]routeRule, 0, len(cfg.Restricted))
+	for _, rule := range cfg.Restricted {
+		// 配置加载时已校验格式
+		r, err := parseRouteRule(rule)
+		if err != nil {
+			continue
+		}
+		rules = append(rules, r)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg
+	s.restricted = rules
+}
+
+func (s *EmailVerificationService) current() config.EmailVerificationConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
+
+// Run 启动固定数量的后台任务发送 enqueue 提交的邮件，直到 ctx 结束
+func (s *EmailVerificationService) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < emailVerificationWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case mail := <-s.queue:
+					s.deliver(mail)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// enqueue 提交邮件，由 Run 启动的后台任务发送；等待发送的邮件已满时丢弃、记录日志并返回 false
+func (s *EmailVerificationService) enqueue(mail verificationMail) bool {
+	select {
+	case s.queue <- mail:
+		return true
+	default:
+		logger.Warnf("等待发送的验证邮件过多，丢弃发送给 %s 的邮件", mail.msg.To)
+		return false
+	}
+}
+
+// deliver 发送邮件，验证邮件发送失败时撤销对应的令牌和待验证邮箱
+func (s *EmailVerificationService) deliver(mail verificationMail) {
+	err := s.mailer.Send(mail.msg)
+	if err == nil {
+		return
+	}
+	logger.Errorf("发送邮件 %q 到 %s 失败: %v", mail.msg.Subject, mail.msg.To, err)
+	if mail.userID == 0 {
+		return
+	}
+	if err := s.discard(mail.userID, mail.msg.To); err != nil {
+		logger.Errorf("撤销用户 %d 的邮箱验证失败: %v", mail.userID, err)
+	}
+}
+
+// discard 删除用户的验证令牌，待验证的新邮箱为 email 时一并取消
+func (s *EmailVerificationService) discard(userID uint, email string) error {
+	if err := s.tokens.DeleteByUser(userID); err != nil {
+		return err
+	}
+	user, err := s.users.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.PendingEmail != "" && strings.EqualFold(user.PendingEmail, email) {
+		return s.users.SetPendingEmail(userID, "")
+	}
+	return nil
+}
+
+// Restricted 邮箱未验证的账号是否不能访问该接口，path 为路由模板（gin.Context.FullPath）
+func (s *EmailVerificationService) Restricted(method, path string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, r := range s.restricted {
+		if r.match(method, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsVerified 用户当前邮箱是否已验证
+func (s *EmailVerificationService) IsVerified(userID uint) (bool, error) {
+	user, err := s.users.FindByID(userID)
+	if err != nil {
+		return false, err
+	}
+	return user.IsEmailVerified(), nil
+}
+
+// SendVerification 提交验证邮件：有待验证的新邮箱时发送到新邮箱，否则发送到未验证的当前邮箱；
+// 没有需要验证的邮箱时返回 errcode.EmailAlreadyVerified。重新发送后之前的验证链接失效。
+// 冷却时间内已发送过仍然有效的链接时返回 errcode.EmailVerificationCooldown，等待发送的邮件已满时返回 errcode.TooManyRequests
+func (s *EmailVerificationService) SendVerification(userID uint) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+	email := user.Email
+	switch {
+	case user.PendingEmail != "":
+		email = user.PendingEmail
+	case user.IsEmailVerified():
+		return errcode.EmailAlreadyVerified
+	}
+
+	msg, err := s.createToken(user, email)
+	if err != nil {
+		return err
+	}
+	if !s.enqueue(verificationMail{msg: msg, userID: user.ID}) {
+		if err := s.tokens.DeleteByUser(user.ID); err != nil {
+			return err
+		}
+		return errcode.TooManyRequests
+	}
+	return nil
+}
+
+// RequestEmailChange 将新邮箱保存为待验证邮箱，向新邮箱发送验证链接并通知原邮箱，
+// 新邮箱验证通过前当前邮箱保持不变。newEmail 与当前邮箱相同时取消进行中的修改。
+// 与 SendVerification 相同受发送间隔限制；验证邮件未能提交或发送失败时取消本次修改
+func (s *EmailVerificationService) RequestEmailChange(userID uint, newEmail string) (*models.User, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(newEmail, user.Email) {
+		if user.PendingEmail != "" {
+			if err := s.users.SetPendingEmail(user.ID, ""); err != nil {
+				return nil, err
+			}
+			if err := s.tokens.DeleteByUser(user.ID); err != nil {
+				return nil, err
+			}
+			user.PendingEmail = ""
+		}
+		return user, nil
+	}
+
+	exists, err := s.users.EmailExists(newEmail)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, errcode.UserAlreadyExists
+	}
+
+	// 先创建令牌，冷却时间内不修改待验证邮箱
+	msg, err := s.createToken(user, newEmail)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.users.SetPendingEmail(user.ID, newEmail); err != nil {
+		s.tokens.DeleteByUser(user.ID)
+		return nil, err
+	}
+	if !s.enqueue(verificationMail{msg: msg, userID: user.ID}) {
+		if err := s.discard(user.ID, newEmail); err != nil {
+			return nil, err
+		}
+		return nil, errcode.TooManyRequests
+	}
+	user.PendingEmail = newEmail
+
+	// 通知邮件发送失败不影响修改
+	s.enqueue(verificationMail{msg: &mailer.Message{
+		To:      user.Email,
+		Subject: "邮箱修改通知",
+		Body:    emailChangeNoticeBody(user.Username, newEmail),
+	}})
+	return user, nil
+}
+
+// Verify 使用验证令牌完成邮箱验证，令牌只能使用一次；
+// 令牌对应待验证的新邮箱时，验证通过后替换当前邮箱
+func (s *EmailVerificationService) Verify(token string) (*models.User, error) {
+	verification, err := s.tokens.FindByHash(utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errcode.EmailVerificationTokenInvalid
+		}
+		return nil, err
+	}
+	if verification.IsUsed() || verification.IsExpired() {
+		return nil, errcode.EmailVerificationTokenInvalid
+	}
+	if err := s.tokens.Consume(verification.ID); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, errcode.EmailVerificationTokenInvalid
+		}
+		return nil, err
+	}
+
+	if err := s.users.VerifyEmail(verification.UserID, verification.Email, time.Now()); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound), errors.Is(err, repository.ErrConflict):
+			return nil, errcode.EmailVerificationTokenInvalid
+		case errors.Is(err, repository.ErrDuplicate):
+			return nil, errcode.UserAlreadyExists
+		}
+		return nil, err
+	}
+	if err := s.tokens.DeleteByUser(verification.UserID); err != nil {
+		return nil, err
+	}
+	return s.users.FindByID(verification.UserID)
+}
+
+// createToken 为 email 生成验证令牌并返回验证邮件，之前的令牌随即失效；
+// 冷却时间内已发送过仍然有效的令牌时返回 errcode.EmailVerificationCooldown
+func (s *EmailVerificationService) createToken(user *models.User, email string) (*mailer.Message, error) {
+	token, err := utils.RandomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	cfg := s.current()
+	now := time.Now()
+	expire := time.Duration(cfg.Expire) * time.Minute
+	cooldown := time.Duration(cfg.Cooldown) * time.Minute
+	if err := s.tokens.CreateUnlessRecent(&models.EmailVerificationToken{
+		UserID:    user.ID,
+		Email:     email,
+		TokenHash: utils.HashToken(token),
+		ExpiredAt: now.Add(expire),
+	}, now.Add(-cooldown)); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, errcode.EmailVerificationCooldown
+		}
+		return nil, err
+	}
+
+	return &mailer.Message{
+		To:      email,
+		Subject: "验证邮箱",
+		Body:    verificationMailBody(user.Username, email, tokenLink(cfg.URL, token), cfg.Expire),
+	}, nil
+}
+
+func (s *EmailVerificationService) findUser(userID uint) (*models.User, error) {
+	user, err := s.users.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errcode.UserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func verificationMailBody(username, email, link string, expire int) string {
+	return fmt.Sprintf("%s，你好：\n\n请在 %s内通过以下链接验证你的邮箱 %s：\n\n%s\n\n"+
+		"链接只能使用一次。如果不是你本人操作，请忽略本邮件。\n",
+		username, formatMinutes(expire), email, link)
+}
+
+func emailChangeNoticeBody(username, newEmail string) string {
+	return fmt.Sprintf("%s，你好：\n\n你的账号申请将登录邮箱修改为 %s，新邮箱验证通过后生效，在此之前当前邮箱仍然有效。\n\n"+
+		"如果不是你本人操作，请尽快登录并修改密码。\n",
+		username, newEmail)
+}
+
+// formatMinutes 将分钟数格式化为 "N 小时" 或 "N 分钟"
+func formatMinutes(minutes int) string {
+	if minutes >= 60 && minutes%60 == 0 {
+		return fmt.Sprintf("%d 小时", minutes/60)
+	}
+	return fmt.Sprintf("%d 分钟", minutes)
+}
@@ -0,0 +1,76 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go_app/config"
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/pkg/mailer"
+	"go_app/repository"
+	"go_app/services"
+)
+
+// TestEmailChangeSendFailure 验证邮件发送失败时取消修改并删除令牌，用户可以立即重新修改
+func TestEmailChangeSendFailure(t *testing.T) {
+	users := repository.NewMemoryUserRepository()
+	now := time.Now()
+	user := &models.User{Username: "alice", Email: "alice@example.com", Password: "-", EmailVerifiedAt: &now}
+	if err := users.Create(user); err != nil {
+		t.Fatal(err)
+	}
+	mail := &failingMailer{fail: "bad@example.com"}
+	service := services.NewEmailVerificationService(users, repository.NewMemoryEmailVerificationRepository(), mail, config.Default().EmailVerification)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go service.Run(ctx)
+
+	changed, err := service.RequestEmailChange(user.ID, "bad@example.com")
+	if err != nil {
+		t.Fatalf("修改邮箱失败: %v", err)
+	}
+	if changed.PendingEmail != "bad@example.com" {
+		t.Fatalf("pendingEmail = %q", changed.PendingEmail)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for pendingEmail(t, users, user.ID) != "" {
+		if time.Now().After(deadline) {
+			t.Fatal("发送失败后待验证邮箱未取消")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 令牌已删除，不受冷却时间限制
+	if _, err := service.RequestEmailChange(user.ID, "good@example.com"); err != nil {
+		t.Fatalf("发送失败后重新修改邮箱失败: %v", err)
+	}
+	if got := pendingEmail(t, users, user.ID); got != "good@example.com" {
+		t.Fatalf("pendingEmail = %q, want good@example.com", got)
+	}
+	_, err = service.RequestEmailChange(user.ID, "other@example.com")
+	expectErr(t, err, errcode.EmailVerificationCooldown)
+}
+
+// failingMailer 发送到 fail 的邮件返回错误，其他邮件直接丢弃
+type failingMailer struct {
+	fail string
+}
+
+func (m *failingMailer) Send(msg *mailer.Message) error {
+	if msg.To == m.fail {
+		return errors.New("mailbox unavailable")
+	}
+	return nil
+}
+
+func pendingEmail(t *testing.T, users repository.UserRepository, id uint) string {
+	t.Helper()
+	user, err := users.FindByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user.PendingEmail
+}
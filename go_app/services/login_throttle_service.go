@@ -0,0 +1,214 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"go_app/config"
+	"go_app/pkg/errcode"
+	"go_app/pkg/logger"
+	"go_app/repository"
+	"go_app/utils"
+)
+
+// LoginThrottledError 登录尝试被限制，RetryAfter 为需要等待的时间
+type LoginThrottledError struct {
+	*errcode.ErrorCode
+	RetryAfter time.Duration
+}
+
+// LoginThrottleService 登录失败限制：同时按账号和客户端 IP 统计失败次数。
+// 账号每次失败后需等待的时间按 base_delay 翻倍，连续失败 max_failures 次后临时锁定；
+// IP 失败 ip_max_failures 次后临时锁定，用于限制同一来源对大量账号的尝试。
+// 不存在的邮箱同样计数，锁定状态不会暴露邮箱是否已注册
+type LoginThrottleService struct {
+	attempts repository.LoginAttemptRepository
+	mu       sync.RWMutex
+	cfg      config.LoginThrottleConfig
+}
+
+func NewLoginThrottleService(attempts repository.LoginAttemptRepository, cfg config.LoginThrottleConfig) *LoginThrottleService {
+	return &LoginThrottleService{attempts: attempts, cfg: cfg}
+}
+
+// OnConfigChange 配置热加载回调，更新次数和时长限制；存储类型需要重启后生效
+func (s *LoginThrottleService) OnConfigChange(cfg *config.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg.LoginThrottle
+}
+
+func (s *LoginThrottleService) current() config.LoginThrottleConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
+
+// Check 校验账号和 IP 当前能否尝试登录，并在校验密码之前为两者各占用一次尝试，被限制时返回 *LoginThrottledError。
+// 占用的尝试先按失败计数，登录成功后由 Succeed 退还；并发的请求只有一个能占用同一次尝试，不会越过等待时间和次数上限
+func (s *LoginThrottleService) Check(email, ip string) error {
+	cfg := s.current()
+	now := time.Now()
+
+	if ip != "" {
+		// 先检查 IP 是否锁定，避免被锁定的 IP 占用账号的尝试
+		attempt, err := s.attempts.Get(ipKey(ip))
+		if err != nil {
+			return err
+		}
+		if attempt.IsLocked(now) {
+			return &LoginThrottledError{ErrorCode: errcode.LoginLocked, RetryAfter: attempt.LockedUntil.Sub(now)}
+		}
+	}
+
+	window := time.Duration(cfg.Window) * time.Minute
+	if err := s.reserve(accountKey(email), cfg.MaxFailures, func(failures int) time.Duration { return backoff(cfg, failures) }, now, window); err != nil {
+		return err
+	}
+	if ip == "" {
+		return nil
+	}
+	if err := s.reserve(ipKey(ip), cfg.IPMaxFailures, nil, now, window); err != nil {
+		if releaseErr := s.attempts.Release(accountKey(email)); releaseErr != nil {
+			return releaseErr
+		}
+		return err
+	}
+	return nil
+}
+
+// reserve 记录未锁定、没有其他请求正在进行最后一次尝试且已过等待时间（delay 为 nil 时不限制）时占用一次尝试，
+// 记录有效期重置为 window
+func (s *LoginThrottleService) reserve(key string, maxFailures int, delay func(failures int) time.Duration, now time.Time, window time.Duration) error {
+	attempt, err := s.attempts.Get(key)
+	if err != nil {
+		return err
+	}
+	if attempt.IsLocked(now) {
+		return &LoginThrottledError{ErrorCode: errcode.LoginLocked, RetryAfter: attempt.LockedUntil.Sub(now)}
+	}
+	// 次数已达上限且在上次锁定之后又有尝试：该尝试的结果确定之前不再允许新的尝试
+	if attempt.Failures >= maxFailures && attempt.LockedUntil.Before(attempt.LastFailedAt) {
+		return &LoginThrottledError{ErrorCode: errcode.LoginTooManyAttempts, RetryAfter: time.Second}
+	}
+	if attempt.Failures > 0 && delay != nil {
+		if next := attempt.LastFailedAt.Add(delay(attempt.Failures)); now.Before(next) {
+			return &LoginThrottledError{ErrorCode: errcode.LoginTooManyAttempts, RetryAfter: next.Sub(now)}
+		}
+	}
+
+	if _, err := s.attempts.Reserve(key, attempt.Failures, now, window); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			// 其他请求同时占用了这次尝试
+			return &LoginThrottledError{ErrorCode: errcode.LoginTooManyAttempts, RetryAfter: time.Second}
+		}
+		return err
+	}
+	return nil
+}
+
+// Fail 登录失败，Check 占用的尝试计为失败，达到次数上限时锁定账号或 IP
+func (s *LoginThrottleService) Fail(email, ip string) error {
+	cfg := s.current()
+	now := time.Now()
+	window := time.Duration(cfg.Window) * time.Minute
+	lockout := time.Duration(cfg.Lockout) * time.Minute
+
+	attempt, err := s.attempts.Get(accountKey(email))
+	if err != nil {
+		return err
+	}
+	if attempt.Failures >= cfg.MaxFailures && !attempt.IsLocked(now) {
+		// 锁定结束后失败次数继续保留一个统计窗口，期间再次失败会立即重新锁定
+		if err := s.attempts.Lock(accountKey(email), now.Add(lockout), lockout+window); err != nil {
+			return err
+		}
+		logger.Warnf("账号 %s 连续登录失败 %d 次，锁定 %d 分钟", email, attempt.Failures, cfg.Lockout)
+	}
+
+	if ip == "" {
+		return nil
+	}
+	attempt, err = s.attempts.Get(ipKey(ip))
+	if err != nil {
+		return err
+	}
+	if attempt.Failures >= cfg.IPMaxFailures && !attempt.IsLocked(now) {
+		if err := s.attempts.Lock(ipKey(ip), now.Add(lockout), lockout+window); err != nil {
+			return err
+		}
+		logger.Warnf("IP %s 登录失败 %d 次，锁定 %d 分钟", ip, attempt.Failures, cfg.Lockout)
+	}
+	return nil
+}
+
+// Succeed 登录成功后清除账号的失败记录，并退还 IP 占用的尝试。IP 的失败记录不清除，
+// 避免攻击者穿插登录自己的账号来重置同一来源的计数
+func (s *LoginThrottleService) Succeed(email, ip string) error {
+	if err := s.attempts.Reset(accountKey(email)); err != nil {
+		return err
+	}
+	if ip == "" {
+		return nil
+	}
+	return s.attempts.Release(ipKey(ip))
+}
+
+// Reserve 在登录以外需要校验密码或验证码的操作（如确认两步验证、修改密码）校验之前占用一次尝试，
+// key 标识操作和用户，与登录的失败记录相互独立。规则与账号登录相同：占用的尝试先按失败计数，
+// 每次失败后的等待时间按 base_delay 翻倍，连续失败 max_failures 次后在统计窗口内不再允许尝试，
+// 被限制时返回 *LoginThrottledError
+func (s *LoginThrottleService) Reserve(key string) error {
+	cfg := s.current()
+	now := time.Now()
+	window := time.Duration(cfg.Window) * time.Minute
+	return s.reserve(verifyKey(key), cfg.MaxFailures, func(failures int) time.Duration { return backoff(cfg, failures) }, now, window)
+}
+
+// Release 校验通过后清除 key 的失败记录，包括 Reserve 占用的尝试
+func (s *LoginThrottleService) Release(key string) error {
+	return s.attempts.Reset(verifyKey(key))
+}
+
+// Unlock 清除账号和 IP 的失败记录并解除锁定，参数为空时跳过
+func (s *LoginThrottleService) Unlock(email, ip string) error {
+	if email != "" {
+		if err := s.attempts.Reset(accountKey(email)); err != nil {
+			return err
+		}
+	}
+	if ip != "" {
+		if err := s.attempts.Reset(ipKey(ip)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backoff 第 failures 次失败后需要等待的时间：base_delay * 2^(failures-1)，不超过 max_delay
+func backoff(cfg config.LoginThrottleConfig, failures int) time.Duration {
+	delay := time.Duration(cfg.BaseDelay) * time.Second
+	maxDelay := time.Duration(cfg.MaxDelay) * time.Second
+	for i := 1; i < failures && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// accountKey 账号的失败记录 key，邮箱不区分大小写，只保存摘要
+func accountKey(email string) string {
+	return "account:" + utils.HashToken(strings.ToLower(strings.TrimSpace(email)))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func verifyKey(key string) string {
+	return "verify:" + key
+}
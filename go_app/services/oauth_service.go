@@ -0,0 +1,888 @@
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"go_app/config"
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/pkg/logger"
+	"go_app/repository"
+	"go_app/utils"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OAuth 协议错误码（RFC 6749 4.1.2.1、5.2，RFC 6750 3.1）
+const (
+	OAuthErrInvalidRequest          = "invalid_request"
+	OAuthErrInvalidClient           = "invalid_client"
+	OAuthErrInvalidGrant            = "invalid_grant"
+	OAuthErrUnauthorizedClient      = "unauthorized_client"
+	OAuthErrUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthErrUnsupportedResponseType = "unsupported_response_type"
+	OAuthErrInvalidScope            = "invalid_scope"
+	OAuthErrAccessDenied            = "access_denied"
+	OAuthErrInvalidToken            = "invalid_token"
+	OAuthErrInsufficientScope       = "insufficient_scope"
+)
+
+// OAuthError OAuth 协议错误。授权服务的协议接口面向第三方客户端，按 RFC 6749 的格式返回错误，不使用统一响应格式
+type OAuthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func oauthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// pkceChallengeLength S256 校验值（32 字节摘要的 base64url 编码）的长度
+const pkceChallengeLength = 43
+
+// OAuthService OAuth2 / OIDC 授权服务：供其他应用使用本服务的账号登录。
+// 支持授权码模式（公开客户端必须使用 PKCE）、刷新令牌和客户端凭证模式；
+// 访问令牌和刷新令牌是不透明的随机串，数据库只保存哈希值，资源服务通过令牌内省校验；
+// 申请 openid 时另外签发 ID Token，使用与登录令牌相同的签名密钥，公钥通过 /.well-known/jwks.json 公开
+type OAuthService struct {
+	users    repository.UserRepository
+	clients  repository.OAuthClientRepository
+	consents repository.OAuthConsentRepository
+	codes    repository.OAuthCodeRepository
+	tokens   repository.OAuthTokenRepository
+	keys     *utils.KeySet
+
+	mu  sync.RWMutex
+	cfg config.OAuthServerConfig
+}
+
+// NewOAuthService 根据 JWT 配置加载签名密钥并创建授权服务
+func NewOAuthService(users repository.UserRepository, clients repository.OAuthClientRepository, consents repository.OAuthConsentRepository,
+	codes repository.OAuthCodeRepository, tokens repository.OAuthTokenRepository, jwtCfg config.JWTConfig, cfg config.OAuthServerConfig) (*OAuthService, error) {
+	keys, err := utils.LoadKeySet(jwtCfg)
+	if err != nil {
+		return nil, err
+	}
+	return &OAuthService{users: users, clients: clients, consents: consents, codes: codes, tokens: tokens, keys: keys, cfg: cfg}, nil
+}
+
+// OnConfigChange 配置热加载回调，更新签发者、授权确认页面和各类有效期
+func (s *OAuthService) OnConfigChange(cfg *config.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg.OAuthServer
+}
+
+func (s *OAuthService) current() config.OAuthServerConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
+
+func (s *OAuthService) issuer() string {
+	return strings.TrimRight(s.current().Issuer, "/")
+}
+
+// Discovery OIDC 发现文档
+func (s *OAuthService) Discovery() *models.OpenIDConfiguration {
+	issuer := s.issuer()
+	return &models.OpenIDConfiguration{
+		Issuer:                           issuer,
+		AuthorizationEndpoint:            issuer + "/oauth/authorize",
+		TokenEndpoint:                    issuer + "/oauth/token",
+		UserinfoEndpoint:                 issuer + "/oauth/userinfo",
+		JWKSURI:                          issuer + "/.well-known/jwks.json",
+		RevocationEndpoint:               issuer + "/oauth/revoke",
+		IntrospectionEndpoint:            issuer + "/oauth/introspect",
+		ResponseTypesSupported:           []string{"code"},
+		GrantTypesSupported:              models.OAuthGrantTypes(),
+		SubjectTypesSupported:            []string{"public"},
+		IDTokenSigningAlgValuesSupported: []string{s.keys.Algorithm()},
+		ScopesSupported:                  models.OAuthScopes(),
+		ClaimsSupported: []string{"iss", "sub", "aud", "exp", "iat", "nonce",
+			"name", "preferred_username", "picture", "birthdate", "gender", "updated_at", "email", "email_verified"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+	}
+}
+
+// CreateClient 登记客户端，返回客户端和密钥明文，公开客户端没有密钥。
+// 未指定授权范围和授权类型时使用全部内置授权范围和 authorization_code、refresh_token
+func (s *OAuthService) CreateClient(createdBy uint, req *models.OAuthClientCreateRequest) (*models.OAuthClient, string, error) {
+	grantTypes := uniqueStrings(req.GrantTypes)
+	if len(grantTypes) == 0 {
+		grantTypes = []string{models.OAuthGrantAuthorizationCode, models.OAuthGrantRefreshToken}
+	}
+	scopes := uniqueStrings(req.Scopes)
+	if len(scopes) == 0 {
+		scopes = models.OAuthScopes()
+	}
+	redirectURIs := uniqueStrings(req.RedirectURIs)
+	if err := validateOAuthClient(redirectURIs, scopes, grantTypes, req.Public); err != nil {
+		return nil, "", err
+	}
+
+	clientID, err := utils.RandomHex(16)
+	if err != nil {
+		return nil, "", err
+	}
+	client := &models.OAuthClient{
+		ClientID:     clientID,
+		Name:         strings.TrimSpace(req.Name),
+		RedirectURIs: strings.Join(redirectURIs, " "),
+		Scopes:       strings.Join(scopes, " "),
+		GrantTypes:   strings.Join(grantTypes, " "),
+		CreatedBy:    createdBy,
+	}
+	var secret string
+	if !req.Public {
+		if secret, err = utils.RandomHex(32); err != nil {
+			return nil, "", err
+		}
+		client.SecretHash = utils.HashToken(secret)
+	}
+	if err := s.clients.Create(client); err != nil {
+		return nil, "", err
+	}
+	return client, secret, nil
+}
+
+// validateOAuthClient 校验登记的回调地址、授权范围和授权类型，三者都以空格分隔保存，不能包含空白字符
+func validateOAuthClient(redirectURIs, scopes, grantTypes []string, public bool) error {
+	for _, g := range grantTypes {
+		if !containsString(models.OAuthGrantTypes(), g) {
+			return errcode.OAuthClientInvalid
+		}
+	}
+	hasCode := containsString(grantTypes, models.OAuthGrantAuthorizationCode)
+	// 刷新令牌只能通过授权码换取
+	if containsString(grantTypes, models.OAuthGrantRefreshToken) && !hasCode {
+		return errcode.OAuthClientInvalid
+	}
+	// 公开客户端没有密钥，无法证明自己的身份，只能由用户授权
+	if public && (containsString(grantTypes, models.OAuthGrantClientCredentials) || !hasCode) {
+		return errcode.OAuthClientInvalid
+	}
+	if hasCode && len(redirectURIs) == 0 {
+		return errcode.OAuthClientInvalid
+	}
+	for _, uri := range redirectURIs {
+		u, err := url.Parse(uri)
+		if err != nil || !u.IsAbs() || u.Fragment != "" || strings.ContainsAny(uri, " \t\r\n") || len(uri) > 500 {
+			return errcode.OAuthClientInvalid
+		}
+	}
+	for _, scope := range scopes {
+		if !validScopeToken(scope) {
+			return errcode.OAuthClientInvalid
+		}
+	}
+	if len(strings.Join(redirectURIs, " ")) > 2000 || len(strings.Join(scopes, " ")) > 500 {
+		return errcode.OAuthClientInvalid
+	}
+	return nil
+}
+
+// validScopeToken 授权范围只能包含可见 ASCII 字符，不能包含空格、双引号和反斜杠（RFC 6749 3.3）
+func validScopeToken(scope string) bool {
+	if scope == "" || len(scope) > 100 {
+		return false
+	}
+	for i := 0; i < len(scope); i++ {
+		c := scope[i]
+		if c <= 0x20 || c >= 0x7f || c == '"' || c == '\\' {
+			return false
+		}
+	}
+	return true
+}
+
+// ListClients 列出全部客户端
+func (s *OAuthService) ListClients() ([]*models.OAuthClient, error) {
+	return s.clients.List()
+}
+
+// DeleteClient 删除客户端，同时删除用户的授权记录并吊销已签发的令牌
+func (s *OAuthService) DeleteClient(clientID string) error {
+	if err := s.clients.Delete(clientID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return errcode.OAuthClientNotFound
+		}
+		return err
+	}
+	if err := s.consents.DeleteByClient(clientID); err != nil {
+		return err
+	}
+	return s.tokens.RevokeByClient(clientID)
+}
+
+// AuthorizationClient 校验授权请求的客户端和回调地址。
+// 两者无效时不能跳转回客户端（RFC 6749 4.1.2.1），由调用方直接返回错误
+func (s *OAuthService) AuthorizationClient(clientID, redirectURI string) (*models.OAuthClient, error) {
+	if clientID == "" || redirectURI == "" {
+		return nil, oauthError(OAuthErrInvalidRequest, "缺少 client_id 或 redirect_uri")
+	}
+	client, err := s.clients.FindByClientID(clientID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, oauthError(OAuthErrInvalidRequest, "客户端不存在")
+		}
+		return nil, err
+	}
+	if !client.AllowsGrant(models.OAuthGrantAuthorizationCode) {
+		return nil, oauthError(OAuthErrInvalidRequest, "客户端不支持授权码模式")
+	}
+	if !client.AllowsRedirectURI(redirectURI) {
+		return nil, oauthError(OAuthErrInvalidRequest, "redirect_uri 未登记")
+	}
+	return client, nil
+}
+
+// AuthorizationScopes 校验授权请求的其余参数，返回申请的授权范围。
+// 返回的 *OAuthError 可以通过 AuthorizationErrorURL 携带到客户端的回调地址
+func (s *OAuthService) AuthorizationScopes(client *models.OAuthClient, req *models.OAuthAuthorizeRequest) ([]string, error) {
+	if req.ResponseType != "code" {
+		return nil, oauthError(OAuthErrUnsupportedResponseType, "仅支持 response_type=code")
+	}
+	scopes := uniqueStrings(strings.Fields(req.Scope))
+	if len(scopes) == 0 {
+		return nil, oauthError(OAuthErrInvalidScope, "scope 不能为空")
+	}
+	for _, scope := range scopes {
+		if !client.AllowsScope(scope) {
+			return nil, oauthError(OAuthErrInvalidScope, "客户端不允许申请 "+scope)
+		}
+	}
+	if req.CodeChallenge == "" {
+		if client.IsPublic() {
+			return nil, oauthError(OAuthErrInvalidRequest, "公开客户端必须使用 PKCE")
+		}
+	} else if req.CodeChallengeMethod != "S256" {
+		return nil, oauthError(OAuthErrInvalidRequest, "code_challenge_method 仅支持 S256")
+	} else if len(req.CodeChallenge) != pkceChallengeLength {
+		return nil, oauthError(OAuthErrInvalidRequest, "code_challenge 格式错误")
+	}
+	if len(req.Nonce) > 255 {
+		return nil, oauthError(OAuthErrInvalidRequest, "nonce 过长")
+	}
+	return scopes, nil
+}
+
+// AuthorizationErrorURL 携带错误和 state 的客户端回调地址
+func AuthorizationErrorURL(redirectURI, state string, err *OAuthError) string {
+	params := url.Values{"error": {err.Code}}
+	if err.Description != "" {
+		params.Set("error_description", err.Description)
+	}
+	return appendQuery(redirectURI, params, state)
+}
+
+// ConsentURL 授权确认页面地址，原样携带 /oauth/authorize 的查询参数
+func (s *OAuthService) ConsentURL(rawQuery string) string {
+	consentURL := s.current().ConsentURL
+	if rawQuery == "" {
+		return consentURL
+	}
+	if strings.Contains(consentURL, "?") {
+		return consentURL + "&" + rawQuery
+	}
+	return consentURL + "?" + rawQuery
+}
+
+// appendQuery 在回调地址原有的查询参数后追加参数，state 非空时原样带回
+func appendQuery(redirectURI string, params url.Values, state string) string {
+	if state != "" {
+		params.Set("state", state)
+	}
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// validateAuthorization 授权确认接口使用，协议错误统一转换为 *OAuthRequestError
+func (s *OAuthService) validateAuthorization(req *models.OAuthAuthorizeRequest) (*models.OAuthClient, []string, error) {
+	client, err := s.AuthorizationClient(req.ClientID, req.RedirectURI)
+	if err == nil {
+		var scopes []string
+		if scopes, err = s.AuthorizationScopes(client, req); err == nil {
+			return client, scopes, nil
+		}
+	}
+	var oauthErr *OAuthError
+	if errors.As(err, &oauthErr) {
+		return nil, nil, &OAuthRequestError{Description: oauthErr.Description}
+	}
+	return nil, nil, err
+}
+
+// OAuthRequestError 授权确认接口收到的授权请求无效，控制器返回 OAuthRequestInvalid 并附带原因
+type OAuthRequestError struct {
+	Description string
+}
+
+func (e *OAuthRequestError) Error() string {
+	return errcode.OAuthRequestInvalid.Message + ": " + e.Description
+}
+
+// PrepareAuthorization 授权确认页面展示的客户端和授权范围，用户已同意过全部授权范围时 Consented 为 true
+func (s *OAuthService) PrepareAuthorization(userID uint, req *models.OAuthAuthorizeRequest) (*models.OAuthAuthorizeInfo, error) {
+	client, scopes, err := s.validateAuthorization(req)
+	if err != nil {
+		return nil, err
+	}
+	info := &models.OAuthAuthorizeInfo{ClientID: client.ClientID, ClientName: client.Name, Scopes: scopes}
+	consent, err := s.consents.Find(userID, client.ClientID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+	info.Consented = consent != nil && consent.Covers(scopes)
+	return info, nil
+}
+
+// Authorize 用户同意或拒绝授权，返回需要跳转的客户端回调地址。
+// 同意时记录授权范围并签发授权码，拒绝时携带 access_denied
+func (s *OAuthService) Authorize(userID uint, req *models.OAuthAuthorizeRequest, approve bool) (string, error) {
+	client, scopes, err := s.validateAuthorization(req)
+	if err != nil {
+		return "", err
+	}
+	if !approve {
+		return AuthorizationErrorURL(req.RedirectURI, req.State, oauthError(OAuthErrAccessDenied, "用户拒绝授权")), nil
+	}
+
+	granted := scopes
+	consent, err := s.consents.Find(userID, client.ClientID)
+	if err == nil {
+		granted = uniqueStrings(append(consent.ScopeList(), scopes...))
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return "", err
+	}
+	if err := s.consents.Save(&models.OAuthConsent{UserID: userID, ClientID: client.ClientID, Scopes: strings.Join(granted, " ")}); err != nil {
+		return "", err
+	}
+
+	code, err := utils.RandomHex(32)
+	if err != nil {
+		return "", err
+	}
+	if err := s.codes.Create(&models.OAuthAuthorizationCode{
+		CodeHash:      utils.HashToken(code),
+		ClientID:      client.ClientID,
+		UserID:        userID,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        strings.Join(scopes, " "),
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		ExpiredAt:     time.Now().Add(time.Duration(s.current().CodeExpire) * time.Minute),
+	}); err != nil {
+		return "", err
+	}
+	// iss 参数用于客户端防范混淆攻击（RFC 9207）
+	return appendQuery(req.RedirectURI, url.Values{"code": {code}, "iss": {s.issuer()}}, req.State), nil
+}
+
+// ListConsents 列出用户已授权的客户端
+func (s *OAuthService) ListConsents(userID uint) ([]*models.OAuthConsentInfo, error) {
+	consents, err := s.consents.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*models.OAuthConsentInfo, 0, len(consents))
+	for _, c := range consents {
+		info := &models.OAuthConsentInfo{ClientID: c.ClientID, ClientName: c.ClientID, Scopes: c.ScopeList(), CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt}
+		if client, err := s.clients.FindByClientID(c.ClientID); err == nil {
+			info.ClientName = client.Name
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+		list = append(list, info)
+	}
+	return list, nil
+}
+
+// RevokeConsent 撤销对客户端的授权，同时吊销已签发给该客户端的令牌
+func (s *OAuthService) RevokeConsent(userID uint, clientID string) error {
+	if err := s.consents.Delete(userID, clientID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return errcode.OAuthConsentNotFound
+		}
+		return err
+	}
+	return s.tokens.RevokeByUserClient(userID, clientID)
+}
+
+// RevokeUser 吊销用户授权给所有客户端的令牌和尚未使用的授权码，授权同意记录保留，
+// 客户端需要用户重新登录授权才能获取新的令牌
+func (s *OAuthService) RevokeUser(userID uint) error {
+	if err := s.codes.ConsumeByUser(userID); err != nil {
+		return err
+	}
+	return s.tokens.RevokeByUser(userID)
+}
+
+// authenticateClient 校验客户端凭证，公开客户端只提供 client_id
+func (s *OAuthService) authenticateClient(clientID, secret string) (*models.OAuthClient, error) {
+	if clientID == "" {
+		return nil, oauthError(OAuthErrInvalidClient, "缺少客户端凭证")
+	}
+	client, err := s.clients.FindByClientID(clientID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, oauthError(OAuthErrInvalidClient, "客户端认证失败")
+		}
+		return nil, err
+	}
+	if client.IsPublic() {
+		if secret != "" {
+			return nil, oauthError(OAuthErrInvalidClient, "客户端认证失败")
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(secret)), []byte(client.SecretHash)) != 1 {
+		return nil, oauthError(OAuthErrInvalidClient, "客户端认证失败")
+	}
+	return client, nil
+}
+
+// Token 令牌端点：按 grant_type 使用授权码、刷新令牌或客户端凭证换取令牌
+func (s *OAuthService) Token(clientID, clientSecret string, req *models.OAuthTokenRequest) (*models.OAuthTokenResponse, error) {
+	client, err := s.authenticateClient(clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	switch req.GrantType {
+	case "":
+		return nil, oauthError(OAuthErrInvalidRequest, "缺少 grant_type")
+	case models.OAuthGrantAuthorizationCode, models.OAuthGrantRefreshToken, models.OAuthGrantClientCredentials:
+	default:
+		return nil, oauthError(OAuthErrUnsupportedGrantType, "不支持的 grant_type")
+	}
+	if !client.AllowsGrant(req.GrantType) {
+		return nil, oauthError(OAuthErrUnauthorizedClient, "客户端不允许使用 "+req.GrantType)
+	}
+
+	switch req.GrantType {
+	case models.OAuthGrantAuthorizationCode:
+		return s.exchangeCode(client, req)
+	case models.OAuthGrantRefreshToken:
+		return s.refresh(client, req)
+	default:
+		return s.clientCredentials(client, req)
+	}
+}
+
+// exchangeCode 使用授权码换取令牌。授权码被重复使用时吊销已用它换取的令牌（RFC 6749 4.1.2）
+func (s *OAuthService) exchangeCode(client *models.OAuthClient, req *models.OAuthTokenRequest) (*models.OAuthTokenResponse, error) {
+	if req.Code == "" || req.RedirectURI == "" {
+		return nil, oauthError(OAuthErrInvalidRequest, "缺少 code 或 redirect_uri")
+	}
+	code, err := s.codes.FindByHash(utils.HashToken(req.Code))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, oauthError(OAuthErrInvalidGrant, "授权码无效")
+		}
+		return nil, err
+	}
+	if code.ClientID != client.ClientID {
+		return nil, oauthError(OAuthErrInvalidGrant, "授权码无效")
+	}
+	if code.IsUsed() {
+		logger.Warnf("OAuth 客户端 %s 重复使用授权码，吊销对应的令牌", client.ClientID)
+		if err := s.tokens.RevokeFamily(code.CodeHash); err != nil {
+			return nil, err
+		}
+		return nil, oauthError(OAuthErrInvalidGrant, "授权码已被使用")
+	}
+	if code.IsExpired() {
+		return nil, oauthError(OAuthErrInvalidGrant, "授权码已过期")
+	}
+	if code.RedirectURI != req.RedirectURI {
+		return nil, oauthError(OAuthErrInvalidGrant, "redirect_uri 与授权请求不一致")
+	}
+	if code.CodeChallenge != "" && !verifyPKCE(req.CodeVerifier, code.CodeChallenge) {
+		return nil, oauthError(OAuthErrInvalidGrant, "code_verifier 校验失败")
+	}
+	if err := s.codes.Consume(code.ID); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, oauthError(OAuthErrInvalidGrant, "授权码已被使用")
+		}
+		return nil, err
+	}
+
+	user, err := s.activeUser(code.UserID)
+	if err != nil {
+		return nil, err
+	}
+	// 同一授权码换取的令牌及其刷新得到的令牌以授权码哈希为 FamilyID，授权码被重复使用时据此吊销
+	return s.issue(client, user, code.ScopeList(), code.CodeHash, code.Nonce)
+}
+
+// verifyPKCE 校验 code_verifier 的 S256 摘要（RFC 7636 4.6）
+func verifyPKCE(verifier, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(sum[:])), []byte(challenge)) == 1
+}
+
+// refresh 使用刷新令牌换取新的令牌，旧记录随即吊销。已吊销的刷新令牌被再次使用说明可能已泄露，吊销整个授权
+func (s *OAuthService) refresh(client *models.OAuthClient, req *models.OAuthTokenRequest) (*models.OAuthTokenResponse, error) {
+	if req.RefreshToken == "" {
+		return nil, oauthError(OAuthErrInvalidRequest, "缺少 refresh_token")
+	}
+	token, err := s.tokens.FindByRefreshHash(utils.HashToken(req.RefreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, oauthError(OAuthErrInvalidGrant, "刷新令牌无效")
+		}
+		return nil, err
+	}
+	if token.ClientID != client.ClientID {
+		return nil, oauthError(OAuthErrInvalidGrant, "刷新令牌无效")
+	}
+	if token.IsRevoked() {
+		return nil, s.refreshReused(token)
+	}
+	if !token.RefreshActive() {
+		return nil, oauthError(OAuthErrInvalidGrant, "刷新令牌已过期")
+	}
+	scopes := token.ScopeList()
+	if req.Scope != "" {
+		scopes = uniqueStrings(strings.Fields(req.Scope))
+		for _, scope := range scopes {
+			if !token.HasScope(scope) {
+				return nil, oauthError(OAuthErrInvalidScope, "不能申请超出原授权的范围")
+			}
+		}
+	}
+	if err := s.tokens.Revoke(token.ID); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, s.refreshReused(token)
+		}
+		return nil, err
+	}
+
+	user, err := s.activeUser(token.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(client, user, scopes, token.FamilyID, "")
+}
+
+func (s *OAuthService) refreshReused(token *models.OAuthToken) error {
+	logger.Warnf("OAuth 客户端 %s 重复使用刷新令牌，吊销用户 %d 的整个授权", token.ClientID, token.UserID)
+	if err := s.tokens.RevokeFamily(token.FamilyID); err != nil {
+		return err
+	}
+	return oauthError(OAuthErrInvalidGrant, "刷新令牌已失效")
+}
+
+// clientCredentials 客户端以自己的身份换取访问令牌，不关联用户，不签发刷新令牌和 ID Token
+func (s *OAuthService) clientCredentials(client *models.OAuthClient, req *models.OAuthTokenRequest) (*models.OAuthTokenResponse, error) {
+	if client.IsPublic() {
+		return nil, oauthError(OAuthErrUnauthorizedClient, "公开客户端不能使用 client_credentials")
+	}
+	userScopes := []string{models.OAuthScopeOpenID, models.OAuthScopeProfile, models.OAuthScopeEmail, models.OAuthScopeOfflineAccess}
+	var scopes []string
+	if req.Scope == "" {
+		for _, scope := range client.ScopeList() {
+			if !containsString(userScopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+	} else {
+		scopes = uniqueStrings(strings.Fields(req.Scope))
+		for _, scope := range scopes {
+			if !client.AllowsScope(scope) || containsString(userScopes, scope) {
+				return nil, oauthError(OAuthErrInvalidScope, "客户端不能申请 "+scope)
+			}
+		}
+	}
+	familyID, err := utils.RandomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(client, nil, scopes, familyID, "")
+}
+
+// activeUser 查找授权的用户，用户已删除或被禁用时授权失效
+func (s *OAuthService) activeUser(userID uint) (*models.User, error) {
+	user, err := s.users.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, oauthError(OAuthErrInvalidGrant, "用户不存在")
+		}
+		return nil, err
+	}
+	if user.IsDisabled() {
+		return nil, oauthError(OAuthErrInvalidGrant, "用户已被禁用")
+	}
+	return user, nil
+}
+
+// issue 签发访问令牌，授权包含 offline_access 且客户端允许刷新时签发刷新令牌，包含 openid 时签发 ID Token。
+// user 为 nil 表示 client_credentials
+func (s *OAuthService) issue(client *models.OAuthClient, user *models.User, scopes []string, familyID, nonce string) (*models.OAuthTokenResponse, error) {
+	cfg := s.current()
+	now := time.Now()
+	access, err := utils.RandomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	token := &models.OAuthToken{
+		FamilyID:        familyID,
+		ClientID:        client.ClientID,
+		Scopes:          strings.Join(scopes, " "),
+		AccessTokenHash: utils.HashToken(access),
+		AccessExpiredAt: now.Add(time.Duration(cfg.AccessExpire) * time.Minute),
+	}
+	resp := &models.OAuthTokenResponse{
+		AccessToken: access,
+		TokenType:   "Bearer",
+		ExpiresIn:   cfg.AccessExpire * 60,
+		Scope:       token.Scopes,
+	}
+
+	if user != nil {
+		token.UserID = user.ID
+		if containsString(scopes, models.OAuthScopeOfflineAccess) && client.AllowsGrant(models.OAuthGrantRefreshToken) {
+			refresh, err := utils.RandomHex(32)
+			if err != nil {
+				return nil, err
+			}
+			hash := utils.HashToken(refresh)
+			expiredAt := now.Add(time.Duration(cfg.RefreshExpire) * time.Hour)
+			token.RefreshTokenHash = &hash
+			token.RefreshExpiredAt = &expiredAt
+			resp.RefreshToken = refresh
+		}
+		if containsString(scopes, models.OAuthScopeOpenID) {
+			claims := userClaims(user.ToUserInfo(), scopes)
+			claims["iss"] = s.issuer()
+			claims["aud"] = client.ClientID
+			claims["iat"] = now.Unix()
+			claims["exp"] = token.AccessExpiredAt.Unix()
+			if nonce != "" {
+				claims["nonce"] = nonce
+			}
+			if resp.IDToken, err = s.keys.SignClaims(claims); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := s.tokens.Create(token); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// userClaims 按授权范围从用户信息生成 OIDC 标准声明，ID Token 和 /oauth/userinfo 共用
+func userClaims(info *models.UserInfo, scopes []string) jwt.MapClaims {
+	claims := jwt.MapClaims{"sub": strconv.FormatUint(uint64(info.UserID), 10)}
+	if containsString(scopes, models.OAuthScopeProfile) {
+		claims["name"] = info.Username
+		claims["preferred_username"] = info.Username
+		claims["updated_at"] = info.UpdatedAt.Unix()
+		if info.AvatarURL != "" {
+			claims["picture"] = info.AvatarURL
+		}
+		if info.Gender != "" {
+			claims["gender"] = info.Gender
+		}
+		if info.Birthday != nil {
+			claims["birthdate"] = info.Birthday.Format("2006-01-02")
+		}
+	}
+	if containsString(scopes, models.OAuthScopeEmail) {
+		claims["email"] = info.Email
+		claims["email_verified"] = info.EmailVerified
+	}
+	return claims
+}
+
+// UserInfo 使用访问令牌获取用户信息（OIDC Core 5.3），令牌必须包含 openid
+func (s *OAuthService) UserInfo(accessToken string) (map[string]interface{}, error) {
+	accessToken = trimBearer(accessToken)
+	if accessToken == "" {
+		return nil, oauthError(OAuthErrInvalidToken, "缺少访问令牌")
+	}
+	token, err := s.tokens.FindByAccessHash(utils.HashToken(accessToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, oauthError(OAuthErrInvalidToken, "访问令牌无效")
+		}
+		return nil, err
+	}
+	if !token.AccessActive() {
+		return nil, oauthError(OAuthErrInvalidToken, "访问令牌已失效")
+	}
+	if token.UserID == 0 || !token.HasScope(models.OAuthScopeOpenID) {
+		return nil, oauthError(OAuthErrInsufficientScope, "访问令牌未包含 openid")
+	}
+	user, err := s.users.FindByID(token.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, oauthError(OAuthErrInvalidToken, "用户不存在")
+		}
+		return nil, err
+	}
+	if user.IsDisabled() {
+		return nil, oauthError(OAuthErrInvalidToken, "用户已被禁用")
+	}
+	return userClaims(user.ToUserInfo(), token.ScopeList()), nil
+}
+
+// findToken 按提示的类型优先查找令牌，返回令牌记录和是否为刷新令牌
+func (s *OAuthService) findToken(plain, hint string) (*models.OAuthToken, bool, error) {
+	hash := utils.HashToken(plain)
+	lookups := []bool{false, true}
+	if hint == "refresh_token" {
+		lookups = []bool{true, false}
+	}
+	for _, refresh := range lookups {
+		var token *models.OAuthToken
+		var err error
+		if refresh {
+			token, err = s.tokens.FindByRefreshHash(hash)
+		} else {
+			token, err = s.tokens.FindByAccessHash(hash)
+		}
+		if err == nil {
+			return token, refresh, nil
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return nil, false, err
+		}
+	}
+	return nil, false, repository.ErrNotFound
+}
+
+// Revoke 吊销令牌（RFC 7009）。吊销刷新令牌时同时吊销整个授权；
+// 令牌不存在或属于其他客户端时同样视为成功，避免泄露令牌是否存在
+func (s *OAuthService) Revoke(clientID, clientSecret string, req *models.OAuthTokenActionRequest) error {
+	client, err := s.authenticateClient(clientID, clientSecret)
+	if err != nil {
+		return err
+	}
+	if req.Token == "" {
+		return oauthError(OAuthErrInvalidRequest, "缺少 token")
+	}
+	token, refresh, err := s.findToken(req.Token, req.TokenTypeHint)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if token.ClientID != client.ClientID {
+		return nil
+	}
+	if refresh {
+		return s.tokens.RevokeFamily(token.FamilyID)
+	}
+	if err := s.tokens.Revoke(token.ID); err != nil && !errors.Is(err, repository.ErrConflict) {
+		return err
+	}
+	return nil
+}
+
+// Introspect 令牌内省（RFC 7662），供资源服务校验访问令牌，只允许有密钥的客户端调用
+func (s *OAuthService) Introspect(clientID, clientSecret string, req *models.OAuthTokenActionRequest) (*models.OAuthIntrospection, error) {
+	client, err := s.authenticateClient(clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if client.IsPublic() {
+		return nil, oauthError(OAuthErrInvalidClient, "公开客户端不能调用令牌内省")
+	}
+	if req.Token == "" {
+		return nil, oauthError(OAuthErrInvalidRequest, "缺少 token")
+	}
+	inactive := &models.OAuthIntrospection{Active: false}
+	token, refresh, err := s.findToken(req.Token, req.TokenTypeHint)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return inactive, nil
+		}
+		return nil, err
+	}
+
+	result := &models.OAuthIntrospection{
+		Active:   true,
+		Scope:    token.Scopes,
+		ClientID: token.ClientID,
+		Iat:      token.CreatedAt.Unix(),
+		Sub:      token.ClientID,
+		Aud:      token.ClientID,
+		Iss:      s.issuer(),
+	}
+	if refresh {
+		if !token.RefreshActive() {
+			return inactive, nil
+		}
+		result.TokenType = "refresh_token"
+		result.Exp = token.RefreshExpiredAt.Unix()
+	} else {
+		if !token.AccessActive() {
+			return inactive, nil
+		}
+		result.TokenType = "Bearer"
+		result.Exp = token.AccessExpiredAt.Unix()
+	}
+	if token.UserID != 0 {
+		user, err := s.users.FindByID(token.UserID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return inactive, nil
+			}
+			return nil, err
+		}
+		if user.IsDisabled() {
+			return inactive, nil
+		}
+		result.Sub = strconv.FormatUint(uint64(user.ID), 10)
+		result.Username = user.Username
+	}
+	return result, nil
+}
+
+// uniqueStrings 去掉空白项和重复项，保持原有顺序
+func uniqueStrings(list []string) []string {
+	var result []string
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if s != "" && !containsString(result, s) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
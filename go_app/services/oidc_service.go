@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_app/config"
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/pkg/logger"
+	"go_app/pkg/oidc"
+	"go_app/repository"
+	"go_app/utils"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// oidcRequestTimeout 访问提供方（发现文档、令牌端点、JWKS）的超时时间
+const oidcRequestTimeout = 10 * time.Second
+
+// OIDCService 第三方（OpenID Connect）登录：授权码模式 + PKCE，校验 ID Token 后按提供方账号标识查找关联的用户；
+// 没有关联时按提供方已验证的邮箱关联已有用户，邮箱未注册时按配置自动创建账号
+type OIDCService struct {
+	userService *UserService
+	identities  repository.UserIdentityRepository
+	flows       repository.OIDCFlowRepository
+
+	mu  sync.RWMutex
+	cfg config.OIDCConfig
+	// clients 已读取发现文档的提供方客户端，首次使用时创建，配置变更后清空
+	clients    map[string]*oidc.Client
+	generation int
+}
+
+func NewOIDCService(userService *UserService, identities repository.UserIdentityRepository, flows repository.OIDCFlowRepository, cfg config.OIDCConfig) *OIDCService {
+	return &OIDCService{userService: userService, identities: identities, flows: flows, cfg: cfg, clients: make(map[string]*oidc.Client)}
+}
+
+// OnConfigChange 配置热加载回调，更新提供方、回调地址、流程有效期和是否允许自动注册
+func (s *OIDCService) OnConfigChange(cfg *config.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg.OIDC
+	s.clients = make(map[string]*oidc.Client)
+	s.generation++
+}
+
+func (s *OIDCService) current() config.OIDCConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
+
+// Providers 列出可用的提供方
+func (s *OIDCService) Providers() []*models.OIDCProviderInfo {
+	cfg := s.current()
+	list := make([]*models.OIDCProviderInfo, 0, len(cfg.Providers))
+	for _, p := range cfg.Providers {
+		name := p.DisplayName
+		if name == "" {
+			name = p.Name
+		}
+		list = append(list, &models.OIDCProviderInfo{Name: p.Name, DisplayName: name})
+	}
+	return list
+}
+
+// Begin 开始第三方登录，保存 nonce 和 PKCE 校验值，返回提供方登录页面地址
+func (s *OIDCService) Begin(provider string) (*models.OIDCAuthorization, error) {
+	client, err := s.client(provider)
+	if err != nil {
+		return nil, err
+	}
+	req, err := oidc.NewAuthRequest()
+	if err != nil {
+		return nil, err
+	}
+	expiredAt := time.Now().Add(time.Duration(s.current().FlowExpire) * time.Minute)
+	if err := s.flows.Create(&models.OIDCFlow{
+		StateHash:    utils.HashToken(req.State),
+		Provider:     provider,
+		Nonce:        req.Nonce,
+		CodeVerifier: req.CodeVerifier,
+		ExpiredAt:    expiredAt,
+	}); err != nil {
+		return nil, err
+	}
+	return &models.OIDCAuthorization{AuthorizationURL: client.AuthCodeURL(req), State: req.State, ExpiredAt: expiredAt}, nil
+}
+
+// FinishLogin 使用提供方回调带回的 state 和授权码完成登录，返回对应的用户。
+// 流程只能使用一次，失败后需要重新开始
+func (s *OIDCService) FinishLogin(state, code string) (*models.User, error) {
+	flow, err := s.takeFlow(state)
+	if err != nil {
+		return nil, err
+	}
+	client, err := s.client(flow.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), oidcRequestTimeout)
+	defer cancel()
+	identity, err := client.Exchange(ctx, code, &oidc.AuthRequest{State: state, Nonce: flow.Nonce, CodeVerifier: flow.CodeVerifier})
+	if err != nil {
+		logger.Warnf("第三方登录 %s 验证失败: %v", flow.Provider, err)
+		return nil, errcode.OIDCLoginFailed
+	}
+
+	user, err := s.resolveUser(flow.Provider, identity)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsDisabled() {
+		return nil, errcode.UserDisabled
+	}
+	return user, nil
+}
+
+// ListIdentities 列出用户关联的第三方账号
+func (s *OIDCService) ListIdentities(userID uint) ([]*models.UserIdentity, error) {
+	return s.identities.ListByUser(userID)
+}
+
+// Unlink 解除第三方账号关联，之后使用该账号登录会重新按邮箱关联或注册
+func (s *OIDCService) Unlink(userID, id uint) error {
+	if err := s.identities.Delete(userID, id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return errcode.UserIdentityNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// resolveUser 查找第三方账号关联的用户，没有关联时按邮箱关联或创建用户
+func (s *OIDCService) resolveUser(provider string, identity *oidc.Identity) (*models.User, error) {
+	now := time.Now()
+	link, err := s.identities.FindBySubject(provider, identity.Subject)
+	switch {
+	case err == nil:
+		user, err := s.userService.GetUserByID(link.UserID)
+		if err == nil {
+			if err := s.identities.RecordLogin(link.ID, identity.Email, now); err != nil {
+				return nil, err
+			}
+			return user, nil
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+		// 用户已被删除，清理残留的关联后按新账号处理
+		if err := s.identities.Delete(link.UserID, link.ID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+	case !errors.Is(err, repository.ErrNotFound):
+		return nil, err
+	}
+
+	// 只信任提供方验证过的邮箱，否则任何人都可以在提供方填写他人邮箱接管账号
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, errcode.OIDCEmailUnverified
+	}
+	user, err := s.userService.GetUserByEmail(identity.Email)
+	switch {
+	case err == nil:
+		// 本地邮箱未验证时账号可能是他人抢注的，关联后会让抢注者和邮箱所有者共用账号
+		if !user.IsEmailVerified() {
+			return nil, errcode.OIDCAccountUnverified
+		}
+	case errors.Is(err, repository.ErrNotFound):
+		if user, err = s.signup(identity, now); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if err := s.identities.Create(&models.UserIdentity{
+		UserID:      user.ID,
+		Provider:    provider,
+		Subject:     identity.Subject,
+		Email:       identity.Email,
+		LastLoginAt: &now,
+	}); err != nil {
+		return nil, err
+	}
+	logger.Infof("用户 %d 关联了第三方账号 %s", user.ID, provider)
+	return user, nil
+}
+
+// signup 使用第三方账号的信息创建用户，邮箱视为已验证。
+// 密码为随机值，需要密码登录时通过找回密码设置
+func (s *OIDCService) signup(identity *oidc.Identity, now time.Time) (*models.User, error) {
+	if !s.current().AllowSignup {
+		return nil, errcode.OIDCSignupDisabled
+	}
+	password, err := utils.RandomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	hashed, err := s.userService.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	user := &models.User{
+		Username:        signupUsername(identity),
+		Email:           identity.Email,
+		Password:        hashed,
+		EmailVerifiedAt: &now,
+	}
+	if err := s.userService.CreateUser(user); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, errcode.UserAlreadyExists
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// signupUsername 新用户的用户名，优先使用提供方返回的名称，没有时使用邮箱的用户名部分
+func signupUsername(identity *oidc.Identity) string {
+	name := strings.TrimSpace(identity.Name)
+	if name == "" {
+		name = strings.SplitN(identity.Email, "@", 2)[0]
+	}
+	for utf8.RuneCountInString(name) > 50 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+// takeFlow 校验并消费流程，state 只能使用一次
+func (s *OIDCService) takeFlow(state string) (*models.OIDCFlow, error) {
+	flow, err := s.flows.FindByHash(utils.HashToken(state))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errcode.OIDCFlowInvalid
+		}
+		return nil, err
+	}
+	if flow.IsUsed() || flow.IsExpired() {
+		return nil, errcode.OIDCFlowInvalid
+	}
+	if err := s.flows.Consume(flow.ID); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, errcode.OIDCFlowInvalid
+		}
+		return nil, err
+	}
+	return flow, nil
+}
+
+// client 返回提供方的客户端，首次使用时读取发现文档
+func (s *OIDCService) client(name string) (*oidc.Client, error) {
+	s.mu.RLock()
+	client, cached := s.clients[name]
+	cfg, generation := s.cfg, s.generation
+	s.mu.RUnlock()
+	if cached {
+		return client, nil
+	}
+
+	var provider *config.OIDCProviderConfig
+	for i := range cfg.Providers {
+		if cfg.Providers[i].Name == name {
+			provider = &cfg.Providers[i]
+			break
+		}
+	}
+	if provider == nil {
+		return nil, errcode.OIDCProviderNotFound
+	}
+	client, err := newOIDCClient(*provider, cfg.RedirectURL)
+	if err != nil {
+		logger.Errorf("初始化第三方登录 %s 失败: %v", name, err)
+		return nil, errcode.OIDCLoginFailed
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 创建期间配置已变更时不缓存旧配置的客户端
+	if s.generation == generation {
+		s.clients[name] = client
+	}
+	return client, nil
+}
+
+func newOIDCClient(p config.OIDCProviderConfig, redirectURL string) (*oidc.Client, error) {
+	secret := p.ClientSecret
+	if p.ClientSecretFile != "" {
+		data, err := os.ReadFile(p.ClientSecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取 client_secret_file 失败: %v", err)
+		}
+		secret = strings.TrimRight(string(data), "\r\n")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), oidcRequestTimeout)
+	defer cancel()
+	return oidc.NewClient(ctx, oidc.Config{
+		Issuer:       p.Issuer,
+		ClientID:     p.ClientID,
+		ClientSecret: secret,
+		RedirectURL:  redirectURL,
+		Scopes:       p.Scopes,
+	})
+}
@@ -0,0 +1,371 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_app/config"
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/pkg/logger"
+	"go_app/repository"
+	"go_app/utils"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// PasskeyService 通行密钥（WebAuthn）：注册多个通行密钥，并使用通行密钥免密码登录。
+// 通行密钥在身份验证器上完成用户验证（指纹、面容或 PIN），因此登录时不再要求两步验证
+type PasskeyService struct {
+	users      repository.UserRepository
+	passkeys   repository.PasskeyRepository
+	ceremonies repository.PasskeyCeremonyRepository
+
+	mu  sync.RWMutex
+	cfg config.WebAuthnConfig
+	rp  *webauthn.WebAuthn
+}
+
+func NewPasskeyService(users repository.UserRepository, passkeys repository.PasskeyRepository, ceremonies repository.PasskeyCeremonyRepository, cfg config.WebAuthnConfig) (*PasskeyService, error) {
+	rp, err := newRelyingParty(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &PasskeyService{users: users, passkeys: passkeys, ceremonies: ceremonies, cfg: cfg, rp: rp}, nil
+}
+
+// OnConfigChange 配置热加载回调，更新依赖方信息和流程有效期。
+// 修改 rp_id 后已注册的通行密钥将无法使用
+func (s *PasskeyService) OnConfigChange(cfg *config.Config) {
+	rp, err := newRelyingParty(cfg.WebAuthn)
+	if err != nil {
+		logger.Errorf("通行密钥配置无效，继续使用原配置: %v", err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg.WebAuthn
+	s.rp = rp
+}
+
+func (s *PasskeyService) current() (config.WebAuthnConfig, *webauthn.WebAuthn) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg, s.rp
+}
+
+func newRelyingParty(cfg config.WebAuthnConfig) (*webauthn.WebAuthn, error) {
+	timeout := webauthn.TimeoutConfig{Enforce: true, Timeout: time.Duration(cfg.Timeout) * time.Second}
+	timeout.TimeoutUVD = timeout.Timeout
+	return webauthn.New(&webauthn.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: cfg.RPDisplayName,
+		RPOrigins:     cfg.RPOrigins,
+		// 通行密钥代替密码登录：必须是可发现凭证，并要求身份验证器验证用户本人
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:      protocol.ResidentKeyRequirementRequired,
+			UserVerification: protocol.VerificationRequired,
+		},
+		Timeouts: webauthn.TimeoutsConfig{Login: timeout, Registration: timeout},
+	})
+}
+
+// BeginRegistration 开始注册通行密钥，已注册的通行密钥不能在同一身份验证器上重复注册
+func (s *PasskeyService) BeginRegistration(userID uint) (*models.PasskeyOptions, error) {
+	user, err := s.loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	_, rp := s.current()
+	creation, session, err := rp.BeginRegistration(user, webauthn.WithExclusions(webauthn.Credentials(user.credentials).CredentialDescriptors()))
+	if err != nil {
+		return nil, err
+	}
+	return s.startCeremony(models.PasskeyCeremonyRegistration, userID, session, creation)
+}
+
+// FinishRegistration 校验身份验证器返回的注册响应（attestation）并保存通行密钥，
+// name 为空时按序号生成名称
+func (s *PasskeyService) FinishRegistration(userID uint, ceremonyToken, name string, body []byte) (*models.Passkey, error) {
+	session, err := s.takeCeremony(ceremonyToken, models.PasskeyCeremonyRegistration, userID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(body)
+	if err != nil {
+		return nil, verifyFailed(err)
+	}
+	_, rp := s.current()
+	credential, err := rp.CreateCredential(user, *session, parsed)
+	if err != nil {
+		return nil, verifyFailed(err)
+	}
+
+	if name = strings.TrimSpace(name); name == "" {
+		name = fmt.Sprintf("通行密钥 %d", len(user.credentials)+1)
+	}
+	transports := make([]string, 0, len(credential.Transport))
+	for _, t := range credential.Transport {
+		transports = append(transports, string(t))
+	}
+	passkey := &models.Passkey{
+		UserID:          userID,
+		CredentialID:    encodeCredentialID(credential.ID),
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      strings.Join(transports, ","),
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		UserVerified:    credential.Flags.UserVerified,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		Name:            name,
+	}
+	if err := s.passkeys.Create(passkey); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, errcode.PasskeyAlreadyExists
+		}
+		return nil, err
+	}
+	return passkey, nil
+}
+
+// BeginLogin 开始通行密钥登录。不需要提供邮箱，由浏览器列出本站可用的通行密钥供用户选择
+func (s *PasskeyService) BeginLogin() (*models.PasskeyOptions, error) {
+	_, rp := s.current()
+	assertion, session, err := rp.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return nil, err
+	}
+	return s.startCeremony(models.PasskeyCeremonyLogin, 0, session, assertion)
+}
+
+// FinishLogin 校验身份验证器返回的登录响应（assertion），返回通行密钥所属的用户。
+// 签名计数器未增长说明凭证可能被克隆，拒绝登录
+func (s *PasskeyService) FinishLogin(ceremonyToken string, body []byte) (*models.User, error) {
+	session, err := s.takeCeremony(ceremonyToken, models.PasskeyCeremonyLogin, 0)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(body)
+	if err != nil {
+		return nil, verifyFailed(err)
+	}
+
+	var passkey *models.Passkey
+	var owner *passkeyUser
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		found, err := s.passkeys.FindByCredentialID(encodeCredentialID(rawID))
+		if err != nil {
+			return nil, err
+		}
+		if id, ok := parseUserHandle(userHandle); !ok || id != found.UserID {
+			return nil, errors.New("user handle 与通行密钥不匹配")
+		}
+		user, err := s.loadUser(found.UserID)
+		if err != nil {
+			return nil, err
+		}
+		passkey, owner = found, user
+		return user, nil
+	}
+	_, rp := s.current()
+	credential, err := rp.ValidateDiscoverableLogin(handler, *session, parsed)
+	if err != nil {
+		return nil, verifyFailed(err)
+	}
+	if credential.Authenticator.CloneWarning {
+		logger.Warnf("通行密钥 %d（用户 %d）签名计数器异常，可能已被克隆", passkey.ID, passkey.UserID)
+		return nil, errcode.PasskeyVerifyFailed
+	}
+	if err := s.passkeys.RecordUse(passkey.ID, credential.Authenticator.SignCount, credential.Flags.BackupState, time.Now()); err != nil {
+		return nil, err
+	}
+	if owner.user.IsDisabled() {
+		return nil, errcode.UserDisabled
+	}
+	return owner.user, nil
+}
+
+// List 列出用户的全部通行密钥
+func (s *PasskeyService) List(userID uint) ([]*models.Passkey, error) {
+	return s.passkeys.ListByUser(userID)
+}
+
+// Rename 修改通行密钥名称
+func (s *PasskeyService) Rename(userID, id uint, name string) error {
+	return passkeyNotFound(s.passkeys.Rename(userID, id, strings.TrimSpace(name)))
+}
+
+// Delete 删除通行密钥，删除后不能再使用该通行密钥登录
+func (s *PasskeyService) Delete(userID, id uint) error {
+	return passkeyNotFound(s.passkeys.Delete(userID, id))
+}
+
+// startCeremony 保存流程会话数据，返回流程令牌和传给浏览器的参数
+func (s *PasskeyService) startCeremony(ceremonyType string, userID uint, session *webauthn.SessionData, options interface{}) (*models.PasskeyOptions, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return nil, err
+	}
+	token, err := utils.RandomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	cfg, _ := s.current()
+	expiredAt := time.Now().Add(time.Duration(cfg.Timeout) * time.Second)
+	if err := s.ceremonies.Create(&models.PasskeyCeremony{
+		UserID:    userID,
+		TokenHash: utils.HashToken(token),
+		Type:      ceremonyType,
+		Data:      string(data),
+		ExpiredAt: expiredAt,
+	}); err != nil {
+		return nil, err
+	}
+	return &models.PasskeyOptions{CeremonyToken: token, ExpiredAt: expiredAt, Options: options}, nil
+}
+
+// takeCeremony 校验并消费流程令牌，返回开始流程时保存的会话数据；
+// 流程令牌只能使用一次，验证失败后需要重新开始
+func (s *PasskeyService) takeCeremony(token, ceremonyType string, userID uint) (*webauthn.SessionData, error) {
+	ceremony, err := s.ceremonies.FindByHash(utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errcode.PasskeyCeremonyInvalid
+		}
+		return nil, err
+	}
+	if ceremony.Type != ceremonyType || ceremony.UserID != userID || ceremony.IsUsed() || ceremony.IsExpired() {
+		return nil, errcode.PasskeyCeremonyInvalid
+	}
+	if err := s.ceremonies.Consume(ceremony.ID); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, errcode.PasskeyCeremonyInvalid
+		}
+		return nil, err
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal([]byte(ceremony.Data), &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// loadUser 获取用户及其已注册的通行密钥
+func (s *PasskeyService) loadUser(userID uint) (*passkeyUser, error) {
+	user, err := s.users.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errcode.UserNotFound
+		}
+		return nil, err
+	}
+	passkeys, err := s.passkeys.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	credentials := make([]webauthn.Credential, 0, len(passkeys))
+	for _, p := range passkeys {
+		credential, err := toCredential(p)
+		if err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, credential)
+	}
+	return &passkeyUser{user: user, credentials: credentials}, nil
+}
+
+// passkeyUser 实现 webauthn.User
+type passkeyUser struct {
+	user        *models.User
+	credentials []webauthn.Credential
+}
+
+// WebAuthnID 用户句柄，使用 8 字节大端序的用户ID，不包含邮箱等个人信息
+func (u *passkeyUser) WebAuthnID() []byte {
+	handle := make([]byte, 8)
+	binary.BigEndian.PutUint64(handle, uint64(u.user.ID))
+	return handle
+}
+
+func (u *passkeyUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *passkeyUser) WebAuthnDisplayName() string {
+	return u.user.Username
+}
+
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
+
+func parseUserHandle(handle []byte) (uint, bool) {
+	if len(handle) != 8 {
+		return 0, false
+	}
+	return uint(binary.BigEndian.Uint64(handle)), true
+}
+
+func toCredential(p *models.Passkey) (webauthn.Credential, error) {
+	id, err := base64.RawURLEncoding.DecodeString(p.CredentialID)
+	if err != nil {
+		return webauthn.Credential{}, fmt.Errorf("通行密钥 %d 的凭证ID无效: %v", p.ID, err)
+	}
+	var transports []protocol.AuthenticatorTransport
+	if p.Transports != "" {
+		for _, t := range strings.Split(p.Transports, ",") {
+			transports = append(transports, protocol.AuthenticatorTransport(t))
+		}
+	}
+	return webauthn.Credential{
+		ID:              id,
+		PublicKey:       p.PublicKey,
+		AttestationType: p.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			UserPresent:    true,
+			UserVerified:   p.UserVerified,
+			BackupEligible: p.BackupEligible,
+			BackupState:    p.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    p.AAGUID,
+			SignCount: p.SignCount,
+		},
+	}, nil
+}
+
+func encodeCredentialID(id []byte) string {
+	return base64.RawURLEncoding.EncodeToString(id)
+}
+
+// verifyFailed 记录 WebAuthn 校验失败的详细原因，对客户端只返回通用错误
+func verifyFailed(err error) error {
+	var protocolErr *protocol.Error
+	if errors.As(err, &protocolErr) {
+		logger.Debugf("通行密钥校验失败: %s %s", protocolErr.Details, protocolErr.DevInfo)
+	} else {
+		logger.Debugf("通行密钥校验失败: %v", err)
+	}
+	return errcode.PasskeyVerifyFailed
+}
+
+func passkeyNotFound(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return errcode.PasskeyNotFound
+	}
+	return err
+}
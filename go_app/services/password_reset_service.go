@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_app/config"
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/pkg/logger"
+	"go_app/pkg/mailer"
+	"go_app/repository"
+	"go_app/utils"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// passwordResetWorkers 发送重置邮件的后台任务数
+	passwordResetWorkers = 2
+	// passwordResetQueueSize 等待发送的申请数上限，超出时丢弃新的申请
+	passwordResetQueueSize = 100
+)
+
+// PasswordResetService 通过邮件中的一次性令牌找回密码
+type PasswordResetService struct {
+	users  *UserService
+	resets repository.PasswordResetRepository
+	mailer mailer.Mailer
+	queue  chan string
+
+	mu  sync.RWMutex
+	cfg config.PasswordResetConfig
+}
+
+func NewPasswordResetService(users *UserService, resets repository.PasswordResetRepository, m mailer.Mailer, cfg config.PasswordResetConfig) *PasswordResetService {
+	return &PasswordResetService{users: users, resets: resets, mailer: m, queue: make(chan string, passwordResetQueueSize), cfg: cfg}
+}
+
+// OnConfigChange 配置热加载回调，更新令牌有效期和重置页面地址
+func (s *PasswordResetService) OnConfigChange(cfg *config.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg.PasswordReset
+}
+
+func (s *PasswordResetService) current() config.PasswordResetConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
+
+// Run 启动固定数量的后台任务处理 Enqueue 提交的申请，直到 ctx 结束
+func (s *PasswordResetService) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < passwordResetWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case email := <-s.queue:
+					if err := s.Forgot(email); err != nil {
+						logger.Errorf("发送重置密码邮件失败: %v", err)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Enqueue 提交找回密码申请，由 Run 启动的后台任务调用 Forgot 处理；
+// 等待处理的申请已满时丢弃、记录日志并返回 false
+func (s *PasswordResetService) Enqueue(email string) bool {
+	select {
+	case s.queue <- email:
+		return true
+	default:
+		logger.Warnf("找回密码申请过多，丢弃 %s 的申请", email)
+		return false
+	}
+}
+
+// Forgot 为邮箱对应的用户生成重置令牌并发送邮件。
+// 邮箱未注册、账号已被禁用或冷却时间内已发送过仍然有效的令牌时不发送邮件并返回 nil，调用方无法据此判断邮箱是否存在
+func (s *PasswordResetService) Forgot(email string) error {
+	user, err := s.users.GetUserByEmail(email)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.IsDisabled() {
+		return nil
+	}
+
+	token, err := utils.RandomHex(32)
+	if err != nil {
+		return err
+	}
+	cfg := s.current()
+	now := time.Now()
+	expire := time.Duration(cfg.Expire) * time.Minute
+	cooldown := time.Duration(cfg.Cooldown) * time.Minute
+	if err := s.resets.CreateUnlessRecent(&models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiredAt: now.Add(expire),
+	}, now.Add(-cooldown)); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil
+		}
+		return err
+	}
+
+	return s.mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "重置密码",
+		Body:    resetMailBody(user.Username, token, cfg.URL, cfg.Expire),
+	})
+}
+
+// Reset 使用重置令牌设置新密码，令牌只能使用一次；
+// 重置后用户的 token 版本递增，已登录的全部会话失效
+func (s *PasswordResetService) Reset(token, newPassword string) error {
+	reset, err := s.resets.FindByHash(utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return errcode.PasswordResetTokenInvalid
+		}
+		return err
+	}
+	if reset.IsUsed() || reset.IsExpired() {
+		return errcode.PasswordResetTokenInvalid
+	}
+	if err := s.resets.Consume(reset.ID); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return errcode.PasswordResetTokenInvalid
+		}
+		return err
+	}
+
+	if err := s.users.ResetPassword(reset.UserID, newPassword); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return errcode.PasswordResetTokenInvalid
+		}
+		return err
+	}
+	return s.resets.DeleteByUser(reset.UserID)
+}
+
+func resetMailBody(username, token, resetURL string, expire int) string {
+	link := tokenLink(resetURL, token)
+	return fmt.Sprintf("%s，你好：\n\n我们收到了重置你账号密码的申请，请在 %d 分钟内通过以下链接设置新密码：\n\n%s\n\n"+
+		"链接只能使用一次，重置后所有已登录的设备都需要重新登录。如果不是你本人操作，请忽略本邮件，你的密码不会被修改。\n",
+		username, expire, link)
+}
+
+// tokenLink 将令牌以 token 参数追加到前端页面地址，地址为空或无效时只返回令牌
+func tokenLink(pageURL, token string) string {
+	if pageURL == "" {
+		return token
+	}
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return token
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
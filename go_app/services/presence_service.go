@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go_app/config"
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/pkg/logger"
+	"go_app/pkg/websocket"
+	"go_app/repository"
+)
+
+// maxPresenceQuery 一次最多查询的用户数
+const maxPresenceQuery = 100
+
+// PresenceService 用户在线状态，实现 websocket.PresenceTracker。
+// 用户有任一连接处于前台时为 online，全部连接都上报 away 时为 away，没有连接时为 offline。
+// 连接断开后其记录再保留 websocket.presence_grace_period 秒，期间重连（包括连到其他实例）不会产生下线和上线事件。
+// 各实例把本机连接的状态写入共享存储并每 presence_ttl/3 续期，实例异常退出后其连接在 presence_ttl 后失效；
+// 用户状态变化时由发现变化的实例向 presence:{id} 主题广播一次
+type PresenceService struct {
+	presence  repository.PresenceRepository
+	users     repository.UserRepository
+	wsManager *websocket.Manager
+
+	cfgMu sync.RWMutex
+	cfg   config.WebSocketConfig
+
+	// mu 保护 local，并串行化本实例对连接记录的写入，避免续期覆盖断开时写入的记录
+	mu sync.Mutex
+	// local 本实例的连接：用户ID → 连接ID → 状态
+	local map[uint]map[string]string
+}
+
+func NewPresenceService(presence repository.PresenceRepository, users repository.UserRepository, wsManager *websocket.Manager, cfg config.WebSocketConfig) *PresenceService {
+	return &PresenceService{
+		presence:  presence,
+		users:     users,
+		wsManager: wsManager,
+		cfg:       cfg,
+		local:     make(map[uint]map[string]string),
+	}
+}
+
+// OnConfigChange 配置热加载回调，新的宽限期和有效期在下次连接变化或续期时生效
+func (s *PresenceService) OnConfigChange(cfg *config.Config) {
+	s.cfgMu.Lock()
+	defer s.cfgMu.Unlock()
+	s.cfg = cfg.WebSocket
+}
+
+func (s *PresenceService) current() config.WebSocketConfig {
+	s.cfgMu.RLock()
+	defer s.cfgMu.RUnlock()
+	return s.cfg
+}
+
+func presenceTTL(cfg config.WebSocketConfig) time.Duration {
+	return time.Duration(cfg.PresenceTTL) * time.Second
+}
+
+func presenceGracePeriod(cfg config.WebSocketConfig) time.Duration {
+	return time.Duration(cfg.PresenceGracePeriod) * time.Second
+}
+
+// Run 定期续期本实例连接的在线记录并核对用户状态，直到 ctx 取消
+func (s *PresenceService) Run(ctx context.Context) {
+	ticker := time.NewTicker(presenceTTL(s.current()) / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Heartbeat()
+			ticker.Reset(presenceTTL(s.current()) / 3)
+		}
+	}
+}
+
+// Heartbeat 续期本实例连接的在线记录，并核对本实例的用户和所有未离线的用户：
+// 连接已全部失效的用户（如所在实例异常退出）更新为离线，并修正多个实例并发更新时公布的过期状态
+func (s *PresenceService) Heartbeat() {
+	expiresAt := time.Now().Add(presenceTTL(s.current()))
+
+	s.mu.Lock()
+	userIDs := make([]uint, 0, len(s.local))
+	for userID := range s.local {
+		userIDs = append(userIDs, userID)
+	}
+	s.mu.Unlock()
+
+	for _, userID := range userIDs {
+		// 逐个用户加锁，期间断开的连接不会被续期
+		s.mu.Lock()
+		for connectionID, status := range s.local[userID] {
+			if err := s.presence.Touch(userID, connectionID, status, expiresAt); err != nil {
+				logger.Errorf("续期用户 %d 的在线记录失败: %v", userID, err)
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	published, err := s.presence.Published()
+	if err != nil {
+		logger.Errorf("查询在线用户失败: %v", err)
+	}
+	s.reconcile(append(userIDs, published...)...)
+}
+
+// Connected 实现 websocket.PresenceTracker，新连接的状态为 online
+func (s *PresenceService) Connected(userID uint, connectionID string) {
+	now := time.Now()
+	s.mu.Lock()
+	conns := s.local[userID]
+	if conns == nil {
+		conns = make(map[string]string)
+		s.local[userID] = conns
+	}
+	conns[connectionID] = models.PresenceOnline
+	err := s.presence.Touch(userID, connectionID, models.PresenceOnline, now.Add(presenceTTL(s.current())))
+	s.mu.Unlock()
+	if err != nil {
+		logger.Errorf("记录用户 %d 的在线状态失败: %v", userID, err)
+	}
+
+	s.touchLastSeen(userID, now)
+	s.reconcile(userID)
+}
+
+// Disconnected 实现 websocket.PresenceTracker，连接记录保留到宽限期结束，之后再核对用户状态
+func (s *PresenceService) Disconnected(userID uint, connectionID string) {
+	now := time.Now()
+	grace := presenceGracePeriod(s.current())
+	s.mu.Lock()
+	status, ok := s.local[userID][connectionID]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.local[userID], connectionID)
+	if len(s.local[userID]) == 0 {
+		delete(s.local, userID)
+	}
+	err := s.presence.Touch(userID, connectionID, status, now.Add(grace))
+	s.mu.Unlock()
+	if err != nil {
+		logger.Errorf("记录用户 %d 的在线状态失败: %v", userID, err)
+	}
+
+	s.touchLastSeen(userID, now)
+	time.AfterFunc(grace, func() { s.reconcile(userID) })
+}
+
+// SetStatus 实现 websocket.PresenceTracker，客户端只能上报 online 或 away
+func (s *PresenceService) SetStatus(userID uint, connectionID, status string) error {
+	if status != models.PresenceOnline && status != models.PresenceAway {
+		return errcode.PresenceStatusInvalid
+	}
+
+	s.mu.Lock()
+	if _, ok := s.local[userID][connectionID]; !ok {
+		s.mu.Unlock()
+		return nil
+	}
+	s.local[userID][connectionID] = status
+	err := s.presence.Touch(userID, connectionID, status, time.Now().Add(presenceTTL(s.current())))
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	s.reconcile(userID)
+	return nil
+}
+
+// Get 查询用户的在线状态，按用户ID升序返回，不存在的用户忽略
+func (s *PresenceService) Get(userIDs []uint) ([]*models.UserPresence, error) {
+	if len(userIDs) > maxPresenceQuery {
+		return nil, errcode.PresenceTooManyUsers
+	}
+	users, err := s.users.FindByIDs(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := s.presence.Statuses(userIDs, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	presences := make([]*models.UserPresence, len(users))
+	for i, user := range users {
+		presences[i] = &models.UserPresence{UserID: user.ID, Status: aggregatePresence(statuses[user.ID]), LastSeenAt: user.LastSeenAt}
+	}
+	return presences, nil
+}
+
+// reconcile 按未失效的连接记录计算用户状态，与公布的状态不同时更新并广播
+func (s *PresenceService) reconcile(userIDs ...uint) {
+	if len(userIDs) == 0 {
+		return
+	}
+	statuses, err := s.presence.Statuses(userIDs, time.Now())
+	if err != nil {
+		logger.Errorf("查询在线状态失败: %v", err)
+		return
+	}
+
+	seen := make(map[uint]bool, len(userIDs))
+	for _, userID := range userIDs {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+
+		status := aggregatePresence(statuses[userID])
+		previous, err := s.presence.SwapStatus(userID, status)
+		if err != nil {
+			logger.Errorf("更新用户 %d 的在线状态失败: %v", userID, err)
+			continue
+		}
+		if previous != status {
+			s.publish(userID, status)
+		}
+	}
+}
+
+// publish 向用户的在线状态主题广播状态变化
+func (s *PresenceService) publish(userID uint, status string) {
+	presence := &models.UserPresence{UserID: userID, Status: status}
+	if user, err := s.users.FindByID(userID); err == nil {
+		presence.LastSeenAt = user.LastSeenAt
+	}
+	if err := s.wsManager.BroadcastToTopic(websocket.PresenceTopic(userID), websocket.MessageTypePresence, presence); err != nil {
+		logger.Errorf("广播用户 %d 的在线状态失败: %v", userID, err)
+	}
+}
+
+// touchLastSeen 更新最后在线时间，用户已被删除时忽略
+func (s *PresenceService) touchLastSeen(userID uint, at time.Time) {
+	if err := s.users.SetLastSeen(userID, at); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		logger.Errorf("更新用户 %d 的最后在线时间失败: %v", userID, err)
+	}
+}
+
+// aggregatePresence 由各连接的状态得到用户状态
+func aggregatePresence(statuses []string) string {
+	if len(statuses) == 0 {
+		return models.PresenceOffline
+	}
+	for _, status := range statuses {
+		if status == models.PresenceOnline {
+			return models.PresenceOnline
+		}
+	}
+	return models.PresenceAway
+}
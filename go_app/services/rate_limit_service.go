@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"go_app/config"
+	"go_app/pkg/logger"
+	"go_app/pkg/ratelimit"
+	"go_app/utils"
+)
+
+// RateLimitClient 发起请求的客户端，用于按规则区分计数
+type RateLimitClient struct {
+	IP     string
+	UserID uint // 未登录时为 0
+	APIKey string
+}
+
+// RateLimitDecision 一次请求的限流结果
+type RateLimitDecision struct {
+	ratelimit.Result
+	Limit  int
+	Window time.Duration
+}
+
+// RateLimitService 按配置中的规则对接口限流：请求匹配 rate_limit.routes 中的第一条规则，
+// 未匹配时使用 rate_limit.default。计数存储出错时放行请求，避免存储故障导致服务不可用
+type RateLimitService struct {
+	store ratelimit.Store
+
+	mu     sync.RWMutex
+	cfg    config.RateLimitConfig
+	routes []rateLimitRoute
+}
+
+// rateLimitRoute rate_limit.routes 中的一条规则，name 区分不同规则的计数
+type rateLimitRoute struct {
+	routeRule
+	name string
+	rule config.RateLimitRule
+}
+
+func NewRateLimitService(store ratelimit.Store, cfg config.RateLimitConfig) *RateLimitService {
+	s := &RateLimitService{store: store}
+	s.apply(cfg)
+	return s
+}
+
+// OnConfigChange 配置热加载回调，更新开关和规则；存储类型需要重启后生效
+func (s *RateLimitService) OnConfigChange(cfg *config.Config) {
+	s.apply(cfg.RateLimit)
+}
+
+func (s *RateLimitService) apply(cfg config.RateLimitConfig) {
+	routes := make([]rateLimitRoute, 0, len(cfg.Routes))
+	for _, rule := range cfg.Routes {
+		// 配置加载时已校验格式
+		r, err := parseRouteRule(rule.Route)
+		if err != nil {
+			continue
+		}
+		routes = append(routes, rateLimitRoute{routeRule: r, name: rule.Route, rule: rule})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg
+	s.routes = routes
+}
+
+// match 返回请求适用的规则名称和规则，没有适用的规则时 ok 为 false
+func (s *RateLimitService) match(method, path string) (name string, rule config.RateLimitRule, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.cfg.Enabled {
+		return "", rule, false
+	}
+	for _, r := range s.routes {
+		if r.match(method, path) {
+			return r.name, r.rule, true
+		}
+	}
+	return "default", s.cfg.Default, s.cfg.Default.Limit > 0
+}
+
+// Allow 记录一次请求并返回限流结果，path 为路由模板（gin.Context.FullPath）；
+// 请求不受限流规则约束或计数存储出错时返回 nil
+func (s *RateLimitService) Allow(method, path string, client RateLimitClient) *RateLimitDecision {
+	name, rule, ok := s.match(method, path)
+	if !ok {
+		return nil
+	}
+
+	limit := ratelimit.Limit{
+		Algorithm: ratelimit.Algorithm(rule.Algorithm),
+		Limit:     rule.Limit,
+		Window:    time.Duration(rule.Window) * time.Second,
+	}
+	// 规则的次数或窗口修改后重新计数
+	key := name + ":" + strconv.Itoa(rule.Limit) + "/" + strconv.Itoa(rule.Window) + ":" + clientKey(rule.Key, client)
+	result, err := s.store.Allow(context.Background(), key, limit, time.Now())
+	if err != nil {
+		logger.Errorf("限流计数失败，放行请求: %v", err)
+		return nil
+	}
+	return &RateLimitDecision{Result: result, Limit: rule.Limit, Window: limit.Window}
+}
+
+// clientKey 按规则的 key 区分客户端，登录用户和 API Key 不可用时退回按 IP
+func clientKey(key string, client RateLimitClient) string {
+	switch {
+	case key == config.RateLimitKeyUser && client.UserID != 0:
+		return "user:" + strconv.FormatUint(uint64(client.UserID), 10)
+	case key == config.RateLimitKeyAPIKey && client.APIKey != "":
+		return "api_key:" + utils.HashToken(client.APIKey)
+	default:
+		return "ip:" + client.IP
+	}
+}
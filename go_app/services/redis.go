@@ -0,0 +1,27 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go_app/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// NewRedisClient 创建 Redis 客户端并检查连接是否可用
+func NewRedisClient(cfg config.RedisConfig) (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
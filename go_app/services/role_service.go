@@ -0,0 +1,147 @@
+package services
+
+import (
+	"errors"
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/repository"
+)
+
+// RoleService 角色分配与权限校验
+type RoleService struct {
+	roles repository.RoleRepository
+	users repository.UserRepository
+}
+
+func NewRoleService(roles repository.RoleRepository, users repository.UserRepository) *RoleService {
+	return &RoleService{roles: roles, users: users}
+}
+
+// HasPermission 用户的任一角色是否拥有指定权限
+func (s *RoleService) HasPermission(userID uint, permission string) (bool, error) {
+	permissions, err := s.roles.Permissions(userID)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range permissions {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Authorize 校验 actorID 能否操作 targetID 的数据：操作自己的数据总是允许，
+// 操作他人的数据需要拥有 permission 权限，否则返回 errcode.Forbidden
+func (s *RoleService) Authorize(actorID, targetID uint, permission string) error {
+	if actorID != 0 && actorID == targetID {
+		return nil
+	}
+	ok, err := s.HasPermission(actorID, permission)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errcode.Forbidden
+	}
+	return nil
+}
+
+// ListRoles 获取全部角色及其权限
+func (s *RoleService) ListRoles() ([]*models.Role, error) {
+	return s.roles.List()
+}
+
+// UserRoles 获取用户拥有的角色
+func (s *RoleService) UserRoles(userID uint) ([]*models.Role, error) {
+	if err := s.checkUser(userID); err != nil {
+		return nil, err
+	}
+	return s.roles.ListByUser(userID)
+}
+
+// AssignRole 为用户分配角色，已拥有该角色时不做任何修改
+func (s *RoleService) AssignRole(userID uint, roleName string) error {
+	if err := s.checkUser(userID); err != nil {
+		return err
+	}
+	role, err := s.findRole(roleName)
+	if err != nil {
+		return err
+	}
+	return s.roles.Assign(userID, role.ID)
+}
+
+// RevokeRole 移除用户的角色，不允许移除最后一个管理员的 admin 角色，避免无人能够管理角色
+func (s *RoleService) RevokeRole(userID uint, roleName string) error {
+	if err := s.checkUser(userID); err != nil {
+		return err
+	}
+	role, err := s.findRole(roleName)
+	if err != nil {
+		return err
+	}
+
+	if role.Name == models.RoleAdmin {
+		if err := s.ensureNotLastAdmin(userID); err != nil {
+			return err
+		}
+	}
+	return s.roles.Revoke(userID, role.ID)
+}
+
+// assignDefaultRole 为新用户分配默认角色
+func (s *RoleService) assignDefaultRole(userID uint) error {
+	role, err := s.roles.FindByName(models.DefaultRole)
+	if err != nil {
+		return err
+	}
+	return s.roles.Assign(userID, role.ID)
+}
+
+// removeUser 移除被删除用户的全部角色
+func (s *RoleService) removeUser(userID uint) error {
+	return s.roles.DeleteByUser(userID)
+}
+
+// ensureNotLastAdmin 用户是唯一的管理员时返回 errcode.RoleLastAdmin
+func (s *RoleService) ensureNotLastAdmin(userID uint) error {
+	roles, err := s.roles.ListByUser(userID)
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if role.Name != models.RoleAdmin {
+			continue
+		}
+		count, err := s.roles.CountUsers(role.ID)
+		if err != nil {
+			return err
+		}
+		if count <= 1 {
+			return errcode.RoleLastAdmin
+		}
+	}
+	return nil
+}
+
+func (s *RoleService) checkUser(userID uint) error {
+	if _, err := s.users.FindByID(userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return errcode.UserNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *RoleService) findRole(name string) (*models.Role, error) {
+	role, err := s.roles.FindByName(name)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errcode.RoleNotFound
+		}
+		return nil, err
+	}
+	return role, nil
+}
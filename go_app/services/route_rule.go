@@ -0,0 +1,35 @@
+package services
+
+import (
+	"strings"
+
+	"go_app/config"
+)
+
+// routeRule 接口匹配规则，method 为空表示不限方法，prefix 为 true 时按路径前缀匹配
+type routeRule struct {
+	method string
+	path   string
+	prefix bool
+}
+
+// parseRouteRule 解析 "METHOD /path" 或 "/path" 格式的规则，* 结尾表示前缀匹配
+func parseRouteRule(rule string) (routeRule, error) {
+	method, path, err := config.ParseRouteRule(rule)
+	if err != nil {
+		return routeRule{}, err
+	}
+	r := routeRule{method: method, path: path}
+	if strings.HasSuffix(path, "*") {
+		r.path, r.prefix = strings.TrimSuffix(path, "*"), true
+	}
+	return r, nil
+}
+
+// match path 为路由模板（gin.Context.FullPath）
+func (r routeRule) match(method, path string) bool {
+	if r.method != "" && r.method != method {
+		return false
+	}
+	return r.path == path || (r.prefix && strings.HasPrefix(path, r.path))
+}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"errors"
+	"go_app/config"
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/repository"
+	"go_app/utils"
+	"sync"
+	"time"
+)
+
+// SessionService 管理按设备划分的登录会话及其访问令牌、刷新令牌
+type SessionService struct {
+	users    repository.UserRepository
+	sessions repository.SessionRepository
+	tokens   TokenService
+
+	mu       sync.RWMutex
+	settings sessionSettings
+}
+
+// sessionSettings 可热加载的会话配置
+type sessionSettings struct {
+	policy        string
+	accessExpire  time.Duration
+	refreshExpire time.Duration
+}
+
+func NewSessionService(users repository.UserRepository, sessions repository.SessionRepository, tokens TokenService, sessionCfg config.SessionConfig, jwtCfg config.JWTConfig) *SessionService {
+	s := &SessionService{users: users, sessions: sessions, tokens: tokens}
+	s.apply(sessionCfg, jwtCfg)
+	return s
+}
+
+// OnConfigChange 配置热加载回调，更新会话策略和令牌有效期，只影响之后签发的令牌
+func (s *SessionService) OnConfigChange(cfg *config.Config) {
+	s.apply(cfg.Session, cfg.JWT)
+}
+
+func (s *SessionService) apply(sessionCfg config.SessionConfig, jwtCfg config.JWTConfig) {
+	policy := sessionCfg.Policy
+	if policy == "" {
+		policy = config.SessionPolicyPerPlatform
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settings = sessionSettings{
+		policy:        policy,
+		accessExpire:  time.Duration(jwtCfg.AccessExpire) * time.Minute,
+		refreshExpire: time.Duration(jwtCfg.Expire) * time.Hour,
+	}
+}
+
+func (s *SessionService) current() sessionSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings
+}
+
+// Create 为用户创建新会话并签发令牌，按会话策略清理旧会话
+func (s *SessionService) Create(user *models.User, meta models.SessionMeta) (*models.TokenResponse, *models.UserToken, error) {
+	if meta.Platform == "" {
+		meta.Platform = models.PlatformWeb
+	}
+
+	sessionID, err := utils.RandomHex(16)
+	if err != nil {
+		return nil, nil, err
+	}
+	refreshToken, refresh, err := newRefreshToken(user.TokenVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	settings := s.current()
+	now := time.Now()
+	session := &models.UserToken{
+		UserID:       user.ID,
+		Token:        sessionID,
+		Platform:     meta.Platform,
+		DeviceID:     meta.DeviceID,
+		UserAgent:    truncate(meta.UserAgent, 255),
+		IP:           meta.IP,
+		LastActiveAt: now,
+		ExpiredAt:    now.Add(settings.refreshExpire),
+	}
+	refresh.ExpiredAt = session.ExpiredAt
+
+	if err := s.sessions.Create(session, refresh, replaceScope(settings.policy, meta)); err != nil {
+		return nil, nil, err
+	}
+
+	pair, err := s.issueTokens(user.TokenVersion, session, refreshToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, session, nil
+}
+
+// Refresh 使用刷新令牌换取新的令牌对，旧刷新令牌随即失效。
+// 已轮换过的刷新令牌被再次使用时视为泄露，注销整个会话
+func (s *SessionService) Refresh(refreshToken string) (*models.TokenResponse, error) {
+	rt, err := s.sessions.FindRefreshToken(utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errcode.TokenInvalid
+		}
+		return nil, err
+	}
+
+	if rt.IsUsed() {
+		if err := s.sessions.Delete(rt.SessionID); err != nil {
+			return nil, err
+		}
+		return nil, errcode.TokenReused
+	}
+	if rt.IsExpired() {
+		return nil, errcode.TokenExpired
+	}
+
+	user, err := s.users.FindByID(rt.UserID)
+	if err != nil {
+		return nil, errcode.UserNotFound
+	}
+	if user.TokenVersion != rt.TokenVersion {
+		return nil, errcode.TokenVersionError
+	}
+	if user.IsDisabled() {
+		return nil, errcode.UserDisabled
+	}
+
+	session, err := s.sessions.FindByID(rt.SessionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errcode.TokenVersionError
+		}
+		return nil, err
+	}
+
+	newRefreshToken, next, err := newRefreshToken(user.TokenVersion)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	session.LastActiveAt = now
+	session.ExpiredAt = now.Add(s.current().refreshExpire)
+	next.ExpiredAt = session.ExpiredAt
+
+	if err := s.sessions.Rotate(rt.ID, session, next); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrConflict):
+			// 并发请求抢先使用了同一刷新令牌
+			if err := s.sessions.Delete(rt.SessionID); err != nil {
+				return nil, err
+			}
+			return nil, errcode.TokenReused
+		case errors.Is(err, repository.ErrNotFound):
+			return nil, errcode.TokenVersionError
+		}
+		return nil, err
+	}
+
+	return s.issueTokens(user.TokenVersion, session, newRefreshToken)
+}
+
+// newRefreshToken 生成新的刷新令牌，返回明文和待保存的哈希记录
+func newRefreshToken(tokenVersion int) (string, *models.RefreshToken, error) {
+	token, err := utils.RandomHex(32)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, &models.RefreshToken{
+		TokenHash:    utils.HashToken(token),
+		TokenVersion: tokenVersion,
+	}, nil
+}
+
+// issueTokens 签发访问令牌并组装令牌响应
+func (s *SessionService) issueTokens(tokenVersion int, session *models.UserToken, refreshToken string) (*models.TokenResponse, error) {
+	accessExpire := s.current().accessExpire
+	accessToken, err := s.tokens.Issue(session, tokenVersion, time.Now().Add(accessExpire))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(accessExpire / time.Second),
+	}, nil
+}
+
+// replaceScope 按会话策略确定新登录需要替换的旧会话
+func replaceScope(policy string, meta models.SessionMeta) repository.SessionReplace {
+	switch policy {
+	case config.SessionPolicySingle:
+		return repository.SessionReplace{All: true}
+	case config.SessionPolicyUnlimited:
+		// 未提供设备ID时无法识别同一设备，只清理过期会话
+		if meta.DeviceID == "" {
+			return repository.SessionReplace{}
+		}
+		return repository.SessionReplace{Platform: meta.Platform, DeviceID: meta.DeviceID}
+	}
+	return repository.SessionReplace{Platform: meta.Platform}
+}
+
+// List 获取用户当前有效的会话列表
+func (s *SessionService) List(userID uint) ([]models.UserToken, error) {
+	return s.sessions.ListActive(userID)
+}
+
+// Revoke 注销用户的指定会话
+func (s *SessionService) Revoke(userID, sessionID uint) error {
+	session, err := s.sessions.FindByID(sessionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return errcode.SessionNotFound
+		}
+		return err
+	}
+	if session.UserID != userID {
+		return errcode.SessionNotFound
+	}
+	return s.sessions.Delete(sessionID)
+}
+
+// RevokeAll 注销用户的全部会话
+func (s *SessionService) RevokeAll(userID uint) error {
+	return s.sessions.DeleteByUser(userID)
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,237 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+
+	"go_app/config"
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/repository"
+	"go_app/services"
+	"go_app/utils"
+)
+
+// TestRefreshRotation 每次刷新签发新的令牌对，旧刷新令牌随即失效，会话保持不变
+func TestRefreshRotation(t *testing.T) {
+	env := newSessionEnv(t, config.SessionPolicyPerPlatform, 24)
+	first := env.login(t, models.SessionMeta{})
+
+	second, err := env.service.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("刷新失败: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("刷新后刷新令牌未轮换")
+	}
+	if second.Token == "" || second.ExpiresIn <= 0 {
+		t.Fatalf("刷新后令牌对不完整: %+v", second)
+	}
+	claims := env.parse(t, second.Token)
+	if got := env.parse(t, first.Token).SessionID; claims.SessionID != got {
+		t.Fatalf("刷新后会话 = %d, want %d", claims.SessionID, got)
+	}
+
+	third, err := env.service.Refresh(second.RefreshToken)
+	if err != nil {
+		t.Fatalf("再次刷新失败: %v", err)
+	}
+	env.parse(t, third.Token)
+}
+
+// TestRefreshReuse 已轮换的刷新令牌被再次使用时注销整个会话，同一令牌族的全部令牌失效
+func TestRefreshReuse(t *testing.T) {
+	env := newSessionEnv(t, config.SessionPolicyUnlimited, 24)
+	stolen := env.login(t, models.SessionMeta{DeviceID: "phone"})
+	other := env.login(t, models.SessionMeta{DeviceID: "laptop"})
+
+	rotated, err := env.service.Refresh(stolen.RefreshToken)
+	if err != nil {
+		t.Fatalf("刷新失败: %v", err)
+	}
+	next, err := env.service.Refresh(rotated.RefreshToken)
+	if err != nil {
+		t.Fatalf("再次刷新失败: %v", err)
+	}
+
+	_, err = env.service.Refresh(stolen.RefreshToken)
+	expectErr(t, err, errcode.TokenReused)
+
+	// 令牌族中最新的刷新令牌和访问令牌都已失效
+	_, err = env.service.Refresh(next.RefreshToken)
+	expectErr(t, err, errcode.TokenInvalid)
+	_, err = env.tokens.Parse(next.Token)
+	expectErr(t, err, errcode.TokenVersionError)
+
+	// 其他设备的会话不受影响
+	env.parse(t, other.Token)
+	if _, err := env.service.Refresh(other.RefreshToken); err != nil {
+		t.Fatalf("其他会话刷新失败: %v", err)
+	}
+}
+
+// TestRefreshExpired 过期的刷新令牌不能换取新的令牌对
+func TestRefreshExpired(t *testing.T) {
+	env := newSessionEnv(t, config.SessionPolicyPerPlatform, -1)
+	pair := env.login(t, models.SessionMeta{})
+
+	_, err := env.service.Refresh(pair.RefreshToken)
+	expectErr(t, err, errcode.TokenExpired)
+
+	_, err = env.service.Refresh("unknown")
+	expectErr(t, err, errcode.TokenInvalid)
+}
+
+// TestSessionPolicy 按会话策略限制每个用户的会话数，新登录替换最早的同范围会话
+func TestSessionPolicy(t *testing.T) {
+	tests := []struct {
+		policy string
+		logins []models.SessionMeta
+		// 保留的登录序号
+		keep []int
+	}{
+		{config.SessionPolicySingle, []models.SessionMeta{
+			{Platform: models.PlatformWeb}, {Platform: models.PlatformIOS}, {Platform: models.PlatformAndroid},
+		}, []int{2}},
+		{config.SessionPolicyPerPlatform, []models.SessionMeta{
+			{Platform: models.PlatformWeb}, {Platform: models.PlatformIOS}, {Platform: models.PlatformWeb, DeviceID: "b"},
+		}, []int{1, 2}},
+		{config.SessionPolicyUnlimited, []models.SessionMeta{
+			{DeviceID: "a"}, {DeviceID: "b"}, {DeviceID: "a"}, {},
+		}, []int{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			env := newSessionEnv(t, tt.policy, 24)
+			pairs := make([]*models.TokenResponse, len(tt.logins))
+			for i, meta := range tt.logins {
+				pairs[i] = env.login(t, meta)
+			}
+
+			active, err := env.service.List(env.user.ID)
+			if err != nil {
+				t.Fatalf("获取会话列表失败: %v", err)
+			}
+			if len(active) != len(tt.keep) {
+				t.Fatalf("会话数 = %d, want %d", len(active), len(tt.keep))
+			}
+			for i, pair := range pairs {
+				_, err := env.tokens.Parse(pair.Token)
+				if containsInt(tt.keep, i) {
+					if err != nil {
+						t.Fatalf("登录 %d 的会话被替换: %v", i, err)
+					}
+					continue
+				}
+				expectErr(t, err, errcode.TokenVersionError)
+				_, err = env.service.Refresh(pair.RefreshToken)
+				expectErr(t, err, errcode.TokenInvalid)
+			}
+		})
+	}
+}
+
+// TestSessionRevoke 按访问令牌的 jti 或会话ID注销单个会话，其他会话不受影响
+func TestSessionRevoke(t *testing.T) {
+	env := newSessionEnv(t, config.SessionPolicyUnlimited, 24)
+	web := env.login(t, models.SessionMeta{DeviceID: "web"})
+	phone := env.login(t, models.SessionMeta{DeviceID: "phone"})
+	tablet := env.login(t, models.SessionMeta{DeviceID: "tablet"})
+
+	// 注销时访问令牌可以已经过期，但必须能验证签名
+	if err := env.tokens.Revoke("Bearer " + web.Token); err != nil {
+		t.Fatalf("按 jti 注销会话失败: %v", err)
+	}
+	_, err := env.tokens.Parse(web.Token)
+	expectErr(t, err, errcode.TokenVersionError)
+	_, err = env.service.Refresh(web.RefreshToken)
+	expectErr(t, err, errcode.TokenInvalid)
+	expectErr(t, env.tokens.Revoke("not-a-jwt"), errcode.TokenInvalid)
+
+	// 不能注销其他用户的会话
+	phoneID := env.parse(t, phone.Token).SessionID
+	expectErr(t, env.service.Revoke(env.user.ID+1, phoneID), errcode.SessionNotFound)
+	if err := env.service.Revoke(env.user.ID, phoneID); err != nil {
+		t.Fatalf("注销会话失败: %v", err)
+	}
+	_, err = env.tokens.Parse(phone.Token)
+	expectErr(t, err, errcode.TokenVersionError)
+	expectErr(t, env.service.Revoke(env.user.ID, phoneID), errcode.SessionNotFound)
+
+	env.parse(t, tablet.Token)
+	if err := env.service.RevokeAll(env.user.ID); err != nil {
+		t.Fatalf("注销全部会话失败: %v", err)
+	}
+	_, err = env.tokens.Parse(tablet.Token)
+	expectErr(t, err, errcode.TokenVersionError)
+}
+
+// sessionEnv 内存仓储上的会话服务和一个用户
+type sessionEnv struct {
+	users    repository.UserRepository
+	sessions repository.SessionRepository
+	tokens   services.TokenService
+	service  *services.SessionService
+	user     *models.User
+}
+
+// newSessionEnv 使用会话策略 policy 创建会话服务，refreshExpire 为刷新令牌有效期（小时）
+func newSessionEnv(t *testing.T, policy string, refreshExpire int) *sessionEnv {
+	t.Helper()
+	users := repository.NewMemoryUserRepository()
+	sessions := repository.NewMemorySessionRepository()
+	jwtCfg := config.JWTConfig{Algorithm: config.JWTAlgorithmHS256, Secret: "services-test-secret", Expire: refreshExpire, AccessExpire: 15}
+	tokens, err := services.NewTokenService(users, sessions, jwtCfg)
+	if err != nil {
+		t.Fatalf("创建令牌服务失败: %v", err)
+	}
+
+	user := &models.User{Username: "alice", Email: "alice@example.com", Password: "hash"}
+	if err := users.Create(user); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	return &sessionEnv{
+		users:    users,
+		sessions: sessions,
+		tokens:   tokens,
+		service:  services.NewSessionService(users, sessions, tokens, config.SessionConfig{Policy: policy}, jwtCfg),
+		user:     user,
+	}
+}
+
+// login 为用户创建一个会话
+func (env *sessionEnv) login(t *testing.T, meta models.SessionMeta) *models.TokenResponse {
+	t.Helper()
+	pair, _, err := env.service.Create(env.user, meta)
+	if err != nil {
+		t.Fatalf("创建会话失败: %v", err)
+	}
+	return pair
+}
+
+// parse 校验访问令牌有效并返回令牌声明
+func (env *sessionEnv) parse(t *testing.T, token string) *utils.Claims {
+	t.Helper()
+	claims, err := env.tokens.Parse(token)
+	if err != nil {
+		t.Fatalf("访问令牌无效: %v", err)
+	}
+	return claims
+}
+
+func expectErr(t *testing.T, err error, want *errcode.ErrorCode) {
+	t.Helper()
+	var got *errcode.ErrorCode
+	if !errors.As(err, &got) || got.Code != want.Code {
+		t.Fatalf("错误 = %v, want %v", err, want)
+	}
+}
+
+func containsInt(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
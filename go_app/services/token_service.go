@@ -0,0 +1,136 @@
+package services
+
+import (
+	"errors"
+	"go_app/config"
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/repository"
+	"go_app/utils"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenService 访问令牌服务，HTTP 接口与 WebSocket 共用同一套签发和校验逻辑
+type TokenService interface {
+	// Issue 为会话签发访问令牌
+	Issue(session *models.UserToken, tokenVersion int, expiresAt time.Time) (string, error)
+	// Parse 校验签名、有效期、所属会话及用户 token 版本，返回令牌声明
+	Parse(tokenString string) (*utils.Claims, error)
+	// Revoke 吊销令牌所属会话，该会话签发的访问令牌和刷新令牌全部失效
+	Revoke(tokenString string) error
+	// JWKS 返回可公开的验签公钥
+	JWKS() utils.JWKS
+}
+
+// 会话最近活跃时间的刷新间隔，避免每个请求都写库
+const sessionTouchInterval = time.Minute
+
+type jwtTokenService struct {
+	users    repository.UserRepository
+	sessions repository.SessionRepository
+	keys     *utils.KeySet
+}
+
+// NewTokenService 根据 JWT 配置加载签名密钥并创建令牌服务
+func NewTokenService(users repository.UserRepository, sessions repository.SessionRepository, cfg config.JWTConfig) (TokenService, error) {
+	keys, err := utils.LoadKeySet(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &jwtTokenService{users: users, sessions: sessions, keys: keys}, nil
+}
+
+func (s *jwtTokenService) Issue(session *models.UserToken, tokenVersion int, expiresAt time.Time) (string, error) {
+	claims := &utils.Claims{
+		UserID:       session.UserID,
+		TokenVersion: tokenVersion,
+		SessionID:    session.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        session.Token,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	return s.keys.Sign(claims)
+}
+
+func (s *jwtTokenService) Parse(tokenString string) (*utils.Claims, error) {
+	claims, err := s.keys.Parse(trimBearer(tokenString))
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证 token 所属会话（设备）是否仍然有效
+	if _, err := s.validateSession(claims); err != nil {
+		return nil, err
+	}
+
+	// 验证 token 版本，修改密码等操作会使旧版本 token 全部失效
+	user, err := s.users.FindByID(claims.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errcode.UserNotFound
+		}
+		return nil, err
+	}
+	if claims.TokenVersion != user.TokenVersion {
+		return nil, errcode.TokenVersionError
+	}
+	if user.IsDisabled() {
+		return nil, errcode.UserDisabled
+	}
+
+	return claims, nil
+}
+
+func (s *jwtTokenService) Revoke(tokenString string) error {
+	claims, err := s.keys.ParseIgnoreExpiry(trimBearer(tokenString))
+	if err != nil {
+		return err
+	}
+	return s.sessions.Delete(claims.SessionID)
+}
+
+func (s *jwtTokenService) JWKS() utils.JWKS {
+	return s.keys.JWKS()
+}
+
+// validateSession 校验令牌对应的会话记录
+func (s *jwtTokenService) validateSession(claims *utils.Claims) (*models.UserToken, error) {
+	if claims.ID == "" {
+		return nil, errcode.TokenInvalid
+	}
+
+	session, err := s.sessions.FindByToken(claims.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errcode.TokenVersionError
+		}
+		return nil, err
+	}
+
+	if session.ID != claims.SessionID || session.UserID != claims.UserID {
+		return nil, errcode.TokenInvalid
+	}
+	if session.IsExpired() {
+		return nil, errcode.TokenExpired
+	}
+
+	if time.Since(session.LastActiveAt) > sessionTouchInterval {
+		session.LastActiveAt = time.Now()
+		s.sessions.Touch(session.ID, session.LastActiveAt)
+	}
+	return session, nil
+}
+
+// trimBearer 兼容 "Bearer <token>" 与直接传递 token 两种格式
+func trimBearer(tokenString string) string {
+	tokenString = strings.TrimSpace(tokenString)
+	if len(tokenString) > 7 && strings.EqualFold(tokenString[:7], "Bearer ") {
+		return strings.TrimSpace(tokenString[7:])
+	}
+	return tokenString
+}
@@ -0,0 +1,410 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go_app/config"
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/repository"
+	"go_app/utils"
+	"image/png"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// TOTP 参数使用身份验证器普遍支持的默认值：30 秒时间步、6 位数字、SHA1
+const (
+	totpPeriod = 30
+	totpDigits = otp.DigitsSix
+	// totpSkew 允许前后各一个时间步的时钟误差
+	totpSkew = 1
+	// 二维码图片边长（像素）
+	totpQRCodeSize = 256
+)
+
+// recoveryCodeEncoding 恢复码字符集，去掉了容易混淆的字符
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghjkmnpqrstuvwxyz023456789").WithPadding(base32.NoPadding)
+
+// TwoFactorService TOTP 两步验证（RFC 6238）：绑定身份验证器、恢复码和两步登录
+type TwoFactorService struct {
+	users      repository.UserRepository
+	twoFactors repository.TwoFactorRepository
+	challenges repository.TwoFactorChallengeRepository
+	throttle   *LoginThrottleService
+
+	mu  sync.RWMutex
+	cfg config.TwoFactorConfig
+}
+
+func NewTwoFactorService(users repository.UserRepository, twoFactors repository.TwoFactorRepository, challenges repository.TwoFactorChallengeRepository, throttle *LoginThrottleService, cfg config.TwoFactorConfig) *TwoFactorService {
+	return &TwoFactorService{users: users, twoFactors: twoFactors, challenges: challenges, throttle: throttle, cfg: cfg}
+}
+
+// OnConfigChange 配置热加载回调，更新服务名称、挑战令牌有效期、错误次数上限和恢复码数量
+func (s *TwoFactorService) OnConfigChange(cfg *config.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg.TwoFactor
+}
+
+func (s *TwoFactorService) current() config.TwoFactorConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
+
+// Enabled 用户是否已开启两步验证
+func (s *TwoFactorService) Enabled(userID uint) (bool, error) {
+	twoFactor, err := s.twoFactors.Find(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return twoFactor.IsEnabled(), nil
+}
+
+// Status 获取用户的两步验证状态
+func (s *TwoFactorService) Status(userID uint) (*models.TwoFactorStatus, error) {
+	enabled, err := s.Enabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &models.TwoFactorStatus{Enabled: enabled}
+	if enabled {
+		if status.RecoveryCodesRemaining, err = s.twoFactors.CountRecoveryCodes(userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// Enroll 为用户生成新的 TOTP 密钥，返回 otpauth URI 和二维码。
+// 重复调用会覆盖之前未确认的密钥，已开启两步验证时返回 errcode.TwoFactorAlreadyEnabled
+func (s *TwoFactorService) Enroll(userID uint) (*models.TwoFactorEnrollment, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	enabled, err := s.Enabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, errcode.TwoFactorAlreadyEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      s.current().Issuer,
+		AccountName: user.Email,
+		Period:      totpPeriod,
+		Digits:      totpDigits,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactors.Save(&models.TwoFactor{UserID: userID, Secret: key.Secret()}); err != nil {
+		return nil, err
+	}
+
+	img, err := key.Image(totpQRCodeSize, totpQRCodeSize)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return &models.TwoFactorEnrollment{
+		Secret:     key.Secret(),
+		OTPAuthURI: key.URL(),
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// Confirm 使用身份验证器中的验证码确认绑定，两步验证随即生效，返回新生成的恢复码。
+// 错误次数按登录失败的规则限制，超过限制时返回 errcode.TwoFactorLocked
+func (s *TwoFactorService) Confirm(userID uint, code string) ([]string, error) {
+	twoFactor, err := s.twoFactors.Find(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errcode.TwoFactorNotEnrolled
+		}
+		return nil, err
+	}
+	if twoFactor.IsEnabled() {
+		return nil, errcode.TwoFactorAlreadyEnabled
+	}
+	key := confirmThrottleKey(userID)
+	if err := s.throttle.Reserve(key); err != nil {
+		var throttled *LoginThrottledError
+		if errors.As(err, &throttled) {
+			return nil, errcode.TwoFactorLocked
+		}
+		return nil, err
+	}
+	if err := s.verifyTOTP(twoFactor, code); err != nil {
+		return nil, err
+	}
+	if err := s.throttle.Release(key); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactors.Enable(userID, time.Now(), hashes); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, errcode.TwoFactorAlreadyEnabled
+		}
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable 使用验证码或恢复码关闭两步验证
+func (s *TwoFactorService) Disable(userID uint, code string) error {
+	if err := s.verifyLimited(userID, code); err != nil {
+		return err
+	}
+	return s.twoFactors.Delete(userID)
+}
+
+// RegenerateRecoveryCodes 使用验证码或恢复码重新生成恢复码，之前的恢复码全部失效
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	if err := s.verifyLimited(userID, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactors.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Reset 管理员重置用户的两步验证，用于用户丢失身份验证器和恢复码的情况；
+// 用户未开启两步验证时返回 errcode.TwoFactorNotEnabled
+func (s *TwoFactorService) Reset(userID uint) error {
+	if _, err := s.findUser(userID); err != nil {
+		return err
+	}
+	if _, err := s.twoFactors.Find(userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return errcode.TwoFactorNotEnabled
+		}
+		return err
+	}
+	if err := s.twoFactors.Delete(userID); err != nil {
+		return err
+	}
+	return s.challenges.DeleteByUser(userID)
+}
+
+// Challenge 密码校验通过后为开启两步验证的用户签发挑战令牌，meta 中的平台和设备信息在完成登录时使用
+func (s *TwoFactorService) Challenge(user *models.User, meta models.SessionMeta) (*models.TwoFactorChallengeResponse, error) {
+	token, err := utils.RandomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	expiredAt := time.Now().Add(time.Duration(s.current().ChallengeExpire) * time.Minute)
+	if err := s.challenges.Create(&models.TwoFactorChallenge{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		Platform:  meta.Platform,
+		DeviceID:  meta.DeviceID,
+		ExpiredAt: expiredAt,
+	}); err != nil {
+		return nil, err
+	}
+	return &models.TwoFactorChallengeResponse{TwoFactorRequired: true, ChallengeToken: token, ExpiredAt: expiredAt}, nil
+}
+
+// VerifyChallenge 校验挑战令牌和验证码（或恢复码），返回待登录的用户及第一步登录时的设备信息。
+// 令牌只能使用一次，验证码错误次数达到上限后令牌失效，需要重新输入密码
+func (s *TwoFactorService) VerifyChallenge(token, code string) (*models.User, models.SessionMeta, error) {
+	challenge, err := s.challenges.FindByHash(utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, models.SessionMeta{}, errcode.TwoFactorChallengeInvalid
+		}
+		return nil, models.SessionMeta{}, err
+	}
+	maxAttempts := s.current().MaxAttempts
+	if challenge.IsUsed() || challenge.IsExpired() {
+		return nil, models.SessionMeta{}, errcode.TwoFactorChallengeInvalid
+	}
+	// 校验前先占用一次次数，并发提交同一令牌时也不会超过错误次数上限
+	if err := s.challenges.Attempt(challenge.ID, maxAttempts); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, models.SessionMeta{}, errcode.TwoFactorChallengeInvalid
+		}
+		return nil, models.SessionMeta{}, err
+	}
+
+	if err := s.verifyEnabled(challenge.UserID, code); err != nil {
+		// 挑战签发后两步验证被关闭或重置，需要重新登录
+		if err == errcode.TwoFactorNotEnabled {
+			return nil, models.SessionMeta{}, errcode.TwoFactorChallengeInvalid
+		}
+		return nil, models.SessionMeta{}, err
+	}
+	if err := s.challenges.Consume(challenge.ID, maxAttempts); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, models.SessionMeta{}, errcode.TwoFactorChallengeInvalid
+		}
+		return nil, models.SessionMeta{}, err
+	}
+
+	user, err := s.findUser(challenge.UserID)
+	if err != nil {
+		return nil, models.SessionMeta{}, err
+	}
+	if user.IsDisabled() {
+		return nil, models.SessionMeta{}, errcode.UserDisabled
+	}
+	return user, models.SessionMeta{Platform: challenge.Platform, DeviceID: challenge.DeviceID}, nil
+}
+
+// confirmThrottleKey 确认绑定时验证码错误次数的限制 key
+func confirmThrottleKey(userID uint) string {
+	return fmt.Sprintf("2fa-confirm:%d", userID)
+}
+
+// verifyLimited 校验前先占用一次次数，连续错误 max_attempts 次后锁定 lockout 分钟，
+// 避免持有访问令牌的人穷举验证码关闭两步验证
+func (s *TwoFactorService) verifyLimited(userID uint, code string) error {
+	cfg := s.current()
+	now := time.Now()
+	if err := s.twoFactors.Attempt(userID, cfg.MaxAttempts, now.Add(-time.Duration(cfg.Lockout)*time.Minute), now); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return errcode.TwoFactorNotEnabled
+		case errors.Is(err, repository.ErrConflict):
+			return errcode.TwoFactorLocked
+		}
+		return err
+	}
+	if err := s.verifyEnabled(userID, code); err != nil {
+		return err
+	}
+	return s.twoFactors.ResetAttempts(userID)
+}
+
+// verifyEnabled 校验已开启两步验证的用户提交的验证码，6 位数字按 TOTP 验证码校验，否则按恢复码校验
+func (s *TwoFactorService) verifyEnabled(userID uint, code string) error {
+	twoFactor, err := s.twoFactors.Find(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return errcode.TwoFactorNotEnabled
+		}
+		return err
+	}
+	if !twoFactor.IsEnabled() {
+		return errcode.TwoFactorNotEnabled
+	}
+
+	code = normalizeCode(code)
+	if isTOTPCode(code) {
+		return s.verifyTOTP(twoFactor, code)
+	}
+	if err := s.twoFactors.UseRecoveryCode(userID, utils.HashToken(code)); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return errcode.TwoFactorCodeInvalid
+		}
+		return err
+	}
+	return nil
+}
+
+// verifyTOTP 校验 TOTP 验证码，并记录使用的时间步，同一验证码不能重复使用
+func (s *TwoFactorService) verifyTOTP(twoFactor *models.TwoFactor, code string) error {
+	code = normalizeCode(code)
+	if !isTOTPCode(code) {
+		return errcode.TwoFactorCodeInvalid
+	}
+
+	now := time.Now().Unix() / totpPeriod
+	for step := now - totpSkew; step <= now+totpSkew; step++ {
+		expected, err := totp.GenerateCodeCustom(twoFactor.Secret, time.Unix(step*totpPeriod, 0), totp.ValidateOpts{
+			Period:    totpPeriod,
+			Digits:    totpDigits,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return err
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) != 1 {
+			continue
+		}
+		if err := s.twoFactors.UseStep(twoFactor.UserID, step); err != nil {
+			if errors.Is(err, repository.ErrConflict) {
+				return errcode.TwoFactorCodeInvalid
+			}
+			return err
+		}
+		return nil
+	}
+	return errcode.TwoFactorCodeInvalid
+}
+
+// newRecoveryCodes 生成一组恢复码，返回恢复码原文（形如 k3v9q-7xm2a）及其哈希值
+func (s *TwoFactorService) newRecoveryCodes() (codes, hashes []string, err error) {
+	n := s.current().RecoveryCodes
+	codes = make([]string, 0, n)
+	hashes = make([]string, 0, n)
+	b := make([]byte, 7)
+	for len(codes) < n {
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := recoveryCodeEncoding.EncodeToString(b)[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, utils.HashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+func (s *TwoFactorService) findUser(userID uint) (*models.User, error) {
+	user, err := s.users.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errcode.UserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// normalizeCode 去掉用户输入中的空格和连字符，恢复码不区分大小写
+func normalizeCode(code string) string {
+	code = strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code))
+	return strings.ToLower(code)
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != totpDigits.Length() {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
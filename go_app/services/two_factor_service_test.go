@@ -0,0 +1,76 @@
+package services_test
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"go_app/config"
+	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/repository"
+	"go_app/services"
+
+	"github.com/pquerna/otp/totp"
+)
+
+// TestTwoFactorConfirmThrottle 确认绑定时验证码错误次数按登录失败的规则限制，达到上限后正确的验证码也被拒绝
+func TestTwoFactorConfirmThrottle(t *testing.T) {
+	users := repository.NewMemoryUserRepository()
+	throttleCfg := config.Default().LoginThrottle
+	throttleCfg.BaseDelay = 0
+	throttleCfg.MaxFailures = 3
+	throttle := services.NewLoginThrottleService(repository.NewMemoryLoginAttemptRepository(), throttleCfg)
+	service := services.NewTwoFactorService(users, repository.NewMemoryTwoFactorRepository(), repository.NewMemoryTwoFactorChallengeRepository(), throttle, config.Default().TwoFactor)
+
+	enroll := func(t *testing.T, email string) (uint, string) {
+		t.Helper()
+		user := &models.User{Username: email, Email: email, Password: "-"}
+		if err := users.Create(user); err != nil {
+			t.Fatal(err)
+		}
+		enrollment, err := service.Enroll(user.ID)
+		if err != nil {
+			t.Fatalf("绑定失败: %v", err)
+		}
+		return user.ID, enrollment.Secret
+	}
+
+	alice, aliceSecret := enroll(t, "alice@example.com")
+	for i := 0; i < 3; i++ {
+		_, err := service.Confirm(alice, wrongCode(t, aliceSecret))
+		expectErr(t, err, errcode.TwoFactorCodeInvalid)
+	}
+	_, err := service.Confirm(alice, validCode(t, aliceSecret))
+	expectErr(t, err, errcode.TwoFactorLocked)
+
+	// 其他用户不受影响，达到上限前可以继续尝试
+	bob, bobSecret := enroll(t, "bob@example.com")
+	for i := 0; i < 2; i++ {
+		_, err := service.Confirm(bob, wrongCode(t, bobSecret))
+		expectErr(t, err, errcode.TwoFactorCodeInvalid)
+	}
+	if _, err := service.Confirm(bob, validCode(t, bobSecret)); err != nil {
+		t.Fatalf("确认绑定失败: %v", err)
+	}
+}
+
+func validCode(t *testing.T, secret string) string {
+	t.Helper()
+	code, err := totp.GenerateCode(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// wrongCode 与当前时间步的验证码不同的 6 位数字
+func wrongCode(t *testing.T, secret string) string {
+	t.Helper()
+	n, err := strconv.Atoi(validCode(t, secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf("%06d", (n+500000)%1000000)
+}
@@ -3,16 +3,29 @@ package services
 import (
 	"errors"
 	"fmt"
+	"go_app/config"
 	"go_app/models"
+	"go_app/pkg/errcode"
+	"go_app/repository"
 	"go_app/utils"
 	"mime/multipart"
+	"sync"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
 )
 
+// 用户信息中允许通过 UpdateUserSafe 修改的字段
+var userProfileFields = []string{"username", "avatar_url", "birthday", "gender", "hobbies"}
+
 type UserService struct {
-	db *gorm.DB
+	users     repository.UserRepository
+	sessions  *SessionService
+	roles     *RoleService
+	throttle  *LoginThrottleService
+	oauth     *OAuthService
+	mu        sync.RWMutex
+	imageHost config.ImageHostConfig
 }
 
 // SaveAvatar 保存用户头像到图床并更新数据库
@@ -25,19 +38,22 @@ func (s *UserService) SaveAvatar(userID uint, file *multipart.FileHeader) (strin
 	defer src.Close()
 
 	// 调用图床 API 上传图片
-	imageURL, err := utils.UploadToImageHost(file)
+	s.mu.RLock()
+	apiKey := s.imageHost.Token
+	s.mu.RUnlock()
+	imageURL, err := utils.UploadToImageHost(apiKey, file)
 	if err != nil {
 		return "", fmt.Errorf("上传图片失败: %v", err)
 	}
 
 	// 更新用户头像URL
-	var user models.User
-	if err := s.db.First(&user, userID).Error; err != nil {
+	user, err := s.users.FindByID(userID)
+	if err != nil {
 		return "", fmt.Errorf("查找用户失败: %v", err)
 	}
 
 	user.AvatarURL = imageURL
-	if err := s.db.Save(&user).Error; err != nil {
+	if err := s.users.Update(user, "avatar_url"); err != nil {
 		return "", fmt.Errorf("更新用户头像失败: %v", err)
 	}
 
@@ -49,40 +65,71 @@ func (s *UserService) VerifyPassword(hashedPassword, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
 
-func NewUserService(db *gorm.DB) *UserService {
-	return &UserService{db: db}
+func NewUserService(users repository.UserRepository, sessions *SessionService, roles *RoleService, throttle *LoginThrottleService, imageHost config.ImageHostConfig) *UserService {
+	return &UserService{users: users, sessions: sessions, roles: roles, throttle: throttle, imageHost: imageHost}
+}
+
+// SetOAuthService 设置 OAuth 授权服务，修改或重置密码时同时吊销用户授权给其他应用的令牌；需要在处理请求前调用
+func (s *UserService) SetOAuthService(oauth *OAuthService) {
+	s.oauth = oauth
+}
+
+// OnConfigChange 配置热加载回调，更新图床配置
+func (s *UserService) OnConfigChange(cfg *config.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.imageHost = cfg.ImageHost
 }
 
+// CreateUser 创建用户并分配默认角色
 func (s *UserService) CreateUser(user *models.User) error {
-	return s.db.Create(user).Error
+	if err := s.users.Create(user); err != nil {
+		return err
+	}
+	return s.roles.assignDefaultRole(user.ID)
 }
 
 func (s *UserService) GetUserByID(id uint) (*models.User, error) {
-	var user models.User
-	err := s.db.First(&user, id).Error
-	return &user, err
+	return s.users.FindByID(id)
 }
 
 func (s *UserService) GetUserByEmail(email string) (*models.User, error) {
-	var user models.User
-	// 确保查询包含所有必要字段
-	err := s.db.Where("email = ?", email).First(&user).Error
-	return &user, err
+	return s.users.FindByEmail(email)
 }
 
 func (s *UserService) UpdateUser(user *models.User) error {
-	result := s.db.Save(user)
-	return result.Error
+	return s.users.Update(user, repository.UserUpdatableFields...)
 }
 
+// DeleteUser 删除用户及其角色，不允许删除最后一个管理员
 func (s *UserService) DeleteUser(id uint) error {
-	return s.db.Delete(&models.User{}, id).Error
+	if err := s.roles.ensureNotLastAdmin(id); err != nil {
+		return err
+	}
+	if err := s.users.Delete(id); err != nil {
+		return err
+	}
+	return s.roles.removeUser(id)
 }
 
 func (s *UserService) ListUsers() ([]models.User, error) {
-	var users []models.User
-	err := s.db.Find(&users).Error
-	return users, err
+	users, _, err := s.users.List(repository.Page{})
+	return values(users), err
+}
+
+// sanitize 清除密码等敏感字段
+func sanitize(users ...*models.User) {
+	for _, user := range users {
+		user.Password = ""
+	}
+}
+
+func values(users []*models.User) []models.User {
+	list := make([]models.User, 0, len(users))
+	for _, user := range users {
+		list = append(list, *user)
+	}
+	return list
 }
 
 // HashPassword 密码加密
@@ -91,137 +138,164 @@ func (s *UserService) HashPassword(password string) (string, error) {
 	return string(hashedPassword), err
 }
 
-// Login 登录
-// GenerateToken 生成新的 token
-func (s *UserService) GenerateToken(user *models.User) (string, error) {
-	// 增加 token 版本号
-	user.TokenVersion++
-	if err := s.db.Save(user).Error; err != nil {
-		return "", err
-	}
-
-	// 生成包含版本号的 token
-	token, err := utils.GenerateToken(user.ID, user.TokenVersion)
+// GenerateToken 为用户创建新的登录会话并生成访问令牌和刷新令牌
+func (s *UserService) GenerateToken(user *models.User, meta models.SessionMeta) (*models.TokenResponse, error) {
+	tokens, _, err := s.sessions.Create(user, meta)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-
-	return token, nil
+	return tokens, nil
 }
 
-// Login 用户登录
-func (s *UserService) Login(email, password string) (*models.User, string, error) {
-    var user models.User
-    // 查询时包含 TokenVersion
-    if err := s.db.Select("id, username, email, password, avatar_url, token_version, created_at, updated_at").
-        Where("email = ?", email).First(&user).Error; err != nil {
-        return nil, "", errors.New("用户不存在")
+// Authenticate 校验邮箱和密码，不创建会话；开启两步验证的用户还需通过 TwoFactorService 完成第二步。
+// 邮箱不存在和密码错误统一返回 errcode.LoginFailed，失败次数过多时返回 *LoginThrottledError
+func (s *UserService) Authenticate(email, password, ip string) (*models.User, error) {
+    if err := s.throttle.Check(email, ip); err != nil {
+        return nil, err
+    }
+
+    user, err := s.users.FindByEmail(email)
+    if err != nil {
+        if !errors.Is(err, repository.ErrNotFound) {
+            return nil, err
+        }
+        // 邮箱不存在时同样计算一次哈希，避免通过响应时间判断邮箱是否已注册
+        s.VerifyPassword(dummyPasswordHash(), password)
+        return nil, s.loginFailed(email, ip)
     }
     
     // 验证密码
     if err := s.VerifyPassword(user.Password, password); err != nil {
-        return nil, "", errors.New("密码错误")
+        return nil, s.loginFailed(email, ip)
+    }
+    if err := s.throttle.Succeed(email, ip); err != nil {
+        return nil, err
+    }
+    if user.IsDisabled() {
+        return nil, errcode.UserDisabled
+    }
+    return user, nil
+}
+
+// loginFailed 记录登录失败并返回统一的错误
+func (s *UserService) loginFailed(email, ip string) error {
+    if err := s.throttle.Fail(email, ip); err != nil {
+        return err
+    }
+    return errcode.LoginFailed
+}
+
+var (
+    dummyHashOnce sync.Once
+    dummyHash     string
+)
+
+// dummyPasswordHash 与真实密码相同代价的 bcrypt 哈希，用于邮箱不存在时的密码校验
+func dummyPasswordHash() string {
+    dummyHashOnce.Do(func() {
+        hashed, _ := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+        dummyHash = string(hashed)
+    })
+    return dummyHash
+}
+
+// UnlockLogin 解除登录锁定并清除失败记录，userID 和 ip 为零值时跳过
+func (s *UserService) UnlockLogin(userID uint, ip string) error {
+    var email string
+    if userID != 0 {
+        user, err := s.users.FindByID(userID)
+        if err != nil {
+            if errors.Is(err, repository.ErrNotFound) {
+                return errcode.UserNotFound
+            }
+            return err
+        }
+        email = user.Email
     }
+    return s.throttle.Unlock(email, ip)
+}
 
+// Login 用户登录
+func (s *UserService) Login(email, password string, meta models.SessionMeta) (*models.User, *models.TokenResponse, error) {
+    user, err := s.Authenticate(email, password, meta.IP)
+    if err != nil {
+        return nil, nil, err
+    }
+    return s.CompleteLogin(user, meta)
+}
+
+// CompleteLogin 为已通过认证的用户创建会话并签发令牌
+func (s *UserService) CompleteLogin(user *models.User, meta models.SessionMeta) (*models.User, *models.TokenResponse, error) {
     // 生成新的 token
-    token, err := s.GenerateToken(&user)
+    tokens, err := s.GenerateToken(user, meta)
     if err != nil {
-        return nil, "", fmt.Errorf("生成token失败: %v", err)
+        return nil, nil, fmt.Errorf("生成token失败: %v", err)
     }
 
     // 将 token 设置到用户对象中，并清除敏感信息
-    user.Token = token
-    user.Password = ""
-
-    // 重新查询需要返回的字段
-    // 重新查询完整的用户信息
-    var safeUser models.User
-    if err := s.db.Select("id, username, email, avatar_url, birthday, gender, hobbies, created_at, updated_at").
-        First(&safeUser, user.ID).Error; err != nil {
-        return nil, "", fmt.Errorf("获取用户信息失败: %v", err)
-    }
-    safeUser.Token = token
+    sanitize(user)
+    user.Token = tokens.Token
 
-    return &safeUser, token, nil
+    return user, tokens, nil
 }
 
 // GetUserByIDSafe 安全地获取用户信息，不返回敏感字段
 func (s *UserService) GetUserByIDSafe(id uint) (*models.User, error) {
-    var user models.User
-    err := s.db.Select("id, username, email, avatar_url, birthday, gender, hobbies, created_at, updated_at").
-        First(&user, id).Error
+    user, err := s.users.FindByID(id)
     if err != nil {
         return nil, errors.New("用户不存在")
     }
-    return &user, nil
+    sanitize(user)
+    return user, nil
 }
 
 // ListUsersSafe 安全地获取用户列表，不返回敏感字段
 func (s *UserService) ListUsersSafe() ([]models.User, error) {
-	var users []models.User
-	err := s.db.Select("id, username, email, avatar_url, created_at, updated_at").Find(&users).Error
+	users, _, err := s.users.List(repository.Page{})
 	if err != nil {
 		return nil, errors.New("获取用户列表失败")
 	}
-	return users, err
+	sanitize(users...)
+	return values(users), nil
 }
 
 // ListUsersWithPage 分页获取用户列表
 func (s *UserService) ListUsersWithPage(page, pageSize int) ([]*models.User, int64, error) {
-    var users []*models.User
-    var total int64
-
-    if err := s.db.Model(&models.User{}).Count(&total).Error; err != nil {
-        return nil, 0, err
-    }
-
-    if err := s.db.Select("id, username, email, avatar_url, birthday, gender, hobbies, created_at, updated_at").
-        Offset((page - 1) * pageSize).
-        Limit(pageSize).
-        Find(&users).Error; err != nil {
+    users, total, err := s.users.List(repository.Page{Offset: (page - 1) * pageSize, Limit: pageSize})
+    if err != nil {
         return nil, 0, err
     }
+    sanitize(users...)
 
     return users, total, nil
 }
 
 // UpdateUserSafe 安全地更新用户信息，只允许更新指定字段
 func (s *UserService) UpdateUserSafe(user *models.User) error {
-    // 使用事务确保更新的原子性
-    return s.db.Transaction(func(tx *gorm.DB) error {
-        result := tx.Model(user).
-            Select("username", "avatar_url", "birthday", "gender", "hobbies", "updated_at").
-            Updates(user)
-        
-        if result.Error != nil {
-            return errors.New("更新用户信息失败")
-        }
-        
-        // 验证更新是否成功
-        if result.RowsAffected == 0 {
+    if err := s.users.Update(user, userProfileFields...); err != nil {
+        if errors.Is(err, repository.ErrNotFound) {
             return errors.New("未找到要更新的用户")
         }
-        
-        return nil
-    })
+        return errors.New("更新用户信息失败")
+    }
+    return nil
 }
 
 // ListUsers 获取用户列表
 // ListUsersDetail 获取用户列表(不含敏感数据)
 func (s *UserService) ListUsersDetail() ([]models.User, error) {
-	var users []models.User
-	err := s.db.Select("id, username, email, created_at, updated_at").Find(&users).Error
+	users, _, err := s.users.List(repository.Page{})
 	if err != nil {
 		return nil, errors.New("获取用户列表失败")
 	}
-	return users, err
+	sanitize(users...)
+	return values(users), nil
 }
 
 // 保留新的 UpdateUser 方法，这个实现更安全，只允许更新特定字段
 // UpdateUserInfo 更新用户基本信息，只允许更新用户名和邮箱
 func (s *UserService) UpdateUserInfo(user *models.User) error {
-	result := s.db.Model(user).Select("username", "email").Updates(user)
-	if result.Error != nil {
+	if err := s.users.Update(user, "username", "email"); err != nil {
 		return errors.New("更新用户信息失败")
 	}
 	return nil
@@ -229,16 +303,22 @@ func (s *UserService) UpdateUserInfo(user *models.User) error {
 
 // ChangePassword 修改用户密码
 func (s *UserService) ChangePassword(userID uint, oldPassword, newPassword string) error {
-	var user models.User
-	// 确保查询包含所有字段，特别是密码字段
-	if err := s.db.First(&user, userID).Error; err != nil {
+	user, err := s.users.FindByID(userID)
+	if err != nil {
 		return errors.New("用户不存在")
 	}
 
-	// 验证旧密码
+	// 验证旧密码，错误次数按登录失败的规则限制，避免持有访问令牌的人穷举密码
+	key := fmt.Sprintf("password:%d", userID)
+	if err := s.throttle.Reserve(key); err != nil {
+		return err
+	}
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(oldPassword)); err != nil {
 		return errors.New("旧密码不正确")
 	}
+	if err := s.throttle.Release(key); err != nil {
+		return err
+	}
 
 	// 加密新密码
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
@@ -246,33 +326,74 @@ func (s *UserService) ChangePassword(userID uint, oldPassword, newPassword strin
 		return errors.New("密码加密失败")
 	}
 
-	// 更新密码
-	if err := s.db.Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
+	// 更新密码，同时递增 token 版本使所有已签发的令牌失效
+	if err := s.users.UpdatePassword(user.ID, string(hashedPassword)); err != nil {
 		return errors.New("密码更新失败")
 	}
 
+	// 注销全部会话和其他应用的授权，所有设备需使用新密码重新登录
+	if err := s.revokeCredentials(userID); err != nil {
+		return errors.New("注销会话失败")
+	}
+
 	return nil
 }
 
-// Logout 用户退出
-func (s *UserService) Logout(userID uint) error {
-    var user models.User
-    // 查询时包含 TokenVersion
-    if err := s.db.Select("id, token_version").First(&user, userID).Error; err != nil {
-        return errors.New("用户不存在")
-    }
+// ResetPassword 重置用户密码（管理员操作或找回密码），不校验旧密码，同时注销该用户的全部会话和其他应用的授权
+func (s *UserService) ResetPassword(userID uint, newPassword string) error {
+	hashedPassword, err := s.HashPassword(newPassword)
+	if err != nil {
+		return errors.New("密码加密失败")
+	}
+	if err := s.users.UpdatePassword(userID, hashedPassword); err != nil {
+		return err
+	}
+	return s.revokeCredentials(userID)
+}
 
-    user.TokenVersion++
-    if err := s.db.Model(&user).Update("token_version", user.TokenVersion).Error; err != nil {
-        return errors.New("登出失败")
-    }
+// revokeCredentials 密码变更后注销用户的全部会话，并吊销授权给其他应用的令牌
+func (s *UserService) revokeCredentials(userID uint) error {
+	if err := s.sessions.RevokeAll(userID); err != nil {
+		return err
+	}
+	if s.oauth == nil {
+		return nil
+	}
+	return s.oauth.RevokeUser(userID)
+}
 
-    return nil
+// DisableUser 禁用用户并注销其全部会话，被禁用的用户无法登录或刷新令牌
+func (s *UserService) DisableUser(userID uint) error {
+	now := time.Now()
+	if err := s.users.SetDisabled(userID, &now); err != nil {
+		return err
+	}
+	return s.sessions.RevokeAll(userID)
+}
+
+// EnableUser 解除用户禁用
+func (s *UserService) EnableUser(userID uint) error {
+	return s.users.SetDisabled(userID, nil)
+}
+
+// RevokeSessions 注销用户的全部会话，用户需要重新登录
+func (s *UserService) RevokeSessions(userID uint) error {
+	if _, err := s.users.FindByID(userID); err != nil {
+		return err
+	}
+	return s.sessions.RevokeAll(userID)
+}
+
+// Logout 用户退出，仅注销当前会话
+func (s *UserService) Logout(userID, sessionID uint) error {
+	if err := s.sessions.Revoke(userID, sessionID); err != nil {
+		return errors.New("登出失败")
+	}
+	return nil
 }
 
 // 添加新方法
 func (s *UserService) IsEmailExists(email string) bool {
-	var count int64
-	s.db.Model(&models.User{}).Where("email = ?", email).Count(&count)
-	return count > 0
+	exists, _ := s.users.EmailExists(email)
+	return exists
 }
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"go_app/config"
+	"go_app/models"
+	"go_app/pkg/websocket"
+	"go_app/repository"
+)
+
+// WebSocketOutboxService 基于数据库的 WebSocket 发件箱，实现 websocket.Outbox。
+// 每个用户保留最近 websocket.outbox_size 条、不超过 websocket.outbox_retention 分钟的消息
+type WebSocketOutboxService struct {
+	messages repository.WebSocketOutboxRepository
+
+	mu  sync.RWMutex
+	cfg config.WebSocketConfig
+}
+
+func NewWebSocketOutboxService(messages repository.WebSocketOutboxRepository, cfg config.WebSocketConfig) *WebSocketOutboxService {
+	return &WebSocketOutboxService{messages: messages, cfg: cfg}
+}
+
+// OnConfigChange 配置热加载回调，新的保留条数和时长在下次保存或补发消息时生效
+func (s *WebSocketOutboxService) OnConfigChange(cfg *config.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg.WebSocket
+}
+
+func (s *WebSocketOutboxService) current() config.WebSocketConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
+
+// cutoff 早于该时间的消息不再补发
+func (s *WebSocketOutboxService) cutoff(cfg config.WebSocketConfig) time.Time {
+	return time.Now().Add(-time.Duration(cfg.OutboxRetention) * time.Minute)
+}
+
+func (s *WebSocketOutboxService) Append(userID uint, msg *websocket.Message) error {
+	data, err := json.Marshal(msg.Data)
+	if err != nil {
+		return err
+	}
+	cfg := s.current()
+	record := &models.WebSocketMessage{UserID: userID, Type: msg.Type, Data: string(data)}
+	if err := s.messages.Append(record, cfg.OutboxSize, s.cutoff(cfg)); err != nil {
+		return err
+	}
+	msg.ID = record.Seq
+	msg.Time = record.CreatedAt.Unix()
+	return nil
+}
+
+func (s *WebSocketOutboxService) Replay(userID uint, afterID uint64) ([]*websocket.Message, *websocket.Gap, error) {
+	cursor, err := s.messages.Cursor(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if afterID >= cursor.LastSeq {
+		return nil, nil, nil
+	}
+
+	cfg := s.current()
+	records, err := s.messages.ListAfter(userID, afterID, s.cutoff(cfg), cfg.OutboxSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	messages := make([]*websocket.Message, 0, len(records))
+	for _, record := range records {
+		messages = append(messages, &websocket.Message{
+			ID:   record.Seq,
+			Type: record.Type,
+			Data: json.RawMessage(record.Data),
+			Time: record.CreatedAt.Unix(),
+		})
+	}
+
+	// 第一条可补发的消息之前还有消息，说明它们已被删除
+	first := cursor.LastSeq + 1
+	if len(records) > 0 {
+		first = records[0].Seq
+	}
+	var gap *websocket.Gap
+	if first > afterID+1 {
+		gap = &websocket.Gap{From: afterID + 1, To: first - 1}
+	}
+	return messages, gap, nil
+}
+
+func (s *WebSocketOutboxService) Ack(userID uint, id uint64) error {
+	return s.messages.Ack(userID, id)
+}
+
+func (s *WebSocketOutboxService) Acked(userID uint) (uint64, error) {
+	cursor, err := s.messages.Cursor(userID)
+	if err != nil {
+		return 0, err
+	}
+	return cursor.AckedSeq, nil
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"strconv"
+	"strings"
+
+	"go_app/pkg/errcode"
+	"go_app/pkg/websocket"
+	"go_app/repository"
+)
+
+// WebSocketTopicAuthorizer WebSocket 主题的订阅权限，按主题的根判断：
+// activity 为公开的活动动态；activity:{id} 只有活动参与者可以订阅；user:{id} 只有该用户可以订阅；
+// presence:{id} 为用户的在线状态变化，与在线状态接口一样所有用户都可以订阅。其他主题不允许订阅
+type WebSocketTopicAuthorizer struct {
+	activities repository.ActivityRepository
+}
+
+func NewWebSocketTopicAuthorizer(activities repository.ActivityRepository) *WebSocketTopicAuthorizer {
+	return &WebSocketTopicAuthorizer{activities: activities}
+}
+
+// Authorize 实现 websocket.TopicAuthorizer，订阅后退出活动不会取消已有的订阅
+func (a *WebSocketTopicAuthorizer) Authorize(userID uint, pattern string) error {
+	kind, id, scoped := strings.Cut(websocket.TopicRoot(pattern), ":")
+	switch {
+	case kind == "activity" && !scoped:
+		return nil
+	case kind == "activity":
+		activityID, err := strconv.ParseUint(id, 10, 0)
+		if err != nil {
+			return errcode.WebSocketTopicForbidden
+		}
+		ok, err := a.activities.IsParticipant(uint(activityID), userID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errcode.WebSocketTopicForbidden
+		}
+		return nil
+	case kind == "user" && scoped && id == strconv.FormatUint(uint64(userID), 10):
+		return nil
+	case kind == "presence" && scoped:
+		if _, err := strconv.ParseUint(id, 10, 0); err == nil {
+			return nil
+		}
+	}
+	return errcode.WebSocketTopicForbidden
+}
@@ -7,7 +7,6 @@ import (
     "io"
     "mime/multipart"
     "net/http"
-    "go_app/config"  // 添加配置包导入
 )
 
 // ImageResponse ImgBB响应结构
@@ -20,19 +19,17 @@ type ImageResponse struct {
     Status  int    `json:"status"`
 }
 
-// UploadToImageHost 上传图片到图床
-func UploadToImageHost(file *multipart.FileHeader) (string, error) {
-    // 获取配置
-    cfg, err := config.LoadConfig()
-    if err != nil {
-        return "", fmt.Errorf("加载配置失败: %v", err)
+// UploadToImageHost 上传图片到图床，apiKey 为 ImgBB API Key
+func UploadToImageHost(apiKey string, file *multipart.FileHeader) (string, error) {
+    if apiKey == "" {
+        return "", fmt.Errorf("未配置图床 API Key")
     }
 
     body := &bytes.Buffer{}
     writer := multipart.NewWriter(body)
 
     // 添加API key
-    writer.WriteField("key", cfg.ImageHost.Token)
+    writer.WriteField("key", apiKey)
 
     // 创建文件表单字段
     part, err := writer.CreateFormFile("image", file.Filename)
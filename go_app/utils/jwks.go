@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"go_app/config"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWK 单个公钥（RFC 7517）
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA 模数
+	E   string `json:"e,omitempty"`   // RSA 指数
+	Crv string `json:"crv,omitempty"` // OKP 曲线
+	X   string `json:"x,omitempty"`   // OKP 公钥
+}
+
+// JWKS 公钥集合，供其他服务验证本服务签发的 token
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadKeySet 根据 JWT 配置加载密钥集合
+func LoadKeySet(cfg config.JWTConfig) (*KeySet, error) {
+	switch cfg.Algorithm {
+	case "", config.JWTAlgorithmHS256:
+		if cfg.Secret == "" {
+			return nil, errors.New("HS256 需要配置 jwt.secret")
+		}
+		return NewHMACKeySet([]byte(cfg.Secret)), nil
+	case config.JWTAlgorithmRS256, config.JWTAlgorithmEdDSA:
+	default:
+		return nil, fmt.Errorf("不支持的签名算法: %s", cfg.Algorithm)
+	}
+
+	if cfg.SigningKey.File == "" {
+		return nil, fmt.Errorf("%s 需要配置 jwt.signing_key.file", cfg.Algorithm)
+	}
+	current, err := loadPEMKey(cfg.SigningKey, true)
+	if err != nil {
+		return nil, err
+	}
+	if current.method.Alg() != cfg.Algorithm {
+		return nil, fmt.Errorf("签名密钥类型 %s 与配置的算法 %s 不一致", current.method.Alg(), cfg.Algorithm)
+	}
+
+	others := make([]*signingKey, 0, len(cfg.VerificationKeys))
+	for _, kc := range cfg.VerificationKeys {
+		key, err := loadPEMKey(kc, false)
+		if err != nil {
+			return nil, err
+		}
+		if key.kid == current.kid {
+			return nil, fmt.Errorf("验签密钥ID与签名密钥重复: %s", key.kid)
+		}
+		others = append(others, key)
+	}
+
+	return newKeySet(current, others...), nil
+}
+
+// loadPEMKey 从 PEM 文件加载 RSA 或 Ed25519 密钥，needPrivate 为 true 时要求是私钥
+func loadPEMKey(kc config.JWTKeyConfig, needPrivate bool) (*signingKey, error) {
+	data, err := os.ReadFile(kc.File)
+	if err != nil {
+		return nil, fmt.Errorf("读取密钥文件失败: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("密钥文件 %s 不是有效的 PEM 格式", kc.File)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("密钥文件 %s 的 PEM 类型 %s 不受支持", kc.File, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析密钥文件 %s 失败: %v", kc.File, err)
+	}
+
+	key := &signingKey{kid: kc.KID, public: true}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.sign, key.verify = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.method, key.verify = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.method, key.sign, key.verify = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.method, key.verify = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("密钥文件 %s 的密钥类型不受支持，仅支持 RSA 和 Ed25519", kc.File)
+	}
+	if pub, ok := key.verify.(*rsa.PublicKey); ok && pub.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("密钥文件 %s 的 RSA 密钥长度不能小于 2048 位", kc.File)
+	}
+	if needPrivate && key.sign == nil {
+		return nil, fmt.Errorf("签名密钥文件 %s 必须是私钥", kc.File)
+	}
+
+	if key.kid == "" {
+		key.kid = thumbprint(toJWK(key))
+	}
+	return key, nil
+}
+
+// JWKS 返回可公开的验签公钥，HS256 共享密钥不会被公开
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range ks.list {
+		if key.public {
+			set.Keys = append(set.Keys, toJWK(key))
+		}
+	}
+	return set
+}
+
+func toJWK(key *signingKey) JWK {
+	jwk := JWK{Kid: key.kid, Use: "sig", Alg: key.method.Alg()}
+	switch pub := key.verify.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	}
+	return jwk
+}
+
+// thumbprint 计算 JWK 指纹（RFC 7638），作为未配置 kid 时的默认值
+func thumbprint(jwk JWK) string {
+	var members interface{}
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -1,48 +1,117 @@
 package utils
 
 import (
-    "time"
+    "errors"
+    "fmt"
     "go_app/pkg/errcode"
-    "github.com/golang-jwt/jwt"
+
+    "github.com/golang-jwt/jwt/v5"
 )
 
 type Claims struct {
     UserID       uint `json:"user_id"`
     TokenVersion int  `json:"token_version"`
-    jwt.StandardClaims
+    SessionID    uint `json:"sid"` // 所属会话 user_tokens.id，jti 为会话标识
+    jwt.RegisteredClaims
+}
+
+// signingKey 单个签名/验签密钥
+type signingKey struct {
+    kid    string
+    method jwt.SigningMethod
+    sign   interface{} // 私钥（HS256 为密钥本身），仅当前签名密钥需要
+    verify interface{} // 公钥（HS256 为密钥本身）
+    public bool        // 是否可通过 JWKS 公开
+}
+
+// KeySet JWT 密钥集合：一个当前签名密钥和若干仅用于验签的密钥
+type KeySet struct {
+    current *signingKey
+    list    []*signingKey // 当前签名密钥在前
+    keys    map[string]*signingKey
+    methods []string
+}
+
+// NewHMACKeySet 使用 HS256 共享密钥创建密钥集合
+func NewHMACKeySet(secret []byte) *KeySet {
+    key := &signingKey{method: jwt.SigningMethodHS256, sign: secret, verify: secret}
+    return newKeySet(key)
+}
+
+func newKeySet(current *signingKey, others ...*signingKey) *KeySet {
+    ks := &KeySet{
+        current: current,
+        list:    append([]*signingKey{current}, others...),
+        keys:    make(map[string]*signingKey),
+    }
+    seen := make(map[string]bool)
+    for _, k := range ks.list {
+        ks.keys[k.kid] = k
+        if !seen[k.method.Alg()] {
+            seen[k.method.Alg()] = true
+            ks.methods = append(ks.methods, k.method.Alg())
+        }
+    }
+    return ks
 }
 
-func GenerateToken(userID uint, tokenVersion int) (string, error) {
-    claims := Claims{
-        UserID:       userID,
-        TokenVersion: tokenVersion,
-        StandardClaims: jwt.StandardClaims{
-            ExpiresAt: time.Now().Add(24 * time.Hour).Unix(),
-            IssuedAt:  time.Now().Unix(),
-        },
+// Sign 使用当前签名密钥签发 token，非空 kid 写入头部
+func (ks *KeySet) Sign(claims *Claims) (string, error) {
+    return ks.SignClaims(claims)
+}
+
+// SignClaims 使用当前签名密钥签发任意声明的 JWT，如 OIDC ID Token
+func (ks *KeySet) SignClaims(claims jwt.Claims) (string, error) {
+    token := jwt.NewWithClaims(ks.current.method, claims)
+    if ks.current.kid != "" {
+        token.Header["kid"] = ks.current.kid
     }
+    return token.SignedString(ks.current.sign)
+}
+
+// Algorithm 当前签名密钥的算法，如 RS256
+func (ks *KeySet) Algorithm() string {
+    return ks.current.method.Alg()
+}
+
+// Parse 校验签名及有效期并解析 token
+func (ks *KeySet) Parse(tokenString string) (*Claims, error) {
+    return ks.parse(tokenString)
+}
 
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-    return token.SignedString([]byte("your-secret-key")) // 请使用配置文件中的密钥
+// ParseIgnoreExpiry 校验签名但忽略有效期，用于注销已过期的令牌
+func (ks *KeySet) ParseIgnoreExpiry(tokenString string) (*Claims, error) {
+    return ks.parse(tokenString, jwt.WithoutClaimsValidation())
 }
 
-func ParseToken(tokenString string) (*Claims, error) {
-    token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-        return []byte("your-secret-key"), nil
-    })
+func (ks *KeySet) parse(tokenString string, opts ...jwt.ParserOption) (*Claims, error) {
+    opts = append(opts, jwt.WithValidMethods(ks.methods))
+    token, err := jwt.ParseWithClaims(tokenString, &Claims{}, ks.keyFunc, opts...)
 
     if err != nil {
-        ve, ok := err.(*jwt.ValidationError)
-        if ok && ve.Errors&jwt.ValidationErrorExpired != 0 {
+        if errors.Is(err, jwt.ErrTokenExpired) {
             return nil, errcode.TokenExpired
         }
         return nil, errcode.TokenInvalid
     }
 
     claims, ok := token.Claims.(*Claims)
-    if !ok || !token.Valid {
+    if !ok || !token.Valid || claims.UserID == 0 {
         return nil, errcode.TokenInvalid
     }
 
     return claims, nil
-}
\ No newline at end of file
+}
+
+// keyFunc 按 kid 选择验签密钥，并要求 token 声明的算法与密钥算法一致
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+    kid, _ := token.Header["kid"].(string)
+    key, ok := ks.keys[kid]
+    if !ok {
+        return nil, fmt.Errorf("未知的密钥ID: %q", kid)
+    }
+    if token.Method.Alg() != key.method.Alg() {
+        return nil, fmt.Errorf("签名算法不匹配: %s", token.Method.Alg())
+    }
+    return key.verify, nil
+}
@@ -0,0 +1,283 @@
+package utils_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go_app/config"
+	"go_app/pkg/errcode"
+	"go_app/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TestKeySetAlgorithms 使用 RS256 和 EdDSA 私钥签发，签发的 token 头部带有 kid 并能通过验签
+func TestKeySetAlgorithms(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey := newRSAKey(t)
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		alg  string
+		file string
+	}{
+		{config.JWTAlgorithmRS256, writePrivateKey(t, dir, "rsa.pem", rsaKey)},
+		{config.JWTAlgorithmEdDSA, writePrivateKey(t, dir, "ed.pem", edKey)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.alg, func(t *testing.T) {
+			ks := loadKeySet(t, config.JWTConfig{Algorithm: tt.alg, SigningKey: config.JWTKeyConfig{KID: "k1", File: tt.file}})
+			if ks.Algorithm() != tt.alg {
+				t.Fatalf("Algorithm() = %s, want %s", ks.Algorithm(), tt.alg)
+			}
+
+			token := sign(t, ks, time.Now().Add(time.Hour))
+			if alg, kid := header(t, token); alg != tt.alg || kid != "k1" {
+				t.Fatalf("头部 alg = %s kid = %s, want %s k1", alg, kid, tt.alg)
+			}
+			claims, err := ks.Parse(token)
+			if err != nil {
+				t.Fatalf("验签失败: %v", err)
+			}
+			if claims.UserID != 1 {
+				t.Fatalf("UserID = %d, want 1", claims.UserID)
+			}
+
+			_, err = ks.Parse(sign(t, ks, time.Now().Add(-time.Minute)))
+			expectCode(t, err, errcode.TokenExpired)
+			if _, err := ks.ParseIgnoreExpiry(sign(t, ks, time.Now().Add(-time.Minute))); err != nil {
+				t.Fatalf("忽略有效期验签失败: %v", err)
+			}
+		})
+	}
+
+	// 算法与密钥类型不一致、签名密钥不是私钥、RSA 密钥过短时拒绝加载
+	short, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid := []config.JWTConfig{
+		{Algorithm: config.JWTAlgorithmEdDSA, SigningKey: config.JWTKeyConfig{File: tests[0].file}},
+		{Algorithm: config.JWTAlgorithmEdDSA, SigningKey: config.JWTKeyConfig{File: writePublicKey(t, dir, "ed.pub", edPub)}},
+		{Algorithm: config.JWTAlgorithmRS256, SigningKey: config.JWTKeyConfig{File: writePrivateKey(t, dir, "short.pem", short)}},
+		{Algorithm: config.JWTAlgorithmRS256},
+		{Algorithm: "HS512", Secret: "secret"},
+	}
+	for _, cfg := range invalid {
+		if _, err := utils.LoadKeySet(cfg); err == nil {
+			t.Fatalf("LoadKeySet(%+v) 未返回错误", cfg)
+		}
+	}
+}
+
+// TestKeyRotation 密钥轮换期间新旧密钥签发的 token 都能通过验签，按 kid 选择验签密钥，未知的 kid 被拒绝
+func TestKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldKey := newRSAKey(t)
+	newPub, newKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldFile := writePrivateKey(t, dir, "old.pem", oldKey)
+	oldPublic := writePublicKey(t, dir, "old.pub", &oldKey.PublicKey)
+	newFile := writePrivateKey(t, dir, "new.pem", newKey)
+
+	before := loadKeySet(t, config.JWTConfig{
+		Algorithm:  config.JWTAlgorithmRS256,
+		SigningKey: config.JWTKeyConfig{KID: "old", File: oldFile},
+	})
+	overlap := loadKeySet(t, config.JWTConfig{
+		Algorithm:        config.JWTAlgorithmEdDSA,
+		SigningKey:       config.JWTKeyConfig{KID: "new", File: newFile},
+		VerificationKeys: []config.JWTKeyConfig{{KID: "old", File: oldPublic}},
+	})
+	after := loadKeySet(t, config.JWTConfig{
+		Algorithm:  config.JWTAlgorithmEdDSA,
+		SigningKey: config.JWTKeyConfig{KID: "new", File: newFile},
+	})
+
+	oldToken := sign(t, before, time.Now().Add(time.Hour))
+	newToken := sign(t, overlap, time.Now().Add(time.Hour))
+	if _, kid := header(t, newToken); kid != "new" {
+		t.Fatalf("轮换后签发的 kid = %s, want new", kid)
+	}
+
+	for name, token := range map[string]string{"Old": oldToken, "New": newToken} {
+		if _, err := overlap.Parse(token); err != nil {
+			t.Fatalf("轮换期间 %s 密钥签发的 token 验签失败: %v", name, err)
+		}
+	}
+	_, err = before.Parse(newToken)
+	expectCode(t, err, errcode.TokenInvalid)
+	_, err = after.Parse(oldToken)
+	expectCode(t, err, errcode.TokenInvalid)
+
+	// 用新密钥签名但声明旧密钥或未知的 kid
+	for _, kid := range []string{"old", "unknown", ""} {
+		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims(time.Now().Add(time.Hour)))
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(newKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = overlap.Parse(signed)
+		expectCode(t, err, errcode.TokenInvalid)
+	}
+
+	// 验签密钥与签名密钥的 kid 不能重复
+	if _, err := utils.LoadKeySet(config.JWTConfig{
+		Algorithm:        config.JWTAlgorithmEdDSA,
+		SigningKey:       config.JWTKeyConfig{KID: "new", File: newFile},
+		VerificationKeys: []config.JWTKeyConfig{{KID: "new", File: writePublicKey(t, dir, "new.pub", newPub)}},
+	}); err == nil {
+		t.Fatal("重复的 kid 未返回错误")
+	}
+}
+
+// TestJWKS JWKS 按签名密钥在前的顺序公开全部公钥，HS256 共享密钥不公开，未配置 kid 时使用 JWK 指纹
+func TestJWKS(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey := newRSAKey(t)
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keys := utils.NewHMACKeySet([]byte("secret")).JWKS().Keys; len(keys) != 0 {
+		t.Fatalf("HS256 公开了 %d 个密钥", len(keys))
+	}
+
+	ks := loadKeySet(t, config.JWTConfig{
+		Algorithm:        config.JWTAlgorithmEdDSA,
+		SigningKey:       config.JWTKeyConfig{File: writePrivateKey(t, dir, "ed.pem", edKey)},
+		VerificationKeys: []config.JWTKeyConfig{{KID: "old", File: writePublicKey(t, dir, "rsa.pub", &rsaKey.PublicKey)}},
+	})
+	keys := ks.JWKS().Keys
+	if len(keys) != 2 {
+		t.Fatalf("JWKS 包含 %d 个密钥, want 2", len(keys))
+	}
+
+	ed, old := keys[0], keys[1]
+	if ed.Kty != "OKP" || ed.Crv != "Ed25519" || ed.Alg != "EdDSA" || ed.Use != "sig" {
+		t.Fatalf("Ed25519 JWK = %+v", ed)
+	}
+	if ed.X != base64.RawURLEncoding.EncodeToString(edPub) {
+		t.Fatal("Ed25519 JWK 的公钥不一致")
+	}
+	if ed.Kid == "" {
+		t.Fatal("未配置 kid 时没有使用 JWK 指纹")
+	}
+	if _, kid := header(t, sign(t, ks, time.Now().Add(time.Hour))); kid != ed.Kid {
+		t.Fatalf("签发的 kid = %s, want %s", kid, ed.Kid)
+	}
+
+	if old.Kty != "RSA" || old.Kid != "old" || old.Alg != "RS256" {
+		t.Fatalf("RSA JWK = %+v", old)
+	}
+	if decodeInt(t, old.N).Cmp(rsaKey.N) != 0 || decodeInt(t, old.E).Int64() != int64(rsaKey.E) {
+		t.Fatal("RSA JWK 的公钥不一致")
+	}
+}
+
+func loadKeySet(t *testing.T, cfg config.JWTConfig) *utils.KeySet {
+	t.Helper()
+	ks, err := utils.LoadKeySet(cfg)
+	if err != nil {
+		t.Fatalf("加载密钥失败: %v", err)
+	}
+	return ks
+}
+
+func claims(expiresAt time.Time) *utils.Claims {
+	return &utils.Claims{
+		UserID:           1,
+		SessionID:        1,
+		RegisteredClaims: jwt.RegisteredClaims{ID: "session", ExpiresAt: jwt.NewNumericDate(expiresAt)},
+	}
+}
+
+func sign(t *testing.T, ks *utils.KeySet, expiresAt time.Time) string {
+	t.Helper()
+	token, err := ks.Sign(claims(expiresAt))
+	if err != nil {
+		t.Fatalf("签发失败: %v", err)
+	}
+	return token
+}
+
+// header 返回 token 头部的 alg 和 kid
+func header(t *testing.T, token string) (string, string) {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &utils.Claims{})
+	if err != nil {
+		t.Fatalf("解析 token 失败: %v", err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return parsed.Method.Alg(), kid
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func writePrivateKey(t *testing.T, dir, name string, key interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writePEM(t, dir, name, "PRIVATE KEY", der)
+}
+
+func writePublicKey(t *testing.T, dir, name string, key interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writePEM(t, dir, name, "PUBLIC KEY", der)
+}
+
+func writePEM(t *testing.T, dir, name, typ string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func decodeInt(t *testing.T, s string) *big.Int {
+	t.Helper()
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return new(big.Int).SetBytes(data)
+}
+
+func expectCode(t *testing.T, err error, want *errcode.ErrorCode) {
+	t.Helper()
+	if err != want {
+		t.Fatalf("错误 = %v, want %v", err, want)
+	}
+}